@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Token required in "Authorization: Bearer <token>" for admin-only features.
+// Admin features are disabled when it is empty.
+var adminToken = envString("SIX_ADMIN_TOKEN", "")
+
+// Reports whether r carries the admin token or the X-API-Key of a key with
+// the admin role, writing an error response if not.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if k := lookupAPIKey(r.Header.Get("X-API-Key")); k != nil && k.role.can(permAdmin) {
+		return true
+	}
+	if adminToken == "" {
+		writeError(w, r, codeAdminDisabled)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		writeError(w, r, codeAdminUnauthorized)
+		return false
+	}
+	return true
+}
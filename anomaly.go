@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+)
+
+// The anomaly detector compares each scrape of a schedule with the recent
+// accepted scrapes of the same page. A scrape whose row count, class count,
+// column count, or payload size deviates sharply is flagged in meta and is
+// not allowed to replace the last known good snapshot, so a half-rendered or
+// redesigned page cannot silently wipe out good data.
+var (
+	anomalyHistorySize = envInt("SIX_ANOMALY_HISTORY", 10)
+	anomalyMinHistory  = envInt("SIX_ANOMALY_MIN_HISTORY", 3)
+	anomalyThreshold   = envFloat("SIX_ANOMALY_THRESHOLD", 0.5)
+	// Consecutive anomalous scrapes that agree with each other are accepted
+	// as the new normal, e.g. when a new semester adds many classes.
+	anomalyAcceptAfter = envInt("SIX_ANOMALY_ACCEPT_AFTER", 3)
+)
+
+// Shape of one scraped page.
+type scrapeSample struct {
+	Rows    int
+	Columns int
+	Classes int
+	Bytes   int64
+}
+
+type Anomaly struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+type scrapeHistory struct {
+	accepted []scrapeSample
+	rejected []scrapeSample // consecutive anomalous samples since the last accepted one
+}
+
+// Recent scrape samples per schedule.
+type anomalyDetector struct {
+	mu      sync.Mutex
+	history map[string]*scrapeHistory
+}
+
+func newAnomalyDetector() *anomalyDetector {
+	return &anomalyDetector{history: make(map[string]*scrapeHistory)}
+}
+
+// Measures the schedule table in doc. Columns is the most common cell count
+// per row. bytes is the size of the upstream payload.
+func sampleScrape(doc *goquery.Document, classes []CourseClass, bytes int64) scrapeSample {
+	counts := make(map[int]int)
+	rows := 0
+	scraper.ClassTables(doc).Each(func(_ int, table *goquery.Selection) {
+		for _, cells := range scraper.GridRows(table.ChildrenFiltered("tbody").ChildrenFiltered("tr"), "td, th") {
+			counts[len(cells)]++
+			rows++
+		}
+	})
+	columns, best := 0, 0
+	for n, c := range counts {
+		if c > best || (c == best && n > columns) {
+			columns, best = n, c
+		}
+	}
+	return scrapeSample{Rows: rows, Columns: columns, Classes: len(classes), Bytes: bytes}
+}
+
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// Returns how far got is from the median of history, relative to the median.
+// A drop to zero from a non-zero baseline scores 1.
+func deviation(history []scrapeSample, got float64, metric func(scrapeSample) float64) float64 {
+	values := make([]float64, len(history))
+	for i, s := range history {
+		values[i] = metric(s)
+	}
+	base := median(values)
+	if base == 0 {
+		if got == 0 {
+			return 0
+		}
+		return 1
+	}
+	return math.Abs(got-base) / base
+}
+
+// Scores sample against the accepted history of key and records it. Returns
+// nil when the sample looks normal or there is not enough history yet.
+func (d *anomalyDetector) score(key string, sample scrapeSample) *Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.history[key]
+	if !ok {
+		h = &scrapeHistory{}
+		d.history[key] = h
+	}
+
+	var anomaly *Anomaly
+	if len(h.accepted) >= anomalyMinHistory {
+		anomaly = compareScrape(h.accepted, sample)
+	}
+	if anomaly == nil {
+		h.accept(sample)
+		return nil
+	}
+
+	h.rejected = append(h.rejected, sample)
+	if len(h.rejected) >= anomalyAcceptAfter && compareScrape(h.rejected[:len(h.rejected)-1], sample) == nil {
+		// The "anomaly" is stable; treat it as the new normal.
+		h.accepted = nil
+		for _, s := range h.rejected {
+			h.accept(s)
+		}
+		return nil
+	}
+	return anomaly
+}
+
+func (h *scrapeHistory) accept(s scrapeSample) {
+	h.rejected = nil
+	h.accepted = append(h.accepted, s)
+	if len(h.accepted) > anomalyHistorySize {
+		h.accepted = h.accepted[len(h.accepted)-anomalyHistorySize:]
+	}
+}
+
+func compareScrape(history []scrapeSample, s scrapeSample) *Anomaly {
+	checks := []struct {
+		name   string
+		got    float64
+		metric func(scrapeSample) float64
+	}{
+		{"rows", float64(s.Rows), func(s scrapeSample) float64 { return float64(s.Rows) }},
+		{"classes", float64(s.Classes), func(s scrapeSample) float64 { return float64(s.Classes) }},
+		{"columns", float64(s.Columns), func(s scrapeSample) float64 { return float64(s.Columns) }},
+		{"bytes", float64(s.Bytes), func(s scrapeSample) float64 { return float64(s.Bytes) }},
+	}
+
+	a := &Anomaly{}
+	for _, c := range checks {
+		d := deviation(history, c.got, c.metric)
+		if c.name == "columns" && d > 0 {
+			// Any change in column count means the table layout changed.
+			d = max(d, 1)
+		}
+		a.Score = max(a.Score, d)
+		if d >= anomalyThreshold {
+			a.Reasons = append(a.Reasons, fmt.Sprintf("%s=%v deviates %.0f%% from recent scrapes", c.name, c.got, d*100))
+		}
+	}
+	if len(a.Reasons) == 0 {
+		return nil
+	}
+	a.Score = math.Round(a.Score*100) / 100
+	return a
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoreScrape(t *testing.T) {
+	srv := newTestServer("")
+	key := "anomaly-test"
+	normal := scrapeSample{Rows: 40, Columns: 12, Classes: 40, Bytes: 50000}
+	for i := 0; i < anomalyMinHistory; i++ {
+		if a := srv.anomalies.score(key, normal); a != nil {
+			t.Fatalf("sample %d: unexpected anomaly %+v", i, a)
+		}
+	}
+
+	if a := srv.anomalies.score(key, scrapeSample{Rows: 41, Columns: 12, Classes: 41, Bytes: 51000}); a != nil {
+		t.Errorf("small change flagged: %+v", a)
+	}
+
+	a := srv.anomalies.score(key, scrapeSample{Rows: 0, Columns: 0, Classes: 0, Bytes: 2000})
+	if a == nil {
+		t.Fatal("empty page was not flagged")
+	}
+	if a.Score < 1 || len(a.Reasons) == 0 {
+		t.Errorf("anomaly = %+v", a)
+	}
+
+	if a := srv.anomalies.score(key, scrapeSample{Rows: 40, Columns: 11, Classes: 40, Bytes: 50000}); a == nil {
+		t.Error("column change was not flagged")
+	}
+}
+
+func TestScoreScrape_AcceptsStableChange(t *testing.T) {
+	srv := newTestServer("")
+	key := "anomaly-stable"
+	for i := 0; i < anomalyMinHistory; i++ {
+		srv.anomalies.score(key, scrapeSample{Rows: 10, Columns: 12, Classes: 10, Bytes: 10000})
+	}
+	grown := scrapeSample{Rows: 30, Columns: 12, Classes: 30, Bytes: 30000}
+	for i := 0; i < anomalyAcceptAfter-1; i++ {
+		if srv.anomalies.score(key, grown) == nil {
+			t.Fatalf("scrape %d: expected anomaly before the change is accepted", i)
+		}
+	}
+	if a := srv.anomalies.score(key, grown); a != nil {
+		t.Errorf("stable change not accepted: %+v", a)
+	}
+	if a := srv.anomalies.score(key, grown); a != nil {
+		t.Errorf("accepted baseline still flagged: %+v", a)
+	}
+}
+
+func TestSampleScrape(t *testing.T) {
+	doc := docFromHTML(testScheduleHTML)
+	classes := parseClasses(doc)
+	s := sampleScrape(doc, classes, 1234)
+	if s.Rows == 0 || s.Columns < 10 || s.Classes != len(classes) || s.Bytes != 1234 {
+		t.Errorf("sample = %+v", s)
+	}
+}
+
+func TestUpdateSchedule_AnomalyKeepsLastGood(t *testing.T) {
+	srv := newTestServer("")
+	key := "anomaly-last-good"
+	good := []CourseClass{{Code: "IF2211"}}
+	srv.updateSchedule(key, "123", "1945-1", good, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", nil, time.Now(), &Anomaly{Score: 1, Reasons: []string{"rows"}})
+
+	snap, ok := srv.lastGood.get(key)
+	if !ok || len(snap.Classes) != 1 || snap.Classes[0].Code != "IF2211" {
+		t.Errorf("last good = %+v, %v", snap, ok)
+	}
+	entry, ok := srv.cache.peek(key)
+	if !ok || entry.anomaly == nil {
+		t.Error("anomaly should be kept on the cache entry")
+	}
+}
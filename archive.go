@@ -0,0 +1,225 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Catalog archives move the catalog cache between instances so a new
+// deployment starts warm. An archive is a .tar.gz with catalog.json and
+// catalog.json.sig, an HMAC-SHA256 of catalog.json keyed with
+// SIX_ARCHIVE_SECRET. Both instances must share the secret.
+var (
+	archiveSecret = envString("SIX_ARCHIVE_SECRET", "")
+	archiveMaxMB  = envInt("SIX_ARCHIVE_MAX_MB", 64)
+)
+
+const (
+	archiveVersion       = 1
+	archiveCatalogFile   = "catalog.json"
+	archiveSignatureFile = "catalog.json.sig"
+)
+
+type CatalogArchive struct {
+	Version       int                   `json:"version"`
+	SchemaVersion int                   `json:"schema_version"` // see classSchemaVersion
+	CreatedAt     time.Time             `json:"created_at"`
+	Entries       []CatalogArchiveEntry `json:"entries"`
+}
+
+type CatalogArchiveEntry struct {
+	Key       string        `json:"key"` // see catalogKey
+	FetchedAt time.Time     `json:"fetched_at"`
+	Classes   []CourseClass `json:"classes"`
+}
+
+// Result of an import.
+type CatalogImport struct {
+	Imported int `json:"imported"`
+	Expired  int `json:"expired"` // entries older than the catalog TTL, skipped
+}
+
+func signArchive(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns every unexpired catalog page.
+func (c *scheduleCache) archiveEntries() []CatalogArchiveEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	entries := []CatalogArchiveEntry{}
+	for key, e := range c.entries {
+		if now.Before(e.expiresAt) {
+			entries = append(entries, CatalogArchiveEntry{Key: key, FetchedAt: e.fetchedAt, Classes: e.data})
+		}
+	}
+	return entries
+}
+
+// Stores entry under key, expiring it one jittered TTL after it was fetched
+// rather than after now. Returns false if it has already expired.
+func (c *scheduleCache) restore(key string, entry cacheEntry) bool {
+	entry.expiresAt = entry.fetchedAt.Add(jitterTTL(c.ttl, cacheJitter, rand.Float64()))
+	if !time.Now().Before(entry.expiresAt) {
+		return false
+	}
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+	if c.onStore != nil {
+		c.onStore(key, entry)
+	}
+	return true
+}
+
+// Writes the catalog cache to w as a signed archive.
+func writeCatalogArchive(w io.Writer, secret string, entries []CatalogArchiveEntry) error {
+	data, err := json.Marshal(CatalogArchive{Version: archiveVersion, SchemaVersion: classSchemaVersion, CreatedAt: time.Now(), Entries: entries})
+	if err != nil {
+		return err
+	}
+	sig := []byte(signArchive(secret, data))
+
+	return writeTarGz(w, []tarFile{{archiveCatalogFile, data}, {archiveSignatureFile, sig}})
+}
+
+type tarFile struct {
+	name string
+	data []byte
+}
+
+// Writes files to w as a .tar.gz.
+func writeTarGz(w io.Writer, files []tarFile) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), ModTime: now}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Reads a signed archive and verifies its signature.
+func readCatalogArchive(r io.Reader, secret string) (CatalogArchive, error) {
+	var archive CatalogArchive
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return archive, fmt.Errorf("not a gzip file: %w", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return archive, fmt.Errorf("not a tar archive: %w", err)
+		}
+		if h.Name != archiveCatalogFile && h.Name != archiveSignatureFile {
+			continue
+		}
+		if files[h.Name], err = io.ReadAll(tr); err != nil {
+			return archive, err
+		}
+	}
+
+	data, sig := files[archiveCatalogFile], files[archiveSignatureFile]
+	if data == nil || sig == nil {
+		return archive, fmt.Errorf("missing %s or %s", archiveCatalogFile, archiveSignatureFile)
+	}
+	if !hmac.Equal([]byte(signArchive(secret, data)), bytes.TrimSpace(sig)) {
+		return archive, errArchiveSignature
+	}
+	err = unmarshalVersioned(data, &archive, func(blob map[string]any) []any {
+		return nestedLists(blob, "entries", "classes")
+	})
+	if err != nil {
+		return archive, err
+	}
+	if archive.Version != archiveVersion {
+		return archive, fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+	return archive, nil
+}
+
+var errArchiveSignature = errors.New("archive signature does not match")
+
+// GET /api/admin/catalog/export
+func (s *Server) exportCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if archiveSecret == "" {
+		writeError(w, r, codeArchiveDisabled)
+		return
+	}
+	entries := s.catalog.archiveEntries()
+	var buf bytes.Buffer
+	if err := writeCatalogArchive(&buf, archiveSecret, entries); err != nil {
+		log.Printf("catalog export failed: %v", err)
+		writeInternalError(w, r)
+		return
+	}
+	log.Printf("catalog exported entries=%d bytes=%d", len(entries), buf.Len())
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalog-%s.tar.gz"`, time.Now().In(wib).Format("20060102-150405")))
+	w.Write(buf.Bytes())
+}
+
+// POST /api/admin/catalog/import with an archive from exportCatalogHandler as
+// the body.
+func (s *Server) importCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if archiveSecret == "" {
+		writeError(w, r, codeArchiveDisabled)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, int64(archiveMaxMB)<<20)
+	archive, err := readCatalogArchive(body, archiveSecret)
+	if errors.Is(err, errArchiveSignature) {
+		writeError(w, r, codeArchiveSignature)
+		return
+	}
+	if err != nil {
+		writeError(w, r, codeInvalidArchive, err.Error())
+		return
+	}
+
+	var result CatalogImport
+	for _, e := range archive.Entries {
+		sortClasses(e.Classes)
+		if s.catalog.restore(e.Key, cacheEntry{data: e.Classes, fetchedAt: e.FetchedAt}) {
+			result.Imported++
+		} else {
+			result.Expired++
+		}
+	}
+	log.Printf("catalog imported entries=%d expired=%d created_at=%s", result.Imported, result.Expired, archive.CreatedAt)
+	writeSuccess(w, result)
+}
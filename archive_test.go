@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupArchives(t *testing.T) {
+	t.Helper()
+	oldToken, oldSecret := adminToken, archiveSecret
+	adminToken, archiveSecret = "secret", "shared"
+	t.Cleanup(func() { adminToken, archiveSecret = oldToken, oldSecret })
+}
+
+func adminRequest(srv *Server, method, path string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestCatalogArchive_RoundTrip(t *testing.T) {
+	setupArchives(t)
+	src := newTestServer("")
+	src.catalog.set("1945-1?prodi=135", []CourseClass{{Code: "IF2211", ClassNo: "01"}}, time.Now())
+	src.catalog.set("1945-1?prodi=182", []CourseClass{{Code: "II2230", ClassNo: "01"}}, time.Now().Add(-2*time.Hour))
+
+	w := adminRequest(src, "GET", "/api/admin/catalog/export", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("export: got status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	archive := w.Body.Bytes()
+
+	dst := newTestServer("")
+	w = adminRequest(dst, "POST", "/api/admin/catalog/import", archive)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import: got status %d: %s", w.Code, w.Body)
+	}
+	// The entry fetched two hours ago is exported (the source set it just
+	// now) but has outlived the catalog TTL by the time it is imported.
+	if got := decodeData[CatalogImport](t, w); got.Imported != 1 || got.Expired != 1 {
+		t.Errorf("import result = %+v, want 1 imported and 1 expired", got)
+	}
+	if e, ok := dst.catalog.get("1945-1?prodi=135"); !ok || e.data[0].Code != "IF2211" {
+		t.Errorf("imported entry = %+v, %v", e, ok)
+	}
+}
+
+func TestCatalogArchive_RejectsBadSignature(t *testing.T) {
+	setupArchives(t)
+	var buf bytes.Buffer
+	if err := writeCatalogArchive(&buf, "other secret", []CatalogArchiveEntry{{Key: "1945-1?prodi=135"}}); err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer("")
+	if w := adminRequest(srv, "POST", "/api/admin/catalog/import", buf.Bytes()); w.Code != http.StatusBadRequest {
+		t.Errorf("bad signature: got status %d, want 400", w.Code)
+	}
+	if w := adminRequest(srv, "POST", "/api/admin/catalog/import", []byte("not an archive")); w.Code != http.StatusBadRequest {
+		t.Errorf("garbage: got status %d, want 400", w.Code)
+	}
+	if _, ok := srv.catalog.peek("1945-1?prodi=135"); ok {
+		t.Error("a rejected archive must not be imported")
+	}
+
+	archiveSecret = ""
+	if w := adminRequest(srv, "GET", "/api/admin/catalog/export", nil); w.Code != http.StatusForbidden {
+		t.Errorf("no secret: got status %d, want 403", w.Code)
+	}
+}
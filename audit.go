@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// With Config.AuditLog (SIX_AUDIT_LOG) set, every /api/ request is appended
+// to that file as a line of JSON: when it came, its route, path and query,
+// status, and duration. Headers and bodies are not logged, so the file holds
+// no credentials, and student IDs in the URL are pseudonymized as in the
+// logs (see redactText). "six-scraper-go replay" plays the log back.
+
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`   // path and query, redacted
+	Route      string    `json:"route"` // the pattern that matched, e.g. "GET /api/schedule"
+	Status     int       `json:"status"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+type auditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	out io.Closer
+}
+
+// Opens the audit log at path for appending. An empty path returns nil, which
+// logs nothing.
+func openAuditLog(path string) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	return &auditLog{enc: enc, out: f}, nil
+}
+
+func (a *auditLog) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a == nil || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		a.write(AuditEntry{
+			Time:       start,
+			RequestID:  requestIDFrom(r.Context()),
+			Method:     r.Method,
+			URL:        redactText(r.URL.RequestURI()),
+			Route:      r.Pattern,
+			Status:     sw.status,
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		})
+	})
+}
+
+func (a *auditLog) write(e AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.enc.Encode(e); err != nil {
+		log.Printf("audit log write failed: %v", err)
+	}
+}
+
+func (a *auditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.out.Close()
+}
+
+// Reads an audit log, skipping lines that are not entries.
+func readAuditLog(r io.Reader) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil || e.Method == "" || e.URL == "" {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
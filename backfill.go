@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+// A backfill walks back from a semester, scraping and storing one last-good
+// snapshot per semester, so a student's history is available without waiting
+// for it to be requested semester by semester.
+var (
+	backfillMaxSemesters = envInt("SIX_BACKFILL_MAX_SEMESTERS", 16)
+	// Stop after this many empty semesters in a row, which usually means
+	// the walk has gone back past the student's first semester.
+	backfillMaxEmpty = envInt("SIX_BACKFILL_MAX_EMPTY", 2)
+)
+
+type BackfillSemester struct {
+	Semester string `json:"semester"`
+	Classes  int    `json:"classes"`
+	// Skipped is set when a snapshot already existed and force was off.
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BackfillJob struct {
+	ID         string             `json:"id"`
+	StudentID  string             `json:"student_id"`
+	From       string             `json:"from"`
+	Semesters  int                `json:"semesters"`
+	Force      bool               `json:"force"`
+	Status     string             `json:"status"` // running, done, or failed
+	Error      string             `json:"error,omitempty"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Results    []BackfillSemester `json:"results"`
+}
+
+// Backfill jobs started through the admin API, kept in memory.
+type backfillJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*BackfillJob
+}
+
+func newBackfillJobs() *backfillJobs {
+	return &backfillJobs{jobs: make(map[string]*BackfillJob)}
+}
+
+func (b *backfillJobs) add(job *BackfillJob) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.jobs[job.ID] = job
+}
+
+// Returns a copy of the job with the given ID.
+func (b *backfillJobs) get(id string) (BackfillJob, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	job, ok := b.jobs[id]
+	if !ok {
+		return BackfillJob{}, false
+	}
+	copied := *job
+	copied.Results = slices.Clone(job.Results)
+	return copied, true
+}
+
+// Applies update to the job under the lock.
+func (b *backfillJobs) update(id string, update func(*BackfillJob)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if job, ok := b.jobs[id]; ok {
+		update(job)
+	}
+}
+
+// Scrapes count semesters of studentID's schedule, from back to older ones,
+// with the SIX credentials in auth. Each successful scrape goes through
+// updateSchedule, so it is cached and stored as the last-good snapshot.
+// Semesters that already have a snapshot are skipped unless force is set.
+// progress is called after every semester. It stops early on SIX maintenance
+// and after backfillMaxEmpty empty semesters in a row.
+func (s *Server) backfill(ctx context.Context, auth http.Header, studentID string, from scraper.Semester, count int, force bool, progress func(BackfillSemester)) error {
+	ctx = withPriority(ctx, priorityBatch)
+	empty := 0
+	for i := range count {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if scrapingPaused() {
+			return errUpstreamMaintenance
+		}
+
+		semester := from.Add(-i).String()
+		key := schedulePath(studentID, semester, nil)
+		if !force {
+			if snap, ok := s.lastGood.get(key); ok {
+				progress(BackfillSemester{Semester: semester, Classes: len(snap.Classes), Skipped: true})
+				empty = 0
+				continue
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", "/api/admin/backfill", nil)
+		if err != nil {
+			return err
+		}
+		req.Header = auth.Clone()
+		classes, _, err := s.scrapeSchedule(req, studentID, semester, nil)
+		if err != nil {
+			log.Printf("backfill failed student_id=%s semester=%s err=%v", studentID, semester, err)
+			progress(BackfillSemester{Semester: semester, Error: err.Error()})
+			if errors.Is(err, errUpstreamMaintenance) {
+				return err
+			}
+			var missing *missingCookieError
+			if errors.As(err, &missing) {
+				return err
+			}
+			continue
+		}
+		progress(BackfillSemester{Semester: semester, Classes: len(classes)})
+
+		if len(classes) == 0 {
+			empty++
+			if empty >= backfillMaxEmpty {
+				log.Printf("backfill stopping student_id=%s semester=%s: %d empty semesters", studentID, semester, empty)
+				return nil
+			}
+		} else {
+			empty = 0
+		}
+	}
+	return nil
+}
+
+type backfillRequest struct {
+	StudentID string `json:"student_id"`
+	From      string `json:"from"`
+	Semesters int    `json:"semesters"`
+	Force     bool   `json:"force"`
+}
+
+// Starts a backfill job with the SIX credentials of the request. The job runs
+// in the background; poll GET /api/admin/backfill/{id} for progress.
+func (s *Server) startBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var body backfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if body.From == "" {
+		body.From = "current"
+	}
+	if body.Semesters == 0 {
+		body.Semesters = backfillMaxSemesters
+	}
+	if body.Semesters > backfillMaxSemesters {
+		writeError(w, r, codeInvalidRequest, fmt.Sprintf("semesters must be at most %d", backfillMaxSemesters))
+		return
+	}
+	// Fail fast on missing credentials rather than in the background.
+	if err := forwardCookies(&http.Request{Header: http.Header{}}, r); err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+
+	resolved, _ := s.semesters.resolve(body.StudentID, body.From, time.Now())
+	from, _ := scraper.ParseSemester(resolved)
+	job := &BackfillJob{
+		ID:        randomHex(8),
+		StudentID: body.StudentID,
+		From:      from.String(),
+		Semesters: body.Semesters,
+		Force:     body.Force,
+		Status:    "running",
+		StartedAt: time.Now(),
+		Results:   []BackfillSemester{},
+	}
+	s.backfills.add(job)
+	log.Printf("backfill started id=%s student_id=%s from=%s semesters=%d", job.ID, job.StudentID, job.From, job.Semesters)
+
+	auth := sixAuthHeaders(r)
+	go func() {
+		err := s.backfill(context.Background(), auth, job.StudentID, from, job.Semesters, job.Force, func(res BackfillSemester) {
+			s.backfills.update(job.ID, func(j *BackfillJob) { j.Results = append(j.Results, res) })
+		})
+		s.backfills.update(job.ID, func(j *BackfillJob) {
+			now := time.Now()
+			j.FinishedAt = &now
+			j.Status = "done"
+			if err != nil {
+				j.Status, j.Error = "failed", err.Error()
+			}
+		})
+		log.Printf("backfill finished id=%s err=%v", job.ID, err)
+	}()
+
+	created, _ := s.backfills.get(job.ID)
+	writeCreated(w, created)
+}
+
+func (s *Server) getBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	job, ok := s.backfills.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, r, codeJobNotFound)
+		return
+	}
+	writeSuccess(w, job)
+}
+
+// Runs "six-scraper-go backfill", which backfills one student from the
+// command line and prints a line per semester. It returns the exit code.
+func runBackfillCommand(args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	studentID := fs.String("student-id", "", "student ID (NIM) to backfill")
+	fromFlag := fs.String("from", "current", "newest semester to scrape, e.g. 2025-2, or current, previous, or next")
+	count := fs.Int("semesters", backfillMaxSemesters, "number of semesters to walk back through")
+	force := fs.Bool("force", false, "scrape semesters that already have a snapshot")
+	cookies := fs.String("cookies", os.Getenv("SIX_COOKIES"), `SIX Cookie header, e.g. "nissin=...; khongguan=..." (default $SIX_COOKIES)`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !studentIDParamRe.MatchString(*studentID) || *cookies == "" || *count < 1 {
+		fmt.Fprintln(os.Stderr, "backfill: -student-id, -cookies, and a positive -semesters are required")
+		fs.Usage()
+		return 2
+	}
+
+	cfg := configFromEnv()
+	transport, err := upstreamTransport()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+		return 2
+	}
+	cfg.Transport = transport
+	srv := NewServer(cfg)
+	resolved, _ := srv.semesters.resolve(*studentID, *fromFlag, time.Now())
+	from, err := scraper.ParseSemester(resolved)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+		return 2
+	}
+	if cfg.DataDir == "" {
+		fmt.Fprintln(os.Stderr, "backfill: SIX_DATA_DIR is not set, snapshots will not be kept")
+	}
+
+	auth := http.Header{"Cookie": {*cookies}}
+	err = srv.backfill(context.Background(), auth, *studentID, from, *count, *force, func(res BackfillSemester) {
+		switch {
+		case res.Error != "":
+			fmt.Printf("%s\terror: %s\n", res.Semester, res.Error)
+		case res.Skipped:
+			fmt.Printf("%s\t%d classes (already stored)\n", res.Semester, res.Classes)
+		default:
+			fmt.Printf("%s\t%d classes\n", res.Semester, res.Classes)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Returns the headers of r that carry SIX credentials: Cookie and X-Six-*.
+func sixAuthHeaders(r *http.Request) http.Header {
+	auth := http.Header{}
+	for name, values := range r.Header {
+		if name == "Cookie" || strings.HasPrefix(name, "X-Six-") {
+			auth[name] = values
+		}
+	}
+	return auth
+}
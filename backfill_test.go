@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+// Serves a schedule for the given semesters and an empty page for the rest,
+// counting schedule fetches.
+func mockSIXHistory(t *testing.T, semesters ...string) (*httptest.Server, *int) {
+	t.Helper()
+	hits := 0
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		for _, sem := range semesters {
+			if strings.Contains(r.URL.Path, "+"+sem+"/") {
+				fmt.Fprint(w, testScheduleHTML)
+				return
+			}
+		}
+		fmt.Fprint(w, `<html><body><table class="table"><tbody></tbody></table></body></html>`)
+	}))
+	t.Cleanup(mock.Close)
+	return mock, &hits
+}
+
+func TestBackfill_WalksBackAndStopsWhenEmpty(t *testing.T) {
+	mock, hits := mockSIXHistory(t, "1945-2", "1945-1")
+	srv := newTestServer(mock.URL)
+	auth := http.Header{"Cookie": {"nissin=a; khongguan=b"}}
+
+	var got []string
+	progress := func(res BackfillSemester) {
+		got = append(got, fmt.Sprintf("%s:%d:%v", res.Semester, res.Classes, res.Skipped))
+	}
+	from := scraper.Semester{Year: 1945, Term: 2}
+	if err := srv.backfill(t.Context(), auth, "123", from, 8, false, progress); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1945-2:2:false", "1945-1:2:false", "1944-2:0:false", "1944-1:0:false"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if _, ok := srv.lastGood.get(schedulePath("123", "1945-1", nil)); !ok {
+		t.Error("expected a last-good snapshot for 1945-1")
+	}
+
+	// A second run skips semesters that already have a snapshot.
+	got, *hits = nil, 0
+	if err := srv.backfill(t.Context(), auth, "123", from, 2, false, progress); err != nil {
+		t.Fatal(err)
+	}
+	if *hits != 0 || got[0] != "1945-2:2:true" {
+		t.Errorf("second run: hits=%d results=%v, want everything skipped", *hits, got)
+	}
+}
+
+func TestBackfillHandler(t *testing.T) {
+	mock, _ := mockSIXHistory(t, "1945-1")
+	srv := newTestServer(mock.URL)
+	old := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = old })
+
+	do := func(method, path, body string, cookies bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		if cookies {
+			addAuthCookies(req)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/api/admin/backfill", `{"student_id":"123","from":"1945-1"}`, false); w.Code != http.StatusBadGateway {
+		t.Errorf("no cookies: got status %d, want 502", w.Code)
+	}
+	if w := do("POST", "/api/admin/backfill", `{"student_id":"123","semesters":1000}`, true); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("too many semesters: got status %d, want 422", w.Code)
+	}
+
+	w := do("POST", "/api/admin/backfill", `{"student_id":"123","from":"1945-1","semesters":3}`, true)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	job := decodeData[BackfillJob](t, w)
+	if job.Status != "running" || job.From != "1945-1" {
+		t.Errorf("job = %+v", job)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == "running" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		job = decodeData[BackfillJob](t, do("GET", "/api/admin/backfill/"+job.ID, "", false))
+	}
+	if job.Status != "done" || len(job.Results) != 3 || job.Results[0].Classes != 2 {
+		t.Errorf("finished job = %+v", job)
+	}
+
+	if w := do("GET", "/api/admin/backfill/nope", "", false); w.Code != http.StatusNotFound {
+		t.Errorf("unknown job: got status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GET /api/bundle packages everything a mobile app needs to show a student's
+// semester offline into one .tar.gz: manifest.json describing the other
+// files, profile.json, schedule.json, calendar.json with every dated meeting
+// of SIX_BUNDLE_WEEKS weeks from the current one, and rooms.json locating the
+// rooms.
+//
+// A bundle is identified by a hash of what it is built from, so it is only
+// rebuilt when the schedule changes or a new week starts. The hash is the
+// bundle's strong ETag, and the last SIX_BUNDLE_KEEP bundles stay available
+// at /api/bundle/{hash}.
+var (
+	bundleWeeks = envInt("SIX_BUNDLE_WEEKS", 8)
+	bundleKeep  = envInt("SIX_BUNDLE_KEEP", 50)
+)
+
+// Version of the bundle layout. Files are only ever added; anything else gets
+// a new version.
+const bundleVersion = 1
+
+type BundleManifest struct {
+	Hash          string       `json:"hash"` // see bundleHash
+	Version       int          `json:"version"`
+	ParserVersion int          `json:"parser_version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	FetchedAt     time.Time    `json:"fetched_at"`  // when the schedule was fetched from SIX
+	ValidUntil    time.Time    `json:"valid_until"` // end of the last week in calendar.json
+	StudentID     string       `json:"student_id"`
+	Semester      string       `json:"semester"`
+	Files         []BundleFile `json:"files"`
+}
+
+type BundleFile struct {
+	Name   string `json:"name"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+type BundleProfile struct {
+	StudentID string `json:"student_id"`
+	Semester  string `json:"semester"`
+	Classes   int    `json:"classes"`
+	SKS       int    `json:"sks"`
+}
+
+// A room of the schedule, with its building if SIX_BUILDINGS_FILE locates it.
+type BundleRoom struct {
+	Room     string   `json:"room"`
+	Building string   `json:"building,omitempty"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+}
+
+// Returns every meeting of classes in the weeks weeks from monday.
+func bundleCalendar(classes []CourseClass, monday time.Time, weeks int) []UpcomingSession {
+	grid := scheduleGrid(classes, nil)
+	out := []UpcomingSession{}
+	for offset := range weeks * 7 {
+		out = append(out, sessionsOn(grid, monday.AddDate(0, 0, offset))...)
+	}
+	return out
+}
+
+// Returns the Monday of the week of now, a WIB midnight.
+func bundleWeekStart(now time.Time) time.Time {
+	today := wibDay(now)
+	return today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+}
+
+// Identifies the bundle built from classes in the week starting monday.
+// Bundles with the same hash have the same contents apart from when they
+// were created.
+func bundleHash(studentID, semester string, classes []CourseClass, monday time.Time) string {
+	content, _ := classesHash(classes)
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d\n%d\n%d\n%s\n%s\n%s\n%s",
+		bundleVersion, scheduleShadow.servedVersion(), bundleWeeks, studentID, semester, monday.Format(time.DateOnly), content))
+	return hex.EncodeToString(sum[:16])
+}
+
+func bundleRooms(classes []CourseClass, buildings []Building) []BundleRoom {
+	var rooms []string
+	for _, c := range classes {
+		for _, m := range c.Schedules {
+			if room := strings.TrimSpace(m.Room); room != "" {
+				rooms = append(rooms, room)
+			}
+		}
+	}
+	slices.Sort(rooms)
+	out := []BundleRoom{}
+	for _, room := range slices.Compact(rooms) {
+		r := BundleRoom{Room: room}
+		if b, ok := locateRoom(buildings, room); ok {
+			r.Building, r.Lat, r.Lon = b.Name, &b.Lat, &b.Lon
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Builds the bundle files, manifest first.
+func buildBundle(hash, studentID, semester string, classes []CourseClass, buildings []Building, fetchedAt, now time.Time) ([]tarFile, error) {
+	if classes == nil {
+		classes = []CourseClass{}
+	}
+	profile := BundleProfile{StudentID: studentID, Semester: semester, Classes: len(classes)}
+	for _, c := range classes {
+		profile.SKS += c.SKS
+	}
+	monday := bundleWeekStart(now)
+	contents := []struct {
+		name string
+		v    any
+	}{
+		{"profile.json", profile},
+		{"schedule.json", classes},
+		{"calendar.json", bundleCalendar(classes, monday, bundleWeeks)},
+		{"rooms.json", bundleRooms(classes, buildings)},
+	}
+
+	manifest := BundleManifest{
+		Hash:          hash,
+		Version:       bundleVersion,
+		ParserVersion: scheduleShadow.servedVersion(),
+		CreatedAt:     now,
+		FetchedAt:     fetchedAt,
+		ValidUntil:    monday.AddDate(0, 0, bundleWeeks*7),
+		StudentID:     studentID,
+		Semester:      semester,
+	}
+	files := []tarFile{{name: "manifest.json"}}
+	for _, c := range contents {
+		data, err := json.MarshalIndent(c.v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.name, err)
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, BundleFile{Name: c.name, Bytes: len(data), SHA256: hex.EncodeToString(sum[:])})
+		files = append(files, tarFile{c.name, data})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[0].data = data
+	return files, nil
+}
+
+// A built bundle.
+type storedBundle struct {
+	hash      string
+	studentID string
+	semester  string
+	data      []byte
+}
+
+// The most recently built bundles, addressable by hash. The oldest is dropped
+// once there are more than keep.
+type bundleStore struct {
+	mu      sync.Mutex
+	keep    int
+	bundles map[string]*storedBundle
+	order   []string // hashes, oldest first
+}
+
+func newBundleStore(keep int) *bundleStore {
+	return &bundleStore{keep: max(keep, 1), bundles: make(map[string]*storedBundle)}
+}
+
+func (st *bundleStore) get(hash string) (*storedBundle, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	b, ok := st.bundles[hash]
+	return b, ok
+}
+
+func (st *bundleStore) put(b *storedBundle) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.bundles[b.hash]; ok {
+		return
+	}
+	st.bundles[b.hash] = b
+	st.order = append(st.order, b.hash)
+	for len(st.order) > st.keep {
+		delete(st.bundles, st.order[0])
+		st.order = st.order[1:]
+	}
+}
+
+// GET /api/bundle
+//
+// Serves the bundle for the current schedule, building it only if no stored
+// bundle has the same hash.
+func (s *Server) bundleHandler(w http.ResponseWriter, r *http.Request) {
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester := cmp.Or(meta.Semester, query.Get("semester"))
+	now := time.Now()
+	hash := bundleHash(studentID, semester, classes, bundleWeekStart(now))
+	b, ok := s.bundles.get(hash)
+	if !ok {
+		files, err := buildBundle(hash, studentID, semester, classes, s.buildings, meta.FetchedAt, now)
+		var buf bytes.Buffer
+		if err == nil {
+			err = writeTarGz(&buf, files)
+		}
+		if err != nil {
+			log.Printf("bundle failed student_id=%s semester=%s err=%v", studentID, semester, err)
+			writeInternalError(w, r)
+			return
+		}
+		b = &storedBundle{hash: hash, studentID: studentID, semester: semester, data: buf.Bytes()}
+		s.bundles.put(b)
+		log.Printf("bundle built student_id=%s semester=%s hash=%s bytes=%d", studentID, semester, hash, len(b.data))
+	}
+	// private: the bundle belongs to the student whose cookies fetched it.
+	writeBundle(w, r, b, "private, no-cache")
+}
+
+// GET /api/bundle/{hash}
+//
+// Serves a stored bundle. student_id has to match the bundle's, so a hash
+// alone does not give away another student's bundle.
+func (s *Server) storedBundleHandler(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.bundles.get(r.PathValue("hash"))
+	if !ok || b.studentID != r.URL.Query().Get("student_id") {
+		writeError(w, r, codeBundleNotFound)
+		return
+	}
+	writeBundle(w, r, b, "private, max-age=31536000, immutable")
+}
+
+func writeBundle(w http.ResponseWriter, r *http.Request, b *storedBundle, cacheControl string) {
+	etag := `"` + b.hash + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="bundle-%s-%s.tar.gz"`, b.studentID, b.semester))
+	w.Write(b.data)
+}
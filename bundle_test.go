@@ -0,0 +1,141 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleCalendar(t *testing.T) {
+	classes := []CourseClass{{Code: "IF2211", Schedules: []ScheduleEntry{
+		{Day: "Senin", Time: "07:00-09:00", Room: "7602"},
+		{Day: "Rabu", Time: "09:00-11:00", Room: "9009"},
+	}}}
+	monday := time.Date(2025, 2, 3, 0, 0, 0, 0, wib)
+	sessions := bundleCalendar(classes, monday, 2)
+	if len(sessions) != 4 {
+		t.Fatalf("got %d sessions, want 4", len(sessions))
+	}
+	if want := time.Date(2025, 2, 12, 9, 0, 0, 0, wib); !sessions[3].StartsAt.Equal(want) {
+		t.Errorf("last session starts %v, want %v", sessions[3].StartsAt, want)
+	}
+
+	rooms := bundleRooms(append(classes, classes...), []Building{{Name: "Labtek V", Prefixes: []string{"76"}, Lat: -6.89, Lon: 107.61}})
+	if len(rooms) != 2 || rooms[0].Room != "7602" || rooms[0].Building != "Labtek V" || rooms[1].Lat != nil {
+		t.Errorf("rooms = %+v", rooms)
+	}
+}
+
+func TestBundleHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		addAuthCookies(req)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	w := get("/api/bundle?student_id=123&semester=1945-1", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("got status %d, content type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+
+	body, etag := w.Body.Bytes(), w.Header().Get("ETag")
+
+	// Unchanged schedule: the same bundle, or 304 for a client that has it.
+	if again := get("/api/bundle?student_id=123&semester=1945-1", ""); !bytes.Equal(again.Body.Bytes(), body) || again.Header().Get("ETag") != etag {
+		t.Error("bundle was rebuilt although nothing changed")
+	}
+	if w := get("/api/bundle?student_id=123&semester=1945-1", etag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", w.Code)
+	}
+	hash := strings.Trim(etag, `"`)
+	if w := get("/api/bundle/"+hash+"?student_id=123", ""); w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), body) {
+		t.Errorf("stored bundle: status %d", w.Code)
+	}
+	if w := get("/api/bundle/"+hash+"?student_id=456", ""); w.Code != http.StatusNotFound {
+		t.Errorf("another student's bundle: status %d, want 404", w.Code)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name], _ = io.ReadAll(tr)
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Hash != hash || manifest.Version != bundleVersion || manifest.StudentID != "123" || manifest.Semester != "1945-1" || len(manifest.Files) != 4 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	for _, f := range manifest.Files {
+		sum := sha256.Sum256(files[f.Name])
+		if hex.EncodeToString(sum[:]) != f.SHA256 || len(files[f.Name]) != f.Bytes {
+			t.Errorf("%s does not match its manifest entry", f.Name)
+		}
+	}
+	var classes []CourseClass
+	if err := json.Unmarshal(files["schedule.json"], &classes); err != nil || len(classes) == 0 {
+		t.Errorf("schedule.json: %v, %d classes", err, len(classes))
+	}
+}
+
+func TestBundleHash(t *testing.T) {
+	classes := []CourseClass{{Code: "IF2211", ClassNo: "01"}}
+	monday := bundleWeekStart(time.Date(2025, 2, 6, 12, 0, 0, 0, wib))
+	if want := time.Date(2025, 2, 3, 0, 0, 0, 0, wib); !monday.Equal(want) {
+		t.Fatalf("week start = %v, want %v", monday, want)
+	}
+	h := bundleHash("123", "2024-2", classes, monday)
+	if bundleHash("123", "2024-2", classes, bundleWeekStart(time.Date(2025, 2, 9, 23, 0, 0, 0, wib))) != h {
+		t.Error("hash changed within the week")
+	}
+	if bundleHash("123", "2024-2", classes, monday.AddDate(0, 0, 7)) == h {
+		t.Error("hash unchanged in a new week")
+	}
+	if bundleHash("123", "2024-2", []CourseClass{{Code: "IF2211", ClassNo: "02"}}, monday) == h {
+		t.Error("hash unchanged for a changed schedule")
+	}
+}
+
+func TestBundleStore_KeepsLastN(t *testing.T) {
+	st := newBundleStore(2)
+	for _, hash := range []string{"a", "b", "a", "c"} {
+		st.put(&storedBundle{hash: hash})
+	}
+	for hash, want := range map[string]bool{"a": false, "b": true, "c": true} {
+		if _, ok := st.get(hash); ok != want {
+			t.Errorf("bundle %s kept = %v, want %v", hash, ok, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Fraction by which each entry's TTL is randomly shortened or lengthened, so
+// entries cached in the same burst do not all expire, and get fetched again,
+// at the same moment.
+var cacheJitter = min(max(envFloat("SIX_CACHE_JITTER", 0.15), 0), 0.5)
+
+// Returns ttl moved by up to jitter of itself in either direction. r is a
+// random number in [0, 1).
+func jitterTTL(ttl time.Duration, jitter, r float64) time.Duration {
+	return time.Duration(float64(ttl) * (1 + jitter*(2*r-1)))
+}
+
+// A cached value with when it was fetched and when it expires.
+type ttlEntry[T any] struct {
+	data      T
+	fetchedAt time.Time
+	expiresAt time.Time
+	anomaly   *Anomaly // set on a scraped schedule that looked wrong
+}
+
+type cacheEntry = ttlEntry[[]CourseClass]
+
+// In-memory cache whose entries expire after a jittered TTL.
+type ttlCache[T any] struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]ttlEntry[T]
+	onStore func(key string, entry ttlEntry[T]) // called after an entry is stored, if set
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{ttl: ttl, entries: make(map[string]ttlEntry[T])}
+}
+
+// In-memory schedule cache keyed by schedulePath.
+type scheduleCache struct {
+	ttlCache[[]CourseClass]
+}
+
+func newScheduleCache(ttl time.Duration) *scheduleCache {
+	return &scheduleCache{ttlCache[[]CourseClass]{ttl: ttl, entries: make(map[string]cacheEntry)}}
+}
+
+func (c *ttlCache[T]) get(key string) (ttlEntry[T], bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return ttlEntry[T]{}, false
+	}
+	return entry, true
+}
+
+// Returns the entry for key even if it has expired.
+func (c *ttlCache[T]) peek(key string) (ttlEntry[T], bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *ttlCache[T]) set(key string, data T, fetchedAt time.Time) {
+	c.put(key, ttlEntry[T]{data: data, fetchedAt: fetchedAt})
+}
+
+// Stores entry under key, setting its expiry from the jittered cache TTL.
+func (c *ttlCache[T]) put(key string, entry ttlEntry[T]) {
+	c.putUntil(key, entry, time.Now().Add(jitterTTL(c.ttl, cacheJitter, rand.Float64())))
+}
+
+// Stores entry under key until expiresAt instead of for the cache TTL.
+func (c *ttlCache[T]) putUntil(key string, entry ttlEntry[T], expiresAt time.Time) {
+	entry.expiresAt = expiresAt
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+	if c.onStore != nil {
+		c.onStore(key, entry)
+	}
+}
+
+// Returns the most entries due to expire within any one minute from now.
+func (c *ttlCache[T]) expiryPeak(now time.Time) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	perMinute := make(map[int64]int)
+	peak := 0
+	for _, e := range c.entries {
+		if e.expiresAt.After(now) {
+			m := int64(e.expiresAt.Sub(now) / time.Minute)
+			perMinute[m]++
+			peak = max(peak, perMinute[m])
+		}
+	}
+	return peak
+}
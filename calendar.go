@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"log"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+	"six-scraper-go/textnorm"
+)
+
+// SIX page with ITB's academic calendar. It is the same for every student.
+const calendarPath = "/app/kalender-akademik"
+
+// One event of the academic calendar.
+type CalendarEntry struct {
+	Title string `json:"title"` // as SIX shows it
+	// Type is one of the calendarTypes, or "other".
+	Type string `json:"type"`
+	// Start and End are YYYY-MM-DD, the same for a one-day event. If SIX
+	// shows something other than a date, Start is that text and End is
+	// empty.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Semester is the semester the event belongs to, from the heading of its
+	// table or else its start date, or empty if neither tells.
+	Semester string `json:"semester"`
+}
+
+// Types of calendar events, with words of their titles in textnorm.Key
+// form. The first type one of whose words is in a title is the event's.
+// Exams come before semester ends, since "Ujian Akhir Semester" is an exam.
+var calendarTypes = []struct {
+	typ   string
+	words []string
+}{
+	{"exam", []string{"ujian", "uts", "uas"}},
+	{"holiday", []string{"libur", "cuti bersama", "hari raya"}},
+	{"registration", []string{"frs", "registrasi", "pendaftaran", "perwalian", "pembayaran"}},
+	{"semester_start", []string{"awal perkuliahan", "awal kuliah", "mulai kuliah", "perkuliahan dimulai", "awal semester"}},
+	{"semester_end", []string{"akhir perkuliahan", "akhir kuliah", "perkuliahan berakhir", "akhir semester"}},
+	{"grades", []string{"nilai"}},
+}
+
+var calendarHeaderWords = headerWords{
+	{"tanggal", []string{"tanggal", "waktu", "pelaksanaan", "jadwal"}},
+	{"kegiatan", []string{"kegiatan", "acara", "agenda", "keterangan", "uraian"}},
+}
+
+// Parses the academic calendar page: tables with an event and a date
+// column, found by their header words as on the exam page, each usually
+// under a heading such as "Semester I 2025/2026".
+func parseCalendar(doc *goquery.Document) []CalendarEntry {
+	var entries []CalendarEntry
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := wordColumns(table, calendarHeaderWords)
+		date, hasDate := cols["tanggal"]
+		title, hasTitle := cols["kegiatan"]
+		if !hasDate || !hasTitle {
+			return
+		}
+		semester := headingSemester(scraper.TableHeading(table))
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			e := CalendarEntry{Title: scraper.CellText(cells, title), Semester: semester}
+			if e.Title == "" || e.Title == scraper.CellText(cells, date) {
+				continue
+			}
+			e.Start, e.End = calendarDates(scraper.CellText(cells, date))
+			e.Type = calendarType(e.Title)
+			if start, err := time.Parse(time.DateOnly, e.Start); err == nil && e.Semester == "" {
+				e.Semester = scraper.CalendarSemester(start).String()
+			}
+			entries = append(entries, e)
+		}
+	})
+	return entries
+}
+
+func calendarType(title string) string {
+	key := textnorm.Key(title)
+	for _, t := range calendarTypes {
+		for _, w := range t.words {
+			if strings.Contains(key, w) {
+				return t.typ
+			}
+		}
+	}
+	return "other"
+}
+
+var (
+	academicYearRe = regexp.MustCompile(`(\d{4})\s*/\s*\d{4}`)
+	semesterTermRe = regexp.MustCompile(`\b(?:semester\s+(i{1,2}|[12])|(ganjil|genap|pendek))\b`)
+)
+
+// Returns the semester a heading such as "Semester I 2025/2026",
+// "Semester Pendek Tahun Akademik 2025/2026", or "2025/2026 Genap" names,
+// or "".
+func headingSemester(heading string) string {
+	key := textnorm.Key(heading)
+	year := academicYearRe.FindStringSubmatch(key)
+	term := semesterTermRe.FindStringSubmatch(key)
+	if year == nil || term == nil {
+		return ""
+	}
+	y, _ := strconv.Atoi(year[1])
+	t := map[string]int{"i": 1, "1": 1, "ganjil": 1, "ii": 2, "2": 2, "genap": 2, "pendek": 3}[term[1]+term[2]]
+	return scraper.Semester{Year: y, Term: t}.String()
+}
+
+// Separators of the two dates of a range.
+var dateRangeRe = regexp.MustCompile(`\s+(?:-|–|s\.?/?d\.?|sampai|hingga)\s+`)
+
+// Returns the first and last day of a date or range as SIX writes them,
+// e.g. "12 Agustus 2025", "12 - 16 Agustus 2025", "28 Agustus - 5
+// September 2025", or "2025-08-12 s.d. 2025-08-16", as YYYY-MM-DD. The
+// first date may leave out the month and year it shares with the last.
+// Returns text and "" if it is not a date.
+func calendarDates(text string) (start, end string) {
+	first, last, isRange := text, text, false
+	if loc := dateRangeRe.FindStringIndex(text); loc != nil {
+		first, last, isRange = text[:loc[0]], text[loc[1]:], true
+	}
+	end = examDate(strings.TrimSpace(last))
+	endDay, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return text, ""
+	}
+	if !isRange {
+		return end, end
+	}
+	start = examDate(strings.TrimSpace(first))
+	if _, err := time.Parse(time.DateOnly, start); err != nil {
+		// Borrow what the first date leaves out from the last.
+		fields := strings.Fields(first)
+		if _, after, ok := strings.Cut(first, ","); ok {
+			fields = strings.Fields(after)
+		}
+		lastFields := strings.Fields(last)
+		if _, after, ok := strings.Cut(last, ","); ok {
+			lastFields = strings.Fields(after)
+		}
+		if len(fields) == 0 || len(fields) > 2 || len(lastFields) != 3 {
+			return text, ""
+		}
+		start = examDate(strings.Join(append(fields, lastFields[len(fields):]...), " "))
+		startDay, err := time.Parse(time.DateOnly, start)
+		if err != nil {
+			return text, ""
+		}
+		// "28 Desember - 3 Januari 2026" starts the year before.
+		if startDay.After(endDay) {
+			start = startDay.AddDate(-1, 0, 0).Format(time.DateOnly)
+		}
+	}
+	return start, end
+}
+
+// Semester ends and FRS periods learned from the academic calendar, each
+// time it is fetched. Watcher expiry (expiry.go) and the catalog warmer's
+// FRS cadence (warmer.go) go by them unless SIX_SEMESTER_ENDS or
+// SIX_FRS_PERIODS say otherwise. The catalog warmer refetches the calendar
+// every calendarRefresh with its service account.
+type academicCalendar struct {
+	mu        sync.RWMutex
+	ends      map[scraper.Semester]time.Time // as in semesterEnds
+	frs       []dateRange
+	checkedAt time.Time // when the warmer last fetched it, or tried to
+}
+
+const calendarRefresh = 24 * time.Hour
+
+// Event types after whose last one a semester is over: lectures, then exams,
+// then grading.
+var semesterEndTypes = []string{"semester_end", "exam", "grades"}
+
+// Words, in textnorm.Key form, of the titles of FRS events.
+var frsWords = []string{"frs", "rencana studi", "perwalian"}
+
+func newAcademicCalendar() *academicCalendar {
+	return &academicCalendar{ends: make(map[scraper.Semester]time.Time)}
+}
+
+// Learns the semester ends and FRS periods of entries, the whole calendar,
+// in place of what it learned before. A semester with an end of lectures
+// ends the day after its last lecture, exam, or grading event, whichever
+// comes last. Events without dates are skipped,
+// and an empty calendar, as a changed page layout gives, is ignored.
+func (c *academicCalendar) learn(entries []CalendarEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	ends := make(map[scraper.Semester]time.Time)
+	ended := make(map[scraper.Semester]bool) // semesters with an end of lectures
+	var frs []dateRange
+	for _, e := range entries {
+		start, err1 := time.ParseInLocation(time.DateOnly, e.Start, wib)
+		end, err2 := time.ParseInLocation(time.DateOnly, e.End, wib)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		end = end.AddDate(0, 0, 1)
+		if sem, err := scraper.ParseSemester(e.Semester); err == nil && slices.Contains(semesterEndTypes, e.Type) {
+			ended[sem] = ended[sem] || e.Type == "semester_end"
+			if end.After(ends[sem]) {
+				ends[sem] = end
+			}
+		}
+		key := textnorm.Key(e.Title)
+		if slices.ContainsFunc(frsWords, func(w string) bool { return strings.Contains(key, w) }) {
+			frs = append(frs, dateRange{start: start, end: end})
+		}
+	}
+	// A midterm alone does not tell when the semester is over.
+	maps.DeleteFunc(ends, func(sem scraper.Semester, _ time.Time) bool { return !ended[sem] })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ends, c.frs = ends, frs
+}
+
+// Returns when sem ends according to the calendar, if it says.
+func (c *academicCalendar) semesterEnd(sem scraper.Semester) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	end, ok := c.ends[sem]
+	return end, ok
+}
+
+func (c *academicCalendar) frsPeriods() []dateRange {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.frs
+}
+
+// Reports whether the calendar is due to be fetched again at now, and if so
+// notes the attempt, so a failing fetch is not retried before
+// calendarRefresh either.
+func (c *academicCalendar) due(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && now.Sub(c.checkedAt) < calendarRefresh {
+		return false
+	}
+	c.checkedAt = now
+	return true
+}
+
+// Fetches the academic calendar as the credentials in auth and learns its
+// dates, if it is due.
+func (s *Server) refreshAcademicCalendar(ctx context.Context, auth http.Header, now time.Time) {
+	if !s.academic.due(now) {
+		return
+	}
+	req, err := http.NewRequestWithContext(withPriority(ctx, priorityBatch), "GET", "/api/calendar", nil)
+	if err != nil {
+		return
+	}
+	req.Header = auth.Clone()
+	entries, err := s.provider.FetchCalendar(req)
+	if err != nil {
+		log.Printf("academic calendar refresh failed err=%v", err)
+		return
+	}
+	s.academic.learn(entries)
+}
+
+// GET /api/calendar?semester=...
+func (s *Server) calendarHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	entries, err := s.provider.FetchCalendar(r)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	s.academic.learn(entries)
+	meta := &Meta{FetchedAt: time.Now()}
+	if semester := r.URL.Query().Get("semester"); semester != "" {
+		resolved, relative := s.semesters.resolve("", semester, time.Now())
+		kept := []CalendarEntry{}
+		for _, e := range entries {
+			if e.Semester == resolved {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+		if relative {
+			meta.Semester = resolved
+		}
+	}
+	if entries == nil {
+		entries = []CalendarEntry{}
+	}
+	log.Printf("parsed calendar entries=%d", len(entries))
+	writeSuccessWithMeta(w, entries, meta)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+const testCalendarHTML = `<html><body>
+<h4>Kalender Akademik Semester I 2025/2026</h4>
+<table class="table">
+  <thead><tr><th>No</th><th>Kegiatan</th><th>Tanggal Pelaksanaan</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>Pengisian FRS</td><td>4 - 15 Agustus 2025</td></tr>
+    <tr><td>2</td><td>Awal Perkuliahan</td><td>Senin, 18 Agustus 2025</td></tr>
+    <tr><td>3</td><td>Ujian Tengah Semester</td><td>6 Oktober 2025 s.d. 10 Oktober 2025</td></tr>
+    <tr><td>4</td><td>Libur Akhir Tahun</td><td>22 Desember - 2 Januari 2026</td></tr>
+    <tr><td>5</td><td>Ujian Akhir Semester</td><td>akan diumumkan</td></tr>
+  </tbody>
+</table>
+<h4>Semester II 2025/2026</h4>
+<table class="table">
+  <thead><tr><th>Kegiatan</th><th>Waktu</th></tr></thead>
+  <tbody>
+    <tr><td>Akhir Perkuliahan</td><td>2026-05-29</td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+func TestParseCalendar(t *testing.T) {
+	got := parseCalendar(docFromHTML(testCalendarHTML))
+	want := []CalendarEntry{
+		{Title: "Pengisian FRS", Type: "registration", Start: "2025-08-04", End: "2025-08-15", Semester: "2025-1"},
+		{Title: "Awal Perkuliahan", Type: "semester_start", Start: "2025-08-18", End: "2025-08-18", Semester: "2025-1"},
+		{Title: "Ujian Tengah Semester", Type: "exam", Start: "2025-10-06", End: "2025-10-10", Semester: "2025-1"},
+		{Title: "Libur Akhir Tahun", Type: "holiday", Start: "2025-12-22", End: "2026-01-02", Semester: "2025-1"},
+		{Title: "Ujian Akhir Semester", Type: "exam", Start: "akan diumumkan", Semester: "2025-1"},
+		{Title: "Akhir Perkuliahan", Type: "semester_end", Start: "2026-05-29", End: "2026-05-29", Semester: "2025-2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCalendarDates(t *testing.T) {
+	for text, want := range map[string][2]string{
+		"12 Agustus 2025":               {"2025-08-12", "2025-08-12"},
+		"28 Agustus - 5 September 2025": {"2025-08-28", "2025-09-05"},
+		"2025-08-12 s/d 2025-08-16":     {"2025-08-12", "2025-08-16"},
+		"Senin, 1 - Jumat, 5 Juni 2026": {"2026-06-01", "2026-06-05"},
+		"12 - 16":                       {"12 - 16", ""},
+		"minggu ke-8":                   {"minggu ke-8", ""},
+	} {
+		if start, end := calendarDates(text); start != want[0] || end != want[1] {
+			t.Errorf("%q: got %q, %q, want %q", text, start, end, want)
+		}
+	}
+}
+
+func TestHeadingSemester(t *testing.T) {
+	for heading, want := range map[string]string{
+		"Semester I 2025/2026":                      "2025-1",
+		"Kalender Semester Genap Tahun 2025 / 2026": "2025-2",
+		"Semester Pendek 2025/2026":                 "2025-3",
+		"2025/2026 Genap":                           "2025-2",
+		"Kalender Akademik":                         "",
+	} {
+		if got := headingSemester(heading); got != want {
+			t.Errorf("%q: got %q, want %q", heading, got, want)
+		}
+	}
+}
+
+func TestCalendarHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != calendarPath {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, testCalendarHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func(target string) []CalendarEntry {
+		req := httptest.NewRequest("GET", target, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d: %s", target, w.Code, w.Body)
+		}
+		return decodeData[[]CalendarEntry](t, w)
+	}
+	if entries := get("/api/calendar"); len(entries) != 6 {
+		t.Errorf("all: %d entries", len(entries))
+	}
+	if entries := get("/api/calendar?semester=2025-2"); len(entries) != 1 || entries[0].Type != "semester_end" {
+		t.Errorf("2025-2: %+v", entries)
+	}
+	// The fetched calendar's dates are learned.
+	if end, ok := srv.academic.semesterEnd(scraper.Semester{Year: 2025, Term: 2}); !ok || end.Format(time.DateOnly) != "2026-05-30" {
+		t.Errorf("2025-2 ends %s, %v", end, ok)
+	}
+	if periods := srv.academic.frsPeriods(); len(periods) != 1 || !inFRS(periods, time.Date(2025, 8, 15, 12, 0, 0, 0, wib)) {
+		t.Errorf("FRS periods = %+v", periods)
+	}
+}
+
+func TestAcademicCalendar_Learn(t *testing.T) {
+	c := newAcademicCalendar()
+	c.learn([]CalendarEntry{
+		{Title: "Pengisian FRS", Type: "registration", Start: "2026-01-05", End: "2026-01-16", Semester: "2025-2"},
+		{Title: "Pembayaran UKT", Type: "registration", Start: "2026-01-01", End: "2026-01-10", Semester: "2025-2"},
+		{Title: "Akhir Perkuliahan", Type: "semester_end", Start: "2025-12-05", End: "2025-12-05", Semester: "2025-1"},
+		{Title: "Ujian Akhir Semester", Type: "exam", Start: "2025-12-08", End: "2025-12-19", Semester: "2025-1"},
+		{Title: "Batas Pengisian Nilai", Type: "grades", Start: "akan diumumkan", Semester: "2025-1"},
+		{Title: "Ujian Tengah Semester", Type: "exam", Start: "2026-03-09", End: "2026-03-13", Semester: "2025-2"},
+	})
+	if end, ok := c.semesterEnd(scraper.Semester{Year: 2025, Term: 1}); !ok || end.Format(time.DateOnly) != "2025-12-20" {
+		t.Errorf("2025-1 ends %s, %v; want the day after its final exams", end, ok)
+	}
+	if end, ok := c.semesterEnd(scraper.Semester{Year: 2025, Term: 2}); ok {
+		t.Errorf("2025-2 ends %s; a midterm is not its end", end)
+	}
+	if periods := c.frsPeriods(); len(periods) != 1 || periods[0].end.Format(time.DateOnly) != "2026-01-17" {
+		t.Errorf("FRS periods = %+v", periods)
+	}
+
+	c.learn(nil)
+	if len(c.frsPeriods()) != 1 {
+		t.Error("an empty calendar replaced the learned one")
+	}
+}
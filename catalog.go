@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// A schedule page filtered by fakultas or prodi lists the classes offered to
+// that faculty or program, which are the same whichever student fetches it.
+// Those pages are the course catalog: they are also cached under a key
+// without the student ID, so one student's fetch serves everyone, and they
+// can be shared with peer instances.
+
+// Returns the catalog cache key for a schedule query, and false if the query
+// is not a catalog page.
+func catalogKey(semester string, query url.Values) (string, bool) {
+	filters := scheduleFilters(query)
+	if filters.Get("fakultas") == "" && filters.Get("prodi") == "" {
+		return "", false
+	}
+	return semester + "?" + filters.Encode(), true
+}
+
+// GET /api/peer/catalog?semester=...&fakultas=...
+//
+// Serves a catalog page to peer instances from the catalog cache only. It
+// never contacts SIX and needs no SIX cookies.
+func (s *Server) peerCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	key, ok := catalogKey(query.Get("semester"), query)
+	if !ok {
+		writeError(w, r, codeInvalidRequest, "fakultas or prodi is required")
+		return
+	}
+	entry, ok := s.catalog.get(key)
+	if !ok {
+		writeError(w, r, codeCatalogNotCached)
+		return
+	}
+	writeSuccessWithMeta(w, entry.data, &Meta{FetchedAt: entry.fetchedAt, Cached: true})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Besides its url, a subscription can notify through channels: more
+// webhooks, Telegram chats, or email addresses. Each channel may render
+// events with its own template, and keeps its own delivery status and
+// retries, so one failing channel does not hold back the others.
+//
+// Telegram messages are sent by the bot of SIX_TELEGRAM_BOT_TOKEN, and email
+// through the SMTP server at SIX_SMTP_ADDR. Channels of a kind that is not
+// configured are rejected.
+var (
+	telegramBotToken = envString("SIX_TELEGRAM_BOT_TOKEN", "")
+	telegramAPIURL   = envString("SIX_TELEGRAM_API_URL", "https://api.telegram.org")
+	smtpAddr         = envString("SIX_SMTP_ADDR", "")
+	smtpFrom         = envString("SIX_SMTP_FROM", "")
+	smtpUsername     = envString("SIX_SMTP_USERNAME", "")
+	smtpPassword     = envString("SIX_SMTP_PASSWORD", "")
+)
+
+const (
+	channelWebhook  = "webhook"
+	channelTelegram = "telegram"
+	channelEmail    = "email"
+
+	maxChannels = 5
+	// Longest template source a channel accepts, in bytes.
+	maxChannelTemplate = 4 << 10
+)
+
+// Sends an email; replaced in tests.
+var sendMail = smtp.SendMail
+
+type Channel struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`   // webhook, telegram, or email
+	Target string `json:"target"` // URL, Telegram chat ID, or email address
+	// Template is Go text/template source executed with the WebhookEvent,
+	// using the functions of operator templates. Without one, webhooks get
+	// the event as JSON and other channels a plain summary.
+	Template     string          `json:"template,omitempty"`
+	LastDelivery *DeliveryStatus `json:"last_delivery,omitempty"`
+	Errors       []DeliveryError `json:"errors,omitempty"` // most recent failed attempts, oldest first
+
+	tmpl *template.Template
+}
+
+type channelRequest struct {
+	Type     string `json:"type"`
+	Target   string `json:"target"`
+	Template string `json:"template"`
+}
+
+// Validates requested channels and builds them with fresh IDs.
+func parseChannels(reqs []channelRequest) ([]*Channel, error) {
+	if len(reqs) > maxChannels {
+		return nil, fmt.Errorf("at most %d channels are allowed", maxChannels)
+	}
+	channels := make([]*Channel, 0, len(reqs))
+	for i, req := range reqs {
+		ch := &Channel{ID: randomHex(4), Type: req.Type, Target: strings.TrimSpace(req.Target), Template: req.Template}
+		switch ch.Type {
+		case channelWebhook:
+			if !validWebhookURL(ch.Target) {
+				return nil, fmt.Errorf("channels[%d]: target must be an absolute http or https URL", i)
+			}
+		case channelTelegram:
+			if telegramBotToken == "" {
+				return nil, fmt.Errorf("channels[%d]: telegram is not configured", i)
+			}
+			if ch.Target == "" || strings.ContainsAny(ch.Target, " /?#") {
+				return nil, fmt.Errorf("channels[%d]: target must be a Telegram chat ID", i)
+			}
+		case channelEmail:
+			if smtpAddr == "" {
+				return nil, fmt.Errorf("channels[%d]: email is not configured", i)
+			}
+			addr, err := mail.ParseAddress(ch.Target)
+			if err != nil || addr.Name != "" {
+				return nil, fmt.Errorf("channels[%d]: target must be an email address", i)
+			}
+		default:
+			return nil, fmt.Errorf("channels[%d]: type must be webhook, telegram, or email", i)
+		}
+		if ch.Template != "" {
+			if len(ch.Template) > maxChannelTemplate {
+				return nil, fmt.Errorf("channels[%d]: template is longer than %d bytes", i, maxChannelTemplate)
+			}
+			tmpl, err := template.New(ch.ID).Funcs(templateFuncs).Parse(ch.Template)
+			if err != nil {
+				return nil, fmt.Errorf("channels[%d]: %v", i, err)
+			}
+			ch.tmpl = tmpl
+		}
+		channels = append(channels, ch)
+	}
+	return channels, nil
+}
+
+// Returns a copy of ch whose status is not shared with the live channel.
+func (ch *Channel) view() *Channel {
+	v := *ch
+	v.Errors = slices.Clone(ch.Errors)
+	if ch.LastDelivery != nil {
+		last := *ch.LastDelivery
+		v.LastDelivery = &last
+	}
+	return &v
+}
+
+// Returns event rendered with the channel's template, or ok false if it has
+// none.
+func (ch *Channel) render(event WebhookEvent) (text string, ok bool, err error) {
+	if ch.tmpl == nil {
+		return "", false, nil
+	}
+	buf := &cappedBuffer{n: templateMaxKB << 10}
+	if err := ch.tmpl.Execute(buf, event); err != nil {
+		return "", true, fmt.Errorf("template: %w", err)
+	}
+	return buf.String(), true, nil
+}
+
+// One line about the event, e.g. "Schedule 2026-1 of 13523001 changed".
+func eventSubject(event WebhookEvent) string {
+	switch event.Type {
+	case "schedule.summary":
+		return fmt.Sprintf("Schedule %s of %s at the deadline", event.Semester, event.StudentID)
+	case "subscription.expiring":
+		return fmt.Sprintf("Notifications for schedule %s of %s end %s WIB", event.Semester, event.StudentID, event.ExpiresAt.In(wib).Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("Schedule %s of %s changed", event.Semester, event.StudentID)
+}
+
+// The plain summary of an event sent to channels without a template.
+func eventText(event WebhookEvent) string {
+	if event.Type == "subscription.expiring" {
+		return eventSubject(event) + "\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d classes", eventSubject(event), len(event.Classes))
+	if len(event.Removed) > 0 {
+		fmt.Fprintf(&b, ", %d removed", len(event.Removed))
+	}
+	b.WriteString("\n")
+	for _, c := range event.Classes {
+		fmt.Fprintf(&b, "\n%s-%s %s", c.Code, c.ClassNo, c.Name)
+	}
+	for _, c := range event.Removed {
+		fmt.Fprintf(&b, "\n(removed) %s-%s %s", c.Code, c.ClassNo, c.Name)
+	}
+	return b.String()
+}
+
+// Delivers event to one channel of sub, with the retries of webhooks.
+func (s *Server) deliverChannel(ctx context.Context, sub Subscription, ch Channel, event WebhookEvent) error {
+	client := &http.Client{Timeout: webhookTimeout}
+	return retryDelivery(ctx, ch.Type, sub.ID, event, func() error {
+		return ch.send(ctx, client, sub, event)
+	}, func(attempt int, final bool, err error) {
+		recordChannelDelivery(sub.ID, ch.ID, event, attempt, final, err)
+		if final && err != nil {
+			s.deadLetters.add(sub.ID, ch.ID, event, attempt, err)
+		}
+	})
+}
+
+// Makes one delivery attempt of event to the channel.
+func (ch Channel) send(ctx context.Context, client *http.Client, sub Subscription, event WebhookEvent) error {
+	text, templated, err := ch.render(event)
+	if err != nil {
+		return err
+	}
+	switch ch.Type {
+	case channelWebhook:
+		if templated {
+			return postWebhook(ctx, client, ch.Target, sub.Secret, event.ID, event.Sequence, "text/plain; charset=utf-8", []byte(text))
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return postWebhook(ctx, client, ch.Target, sub.Secret, event.ID, event.Sequence, "application/json", body)
+	case channelTelegram:
+		if !templated {
+			text = eventText(event)
+		}
+		return sendTelegram(ctx, client, ch.Target, text)
+	case channelEmail:
+		if !templated {
+			text = eventText(event)
+		}
+		return sendEmail(ch.Target, eventSubject(event), text)
+	}
+	return fmt.Errorf("unknown channel type %q", ch.Type)
+}
+
+// Sends text to a Telegram chat through the Bot API.
+func sendTelegram(ctx context.Context, client *http.Client, chatID, text string) error {
+	body, err := json.Marshal(map[string]string{"chat_id": chatID, "text": text})
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(telegramAPIURL, "/") + "/bot" + telegramBotToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// Leave out the URL, which holds the bot token.
+		return fmt.Errorf("telegram request failed: %w", errors.Unwrap(err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("telegram returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Sends a plain text email through SIX_SMTP_ADDR.
+func sendEmail(to, subject, text string) error {
+	var auth smtp.Auth
+	if smtpUsername != "" {
+		host, _, _ := strings.Cut(smtpAddr, ":")
+		auth = smtp.PlainAuth("", smtpUsername, smtpPassword, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", smtpFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	return sendMail(smtpAddr, auth, smtpFrom, []string{to}, []byte(msg.String()))
+}
+
+// Updates the delivery status and error history of a channel, if it and its
+// subscription still exist.
+func recordChannelDelivery(subID, channelID string, event WebhookEvent, attempt int, final bool, err error) {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	sub, ok := subscriptions[subID]
+	if !ok {
+		return
+	}
+	for _, ch := range sub.Channels {
+		if ch.ID == channelID {
+			noteDelivery(&ch.LastDelivery, &ch.Errors, event, attempt, final, err)
+		}
+	}
+}
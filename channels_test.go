@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Configures Telegram and email channels. Telegram messages go to a server
+// failing the first telegramFailures requests; the returned functions list
+// the texts it got and the emails sent.
+func setupChannels(t *testing.T, telegramFailures int) (telegram func() []string, emails func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var texts, mails []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottok/sendMessage" {
+			http.NotFound(w, r)
+			return
+		}
+		var msg struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		defer mu.Unlock()
+		if telegramFailures > 0 {
+			telegramFailures--
+			http.Error(w, "busy", http.StatusTooManyRequests)
+			return
+		}
+		texts = append(texts, msg.ChatID+": "+msg.Text)
+	}))
+	t.Cleanup(api.Close)
+
+	oldToken, oldURL, oldAddr, oldSend := telegramBotToken, telegramAPIURL, smtpAddr, sendMail
+	telegramBotToken, telegramAPIURL, smtpAddr = "tok", api.URL, "smtp.example.com:587"
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		mails = append(mails, strings.Join(to, ",")+"\n"+string(msg))
+		return nil
+	}
+	t.Cleanup(func() {
+		telegramBotToken, telegramAPIURL, smtpAddr, sendMail = oldToken, oldURL, oldAddr, oldSend
+	})
+	list := func(s *[]string) func() []string {
+		return func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), *s...)
+		}
+	}
+	return list(&texts), list(&mails)
+}
+
+func TestParseChannels(t *testing.T) {
+	setupChannels(t, 0)
+	if _, err := parseChannels([]channelRequest{
+		{Type: "webhook", Target: "https://example.com/hook"},
+		{Type: "telegram", Target: "-100123"},
+		{Type: "email", Target: "student@example.com", Template: "{{len .Classes}} classes"},
+	}); err != nil {
+		t.Fatalf("valid channels: %v", err)
+	}
+	for _, ch := range []channelRequest{
+		{Type: "webhook", Target: "nope"},
+		{Type: "telegram", Target: "12/3"},
+		{Type: "email", Target: "Student <student@example.com>"},
+		{Type: "email", Target: "student@example.com", Template: "{{.Nope"},
+		{Type: "sms", Target: "0812"},
+	} {
+		if _, err := parseChannels([]channelRequest{ch}); err == nil {
+			t.Errorf("%+v: want an error", ch)
+		}
+	}
+
+	telegramBotToken = ""
+	if _, err := parseChannels([]channelRequest{{Type: "telegram", Target: "1"}}); err == nil {
+		t.Error("telegram without a bot token: want an error")
+	}
+}
+
+func TestChannels_FanOutWithOwnTemplatesAndRetries(t *testing.T) {
+	setupWebhooks(t)
+	telegram, emails := setupChannels(t, 1)
+	receiver, received := webhookReceiver(t, 0)
+	srv := newWebhookTestServer(t, "")
+
+	body := `{"student_id":"123","semester":"1945-1","channels":[
+		{"type":"webhook","target":"` + receiver.URL + `","template":"{{.Type}} {{range .Classes}}{{.Code}} {{end}}"},
+		{"type":"telegram","target":"42"},
+		{"type":"email","target":"student@example.com"}]}`
+	w, sub := postSubscription(t, body)
+	if w.Code != http.StatusCreated || len(sub.Channels) != 3 {
+		t.Fatalf("create: got status %d: %s", w.Code, w.Body)
+	}
+
+	key := schedulePath("123", "1945-1", nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "A"}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "B", ClassNo: "01", Name: "Basis Data"}}, time.Now(), nil)
+	waitFor(t, func() bool { return len(received()) == 1 && len(telegram()) == 1 && len(emails()) == 1 })
+
+	if got := received()[0]; string(got.body) != "schedule.changed B " || !strings.HasPrefix(got.header.Get("Content-Type"), "text/plain") {
+		t.Errorf("webhook got %q, %s", got.body, got.header.Get("Content-Type"))
+	}
+	if got := telegram()[0]; !strings.HasPrefix(got, "42: Schedule 1945-1 of 123 changed: 1 classes") || !strings.Contains(got, "B-01 Basis Data") {
+		t.Errorf("telegram got %q", got)
+	}
+	if got := emails()[0]; !strings.HasPrefix(got, "student@example.com\n") || !strings.Contains(got, "Subject: Schedule 1945-1 of 123 changed\r\n") {
+		t.Errorf("email got %q", got)
+	}
+
+	// Each channel tracks its own deliveries: only Telegram needed a retry.
+	var attempts []int
+	waitFor(t, func() bool {
+		v := decodeData[Subscription](t, subscriptionRequest(t, "GET", "/api/subscriptions/"+sub.ID, "", ""))
+		attempts = attempts[:0]
+		for _, ch := range v.Channels {
+			if ch.LastDelivery == nil {
+				return false
+			}
+			attempts = append(attempts, ch.LastDelivery.Attempts)
+		}
+		return true
+	})
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[1] != 2 || attempts[2] != 1 {
+		t.Errorf("attempts per channel = %v, want [1 2 1]", attempts)
+	}
+}
+
+func TestChannels_PatchKeepsATarget(t *testing.T) {
+	setupWebhooks(t)
+	setupChannels(t, 0)
+	_, sub := postSubscription(t, testSubscriptionBody)
+
+	if w := subscriptionRequest(t, "PATCH", "/api/subscriptions/"+sub.ID, `{"url":""}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("removing the only target: got status %d, want 400", w.Code)
+	}
+	w := subscriptionRequest(t, "PATCH", "/api/subscriptions/"+sub.ID, `{"url":"","channels":[{"type":"telegram","target":"42"}]}`, "")
+	if got := decodeData[Subscription](t, w); w.Code != http.StatusOK || got.URL != "" || len(got.Channels) != 1 {
+		t.Errorf("got status %d: %s", w.Code, w.Body)
+	}
+	if w := subscriptionRequest(t, "POST", "/api/subscriptions", `{"student_id":"123","semester":"1945-1","channels":[{"type":"telegram","target":"a/b"}]}`, ""); w.Code != http.StatusUnprocessableEntity {
+		body, _ := io.ReadAll(w.Body)
+		t.Errorf("invalid channel: got status %d: %s", w.Code, body)
+	}
+}
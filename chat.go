@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"six-scraper-go/textnorm"
+)
+
+// Chatbots on LINE and WhatsApp answer simple questions such as today's
+// schedule or a class's quota. The provider's webhook delivers messages to
+// /api/chat/webhook/{provider}, and the provider's ChatAdapter verifies and parses
+// them and sends the answer in the provider's message format.
+//
+// A chat account is linked to a student in two steps: the student calls
+// POST /api/chat/links with their SIX cookies to get a short code, then
+// sends "link <code>" to the bot. The cookies are kept, like those of an
+// organization member, under a consent the student can revoke.
+var (
+	chatLinkCodeTTL = envDuration("SIX_CHAT_LINK_CODE_TTL", 10*time.Minute)
+	chatMaxLinks    = envInt("SIX_CHAT_MAX_LINKS", 1000)
+)
+
+// A ChatAdapter connects a chat provider's webhooks to the bot.
+type ChatAdapter interface {
+	Name() string
+	// Reports whether a webhook delivery was signed by the provider.
+	Verify(r *http.Request, body []byte) bool
+	// Returns the text messages in a webhook delivery.
+	Messages(body []byte) ([]ChatMessage, error)
+	// Sends text in reply to msg.
+	Reply(ctx context.Context, msg ChatMessage, text string) error
+}
+
+type ChatMessage struct {
+	UserID     string // the sender's ID at the provider
+	Text       string
+	ReplyToken string // for providers that reply by token rather than to the user
+}
+
+// Returns the adapters of the providers configured through the environment,
+// keyed by name.
+func newChatAdapters() map[string]ChatAdapter {
+	adapters := make(map[string]ChatAdapter)
+	if a := newLINEAdapter(); a != nil {
+		adapters[a.Name()] = a
+	}
+	if a := newWhatsAppAdapter(); a != nil {
+		adapters[a.Name()] = a
+	}
+	return adapters
+}
+
+// A code from POST /api/chat/links, waiting to be sent to the bot.
+type ChatLinkCode struct {
+	Code      string    `json:"code"`
+	LinkID    string    `json:"link_id"`
+	StudentID string    `json:"student_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ConsentID string    `json:"consent_id"`
+
+	auth http.Header // Cookie and X-Six-* headers of the creating request
+}
+
+// A chat account linked to a student.
+type chatLink struct {
+	id        string
+	studentID string
+	consentID string
+	auth      http.Header
+}
+
+type chatLinks struct {
+	mu    sync.Mutex
+	codes map[string]*ChatLinkCode // by code
+	links map[string]*chatLink     // by chatUserKey
+}
+
+func newChatLinks() *chatLinks {
+	return &chatLinks{codes: make(map[string]*ChatLinkCode), links: make(map[string]*chatLink)}
+}
+
+func chatUserKey(provider, userID string) string {
+	return provider + ":" + userID
+}
+
+// Adds a code unless chatMaxLinks codes and links are already stored.
+func (c *chatLinks) add(lc *ChatLinkCode) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.codes)+len(c.links) >= chatMaxLinks {
+		return false
+	}
+	c.codes[lc.Code] = lc
+	return true
+}
+
+// Removes the code or link with the given link ID.
+func (c *chatLinks) drop(linkID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for code, lc := range c.codes {
+		if lc.LinkID == linkID {
+			delete(c.codes, code)
+		}
+	}
+	for key, l := range c.links {
+		if l.id == linkID {
+			delete(c.links, key)
+		}
+	}
+}
+
+// Turns an unexpired code into a link for the chat user, returning the new
+// link and the one it replaced, if any.
+func (c *chatLinks) redeem(code, userKey string, now time.Time) (link, old *chatLink, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lc, ok := c.codes[strings.ToUpper(code)]
+	if !ok || now.After(lc.ExpiresAt) {
+		return nil, nil, false
+	}
+	delete(c.codes, lc.Code)
+	link = &chatLink{id: lc.LinkID, studentID: lc.StudentID, consentID: lc.ConsentID, auth: lc.auth}
+	old = c.links[userKey]
+	c.links[userKey] = link
+	return link, old, true
+}
+
+// Removes expired codes and returns their consent IDs.
+func (c *chatLinks) sweep(now time.Time) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var consents []string
+	for code, lc := range c.codes {
+		if now.After(lc.ExpiresAt) {
+			delete(c.codes, code)
+			consents = append(consents, lc.ConsentID)
+		}
+	}
+	return consents
+}
+
+func (c *chatLinks) get(userKey string) (*chatLink, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.links[userKey]
+	return l, ok
+}
+
+// POST /api/chat/links
+//
+// Creates a link code for the caller's SIX session. The student ID comes
+// from the session, so a chat account only ever sees its own schedule.
+func (s *Server) createChatLink(w http.ResponseWriter, r *http.Request) {
+	if len(s.chat) == 0 {
+		writeError(w, r, codeChatDisabled)
+		return
+	}
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	home, err := s.provider.FetchHomePage(r)
+	release()
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	now := time.Now()
+	s.semesters.learn(home.StudentID, home.Semester, now)
+	// Unused codes hold cookies too, so they do not wait for the consent
+	// sweeper.
+	for _, id := range s.chatLinks.sweep(now) {
+		s.consents.end(id, revokedExpiry)
+	}
+
+	lc := &ChatLinkCode{
+		Code:      strings.ToUpper(randomHex(4)),
+		LinkID:    randomHex(8),
+		StudentID: home.StudentID,
+		ExpiresAt: now.Add(chatLinkCodeTTL),
+		auth:      sixAuthHeaders(r),
+	}
+	consent := s.consents.grant(r, scopeChatLink, lc.StudentID, lc.LinkID, func() { s.chatLinks.drop(lc.LinkID) })
+	lc.ConsentID = consent.ID
+	if !s.chatLinks.add(lc) {
+		s.consents.end(consent.ID, revokedDelete)
+		writeError(w, r, codeServerBusy)
+		return
+	}
+	log.Printf("chat link code created link=%s", lc.LinkID)
+	writeCreated(w, lc)
+}
+
+// Largest webhook delivery read from a provider.
+const maxChatBody = 1 << 20
+
+// POST /api/chat/webhook/{provider}
+//
+// Receives a provider's webhook. Messages are answered in the background
+// because providers expect a quick 200.
+func (s *Server) chatWebhook(w http.ResponseWriter, r *http.Request) {
+	adapter, ok := s.chat[r.PathValue("provider")]
+	if !ok {
+		writeError(w, r, codeChatDisabled)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxChatBody))
+	if err != nil {
+		writeError(w, r, codeUnreadableBody)
+		return
+	}
+	if !adapter.Verify(r, body) {
+		writeError(w, r, codeChatSignature)
+		return
+	}
+	messages, err := adapter.Messages(body)
+	if err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	for _, msg := range messages {
+		go s.answerChat(adapter, msg)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) answerChat(adapter ChatAdapter, msg ChatMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	text := s.chatReply(ctx, chatUserKey(adapter.Name(), msg.UserID), msg.Text, time.Now())
+	if err := adapter.Reply(ctx, msg, text); err != nil {
+		log.Printf("chat reply failed provider=%s err=%v", adapter.Name(), err)
+	}
+}
+
+// Intents a chat message can have, with their keywords as textnorm keys and
+// the language each keyword implies. The first intent with a keyword in the
+// message wins, so "jadwal besok" is tomorrow's schedule rather than today's.
+var chatIntents = []struct {
+	intent string
+	lang   string
+	words  []string
+}{
+	{"link", "en", []string{"link"}},
+	{"link", "id", []string{"hubungkan", "tautkan"}},
+	{"unlink", "en", []string{"unlink"}},
+	{"unlink", "id", []string{"putuskan"}},
+	{"quota", "en", []string{"quota"}},
+	{"quota", "id", []string{"kuota"}},
+	{"tomorrow", "en", []string{"tomorrow"}},
+	{"tomorrow", "id", []string{"besok"}},
+	{"week", "en", []string{"this week", "week"}},
+	{"week", "id", []string{"minggu ini", "pekan ini"}},
+	{"today", "en", []string{"today", "schedule"}},
+	{"today", "id", []string{"hari ini", "jadwal"}},
+	{"help", "en", []string{"help"}},
+}
+
+// Returns the intent of a chat message, its language, and the words after
+// the keyword, ignoring punctuation. Messages without a keyword get help in
+// Indonesian.
+func parseChatIntent(text string) (intent, lang string, args []string) {
+	words := strings.FieldsFunc(textnorm.Key(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	key := " " + strings.Join(words, " ") + " "
+	for _, in := range chatIntents {
+		for _, word := range in.words {
+			if i := strings.Index(key, " "+word+" "); i >= 0 {
+				return in.intent, in.lang, strings.Fields(key[i+len(word)+1:])
+			}
+		}
+	}
+	return "help", "id", nil
+}
+
+// Chat replies in each language.
+var chatReplies = map[string]map[string]string{
+	"id": {
+		"help":        "Kirim \"jadwal\", \"besok\", atau \"minggu ini\" untuk jadwalmu, atau \"kuota IF2211 01\" untuk kuota kelas. Untuk menghubungkan akun, buat kode lewat POST /api/chat/links lalu kirim \"link KODE\".",
+		"linked":      "Akun terhubung dengan NIM %s.",
+		"bad code":    "Kode tidak dikenal atau sudah kedaluwarsa. Buat kode baru lewat POST /api/chat/links.",
+		"unlinked":    "Akun tidak lagi terhubung dan cookie SIX-mu sudah dihapus.",
+		"not linked":  "Akun belum terhubung. Buat kode lewat POST /api/chat/links lalu kirim \"link KODE\".",
+		"fetch error": "Gagal mengambil jadwal dari SIX. Sesi SIX-mu mungkin sudah habis; buat kode baru dan hubungkan lagi.",
+		"quota usage": "Tulis kode mata kuliah, misalnya \"kuota IF2211 01\".",
+		"no class":    "Kelas %s tidak ditemukan di jadwalmu atau katalog yang tersimpan.",
+		"quota":       "Kuota %s kelas %s: %d",
+	},
+	"en": {
+		"help":        "Send \"today\", \"tomorrow\", or \"week\" for your schedule, or \"quota IF2211 01\" for a class's quota. To link your account, create a code with POST /api/chat/links and send \"link CODE\".",
+		"linked":      "Linked to student ID %s.",
+		"bad code":    "Unknown or expired code. Create a new one with POST /api/chat/links.",
+		"unlinked":    "Your account is no longer linked and your SIX cookies were deleted.",
+		"not linked":  "Your account is not linked yet. Create a code with POST /api/chat/links and send \"link CODE\".",
+		"fetch error": "Could not get your schedule from SIX. Your SIX session may have expired; create a new code and link again.",
+		"quota usage": "Add a course code, e.g. \"quota IF2211 01\".",
+		"no class":    "Class %s was not found in your schedule or the stored catalog.",
+		"quota":       "Quota of %s class %s: %d",
+	},
+}
+
+// Returns the answer to a chat message from userKey.
+func (s *Server) chatReply(ctx context.Context, userKey, text string, now time.Time) string {
+	intent, lang, args := parseChatIntent(text)
+	t := chatReplies[lang]
+
+	switch intent {
+	case "help":
+		return t["help"]
+	case "link":
+		if len(args) == 0 {
+			return t["bad code"]
+		}
+		link, old, ok := s.chatLinks.redeem(args[0], userKey, now)
+		if !ok {
+			return t["bad code"]
+		}
+		if old != nil && old.id != link.id {
+			s.consents.end(old.consentID, revokedDelete)
+		}
+		log.Printf("chat account linked link=%s", link.id)
+		return fmt.Sprintf(t["linked"], link.studentID)
+	}
+
+	link, ok := s.chatLinks.get(userKey)
+	if !ok {
+		return t["not linked"]
+	}
+	if intent == "unlink" {
+		s.chatLinks.drop(link.id)
+		s.consents.end(link.consentID, revokedDelete)
+		return t["unlinked"]
+	}
+
+	semester, _ := s.semesters.resolve(link.studentID, "current", now)
+	classes, err := s.linkedSchedule(ctx, link, semester)
+	if err != nil {
+		log.Printf("chat schedule failed link=%s err=%v", link.id, err)
+		return t["fetch error"]
+	}
+	if intent == "quota" {
+		return s.chatQuota(t, args, classes, semester, now)
+	}
+	return scheduleText(classes, lang, intent, wibDay(now), wibDay(now)).Text
+}
+
+// Returns a linked student's schedule from the cache, or fetches it with
+// their stored credentials.
+func (s *Server) linkedSchedule(ctx context.Context, link *chatLink, semester string) ([]CourseClass, error) {
+	if entry, ok := s.cache.get(schedulePath(link.studentID, semester, nil)); ok {
+		return entry.data, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "/api/chat", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = link.auth.Clone()
+	classes, _, err := s.scrapeSchedule(req, link.studentID, semester, nil)
+	return classes, err
+}
+
+// Answers "kuota IF2211 01" from the student's schedule or, failing that,
+// the cached catalog. Without a class number every class of the course is
+// listed.
+func (s *Server) chatQuota(t map[string]string, args []string, classes []CourseClass, semester string, now time.Time) string {
+	if len(args) == 0 {
+		return t["quota usage"]
+	}
+	code, classNo := strings.ToUpper(args[0]), ""
+	if len(args) > 1 {
+		classNo = strings.TrimLeft(args[1], "0")
+	}
+	match := func(c CourseClass) bool {
+		return strings.EqualFold(c.Code, code) && (classNo == "" || strings.TrimLeft(c.ClassNo, "0") == classNo)
+	}
+
+	var found []string
+	for _, c := range classes {
+		if match(c) {
+			found = append(found, fmt.Sprintf(t["quota"], c.Code, c.ClassNo, c.Quota))
+		}
+	}
+	if len(found) == 0 {
+		for _, hit := range s.search.search(code, semester, 50, now) {
+			if match(hit.CourseClass) {
+				found = append(found, fmt.Sprintf(t["quota"], hit.Code, hit.ClassNo, hit.Quota))
+			}
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Sprintf(t["no class"], strings.ToUpper(strings.Join(args[:min(len(args), 2)], " ")))
+	}
+	return strings.Join(found, "\n")
+}
+
+// Posts payload as JSON to a provider's messaging API.
+func postChatAPI(ctx context.Context, target, token string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := (&http.Client{Timeout: webhookTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChatIntent(t *testing.T) {
+	tests := []struct {
+		text, intent, lang string
+		args               []string
+	}{
+		{"Jadwal hari ini?", "today", "id", []string{}},
+		{"jadwal besok", "tomorrow", "id", []string{}},
+		{"schedule this week", "week", "en", []string{}},
+		{"Kuota IF2211 01", "quota", "id", []string{"if2211", "01"}},
+		{"link ab12cd34", "link", "en", []string{"ab12cd34"}},
+		{"halo", "help", "id", nil},
+	}
+	for _, tt := range tests {
+		intent, lang, args := parseChatIntent(tt.text)
+		if intent != tt.intent || lang != tt.lang || !slices.Equal(args, tt.args) {
+			t.Errorf("parseChatIntent(%q) = %s, %s, %q; want %s, %s, %q", tt.text, intent, lang, args, tt.intent, tt.lang, tt.args)
+		}
+	}
+}
+
+// Records the bodies posted to a provider's messaging API.
+func chatAPI(t *testing.T) (*httptest.Server, chan string) {
+	t.Helper()
+	sent := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent <- r.URL.Path + " " + r.Header.Get("Authorization") + " " + string(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, sent
+}
+
+func waitChatReply(t *testing.T, sent chan string) string {
+	t.Helper()
+	select {
+	case s := <-sent:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply sent")
+		return ""
+	}
+}
+
+func setupLINE(t *testing.T, apiURL string) {
+	t.Helper()
+	oldSecret, oldToken, oldURL := lineChannelSecret, lineAccessToken, lineAPIURL
+	lineChannelSecret, lineAccessToken, lineAPIURL = "line-secret", "line-token", apiURL
+	t.Cleanup(func() { lineChannelSecret, lineAccessToken, lineAPIURL = oldSecret, oldToken, oldURL })
+}
+
+func lineWebhookRequest(srv *Server, secret, userID, text string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{"events": []map[string]any{{
+		"type":       "message",
+		"replyToken": "token-" + text,
+		"source":     map[string]string{"userId": userID},
+		"message":    map[string]string{"type": "text", "text": text},
+	}}})
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	req := httptest.NewRequest("POST", "/api/chat/webhook/line", strings.NewReader(string(body)))
+	req.Header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestChat_Disabled(t *testing.T) {
+	srv := newTestServer("")
+	for _, path := range []string{"/api/chat/webhook/line", "/api/chat/links"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader("{}")))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", path, w.Code)
+		}
+	}
+}
+
+func TestChat_LINELinkQuotaAndUnlink(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	api, sent := chatAPI(t)
+	setupLINE(t, api.URL)
+	srv := newTestServer(mock.URL)
+
+	if w := lineWebhookRequest(srv, "wrong", "U1", "help"); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", w.Code)
+	}
+
+	lineWebhookRequest(srv, "line-secret", "U1", "jadwal")
+	if got := waitChatReply(t, sent); !strings.Contains(got, "Akun belum terhubung") {
+		t.Errorf("unlinked reply = %s", got)
+	}
+
+	req := httptest.NewRequest("POST", "/api/chat/links", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create link: status %d: %s", w.Code, w.Body)
+	}
+	code := decodeData[ChatLinkCode](t, w)
+	if code.StudentID != "13520001" || code.ConsentID == "" {
+		t.Errorf("link code = %+v", code)
+	}
+
+	if w := lineWebhookRequest(srv, "line-secret", "U1", "link "+strings.ToLower(code.Code)); w.Code != http.StatusOK {
+		t.Fatalf("webhook: status %d", w.Code)
+	}
+	got := waitChatReply(t, sent)
+	want := `/v2/bot/message/reply Bearer line-token {"replyToken":"token-link ` + strings.ToLower(code.Code) + `","messages":[{"type":"text","text":"Linked to student ID 13520001."}]}`
+	if got != want {
+		t.Errorf("link reply =\n%s\nwant\n%s", got, want)
+	}
+
+	lineWebhookRequest(srv, "line-secret", "U1", "kuota fi1210")
+	if got := waitChatReply(t, sent); !strings.Contains(got, "Kuota FI1210 kelas 01: 45") {
+		t.Errorf("quota reply = %s", got)
+	}
+	lineWebhookRequest(srv, "line-secret", "U1", "quota FI9999")
+	if got := waitChatReply(t, sent); !strings.Contains(got, "Class FI9999 was not found") {
+		t.Errorf("unknown class reply = %s", got)
+	}
+
+	// The code is used up.
+	lineWebhookRequest(srv, "line-secret", "U2", "link "+code.Code)
+	if got := waitChatReply(t, sent); !strings.Contains(got, "Unknown or expired code") {
+		t.Errorf("reused code reply = %s", got)
+	}
+
+	lineWebhookRequest(srv, "line-secret", "U1", "unlink")
+	if got := waitChatReply(t, sent); !strings.Contains(got, "no longer linked") {
+		t.Errorf("unlink reply = %s", got)
+	}
+	if _, ok := srv.chatLinks.get(chatUserKey("line", "U1")); ok {
+		t.Error("link kept after unlink")
+	}
+	srv.consents.mu.Lock()
+	reason := srv.consents.consents[code.ConsentID].Reason
+	srv.consents.mu.Unlock()
+	if reason != revokedDelete {
+		t.Errorf("consent reason = %q, want %q", reason, revokedDelete)
+	}
+}
+
+func TestChat_RevokingConsentUnlinks(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	api, _ := chatAPI(t)
+	setupLINE(t, api.URL)
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("POST", "/api/chat/links", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	code := decodeData[ChatLinkCode](t, w)
+	if _, _, ok := srv.chatLinks.redeem(code.Code, chatUserKey("line", "U1"), time.Now()); !ok {
+		t.Fatal("redeem failed")
+	}
+
+	srv.consents.end(code.ConsentID, revokedByUser)
+	if _, ok := srv.chatLinks.get(chatUserKey("line", "U1")); ok {
+		t.Error("link kept after its consent was revoked")
+	}
+}
+
+func TestChat_WhatsApp(t *testing.T) {
+	api, sent := chatAPI(t)
+	old := []string{whatsappAppSecret, whatsappToken, whatsappPhoneID, whatsappVerifyToken, whatsappAPIURL}
+	whatsappAppSecret, whatsappToken, whatsappPhoneID, whatsappVerifyToken, whatsappAPIURL = "wa-secret", "wa-token", "1234", "verify-me", api.URL
+	t.Cleanup(func() {
+		whatsappAppSecret, whatsappToken, whatsappPhoneID, whatsappVerifyToken, whatsappAPIURL = old[0], old[1], old[2], old[3], old[4]
+	})
+	srv := newTestServer("")
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/chat/webhook/whatsapp?hub.mode=subscribe&hub.verify_token=verify-me&hub.challenge=42", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "42" {
+		t.Errorf("verification: status %d body %q, want 200 42", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/chat/webhook/whatsapp?hub.mode=subscribe&hub.verify_token=nope&hub.challenge=42", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong verify token: status %d, want 401", w.Code)
+	}
+
+	body := `{"entry":[{"changes":[{"value":{"messages":[{"from":"628123","type":"text","text":{"body":"help"}}]}}]}]}`
+	mac := hmac.New(sha256.New, []byte("wa-secret"))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest("POST", "/api/chat/webhook/whatsapp", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("webhook: status %d: %s", w.Code, w.Body)
+	}
+	got := waitChatReply(t, sent)
+	if !strings.HasPrefix(got, `/1234/messages Bearer wa-token {"messaging_product":"whatsapp","to":"628123","type":"text","text":{"body":"Send \"today\"`) {
+		t.Errorf("reply = %s", got)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GET /api/analytics/clashes shows, for a catalog page such as one prodi's
+// classes in a semester, which courses are scheduled against each other.
+// It is meant for reviewing a timetable: two required courses of the same
+// year should not clash in every combination of their classes.
+
+// Entries of the top list by default.
+const clashTopDefault = 20
+
+type ClashReport struct {
+	Courses []string `json:"courses"` // course codes, in the order of the matrix rows and columns
+	// Matrix[i][j] is the number of class pairs of Courses[i] and
+	// Courses[j] that meet at the same time. The diagonal is zero.
+	Matrix        [][]int       `json:"matrix"`
+	ClashingPairs int           `json:"clashing_pairs"` // course pairs with at least one clash
+	Top           []CourseClash `json:"top"`
+}
+
+type CourseClash struct {
+	A          string `json:"a"`
+	AName      string `json:"a_name"`
+	B          string `json:"b"`
+	BName      string `json:"b_name"`
+	ClassPairs int    `json:"class_pairs"` // class pairs that clash
+	TotalPairs int    `json:"total_pairs"` // class pairs in all
+	// Every class of one clashes with every class of the other, so no
+	// student can take both.
+	Unavoidable bool     `json:"unavoidable"`
+	Slots       []string `json:"slots"` // when they clash, e.g. "Senin 07:00-09:00"
+}
+
+type meetingSlot struct {
+	day        string
+	start, end int
+}
+
+// The meetings of a class that have a day and a valid time.
+func classSlots(c CourseClass) []meetingSlot {
+	var slots []meetingSlot
+	for _, e := range c.Schedules {
+		startText, endText, _ := strings.Cut(e.Time, "-")
+		start, ok1 := clockMinutes(startText)
+		end, ok2 := clockMinutes(endText)
+		if ok1 && ok2 && end > start && e.Day != "" {
+			slots = append(slots, meetingSlot{e.Day, start, end})
+		}
+	}
+	return slots
+}
+
+// Returns the overlaps between two classes' meetings.
+func slotOverlaps(a, b []meetingSlot) []meetingSlot {
+	var out []meetingSlot
+	for _, x := range a {
+		for _, y := range b {
+			if x.day == y.day && x.start < y.end && y.start < x.end {
+				out = append(out, meetingSlot{x.day, max(x.start, y.start), min(x.end, y.end)})
+			}
+		}
+	}
+	return out
+}
+
+// Builds the clash matrix of classes by course, and the top course pairs:
+// unavoidable clashes first, then by the share of class pairs that clash.
+func clashReport(classes []CourseClass, top int) ClashReport {
+	byCourse := make(map[string][]CourseClass)
+	names := make(map[string]string)
+	for _, c := range classes {
+		byCourse[c.Code] = append(byCourse[c.Code], c)
+		names[c.Code] = cmp.Or(names[c.Code], c.Name)
+	}
+	report := ClashReport{Courses: make([]string, 0, len(byCourse)), Top: []CourseClash{}}
+	for code := range byCourse {
+		report.Courses = append(report.Courses, code)
+	}
+	slices.Sort(report.Courses)
+	slots := make(map[string][][]meetingSlot, len(byCourse))
+	for code, cs := range byCourse {
+		for _, c := range cs {
+			slots[code] = append(slots[code], classSlots(c))
+		}
+	}
+
+	report.Matrix = make([][]int, len(report.Courses))
+	for i := range report.Matrix {
+		report.Matrix[i] = make([]int, len(report.Courses))
+	}
+	var clashes []CourseClash
+	for i, a := range report.Courses {
+		for j := i + 1; j < len(report.Courses); j++ {
+			b := report.Courses[j]
+			cc := CourseClash{A: a, AName: names[a], B: b, BName: names[b], Slots: []string{}}
+			for _, sa := range slots[a] {
+				for _, sb := range slots[b] {
+					cc.TotalPairs++
+					overlaps := slotOverlaps(sa, sb)
+					if len(overlaps) == 0 {
+						continue
+					}
+					cc.ClassPairs++
+					for _, o := range overlaps {
+						slot := o.day + " " + clockString(o.start) + "-" + clockString(o.end)
+						if !slices.Contains(cc.Slots, slot) {
+							cc.Slots = append(cc.Slots, slot)
+						}
+					}
+				}
+			}
+			if cc.ClassPairs == 0 {
+				continue
+			}
+			cc.Unavoidable = cc.ClassPairs == cc.TotalPairs
+			report.Matrix[i][j], report.Matrix[j][i] = cc.ClassPairs, cc.ClassPairs
+			clashes = append(clashes, cc)
+		}
+	}
+	report.ClashingPairs = len(clashes)
+
+	slices.SortStableFunc(clashes, func(x, y CourseClash) int {
+		if x.Unavoidable != y.Unavoidable {
+			if x.Unavoidable {
+				return -1
+			}
+			return 1
+		}
+		// Compare x.ClassPairs/x.TotalPairs with y's, larger first.
+		return cmp.Or(
+			cmp.Compare(y.ClassPairs*x.TotalPairs, x.ClassPairs*y.TotalPairs),
+			cmp.Compare(y.ClassPairs, x.ClassPairs),
+		)
+	})
+	for _, cc := range clashes[:min(top, len(clashes))] {
+		slices.SortFunc(cc.Slots, func(a, b string) int {
+			dayA, timeA, _ := strings.Cut(a, " ")
+			dayB, timeB, _ := strings.Cut(b, " ")
+			return cmp.Or(cmp.Compare(dayRank(dayA), dayRank(dayB)), cmp.Compare(timeA, timeB))
+		})
+		report.Top = append(report.Top, cc)
+	}
+	return report
+}
+
+// GET /api/analytics/clashes
+func (s *Server) clashHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := catalogKey(query.Get("semester"), query); !ok {
+		writeError(w, r, codeInvalidRequest, "fakultas or prodi is required")
+		return
+	}
+	top := clashTopDefault
+	if v := query.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, codeInvalidRequest, "top must be a non-negative integer")
+			return
+		}
+		top = n
+	}
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	writeSuccessWithMeta(w, clashReport(classes, top), meta)
+}
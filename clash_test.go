@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestClashReport(t *testing.T) {
+	class := func(code, classNo, day, time string) CourseClass {
+		return CourseClass{Code: code, Name: "Kuliah " + code, ClassNo: classNo, Schedules: []ScheduleEntry{{Day: day, Time: time}}}
+	}
+	classes := []CourseClass{
+		class("IF2211", "01", "Senin", "07:00-09:00"),
+		class("IF2211", "02", "Selasa", "07:00-09:00"),
+		class("IF2230", "01", "Senin", "08:00-10:00"),
+		class("IF2240", "01", "Rabu", "07:00-09:00"),
+		class("IF2250", "01", "Rabu", "08:00-09:00"),
+		class("IF2250", "02", "Rabu", "09:00-11:00"), // touching is not clashing
+		class("IF2260", "01", "Kamis", "bad"),
+	}
+	r := clashReport(classes, 10)
+
+	if !slices.Equal(r.Courses, []string{"IF2211", "IF2230", "IF2240", "IF2250", "IF2260"}) {
+		t.Fatalf("courses = %v", r.Courses)
+	}
+	if r.Matrix[0][1] != 1 || r.Matrix[1][0] != 1 || r.Matrix[2][3] != 1 || r.Matrix[0][0] != 0 || r.Matrix[0][4] != 0 {
+		t.Errorf("matrix = %v", r.Matrix)
+	}
+	if r.ClashingPairs != 2 || len(r.Top) != 2 {
+		t.Fatalf("report = %+v", r)
+	}
+	// IF2211 and IF2230 clash in 1 of 2 class pairs, IF2240 and IF2250 in
+	// 1 of 2 as well, but neither is unavoidable; the tie keeps code order.
+	first := r.Top[0]
+	if first.A != "IF2211" || first.B != "IF2230" || first.ClassPairs != 1 || first.TotalPairs != 2 || first.Unavoidable || !slices.Equal(first.Slots, []string{"Senin 08:00-09:00"}) {
+		t.Errorf("top[0] = %+v", first)
+	}
+
+	classes = append(classes, class("IF2270", "01", "Senin", "07:30-08:30"))
+	r = clashReport(classes, 1)
+	if len(r.Top) != 1 || r.Top[0].A != "IF2230" || r.Top[0].B != "IF2270" || !r.Top[0].Unavoidable {
+		t.Errorf("top = %+v, want the unavoidable IF2230/IF2270 clash first", r.Top)
+	}
+}
+
+func TestClashHandler(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/analytics/clashes?student_id=13520001&semester=2025-2"+query, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	if w := get(""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("without prodi: status %d, want 422", w.Code)
+	}
+	w := get("&prodi=135")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if r := decodeData[ClashReport](t, w); len(r.Courses) != 2 || r.ClashingPairs != 0 {
+		t.Errorf("report = %+v", r)
+	}
+}
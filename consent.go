@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Features that keep a student's SIX cookies on the server (grade watches,
+// organization memberships, and chat links) record a consent when the
+// cookies are handed over. Consents expire after SIX_CONSENT_TTL, and the
+// caller can list them and revoke them through /api/me/consent. Expiring or
+// revoking a consent drops the stored cookies with the feature that held
+// them. Revoked consents stay listed for another SIX_CONSENT_TTL as an audit trail.
+var consentTTL = envDuration("SIX_CONSENT_TTL", 30*24*time.Hour)
+
+// Consent scopes: what the stored cookies are used for.
+const (
+	scopeGradeWatch    = "grade_watch"    // polling the transcript for new grades
+	scopeOrgMembership = "org_membership" // sharing busy times with an organization
+	scopeChatLink      = "chat_link"      // answering a chat account's questions
+)
+
+// Why a consent ended.
+const (
+	revokedByUser = "revoked" // through DELETE /api/me/consent/{id}
+	revokedExpiry = "expired"
+	revokedDelete = "deleted" // the watch, membership, or link was removed
+)
+
+type Consent struct {
+	ID        string     `json:"id"`
+	Scope     string     `json:"scope"`
+	StudentID string     `json:"student_id"`
+	Resource  string     `json:"resource"` // ID of the grade watch, organization, or chat link
+	GrantedAt time.Time  `json:"granted_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"` // set with RevokedAt
+
+	owner string
+	drop  func() // removes the stored cookies
+}
+
+type consentLedger struct {
+	mu       sync.Mutex
+	consents map[string]*Consent
+}
+
+func newConsentLedger() *consentLedger {
+	return &consentLedger{consents: make(map[string]*Consent)}
+}
+
+// Records a consent by the caller of r. drop is called when the consent is
+// revoked or expires, but not when the resource itself was deleted.
+func (l *consentLedger) grant(r *http.Request, scope, studentID, resource string, drop func()) Consent {
+	now := time.Now()
+	c := &Consent{
+		ID:        randomHex(8),
+		Scope:     scope,
+		StudentID: studentID,
+		Resource:  resource,
+		GrantedAt: now,
+		ExpiresAt: now.Add(consentTTL),
+		owner:     subscriptionOwner(r),
+		drop:      drop,
+	}
+	l.mu.Lock()
+	l.consents[c.ID] = c
+	l.mu.Unlock()
+	log.Printf("consent granted id=%s scope=%s student_id=%s", c.ID, scope, studentID)
+	return *c
+}
+
+// Ends a consent that is still active. Unless reason is revokedDelete, it
+// drops the stored cookies. Reports whether the consent was active.
+func (l *consentLedger) end(id, reason string) bool {
+	l.mu.Lock()
+	c, ok := l.consents[id]
+	if !ok || c.RevokedAt != nil {
+		l.mu.Unlock()
+		return false
+	}
+	now := time.Now()
+	c.RevokedAt, c.Reason = &now, reason
+	drop := c.drop
+	c.drop = nil
+	l.mu.Unlock()
+
+	log.Printf("consent ended id=%s reason=%s", id, reason)
+	if drop != nil && reason != revokedDelete {
+		drop()
+	}
+	return true
+}
+
+// Expires consents past their expiry and forgets ones that ended more than
+// consentTTL ago.
+func (l *consentLedger) sweep(now time.Time) {
+	var expired []string
+	l.mu.Lock()
+	for id, c := range l.consents {
+		switch {
+		case c.RevokedAt == nil && !now.Before(c.ExpiresAt):
+			expired = append(expired, id)
+		case c.RevokedAt != nil && now.Sub(*c.RevokedAt) > consentTTL:
+			delete(l.consents, id)
+		}
+	}
+	l.mu.Unlock()
+	for _, id := range expired {
+		l.end(id, revokedExpiry)
+	}
+}
+
+// Runs sweep every minute until ctx is done.
+func (s *Server) runConsentSweeper(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.consents.sweep(now)
+		}
+	}
+}
+
+// GET /api/me/consent
+func (s *Server) listConsents(w http.ResponseWriter, r *http.Request) {
+	owner := subscriptionOwner(r)
+	s.consents.mu.Lock()
+	list := []Consent{}
+	for _, c := range s.consents.consents {
+		if c.owner == owner {
+			list = append(list, *c)
+		}
+	}
+	s.consents.mu.Unlock()
+
+	slices.SortFunc(list, func(a, b Consent) int {
+		return cmp.Or(b.GrantedAt.Compare(a.GrantedAt), cmp.Compare(a.ID, b.ID))
+	})
+	writeSuccess(w, list)
+}
+
+// DELETE /api/me/consent/{id}
+func (s *Server) revokeConsent(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.consents.mu.Lock()
+	c, ok := s.consents.consents[id]
+	ok = ok && c.owner == subscriptionOwner(r)
+	s.consents.mu.Unlock()
+	if !ok {
+		writeError(w, r, codeConsentNotFound)
+		return
+	}
+	s.consents.end(id, revokedByUser)
+
+	s.consents.mu.Lock()
+	v := *c
+	s.consents.mu.Unlock()
+	writeSuccess(w, v)
+}
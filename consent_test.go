@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsentLedger_ExpiryAndRetention(t *testing.T) {
+	l := newConsentLedger()
+	req := httptest.NewRequest("GET", "/", nil)
+	dropped := 0
+	c := l.grant(req, scopeGradeWatch, "123", "w1", func() { dropped++ })
+	deleted := l.grant(req, scopeGradeWatch, "123", "w2", func() { dropped++ })
+
+	// Deleting the resource ends its consent without dropping it again.
+	if !l.end(deleted.ID, revokedDelete) || dropped != 0 {
+		t.Fatalf("end(deleted): dropped %d times, want 0", dropped)
+	}
+	if l.end(deleted.ID, revokedByUser) {
+		t.Error("ending an ended consent reported it as active")
+	}
+
+	l.sweep(c.ExpiresAt.Add(-time.Second))
+	if dropped != 0 {
+		t.Fatal("sweep dropped a consent before it expired")
+	}
+	l.sweep(c.ExpiresAt)
+	if dropped != 1 || l.consents[c.ID].Reason != revokedExpiry {
+		t.Fatalf("after expiry: dropped %d, consent %+v", dropped, *l.consents[c.ID])
+	}
+
+	// Ended consents stay for consentTTL as an audit trail.
+	l.sweep(time.Now().Add(consentTTL + time.Minute))
+	if len(l.consents) != 0 {
+		t.Errorf("%d consents left after the retention period", len(l.consents))
+	}
+}
+
+func TestConsent_RevokeDropsGradeWatch(t *testing.T) {
+	setupWebhooks(t)
+	old := gradeWatchEnabled
+	gradeWatchEnabled = true
+	t.Cleanup(func() { gradeWatchEnabled = old })
+	srv := newTestServer("")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	w := do("POST", "/api/grades/watches", `{"student_id":"123","url":"https://example.com/hook"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", w.Code, w.Body)
+	}
+	watch := decodeData[GradeWatch](t, w)
+
+	consents := decodeData[[]Consent](t, do("GET", "/api/me/consent", ""))
+	if len(consents) != 1 {
+		t.Fatalf("got %d consents, want 1", len(consents))
+	}
+	c := consents[0]
+	if c.ID != watch.ConsentID || c.Scope != scopeGradeWatch || c.Resource != watch.ID || c.StudentID != "123" || c.RevokedAt != nil {
+		t.Errorf("consent = %+v", c)
+	}
+	if !c.ExpiresAt.Equal(c.GrantedAt.Add(consentTTL)) {
+		t.Errorf("expires at %v, want %v after %v", c.ExpiresAt, consentTTL, c.GrantedAt)
+	}
+
+	w = do("DELETE", "/api/me/consent/"+c.ID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke: status %d: %s", w.Code, w.Body)
+	}
+	if got := decodeData[Consent](t, w); got.RevokedAt == nil || got.Reason != revokedByUser {
+		t.Errorf("revoked consent = %+v", got)
+	}
+	if w := do("GET", "/api/grades/watches/"+watch.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("watch after revoking: status %d, want 404", w.Code)
+	}
+	if w := do("DELETE", "/api/me/consent/nope", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown consent: status %d, want 404", w.Code)
+	}
+}
+
+func TestConsent_OrgMembership(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	srv := setupOrgs(t, mock.URL)
+
+	org := decodeData[Org](t, orgRequest(srv, "POST", "/api/orgs", "owner", `{"name":"Asrama"}`))
+	base := "/api/orgs/" + org.ID
+	member := decodeData[OrgMember](t, orgRequest(srv, "POST", base+"/members", "member", `{"join_code":"`+org.JoinCode+`","name":"Budi","consent":true}`))
+
+	// The consent belongs to the member, not to the organization's owner.
+	if got := decodeData[[]Consent](t, orgRequest(srv, "GET", "/api/me/consent", "owner", "")); len(got) != 0 {
+		t.Errorf("owner sees %d consents, want 0", len(got))
+	}
+	if w := orgRequest(srv, "DELETE", "/api/me/consent/"+member.ConsentID, "owner", ""); w.Code != http.StatusNotFound {
+		t.Errorf("owner revoking: status %d, want 404", w.Code)
+	}
+
+	// Removing the member ends the consent as deleted.
+	if w := orgRequest(srv, "DELETE", base+"/members/"+member.ID, "owner", ""); w.Code != http.StatusOK {
+		t.Fatalf("remove member: status %d: %s", w.Code, w.Body)
+	}
+	got := decodeData[[]Consent](t, orgRequest(srv, "GET", "/api/me/consent", "member", ""))
+	if len(got) != 1 || got[0].Scope != scopeOrgMembership || got[0].Resource != org.ID || got[0].Reason != revokedDelete {
+		t.Errorf("member's consents = %+v", got)
+	}
+}
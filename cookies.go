@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// Cookies every request must carry. Configurable because SIX may rename them.
+var requiredCookies = envList("SIX_REQUIRED_COOKIES", []string{"nissin", "khongguan"})
+
+// When set, inbound cookies whose names match this pattern are forwarded too,
+// e.g. "^(nissin|khongguan|XSRF-TOKEN)$" or ".*" to pass everything through.
+var cookiePassthrough = compileCookiePattern(envString("SIX_COOKIE_PASSTHROUGH", ""))
+
+func compileCookiePattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Printf("config: invalid SIX_COOKIE_PASSTHROUGH %q: %v", pattern, err)
+		return nil
+	}
+	return re
+}
+
+type missingCookieError struct {
+	name string
+}
+
+func (e *missingCookieError) Error() string {
+	return fmt.Sprintf("missing required %s cookie", e.name)
+}
+
+// Copies the SIX session cookies from the inbound request r onto req. Each
+// required cookie may arrive as a cookie or, for clients that cannot set
+// cross-origin cookies, as an X-Six-<Name> header.
+func forwardCookies(req, r *http.Request) error {
+	for _, name := range requiredCookies {
+		v := sessionCookie(r, name)
+		if v == "" {
+			return &missingCookieError{name: name}
+		}
+		req.AddCookie(&http.Cookie{Name: name, Value: v})
+	}
+
+	for _, c := range r.Cookies() {
+		if slices.Contains(requiredCookies, c.Name) {
+			continue
+		}
+		if cookiePassthrough != nil && cookiePassthrough.MatchString(c.Name) || isDetectedCookie(c.Name) {
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return nil
+}
+
+// Returns the value of the required cookie name on the inbound request r, from
+// the cookie or else its X-Six-<Name> header.
+func sessionCookie(r *http.Request, name string) string {
+	if c, err := r.Cookie(name); err == nil && c.Value != "" {
+		return c.Value
+	}
+	return r.Header.Get("X-Six-" + name)
+}
+
+// Returns a hash of the SIX session r carries, for keying cached private
+// data by the session that may see it.
+func sessionKey(r *http.Request) string {
+	h := sha256.New()
+	for _, name := range requiredCookies {
+		fmt.Fprintf(h, "%s=%s;", name, sessionCookie(r, name))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// At most this many detected cookie names are forwarded, so a SIX that sets
+// a new cookie on every response cannot grow the set without bound.
+const maxDetectedCookies = 4
+
+// Cookie names that are plausible session cookies: short, and free of
+// anything a cookie header or a log line would choke on.
+var detectedCookieNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,63}$`)
+
+var (
+	detectedCookiesMu   sync.Mutex
+	detectedCookies     = make(map[string]bool)
+	detectedCookiesFull bool
+)
+
+func isDetectedCookie(name string) bool {
+	detectedCookiesMu.Lock()
+	defer detectedCookiesMu.Unlock()
+	return detectedCookies[name]
+}
+
+// Looks at cookies SIX sets (e.g. on a login redirect) for session-like ones
+// that would not be forwarded, which is the first sign that SIX renamed its
+// session cookies. From then on, inbound cookies with a detected name are
+// forwarded too, up to maxDetectedCookies names; each is logged once.
+// Returns the names that were new.
+func detectSessionCookies(resp *http.Response) []string {
+	if resp == nil {
+		return nil
+	}
+	var detected []string
+	for _, c := range resp.Cookies() {
+		if !c.HttpOnly || c.MaxAge < 0 || slices.Contains(requiredCookies, c.Name) {
+			continue
+		}
+		if cookiePassthrough != nil && cookiePassthrough.MatchString(c.Name) || !detectedCookieNameRe.MatchString(c.Name) {
+			continue
+		}
+
+		detectedCookiesMu.Lock()
+		seen, full := detectedCookies[c.Name], len(detectedCookies) >= maxDetectedCookies
+		warnFull := !seen && full && !detectedCookiesFull
+		if !seen && !full {
+			detectedCookies[c.Name] = true
+		}
+		if warnFull {
+			detectedCookiesFull = true
+		}
+		detectedCookiesMu.Unlock()
+		switch {
+		case warnFull:
+			log.Printf("SIX set session cookie %q which is not forwarded, since %d detected cookies already are; set SIX_REQUIRED_COOKIES or SIX_COOKIE_PASSTHROUGH", c.Name, maxDetectedCookies)
+		case !seen && !full:
+			log.Printf("SIX set session cookie %q; forwarding it from now on. Add it to SIX_REQUIRED_COOKIES if SIX renamed its cookies", c.Name)
+			detected = append(detected, c.Name)
+		}
+	}
+	return detected
+}
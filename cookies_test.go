@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setCookieConfig(t *testing.T, required []string, passthrough string) {
+	t.Helper()
+	oldRequired, oldPass := requiredCookies, cookiePassthrough
+	requiredCookies, cookiePassthrough = required, compileCookiePattern(passthrough)
+	resetDetectedCookies := func() {
+		detectedCookiesMu.Lock()
+		detectedCookies, detectedCookiesFull = make(map[string]bool), false
+		detectedCookiesMu.Unlock()
+	}
+	resetDetectedCookies()
+	t.Cleanup(func() {
+		requiredCookies, cookiePassthrough = oldRequired, oldPass
+		resetDetectedCookies()
+	})
+}
+
+func forwardedCookies(t *testing.T, incoming *http.Request) (map[string]string, error) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "https://example.com", nil)
+	if err := forwardCookies(req, incoming); err != nil {
+		return nil, err
+	}
+	got := make(map[string]string)
+	for _, c := range req.Cookies() {
+		got[c.Name] = c.Value
+	}
+	return got, nil
+}
+
+func TestForwardCookies_ConfiguredNames(t *testing.T) {
+	setCookieConfig(t, []string{"session"}, "")
+
+	incoming := httptest.NewRequest("GET", "/test", nil)
+	incoming.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	incoming.AddCookie(&http.Cookie{Name: "nissin", Value: "old"})
+	got, err := forwardedCookies(t, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["session"] != "s1" {
+		t.Errorf("forwarded %v, want only session", got)
+	}
+
+	_, err = forwardedCookies(t, httptest.NewRequest("GET", "/test", nil))
+	if err == nil || !strings.Contains(err.Error(), "session") {
+		t.Errorf("expected error naming the session cookie, got %v", err)
+	}
+}
+
+func TestForwardCookies_HeaderFallback(t *testing.T) {
+	setCookieConfig(t, []string{"nissin", "khongguan"}, "")
+
+	incoming := httptest.NewRequest("GET", "/test", nil)
+	incoming.AddCookie(&http.Cookie{Name: "nissin", Value: "a"})
+	incoming.Header.Set("X-Six-Khongguan", "b")
+	got, err := forwardedCookies(t, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["nissin"] != "a" || got["khongguan"] != "b" {
+		t.Errorf("forwarded %v", got)
+	}
+}
+
+func TestForwardCookies_Passthrough(t *testing.T) {
+	setCookieConfig(t, []string{"nissin"}, "^(XSRF-TOKEN|lang)$")
+
+	incoming := httptest.NewRequest("GET", "/test", nil)
+	for name, value := range map[string]string{"nissin": "a", "XSRF-TOKEN": "x", "lang": "id", "_ga": "tracker"} {
+		incoming.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	got, err := forwardedCookies(t, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["XSRF-TOKEN"] != "x" || got["lang"] != "id" {
+		t.Errorf("forwarded %v, want nissin, XSRF-TOKEN, and lang", got)
+	}
+}
+
+func TestCompileCookiePattern_Invalid(t *testing.T) {
+	if re := compileCookiePattern("("); re != nil {
+		t.Error("invalid pattern should disable passthrough")
+	}
+	if re := compileCookiePattern(".*"); re == nil || !re.MatchString("anything") {
+		t.Error("expected catch-all pattern to compile")
+	}
+}
+
+func TestDetectSessionCookies(t *testing.T) {
+	setCookieConfig(t, []string{"nissin", "khongguan"}, "^XSRF")
+
+	resp := &http.Response{Header: http.Header{}}
+	for _, c := range []string{
+		"nissin=a; HttpOnly",
+		"sixsession=b; Path=/; HttpOnly",
+		"XSRF-TOKEN=c; HttpOnly",
+		"_ga=d",
+		"expired=e; Max-Age=0; HttpOnly",
+	} {
+		resp.Header.Add("Set-Cookie", c)
+	}
+
+	if got := detectSessionCookies(resp); len(got) != 1 || got[0] != "sixsession" {
+		t.Errorf("detected %v, want [sixsession]", got)
+	}
+	if got := detectSessionCookies(resp); len(got) != 0 {
+		t.Errorf("expected each name to be reported once, got %v", got)
+	}
+
+	incoming := httptest.NewRequest("GET", "/", nil)
+	for _, c := range []string{"nissin", "khongguan", "sixsession", "_ga"} {
+		incoming.AddCookie(&http.Cookie{Name: c, Value: c + "-value"})
+	}
+	got, err := forwardedCookies(t, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["sixsession"] != "sixsession-value" || got["_ga"] != "" {
+		t.Errorf("forwarded %v, want the detected sixsession cookie but not _ga", got)
+	}
+}
+
+func TestDetectSessionCookies_Bounded(t *testing.T) {
+	setCookieConfig(t, []string{"nissin"}, "")
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Add("Set-Cookie", "1bad=x; HttpOnly")
+	resp.Header.Add("Set-Cookie", strings.Repeat("a", 65)+"=x; HttpOnly")
+	for i := range maxDetectedCookies + 2 {
+		resp.Header.Add("Set-Cookie", fmt.Sprintf("session%d=x; HttpOnly", i))
+	}
+
+	got := detectSessionCookies(resp)
+	if len(got) != maxDetectedCookies || got[0] != "session0" {
+		t.Errorf("detected %v, want the first %d valid names", got, maxDetectedCookies)
+	}
+	if isDetectedCookie("1bad") || isDetectedCookie(fmt.Sprintf("session%d", maxDetectedCookies)) {
+		t.Error("forwarding an invalid name or one past the limit")
+	}
+}
+
+func TestNewHTTPClient_DetectsCookiesOnRedirect(t *testing.T) {
+	setCookieConfig(t, []string{"nissin"}, "")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "renamed", Value: "x", HttpOnly: true})
+		http.Redirect(w, r, "/end", http.StatusFound)
+	})
+	mux.HandleFunc("/end", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := newSIXProvider("", nil).client().Get(srv.URL + "/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !isDetectedCookie("renamed") {
+		t.Error("expected cookie set on a redirect hop to be detected")
+	}
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The catalog crawler scrapes the catalog page of every faculty and program
+// for a semester, with the catalog warmer's service account. It reads the
+// faculties offered in the filters of the account's schedule page, then the
+// programs of each faculty from that faculty's page, and stores every page
+// in the catalog. SIX_CRAWL_WORKERS pages are fetched at a time at batch
+// priority, so the politeness settings pace them like any batch work.
+//
+// Progress is checkpointed to SIX_DATA_DIR after every page. A crawl cut
+// short by a restart resumes when the server starts again, and one stopped
+// by SIX maintenance resumes when it is started again, skipping the pages
+// already done.
+var crawlWorkers = envInt("SIX_CRAWL_WORKERS", 2)
+
+// Returned for a page the refresher did not run, because its queue was full
+// or the page was already being fetched. The page stays pending and is tried
+// again after crawlRetryDelay.
+var errCrawlNotRun = errors.New("not run: the refresh queue is full or the page is already being fetched")
+
+var crawlRetryDelay = 5 * time.Second
+
+// Values of CrawlJob.Status and CrawlPage.Status.
+const (
+	crawlRunning     = "running"
+	crawlInterrupted = "interrupted" // stopped early; resumable
+	crawlDone        = "done"
+	crawlFailed      = "failed"
+	crawlPending     = "pending"
+)
+
+// One catalog page of a crawl. A page without Prodi is a faculty's page,
+// which also lists the faculty's programs.
+type CrawlPage struct {
+	Fakultas string `json:"fakultas"`
+	Prodi    string `json:"prodi,omitempty"`
+	Status   string `json:"status"` // pending, done, or failed
+	Classes  int    `json:"classes"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (p CrawlPage) query() url.Values {
+	q := url.Values{"fakultas": {p.Fakultas}}
+	if p.Prodi != "" {
+		q.Set("prodi", p.Prodi)
+	}
+	return q
+}
+
+type CrawlJob struct {
+	ID       string `json:"id"`
+	Semester string `json:"semester"`
+	Status   string `json:"status"` // running, interrupted, done, or failed
+	Error    string `json:"error,omitempty"`
+	// Discovered is set once the faculties are known. Programs are added
+	// to Pages as their faculty's page is crawled.
+	Discovered bool        `json:"discovered"`
+	StartedAt  time.Time   `json:"started_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Pages      []CrawlPage `json:"pages"`
+}
+
+// Counts the job's pages by status.
+func (j *CrawlJob) progress() JobProgress {
+	p := JobProgress{Total: len(j.Pages)}
+	for _, page := range j.Pages {
+		switch page.Status {
+		case crawlDone:
+			p.Done++
+		case crawlFailed:
+			p.Failed++
+		}
+	}
+	return p
+}
+
+// Holds the latest crawl and checkpoints it to dir, if set.
+type catalogCrawler struct {
+	dir string
+
+	mu      sync.Mutex
+	job     *CrawlJob
+	running bool
+}
+
+func crawlCheckpointPath(dir string) string {
+	return filepath.Join(dir, "crawl.json")
+}
+
+// Loads the checkpoint in dir, if there is one.
+func newCatalogCrawler(dir string) *catalogCrawler {
+	c := &catalogCrawler{dir: dir}
+	if dir == "" {
+		return c
+	}
+	data, err := os.ReadFile(crawlCheckpointPath(dir))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("crawl checkpoint not loaded: %v", err)
+		}
+		return c
+	}
+	var job CrawlJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		log.Printf("crawl checkpoint not loaded: %v", err)
+		return c
+	}
+	c.job = &job
+	return c
+}
+
+// Writes the job to the checkpoint. Call with c.mu held.
+func (c *catalogCrawler) checkpointLocked() {
+	if c.dir == "" || c.job == nil {
+		return
+	}
+	data, err := json.Marshal(c.job)
+	if err == nil {
+		err = writeFileAtomic(crawlCheckpointPath(c.dir), data)
+	}
+	if err != nil {
+		log.Printf("crawl checkpoint not written: %v", err)
+	}
+}
+
+// Returns a copy of the latest job.
+func (c *catalogCrawler) get() (CrawlJob, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.job == nil {
+		return CrawlJob{}, false
+	}
+	job := *c.job
+	job.Pages = slices.Clone(c.job.Pages)
+	return job, true
+}
+
+// Applies update to the job under the lock and checkpoints it.
+func (c *catalogCrawler) update(update func(*CrawlJob)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(c.job)
+	c.job.UpdatedAt = time.Now()
+	c.checkpointLocked()
+}
+
+// Marks a crawl of semester as running: semester's unfinished crawl is
+// resumed, and anything else is replaced by a new job. It returns false if a
+// crawl is already running.
+func (c *catalogCrawler) start(semester string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return false
+	}
+	if c.job == nil || c.job.Semester != semester || c.job.Status == crawlDone || c.job.Status == crawlFailed {
+		c.job = &CrawlJob{ID: randomHex(8), Semester: semester, StartedAt: now, Pages: []CrawlPage{}}
+	}
+	c.job.Status, c.job.Error, c.job.FinishedAt, c.job.UpdatedAt = crawlRunning, "", nil, now
+	c.running = true
+	c.checkpointLocked()
+	return true
+}
+
+// Records how the running crawl ended. Maintenance and cancellation leave it
+// interrupted, to be resumed.
+func (c *catalogCrawler) finish(err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j := c.job
+	switch {
+	case err == nil:
+		j.Status, j.FinishedAt = crawlDone, &now
+	case errors.Is(err, errUpstreamMaintenance), errors.Is(err, context.Canceled):
+		j.Status, j.Error = crawlInterrupted, err.Error()
+	default:
+		j.Status, j.Error, j.FinishedAt = crawlFailed, err.Error(), &now
+	}
+	j.UpdatedAt = now
+	c.running = false
+	c.checkpointLocked()
+}
+
+// Returns the semester of a checkpointed crawl that was running when the
+// server stopped, if there is one.
+func (c *catalogCrawler) resumable() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.job == nil || c.running || c.job.Status != crawlRunning {
+		return "", false
+	}
+	return c.job.Semester, true
+}
+
+// Returns the values offered by the page's select element for each of
+// scheduleFilterKeys, e.g. the faculties under "fakultas". Placeholder
+// options without a value are left out.
+func catalogFilterOptions(doc *goquery.Document) url.Values {
+	options := url.Values{}
+	for _, key := range scheduleFilterKeys {
+		doc.Find(fmt.Sprintf("select[name=%q] option", key)).Each(func(_ int, o *goquery.Selection) {
+			if v := strings.TrimSpace(o.AttrOr("value", "")); v != "" && !slices.Contains(options[key], v) {
+				options.Add(key, v)
+			}
+		})
+	}
+	return options
+}
+
+// Returns the faculty pages offered in options, the filter options of the
+// unfiltered schedule page.
+func catalogFacultyPages(options url.Values) ([]CrawlPage, error) {
+	var pages []CrawlPage
+	for _, f := range options["fakultas"] {
+		pages = append(pages, CrawlPage{Fakultas: f})
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("no faculties found in the schedule page filters")
+	}
+	return pages, nil
+}
+
+// Returns the program pages offered in options, the filter options of
+// fakultas's page.
+func catalogProgramPages(fakultas string, options url.Values) []CrawlPage {
+	var pages []CrawlPage
+	for _, p := range options["prodi"] {
+		pages = append(pages, CrawlPage{Fakultas: fakultas, Prodi: p})
+	}
+	return pages
+}
+
+// Starts a crawl of semester in the background with the service account,
+// or resumes semester's unfinished one. It returns false if a crawl is
+// already running.
+func (s *Server) startCatalogCrawl(ctx context.Context, semester string) (CrawlJob, bool) {
+	if !s.crawler.start(semester, time.Now()) {
+		return CrawlJob{}, false
+	}
+	job, _ := s.crawler.get()
+	log.Printf("catalog crawl started id=%s semester=%s pages_done=%d", job.ID, semester, job.progress().Done)
+	go func() {
+		err := s.crawlCatalog(ctx, http.Header{"Cookie": {warmCookies}}, warmStudentID)
+		s.crawler.finish(err, time.Now())
+		log.Printf("catalog crawl finished id=%s err=%v", job.ID, err)
+	}()
+	return job, true
+}
+
+// Resumes a crawl that was running when the server last stopped.
+func (s *Server) resumeCatalogCrawl(ctx context.Context) {
+	semester, ok := s.crawler.resumable()
+	if !ok {
+		return
+	}
+	if warmCookies == "" || warmStudentID == "" {
+		log.Printf("catalog crawl not resumed: SIX_WARM_COOKIES and SIX_WARM_STUDENT_ID are required")
+		return
+	}
+	s.startCatalogCrawl(ctx, semester)
+}
+
+// Runs the crawler's job to the end, as studentID with the credentials in
+// auth: it discovers the faculties if that is not done yet, then crawls the
+// pending pages, crawlWorkers at a time, until none are left. It stops early
+// on SIX maintenance and on missing credentials.
+func (s *Server) crawlCatalog(ctx context.Context, auth http.Header, studentID string) error {
+	ctx = withPriority(ctx, priorityBatch)
+	job, _ := s.crawler.get()
+
+	if !job.Discovered {
+		page, _, err := s.crawlPage(ctx, auth, studentID, job.Semester, nil)
+		for errors.Is(err, errCrawlNotRun) {
+			if err := waitCrawlRetry(ctx); err != nil {
+				return err
+			}
+			page, _, err = s.crawlPage(ctx, auth, studentID, job.Semester, nil)
+		}
+		if err != nil {
+			return err
+		}
+		faculties, err := catalogFacultyPages(page.FilterOptions)
+		if err != nil {
+			return err
+		}
+		s.crawler.update(func(j *CrawlJob) {
+			for _, f := range faculties {
+				f.Status = crawlPending
+				j.Pages = append(j.Pages, f)
+			}
+			j.Discovered = true
+		})
+	}
+
+	for {
+		job, _ = s.crawler.get()
+		var pending []int
+		for i, p := range job.Pages {
+			if p.Status == crawlPending {
+				pending = append(pending, i)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		// Pages are crawled in rounds: a faculty's programs join the
+		// next round. A worker that hits an error fatal to the whole crawl
+		// cancels the round.
+		round, cancel := context.WithCancelCause(ctx)
+		work := make(chan int)
+		var wg sync.WaitGroup
+		for range max(crawlWorkers, 1) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range work {
+					if err := s.crawlJobPage(round, auth, studentID, job.Semester, i, job.Pages[i]); err != nil {
+						cancel(err)
+					}
+				}
+			}()
+		}
+		for _, i := range pending {
+			if round.Err() != nil || scrapingPaused() {
+				break
+			}
+			work <- i
+		}
+		close(work)
+		wg.Wait()
+		err := context.Cause(round)
+		cancel(nil)
+		if err != nil {
+			return err
+		}
+		if scrapingPaused() {
+			return errUpstreamMaintenance
+		}
+	}
+}
+
+// Crawls the job's page i and records the outcome. It returns an error only
+// when the whole crawl should stop.
+func (s *Server) crawlJobPage(ctx context.Context, auth http.Header, studentID, semester string, i int, page CrawlPage) error {
+	fetched, classes, err := s.crawlPage(ctx, auth, studentID, semester, page.query())
+	if errors.Is(err, errCrawlNotRun) {
+		// The page stays pending for the next round.
+		return waitCrawlRetry(ctx)
+	}
+	var missing *missingCookieError
+	if errors.Is(err, errUpstreamMaintenance) || errors.As(err, &missing) || ctx.Err() != nil {
+		// The page stays pending for the next run.
+		return cmp.Or(ctx.Err(), err)
+	}
+	s.crawler.update(func(j *CrawlJob) {
+		if err != nil {
+			log.Printf("catalog crawl failed fakultas=%s prodi=%s semester=%s err=%v", page.Fakultas, page.Prodi, semester, err)
+			j.Pages[i].Status, j.Pages[i].Error = crawlFailed, err.Error()
+			return
+		}
+		j.Pages[i].Status, j.Pages[i].Classes, j.Pages[i].Error = crawlDone, classes, ""
+		if page.Prodi != "" {
+			return
+		}
+		for _, program := range catalogProgramPages(page.Fakultas, fetched.FilterOptions) {
+			if !slices.ContainsFunc(j.Pages, func(p CrawlPage) bool { return p.Fakultas == program.Fakultas && p.Prodi == program.Prodi }) {
+				program.Status = crawlPending
+				j.Pages = append(j.Pages, program)
+			}
+		}
+	})
+	return nil
+}
+
+// Scrapes one schedule page through the refresher, so the crawl shares the
+// per-host limit with other background work, and stores it in the catalog
+// unless it looks anomalous. It returns the page and the number of classes
+// stored.
+func (s *Server) crawlPage(ctx context.Context, auth http.Header, studentID, semester string, query url.Values) (SchedulePage, int, error) {
+	var page SchedulePage
+	var classes int
+	var err error
+	ran := s.refresher.run(ctx, refreshCatalogCrawl, s.upstreamHost(), semester+"?"+query.Encode(), func(ctx context.Context) error {
+		req, reqErr := http.NewRequestWithContext(ctx, "GET", "/api/schedule", nil)
+		if reqErr != nil {
+			err = reqErr
+			return err
+		}
+		req.Header = auth.Clone()
+		var meta *Meta
+		page, meta, err = s.scrapeSchedulePage(req, studentID, semester, query)
+		if err != nil {
+			return err
+		}
+		if meta.Anomaly != nil {
+			err = fmt.Errorf("anomalous page not stored: %s", strings.Join(meta.Anomaly.Reasons, "; "))
+			return err
+		}
+		classes = len(page.Classes)
+		if key, ok := catalogKey(semester, query); ok {
+			s.catalog.set(key, page.Classes, meta.FetchedAt)
+		}
+		return nil
+	})
+	if !ran && ctx.Err() == nil {
+		return page, 0, errCrawlNotRun
+	}
+	return page, classes, cmp.Or(err, ctx.Err())
+}
+
+// Waits crawlRetryDelay before a page that was not run is tried again.
+func waitCrawlRetry(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(crawlRetryDelay):
+		return nil
+	}
+}
+
+type crawlRequest struct {
+	Semester string `json:"semester"`
+}
+
+// POST /api/admin/crawl starts a crawl, or resumes the semester's unfinished
+// one. Poll GET /api/admin/crawl for progress.
+func (s *Server) startCrawlHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if warmCookies == "" || warmStudentID == "" {
+		writeError(w, r, codeCrawlerDisabled)
+		return
+	}
+	var body crawlRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	semester, _ := s.semesters.resolve(warmStudentID, cmp.Or(body.Semester, "current"), time.Now())
+	if !semesterParamRe.MatchString(semester) {
+		writeError(w, r, codeInvalidRequest, "semester must be a semester such as 2025-2, or current, previous, or next")
+		return
+	}
+	job, ok := s.startCatalogCrawl(context.Background(), semester)
+	if !ok {
+		writeError(w, r, codeCrawlRunning)
+		return
+	}
+	writeCreated(w, job)
+}
+
+// GET /api/admin/crawl
+func (s *Server) getCrawlHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	job, ok := s.crawler.get()
+	if !ok {
+		writeError(w, r, codeJobNotFound)
+		return
+	}
+	writeSuccess(w, job)
+}
+
+// Describes the latest crawl for the jobs admin endpoint.
+func (c *catalogCrawler) jobView(enabled bool) Job {
+	v := Job{Name: "catalog_crawl", Enabled: enabled, Schedule: "on demand"}
+	job, ok := c.get()
+	if !ok {
+		return v
+	}
+	progress := job.progress()
+	v.Progress = &progress
+	updated := job.UpdatedAt
+	v.LastRunAt = &updated
+	if job.Status == crawlDone {
+		v.Result = fmt.Sprintf("%d of %d pages for %s, %d failed", progress.Done, progress.Total, job.Semester, progress.Failed)
+	} else if job.Error != "" {
+		v.LastError = job.Status + ": " + job.Error
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Serves a catalog with faculties F1 and F2. F1 has programs 101 and 102,
+// and the page of 102 fails.
+func mockSIXCatalog(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var fetched []string
+	row := `<tr><td>1</td><td></td><td>IF2211</td><td>Strategi Algoritma</td><td>3</td><td>01</td><td>60</td><td></td><td></td><td></td></tr>`
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		mu.Lock()
+		fetched = append(fetched, q.Encode())
+		mu.Unlock()
+		var selects string
+		switch {
+		case q.Get("fakultas") == "":
+			selects = `<select name="fakultas"><option value="">Semua</option><option value="F1">F1</option><option value="F2">F2</option></select>`
+		case q.Get("prodi") == "102":
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		case q.Get("fakultas") == "F1" && q.Get("prodi") == "":
+			selects = `<select name="prodi"><option value="101">101</option><option value="102">102</option></select>`
+		}
+		fmt.Fprintf(w, `<html><body><form>%s</form><table class="table"><tbody>%s</tbody></table></body></html>`, selects, row)
+	}))
+	t.Cleanup(mock.Close)
+	return mock, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), fetched...)
+	}
+}
+
+func TestCatalogFilterOptions(t *testing.T) {
+	doc := docFromHTML(`<select name="fakultas"><option value="">Pilih</option><option value=" STEI ">STEI</option><option value="STEI">STEI</option></select>
+<select name="prodi"><option value="135">Informatika</option></select><select name="other"><option value="x">x</option></select>`)
+	got := catalogFilterOptions(doc)
+	if strings.Join(got["fakultas"], ",") != "STEI" || strings.Join(got["prodi"], ",") != "135" || len(got) != 2 {
+		t.Errorf("options = %v", got)
+	}
+}
+
+func TestCrawlCatalog_DiscoversAndCheckpoints(t *testing.T) {
+	mock, fetched := mockSIXCatalog(t)
+	dir := t.TempDir()
+	srv := NewServer(Config{BaseURL: mock.URL, DataDir: dir})
+	auth := http.Header{"Cookie": {"nissin=a; khongguan=b"}}
+
+	srv.crawler.start("1945-1", time.Now())
+	if err := srv.crawlCatalog(context.Background(), auth, "123"); err != nil {
+		t.Fatal(err)
+	}
+	srv.crawler.finish(nil, time.Now())
+
+	job, _ := srv.crawler.get()
+	var pages []string
+	for _, p := range job.Pages {
+		pages = append(pages, p.Fakultas+"/"+p.Prodi+":"+p.Status)
+	}
+	want := "F1/:done,F2/:done,F1/101:done,F1/102:failed"
+	if strings.Join(pages, ",") != want || !job.Discovered || job.Status != crawlDone {
+		t.Errorf("job %s pages = %v, want %s", job.Status, pages, want)
+	}
+	if _, ok := srv.catalog.get("1945-1?fakultas=F1&prodi=101"); !ok {
+		t.Error("program page not stored in the catalog")
+	}
+	if p := job.progress(); p != (JobProgress{Done: 3, Failed: 1, Total: 4}) {
+		t.Errorf("progress = %+v", p)
+	}
+
+	// A crawl cut short by a restart resumes with only its pending pages.
+	srv.crawler.update(func(j *CrawlJob) {
+		j.Status = crawlRunning
+		j.Pages[2].Status = crawlPending
+	})
+	restarted := NewServer(Config{BaseURL: mock.URL, DataDir: dir})
+	semester, ok := restarted.crawler.resumable()
+	if !ok || semester != "1945-1" {
+		t.Fatalf("resumable = %q, %v", semester, ok)
+	}
+	before := len(fetched())
+	restarted.crawler.start(semester, time.Now())
+	if err := restarted.crawlCatalog(context.Background(), auth, "123"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetched()[before:]; len(got) != 1 || got[0] != "fakultas=F1&prodi=101" {
+		t.Errorf("resumed crawl fetched %v", got)
+	}
+}
+
+func TestCrawlHandler(t *testing.T) {
+	old := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = old })
+	srv := newTestServer("http://six.invalid")
+	req := httptest.NewRequest("POST", "/api/admin/crawl", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), string(codeCrawlerDisabled)) {
+		t.Errorf("without a service account: status %d: %s", w.Code, w.Body)
+	}
+
+	req = httptest.NewRequest("GET", "/api/admin/crawl", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("no crawl yet: status %d", w.Code)
+	}
+}
+
+func TestCrawlJobPage_NotRunStaysPending(t *testing.T) {
+	mock, _ := mockSIXCatalog(t)
+	srv := NewServer(Config{BaseURL: mock.URL, DataDir: t.TempDir()})
+	auth := http.Header{"Cookie": {"nissin=a; khongguan=b"}}
+	oldDelay := crawlRetryDelay
+	crawlRetryDelay = time.Millisecond
+	t.Cleanup(func() { crawlRetryDelay = oldDelay })
+
+	srv.crawler.start("1945-1", time.Now())
+	srv.crawler.update(func(j *CrawlJob) {
+		j.Pages = []CrawlPage{{Fakultas: "F2", Status: crawlPending}}
+		j.Discovered = true
+	})
+	page := CrawlPage{Fakultas: "F2"}
+	// Another fetch of the same page holds its refresher key.
+	key := refreshCatalogCrawl + " 1945-1?" + page.query().Encode()
+	srv.refresher.mu.Lock()
+	srv.refresher.keys[key] = true
+	srv.refresher.mu.Unlock()
+
+	if err := srv.crawlJobPage(context.Background(), auth, "123", "1945-1", 0, page); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := srv.crawler.get(); job.Pages[0].Status != crawlPending {
+		t.Errorf("page not run is %s, want it left pending", job.Pages[0].Status)
+	}
+
+	srv.refresher.mu.Lock()
+	delete(srv.refresher.keys, key)
+	srv.refresher.mu.Unlock()
+	if err := srv.crawlJobPage(context.Background(), auth, "123", "1945-1", 0, page); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := srv.crawler.get(); job.Pages[0].Status != crawlDone {
+		t.Errorf("retried page is %s, want done", job.Pages[0].Status)
+	}
+}
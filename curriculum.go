@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+	"six-scraper-go/textnorm"
+)
+
+// SIX page listing the courses of the student's study program curriculum.
+func curriculumPath(studentID string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s/akademik/kurikulum", studentID)
+}
+
+type CurriculumCourse struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	SKS  int    `json:"sks"`
+	// Semester is the recommended semester, 1 through 8, or 0 if unknown.
+	Semester int `json:"semester,omitempty"`
+	// Required is true for wajib courses and false for electives (pilihan).
+	Required bool `json:"required"`
+	// Prerequisites are the codes of courses that must be passed first.
+	Prerequisites []string `json:"prerequisites,omitempty"`
+}
+
+// Matches the course codes in a prerequisite cell such as "IF2110, IF2120"
+// or "IF2110 dan MA1101". Dashes and other filler are ignored.
+var prerequisiteCodeRe = regexp.MustCompile(`[A-Za-z]{2,3}\d{4}`)
+
+// Parses the curriculum page. Like parseTranscript it finds columns by header
+// text. A course is required when its "Sifat" (or "Jenis") column says wajib.
+func parseCurriculum(doc *goquery.Document) []CurriculumCourse {
+	var courses []CurriculumCourse
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := scraper.HeaderColumns(table)
+		code, hasCode := cols["kode"]
+		sks, hasSKS := cols["sks"]
+		kind, hasKind := cols["sifat"]
+		if !hasKind {
+			kind, hasKind = cols["jenis"]
+		}
+		if !hasCode || !hasSKS || !hasKind {
+			return
+		}
+		name, hasName := cols["nama"]
+		semester, hasSemester := cols["semester"]
+		prereq, hasPrereq := cols["prasyarat"]
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			c := CurriculumCourse{
+				Code:     scraper.CellText(cells, code),
+				Required: textnorm.Equal(scraper.CellText(cells, kind), "wajib"),
+			}
+			c.SKS, _ = strconv.Atoi(scraper.CellText(cells, sks))
+			if hasName {
+				c.Name = scraper.CellText(cells, name)
+			}
+			if hasSemester {
+				c.Semester, _ = strconv.Atoi(scraper.CellText(cells, semester))
+			}
+			if hasPrereq {
+				for _, code := range prerequisiteCodeRe.FindAllString(scraper.CellText(cells, prereq), -1) {
+					c.Prerequisites = append(c.Prerequisites, strings.ToUpper(code))
+				}
+			}
+			if c.Code != "" {
+				courses = append(courses, c)
+			}
+		}
+	})
+	return courses
+}
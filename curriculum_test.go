@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testCurriculumHTML = `<html><body>
+<table class="table">
+  <thead><tr><th>Semester</th><th>Kode</th><th>Nama</th><th>SKS</th><th>Sifat</th><th>Prasyarat</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>MA1101</td><td>Matematika IA</td><td>4</td><td>Wajib</td><td>-</td></tr>
+    <tr><td>1</td><td>FI1101</td><td>Fisika Dasar IA</td><td>4</td><td>Wajib</td><td></td></tr>
+    <tr><td>4</td><td>IF2211</td><td>Strategi Algoritma</td><td>3</td><td>Wajib</td><td>MA1101</td></tr>
+    <tr><td>7</td><td>IF4050</td><td>Pembangunan Perangkat Lunak</td><td>3</td><td>Pilihan</td><td>IF2211 dan fi1101</td></tr>
+    <tr><td>7</td><td>IF4070</td><td>Representasi Pengetahuan</td><td>3</td><td>Pilihan</td><td></td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+func TestParseCurriculum(t *testing.T) {
+	got := parseCurriculum(docFromHTML(testCurriculumHTML))
+	if len(got) != 5 {
+		t.Fatalf("got %d courses, want 5", len(got))
+	}
+	want := CurriculumCourse{Code: "IF4050", Name: "Pembangunan Perangkat Lunak", SKS: 3, Semester: 7, Required: false, Prerequisites: []string{"IF2211", "FI1101"}}
+	if !reflect.DeepEqual(got[3], want) {
+		t.Errorf("got %+v, want %+v", got[3], want)
+	}
+	if !got[0].Required {
+		t.Error("expected wajib course to be required")
+	}
+	if got[0].Prerequisites != nil {
+		t.Errorf("a dash should mean no prerequisites, got %v", got[0].Prerequisites)
+	}
+}
+
+func TestParseCurriculum_IgnoresOtherTables(t *testing.T) {
+	html := `<table><thead><tr><th>Kode</th><th>SKS</th></tr></thead><tbody><tr><td>MA1101</td><td>4</td></tr></tbody></table>`
+	if got := parseCurriculum(docFromHTML(html)); len(got) != 0 {
+		t.Errorf("expected no courses without a Sifat column, got %+v", got)
+	}
+}
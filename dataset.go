@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dataset exports publish the cached catalog of a semester for researchers.
+// Each export is a new numbered version under SIX_DATASET_DIR/{semester}/
+// with the classes as JSON and CSV and a manifest. Nothing about students is
+// in the catalog, and lecturer names are reduced to a count.
+var datasetDir = envString("SIX_DATASET_DIR", "")
+
+// Held while an export picks its version number and writes it.
+var datasetMu sync.Mutex
+
+// Bump datasetSchema whenever the exported files change shape.
+const datasetSchema = 2
+
+const (
+	datasetClassesJSON  = "classes.json"
+	datasetClassesCSV   = "classes.csv"
+	datasetMeetingsCSV  = "meetings.csv"
+	datasetManifestJSON = "manifest.json"
+)
+
+// A class as published in a dataset.
+type DatasetClass struct {
+	Code      string           `json:"code"`
+	Name      string           `json:"name"`
+	SKS       int              `json:"sks"`
+	ClassNo   string           `json:"class_no"`
+	Quota     int              `json:"quota"`
+	Lecturers int              `json:"lecturers"`
+	Fakultas  string           `json:"fakultas,omitempty"`
+	Prodi     string           `json:"prodi,omitempty"`
+	Category  string           `json:"category,omitempty"` // see CourseClass.Category
+	Meetings  []DatasetMeeting `json:"meetings"`
+}
+
+type DatasetMeeting struct {
+	Day      string `json:"day"`
+	Time     string `json:"time"`
+	Room     string `json:"room"`
+	Activity string `json:"activity"`
+	Method   string `json:"method"`
+}
+
+type DatasetManifest struct {
+	Semester      string        `json:"semester"`
+	Version       int           `json:"version"`
+	Schema        int           `json:"schema"`
+	ParserVersion int           `json:"parser_version"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Classes       int           `json:"classes"`
+	Pages         []DatasetPage `json:"pages"`
+	Files         []DatasetFile `json:"files"`
+}
+
+// A catalog page the dataset was built from.
+type DatasetPage struct {
+	Fakultas  string    `json:"fakultas,omitempty"`
+	Prodi     string    `json:"prodi,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Classes   int       `json:"classes"`
+}
+
+type DatasetFile struct {
+	Name   string `json:"name"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Collects the cached catalog pages of semester. A class listed on several
+// pages is kept once, under the first page in key order.
+func datasetClasses(entries []CatalogArchiveEntry, semester string) ([]DatasetClass, []DatasetPage) {
+	slices.SortFunc(entries, func(a, b CatalogArchiveEntry) int { return strings.Compare(a.Key, b.Key) })
+	classes := []DatasetClass{}
+	pages := []DatasetPage{}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		pageSemester, rawQuery, _ := strings.Cut(e.Key, "?")
+		filters, err := url.ParseQuery(rawQuery)
+		if err != nil || pageSemester != semester {
+			continue
+		}
+		page := DatasetPage{Fakultas: filters.Get("fakultas"), Prodi: filters.Get("prodi"), FetchedAt: e.FetchedAt, Classes: len(e.Classes)}
+		pages = append(pages, page)
+		for _, c := range e.Classes {
+			id := classKey(c)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			meetings := make([]DatasetMeeting, len(c.Schedules))
+			for i, s := range c.Schedules {
+				meetings[i] = DatasetMeeting(s)
+			}
+			classes = append(classes, DatasetClass{
+				Code: c.Code, Name: c.Name, SKS: c.SKS, ClassNo: c.ClassNo, Quota: c.Quota,
+				Lecturers: len(c.Lecturers), Fakultas: page.Fakultas, Prodi: page.Prodi, Category: c.Category, Meetings: meetings,
+			})
+		}
+	}
+	slices.SortStableFunc(classes, func(a, b DatasetClass) int {
+		if c := strings.Compare(a.Code, b.Code); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.ClassNo, b.ClassNo); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+	return classes, pages
+}
+
+// Returns the highest version exported so far under dir, or 0.
+func latestDatasetVersion(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	latest := 0
+	for _, e := range entries {
+		if n, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "v")); err == nil && e.IsDir() && strings.HasPrefix(e.Name(), "v") {
+			latest = max(latest, n)
+		}
+	}
+	return latest
+}
+
+// Writes a new version of the semester's dataset under root and returns its
+// manifest. The files are written to a temporary directory that is renamed
+// into place, so a version directory is always complete.
+func exportDataset(root, semester string, classes []DatasetClass, pages []DatasetPage, now time.Time) (DatasetManifest, error) {
+	datasetMu.Lock()
+	defer datasetMu.Unlock()
+	manifest := DatasetManifest{
+		Semester:      semester,
+		Schema:        datasetSchema,
+		ParserVersion: scheduleShadow.servedVersion(),
+		CreatedAt:     now,
+		Classes:       len(classes),
+		Pages:         pages,
+	}
+	dir := filepath.Join(root, semester)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return manifest, err
+	}
+	tmp, err := os.MkdirTemp(dir, ".export-*")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(tmp)
+
+	classesJSON, err := json.MarshalIndent(classes, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{datasetClassesJSON, classesJSON},
+		{datasetClassesCSV, datasetClassesCSVData(classes)},
+		{datasetMeetingsCSV, datasetMeetingsCSVData(classes)},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(tmp, f.name), f.data, 0o644); err != nil {
+			return manifest, err
+		}
+		sum := sha256.Sum256(f.data)
+		manifest.Files = append(manifest.Files, DatasetFile{Name: f.name, Bytes: len(f.data), SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	manifest.Version = latestDatasetVersion(dir) + 1
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, datasetManifestJSON), data, 0o644); err != nil {
+		return manifest, err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, fmt.Sprintf("v%d", manifest.Version))); err != nil {
+		return manifest, err
+	}
+	return manifest, nil
+}
+
+// One row per class; the meetings are in meetings.csv.
+func datasetClassesCSVData(classes []DatasetClass) []byte {
+	var b strings.Builder
+	cw := csv.NewWriter(&b)
+	cw.Write([]string{"code", "name", "sks", "class_no", "quota", "lecturers", "fakultas", "prodi", "category"})
+	for _, c := range classes {
+		cw.Write([]string{c.Code, c.Name, strconv.Itoa(c.SKS), c.ClassNo, strconv.Itoa(c.Quota), strconv.Itoa(c.Lecturers), c.Fakultas, c.Prodi, c.Category})
+	}
+	cw.Flush()
+	return []byte(b.String())
+}
+
+// One row per meeting, keyed by code, class_no, and category.
+func datasetMeetingsCSVData(classes []DatasetClass) []byte {
+	var b strings.Builder
+	cw := csv.NewWriter(&b)
+	cw.Write([]string{"code", "class_no", "day", "time", "room", "activity", "method", "category"})
+	for _, c := range classes {
+		for _, m := range c.Meetings {
+			cw.Write([]string{c.Code, c.ClassNo, m.Day, m.Time, m.Room, m.Activity, m.Method, c.Category})
+		}
+	}
+	cw.Flush()
+	return []byte(b.String())
+}
+
+type datasetRequest struct {
+	Semester string `json:"semester"`
+}
+
+// POST /api/admin/dataset/export
+func (s *Server) exportDatasetHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if datasetDir == "" {
+		writeError(w, r, codeDatasetDisabled)
+		return
+	}
+	var body datasetRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if body.Semester == "" {
+		body.Semester = "current"
+	}
+	semester, _ := s.semesters.resolve("", body.Semester, time.Now())
+	if !semesterParamRe.MatchString(semester) {
+		writeError(w, r, codeInvalidRequest, "semester must look like 2025-2")
+		return
+	}
+	classes, pages := datasetClasses(s.catalog.archiveEntries(), semester)
+	if len(pages) == 0 {
+		writeError(w, r, codeCatalogNotCached)
+		return
+	}
+	manifest, err := exportDataset(datasetDir, semester, classes, pages, time.Now())
+	if err != nil {
+		log.Printf("dataset export failed semester=%s: %v", semester, err)
+		writeInternalError(w, r)
+		return
+	}
+	log.Printf("dataset exported semester=%s version=%d classes=%d pages=%d", semester, manifest.Version, manifest.Classes, len(manifest.Pages))
+	writeCreated(w, manifest)
+}
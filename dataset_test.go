@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportDataset(t *testing.T) {
+	oldToken, oldDir := adminToken, datasetDir
+	adminToken, datasetDir = "secret", t.TempDir()
+	t.Cleanup(func() { adminToken, datasetDir = oldToken, oldDir })
+
+	srv := newTestServer("")
+	fetchedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	srv.catalog.set("2025-2?fakultas=FMIPA&prodi=102", parseClasses(docFromHTML(testScheduleHTML)), fetchedAt)
+	// The same class seen again on another prodi page, and another semester.
+	srv.catalog.set("2025-2?fakultas=FMIPA&prodi=103", []CourseClass{{Code: "FI1210", ClassNo: "01", Lecturers: []string{"Dosen A"}}}, fetchedAt)
+	srv.catalog.set("2025-1?fakultas=FMIPA&prodi=102", []CourseClass{{Code: "FI9999", ClassNo: "01"}}, fetchedAt)
+
+	w := adminRequest(srv, "POST", "/api/admin/dataset/export", []byte(`{"semester":"2025-2"}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	manifest := decodeData[DatasetManifest](t, w)
+	if manifest.Version != 1 || manifest.Classes != 2 || len(manifest.Pages) != 2 || manifest.ParserVersion != parserVersion || len(manifest.Files) != 3 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if !manifest.Pages[0].FetchedAt.Equal(fetchedAt) || manifest.Pages[0].Prodi != "102" {
+		t.Errorf("page = %+v", manifest.Pages[0])
+	}
+
+	dir := filepath.Join(datasetDir, "2025-2", "v1")
+	data, err := os.ReadFile(filepath.Join(dir, datasetClassesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "Dosen") {
+		t.Errorf("lecturer names exported: %s", data)
+	}
+	var classes []DatasetClass
+	if err := json.Unmarshal(data, &classes); err != nil {
+		t.Fatal(err)
+	}
+	if len(classes) != 2 || classes[0].Code != "FI1210" || classes[0].Lecturers != 2 || classes[0].Prodi != "102" || len(classes[0].Meetings) != 2 {
+		t.Errorf("classes = %+v", classes)
+	}
+	meetings, _ := os.ReadFile(filepath.Join(dir, datasetMeetingsCSV))
+	if got := strings.Count(string(meetings), "\n"); got != 4 {
+		t.Errorf("meetings.csv has %d lines, want a header and 3 meetings:\n%s", got, meetings)
+	}
+	if _, err := os.Stat(filepath.Join(dir, datasetManifestJSON)); err != nil {
+		t.Error(err)
+	}
+
+	// Exporting again makes a new version rather than overwriting.
+	w = adminRequest(srv, "POST", "/api/admin/dataset/export", []byte(`{"semester":"2025-2"}`))
+	if got := decodeData[DatasetManifest](t, w); got.Version != 2 {
+		t.Errorf("second export version = %d, want 2", got.Version)
+	}
+
+	if w := adminRequest(srv, "POST", "/api/admin/dataset/export", []byte(`{"semester":"2024-1"}`)); w.Code != http.StatusNotFound {
+		t.Errorf("uncached semester: status %d, want 404", w.Code)
+	}
+	if _, err := os.Stat(filepath.Join(datasetDir, "2024-1", "v1")); err == nil {
+		t.Error("an empty version was written for an uncached semester")
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Deliveries that still fail after every retry land in the dead-letter
+// queue instead of being lost. Admins list them with GET
+// /api/admin/dead-letters and redeliver them once the receiver is back. A
+// redelivery reuses the event's ID and sequence, so receivers that
+// deduplicate on the idempotency key are safe. The queue keeps the newest
+// SIX_DEAD_LETTER_MAX letters in memory, like the subscriptions themselves.
+var deadLetterMax = envInt("SIX_DEAD_LETTER_MAX", 1000)
+
+type DeadLetter struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	// ChannelID is the channel delivered to, or empty for the
+	// subscription's url.
+	ChannelID    string       `json:"channel_id,omitempty"`
+	Event        WebhookEvent `json:"event"`
+	Attempts     int          `json:"attempts"` // including redeliveries
+	Error        string       `json:"error"`    // of the latest attempt
+	FailedAt     time.Time    `json:"failed_at"`
+	Redeliveries int          `json:"redeliveries"`
+}
+
+type deadLetterQueue struct {
+	mu      sync.Mutex
+	letters []*DeadLetter // oldest first
+	dropped int           // letters pushed out by deadLetterMax
+}
+
+// Stores a delivery that failed for good, dropping the oldest letter when
+// the queue is full.
+func (q *deadLetterQueue) add(subID, channelID string, event WebhookEvent, attempts int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, &DeadLetter{
+		ID:             randomHex(8),
+		SubscriptionID: subID,
+		ChannelID:      channelID,
+		Event:          event,
+		Attempts:       attempts,
+		Error:          err.Error(),
+		FailedAt:       time.Now(),
+	})
+	if over := len(q.letters) - max(deadLetterMax, 1); over > 0 {
+		q.dropped += over
+		q.letters = slices.Delete(q.letters, 0, over)
+		log.Printf("dead letters dropped=%d total_dropped=%d", over, q.dropped)
+	}
+	log.Printf("dead letter subscription=%s channel=%s event=%s err=%v", subID, channelID, event.ID, err)
+}
+
+// Returns copies of the letters of subscription subID, or of all if it is
+// empty, oldest first.
+func (q *deadLetterQueue) list(subID string) []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := []DeadLetter{}
+	for _, dl := range q.letters {
+		if subID == "" || dl.SubscriptionID == subID {
+			list = append(list, *dl)
+		}
+	}
+	return list
+}
+
+func (q *deadLetterQueue) get(id string) (DeadLetter, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, dl := range q.letters {
+		if dl.ID == id {
+			return *dl, true
+		}
+	}
+	return DeadLetter{}, false
+}
+
+func (q *deadLetterQueue) remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.letters)
+	q.letters = slices.DeleteFunc(q.letters, func(dl *DeadLetter) bool { return dl.ID == id })
+	return len(q.letters) < n
+}
+
+// Records a failed redelivery of letter id.
+func (q *deadLetterQueue) failed(id string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, dl := range q.letters {
+		if dl.ID == id {
+			dl.Attempts++
+			dl.Redeliveries++
+			dl.Error = err.Error()
+			dl.FailedAt = time.Now()
+		}
+	}
+}
+
+var errDeadLetterTarget = errors.New("the subscription or channel no longer exists")
+
+// Makes one delivery attempt of a dead letter's event to where it first
+// failed, recording the outcome in the subscription or channel's delivery
+// status. The letter is removed if the attempt succeeds.
+func (s *Server) redeliver(ctx context.Context, dl DeadLetter) error {
+	subscriptionsMu.Lock()
+	var sub Subscription
+	var ch *Channel
+	live, ok := subscriptions[dl.SubscriptionID]
+	if ok {
+		sub = *live
+		for _, c := range live.Channels {
+			if c.ID == dl.ChannelID {
+				ch = c.view()
+			}
+		}
+	}
+	subscriptionsMu.Unlock()
+	if !ok || (dl.ChannelID != "" && ch == nil) || (dl.ChannelID == "" && sub.URL == "") {
+		return errDeadLetterTarget
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	var err error
+	if ch != nil {
+		err = ch.send(ctx, client, sub, dl.Event)
+		recordChannelDelivery(sub.ID, ch.ID, dl.Event, dl.Attempts+1, true, err)
+	} else {
+		var body []byte
+		if body, err = json.Marshal(dl.Event); err != nil {
+			return err
+		}
+		err = postWebhook(ctx, client, sub.URL, sub.Secret, dl.Event.ID, dl.Event.Sequence, "application/json", body)
+		recordDelivery(sub.ID, dl.Event, dl.Attempts+1, true, err)
+	}
+	if err != nil {
+		s.deadLetters.failed(dl.ID, err)
+		log.Printf("redelivery failed dead_letter=%s subscription=%s err=%v", dl.ID, sub.ID, err)
+		return err
+	}
+	s.deadLetters.remove(dl.ID)
+	log.Printf("redelivered dead_letter=%s subscription=%s event=%s", dl.ID, sub.ID, dl.Event.ID)
+	return nil
+}
+
+// GET /api/admin/dead-letters?subscription_id=...
+func (s *Server) listDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	writeSuccess(w, s.deadLetters.list(r.URL.Query().Get("subscription_id")))
+}
+
+// POST /api/admin/dead-letters/{id}/redeliver
+func (s *Server) redeliverDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	dl, ok := s.deadLetters.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, r, codeDeadLetterNotFound)
+		return
+	}
+	if err := s.redeliver(r.Context(), dl); errors.Is(err, errDeadLetterTarget) {
+		writeError(w, r, codeSubscriptionNotFound)
+		return
+	} else if err != nil {
+		writeError(w, r, codeRedeliveryFailed, err.Error())
+		return
+	}
+	writeSuccess(w, map[string]string{"id": dl.ID, "event_id": dl.Event.ID})
+}
+
+type redeliverAllRequest struct {
+	SubscriptionID string `json:"subscription_id"`
+}
+
+// Outcome of POST /api/admin/redeliveries.
+type RedeliveryReport struct {
+	Delivered int      `json:"delivered"`
+	Failed    int      `json:"failed"`
+	Remaining []string `json:"remaining"` // IDs of the letters still queued
+}
+
+// POST /api/admin/redeliveries
+//
+// Redelivers every letter, or every letter of one subscription, oldest
+// first, as after a receiver outage. Letters whose subscription is gone
+// count as failed and stay queued.
+func (s *Server) redeliverAllHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var body redeliverAllRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	report := RedeliveryReport{Remaining: []string{}}
+	for _, dl := range s.deadLetters.list(body.SubscriptionID) {
+		if r.Context().Err() != nil {
+			report.Remaining = append(report.Remaining, dl.ID)
+			continue
+		}
+		if err := s.redeliver(r.Context(), dl); err != nil {
+			report.Failed++
+			report.Remaining = append(report.Remaining, dl.ID)
+			continue
+		}
+		report.Delivered++
+	}
+	writeSuccess(w, report)
+}
+
+// DELETE /api/admin/dead-letters/{id}
+func (s *Server) deleteDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	if !s.deadLetters.remove(id) {
+		writeError(w, r, codeDeadLetterNotFound)
+		return
+	}
+	log.Printf("dead letter deleted id=%s", id)
+	writeSuccess(w, map[string]string{"id": id})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadLetters_FailedDeliveryIsRedelivered(t *testing.T) {
+	setupWebhooks(t)
+	oldToken, oldAttempts := adminToken, webhookMaxAttempts
+	adminToken, webhookMaxAttempts = "secret", 2
+	t.Cleanup(func() { adminToken, webhookMaxAttempts = oldToken, oldAttempts })
+	receiver, received := webhookReceiver(t, 2)
+
+	body := strings.Replace(testSubscriptionBody, "https://example.com/hook", receiver.URL, 1)
+	_, sub := postSubscription(t, body)
+	srv := newWebhookTestServer(t, "")
+	key := schedulePath("123", "1945-1", nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "A"}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "B"}}, time.Now(), nil)
+
+	var letters []DeadLetter
+	waitFor(t, func() bool {
+		letters = decodeData[[]DeadLetter](t, adminRequest(srv, "GET", "/api/admin/dead-letters?subscription_id="+sub.ID, nil))
+		return len(letters) == 1
+	})
+	dl := letters[0]
+	if dl.Attempts != 2 || dl.ChannelID != "" || dl.Event.Sequence != 1 || !strings.Contains(dl.Error, "500") {
+		t.Errorf("dead letter = %+v", dl)
+	}
+	if got := decodeData[[]DeadLetter](t, adminRequest(srv, "GET", "/api/admin/dead-letters?subscription_id=other", nil)); len(got) != 0 {
+		t.Errorf("letters of another subscription = %+v", got)
+	}
+
+	w := adminRequest(srv, "POST", "/api/admin/dead-letters/"+dl.ID+"/redeliver", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("redeliver: got status %d: %s", w.Code, w.Body)
+	}
+	got := received()
+	if len(got) != 3 || got[2].header.Get(webhookIdempotencyHeader) != dl.Event.ID || got[2].header.Get(webhookSequenceHeader) != "1" {
+		t.Errorf("receiver got %d deliveries, last %v", len(got), got[len(got)-1].header)
+	}
+	if letters := decodeData[[]DeadLetter](t, adminRequest(srv, "GET", "/api/admin/dead-letters", nil)); len(letters) != 0 {
+		t.Errorf("letters after redelivery = %+v", letters)
+	}
+	v := decodeData[Subscription](t, subscriptionRequest(t, "GET", "/api/subscriptions/"+sub.ID, "", ""))
+	if v.LastDelivery == nil || !v.LastDelivery.Delivered || v.LastDelivery.Attempts != 3 {
+		t.Errorf("last_delivery = %+v", v.LastDelivery)
+	}
+
+	if w := adminRequest(srv, "POST", "/api/admin/dead-letters/"+dl.ID+"/redeliver", nil); w.Code != http.StatusNotFound {
+		t.Errorf("redeliver again: got status %d, want 404", w.Code)
+	}
+}
+
+func TestDeadLetters_RedeliverAll(t *testing.T) {
+	setupWebhooks(t)
+	oldToken := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = oldToken })
+	receiver, received := webhookReceiver(t, 1)
+	srv := newWebhookTestServer(t, "")
+
+	body := strings.Replace(testSubscriptionBody, "https://example.com/hook", receiver.URL, 1)
+	_, sub := postSubscription(t, body)
+	for i := range 3 {
+		srv.deadLetters.add(sub.ID, "", WebhookEvent{ID: "e" + string(rune('1'+i)), Sequence: int64(i + 1)}, 5, http.ErrHandlerTimeout)
+	}
+	srv.deadLetters.add("gone", "", WebhookEvent{ID: "e9"}, 5, http.ErrHandlerTimeout)
+
+	w := adminRequest(srv, "POST", "/api/admin/redeliveries", []byte(`{}`))
+	report := decodeData[RedeliveryReport](t, w)
+	// The first redelivery hits the receiver's failure; the letter of the
+	// deleted subscription cannot be delivered.
+	if report.Delivered != 2 || report.Failed != 2 || len(report.Remaining) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	if n := len(received()); n != 3 {
+		t.Errorf("receiver got %d deliveries, want 3", n)
+	}
+	letters := srv.deadLetters.list("")
+	if len(letters) != 2 || letters[0].Redeliveries != 1 || letters[0].Attempts != 6 {
+		t.Errorf("letters = %+v", letters)
+	}
+
+	if w := adminRequest(srv, "DELETE", "/api/admin/dead-letters/"+letters[1].ID, nil); w.Code != http.StatusOK {
+		t.Errorf("delete: got status %d", w.Code)
+	}
+	if n := len(srv.deadLetters.list("")); n != 1 {
+		t.Errorf("%d letters after delete, want 1", n)
+	}
+}
+
+func TestDeadLetters_KeepsNewest(t *testing.T) {
+	setupWebhooks(t)
+	old := deadLetterMax
+	deadLetterMax = 2
+	t.Cleanup(func() { deadLetterMax = old })
+	srv := newTestServer("")
+	for _, id := range []string{"a", "b", "c"} {
+		srv.deadLetters.add("s", "", WebhookEvent{ID: id}, 1, http.ErrHandlerTimeout)
+	}
+	letters := srv.deadLetters.list("")
+	if len(letters) != 2 || letters[0].Event.ID != "b" || srv.deadLetters.dropped != 1 {
+		t.Errorf("letters = %+v, dropped %d", letters, srv.deadLetters.dropped)
+	}
+}
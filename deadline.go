@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Subscriptions may hold deliveries back. During quiet hours, e.g.
+// "22:00-07:00" WIB, changes are held and delivered as one event when the
+// quiet hours end. With a deadline, such as when FRS closes, changes are
+// batched into digests that come more often as the deadline nears, each
+// at most a deadlineEscalation-th of the time that was left at the last
+// delivery, and a schedule.summary event with the schedule as it stands is
+// sent at the deadline, quiet hours or not.
+const (
+	deadlineEscalation = 4
+	maxDigestGap       = 24 * time.Hour
+	// How often held deliveries and deadlines are checked.
+	digestTick = time.Minute
+)
+
+// A daily window of WIB time, in minutes after midnight. It wraps past
+// midnight when end is before start.
+type quietHours struct {
+	start, end int
+}
+
+// Parses "22:00-07:00".
+func parseQuietHours(spec string) (*quietHours, error) {
+	from, to, ok := parseClockRange(spec)
+	if !ok || from == to {
+		return nil, fmt.Errorf("quiet_hours must look like 22:00-07:00")
+	}
+	return &quietHours{start: from, end: to}, nil
+}
+
+func parseClockRange(spec string) (from, to int, ok bool) {
+	var h1, m1, h2, m2 int
+	if n, _ := fmt.Sscanf(spec, "%d:%d-%d:%d", &h1, &m1, &h2, &m2); n != 4 {
+		return 0, 0, false
+	}
+	if h1 > 23 || h2 > 23 || m1 > 59 || m2 > 59 || h1 < 0 || h2 < 0 || m1 < 0 || m2 < 0 {
+		return 0, 0, false
+	}
+	return h1*60 + m1, h2*60 + m2, true
+}
+
+func minuteOfDay(t time.Time) int {
+	t = t.In(wib)
+	return t.Hour()*60 + t.Minute()
+}
+
+func (q quietHours) contains(t time.Time) bool {
+	m := minuteOfDay(t)
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// Returns when the quiet hours that contain t end.
+func (q quietHours) endAfter(t time.Time) time.Time {
+	t = t.In(wib)
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, wib)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// Returns when a change at now may be delivered to sub: now, or later while
+// its quiet hours or deadline digest hold it back. Callers must hold
+// subscriptionsMu.
+func (sub *Subscription) releaseAt(now time.Time) time.Time {
+	at := now
+	if d := sub.Deadline; d != nil && now.Before(*d) && !sub.lastSentAt.IsZero() {
+		gap := min(d.Sub(sub.lastSentAt)/deadlineEscalation, maxDigestGap)
+		if next := sub.lastSentAt.Add(gap); next.After(at) {
+			at = next
+		}
+	}
+	if sub.quiet != nil && sub.quiet.contains(at) {
+		at = sub.quiet.endAfter(at)
+	}
+	return at
+}
+
+// Holds event for a later delivery, merged with any event already held.
+// Callers must hold subscriptionsMu.
+func (sub *Subscription) hold(event WebhookEvent, now time.Time) {
+	if sub.held == nil {
+		sub.held = &event
+		sub.HeldSince = &now
+		return
+	}
+	removed := append(sub.held.Removed, event.Removed...)
+	*sub.held = event
+	sub.held.Removed = removed
+}
+
+// Numbers event and delivers it to sub's url and every channel in the
+// background, tracked in s.deliveries. Callers must hold subscriptionsMu.
+func (s *Server) sendLocked(sub *Subscription, event WebhookEvent, now time.Time) {
+	sub.sequence++
+	event.ID = randomHex(16)
+	event.Sequence = sub.sequence
+	sub.lastSentAt = now
+	// Copied now, while the lock is held.
+	snapshot := *sub
+	if sub.URL != "" {
+		s.deliveries.Go(func() { s.deliverWebhook(context.Background(), snapshot, event) })
+	}
+	for _, ch := range sub.Channels {
+		channel := *ch
+		s.deliveries.Go(func() { s.deliverChannel(context.Background(), snapshot, channel, event) })
+	}
+}
+
+// Runs flushHeld every digestTick until ctx is done.
+func (s *Server) runDigestScheduler(ctx context.Context) {
+	ticker := time.NewTicker(digestTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.flushHeld(now)
+		}
+	}
+}
+
+// Delivers the held changes that are due at now, and the summary of every
+// subscription whose deadline has passed.
+func (s *Server) flushHeld(now time.Time) {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	for _, sub := range subscriptions {
+		if sub.Deadline != nil && sub.SummarySentAt == nil && !now.Before(*sub.Deadline) {
+			sub.SummarySentAt = &now
+			if !sub.Paused {
+				s.sendSummaryLocked(sub, now)
+			}
+			continue
+		}
+		if sub.held == nil || sub.Paused || now.Before(sub.releaseAt(now)) {
+			continue
+		}
+		event := *sub.held
+		sub.held, sub.HeldSince = nil, nil
+		s.sendLocked(sub, event, now)
+	}
+}
+
+// Sends the schedule.summary event at sub's deadline: the schedule as last
+// fetched, with the classes removed in any changes still held. Callers must
+// hold subscriptionsMu.
+func (s *Server) sendSummaryLocked(sub *Subscription, now time.Time) {
+	event := WebhookEvent{
+		Type:       "schedule.summary",
+		OccurredAt: now,
+		StudentID:  sub.StudentID,
+		Semester:   sub.Semester,
+		Classes:    []CourseClass{},
+	}
+	if snap, ok := s.lastGood.get(sub.key); ok && snap.Classes != nil {
+		event.Classes = snap.Classes
+	}
+	if sub.held != nil {
+		event.Removed = sub.held.Removed
+		sub.held, sub.HeldSince = nil, nil
+	}
+	s.sendLocked(sub, event, now)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	for _, spec := range []string{"", "22:00", "25:00-07:00", "22:00-22:00", "ab:cd-07:00"} {
+		if _, err := parseQuietHours(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+	q, err := parseQuietHours("22:00-07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(h, m int) time.Time { return time.Date(2026, 1, 14, h, m, 0, 0, wib) }
+	for _, tt := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{day(21, 59), false},
+		{day(22, 0), true},
+		{day(3, 0), true},
+		{day(7, 0), false},
+	} {
+		if got := q.contains(tt.at); got != tt.want {
+			t.Errorf("contains %s: got %v, want %v", tt.at.Format("15:04"), got, tt.want)
+		}
+	}
+	if got, want := q.endAfter(day(23, 0)), time.Date(2026, 1, 15, 7, 0, 0, 0, wib); !got.Equal(want) {
+		t.Errorf("end after 23:00: got %s, want %s", got, want)
+	}
+	if got, want := q.endAfter(day(3, 0)), day(7, 0); !got.Equal(want) {
+		t.Errorf("end after 03:00: got %s, want %s", got, want)
+	}
+}
+
+func TestSubscription_ReleaseAtEscalatesTowardsDeadline(t *testing.T) {
+	deadline := time.Date(2026, 1, 16, 16, 0, 0, 0, wib)
+	sub := &Subscription{Deadline: &deadline}
+	now := deadline.Add(-8 * 24 * time.Hour)
+	if got := sub.releaseAt(now); !got.Equal(now) {
+		t.Errorf("nothing sent yet: release at %s, want now", got)
+	}
+
+	// Each digest waits a quarter of the time left at the last delivery,
+	// and never more than a day.
+	for _, tt := range []struct {
+		before, gap time.Duration
+	}{
+		{8 * 24 * time.Hour, 24 * time.Hour},
+		{48 * time.Hour, 12 * time.Hour},
+		{4 * time.Hour, time.Hour},
+		{20 * time.Minute, 5 * time.Minute},
+	} {
+		sub.lastSentAt = deadline.Add(-tt.before)
+		if got := sub.releaseAt(sub.lastSentAt).Sub(sub.lastSentAt); got != tt.gap {
+			t.Errorf("%s before the deadline: gap %s, want %s", tt.before, got, tt.gap)
+		}
+	}
+
+	// Quiet hours push a digest to their end.
+	sub.quiet, _ = parseQuietHours("22:00-07:00")
+	sub.lastSentAt = deadline.Add(-48 * time.Hour) // digest due at 04:00 on the 15th
+	if got, want := sub.releaseAt(sub.lastSentAt), time.Date(2026, 1, 15, 7, 0, 0, 0, wib); !got.Equal(want) {
+		t.Errorf("digest in quiet hours: release at %s, want %s", got, want)
+	}
+}
+
+func TestSubscription_QuietHoursHoldChanges(t *testing.T) {
+	setupWebhooks(t)
+	srv := newWebhookTestServer(t, "")
+	receiver, received := webhookReceiver(t, 0)
+
+	now := time.Now().In(wib)
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	quiet := from.Format("15:04") + "-" + to.Format("15:04")
+	w, sub := postSubscription(t, `{"url":"`+receiver.URL+`","student_id":"123","semester":"1945-1","quiet_hours":"`+quiet+`"}`)
+	if w.Code != http.StatusCreated || sub.QuietHours != quiet {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	key := schedulePath("123", "1945-1", nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "FI1210", Quota: 40}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "FI1210", Quota: 50}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "FI1210", Quota: 45}}, time.Now(), nil)
+	srv.flushHeld(time.Now())
+	time.Sleep(20 * time.Millisecond)
+	if n := len(received()); n != 0 {
+		t.Fatalf("%d deliveries during quiet hours, want 0", n)
+	}
+	subscriptionsMu.Lock()
+	held := subscriptions[sub.ID].HeldSince != nil
+	subscriptionsMu.Unlock()
+	if !held {
+		t.Error("held_since not set")
+	}
+
+	srv.flushHeld(to.Add(time.Minute))
+	waitFor(t, func() bool { return len(received()) == 1 })
+	var event WebhookEvent
+	if err := json.Unmarshal(received()[0].body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "schedule.changed" || event.Sequence != 1 || len(event.Classes) != 1 || event.Classes[0].Quota != 45 {
+		t.Errorf("held delivery = %+v, want the latest schedule", event)
+	}
+}
+
+func TestSubscription_DeadlineSummary(t *testing.T) {
+	setupWebhooks(t)
+	srv := newWebhookTestServer(t, "")
+	receiver, received := webhookReceiver(t, 0)
+
+	if w, _ := postSubscription(t, `{"url":"`+receiver.URL+`","student_id":"123","semester":"1945-1","deadline":"2000-01-01T00:00:00Z"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("past deadline: got status %d, want 422", w.Code)
+	}
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	w, sub := postSubscription(t, `{"url":"`+receiver.URL+`","student_id":"123","semester":"1945-1","deadline":"`+deadline.Format(time.RFC3339)+`"}`)
+	if w.Code != http.StatusCreated || sub.Deadline == nil || !sub.Deadline.Equal(deadline) {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	key := schedulePath("123", "1945-1", nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "FI1210", Quota: 40}}, time.Now(), nil)
+	srv.flushHeld(deadline.Add(-time.Minute))
+	srv.flushHeld(deadline)
+	srv.flushHeld(deadline.Add(time.Minute)) // the summary is sent once
+	waitFor(t, func() bool { return len(received()) == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if n := len(received()); n != 1 {
+		t.Fatalf("%d deliveries, want 1", n)
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(received()[0].body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "schedule.summary" || len(event.Classes) != 1 || event.Classes[0].Code != "FI1210" {
+		t.Errorf("summary = %+v", event)
+	}
+}
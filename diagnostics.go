@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"time"
+)
+
+// GET /api/admin/diagnostics gathers what is needed to debug a stuck or
+// misbehaving instance into one JSON document to attach to a bug report,
+// like the goroutine dump of a SIGQUIT but with the server's own state next
+// to it. It holds no cookies, secrets, or cached classes, but it does list
+// student IDs of grade watches.
+
+type Diagnostics struct {
+	GeneratedAt time.Time             `json:"generated_at"`
+	Runtime     RuntimeDiagnostics    `json:"runtime"`
+	Caches      map[string]CacheStats `json:"caches"`
+	Breakers    BreakerDiagnostics    `json:"breakers"`
+	Queues      QueueDiagnostics      `json:"queues"`
+	Watchers    []WatcherDiagnostic   `json:"watchers"`
+	Jobs        []Job                 `json:"jobs"`
+	// Goroutines is the stack of every goroutine, as a SIGQUIT prints it.
+	Goroutines string `json:"goroutines"`
+}
+
+type RuntimeDiagnostics struct {
+	GoVersion  string `json:"go_version"`
+	NumCPU     int    `json:"num_cpu"`
+	Goroutines int    `json:"goroutines"`
+	HeapBytes  uint64 `json:"heap_bytes"`
+}
+
+type CacheStats struct {
+	Entries               int    `json:"entries"`
+	Expired               int    `json:"expired"` // still held, e.g. for stale-while-revalidate
+	TTL                   string `json:"ttl"`
+	ExpiringPeakPerMinute int    `json:"expiring_peak_per_minute"`
+}
+
+// The server's circuit breakers: when they are open, requests do not go to
+// the upstream they guard.
+type BreakerDiagnostics struct {
+	// Maintenance is the manual switch; while on, nothing goes to SIX.
+	Maintenance MaintenanceState `json:"maintenance"`
+	// DetectedMaintenance opens after SIX serves its maintenance page and
+	// closes at retry_at.
+	DetectedMaintenance DetectedMaintenance `json:"detected_maintenance"`
+	// OfficialAPI lists the data types the official API was found not to
+	// offer, and since when; they are scraped until rechecked.
+	OfficialAPI map[string]time.Time `json:"official_api,omitempty"`
+}
+
+type QueueDiagnostics struct {
+	Upstream          UpstreamQueueStats `json:"upstream"`
+	ExpensiveInflight int64              `json:"expensive_inflight"` // requests admitted by the load shedder
+	Refresher         RefresherStatus    `json:"refresher"`
+	Mirror            MirrorStatus       `json:"mirror"`
+}
+
+type UpstreamQueueStats struct {
+	Slots              int `json:"slots"`
+	Active             int `json:"active"`
+	WaitingInteractive int `json:"waiting_interactive"`
+	WaitingBatch       int `json:"waiting_batch"`
+}
+
+type WatcherDiagnostic struct {
+	Kind          string     `json:"kind"`
+	ID            string     `json:"id"`
+	StudentID     string     `json:"student_id"`
+	Target        string     `json:"target"` // host notified
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+func (c *scheduleCache) stats(now time.Time) CacheStats {
+	c.mu.RLock()
+	st := CacheStats{Entries: len(c.entries), TTL: c.ttl.String()}
+	for _, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			st.Expired++
+		}
+	}
+	c.mu.RUnlock()
+	st.ExpiringPeakPerMinute = c.expiryPeak(now)
+	return st
+}
+
+func (q *upstreamQueue) stats() UpstreamQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return UpstreamQueueStats{
+		Slots:              q.slots,
+		Active:             q.active,
+		WaitingInteractive: len(q.waiting[priorityInteractive]),
+		WaitingBatch:       len(q.waiting[priorityBatch]),
+	}
+}
+
+// Returns when each data type was last found unsupported by the API.
+func (p *fallbackProvider) unsupportedSince() map[string]time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]time.Time, len(p.unsupported))
+	for capability, since := range p.unsupported {
+		out[capability] = since
+	}
+	return out
+}
+
+func (s *Server) watcherDiagnostics() []WatcherDiagnostic {
+	s.gradeWatches.mu.Lock()
+	defer s.gradeWatches.mu.Unlock()
+	out := []WatcherDiagnostic{}
+	for _, gw := range s.gradeWatches.watches {
+		// Only the host: webhook URLs often carry tokens.
+		target := ""
+		if u, err := url.Parse(gw.URL); err == nil {
+			target = u.Host
+		}
+		out = append(out, WatcherDiagnostic{Kind: "grade_watch", ID: gw.ID, StudentID: gw.StudentID, Target: target, LastCheckedAt: gw.LastCheckedAt, LastError: gw.LastError})
+	}
+	slices.SortFunc(out, func(a, b WatcherDiagnostic) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+func (s *Server) diagnostics(now time.Time) Diagnostics {
+	d := Diagnostics{
+		GeneratedAt: now,
+		Runtime: RuntimeDiagnostics{
+			GoVersion:  runtime.Version(),
+			NumCPU:     runtime.NumCPU(),
+			Goroutines: runtime.NumGoroutine(),
+			HeapBytes:  heapBytes(),
+		},
+		Caches: map[string]CacheStats{
+			"schedule": s.cache.stats(now),
+			"catalog":  s.catalog.stats(now),
+		},
+		Breakers: BreakerDiagnostics{
+			Maintenance:         currentMaintenance(),
+			DetectedMaintenance: currentDetectedMaintenance(),
+		},
+		Queues: QueueDiagnostics{
+			Upstream:          upstream.stats(),
+			ExpensiveInflight: expensiveInflight.Load(),
+			Refresher:         s.refresher.status(),
+			Mirror:            s.mirror.status(),
+		},
+		Watchers: s.watcherDiagnostics(),
+		Jobs:     s.jobs(now),
+	}
+	if fp, ok := s.provider.(*fallbackProvider); ok {
+		d.Breakers.OfficialAPI = fp.unsupportedSince()
+	}
+	var stacks bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&stacks, 2)
+	d.Goroutines = stacks.String()
+	return d
+}
+
+// GET /api/admin/diagnostics
+func (s *Server) diagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	now := time.Now()
+	w.Header().Set("Content-Disposition", `attachment; filename="diagnostics-`+now.In(wib).Format("20060102-150405")+`.json"`)
+	writeSuccess(w, s.diagnostics(now))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnostics(t *testing.T) {
+	old := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = old })
+
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	srv.gradeWatches.watches["w1"] = &GradeWatch{ID: "w1", StudentID: "123", URL: "https://hooks.example.com/notify?token=abc"}
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1", nil)
+	addAuthCookies(req)
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/diagnostics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status %d, want 401", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/admin/diagnostics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "token=abc") {
+		t.Error("diagnostics leak the webhook URL")
+	}
+	d := decodeData[Diagnostics](t, w)
+	if d.Runtime.Goroutines == 0 || !strings.Contains(d.Goroutines, "goroutine ") {
+		t.Errorf("runtime = %+v, stacks %d bytes", d.Runtime, len(d.Goroutines))
+	}
+	if st := d.Caches["schedule"]; st.Entries != 1 || st.Expired != 0 {
+		t.Errorf("schedule cache = %+v", st)
+	}
+	if d.Queues.Upstream.Slots == 0 || d.Queues.Upstream.Active != 0 {
+		t.Errorf("upstream queue = %+v", d.Queues.Upstream)
+	}
+	if len(d.Watchers) != 1 || d.Watchers[0].Target != "hooks.example.com" {
+		t.Errorf("watchers = %+v", d.Watchers)
+	}
+	if len(d.Jobs) == 0 {
+		t.Error("no jobs listed")
+	}
+	if d.Breakers.Maintenance.Enabled || d.GeneratedAt.After(time.Now()) {
+		t.Errorf("breakers = %+v", d.Breakers)
+	}
+}
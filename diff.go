@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The changes between two versions of a schedule, matched by course code and
+// class number.
+type ScheduleDiff struct {
+	StudentID string         `json:"student_id"`
+	Semester  string         `json:"semester"`
+	Since     time.Time      `json:"since"` // when the earlier version was fetched
+	Added     []CourseClass  `json:"added"`
+	Removed   []RemovedClass `json:"removed"`
+	Changed   []ClassChange  `json:"changed"`
+}
+
+// A tombstone for a class that disappeared from a schedule, because it was
+// cancelled or hidden. LastSeenAt is the last fetch that still listed it,
+// RemovedAt the first that did not.
+type RemovedClass struct {
+	CourseClass
+	Status     string    `json:"status"` // always "removed"
+	LastSeenAt time.Time `json:"last_seen_at"`
+	RemovedAt  time.Time `json:"removed_at"`
+}
+
+func tombstones(classes []CourseClass, lastSeen, removedAt time.Time) []RemovedClass {
+	out := make([]RemovedClass, len(classes))
+	for i, c := range classes {
+		out[i] = RemovedClass{CourseClass: c, Status: "removed", LastSeenAt: lastSeen, RemovedAt: removedAt}
+	}
+	return out
+}
+
+type ClassChange struct {
+	Code    string        `json:"code"`
+	ClassNo string        `json:"class_no"`
+	Name    string        `json:"name"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// A changed field. Meeting fields of classes with several meetings are
+// prefixed with the meeting number, e.g. "meeting 2 room".
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+func (d ScheduleDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Identifies a class within a schedule, e.g. "IF2211/01". Classes from a
+// practicum or other non-lecture table have their category appended, e.g.
+// "IF2211/01/praktikum", so they do not collide with the lecture class of the
+// same number.
+func classKey(c CourseClass) string {
+	if c.Category == "" || c.Category == "kuliah" {
+		return c.Code + "/" + c.ClassNo
+	}
+	return c.Code + "/" + c.ClassNo + "/" + c.Category
+}
+
+// Compares two versions of a schedule.
+func diffSchedules(before, after []CourseClass) (added, removed []CourseClass, changed []ClassChange) {
+	key := classKey
+	old := make(map[string]CourseClass, len(before))
+	for _, c := range before {
+		old[key(c)] = c
+	}
+	seen := make(map[string]bool, len(after))
+	added, removed, changed = []CourseClass{}, []CourseClass{}, []ClassChange{}
+	for _, c := range after {
+		seen[key(c)] = true
+		prev, ok := old[key(c)]
+		if !ok {
+			added = append(added, c)
+			continue
+		}
+		if changes := classChanges(prev, c); len(changes) > 0 {
+			changed = append(changed, ClassChange{Code: c.Code, ClassNo: c.ClassNo, Name: c.Name, Changes: changes})
+		}
+	}
+	for _, c := range before {
+		if !seen[key(c)] {
+			removed = append(removed, c)
+		}
+	}
+	byClass := func(a, b CourseClass) int {
+		return cmp.Or(cmp.Compare(a.Code, b.Code), cmp.Compare(a.ClassNo, b.ClassNo))
+	}
+	slices.SortFunc(added, byClass)
+	slices.SortFunc(removed, byClass)
+	slices.SortFunc(changed, func(a, b ClassChange) int {
+		return cmp.Or(cmp.Compare(a.Code, b.Code), cmp.Compare(a.ClassNo, b.ClassNo))
+	})
+	return added, removed, changed
+}
+
+func classChanges(a, b CourseClass) []FieldChange {
+	var changes []FieldChange
+	field := func(name, from, to string) {
+		if from != to {
+			changes = append(changes, FieldChange{Field: name, From: from, To: to})
+		}
+	}
+	field("name", a.Name, b.Name)
+	field("sks", strconv.Itoa(a.SKS), strconv.Itoa(b.SKS))
+	field("quota", strconv.Itoa(a.Quota), strconv.Itoa(b.Quota))
+	field("lecturers", strings.Join(a.Lecturers, ", "), strings.Join(b.Lecturers, ", "))
+	field("notes", a.Notes, b.Notes)
+
+	if len(a.Schedules) != len(b.Schedules) {
+		field("schedule", meetingsString(a.Schedules), meetingsString(b.Schedules))
+		return changes
+	}
+	for i, ma := range a.Schedules {
+		mb := b.Schedules[i]
+		prefix := ""
+		if len(a.Schedules) > 1 {
+			prefix = fmt.Sprintf("meeting %d ", i+1)
+		}
+		field(prefix+"day", ma.Day, mb.Day)
+		field(prefix+"time", ma.Time, mb.Time)
+		field(prefix+"room", ma.Room, mb.Room)
+		field(prefix+"activity", ma.Activity, mb.Activity)
+		field(prefix+"method", ma.Method, mb.Method)
+	}
+	return changes
+}
+
+func meetingsString(meetings []ScheduleEntry) string {
+	parts := make([]string, len(meetings))
+	for i, m := range meetings {
+		parts[i] = strings.TrimSpace(m.Day + " " + m.Time + " " + m.Room)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Renders a diff as a short Markdown changelog, one line per class, e.g.
+// "FI2101 class 02: room 7602 → 9121; quota 40 → 50".
+func diffMarkdown(d ScheduleDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Schedule changes, %s** (since %s)\n\n", d.Semester, d.Since.In(wib).Format("2 Jan 2006 15:04 WIB"))
+	if d.empty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	for _, c := range d.Changed {
+		parts := make([]string, len(c.Changes))
+		for i, f := range c.Changes {
+			parts[i] = fmt.Sprintf("%s %s → %s", f.Field, orDash(f.From), orDash(f.To))
+		}
+		fmt.Fprintf(&b, "- %s class %s: %s\n", c.Code, c.ClassNo, strings.Join(parts, "; "))
+	}
+	for _, c := range d.Added {
+		fmt.Fprintf(&b, "- New: %s class %s (%s)\n", c.Code, c.ClassNo, c.Name)
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(&b, "- Removed: %s class %s (%s)\n", c.Code, c.ClassNo, c.Name)
+	}
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "–"
+	}
+	return s
+}
+
+// GET /api/schedule/diff
+//
+// Fetches the schedule fresh and compares it with its last good snapshot, so
+// it reports what changed since the schedule was last fetched.
+func (s *Server) diffHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	before, ok := s.lastGood.get(schedulePath(studentID, semester, query))
+	if !ok {
+		writeError(w, r, codeSnapshotNotFound)
+		return
+	}
+
+	query.Set("refresh", "true")
+	after, meta, ok := s.schedule(w, r, studentID, semester, query)
+	if !ok {
+		return
+	}
+	if relative {
+		meta.Semester = semester
+	}
+	d := ScheduleDiff{StudentID: studentID, Semester: semester, Since: before.FetchedAt}
+	added, removed, changed := diffSchedules(before.Classes, after)
+	d.Added, d.Removed, d.Changed = added, tombstones(removed, before.FetchedAt, meta.FetchedAt), changed
+
+	if query.Get("format") == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		fmt.Fprint(w, diffMarkdown(d))
+		return
+	}
+	writeSuccessWithMeta(w, d, meta)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiffSchedules(t *testing.T) {
+	before := []CourseClass{
+		{Code: "FI2101", ClassNo: "02", Name: "Fisika", Quota: 40, Schedules: []ScheduleEntry{{Day: "Senin", Time: "07:00-09:00", Room: "7602"}}},
+		{Code: "IF2211", ClassNo: "01", Name: "Strategi Algoritma"},
+		{Code: "MA1101", ClassNo: "01", Schedules: []ScheduleEntry{{Day: "Senin"}, {Day: "Rabu", Room: "9009"}}},
+	}
+	after := []CourseClass{
+		{Code: "FI2101", ClassNo: "02", Name: "Fisika", Quota: 50, Schedules: []ScheduleEntry{{Day: "Senin", Time: "07:00-09:00", Room: "9121"}}},
+		{Code: "IF2230", ClassNo: "01", Name: "Sistem Operasi"},
+		{Code: "MA1101", ClassNo: "01", Schedules: []ScheduleEntry{{Day: "Senin"}, {Day: "Rabu", Room: "9010"}}},
+	}
+	added, removed, changed := diffSchedules(before, after)
+	if len(added) != 1 || added[0].Code != "IF2230" || len(removed) != 1 || removed[0].Code != "IF2211" {
+		t.Errorf("added %v, removed %v", added, removed)
+	}
+	want := "[{FI2101 02 Fisika [{quota 40 50} {room 7602 9121}]} {MA1101 01  [{meeting 2 room 9009 9010}]}]"
+	if got := fmt.Sprint(changed); got != want {
+		t.Errorf("changed = %s, want %s", got, want)
+	}
+
+	d := ScheduleDiff{Semester: "2025-2", Since: time.Date(2025, 2, 1, 3, 0, 0, 0, time.UTC), Added: added, Removed: tombstones(removed, time.Time{}, time.Time{}), Changed: changed}
+	md := diffMarkdown(d)
+	for _, line := range []string{
+		"**Schedule changes, 2025-2** (since 1 Feb 2025 10:00 WIB)",
+		"- FI2101 class 02: quota 40 → 50; room 7602 → 9121",
+		"- MA1101 class 01: meeting 2 room 9009 → 9010",
+		"- New: IF2230 class 01 (Sistem Operasi)",
+		"- Removed: IF2211 class 01 (Strategi Algoritma)",
+	} {
+		if !strings.Contains(md, line+"\n") {
+			t.Errorf("markdown lacks %q:\n%s", line, md)
+		}
+	}
+}
+
+func TestDiffHandler(t *testing.T) {
+	var moved atomic.Bool
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		html := testScheduleHTML
+		if moved.Load() {
+			html = strings.Replace(html, "09:00-11:00 / 7604", "09:00-11:00 / 9121", 1)
+		}
+		fmt.Fprint(w, html)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	const query = "?student_id=123&semester=1945-1"
+	if w := get("/api/schedule/diff" + query); w.Code != http.StatusNotFound {
+		t.Errorf("without a snapshot: status %d, want 404", w.Code)
+	}
+	get("/api/schedule" + query)
+	moved.Store(true)
+
+	w := get("/api/schedule/diff" + query + "&format=markdown")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/markdown; charset=utf-8" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "- FI1220 class 02: room 7604 → 9121\n") {
+		t.Errorf("markdown:\n%s", w.Body)
+	}
+
+	// The fresh fetch became the new snapshot, so nothing changed since.
+	d := decodeData[ScheduleDiff](t, get("/api/schedule/diff"+query))
+	if !d.empty() {
+		t.Errorf("second diff = %+v, want no changes", d)
+	}
+}
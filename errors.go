@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Stable, machine-readable error codes returned in the "code" field of error
+// responses. Clients should branch on these, not on the localized message.
+type errorCode string
+
+const (
+	codeAdminDisabled        errorCode = "admin_disabled"
+	codeAdminUnauthorized    errorCode = "admin_unauthorized"
+	codeAPIKeyInvalid        errorCode = "api_key_invalid"
+	codeAPIKeysDisabled      errorCode = "api_keys_disabled"
+	codeArchiveDisabled      errorCode = "archive_disabled"
+	codeArchiveSignature     errorCode = "archive_signature_invalid"
+	codeBudgetExhausted      errorCode = "budget_exhausted"
+	codeBundleNotFound       errorCode = "bundle_not_found"
+	codeCatalogNotCached     errorCode = "catalog_not_cached"
+	codeChatDisabled         errorCode = "chat_disabled"
+	codeChatSignature        errorCode = "chat_signature_invalid"
+	codeClassNotFound        errorCode = "class_not_found"
+	codeConsentNotFound      errorCode = "consent_not_found"
+	codeCrawlerDisabled      errorCode = "crawler_disabled"
+	codeCrawlRunning         errorCode = "crawl_running"
+	codeDatasetDisabled      errorCode = "dataset_disabled"
+	codeDeadLetterNotFound   errorCode = "dead_letter_not_found"
+	codeDeepCheckFailed      errorCode = "deep_check_failed"
+	codeForbidden            errorCode = "forbidden"
+	codeGradeWatchDisabled   errorCode = "grade_watch_disabled"
+	codeGradeWatchNotFound   errorCode = "grade_watch_not_found"
+	codeInternal             errorCode = "internal_error"
+	codeInvalidArchive       errorCode = "invalid_archive"
+	codeInvalidJSON          errorCode = "invalid_json"
+	codeInvalidMirrorTarget  errorCode = "invalid_mirror_target"
+	codeInvalidRequest       errorCode = "invalid_request"
+	codeInvalidWebhookURL    errorCode = "invalid_webhook_url"
+	codeJobNotFound          errorCode = "job_not_found"
+	codeMaintenance          errorCode = "maintenance"
+	codeMethodNotAllowed     errorCode = "method_not_allowed"
+	codeMissingCookie        errorCode = "missing_cookie"
+	codeNoBuildings          errorCode = "buildings_not_configured"
+	codeNotEnrolled          errorCode = "not_enrolled"
+	codeOrgNotFound          errorCode = "org_not_found"
+	codeOrgsDisabled         errorCode = "orgs_disabled"
+	codeProfileNotFound      errorCode = "profile_not_found"
+	codeRedactedFormat       errorCode = "redacted_format"
+	codeRedeliveryFailed     errorCode = "redelivery_failed"
+	codeSemesterNotFound     errorCode = "semester_not_found"
+	codeServerBusy           errorCode = "server_busy"
+	codeSnapshotNotFound     errorCode = "snapshot_not_found"
+	codeStudentIDNotFound    errorCode = "student_id_not_found"
+	codeSubscriptionNotFound errorCode = "subscription_not_found"
+	codeSwapBoardDisabled    errorCode = "swap_board_disabled"
+	codeSwapNotFound         errorCode = "swap_not_found"
+	codeTemplateNotFound     errorCode = "template_not_found"
+	codeUnreadableBody       errorCode = "unreadable_body"
+	codeUpstream             errorCode = "upstream_error"
+	codeUpstreamMaintenance  errorCode = "upstream_maintenance"
+	codeUpstreamTLS          errorCode = "upstream_tls"
+	codeVersionNotFound      errorCode = "version_not_found"
+)
+
+// HTTP status and message templates for an error code. Templates take the
+// arguments passed to writeError.
+type errorMessage struct {
+	status int
+	en, id string
+}
+
+var errorCatalog = map[errorCode]errorMessage{
+	codeAdminDisabled:        {http.StatusForbidden, "Admin features are disabled (SIX_ADMIN_TOKEN is not set)", "Fitur admin dinonaktifkan (SIX_ADMIN_TOKEN belum diatur)"},
+	codeAdminUnauthorized:    {http.StatusUnauthorized, "Missing or invalid admin token", "Token admin tidak ada atau tidak valid"},
+	codeAPIKeyInvalid:        {http.StatusUnauthorized, "Missing or invalid X-API-Key", "X-API-Key tidak ada atau tidak valid"},
+	codeAPIKeysDisabled:      {http.StatusNotFound, "API keys are not configured on this instance", "API key tidak dikonfigurasi di server ini"},
+	codeArchiveDisabled:      {http.StatusForbidden, "Catalog archives are disabled (SIX_ARCHIVE_SECRET is not set)", "Arsip katalog dinonaktifkan (SIX_ARCHIVE_SECRET belum diatur)"},
+	codeArchiveSignature:     {http.StatusBadRequest, "The archive signature does not match; was it exported with the same SIX_ARCHIVE_SECRET?", "Tanda tangan arsip tidak cocok; apakah diekspor dengan SIX_ARCHIVE_SECRET yang sama?"},
+	codeBudgetExhausted:      {http.StatusTooManyRequests, "Daily upstream budget exhausted; only cached data is available until %s", "Kuota harian ke SIX habis; hanya data cache yang tersedia sampai %s"},
+	codeBundleNotFound:       {http.StatusNotFound, "Bundle not found; it may have been replaced by newer ones", "Bundel tidak ditemukan; mungkin sudah digantikan bundel yang lebih baru"},
+	codeCatalogNotCached:     {http.StatusNotFound, "This catalog page is not cached", "Halaman katalog ini tidak ada di cache"},
+	codeChatDisabled:         {http.StatusNotFound, "This chat provider is not configured on this instance", "Penyedia chat ini tidak dikonfigurasi di server ini"},
+	codeChatSignature:        {http.StatusUnauthorized, "Missing or invalid chat provider signature", "Tanda tangan penyedia chat tidak ada atau tidak valid"},
+	codeClassNotFound:        {http.StatusNotFound, "Class not found", "Kelas tidak ditemukan"},
+	codeConsentNotFound:      {http.StatusNotFound, "Consent record not found", "Catatan persetujuan tidak ditemukan"},
+	codeCrawlerDisabled:      {http.StatusForbidden, "The catalog crawler needs a service account (SIX_WARM_COOKIES and SIX_WARM_STUDENT_ID)", "Crawler katalog memerlukan akun layanan (SIX_WARM_COOKIES dan SIX_WARM_STUDENT_ID)"},
+	codeCrawlRunning:         {http.StatusConflict, "A catalog crawl is already running", "Crawl katalog sedang berjalan"},
+	codeDatasetDisabled:      {http.StatusForbidden, "Dataset exports are disabled (SIX_DATASET_DIR is not set)", "Ekspor dataset dinonaktifkan (SIX_DATASET_DIR belum diatur)"},
+	codeDeadLetterNotFound:   {http.StatusNotFound, "Dead letter not found", "Dead letter tidak ditemukan"},
+	codeDeepCheckFailed:      {http.StatusServiceUnavailable, "Deep readiness check failed", "Pemeriksaan kesiapan mendalam gagal"},
+	codeForbidden:            {http.StatusForbidden, "The %s role of this API key does not allow this", "Peran %s pada API key ini tidak mengizinkan tindakan ini"},
+	codeGradeWatchDisabled:   {http.StatusNotFound, "Grade watching is not enabled on this instance", "Pemantauan nilai tidak diaktifkan di server ini"},
+	codeGradeWatchNotFound:   {http.StatusNotFound, "Grade watch not found", "Pemantauan nilai tidak ditemukan"},
+	codeInternal:             {http.StatusInternalServerError, "Internal server error", "Terjadi kesalahan pada server"},
+	codeInvalidArchive:       {http.StatusBadRequest, "Invalid catalog archive: %s", "Arsip katalog tidak valid: %s"},
+	codeInvalidJSON:          {http.StatusBadRequest, "Request body is not valid JSON", "Isi permintaan bukan JSON yang valid"},
+	codeInvalidMirrorTarget:  {http.StatusBadRequest, "target must be an absolute http or https URL that is not an upstream host", "target harus berupa URL http atau https yang lengkap dan bukan host upstream"},
+	codeInvalidRequest:       {http.StatusUnprocessableEntity, "Invalid request: %s", "Permintaan tidak valid: %s"},
+	codeInvalidWebhookURL:    {http.StatusBadRequest, "url must be an absolute http or https URL", "url harus berupa URL http atau https yang lengkap"},
+	codeJobNotFound:          {http.StatusNotFound, "Job not found", "Tugas tidak ditemukan"},
+	codeMaintenance:          {http.StatusServiceUnavailable, "SIX is under maintenance; only cached data is available", "SIX sedang dalam pemeliharaan; hanya data cache yang tersedia"},
+	codeMethodNotAllowed:     {http.StatusMethodNotAllowed, "Method not allowed", "Metode tidak diizinkan"},
+	codeMissingCookie:        {http.StatusBadGateway, "Missing required %s cookie", "Cookie %s wajib ada"},
+	codeNoBuildings:          {http.StatusNotFound, "Building coordinates are not configured (SIX_BUILDINGS_FILE is not set)", "Koordinat gedung belum dikonfigurasi (SIX_BUILDINGS_FILE belum diatur)"},
+	codeNotEnrolled:          {http.StatusUnprocessableEntity, "The student is not enrolled in %s class %s", "Mahasiswa tidak terdaftar di %s kelas %s"},
+	codeOrgNotFound:          {http.StatusNotFound, "Organization or member not found", "Organisasi atau anggota tidak ditemukan"},
+	codeOrgsDisabled:         {http.StatusNotFound, "Organizations are not enabled on this instance (they need SIX_ORGS=true and SIX_API_KEYS)", "Fitur organisasi tidak diaktifkan di server ini (perlu SIX_ORGS=true dan SIX_API_KEYS)"},
+	codeProfileNotFound:      {http.StatusNotFound, "No student profile found on the SIX profile page", "Profil mahasiswa tidak ditemukan di halaman profil SIX"},
+	codeRedactedFormat:       {http.StatusForbidden, "The %s redaction profile of this API key only allows JSON responses", "Profil redaksi %s pada API key ini hanya mengizinkan respons JSON"},
+	codeRedeliveryFailed:     {http.StatusBadGateway, "Redelivery failed: %s", "Pengiriman ulang gagal: %s"},
+	codeSemesterNotFound:     {http.StatusNotFound, "Could not infer the current semester from SIX", "Semester saat ini tidak dapat ditentukan dari SIX"},
+	codeServerBusy:           {http.StatusServiceUnavailable, "Server is busy, please retry later", "Server sedang sibuk, silakan coba lagi nanti"},
+	codeSnapshotNotFound:     {http.StatusNotFound, "No good snapshot of this schedule yet", "Belum ada snapshot jadwal ini yang valid"},
+	codeStudentIDNotFound:    {http.StatusNotFound, "Could not find the student ID on the SIX home page", "NIM tidak ditemukan di halaman utama SIX"},
+	codeSubscriptionNotFound: {http.StatusNotFound, "Subscription not found", "Langganan tidak ditemukan"},
+	codeSwapBoardDisabled:    {http.StatusNotFound, "The swap board is not enabled on this instance", "Papan tukar kelas tidak diaktifkan di server ini"},
+	codeSwapNotFound:         {http.StatusNotFound, "Swap request not found", "Permintaan tukar kelas tidak ditemukan"},
+	codeTemplateNotFound:     {http.StatusNotFound, "Output template not found", "Templat keluaran tidak ditemukan"},
+	codeUnreadableBody:       {http.StatusBadRequest, "Could not read request body", "Isi permintaan tidak dapat dibaca"},
+	codeUpstream:             {http.StatusBadGateway, "Could not fetch data from SIX", "Gagal mengambil data dari SIX"},
+	codeUpstreamMaintenance:  {http.StatusServiceUnavailable, "SIX appears to be under maintenance; only cached data is available until %s", "SIX tampaknya sedang dalam pemeliharaan; hanya data cache yang tersedia sampai %s"},
+	codeUpstreamTLS:          {http.StatusBadGateway, "Could not establish a trusted TLS connection to SIX", "Tidak dapat membuat koneksi TLS tepercaya ke SIX"},
+	codeVersionNotFound:      {http.StatusNotFound, "Schedule version %s not found", "Versi jadwal %s tidak ditemukan"},
+}
+
+// Returns "id" if the client prefers Indonesian over English according to
+// Accept-Language, otherwise "en".
+func errorLanguage(r *http.Request) string {
+	if r == nil {
+		return "en"
+	}
+	best, bestQ := "en", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if lang == "in" {
+			lang = "id" // legacy code for Indonesian
+		}
+		if (lang == "id" || lang == "en") && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Returns the localized message for code.
+func errorText(r *http.Request, code errorCode, args ...any) string {
+	m := errorCatalog[code]
+	tmpl := m.en
+	if errorLanguage(r) == "id" {
+		tmpl = m.id
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(tmpl, args...)
+	}
+	return tmpl
+}
+
+// Writes an error response for code with its catalog status and the message
+// in the client's language.
+func writeError(w http.ResponseWriter, r *http.Request, code errorCode, args ...any) {
+	writeErrorResponse(w, r, errorCatalog[code].status, APIResponse{Code: code, Error: errorText(r, code, args...)})
+}
+
+// Writes a 500 that carries the request ID, so users can quote it in reports.
+func writeInternalError(w http.ResponseWriter, r *http.Request) {
+	writeErrorResponse(w, r, http.StatusInternalServerError, APIResponse{Code: codeInternal, Error: errorText(r, codeInternal), RequestID: requestIDFrom(r.Context())})
+}
+
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, resp APIResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", errorLanguage(r))
+	w.WriteHeader(status)
+	noteEncoded(w, resp)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorLanguage(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"id", "id"},
+		{"id-ID,id;q=0.9,en;q=0.8", "id"},
+		{"en-US,en;q=0.9,id;q=0.8", "en"},
+		{"in", "id"},
+		{"fr, id;q=0.5", "id"},
+		{"de", "en"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", tt.header)
+		if got := errorLanguage(r); got != tt.want {
+			t.Errorf("errorLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestErrorCatalog_Complete(t *testing.T) {
+	for code, m := range errorCatalog {
+		if m.status == 0 || m.en == "" || m.id == "" {
+			t.Errorf("%s: incomplete catalog entry %+v", code, m)
+		}
+		if strings.Count(m.en, "%") != strings.Count(m.id, "%") {
+			t.Errorf("%s: en and id take different arguments", code)
+		}
+	}
+}
+
+func TestWriteError_Localized(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "id")
+	w := httptest.NewRecorder()
+	writeError(w, r, codeClassNotFound)
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Language") != "id" {
+		t.Errorf("got status %d, Content-Language %q", w.Code, w.Header().Get("Content-Language"))
+	}
+	var resp APIResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != codeClassNotFound || resp.Error != "Kelas tidak ditemukan" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestWriteUpstreamError_HidesRawError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeUpstreamError(w, httptest.NewRequest("GET", "/", nil), errors.New("dial tcp 10.0.0.1:443: connection refused"))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("got status %d, want 502", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.1") {
+		t.Errorf("response leaks the upstream error: %s", w.Body)
+	}
+	var resp APIResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != codeUpstream {
+		t.Errorf("code = %q, want %q", resp.Code, codeUpstream)
+	}
+}
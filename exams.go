@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+	"six-scraper-go/textnorm"
+)
+
+// SIX page listing a student's midterm (UTS) and final (UAS) exams for one
+// semester.
+func examsPath(studentID, semester string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s+%s/kelas/jadwal/ujian", studentID, semester)
+}
+
+// One exam on the exam schedule page.
+type ExamEntry struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	ClassNo string `json:"class_no"`
+	// Type is "UTS" or "UAS", or the text SIX shows if it is neither.
+	Type string `json:"type"`
+	// Date is YYYY-MM-DD, or the text SIX shows if it is not a date.
+	Date string `json:"date"`
+	Time string `json:"time"` // e.g. "07:00-09:00"
+	Room string `json:"room"`
+	Seat string `json:"seat"`
+}
+
+// Parses the exam schedule page. Unlike the lecture table, its columns move
+// around between faculties, so they are found by header text. The seat
+// column is often headed "No. Kursi", so columns are matched on any word of
+// the header, not just the first. Tables without a code and a date column
+// are ignored. When there is no type column, the type comes from the
+// table's heading, as on pages with one table for UTS and one for UAS.
+func parseExams(doc *goquery.Document) []ExamEntry {
+	var exams []ExamEntry
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := examColumns(table)
+		code, hasCode := cols["kode"]
+		date, hasDate := cols["tanggal"]
+		if !hasCode || !hasDate {
+			return
+		}
+		heading := scraper.TableHeading(table)
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			e := ExamEntry{
+				Code: scraper.CellText(cells, code),
+				Date: examDate(scraper.CellText(cells, date)),
+				Type: examType(heading),
+			}
+			if i, ok := cols["nama"]; ok {
+				e.Name = scraper.CellText(cells, i)
+			}
+			if i, ok := cols["kelas"]; ok {
+				e.ClassNo = scraper.CellText(cells, i)
+			}
+			if i, ok := cols["jenis"]; ok {
+				e.Type = examType(scraper.CellText(cells, i))
+			}
+			if i, ok := cols["waktu"]; ok {
+				e.Time = strings.ReplaceAll(scraper.CellText(cells, i), " - ", "-")
+			}
+			if i, ok := cols["ruang"]; ok {
+				e.Room = scraper.CellText(cells, i)
+			}
+			if i, ok := cols["kursi"]; ok {
+				e.Seat = scraper.CellText(cells, i)
+			}
+			// Like parseGrades, skip footers that span every column.
+			if e.Code != "" && e.Code != scraper.CellText(cells, date) {
+				exams = append(exams, e)
+			}
+		}
+	})
+	return exams
+}
+
+// Names of columns found by the words of their headers, with those words.
+type headerWords []struct {
+	col   string
+	words []string
+}
+
+// Header words of each exam column, by the name parseExams uses for it.
+// A header cell goes to the first column, in this order, that one of its
+// words names, so "Kode Mata Kuliah" is the code and "Tanggal Ujian" the
+// date, while "Jenis Ujian" is the type.
+var examHeaderWords = headerWords{
+	{"kode", []string{"kode"}},
+	{"tanggal", []string{"tanggal", "hari"}},
+	{"waktu", []string{"waktu", "jam"}},
+	{"ruang", []string{"ruang", "ruangan"}},
+	{"kursi", []string{"kursi", "seat"}},
+	{"kelas", []string{"kelas"}},
+	{"nama", []string{"nama", "mata"}},
+	{"jenis", []string{"jenis", "ujian", "tipe"}},
+}
+
+// Maps each exam column to the index of its header cell.
+func examColumns(table *goquery.Selection) map[string]int {
+	return wordColumns(table, examHeaderWords)
+}
+
+// Maps each of headers' columns to the index of the first header cell of
+// table that one of its words names, each cell going to the first column
+// that matches.
+func wordColumns(table *goquery.Selection, headers headerWords) map[string]int {
+	cols := make(map[string]int)
+	for _, cells := range scraper.GridRows(table.Find("thead tr").First(), "th, td") {
+		for i, th := range cells {
+			words := strings.FieldsFunc(textnorm.Key(th.Text()), func(r rune) bool {
+				return r < 'a' || r > 'z'
+			})
+			for _, h := range headers {
+				if _, taken := cols[h.col]; taken {
+					continue
+				}
+				if slices.ContainsFunc(words, func(w string) bool { return slices.Contains(h.words, w) }) {
+					cols[h.col] = i
+					break
+				}
+			}
+		}
+	}
+	return cols
+}
+
+// Returns "UTS" or "UAS" if text names one of them, or text as it is.
+func examType(text string) string {
+	key := textnorm.Key(text)
+	switch {
+	case strings.Contains(key, "uts"), strings.Contains(key, "tengah semester"):
+		return "UTS"
+	case strings.Contains(key, "uas"), strings.Contains(key, "akhir semester"):
+		return "UAS"
+	}
+	return text
+}
+
+var indonesianMonths = map[string]time.Month{
+	"januari": time.January, "februari": time.February, "maret": time.March,
+	"april": time.April, "mei": time.May, "juni": time.June, "juli": time.July,
+	"agustus": time.August, "september": time.September, "oktober": time.October,
+	"november": time.November, "desember": time.December,
+}
+
+// Returns a date SIX shows as "2026-10-12", "12-10-2026", "12/10/2026", or
+// "Senin, 12 Oktober 2026" as YYYY-MM-DD, or text as it is otherwise.
+func examDate(text string) string {
+	s := text
+	if _, after, ok := strings.Cut(s, ","); ok {
+		s = after // a leading day name
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.DateOnly, "02-01-2006", "02/01/2006", "2-1-2006", "2/1/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(time.DateOnly)
+		}
+	}
+	var day, year int
+	var month string
+	if n, _ := fmt.Sscanf(s, "%d %s %d", &day, &month, &year); n == 3 {
+		if m, ok := indonesianMonths[textnorm.Key(month)]; ok {
+			t := time.Date(year, m, day, 0, 0, 0, 0, wib)
+			if t.Day() == day {
+				return t.Format(time.DateOnly)
+			}
+		}
+	}
+	return text
+}
+
+// GET /api/exams?student_id=...&semester=...
+func (s *Server) examsHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	exams, err := s.provider.FetchExams(r, studentID, semester)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	if exams == nil {
+		exams = []ExamEntry{}
+	}
+	log.Printf("parsed exams=%d student_id=%s semester=%s", len(exams), studentID, semester)
+
+	meta := &Meta{FetchedAt: time.Now()}
+	if relative {
+		meta.Semester = semester
+	}
+	writeSuccessWithMeta(w, exams, meta)
+}
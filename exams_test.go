@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testExamsHTML = `<html><body>
+<table class="table">
+  <thead><tr><th>No</th><th>Kode Mata Kuliah</th><th>Nama Mata Kuliah</th><th>Kelas</th><th>Jenis Ujian</th><th>Hari, Tanggal</th><th>Waktu</th><th>Ruang</th><th>No. Kursi</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>IF2211</td><td>Strategi  Algoritma</td><td>01</td><td>Ujian Tengah Semester</td><td>Senin, 12 Oktober 2026</td><td>07:00 - 09:00</td><td>7602</td><td>A12</td></tr>
+    <tr><td>2</td><td>IF2230</td><td>Sistem Operasi</td><td>02</td><td>UAS</td><td>2026-12-14</td><td>13:00 - 15:00</td><td>9009</td><td></td></tr>
+    <tr><td colspan="9">Tidak ada ujian lain</td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+func TestParseExams(t *testing.T) {
+	got := parseExams(docFromHTML(testExamsHTML))
+	want := []ExamEntry{
+		{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", Type: "UTS", Date: "2026-10-12", Time: "07:00-09:00", Room: "7602", Seat: "A12"},
+		{Code: "IF2230", Name: "Sistem Operasi", ClassNo: "02", Type: "UAS", Date: "2026-12-14", Time: "13:00-15:00", Room: "9009"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// Some faculties list UTS and UAS in separate tables under their own
+// headings, with one date cell spanning the exams of a day.
+func TestParseExams_TypeFromHeading(t *testing.T) {
+	doc := docFromHTML(`<html><body>
+<h4>Jadwal UTS</h4>
+<table><thead><tr><th>Tanggal</th><th>Kode</th><th>Ruangan</th></tr></thead><tbody>
+  <tr><td rowspan="2">12/10/2026</td><td>IF2211</td><td>7602</td></tr>
+  <tr><td>IF2230</td><td>7603</td></tr>
+</tbody></table>
+<h4>Jadwal UAS</h4>
+<table><thead><tr><th>Tanggal</th><th>Kode</th><th>Ruangan</th></tr></thead><tbody>
+  <tr><td>belum dijadwalkan</td><td>IF2211</td><td></td></tr>
+</tbody></table>
+</body></html>`)
+	got := parseExams(doc)
+	want := []ExamEntry{
+		{Code: "IF2211", Type: "UTS", Date: "2026-10-12", Room: "7602"},
+		{Code: "IF2230", Type: "UTS", Date: "2026-10-12", Room: "7603"},
+		{Code: "IF2211", Type: "UAS", Date: "belum dijadwalkan"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestExamDate(t *testing.T) {
+	for text, want := range map[string]string{
+		"2026-10-12":             "2026-10-12",
+		"12-10-2026":             "2026-10-12",
+		"2/3/2026":               "2026-03-02",
+		"Jumat, 4 Desember 2026": "2026-12-04",
+		"31 Februari 2026":       "31 Februari 2026",
+		"TBA":                    "TBA",
+	} {
+		if got := examDate(text); got != want {
+			t.Errorf("%q: got %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestExamsHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/mahasiswa:123+2026-1/kelas/jadwal/ujian") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, testExamsHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/exams?student_id=123&semester=2026-1", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if exams := decodeData[[]ExamEntry](t, w); len(exams) != 2 || exams[0].Seat != "A12" {
+		t.Errorf("exams = %+v", exams)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+// Subscriptions and grade watches expire when their semester ends, so
+// nothing keeps notifying about, or polling SIX for, a semester that is
+// over. A subscription follows its semester and a grade watch the semester
+// in session when it was created. SIX_EXPIRY_NOTICE before that, an
+// expiring notice goes out the way their changes do, and at the end they
+// are removed. Semesters end as the academic calendar says (see
+// academicCalendar), or else as in ITB's usual one. SIX_SEMESTER_ENDS
+// overrides both with the last day of a semester, e.g.
+// "2025-1=2026-01-24,2025-2=2026-07-11".
+var (
+	semesterEnds = parseSemesterEnds(envString("SIX_SEMESTER_ENDS", ""))
+	expiryNotice = envDuration("SIX_EXPIRY_NOTICE", 7*24*time.Hour)
+)
+
+// How often expiring and expired watchers are checked.
+const expiryTick = time.Minute
+
+// Parses "2025-1=2026-01-24,...". Each semester ends when its last day does.
+func parseSemesterEnds(spec string) map[scraper.Semester]time.Time {
+	ends := make(map[scraper.Semester]time.Time)
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		semSpec, daySpec, _ := strings.Cut(entry, "=")
+		sem, err1 := scraper.ParseSemester(strings.TrimSpace(semSpec))
+		day, err2 := time.ParseInLocation(time.DateOnly, strings.TrimSpace(daySpec), wib)
+		if err1 != nil || err2 != nil {
+			log.Printf("config: invalid SIX_SEMESTER_ENDS entry %q, skipping", entry)
+			continue
+		}
+		ends[sem] = day.AddDate(0, 0, 1)
+	}
+	return ends
+}
+
+// Returns when sem ends: as SIX_SEMESTER_ENDS says, or else the academic
+// calendar, or else when the next semester starts in
+// scraper.CalendarSemester, February for odd semesters and August for even
+// ones. Short semesters run through August.
+func (s *Server) semesterEnd(sem scraper.Semester) time.Time {
+	if end, ok := semesterEnds[sem]; ok {
+		return end
+	}
+	if end, ok := s.academic.semesterEnd(sem); ok {
+		return end
+	}
+	month := time.February
+	switch sem.Term {
+	case 2:
+		month = time.August
+	case 3:
+		month = time.September
+	}
+	return time.Date(sem.Year+1, month, 1, 0, 0, 0, 0, wib)
+}
+
+// Returns when a watcher of semester expires, or nil if semester is not a
+// concrete semester.
+func (s *Server) semesterExpiry(semester string) *time.Time {
+	sem, err := scraper.ParseSemester(semester)
+	if err != nil {
+		return nil
+	}
+	end := s.semesterEnd(sem)
+	return &end
+}
+
+// Runs sweepExpired every expiryTick until ctx is done.
+func (s *Server) runExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(expiryTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sweepExpired(now)
+		}
+	}
+}
+
+// Sends the expiring notices due at now, and removes the subscriptions and
+// grade watches that have expired.
+func (s *Server) sweepExpired(now time.Time) {
+	subscriptionsMu.Lock()
+	for id, sub := range subscriptions {
+		switch {
+		case sub.ExpiresAt == nil:
+		case !now.Before(*sub.ExpiresAt):
+			delete(subscriptions, id)
+			log.Printf("subscription expired id=%s semester=%s", id, sub.Semester)
+		case sub.ExpiryNoticeSentAt == nil && !now.Before(sub.ExpiresAt.Add(-expiryNotice)):
+			sub.ExpiryNoticeSentAt = &now
+			if !sub.Paused {
+				s.sendLocked(sub, WebhookEvent{
+					Type:       "subscription.expiring",
+					OccurredAt: now,
+					StudentID:  sub.StudentID,
+					Semester:   sub.Semester,
+					Classes:    []CourseClass{},
+					ExpiresAt:  sub.ExpiresAt,
+				}, now)
+			}
+		}
+	}
+	subscriptionsMu.Unlock()
+
+	var expired []GradeWatch
+	s.gradeWatches.mu.Lock()
+	for id, gw := range s.gradeWatches.watches {
+		switch {
+		case gw.ExpiresAt == nil:
+		case !now.Before(*gw.ExpiresAt):
+			delete(s.gradeWatches.watches, id)
+			expired = append(expired, *gw)
+		case gw.ExpiryNoticeSentAt == nil && !now.Before(gw.ExpiresAt.Add(-expiryNotice)):
+			gw.ExpiryNoticeSentAt = &now
+			n := newNotification("grade_watch.expiring", gw.StudentID, "Grade watch ends "+gw.ExpiresAt.In(wib).Format("2006-01-02 15:04")+" WIB",
+				map[string]any{"id": gw.ID, "expires_at": gw.ExpiresAt})
+			go func(notifier Notifier, id string) {
+				if err := notifier.Notify(context.Background(), n); err != nil {
+					log.Printf("grade watch expiry notice failed id=%s err=%v", id, err)
+				}
+			}(gw.notifier, gw.ID)
+		}
+	}
+	s.gradeWatches.mu.Unlock()
+	for _, gw := range expired {
+		s.consents.end(gw.ConsentID, revokedDelete)
+		log.Printf("grade watch expired id=%s student_id=%s", gw.ID, gw.StudentID)
+	}
+}
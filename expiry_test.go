@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+func TestSemesterEnd(t *testing.T) {
+	old := semesterEnds
+	semesterEnds = parseSemesterEnds("2025-2=2026-07-11, bogus=2026-01-01")
+	t.Cleanup(func() { semesterEnds = old })
+	srv := newTestServer("")
+	srv.academic.learn([]CalendarEntry{
+		{Title: "Akhir Perkuliahan", Type: "semester_end", Start: "2026-12-04", End: "2026-12-04", Semester: "2026-1"},
+		{Title: "Akhir Perkuliahan", Type: "semester_end", Start: "2026-05-29", End: "2026-05-29", Semester: "2025-2"},
+	})
+
+	for _, tt := range []struct {
+		sem  scraper.Semester
+		want string
+	}{
+		{scraper.Semester{Year: 2025, Term: 1}, "2026-02-01"},
+		{scraper.Semester{Year: 2025, Term: 2}, "2026-07-12"}, // the day after the configured last day
+		{scraper.Semester{Year: 2026, Term: 1}, "2026-12-05"}, // the day after the calendar's
+		{scraper.Semester{Year: 2025, Term: 3}, "2026-09-01"},
+		{scraper.Semester{Year: 2026, Term: 2}, "2027-08-01"},
+	} {
+		if got := srv.semesterEnd(tt.sem).In(wib).Format(time.DateOnly); got != tt.want {
+			t.Errorf("semesterEnd(%s) = %s, want %s", tt.sem, got, tt.want)
+		}
+	}
+	if srv.semesterExpiry("current") != nil {
+		t.Error("a relative semester must not expire")
+	}
+}
+
+func TestSweepExpired_Subscription(t *testing.T) {
+	setupWebhooks(t)
+	receiver, received := webhookReceiver(t, 0)
+	srv := newWebhookTestServer(t, "")
+
+	body := strings.NewReplacer("https://example.com/hook", receiver.URL, "1945-1", "2025-1").Replace(testSubscriptionBody)
+	_, sub := postSubscription(t, body)
+	end := time.Date(2026, time.February, 1, 0, 0, 0, 0, wib)
+	if sub.ExpiresAt == nil || !sub.ExpiresAt.Equal(end) {
+		t.Fatalf("expires_at = %v, want %v", sub.ExpiresAt, end)
+	}
+
+	srv.sweepExpired(end.Add(-expiryNotice - time.Minute))
+	time.Sleep(20 * time.Millisecond)
+	if n := len(received()); n != 0 {
+		t.Errorf("%d deliveries before the notice is due", n)
+	}
+	for range 2 {
+		srv.sweepExpired(end.Add(-24 * time.Hour))
+	}
+	waitFor(t, func() bool { return len(received()) == 1 })
+	var event WebhookEvent
+	json.Unmarshal(received()[0].body, &event)
+	if event.Type != "subscription.expiring" || event.ExpiresAt == nil || !event.ExpiresAt.Equal(end) {
+		t.Errorf("notice = %+v", event)
+	}
+
+	srv.sweepExpired(end)
+	if w := subscriptionRequest(t, "GET", "/api/subscriptions/"+sub.ID, "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expired subscription: got status %d, want 404", w.Code)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(received()); n != 1 {
+		t.Errorf("%d deliveries, want only the notice", n)
+	}
+}
+
+func TestSweepExpired_GradeWatch(t *testing.T) {
+	setupWebhooks(t)
+	receiver, received := webhookReceiver(t, 0)
+	srv := newWebhookTestServer(t, "")
+	end := time.Now().Add(time.Hour)
+	srv.gradeWatches.watches["w1"] = &GradeWatch{ID: "w1", StudentID: "123", ExpiresAt: &end, notifier: newWebhookNotifier(receiver.URL, "s")}
+
+	srv.sweepExpired(time.Now())
+	waitFor(t, func() bool { return len(received()) == 1 })
+	var n Notification
+	json.Unmarshal(received()[0].body, &n)
+	if n.Type != "grade_watch.expiring" || n.StudentID != "123" {
+		t.Errorf("notice = %+v", n)
+	}
+
+	srv.sweepExpired(end)
+	if _, ok := srv.gradeWatches.watches["w1"]; ok {
+		t.Error("expired grade watch was not removed")
+	}
+}
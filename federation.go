@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// In federation mode an instance asks a peer instance for catalog pages
+// before fetching them from SIX, so community deployments share one scrape
+// instead of each hitting SIX. Only catalog pages are shared; nothing
+// student-specific and no SIX cookies are sent to the peer.
+var peerTimeout = envDuration("SIX_PEER_TIMEOUT", 5*time.Second)
+
+var errPeerMiss = errors.New("peer does not have the page cached")
+
+type peerClient struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newPeerClient(baseURL, apiKey string) *peerClient {
+	return &peerClient{baseURL: baseURL, apiKey: apiKey, client: &http.Client{Timeout: peerTimeout}}
+}
+
+// Fetches a catalog page from the peer's /api/peer/catalog. Returns
+// errPeerMiss if the peer has not cached it.
+func (p *peerClient) catalog(ctx context.Context, semester string, query url.Values) ([]CourseClass, time.Time, error) {
+	q := scheduleFilters(query)
+	q.Set("semester", semester)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/peer/catalog?"+q.Encode(), nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("X-API-Key", p.apiKey)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, time.Time{}, errPeerMiss
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("peer returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data []CourseClass `json:"data"`
+		Meta *Meta         `json:"meta"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxPeerResponse)).Decode(&body); err != nil {
+		return nil, time.Time{}, err
+	}
+	if body.Meta == nil {
+		return nil, time.Time{}, errors.New("peer response has no meta")
+	}
+	return body.Data, body.Meta.FetchedAt, nil
+}
+
+// Largest catalog page accepted from a peer.
+const maxPeerResponse = 16 << 20
+
+// Looks up a catalog page in the local catalog cache, then at the peer. The
+// caller falls back to SIX when ok is false.
+func (s *Server) sharedCatalog(ctx context.Context, semester string, query url.Values) (entry cacheEntry, source string, ok bool) {
+	key, isCatalog := catalogKey(semester, query)
+	if !isCatalog {
+		return cacheEntry{}, "", false
+	}
+	if entry, ok := s.catalog.get(key); ok {
+		return entry, "catalog", true
+	}
+	if s.peer == nil {
+		return cacheEntry{}, "", false
+	}
+
+	classes, fetchedAt, err := s.peer.catalog(ctx, semester, query)
+	if err != nil {
+		if !errors.Is(err, errPeerMiss) {
+			log.Printf("peer catalog failed semester=%s err=%v", semester, err)
+		}
+		return cacheEntry{}, "", false
+	}
+	log.Printf("peer catalog hit semester=%s classes=%d", semester, len(classes))
+	sortClasses(classes)
+	s.catalog.set(key, classes, fetchedAt)
+	return cacheEntry{data: classes, fetchedAt: fetchedAt}, "peer", true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCatalogKey(t *testing.T) {
+	if _, ok := catalogKey("1945-1", url.Values{"pekan": {"3"}}); ok {
+		t.Error("a query without fakultas or prodi is not a catalog page")
+	}
+	a, _ := catalogKey("1945-1", url.Values{"prodi": {"135"}, "fakultas": {"STEI"}, "refresh": {"true"}})
+	b, _ := catalogKey("1945-1", url.Values{"fakultas": {" STEI "}, "prodi": {"135"}})
+	if a != b || a != "1945-1?fakultas=STEI&prodi=135" {
+		t.Errorf("keys %q and %q, want both 1945-1?fakultas=STEI&prodi=135", a, b)
+	}
+}
+
+func TestScheduleHandler_SharesCatalogAcrossStudents(t *testing.T) {
+	mock := mockSIX("111", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func(studentID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id="+studentID+"&semester=1945-1&fakultas=STEI", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	get("111")
+	mock.Close() // the second student must not need SIX
+
+	w := get("222")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if meta := decodeMeta(t, w); meta.Source != "catalog" || !meta.Cached {
+		t.Errorf("meta = %+v, want source catalog", meta)
+	}
+}
+
+func TestScheduleHandler_ReadsCatalogFromPeer(t *testing.T) {
+	peer := newTestServer("")
+	key, _ := catalogKey("1945-1", url.Values{"prodi": {"135"}})
+	fetchedAt := time.Date(2025, 2, 8, 12, 0, 0, 0, time.UTC)
+	peer.catalog.set(key, []CourseClass{{Code: "IF2211", ClassNo: "01"}}, fetchedAt)
+
+	var gotKey string
+	peerHTTP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-API-Key")
+		if r.Header.Get("Cookie") != "" {
+			t.Error("SIX cookies were sent to the peer")
+		}
+		peer.ServeHTTP(w, r)
+	}))
+	defer peerHTTP.Close()
+
+	hitsSIX := 0
+	six := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hitsSIX++ }))
+	defer six.Close()
+	srv := NewServer(Config{BaseURL: six.URL, PeerURL: peerHTTP.URL + "/", PeerAPIKey: "peer-key"})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&"+query, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("prodi=135")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	meta := decodeMeta(t, w)
+	if meta.Source != "peer" || !meta.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("meta = %+v, want source peer and the peer's fetched_at", meta)
+	}
+	if hitsSIX != 0 || gotKey != "peer-key" {
+		t.Errorf("SIX hits = %d, peer key = %q", hitsSIX, gotKey)
+	}
+
+	// A page the peer lacks falls back to SIX.
+	get("prodi=182")
+	if hitsSIX != 1 {
+		t.Errorf("SIX hits = %d, want 1 after a peer miss", hitsSIX)
+	}
+}
+
+func decodeMeta(t *testing.T, w *httptest.ResponseRecorder) Meta {
+	t.Helper()
+	var resp APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Meta == nil {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+	return *resp.Meta
+}
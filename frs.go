@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+	"six-scraper-go/textnorm"
+)
+
+// SIX page with a student's study plan (FRS, formulir rencana studi) for one
+// semester: the classes they picked and whether their academic advisor
+// (dosen wali) approved them.
+func frsPath(studentID, semester string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s+%s/registrasi/frs", studentID, semester)
+}
+
+// A student's study plan for one semester.
+type FRS struct {
+	// Status is the approval status as SIX shows it, e.g. "Disetujui".
+	Status string `json:"status"`
+	// Approved is set once the advisor has approved the plan.
+	Approved bool   `json:"approved"`
+	Advisor  string `json:"advisor"` // the dosen wali
+	// Classes are the classes picked, approved or not.
+	Classes  []FRSClass `json:"classes"`
+	TotalSKS int        `json:"total_sks"`
+}
+
+type FRSClass struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	ClassNo string `json:"class_no"`
+	SKS     int    `json:"sks"`
+}
+
+// Labels of the FRS page's status and advisor fields.
+var (
+	frsStatusLabels  = []string{"status frs", "status persetujuan", "persetujuan"}
+	frsAdvisorLabels = []string{"dosen wali", "wali", "pembimbing akademik", "dosen pembimbing"}
+)
+
+// Parses the FRS page. The classes are in a table found by its "Kode" and
+// "SKS" headers, like parseGrades. The status and advisor are labeled
+// fields above it (see labeledField). The total is summed from the classes rather
+// than read from the footer, which not every faculty shows.
+func parseFRS(doc *goquery.Document) FRS {
+	frs := FRS{
+		Status:  labeledField(doc, frsStatusLabels),
+		Advisor: labeledField(doc, frsAdvisorLabels),
+		Classes: []FRSClass{},
+	}
+	frs.Approved = frsApproved(frs.Status)
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := scraper.HeaderColumns(table)
+		code, hasCode := cols["kode"]
+		sks, hasSKS := cols["sks"]
+		if !hasCode || !hasSKS {
+			return
+		}
+		name, hasName := cols["nama"]
+		classNo, hasClass := cols["kelas"]
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			c := FRSClass{Code: scraper.CellText(cells, code)}
+			c.SKS, _ = strconv.Atoi(scraper.CellText(cells, sks))
+			if hasName {
+				c.Name = scraper.CellText(cells, name)
+			}
+			if hasClass {
+				c.ClassNo = scraper.CellText(cells, classNo)
+			}
+			// Like parseGrades, skip footers such as "Total SKS" that
+			// span every column.
+			if c.Code == "" || c.Code == scraper.CellText(cells, sks) {
+				continue
+			}
+			frs.Classes = append(frs.Classes, c)
+			frs.TotalSKS += c.SKS
+		}
+	})
+	return frs
+}
+
+// Reports whether an FRS status says the advisor approved the plan, as
+// "Disetujui" or "Sudah disetujui" do, but not "Belum disetujui".
+func frsApproved(status string) bool {
+	key := textnorm.Key(status)
+	if strings.Contains(key, "belum") || strings.Contains(key, "tidak") || strings.Contains(key, "ditolak") {
+		return false
+	}
+	return strings.Contains(key, "disetujui") || strings.Contains(key, "approved")
+}
+
+// GET /api/frs?student_id=...&semester=...
+func (s *Server) frsHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	frs, err := s.provider.FetchFRS(r, studentID, semester)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	if frs.Classes == nil {
+		frs.Classes = []FRSClass{}
+	}
+	log.Printf("parsed frs classes=%d sks=%d approved=%t student_id=%s semester=%s", len(frs.Classes), frs.TotalSKS, frs.Approved, studentID, semester)
+
+	meta := &Meta{FetchedAt: time.Now()}
+	if relative {
+		meta.Semester = semester
+	}
+	writeSuccessWithMeta(w, frs, meta)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testFRSHTML = `<html><body>
+<table class="table-info">
+  <tr><th>Dosen Wali</th><td>Dr. Budi  Santoso</td></tr>
+  <tr><th>Status FRS :</th><td>Sudah disetujui</td></tr>
+</table>
+<table class="table">
+  <thead><tr><th>No</th><th>Kode</th><th>Nama Mata Kuliah</th><th>Kelas</th><th>SKS</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>IF2211</td><td>Strategi Algoritma</td><td>01</td><td>3</td></tr>
+    <tr><td>2</td><td>IF2230</td><td>Sistem Operasi</td><td>02</td><td>4</td></tr>
+    <tr><td colspan="5">Total SKS: 7</td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+func TestParseFRS(t *testing.T) {
+	got := parseFRS(docFromHTML(testFRSHTML))
+	want := FRS{
+		Status:   "Sudah disetujui",
+		Approved: true,
+		Advisor:  "Dr. Budi Santoso",
+		Classes: []FRSClass{
+			{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", SKS: 3},
+			{Code: "IF2230", Name: "Sistem Operasi", ClassNo: "02", SKS: 4},
+		},
+		TotalSKS: 7,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// Some faculties show the fields as text rather than in a table.
+func TestParseFRS_TextLabels(t *testing.T) {
+	doc := docFromHTML(`<html><body>
+<p><b>Dosen Wali</b>: Dr. Ani</p>
+<p><strong>Status Persetujuan: Belum disetujui</strong></p>
+</body></html>`)
+	got := parseFRS(doc)
+	if got.Advisor != "Dr. Ani" || got.Status != "Belum disetujui" || got.Approved || len(got.Classes) != 0 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestFRSApproved(t *testing.T) {
+	for status, want := range map[string]bool{
+		"Disetujui":            true,
+		"Sudah Disetujui":      true,
+		"Belum disetujui":      false,
+		"Tidak disetujui":      false,
+		"Ditolak":              false,
+		"Menunggu persetujuan": false,
+		"":                     false,
+	} {
+		if got := frsApproved(status); got != want {
+			t.Errorf("%q: got %t, want %t", status, got, want)
+		}
+	}
+}
+
+func TestFRSHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/mahasiswa:123+2026-1/registrasi/frs") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, testFRSHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/frs?student_id=123&semester=2026-1", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if frs := decodeData[FRS](t, w); len(frs.Classes) != 2 || frs.TotalSKS != 7 || !frs.Approved {
+		t.Errorf("frs = %+v", frs)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GET /api/catalog assembles the full catalog of a semester from the catalog
+// page of every faculty and program, discovered as the crawler discovers
+// them (see crawl.go). That is dozens of pages, so they are fetched
+// SIX_CRAWL_WORKERS at a time, like a crawl's, each page is served from the
+// catalog cache when it can be, and the assembled catalog is cached for
+// SIX_CATALOG_TTL.
+
+type FullCatalog struct {
+	Semester string `json:"semester"`
+	// Pages is how many catalog pages were merged. Failed lists the pages
+	// that could not be fetched, whose classes may be missing.
+	Pages   int                `json:"pages"`
+	Failed  []CatalogPageError `json:"failed"`
+	Classes []CourseClass      `json:"classes"`
+}
+
+type CatalogPageError struct {
+	Fakultas string `json:"fakultas"`
+	Prodi    string `json:"prodi,omitempty"`
+	Error    string `json:"error"`
+}
+
+// Semesters whose catalog is being assembled. Requests for one wait for it
+// instead of fetching the same pages again.
+type catalogBuilds struct {
+	mu       sync.Mutex
+	building map[string]chan struct{} // closed when the semester's assembly ends
+}
+
+func newCatalogBuilds() *catalogBuilds {
+	return &catalogBuilds{building: make(map[string]chan struct{})}
+}
+
+// Claims the assembly of semester. It returns a func to call when the
+// assembly ends, or, if another request holds it, a channel closed then.
+func (b *catalogBuilds) claim(semester string) (end func(), wait <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if done, ok := b.building[semester]; ok {
+		return nil, done
+	}
+	done := make(chan struct{})
+	b.building[semester] = done
+	return func() {
+		b.mu.Lock()
+		delete(b.building, semester)
+		b.mu.Unlock()
+		close(done)
+	}, nil
+}
+
+// Returns the semester's catalog from s.fullCatalogs, or from build, which
+// only one caller at a time runs for a semester. Only complete catalogs are
+// cached. cached reports which.
+func (s *Server) fullCatalog(ctx context.Context, semester string, refresh bool, build func() (FullCatalog, error)) (entry ttlEntry[FullCatalog], cached bool, err error) {
+	for {
+		if e, ok := s.fullCatalogs.get(semester); ok && !refresh {
+			return e, true, nil
+		}
+		end, wait := s.assembling.claim(semester)
+		if wait != nil {
+			select {
+			case <-wait:
+				refresh = false
+				continue
+			case <-ctx.Done():
+				return ttlEntry[FullCatalog]{}, false, ctx.Err()
+			}
+		}
+		// An assembly may have ended since the lookup above.
+		if e, ok := s.fullCatalogs.get(semester); ok && !refresh {
+			end()
+			return e, true, nil
+		}
+
+		catalog, err := build()
+		entry := ttlEntry[FullCatalog]{data: catalog, fetchedAt: time.Now()}
+		if err == nil && len(catalog.Failed) == 0 {
+			s.fullCatalogs.put(semester, entry)
+		}
+		end()
+		return entry, false, err
+	}
+}
+
+// Like catalogKey, but also for the unfiltered page.
+func catalogPageKey(semester string, query url.Values) string {
+	return semester + "?" + scheduleFilters(query).Encode()
+}
+
+// Returns the classes and filter options of one catalog page, from the
+// caches unless refresh is set, or else scraped and stored in them.
+// needOptions says whether the filter options are wanted; a program page's
+// are not.
+func (s *Server) fullCatalogPage(r *http.Request, studentID, semester string, query url.Values, refresh, needOptions bool) ([]CourseClass, url.Values, error) {
+	key := catalogPageKey(semester, query)
+	if !refresh {
+		options, haveOptions := s.pageOptions.get(key)
+		catKey, isCatalog := catalogKey(semester, query)
+		switch {
+		case !isCatalog && haveOptions:
+			// The unfiltered page is the student's own schedule, only read
+			// for its options.
+			return nil, options.data, nil
+		case isCatalog && (haveOptions || !needOptions):
+			if entry, ok := s.catalog.get(catKey); ok {
+				return entry.data, options.data, nil
+			}
+		}
+	}
+
+	page, meta, err := s.scrapeSchedulePage(r, studentID, semester, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta.Anomaly != nil {
+		return nil, nil, fmt.Errorf("anomalous page skipped: %s", strings.Join(meta.Anomaly.Reasons, "; "))
+	}
+	if catKey, ok := catalogKey(semester, query); ok {
+		s.catalog.set(catKey, page.Classes, meta.FetchedAt)
+	}
+	s.pageOptions.set(key, page.FilterOptions, meta.FetchedAt)
+	return page.Classes, page.FilterOptions, nil
+}
+
+// Reports whether err means no page can be fetched now, so the whole
+// catalog should fail rather than list the page as failed.
+func fatalCatalogError(err error) bool {
+	var missing *missingCookieError
+	return errors.Is(err, errUpstreamMaintenance) || errors.As(err, &missing) || errors.Is(err, context.Canceled)
+}
+
+// Fetches pages, crawlWorkers at a time, and passes each page's classes and
+// filter options to add. It returns the first error fatalCatalogError
+// reports on, after the running fetches end.
+func (s *Server) fetchCatalogPages(r *http.Request, studentID, semester string, pages []CrawlPage, refresh, needOptions bool, add func(CrawlPage, []CourseClass, url.Values, error)) error {
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+	r = r.WithContext(ctx)
+
+	sem := make(chan struct{}, max(crawlWorkers, 1))
+	var wg sync.WaitGroup
+	for _, page := range pages {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			classes, options, err := s.fullCatalogPage(r, studentID, semester, page.query(), refresh, needOptions)
+			if err != nil && fatalCatalogError(err) {
+				cancel(err)
+				return
+			}
+			add(page, classes, options, err)
+		}()
+	}
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// Assembles the full catalog of semester: the faculties from the unfiltered
+// schedule page, then every faculty page, then the page of every program
+// the faculty pages offer. Classes on several pages are listed once.
+func (s *Server) buildFullCatalog(r *http.Request, studentID, semester string, refresh bool) (FullCatalog, error) {
+	catalog := FullCatalog{Semester: semester, Failed: []CatalogPageError{}}
+	_, options, err := s.fullCatalogPage(r, studentID, semester, nil, refresh, true)
+	if err != nil {
+		return catalog, err
+	}
+	faculties, err := catalogFacultyPages(options)
+	if err != nil {
+		return catalog, err
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var programs []CrawlPage
+	add := func(page CrawlPage, classes []CourseClass, options url.Values, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			log.Printf("catalog page failed fakultas=%s prodi=%s semester=%s err=%v", page.Fakultas, page.Prodi, semester, err)
+			catalog.Failed = append(catalog.Failed, CatalogPageError{Fakultas: page.Fakultas, Prodi: page.Prodi, Error: err.Error()})
+			return
+		}
+		catalog.Pages++
+		for _, c := range classes {
+			key := strings.ToUpper(c.Code) + "/" + c.ClassNo + "/" + c.Category
+			if !seen[key] {
+				seen[key] = true
+				catalog.Classes = append(catalog.Classes, c)
+			}
+		}
+		if page.Prodi == "" {
+			programs = append(programs, catalogProgramPages(page.Fakultas, options)...)
+		}
+	}
+	if err := s.fetchCatalogPages(r, studentID, semester, faculties, refresh, true, add); err != nil {
+		return catalog, err
+	}
+	if err := s.fetchCatalogPages(r, studentID, semester, programs, refresh, false, add); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Classes == nil {
+		catalog.Classes = []CourseClass{}
+	}
+	sortClasses(catalog.Classes)
+	slices.SortFunc(catalog.Failed, func(a, b CatalogPageError) int {
+		return cmp.Or(strings.Compare(a.Fakultas, b.Fakultas), strings.Compare(a.Prodi, b.Prodi))
+	})
+	return catalog, nil
+}
+
+// GET /api/catalog?student_id=...&semester=...
+func (s *Server) fullCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	refresh := query.Get("refresh") == "true"
+
+	var release func()
+	entry, cached, err := s.fullCatalog(r.Context(), semester, refresh, func() (FullCatalog, error) {
+		var ok bool
+		if release, ok = admitUpstream(w, r); !ok {
+			return FullCatalog{}, errResponseWritten
+		}
+		defer release()
+		return s.buildFullCatalog(r, studentID, semester, refresh)
+	})
+	if errors.Is(err, errResponseWritten) {
+		return
+	}
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+
+	meta := &Meta{FetchedAt: entry.fetchedAt, Cached: cached}
+	if relative {
+		meta.Semester = semester
+	}
+	writeSuccessWithMeta(w, entry.data, meta)
+}
+
+// Returned by a build that has already written the error response.
+var errResponseWritten = errors.New("response already written")
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFullCatalogHandler(t *testing.T) {
+	old := crawlWorkers
+	crawlWorkers = 2
+	t.Cleanup(func() { crawlWorkers = old })
+
+	var mu sync.Mutex
+	var fetched []string
+	var inFlight, maxInFlight atomic.Int32
+	var broken atomic.Bool
+	broken.Store(true)
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		q := r.URL.Query()
+		mu.Lock()
+		fetched = append(fetched, q.Encode())
+		mu.Unlock()
+		var selects string
+		codes := []string{"KU1101"}
+		switch {
+		case q.Get("fakultas") == "":
+			selects = `<select name="fakultas"><option value="">Semua</option><option value="F1">F1</option><option value="F2">F2</option></select>`
+		case q.Get("prodi") == "102" && broken.Load():
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		case q.Get("prodi") != "":
+			codes = []string{"IF" + q.Get("prodi"), "KU1101"}
+		case q.Get("fakultas") == "F1":
+			selects = `<select name="prodi"><option value="101">101</option><option value="102">102</option></select>`
+			codes = []string{"IF1000", "KU1101"}
+		}
+		var rows string
+		for _, code := range codes {
+			rows += fmt.Sprintf(`<tr><td>1</td><td></td><td>%s</td><td>Kuliah</td><td>3</td><td>01</td><td>60</td><td></td><td></td><td></td></tr>`, code)
+		}
+		fmt.Fprintf(w, `<html><body><form>%s</form><table class="table"><tbody>%s</tbody></table></body></html>`, selects, rows)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func() (FullCatalog, Meta, []string) {
+		t.Helper()
+		mu.Lock()
+		fetched = nil
+		mu.Unlock()
+		req := httptest.NewRequest("GET", "/api/catalog?student_id=123&semester=2026-1", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", w.Code, w.Body)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return decodeData[FullCatalog](t, w), decodeMeta(t, w), fetched
+	}
+
+	catalog, _, pages := get()
+	if len(pages) != 5 || catalog.Pages != 3 {
+		t.Errorf("fetched %d pages, merged %d", len(pages), catalog.Pages)
+	}
+	if len(catalog.Failed) != 1 || catalog.Failed[0].Prodi != "102" {
+		t.Errorf("failed = %+v", catalog.Failed)
+	}
+	var codes []string
+	for _, c := range catalog.Classes {
+		codes = append(codes, c.Code)
+	}
+	if got := strings.Join(codes, ","); got != "IF1000,IF101,KU1101" {
+		t.Errorf("classes = %s", got)
+	}
+	if m := maxInFlight.Load(); m > 2 {
+		t.Errorf("%d pages fetched at once, want at most 2", m)
+	}
+
+	// An incomplete catalog is not cached, but its pages are, so only the
+	// failed page is fetched again.
+	broken.Store(false)
+	catalog, meta, pages := get()
+	if len(pages) != 1 || !strings.Contains(pages[0], "prodi=102") || meta.Cached {
+		t.Errorf("fetched %v, cached %v", pages, meta.Cached)
+	}
+	if len(catalog.Failed) != 0 || len(catalog.Classes) != 4 {
+		t.Errorf("catalog = %+v", catalog)
+	}
+
+	if _, meta, pages := get(); len(pages) != 0 || !meta.Cached {
+		t.Errorf("fetched %v, cached %v", pages, meta.Cached)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"six-scraper-go/scraper"
+)
+
+// Adds the SIX pages recorded in testdata/cassettes to the fuzz corpus.
+func addCassetteSeeds(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "cassettes", "*.json"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		var c Cassette
+		if err := json.Unmarshal(data, &c); err != nil {
+			f.Fatal(err)
+		}
+		for _, u := range c.Upstream {
+			f.Add(u.Body)
+		}
+	}
+}
+
+func FuzzParseClasses(f *testing.F) {
+	f.Add(testScheduleHTML)
+	f.Add(`<table class="table"><tbody><tr><td>1<td>2<td>X<td>4<td>5<td>6<td>7<td><ul><li>a<td><td><ul><li>a/b/c/d/e/f`)
+	f.Add(`<table class="table"><tbody><tr>` + strings.Repeat("<td>", 10) + `</table></tbody></tr>`)
+	addCassetteSeeds(f)
+
+	f.Fuzz(func(t *testing.T, html string) {
+		classes := parseClasses(docFromHTML(html))
+		sortClasses(classes)
+		for _, c := range classes {
+			if c.Code == "" {
+				t.Error("parsed class with empty code")
+			}
+			if len(c.Notes) > scraper.MaxCellText || len(c.Name) > scraper.MaxCellText {
+				t.Errorf("cell text longer than %d bytes", scraper.MaxCellText)
+			}
+			for _, s := range c.Schedules {
+				if strings.Contains(s.Room, "/") {
+					t.Errorf("schedule %+v mixes nested entries", s)
+				}
+			}
+		}
+	})
+}
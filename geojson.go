@@ -0,0 +1,145 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"six-scraper-go/textnorm"
+)
+
+// With SIX_BUILDINGS_FILE pointing at a list of building coordinates,
+// /api/schedule?format=geojson returns the week as GeoJSON points, one per
+// meeting, so map apps can plot where a student needs to be and when.
+var buildingsFile = envString("SIX_BUILDINGS_FILE", "")
+
+// A campus building. Rooms whose code starts with one of its prefixes, e.g.
+// "76" for room 7602, are located there.
+type Building struct {
+	Name     string   `json:"name"`
+	Prefixes []string `json:"prefixes"`
+	Lat      float64  `json:"lat"`
+	Lon      float64  `json:"lon"`
+}
+
+// Reads a JSON array of buildings from path.
+func loadBuildings(path string) ([]Building, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var buildings []Building
+	if err := json.Unmarshal(data, &buildings); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, b := range buildings {
+		if b.Lat < -90 || b.Lat > 90 || b.Lon < -180 || b.Lon > 180 {
+			return nil, fmt.Errorf("%s: building %d (%s) has invalid coordinates", path, i, b.Name)
+		}
+	}
+	return buildings, nil
+}
+
+// Returns the building of the longest prefix matching room, compared with
+// textnorm.Key.
+func locateRoom(buildings []Building, room string) (Building, bool) {
+	room = textnorm.Key(room)
+	var best Building
+	bestLen := 0
+	for _, b := range buildings {
+		for _, p := range b.Prefixes {
+			p = textnorm.Key(p)
+			if p != "" && len(p) > bestLen && strings.HasPrefix(room, p) {
+				best, bestLen = b, len(p)
+			}
+		}
+	}
+	return best, bestLen > 0
+}
+
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+	// Rooms of in-person meetings that no building prefix matched. A
+	// foreign member, which GeoJSON readers ignore.
+	UnlocatedRooms []string `json:"unlocated_rooms"`
+}
+
+type GeoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   GeoJSONPoint    `json:"geometry"`
+	Properties MeetingLocation `json:"properties"`
+}
+
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // longitude, latitude
+}
+
+// One meeting of a class, as a feature's properties.
+type MeetingLocation struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	ClassNo  string `json:"class_no"`
+	Day      string `json:"day"`
+	Weekday  int    `json:"weekday,omitempty"` // ISO 8601: 1 for Senin to 7 for Minggu
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Room     string `json:"room"`
+	Building string `json:"building"`
+	Activity string `json:"activity"`
+	Method   string `json:"method"`
+}
+
+// Returns the meetings of classes as GeoJSON points, ordered by day and
+// time. Online meetings and meetings without a room are left out.
+func scheduleGeoJSON(classes []CourseClass, buildings []Building) GeoJSONFeatureCollection {
+	fc := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{}, UnlocatedRooms: []string{}}
+	for _, c := range classes {
+		for _, e := range c.Schedules {
+			if e.Room == "" || strings.EqualFold(e.Method, "online") {
+				continue
+			}
+			b, ok := locateRoom(buildings, e.Room)
+			if !ok {
+				if !slices.Contains(fc.UnlocatedRooms, e.Room) {
+					fc.UnlocatedRooms = append(fc.UnlocatedRooms, e.Room)
+				}
+				continue
+			}
+			start, end, _ := strings.Cut(e.Time, "-")
+			props := MeetingLocation{
+				Code: c.Code, Name: c.Name, ClassNo: c.ClassNo,
+				Day: e.Day, Start: strings.TrimSpace(start), End: strings.TrimSpace(end),
+				Room: e.Room, Building: b.Name, Activity: e.Activity, Method: e.Method,
+			}
+			if rank, ok := dayOrder[e.Day]; ok {
+				props.Weekday = rank + 1
+			}
+			fc.Features = append(fc.Features, GeoJSONFeature{
+				Type:       "Feature",
+				Geometry:   GeoJSONPoint{Type: "Point", Coordinates: [2]float64{b.Lon, b.Lat}},
+				Properties: props,
+			})
+		}
+	}
+	slices.SortStableFunc(fc.Features, func(a, b GeoJSONFeature) int {
+		pa, pb := a.Properties, b.Properties
+		return cmp.Or(cmp.Compare(dayRank(pa.Day), dayRank(pb.Day)), cmp.Compare(pa.Start, pb.Start))
+	})
+	slices.Sort(fc.UnlocatedRooms)
+	return fc
+}
+
+func writeGeoJSON(w http.ResponseWriter, fc GeoJSONFeatureCollection) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	noteEncoded(w, fc)
+	if err := json.NewEncoder(w).Encode(fc); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testBuildings = []Building{
+	{Name: "Labtek V", Prefixes: []string{"76"}, Lat: -6.8906, Lon: 107.6098},
+	{Name: "Labtek V Lantai 6", Prefixes: []string{"760"}, Lat: -6.8907, Lon: 107.6099},
+	{Name: "GKU Timur", Prefixes: []string{"GKU Timur"}, Lat: -6.8880, Lon: 107.6117},
+}
+
+func TestLocateRoom(t *testing.T) {
+	tests := []struct{ room, want string }{
+		{"7602", "Labtek V Lantai 6"},
+		{"7610", "Labtek V"},
+		{"gku timur 1", "GKU Timur"},
+		{"9009", ""},
+	}
+	for _, tt := range tests {
+		b, ok := locateRoom(testBuildings, tt.room)
+		if got := b.Name; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("locateRoom(%q) = %q, %v, want %q", tt.room, got, ok, tt.want)
+		}
+	}
+}
+
+func TestScheduleGeoJSON(t *testing.T) {
+	classes := []CourseClass{
+		{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Rabu", Time: "13:00-15:00", Room: "7610", Activity: "Kuliah", Method: "Offline"},
+			{Day: "Senin", Time: "09:00-11:00", Room: "7602", Activity: "Kuliah", Method: "Offline"},
+			{Day: "Selasa", Time: "07:00-09:00", Room: "7603", Activity: "Kuliah", Method: "Online"},
+			{Day: "Kamis", Time: "07:00-09:00", Room: "9009", Activity: "Praktikum", Method: "Offline"},
+		}},
+	}
+	fc := scheduleGeoJSON(classes, testBuildings)
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("fc = %+v", fc)
+	}
+	first := fc.Features[0]
+	if first.Geometry.Coordinates != [2]float64{107.6099, -6.8907} {
+		t.Errorf("coordinates = %v, want longitude first", first.Geometry.Coordinates)
+	}
+	want := MeetingLocation{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", Day: "Senin", Weekday: 1, Start: "09:00", End: "11:00", Room: "7602", Building: "Labtek V Lantai 6", Activity: "Kuliah", Method: "Offline"}
+	if first.Properties != want {
+		t.Errorf("properties = %+v, want %+v", first.Properties, want)
+	}
+	if fc.Features[1].Properties.Weekday != 3 {
+		t.Errorf("second feature = %+v", fc.Features[1].Properties)
+	}
+	if len(fc.UnlocatedRooms) != 1 || fc.UnlocatedRooms[0] != "9009" {
+		t.Errorf("unlocated = %v", fc.UnlocatedRooms)
+	}
+}
+
+func TestScheduleHandler_GeoJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buildings.json")
+	data, _ := json.Marshal(testBuildings)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := buildingsFile
+	defer func() { buildingsFile = old }()
+
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	get := func(srv *Server) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2025-2&format=geojson", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	buildingsFile = ""
+	if w := get(newTestServer(mock.URL)); w.Code != http.StatusNotFound {
+		t.Errorf("without buildings: status %d, want 404", w.Code)
+	}
+
+	buildingsFile = path
+	w := get(newTestServer(mock.URL))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var fc GeoJSONFeatureCollection
+	if err := json.NewDecoder(w.Body).Decode(&fc); err != nil {
+		t.Fatal(err)
+	}
+	// testScheduleHTML has two offline meetings, in rooms 7602 and 7604.
+	if len(fc.Features) != 2 {
+		t.Errorf("features = %+v", fc.Features)
+	}
+}
+
+func TestLoadBuildings_InvalidCoordinates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buildings.json")
+	os.WriteFile(path, []byte(`[{"name":"X","prefixes":["1"],"lat":107.6,"lon":-6.9}]`), 0o644)
+	if _, err := loadBuildings(path); err == nil {
+		t.Error("expected an error for swapped coordinates")
+	}
+}
@@ -2,7 +2,10 @@ module six-scraper-go
 
 go 1.25.5
 
-require github.com/PuerkitoBio/goquery v1.11.0
+require (
+	github.com/PuerkitoBio/goquery v1.11.0
+	golang.org/x/text v0.31.0
+)
 
 require (
 	github.com/andybalholm/cascadia v1.3.3 // indirect
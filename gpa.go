@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+// Projected grade point averages for a what-if scenario. IP is the average
+// of one semester, IPK the cumulative average.
+type GPAProjection struct {
+	StudentID     string  `json:"student_id"`
+	Semester      string  `json:"semester,omitempty"`
+	IP            float64 `json:"ip"`
+	IPK           float64 `json:"ipk"`
+	CurrentIPK    float64 `json:"current_ipk"`
+	SKSIP         int     `json:"sks_ip"`
+	SKSIPK        int     `json:"sks_ipk"`
+	CurrentSKSIPK int     `json:"current_sks_ipk"`
+	// Ungraded lists in-progress courses with no hypothetical grade. They
+	// are left out of both averages.
+	Ungraded []string `json:"ungraded"`
+}
+
+// Computes IP and IPK with the hypothetical grades applied to in-progress
+// courses, following ITB rules: averages are weighted by SKS, an E counts
+// with zero points, and a retaken course counts once, with its best grade,
+// in IPK. IP is for the semester of the in-progress courses, or the latest
+// semester on the transcript if nothing is in progress. Averages are
+// rounded to two decimals as on ITB transcripts.
+func projectGPA(transcript []TranscriptCourse, grades map[string]string) GPAProjection {
+	var p GPAProjection
+	p.Ungraded = []string{}
+
+	projected := slices.Clone(transcript)
+	for i, c := range projected {
+		if c.Grade != "" {
+			continue
+		}
+		if g, ok := grades[c.Code]; ok {
+			projected[i].Grade = g
+			p.Semester = c.Semester
+		} else {
+			p.Ungraded = append(p.Ungraded, c.Code)
+		}
+	}
+	if p.Semester == "" {
+		for _, c := range transcript {
+			p.Semester = max(p.Semester, c.Semester)
+		}
+	}
+	sort.Strings(p.Ungraded)
+
+	p.CurrentIPK, p.CurrentSKSIPK = cumulativeGPA(transcript)
+	p.IPK, p.SKSIPK = cumulativeGPA(projected)
+
+	var points float64
+	for _, c := range projected {
+		if gp, ok := gradePoints[c.Grade]; ok && c.Semester == p.Semester {
+			points += gp * float64(c.SKS)
+			p.SKSIP += c.SKS
+		}
+	}
+	p.IP = roundGPA(points, p.SKSIP)
+	return p
+}
+
+// Returns the SKS-weighted average over the best grade of each course, and
+// the SKS it covers.
+func cumulativeGPA(courses []TranscriptCourse) (float64, int) {
+	best := make(map[string]TranscriptCourse)
+	for _, c := range courses {
+		gp, ok := gradePoints[c.Grade]
+		if !ok {
+			continue
+		}
+		if prev, seen := best[c.Code]; !seen || gp > gradePoints[prev.Grade] {
+			best[c.Code] = c
+		}
+	}
+	var points float64
+	sks := 0
+	for _, c := range best {
+		points += gradePoints[c.Grade] * float64(c.SKS)
+		sks += c.SKS
+	}
+	return roundGPA(points, sks), sks
+}
+
+func roundGPA(points float64, sks int) float64 {
+	if sks == 0 {
+		return 0
+	}
+	return math.Round(points/float64(sks)*100) / 100
+}
+
+// The averages of one semester, computed from the transcript, next to those
+// SIX shows.
+type SemesterGPA struct {
+	Semester string  `json:"semester"`
+	IP       float64 `json:"ip"`
+	SKS      int     `json:"sks"`
+	// IPK and SKSIPK are cumulative up to and including this semester.
+	IPK    float64 `json:"ipk"`
+	SKSIPK int     `json:"sks_ipk"`
+	// Reported is what SIX shows for the semester, if anything, and
+	// Mismatch is set when one of its values differs from the computed one.
+	Reported *ReportedGPA `json:"reported"`
+	Mismatch bool         `json:"mismatch"`
+}
+
+// A student's averages as returned by /api/gpa.
+type GPAReport struct {
+	StudentID string        `json:"student_id"`
+	IPK       float64       `json:"ipk"`
+	SKSIPK    int           `json:"sks_ipk"`
+	Semesters []SemesterGPA `json:"semesters"`
+}
+
+// Computes the IP and cumulative IPK of every semester on the transcript
+// that has a grade, oldest first, by the rules of projectGPA. Each semester
+// is paired with the averages SIX reports for it.
+func semesterGPAs(history GradeHistory) []SemesterGPA {
+	var semesters []string
+	for _, c := range history.Courses {
+		if _, graded := gradePoints[c.Grade]; graded && c.Semester != "" && !slices.Contains(semesters, c.Semester) {
+			semesters = append(semesters, c.Semester)
+		}
+	}
+	slices.Sort(semesters)
+
+	result := make([]SemesterGPA, 0, len(semesters))
+	for _, sem := range semesters {
+		g := SemesterGPA{Semester: sem}
+		var points float64
+		var upTo []TranscriptCourse
+		for _, c := range history.Courses {
+			if c.Semester == "" || c.Semester > sem {
+				continue
+			}
+			upTo = append(upTo, c)
+			if gp, ok := gradePoints[c.Grade]; ok && c.Semester == sem {
+				points += gp * float64(c.SKS)
+				g.SKS += c.SKS
+			}
+		}
+		g.IP = roundGPA(points, g.SKS)
+		g.IPK, g.SKSIPK = cumulativeGPA(upTo)
+		for _, rep := range history.Reported {
+			if rep.Semester == sem {
+				g.Reported = &rep
+				g.Mismatch = differs(rep.IP, g.IP) || differs(rep.IPK, g.IPK) || (rep.SKS != nil && *rep.SKS != g.SKS)
+				break
+			}
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+// Reports whether a reported average differs from a computed one by more
+// than rounding to two decimals explains.
+func differs(reported *float64, computed float64) bool {
+	return reported != nil && math.Abs(*reported-computed) > 0.005+1e-9
+}
+
+// GET /api/gpa?student_id=...
+func (s *Server) gpaHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	studentID := r.URL.Query().Get("student_id")
+	history, err := s.provider.FetchGradeHistory(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	report := GPAReport{StudentID: studentID, Semesters: semesterGPAs(history)}
+	report.IPK, report.SKSIPK = cumulativeGPA(history.Courses)
+	writeSuccess(w, report)
+}
+
+type whatIfRequest struct {
+	StudentID string            `json:"student_id"`
+	Grades    map[string]string `json:"grades"`
+}
+
+// POST /api/gpa/what-if
+func (s *Server) gpaWhatIfHandler(w http.ResponseWriter, r *http.Request) {
+	var body whatIfRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	transcript, err := s.provider.FetchTranscript(r, body.StudentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	p := projectGPA(transcript, body.Grades)
+	p.StudentID = body.StudentID
+	writeSuccess(w, p)
+}
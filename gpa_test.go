@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProjectGPA(t *testing.T) {
+	transcript := []TranscriptCourse{
+		{Code: "MA1101", SKS: 4, Semester: "2023-1", Grade: "A"},
+		{Code: "FI1101", SKS: 4, Semester: "2023-1", Grade: "E"},
+		{Code: "FI1101", SKS: 4, Semester: "2024-3", Grade: "B"}, // retake
+		{Code: "IF2211", SKS: 3, Semester: "2025-2"},
+		{Code: "IF2230", SKS: 3, Semester: "2025-2"},
+		{Code: "IF2240", SKS: 2, Semester: "2025-2"},
+	}
+
+	p := projectGPA(transcript, map[string]string{"IF2211": "A", "IF2230": "BC"})
+	// Current: MA1101 A (16) + FI1101 best B (12) over 8 SKS.
+	if p.CurrentIPK != 3.5 || p.CurrentSKSIPK != 8 {
+		t.Errorf("current IPK = %v over %d SKS, want 3.5 over 8", p.CurrentIPK, p.CurrentSKSIPK)
+	}
+	// IP: A (12) + BC (7.5) over 6 SKS = 3.25.
+	if p.Semester != "2025-2" || p.IP != 3.25 || p.SKSIP != 6 {
+		t.Errorf("IP = %v over %d SKS in %s, want 3.25 over 6 in 2025-2", p.IP, p.SKSIP, p.Semester)
+	}
+	// IPK: 16 + 12 + 12 + 7.5 = 47.5 over 14 SKS = 3.39.
+	if p.IPK != 3.39 || p.SKSIPK != 14 {
+		t.Errorf("IPK = %v over %d SKS, want 3.39 over 14", p.IPK, p.SKSIPK)
+	}
+	if fmt.Sprint(p.Ungraded) != "[IF2240]" {
+		t.Errorf("ungraded = %v, want [IF2240]", p.Ungraded)
+	}
+}
+
+func TestProjectGPA_NothingInProgress(t *testing.T) {
+	p := projectGPA([]TranscriptCourse{
+		{Code: "MA1101", SKS: 4, Semester: "2023-1", Grade: "AB"},
+		{Code: "MA1201", SKS: 4, Semester: "2023-2", Grade: "C"},
+	}, nil)
+	if p.Semester != "2023-2" || p.IP != 2 || p.IPK != 2.75 {
+		t.Errorf("projection = %+v", p)
+	}
+}
+
+func TestSemesterGPAs(t *testing.T) {
+	ip, ipk := 3.0, 3.5
+	history := GradeHistory{
+		Courses: []TranscriptCourse{
+			{Code: "MA1101", SKS: 4, Semester: "2023-1", Grade: "A"},
+			{Code: "FI1101", SKS: 4, Semester: "2023-1", Grade: "E"},
+			{Code: "FI1101", SKS: 4, Semester: "2024-3", Grade: "B"}, // retake
+			{Code: "IF2211", SKS: 3, Semester: "2025-2"},
+		},
+		Reported: []ReportedGPA{{Semester: "2024-3", IP: &ip, IPK: &ipk}},
+	}
+	got := semesterGPAs(history)
+	if len(got) != 2 {
+		t.Fatalf("got %d semesters, want 2 (2025-2 has no grades): %+v", len(got), got)
+	}
+	// 2023-1: A (16) + E (0) over 8 SKS.
+	if g := got[0]; g.Semester != "2023-1" || g.IP != 2 || g.SKS != 8 || g.IPK != 2 || g.SKSIPK != 8 || g.Reported != nil || g.Mismatch {
+		t.Errorf("2023-1 = %+v", g)
+	}
+	// 2024-3: the retake replaces the E in IPK, (16 + 12) over 8 SKS.
+	if g := got[1]; g.Semester != "2024-3" || g.IP != 3 || g.SKS != 4 || g.IPK != 3.5 || g.SKSIPK != 8 || g.Reported == nil || g.Mismatch {
+		t.Errorf("2024-3 = %+v", g)
+	}
+
+	ip = 3.25
+	if got := semesterGPAs(history); !got[1].Mismatch {
+		t.Error("reported IP 3.25 against computed 3: no mismatch")
+	}
+}
+
+func TestGPAHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testTranscriptHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/gpa?student_id=123", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	got := decodeData[GPAReport](t, w)
+	if got.StudentID != "123" || got.IPK != 2 || got.SKSIPK != 8 || len(got.Semesters) != 1 {
+		t.Fatalf("report = %+v", got)
+	}
+	// The test transcript reports an IP of 3.50 for 2023-1, but its grades
+	// average 2.
+	if g := got.Semesters[0]; g.IP != 2 || g.Reported == nil || g.Reported.IP == nil || *g.Reported.IP != 3.5 || !g.Mismatch {
+		t.Errorf("2023-1 = %+v", g)
+	}
+}
+
+func TestGPAWhatIfHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testTranscriptHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/gpa/what-if", strings.NewReader(body))
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"student_id":"123","grades":{"IF2211":"AB"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if p := decodeData[GPAProjection](t, w); p.IP != 3.5 || p.IPK != 2.41 {
+		t.Errorf("projection = %+v, want IP 3.5 and IPK 2.41", p)
+	}
+
+	if w := post(`{"student_id":"123","grades":{"IF2211":"F"}}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid grade: got status %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+)
+
+// SIX page listing a student's grades for one semester, released or not.
+func gradesPath(studentID, semester string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s+%s/akademik/nilai", studentID, semester)
+}
+
+// One course on the semester grade page.
+type GradeEntry struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	SKS     int    `json:"sks"`
+	ClassNo string `json:"class_no"`
+	// Grade is the index grade, e.g. "AB", or empty until it is released.
+	Grade string `json:"grade"`
+	// Status is what SIX says about the grade, e.g. "Final", as shown.
+	Status string `json:"status"`
+}
+
+// Parses the semester grade page. Like parseTranscript it finds columns by
+// header text; the grade column is "Indeks" or "Nilai".
+func parseGrades(doc *goquery.Document) []GradeEntry {
+	var grades []GradeEntry
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := scraper.HeaderColumns(table)
+		code, hasCode := cols["kode"]
+		grade, hasGrade := cols["indeks"]
+		if !hasGrade {
+			grade, hasGrade = cols["nilai"]
+		}
+		if !hasCode || !hasGrade {
+			return
+		}
+		name, hasName := cols["nama"]
+		sks, hasSKS := cols["sks"]
+		classNo, hasClass := cols["kelas"]
+		status, hasStatus := cols["status"]
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			g := GradeEntry{
+				Code:  scraper.CellText(cells, code),
+				Grade: strings.ToUpper(scraper.CellText(cells, grade)),
+			}
+			if hasName {
+				g.Name = scraper.CellText(cells, name)
+			}
+			if hasSKS {
+				g.SKS, _ = strconv.Atoi(scraper.CellText(cells, sks))
+			}
+			if hasClass {
+				g.ClassNo = scraper.CellText(cells, classNo)
+			}
+			if hasStatus {
+				g.Status = scraper.CellText(cells, status)
+			}
+			// A footer such as "Total SKS" spans every column, so its
+			// text shows up as both code and grade.
+			if g.Code != "" && !strings.EqualFold(g.Code, g.Grade) {
+				grades = append(grades, g)
+			}
+		}
+	})
+	return grades
+}
+
+// GET /api/grades?student_id=...&semester=...
+func (s *Server) gradesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	// Grades are private, so cached grades are only served to the same SIX
+	// session that fetched them.
+	key := gradesPath(studentID, semester) + "#" + sessionKey(r)
+
+	entry, cached := ttlEntry[[]GradeEntry]{}, false
+	if query.Get("refresh") != "true" {
+		entry, cached = s.grades.get(key)
+	}
+	if !cached {
+		release, ok := admitUpstream(w, r)
+		if !ok {
+			return
+		}
+		defer release()
+
+		grades, err := s.provider.FetchGrades(r, studentID, semester)
+		if err != nil {
+			writeUpstreamError(w, r, err)
+			return
+		}
+		if grades == nil {
+			grades = []GradeEntry{}
+		}
+		log.Printf("parsed grades=%d student_id=%s semester=%s", len(grades), studentID, semester)
+		entry = ttlEntry[[]GradeEntry]{data: grades, fetchedAt: time.Now()}
+		s.grades.put(key, entry)
+	}
+
+	meta := &Meta{FetchedAt: entry.fetchedAt, Cached: cached}
+	if relative {
+		meta.Semester = semester
+	}
+	writeSuccessWithMeta(w, entry.data, meta)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testGradesHTML = `<html><body>
+<table class="table">
+  <thead><tr><th>No</th><th>Kode</th><th>Nama Mata Kuliah</th><th>SKS</th><th>Kelas</th><th>Indeks</th><th>Status</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>IF2211</td><td>Strategi  Algoritma</td><td>3</td><td>01</td><td>ab</td><td>Final</td></tr>
+    <tr><td>2</td><td>IF2230</td><td>Sistem Operasi</td><td>3</td><td>02</td><td></td><td>Belum keluar</td></tr>
+    <tr><td colspan="7">Total SKS 6</td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+func TestParseGrades(t *testing.T) {
+	got := parseGrades(docFromHTML(testGradesHTML))
+	want := []GradeEntry{
+		{Code: "IF2211", Name: "Strategi Algoritma", SKS: 3, ClassNo: "01", Grade: "AB", Status: "Final"},
+		{Code: "IF2230", Name: "Sistem Operasi", SKS: 3, ClassNo: "02", Status: "Belum keluar"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestGradesHandler_Caches(t *testing.T) {
+	var hits atomic.Int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/mahasiswa:123+2025-1/akademik/nilai") {
+			http.NotFound(w, r)
+			return
+		}
+		hits.Add(1)
+		fmt.Fprint(w, testGradesHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/grades?"+query, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("student_id=123&semester=2025-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if grades := decodeData[[]GradeEntry](t, w); len(grades) != 2 || grades[0].Grade != "AB" {
+		t.Errorf("grades = %+v", grades)
+	}
+	if meta := decodeMeta(t, get("student_id=123&semester=2025-1")); !meta.Cached {
+		t.Error("second request was not served from cache")
+	}
+	get("student_id=123&semester=2025-1&refresh=true")
+	if n := hits.Load(); n != 2 {
+		t.Errorf("upstream hit %d times, want 2", n)
+	}
+
+	if w := get("student_id=123&semester=2025"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("bad semester: got status %d, want 422", w.Code)
+	}
+
+	// Another session asking for the same student's grades is not served
+	// the cached ones.
+	req := httptest.NewRequest("GET", "/api/grades?student_id=123&semester=2025-1", nil)
+	req.AddCookie(&http.Cookie{Name: "nissin", Value: "other"})
+	req.AddCookie(&http.Cookie{Name: "khongguan", Value: "other"})
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if meta := decodeMeta(t, w); meta.Cached {
+		t.Error("grades cached for one session were served to another")
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("upstream hit %d times, want 3", n)
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The grade watcher polls the transcript of each watched student and sends a
+// grade.released notification as soon as a course gets a letter grade. Like
+// the pekan prefetcher it keeps the student's SIX cookies in memory, so it is
+// opt-in; turn it on for exam season. A watch whose grades stay the same is
+// checked less often (see poll.go).
+var (
+	gradeWatchEnabled  = envBool("SIX_GRADE_WATCH", false)
+	gradeWatchInterval = envDuration("SIX_GRADE_WATCH_INTERVAL", 15*time.Minute)
+	gradeWatchMax      = envInt("SIX_GRADE_WATCH_MAX", 500)
+)
+
+type GradeWatch struct {
+	ID            string     `json:"id"`
+	StudentID     string     `json:"student_id"`
+	URL           string     `json:"url"`
+	Secret        string     `json:"secret,omitempty"` // only returned on creation
+	CreatedAt     time.Time  `json:"created_at"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	NextCheckAt   *time.Time `json:"next_check_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Released      int        `json:"released"` // grades notified so far
+	ConsentID     string     `json:"consent_id"`
+	// ExpiresAt is the end of the semester in session when the watch was
+	// created; see expiry.go.
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	ExpiryNoticeSentAt *time.Time `json:"expiry_notice_sent_at,omitempty"`
+
+	owner    string
+	auth     http.Header // Cookie and X-Six-* headers of the creating request
+	notifier Notifier
+	grades   map[string]string // course code to grade at the last check, nil before the first
+	backoff  pollBackoff
+}
+
+// Data of a grade.released notification.
+type ReleasedGrade struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Grade string `json:"grade"`
+}
+
+type gradeWatcher struct {
+	mu      sync.Mutex
+	watches map[string]*GradeWatch
+}
+
+func newGradeWatcher() *gradeWatcher {
+	return &gradeWatcher{watches: make(map[string]*GradeWatch)}
+}
+
+func (gw *GradeWatch) view() GradeWatch {
+	v := *gw
+	v.Secret = ""
+	if gw.LastCheckedAt != nil {
+		next := gw.nextCheck()
+		v.NextCheckAt = &next
+	}
+	return v
+}
+
+// Returns when the watch is next checked. A watch never checked is due at
+// once.
+func (gw *GradeWatch) nextCheck() time.Time {
+	if gw.LastCheckedAt == nil {
+		return time.Time{}
+	}
+	return gw.LastCheckedAt.Add(gw.backoff.stretch(gradeWatchInterval))
+}
+
+// Returns the courses that have a grade in courses but had none in prev,
+// which maps course codes to their previous grade.
+func releasedGrades(prev map[string]string, courses []TranscriptCourse) []ReleasedGrade {
+	var released []ReleasedGrade
+	for _, c := range courses {
+		if c.Grade != "" && prev[c.Code] == "" {
+			released = append(released, ReleasedGrade{Code: c.Code, Name: c.Name, Grade: c.Grade})
+		}
+	}
+	return released
+}
+
+// Runs checkGrades every gradeWatchInterval until ctx is done.
+func (s *Server) runGradeWatcher(ctx context.Context) {
+	ticker := time.NewTicker(gradeWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if scrapingPaused() {
+				log.Printf("grade watch skipped: SIX maintenance")
+				continue
+			}
+			s.checkGrades(ctx, now)
+		}
+	}
+}
+
+// Fetches the transcript of every watch due at now and notifies about new
+// grades. The first check of a watch only records the grades already there.
+func (s *Server) checkGrades(ctx context.Context, now time.Time) {
+	s.gradeWatches.mu.Lock()
+	var watches []GradeWatch
+	for _, gw := range s.gradeWatches.watches {
+		if !now.Before(gw.nextCheck()) {
+			watches = append(watches, *gw)
+		}
+	}
+	s.gradeWatches.mu.Unlock()
+
+	ctx = withPriority(ctx, priorityBatch)
+	for _, gw := range watches {
+		if ctx.Err() != nil || scrapingPaused() {
+			return
+		}
+		s.refresher.run(ctx, refreshGradeWatch, s.upstreamHost(), gw.ID, func(ctx context.Context) error {
+			return s.checkGrade(ctx, gw, now)
+		})
+	}
+}
+
+// Checks the transcript of one watch at now.
+func (s *Server) checkGrade(ctx context.Context, gw GradeWatch, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "/api/grades/watches", nil)
+	if err != nil {
+		return err
+	}
+	req.Header = gw.auth.Clone()
+	courses, err := s.provider.FetchTranscript(req, gw.StudentID)
+	if err != nil {
+		log.Printf("grade watch failed id=%s student_id=%s err=%v", gw.ID, gw.StudentID, err)
+		s.gradeWatches.update(gw.ID, func(w *GradeWatch) { w.LastCheckedAt, w.LastError = &now, err.Error() })
+		return err
+	}
+
+	var released []ReleasedGrade
+	if gw.grades != nil {
+		released = releasedGrades(gw.grades, courses)
+	}
+	grades := make(map[string]string, len(courses))
+	for _, c := range courses {
+		// A retaken course keeps any grade it already had.
+		if c.Grade != "" || grades[c.Code] == "" {
+			grades[c.Code] = c.Grade
+		}
+	}
+	s.gradeWatches.update(gw.ID, func(w *GradeWatch) {
+		w.backoff.observe(!maps.Equal(w.grades, grades))
+		w.LastCheckedAt, w.LastError, w.grades = &now, "", grades
+		w.Released += len(released)
+	})
+	if len(released) == 0 {
+		return nil
+	}
+
+	codes := make([]string, len(released))
+	for i, g := range released {
+		codes[i] = g.Code + " " + g.Grade
+	}
+	n := newNotification("grade.released", gw.StudentID, "New grades: "+strings.Join(codes, ", "), released)
+	log.Printf("grades released id=%s student_id=%s count=%d", gw.ID, gw.StudentID, len(released))
+	go func() {
+		if err := gw.notifier.Notify(context.Background(), n); err != nil {
+			log.Printf("grade notification failed id=%s err=%v", gw.ID, err)
+		}
+	}()
+	return nil
+}
+
+// Applies update to the watch under the lock, if it still exists.
+func (w *gradeWatcher) update(id string, update func(*GradeWatch)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if gw, ok := w.watches[id]; ok {
+		update(gw)
+	}
+}
+
+// Returns the watch with id if the caller owns it. Callers must hold w.mu.
+func (w *gradeWatcher) ownedLocked(r *http.Request, id string) (*GradeWatch, bool) {
+	gw, ok := w.watches[id]
+	if !ok || gw.owner != subscriptionOwner(r) {
+		return nil, false
+	}
+	return gw, true
+}
+
+type createGradeWatchRequest struct {
+	StudentID string `json:"student_id"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret"`
+}
+
+// POST /api/grades/watches
+func (s *Server) createGradeWatch(w http.ResponseWriter, r *http.Request) {
+	if !gradeWatchEnabled {
+		writeError(w, r, codeGradeWatchDisabled)
+		return
+	}
+	var body createGradeWatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if !validWebhookURL(body.URL) {
+		writeError(w, r, codeInvalidWebhookURL)
+		return
+	}
+	// The cookies are needed on every poll, so check them now.
+	if err := forwardCookies(&http.Request{Header: http.Header{}}, r); err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	if body.Secret == "" {
+		body.Secret = randomHex(32)
+	}
+
+	now := time.Now()
+	expires := s.semesterEnd(s.semesters.current(body.StudentID, now))
+	gw := &GradeWatch{
+		ID:        randomHex(8),
+		StudentID: body.StudentID,
+		URL:       body.URL,
+		Secret:    body.Secret,
+		CreatedAt: now,
+		ExpiresAt: &expires,
+		owner:     subscriptionOwner(r),
+		auth:      sixAuthHeaders(r),
+		notifier:  newWebhookNotifier(body.URL, body.Secret),
+	}
+
+	s.gradeWatches.mu.Lock()
+	full := len(s.gradeWatches.watches) >= gradeWatchMax
+	if !full {
+		s.gradeWatches.watches[gw.ID] = gw
+	}
+	s.gradeWatches.mu.Unlock()
+	if full {
+		writeError(w, r, codeServerBusy)
+		return
+	}
+
+	consent := s.consents.grant(r, scopeGradeWatch, gw.StudentID, gw.ID, func() {
+		s.gradeWatches.mu.Lock()
+		delete(s.gradeWatches.watches, gw.ID)
+		s.gradeWatches.mu.Unlock()
+	})
+	s.gradeWatches.update(gw.ID, func(w *GradeWatch) { w.ConsentID = consent.ID })
+	created := *gw
+	created.ConsentID = consent.ID
+
+	log.Printf("grade watch created id=%s student_id=%s", gw.ID, gw.StudentID)
+	writeCreated(w, created)
+}
+
+// GET /api/grades/watches/{id}
+func (s *Server) getGradeWatch(w http.ResponseWriter, r *http.Request) {
+	s.gradeWatches.mu.Lock()
+	gw, ok := s.gradeWatches.ownedLocked(r, r.PathValue("id"))
+	var v GradeWatch
+	if ok {
+		v = gw.view()
+	}
+	s.gradeWatches.mu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeGradeWatchNotFound)
+		return
+	}
+	writeSuccess(w, v)
+}
+
+// DELETE /api/grades/watches/{id}
+func (s *Server) deleteGradeWatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.gradeWatches.mu.Lock()
+	gw, ok := s.gradeWatches.ownedLocked(r, id)
+	if ok {
+		delete(s.gradeWatches.watches, id)
+	}
+	s.gradeWatches.mu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeGradeWatchNotFound)
+		return
+	}
+	s.consents.end(gw.ConsentID, revokedDelete)
+	log.Printf("grade watch deleted id=%s", id)
+	writeSuccess(w, map[string]string{"id": id})
+}
+
+// GET /api/grades/watches
+func (s *Server) listGradeWatches(w http.ResponseWriter, r *http.Request) {
+	owner := subscriptionOwner(r)
+	s.gradeWatches.mu.Lock()
+	list := []GradeWatch{}
+	for _, gw := range s.gradeWatches.watches {
+		if gw.owner == owner {
+			list = append(list, gw.view())
+		}
+	}
+	s.gradeWatches.mu.Unlock()
+
+	slices.SortFunc(list, func(a, b GradeWatch) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	writeSuccess(w, list)
+}
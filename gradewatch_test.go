@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReleasedGrades(t *testing.T) {
+	prev := map[string]string{"MA1101": "A", "IF2211": ""}
+	got := releasedGrades(prev, []TranscriptCourse{
+		{Code: "MA1101", Grade: "A"},
+		{Code: "IF2211", Name: "Strategi Algoritma", Grade: "AB"},
+		{Code: "IF2230", Grade: "B"}, // new to the transcript
+		{Code: "IF2240"},
+	})
+	want := "[{IF2211 Strategi Algoritma AB} {IF2230  B}]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGradeWatch_NotifiesOnRelease(t *testing.T) {
+	setupWebhooks(t)
+	old := gradeWatchEnabled
+	gradeWatchEnabled = true
+	t.Cleanup(func() { gradeWatchEnabled = old })
+
+	var released atomic.Bool
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		html := testTranscriptHTML
+		if released.Load() {
+			html = strings.Replace(html, "<td>2025-2</td><td></td>", "<td>2025-2</td><td>AB</td>", 1)
+		}
+		fmt.Fprint(w, html)
+	}))
+	defer mock.Close()
+	receiver, received := webhookReceiver(t, 0)
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("POST", "/api/grades/watches", strings.NewReader(`{"student_id":"123","url":"`+receiver.URL+`","secret":"s3cret"}`))
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got status %d: %s", w.Code, w.Body)
+	}
+	watch := decodeData[GradeWatch](t, w)
+
+	// The first check records the existing grades without notifying.
+	now := time.Now()
+	srv.checkGrades(t.Context(), now)
+	released.Store(true)
+	srv.checkGrades(t.Context(), now.Add(gradeWatchInterval))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := received()
+	if len(got) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(got))
+	}
+	ts, _ := strconv.ParseInt(got[0].header.Get(webhookTimestampHeader), 10, 64)
+	if !verifyWebhook("s3cret", ts, got[0].body, got[0].header.Get(webhookSignatureHeader)) {
+		t.Error("notification signature does not verify")
+	}
+	var n struct {
+		Notification
+		Data []ReleasedGrade `json:"data"`
+	}
+	if err := json.Unmarshal(got[0].body, &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Type != "grade.released" || len(n.Data) != 1 || n.Data[0].Code != "IF2211" || n.Data[0].Grade != "AB" {
+		t.Errorf("notification = %s", got[0].body)
+	}
+
+	req = httptest.NewRequest("GET", "/api/grades/watches/"+watch.ID, nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if v := decodeData[GradeWatch](t, w); v.Released != 1 || v.Secret != "" || v.LastCheckedAt == nil {
+		t.Errorf("watch = %+v", v)
+	}
+}
+
+func TestGradeWatch_Disabled(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/grades/watches", strings.NewReader(`{"student_id":"123","url":"https://example.com/hook"}`))
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	newTestServer("").ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// /api/schedule?format=grid lays the week out by day, and lists the
+// transitions between each day's consecutive meetings with the gap and, if
+// SIX_BUILDINGS_FILE locates both rooms, how far apart they are.
+
+// Walking speed used for walk_minutes, in meters per minute.
+const walkingSpeed = 75
+
+type ScheduleGrid struct {
+	Days        []GridDay    `json:"days"`
+	Transitions []Transition `json:"transitions"`
+}
+
+type GridDay struct {
+	Day      string        `json:"day"`
+	Weekday  int           `json:"weekday,omitempty"` // ISO 8601: 1 for Senin to 7 for Minggu
+	Sessions []GridSession `json:"sessions"`
+}
+
+type GridSession struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	ClassNo  string `json:"class_no"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Room     string `json:"room"`
+	Building string `json:"building,omitempty"`
+	Activity string `json:"activity"`
+	Method   string `json:"method"`
+
+	building *Building
+}
+
+// Getting from one meeting to the next on the same day.
+type Transition struct {
+	Day        string      `json:"day"`
+	From       GridSession `json:"from"`
+	To         GridSession `json:"to"`
+	GapMinutes int         `json:"gap_minutes"` // negative if they overlap
+	// Distance is same_room, same_building, near (under 300 m), medium
+	// (under 700 m), far, online (either meeting is online), or unknown.
+	Distance    string `json:"distance"`
+	DistanceM   int    `json:"distance_m,omitempty"`
+	WalkMinutes int    `json:"walk_minutes,omitempty"`
+}
+
+// Parses "07:00" as minutes since midnight.
+func clockMinutes(s string) (int, bool) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hh < 0 || hh > 23 || mm < 0 || mm > 59 {
+		return 0, false
+	}
+	return hh*60 + mm, true
+}
+
+// Returns the great-circle distance between two buildings in meters.
+func haversine(a, b Building) float64 {
+	const earthRadius = 6371000
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := rad(b.Lat-a.Lat), rad(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+func transition(day string, from, to GridSession) Transition {
+	t := Transition{Day: day, From: from, To: to, Distance: "unknown"}
+	end, _ := clockMinutes(from.End)
+	start, _ := clockMinutes(to.Start)
+	t.GapMinutes = start - end
+
+	switch {
+	case strings.EqualFold(from.Method, "online") || strings.EqualFold(to.Method, "online"):
+		t.Distance = "online"
+	case from.Room != "" && from.Room == to.Room:
+		t.Distance = "same_room"
+	case from.building == nil || to.building == nil:
+	case from.building.Name == to.building.Name:
+		t.Distance = "same_building"
+	default:
+		meters := haversine(*from.building, *to.building)
+		t.DistanceM = int(math.Round(meters))
+		t.WalkMinutes = int(math.Ceil(meters / walkingSpeed))
+		switch {
+		case meters < 300:
+			t.Distance = "near"
+		case meters < 700:
+			t.Distance = "medium"
+		default:
+			t.Distance = "far"
+		}
+	}
+	return t
+}
+
+// Groups the meetings of classes by day, ordered by start time, and works
+// out the transition between each pair of consecutive meetings. buildings
+// may be nil.
+func scheduleGrid(classes []CourseClass, buildings []Building) ScheduleGrid {
+	byDay := make(map[string][]GridSession)
+	for _, c := range classes {
+		for _, e := range c.Schedules {
+			start, end, _ := strings.Cut(e.Time, "-")
+			s := GridSession{
+				Code: c.Code, Name: c.Name, ClassNo: c.ClassNo,
+				Start: strings.TrimSpace(start), End: strings.TrimSpace(end),
+				Room: e.Room, Activity: e.Activity, Method: e.Method,
+			}
+			if b, ok := locateRoom(buildings, e.Room); ok && e.Room != "" {
+				s.building, s.Building = &b, b.Name
+			}
+			byDay[e.Day] = append(byDay[e.Day], s)
+		}
+	}
+
+	grid := ScheduleGrid{Days: []GridDay{}, Transitions: []Transition{}}
+	for day, sessions := range byDay {
+		slices.SortStableFunc(sessions, func(a, b GridSession) int {
+			return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(a.End, b.End), cmp.Compare(a.Code, b.Code))
+		})
+		d := GridDay{Day: day, Sessions: sessions}
+		if rank, ok := dayOrder[day]; ok {
+			d.Weekday = rank + 1
+		}
+		grid.Days = append(grid.Days, d)
+	}
+	slices.SortFunc(grid.Days, func(a, b GridDay) int {
+		return cmp.Or(cmp.Compare(dayRank(a.Day), dayRank(b.Day)), cmp.Compare(a.Day, b.Day))
+	})
+
+	for _, d := range grid.Days {
+		for i := 1; i < len(d.Sessions); i++ {
+			from, to := d.Sessions[i-1], d.Sessions[i]
+			_, okEnd := clockMinutes(from.End)
+			_, okStart := clockMinutes(to.Start)
+			if okEnd && okStart {
+				grid.Transitions = append(grid.Transitions, transition(d.Day, from, to))
+			}
+		}
+	}
+	return grid
+}
+
+// A meeting on a particular date.
+type UpcomingSession struct {
+	GridSession
+	Day      string    `json:"day"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Returns the meetings of the weekly grid that fall on date, a WIB
+// midnight.
+func sessionsOn(grid ScheduleGrid, date time.Time) []UpcomingSession {
+	day := indonesianDays[(int(date.Weekday())+6)%7]
+	out := []UpcomingSession{}
+	for _, d := range grid.Days {
+		if d.Day != day {
+			continue
+		}
+		for _, s := range d.Sessions {
+			start, ok1 := clockMinutes(s.Start)
+			end, ok2 := clockMinutes(s.End)
+			if ok1 && ok2 {
+				out = append(out, UpcomingSession{
+					GridSession: s,
+					Day:         day,
+					StartsAt:    date.Add(time.Duration(start) * time.Minute),
+					EndsAt:      date.Add(time.Duration(end) * time.Minute),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// Returns up to n meetings of the weekly grid that have not ended by now,
+// soonest first, looking one week ahead.
+func upcomingSessions(grid ScheduleGrid, now time.Time, n int) []UpcomingSession {
+	today := wibDay(now)
+	var out []UpcomingSession
+	for offset := 0; offset <= 7; offset++ {
+		for _, u := range sessionsOn(grid, today.AddDate(0, 0, offset)) {
+			// A week ahead, only the meetings that had already ended today
+			// are new.
+			if len(out) == n {
+				return out
+			}
+			if u.EndsAt.After(now) && (offset < 7 || !u.EndsAt.AddDate(0, 0, -7).After(now)) {
+				out = append(out, u)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScheduleGrid(t *testing.T) {
+	classes := []CourseClass{
+		{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+			{Day: "Rabu", Time: "09:00-11:00", Room: "7602", Method: "Offline"},
+		}},
+		{Code: "IF2120", Name: "Matematika Diskrit", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "09:10-11:00", Room: "GKU Timur 1", Method: "Offline"},
+			{Day: "Senin", Time: "11:00-12:00", Room: "GKU Timur 1", Method: "Offline"},
+			{Day: "Senin", Time: "13:00-15:00", Room: "7603", Method: "Online"},
+			{Day: "Rabu", Time: "10:00-12:00", Room: "7610", Method: "Offline"},
+		}},
+	}
+	grid := scheduleGrid(classes, testBuildings)
+
+	if len(grid.Days) != 2 || grid.Days[0].Day != "Senin" || grid.Days[0].Weekday != 1 || grid.Days[1].Day != "Rabu" {
+		t.Fatalf("days = %+v", grid.Days)
+	}
+	if n := len(grid.Days[0].Sessions); n != 4 {
+		t.Fatalf("Senin sessions = %d, want 4", n)
+	}
+	if got := grid.Days[0].Sessions[1].Building; got != "GKU Timur" {
+		t.Errorf("building = %q", got)
+	}
+
+	want := []struct {
+		gap      int
+		distance string
+	}{
+		{10, "medium"},   // Labtek V to GKU Timur
+		{0, "same_room"}, // GKU Timur 1 twice
+		{60, "online"},   // to an online class
+		{-60, "near"},    // overlapping, Labtek V Lantai 6 to Labtek V
+	}
+	if len(grid.Transitions) != len(want) {
+		t.Fatalf("transitions = %+v", grid.Transitions)
+	}
+	for i, w := range want {
+		tr := grid.Transitions[i]
+		if tr.GapMinutes != w.gap || tr.Distance != w.distance {
+			t.Errorf("transition %d = gap %d %s, want gap %d %s", i, tr.GapMinutes, tr.Distance, w.gap, w.distance)
+		}
+	}
+	first := grid.Transitions[0]
+	if first.DistanceM < 300 || first.DistanceM >= 700 || first.WalkMinutes != (first.DistanceM+walkingSpeed-1)/walkingSpeed {
+		t.Errorf("first transition = %+v", first)
+	}
+	if first.From.Code != "IF2211" || first.To.Code != "IF2120" {
+		t.Errorf("first transition goes %s to %s", first.From.Code, first.To.Code)
+	}
+}
+
+func TestScheduleGrid_UnknownBuildings(t *testing.T) {
+	classes := []CourseClass{{Code: "FI1210", Schedules: []ScheduleEntry{
+		{Day: "Selasa", Time: "07:00-09:00", Room: "9009"},
+		{Day: "Selasa", Time: "09:00-11:00", Room: "9131"},
+	}}}
+	grid := scheduleGrid(classes, nil)
+	if len(grid.Transitions) != 1 || grid.Transitions[0].Distance != "unknown" || grid.Transitions[0].DistanceM != 0 {
+		t.Errorf("transitions = %+v", grid.Transitions)
+	}
+}
+
+func TestScheduleHandler_Grid(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2025-2&format=grid", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	newTestServer(mock.URL).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data ScheduleGrid `json:"data"`
+		Meta *Meta        `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Days) == 0 || resp.Meta == nil {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestUpcomingSessions(t *testing.T) {
+	grid := scheduleGrid(parseClasses(docFromHTML(testScheduleHTML)), nil)
+	at := func(day int, clock string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("2026-10-%02d %s", day, clock), wib)
+		return tm
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		n    int
+		want []string
+	}{
+		{"during a class", at(12, "08:00"), 3, []string{"FI1210 2026-10-12 07:00", "FI1220 2026-10-13 09:00", "FI1210 2026-10-14 13:00"}},
+		{"after the week's last class", at(16, "10:00"), 2, []string{"FI1210 2026-10-19 07:00", "FI1220 2026-10-20 09:00"}},
+		{"wraps to next week once", at(12, "10:00"), 5, []string{"FI1220 2026-10-13 09:00", "FI1210 2026-10-14 13:00", "FI1210 2026-10-19 07:00"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, u := range upcomingSessions(grid, tt.now, tt.n) {
+			got = append(got, u.Code+" "+u.StartsAt.Format("2006-01-02 15:04"))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+const userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// Inbound headers copied onto requests to SIX.
+var forwardedHeaders = envList("SIX_FORWARD_HEADERS", []string{"Accept-Language"})
+
+// Headers sent to SIX when the client did not supply a forwarded value.
+// Some SIX pages render differently without a Referer or language.
+var defaultUpstreamHeaders = map[string]string{
+	"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+	"Accept-Language": "id-ID,id;q=0.9,en;q=0.8",
+}
+
+// Headers that are never forwarded, even if configured, because they carry
+// credentials meant for this server or identify the client.
+var neverForwardedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Forwarded":           true,
+	"Host":                true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+	"X-Forwarded-For":     true,
+	"X-Forwarded-Host":    true,
+	"X-Real-Ip":           true,
+}
+
+func init() {
+	for _, name := range forwardedHeaders {
+		if !canForwardHeader(http.CanonicalHeaderKey(name)) {
+			log.Printf("config: header %q in SIX_FORWARD_HEADERS is never forwarded", name)
+		}
+	}
+}
+
+func canForwardHeader(name string) bool {
+	return !neverForwardedHeaders[name] && !strings.HasPrefix(name, "X-Six-")
+}
+
+// Sets the headers of an outbound SIX request: allowlisted inbound headers
+// from r, then defaults for anything still missing.
+func forwardHeaders(req, r *http.Request) {
+	for _, name := range forwardedHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if !canForwardHeader(name) {
+			continue
+		}
+		for _, v := range r.Header.Values(name) {
+			req.Header.Add(name, v)
+		}
+	}
+
+	for name, v := range defaultUpstreamHeaders {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, v)
+		}
+	}
+	if req.Header.Get("Referer") == "" {
+		req.Header.Set("Referer", req.URL.Scheme+"://"+req.URL.Host+"/home")
+	}
+	req.Header.Set("User-Agent", userAgent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func setForwardedHeaders(t *testing.T, names ...string) {
+	t.Helper()
+	old := forwardedHeaders
+	forwardedHeaders = names
+	t.Cleanup(func() { forwardedHeaders = old })
+}
+
+func TestForwardHeaders_Defaults(t *testing.T) {
+	setForwardedHeaders(t, "Accept-Language")
+	req := httptest.NewRequest("GET", "https://example.com", nil)
+	forwardHeaders(req, httptest.NewRequest("GET", "/test", nil))
+
+	if got := req.Header.Get("Referer"); got != "https://example.com/home" {
+		t.Errorf("Referer = %q, want %q", got, "https://example.com/home")
+	}
+	if req.Header.Get("Accept-Language") == "" || req.Header.Get("Accept") == "" {
+		t.Error("expected default Accept and Accept-Language")
+	}
+	if req.Header.Get("User-Agent") != userAgent {
+		t.Error("expected User-Agent to be set")
+	}
+}
+
+func TestForwardHeaders_Allowlist(t *testing.T) {
+	setForwardedHeaders(t, "accept-language", "Referer")
+	incoming := httptest.NewRequest("GET", "/test", nil)
+	incoming.Header.Set("Accept-Language", "en-US")
+	incoming.Header.Set("Referer", "https://six.itb.ac.id/app/x")
+	incoming.Header.Set("X-Custom", "nope")
+
+	req := httptest.NewRequest("GET", "https://example.com", nil)
+	forwardHeaders(req, incoming)
+	if got := req.Header.Get("Accept-Language"); got != "en-US" {
+		t.Errorf("Accept-Language = %q, want en-US", got)
+	}
+	if got := req.Header.Get("Referer"); got != "https://six.itb.ac.id/app/x" {
+		t.Errorf("Referer = %q, want the forwarded value", got)
+	}
+	if req.Header.Get("X-Custom") != "" {
+		t.Error("headers outside the allowlist must not be forwarded")
+	}
+}
+
+func TestForwardHeaders_NeverLeaksSensitiveHeaders(t *testing.T) {
+	sensitive := map[string]string{
+		"Authorization":       "Bearer admin",
+		"Proxy-Authorization": "Basic x",
+		"X-API-Key":           "key",
+		"X-Forwarded-For":     "10.0.0.1",
+		"X-Real-IP":           "10.0.0.1",
+		"Forwarded":           "for=10.0.0.1",
+		"X-Six-Khongguan":     "token",
+	}
+	names := []string{"Cookie"}
+	incoming := httptest.NewRequest("GET", "/test", nil)
+	for name, v := range sensitive {
+		incoming.Header.Set(name, v)
+		names = append(names, name)
+	}
+	addAuthCookies(incoming)
+	// Even an operator allowlisting them must not cause a leak.
+	setForwardedHeaders(t, names...)
+
+	req := httptest.NewRequest("GET", "https://example.com", nil)
+	forwardHeaders(req, incoming)
+	for _, name := range names {
+		if v := req.Header.Get(name); v != "" {
+			t.Errorf("%s leaked upstream: %q", name, v)
+		}
+	}
+}
+
+func TestNewSIXRequest_DoesNotLeakAPIKey(t *testing.T) {
+	incoming := httptest.NewRequest("GET", "/test", nil)
+	addAuthCookies(incoming)
+	incoming.Header.Set("X-API-Key", "secret")
+	incoming.Header.Set("Authorization", "Bearer secret")
+
+	req, err := newSIXRequest("https://example.com", incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range req.Header {
+		for _, v := range values {
+			if v == "secret" || v == "Bearer secret" {
+				t.Errorf("%s leaked upstream", name)
+			}
+		}
+	}
+}
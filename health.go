@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Settings for the deep readiness probe, which scrapes a real schedule page
+// with dedicated probe credentials.
+var (
+	probeCookies    = envString("SIX_PROBE_COOKIES", "") // Cookie header value, e.g. "nissin=...; khongguan=..."
+	probeStudentID  = envString("SIX_PROBE_STUDENT_ID", "")
+	probeSemester   = envString("SIX_PROBE_SEMESTER", "")
+	probeMinClasses = envInt("SIX_PROBE_MIN_CLASSES", 1)
+	probeInterval   = envDuration("SIX_PROBE_INTERVAL", time.Minute)
+)
+
+type ReadinessReport struct {
+	Status     string    `json:"status"`
+	Deep       bool      `json:"deep"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Classes    int       `json:"classes,omitempty"`
+	MinClasses int       `json:"min_classes,omitempty"`
+	Cached     bool      `json:"cached,omitempty"`
+}
+
+var (
+	deepProbeMu   sync.Mutex
+	lastDeepProbe *ReadinessReport
+	lastDeepErr   error
+)
+
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("deep") != "true" {
+		writeSuccess(w, ReadinessReport{Status: "ready", CheckedAt: time.Now()})
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	report, err := s.runDeepProbe()
+	if err != nil {
+		writeError(w, r, codeDeepCheckFailed)
+		return
+	}
+	writeSuccess(w, report)
+}
+
+// Scrapes the probe schedule and checks that enough classes parse. Probes run
+// at most once per probeInterval; calls in between reuse the last outcome.
+func (s *Server) runDeepProbe() (ReadinessReport, error) {
+	deepProbeMu.Lock()
+	defer deepProbeMu.Unlock()
+
+	if lastDeepProbe != nil && time.Since(lastDeepProbe.CheckedAt) < probeInterval {
+		report := *lastDeepProbe
+		report.Cached = true
+		return report, lastDeepErr
+	}
+
+	report := ReadinessReport{Status: "ready", Deep: true, CheckedAt: time.Now(), MinClasses: probeMinClasses}
+	err := s.probeSchedule(&report)
+	if err != nil {
+		report.Status = "not ready"
+		log.Printf("deep readiness check failed: %v", err)
+	}
+	lastDeepProbe, lastDeepErr = &report, err
+	return report, err
+}
+
+func (s *Server) probeSchedule(report *ReadinessReport) error {
+	if probeCookies == "" || probeStudentID == "" || probeSemester == "" {
+		return fmt.Errorf("probe credentials are not configured")
+	}
+	if scrapingPaused() {
+		return fmt.Errorf("SIX maintenance, scraping is paused")
+	}
+
+	// Carry the probe cookies on a synthetic inbound request so the normal
+	// cookie forwarding in newSIXRequest applies.
+	probe, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		return err
+	}
+	probe.Header.Set("Cookie", probeCookies)
+
+	page, err := s.provider.FetchSchedulePage(probe, probeStudentID, probeSemester, nil)
+	if err != nil {
+		return err
+	}
+
+	report.Classes = len(page.Classes)
+	if report.Classes < probeMinClasses {
+		return fmt.Errorf("parsed %d classes, want at least %d", report.Classes, probeMinClasses)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Returns a server whose deep probe points at a mock SIX, and restores the
+// probe globals afterwards.
+func setupDeepProbe(t *testing.T, minClasses int) *Server {
+	t.Helper()
+	mock := mockSIX("10245001", "1945-1")
+	t.Cleanup(mock.Close)
+
+	oldToken := adminToken
+	oldCookies, oldID, oldSem, oldMin := probeCookies, probeStudentID, probeSemester, probeMinClasses
+	t.Cleanup(func() {
+		adminToken = oldToken
+		probeCookies, probeStudentID, probeSemester, probeMinClasses = oldCookies, oldID, oldSem, oldMin
+		lastDeepProbe, lastDeepErr = nil, nil
+	})
+
+	adminToken = "secret"
+	probeCookies, probeStudentID, probeSemester, probeMinClasses = "nissin=a; khongguan=b", "10245001", "1945-1", minClasses
+	lastDeepProbe, lastDeepErr = nil, nil
+	return newTestServer(mock.URL)
+}
+
+func deepReadyz(srv *Server, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/readyz?deep=true", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	srv.readyzHandler(w, req)
+	return w
+}
+
+func decodeReport(t *testing.T, w *httptest.ResponseRecorder) ReadinessReport {
+	t.Helper()
+	var resp struct {
+		Data ReadinessReport `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Data
+}
+
+func TestReadyz_Shallow(t *testing.T) {
+	srv := newTestServer("")
+	w := httptest.NewRecorder()
+	srv.readyzHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	if r := decodeReport(t, w); r.Status != "ready" || r.Deep {
+		t.Errorf("unexpected report %+v", r)
+	}
+}
+
+func TestReadyz_DeepRequiresAdmin(t *testing.T) {
+	srv := setupDeepProbe(t, 1)
+	if w := deepReadyz(srv, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: got status %d, want 401", w.Code)
+	}
+	if w := deepReadyz(srv, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: got status %d, want 401", w.Code)
+	}
+
+	adminToken = ""
+	if w := deepReadyz(srv, "secret"); w.Code != http.StatusForbidden {
+		t.Errorf("admin disabled: got status %d, want 403", w.Code)
+	}
+}
+
+func TestReadyz_DeepParsesClasses(t *testing.T) {
+	srv := setupDeepProbe(t, 2)
+	w := deepReadyz(srv, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	r := decodeReport(t, w)
+	if !r.Deep || r.Classes != 2 || r.Cached {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	// A second call within the probe interval reuses the result.
+	if r := decodeReport(t, deepReadyz(srv, "secret")); !r.Cached {
+		t.Error("expected second deep check to be served from the last probe")
+	}
+}
+
+func TestReadyz_DeepFailsBelowMinimum(t *testing.T) {
+	srv := setupDeepProbe(t, 3)
+	if w := deepReadyz(srv, "secret"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want 503", w.Code)
+	}
+}
+
+func TestReadyz_DeepRateLimited(t *testing.T) {
+	srv := setupDeepProbe(t, 1)
+	lastDeepProbe = &ReadinessReport{Status: "ready", Deep: true, CheckedAt: time.Now(), Classes: 99}
+
+	r := decodeReport(t, deepReadyz(srv, "secret"))
+	if r.Classes != 99 || !r.Cached {
+		t.Errorf("expected the previous probe to be reused, got %+v", r)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A distinct version of a schedule. Consecutive fetches that return the same
+// classes share one version, so the timeline only grows when something
+// changes while still recording when the schedule was last confirmed.
+type ScheduleVersion struct {
+	Hash        string    `json:"hash"` // content hash, see classesHash
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	Fetches     int       `json:"fetches"`
+}
+
+type versionTimeline struct {
+	Key      string            `json:"key"`
+	Versions []ScheduleVersion `json:"versions"`
+}
+
+// The version timeline of every schedule. The classes of each version are
+// stored once per content hash and referenced from the timelines, so an
+// unchanged schedule costs nothing however often it is fetched, and identical
+// schedules of different students share storage. With dir set, timelines and
+// contents are written under it; otherwise they are kept in memory.
+type versionHistory struct {
+	mu        sync.Mutex
+	dir       string
+	timelines map[string]*versionTimeline
+	contents  map[string][]CourseClass // only used without dir
+}
+
+func newVersionHistory(dir string) *versionHistory {
+	return &versionHistory{dir: dir, timelines: make(map[string]*versionTimeline), contents: make(map[string][]CourseClass)}
+}
+
+// Hashes classes in a normalized order, so the same schedule listed in a
+// different order hashes the same.
+func classesHash(classes []CourseClass) (string, []byte) {
+	sorted := slices.Clone(classes)
+	slices.SortStableFunc(sorted, func(a, b CourseClass) int {
+		return cmp.Or(cmp.Compare(a.Code, b.Code), cmp.Compare(a.ClassNo, b.ClassNo))
+	})
+	if sorted == nil {
+		sorted = []CourseClass{}
+	}
+	data, _ := json.Marshal(sorted)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), data
+}
+
+func (h *versionHistory) timelinePath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(h.dir, "history", hex.EncodeToString(sum[:])+".json")
+}
+
+func (h *versionHistory) contentPath(hash string) string {
+	return filepath.Join(h.dir, "history", "contents", hash+".json")
+}
+
+// Records a fetch of the schedule under key. A fetch identical to the latest
+// version only extends it; anything else starts a new version, storing its
+// classes unless the same content is already stored.
+func (h *versionHistory) record(key string, classes []CourseClass, fetchedAt time.Time) {
+	hash, data := classesHash(classes)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	tl := h.timelineLocked(key)
+	if n := len(tl.Versions); n > 0 && tl.Versions[n-1].Hash == hash {
+		v := &tl.Versions[n-1]
+		v.LastSeenAt = fetchedAt
+		v.Fetches++
+	} else {
+		tl.Versions = append(tl.Versions, ScheduleVersion{Hash: hash, FirstSeenAt: fetchedAt, LastSeenAt: fetchedAt, Fetches: 1})
+		if err := h.storeContentLocked(hash, classes, data); err != nil {
+			log.Printf("history: store failed key=%s hash=%s err=%v", key, hash, err)
+		}
+	}
+	if h.dir == "" {
+		return
+	}
+	tlData, err := json.Marshal(tl)
+	if err == nil {
+		err = writeFileAtomic(h.timelinePath(key), tlData)
+	}
+	if err != nil {
+		log.Printf("history: save failed key=%s err=%v", key, err)
+	}
+}
+
+func (h *versionHistory) storeContentLocked(hash string, classes []CourseClass, data []byte) error {
+	if h.dir == "" {
+		if _, ok := h.contents[hash]; !ok {
+			h.contents[hash] = classes
+		}
+		return nil
+	}
+	path := h.contentPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	blob, err := json.Marshal(storedContent{SchemaVersion: classSchemaVersion, Classes: data})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, blob)
+}
+
+// A content file. The hash covers only the classes. Files written before
+// schema_version hold the bare list of classes instead.
+type storedContent struct {
+	SchemaVersion int             `json:"schema_version"` // see classSchemaVersion
+	Classes       json.RawMessage `json:"classes"`
+}
+
+// Returns the timeline of key, loading it from disk if it is not in memory.
+func (h *versionHistory) timelineLocked(key string) *versionTimeline {
+	if tl, ok := h.timelines[key]; ok {
+		return tl
+	}
+	tl := &versionTimeline{Key: key}
+	if h.dir != "" {
+		data, err := os.ReadFile(h.timelinePath(key))
+		switch {
+		case err == nil:
+			var loaded versionTimeline
+			if err := json.Unmarshal(data, &loaded); err != nil || loaded.Key != key {
+				log.Printf("history: ignoring unreadable timeline key=%s", key)
+			} else {
+				tl = &loaded
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			log.Printf("history: load failed key=%s err=%v", key, err)
+		}
+	}
+	h.timelines[key] = tl
+	return tl
+}
+
+// Returns the versions of key, oldest first.
+func (h *versionHistory) versions(key string) []ScheduleVersion {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.timelineLocked(key).Versions)
+}
+
+// Returns the classes of the version with the given hash.
+func (h *versionHistory) content(hash string) ([]CourseClass, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.dir == "" {
+		classes, ok := h.contents[hash]
+		return classes, ok
+	}
+	data, err := os.ReadFile(h.contentPath(hash))
+	if err != nil {
+		return nil, false
+	}
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		data, _ = json.Marshal(storedContent{SchemaVersion: 1, Classes: data})
+	}
+	var content struct {
+		Classes []CourseClass `json:"classes"`
+	}
+	err = unmarshalVersioned(data, &content, func(blob map[string]any) []any {
+		return []any{blob["classes"]}
+	})
+	if err != nil {
+		log.Printf("history: unreadable content hash=%s err=%v", hash, err)
+		return nil, false
+	}
+	return content.Classes, true
+}
+
+// GET /api/schedule/history
+//
+// Lists the versions of a schedule this server has seen, or with version set,
+// returns the classes of one of them. It never contacts SIX.
+func (s *Server) historyHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	key := schedulePath(studentID, semester, query)
+	versions := s.history.versions(key)
+	if len(versions) == 0 {
+		writeError(w, r, codeSnapshotNotFound)
+		return
+	}
+	meta := &Meta{Cached: true}
+	if relative {
+		meta.Semester = semester
+	}
+
+	hash := query.Get("version")
+	if hash == "" {
+		writeSuccessWithMeta(w, versions, meta)
+		return
+	}
+	i := slices.IndexFunc(versions, func(v ScheduleVersion) bool { return v.Hash == hash })
+	if i < 0 {
+		writeError(w, r, codeVersionNotFound, hash)
+		return
+	}
+	classes, ok := s.history.content(hash)
+	if !ok {
+		writeError(w, r, codeVersionNotFound, hash)
+		return
+	}
+	meta.FetchedAt = versions[i].LastSeenAt
+	writeSuccessWithMeta(w, classes, meta)
+}
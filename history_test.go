@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClassesHash_IgnoresOrder(t *testing.T) {
+	a := CourseClass{Code: "IF2211", ClassNo: "01"}
+	b := CourseClass{Code: "IF2230", ClassNo: "02"}
+	h1, _ := classesHash([]CourseClass{a, b})
+	h2, _ := classesHash([]CourseClass{b, a})
+	if h1 != h2 {
+		t.Error("hash depends on class order")
+	}
+	b.Quota = 40
+	if h3, _ := classesHash([]CourseClass{a, b}); h3 == h1 {
+		t.Error("hash ignores a changed quota")
+	}
+}
+
+func TestVersionHistory_DeduplicatesConsecutiveFetches(t *testing.T) {
+	dir := t.TempDir()
+	h := newVersionHistory(dir)
+	v1 := []CourseClass{{Code: "IF2211", ClassNo: "01", Quota: 40}}
+	v2 := []CourseClass{{Code: "IF2211", ClassNo: "01", Quota: 50}}
+	t0 := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i, classes := range [][]CourseClass{v1, v1, v1, v2, v1} {
+		h.record("k", classes, t0.Add(time.Duration(i)*time.Hour))
+	}
+	h.record("other", v1, t0)
+
+	// A fresh store must read the timeline back from disk.
+	h = newVersionHistory(dir)
+	versions := h.versions("k")
+	if len(versions) != 3 {
+		t.Fatalf("versions = %+v, want 3", versions)
+	}
+	first := versions[0]
+	if first.Fetches != 3 || !first.FirstSeenAt.Equal(t0) || !first.LastSeenAt.Equal(t0.Add(2*time.Hour)) {
+		t.Errorf("first version = %+v", first)
+	}
+	if versions[2].Hash != first.Hash {
+		t.Error("reverting to an earlier schedule should reference its content")
+	}
+	contents, _ := filepath.Glob(filepath.Join(dir, "history", "contents", "*.json"))
+	if len(contents) != 2 {
+		t.Errorf("stored %d contents, want 2", len(contents))
+	}
+	classes, ok := h.content(versions[1].Hash)
+	if !ok || len(classes) != 1 || classes[0].Quota != 50 {
+		t.Errorf("content = %+v, %v", classes, ok)
+	}
+}
+
+func TestHistoryHandler(t *testing.T) {
+	srv := newTestServer("")
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule/history?"+query, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	if w := get("student_id=123&semester=1945-1"); w.Code != http.StatusNotFound {
+		t.Errorf("no history: got status %d, want 404", w.Code)
+	}
+
+	key := schedulePath("123", "1945-1", nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "IF2211"}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "IF2230"}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", nil, time.Now(), &Anomaly{Score: 1})
+
+	w := get("student_id=123&semester=1945-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	versions := decodeData[[]ScheduleVersion](t, w)
+	if len(versions) != 2 {
+		t.Fatalf("versions = %+v, want 2 (anomalous scrapes are not recorded)", versions)
+	}
+	w = get("student_id=123&semester=1945-1&version=" + versions[0].Hash)
+	if classes := decodeData[[]CourseClass](t, w); len(classes) != 1 || classes[0].Code != "IF2211" {
+		t.Errorf("classes = %+v", classes)
+	}
+	unknown := "student_id=123&semester=1945-1&version=" + string(make([]byte, 0)) + "0000000000000000000000000000000000000000000000000000000000000000"
+	if w := get(unknown); w.Code != http.StatusNotFound {
+		t.Errorf("unknown version: got status %d, want 404", w.Code)
+	}
+}
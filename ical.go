@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"six-scraper-go/scraper"
+)
+
+// GET /api/schedule/ical turns a schedule into an iCalendar file that
+// calendar apps import: one VEVENT per weekly meeting, repeating for the
+// lecture weeks of the semester. SIX lists meetings by weekday, not by date,
+// so the first week is taken from ITB's usual academic calendar unless the
+// client passes start.
+
+const (
+	icalDefaultWeeks = 16
+	icalMaxWeeks     = 30
+	icalTZID         = "Asia/Jakarta"
+)
+
+// Returns the Monday lectures of sem usually start on: the third Monday of
+// August for odd semesters, the second Monday of January for even ones, and
+// the first Monday of July for short semesters.
+func semesterStart(sem scraper.Semester) time.Time {
+	year, month, nth := sem.Year, time.August, 3
+	switch sem.Term {
+	case 2:
+		year, month, nth = sem.Year+1, time.January, 2
+	case 3:
+		year, month, nth = sem.Year+1, time.July, 1
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, wib)
+	offset := (int(time.Monday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(nth-1))
+}
+
+// Escapes text for an iCalendar TEXT value.
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// Writes one content line, folded at 75 octets as RFC 5545 requires.
+func writeICalLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// Renders classes as an iCalendar file. Meetings whose day or time cannot
+// be read are left out.
+func scheduleICal(classes []CourseClass, studentID, semester string, start time.Time, weeks int, stamp time.Time) string {
+	var b strings.Builder
+	line := func(format string, args ...any) { writeICalLine(&b, fmt.Sprintf(format, args...)) }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//six-scraper-go//schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", icalEscaper.Replace("Jadwal "+semester))
+	line("X-WR-TIMEZONE:%s", icalTZID)
+	// WIB has no daylight saving time, so one standard rule covers it.
+	line("BEGIN:VTIMEZONE")
+	line("TZID:%s", icalTZID)
+	line("BEGIN:STANDARD")
+	line("DTSTART:19700101T000000")
+	line("TZOFFSETFROM:+0700")
+	line("TZOFFSETTO:+0700")
+	line("TZNAME:WIB")
+	line("END:STANDARD")
+	line("END:VTIMEZONE")
+
+	for _, c := range classes {
+		for _, e := range c.Schedules {
+			day, ok := dayOrder[e.Day]
+			startText, endText, _ := strings.Cut(e.Time, "-")
+			from, ok1 := clockMinutes(startText)
+			to, ok2 := clockMinutes(endText)
+			if !ok || !ok1 || !ok2 || to <= from {
+				continue
+			}
+			// dayOrder counts from Monday, as does the offset from start.
+			date := start.AddDate(0, 0, (day-(int(start.Weekday())+6)%7+7)%7)
+			begin := date.Add(time.Duration(from) * time.Minute)
+			end := date.Add(time.Duration(to) * time.Minute)
+
+			sum := sha256.Sum256([]byte(strings.Join([]string{studentID, semester, c.Code, c.ClassNo, c.Category, e.Day, e.Time, e.Activity}, "|")))
+			summary := c.Code + " " + c.Name
+			if e.Activity != "" && !strings.EqualFold(e.Activity, "kuliah") {
+				summary += " (" + e.Activity + ")"
+			}
+			description := []string{"Kelas " + c.ClassNo}
+			if len(c.Lecturers) > 0 {
+				description = append(description, "Dosen: "+strings.Join(c.Lecturers, ", "))
+			}
+			if kind := strings.TrimSpace(e.Activity + " " + e.Method); kind != "" {
+				description = append(description, kind)
+			}
+
+			line("BEGIN:VEVENT")
+			line("UID:%s@six-scraper-go", hex.EncodeToString(sum[:12]))
+			line("DTSTAMP:%s", stamp.UTC().Format("20060102T150405Z"))
+			line("DTSTART;TZID=%s:%s", icalTZID, begin.Format("20060102T150405"))
+			line("DTEND;TZID=%s:%s", icalTZID, end.Format("20060102T150405"))
+			line("RRULE:FREQ=WEEKLY;COUNT=%d", weeks)
+			line("SUMMARY:%s", icalEscaper.Replace(summary))
+			if e.Room != "" {
+				line("LOCATION:%s", icalEscaper.Replace(e.Room))
+			}
+			line("DESCRIPTION:%s", icalEscaper.Replace(strings.Join(description, "\n")))
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// GET /api/schedule/ical
+func (s *Server) icalHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	weeks := icalDefaultWeeks
+	if v := query.Get("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > icalMaxWeeks {
+			writeError(w, r, codeInvalidRequest, fmt.Sprintf("weeks must be between 1 and %d", icalMaxWeeks))
+			return
+		}
+		weeks = n
+	}
+	var start time.Time
+	if v := query.Get("start"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, wib)
+		if err != nil {
+			writeError(w, r, codeInvalidRequest, "start must have the form YYYY-MM-DD")
+			return
+		}
+		start = d
+	}
+
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	semester := cmp.Or(meta.Semester, query.Get("semester"))
+	if start.IsZero() {
+		sem, err := scraper.ParseSemester(semester)
+		if err != nil {
+			writeError(w, r, codeInvalidRequest, err.Error())
+			return
+		}
+		start = semesterStart(sem)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="jadwal-`+semester+`.ics"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Write([]byte(scheduleICal(classes, query.Get("student_id"), semester, start, weeks, meta.FetchedAt)))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+func TestSemesterStart(t *testing.T) {
+	tests := map[string]string{
+		"2024-1": "2024-08-19",
+		"2025-1": "2025-08-18",
+		"2024-2": "2025-01-13",
+		"2024-3": "2025-07-07",
+	}
+	for sem, want := range tests {
+		s, _ := scraper.ParseSemester(sem)
+		if got := semesterStart(s).Format(time.DateOnly); got != want {
+			t.Errorf("semesterStart(%s) = %s, want %s", sem, got, want)
+		}
+	}
+}
+
+func TestScheduleICal(t *testing.T) {
+	classes := parseClasses(docFromHTML(testScheduleHTML))
+	classes[0].Name = "Fisika Dasar; Mekanika, Panas"
+	start := time.Date(2025, 1, 13, 0, 0, 0, 0, wib)
+	ics := scheduleICal(classes, "13520001", "2024-2", start, 14, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Fatalf("not a calendar:\n%s", ics)
+	}
+	if n := strings.Count(ics, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("got %d events, want one per weekly meeting", n)
+	}
+	for _, want := range []string{
+		// Senin 07:00 in the first week; Rabu falls two days later.
+		"DTSTART;TZID=Asia/Jakarta:20250113T070000\r\n",
+		"DTEND;TZID=Asia/Jakarta:20250113T090000\r\n",
+		"DTSTART;TZID=Asia/Jakarta:20250115T130000\r\n",
+		"DTSTART;TZID=Asia/Jakarta:20250114T090000\r\n",
+		"RRULE:FREQ=WEEKLY;COUNT=14\r\n",
+		"LOCATION:7602\r\n",
+		`SUMMARY:FI1210 Fisika Dasar\; Mekanika\, Panas` + "\r\n",
+		`DESCRIPTION:Kelas 01\nDosen: Dosen A\, Dosen B\nKuliah Offline` + "\r\n",
+		"DTSTAMP:20250101T000000Z\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+}
+
+func TestWriteICalLine_FoldsOnRuneBoundaries(t *testing.T) {
+	var b strings.Builder
+	writeICalLine(&b, "SUMMARY:"+strings.Repeat("é", 60))
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 || !strings.HasPrefix(line, "SUMMARY:") && !strings.HasPrefix(line, " é") {
+			t.Errorf("bad fold %q", line)
+		}
+	}
+}
+
+func TestICalHandler(t *testing.T) {
+	mock := mockSIX("123", "2024-2")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/schedule/ical?student_id=123&semester=2024-2", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "jadwal-2024-2.ics") {
+		t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+	if !strings.Contains(w.Body.String(), "DTSTART;TZID=Asia/Jakarta:20250113T070000") {
+		t.Errorf("first meeting not in the semester's first week:\n%s", w.Body)
+	}
+
+	req = httptest.NewRequest("GET", "/api/schedule/ical?student_id=123&semester=2024-2&weeks=99", nil)
+	addAuthCookies(req)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("weeks=99: status %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// A background job as listed by GET /api/admin/jobs.
+type Job struct {
+	Name      string     `json:"name"`
+	Enabled   bool       `json:"enabled"`
+	Schedule  string     `json:"schedule"`
+	Interval  string     `json:"interval,omitempty"` // cadence in effect now, for jobs whose cadence varies
+	FRS       *bool      `json:"frs,omitempty"`      // whether an FRS period is in effect, for catalog warm jobs
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Result    string     `json:"result,omitempty"` // summary of the last successful run
+	// Progress counts the units of work of a job that has them, such as
+	// the pages of a catalog crawl.
+	Progress *JobProgress `json:"progress,omitempty"`
+}
+
+type JobProgress struct {
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+	Total  int `json:"total"`
+}
+
+// GET /api/admin/jobs
+func (s *Server) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	writeSuccess(w, s.jobs(time.Now()))
+}
+
+func (s *Server) jobs(now time.Time) []Job {
+	prefetch := Job{Name: "prefetch_pekan", Enabled: prefetchEnabled, Schedule: "Sundays at 22:00 WIB"}
+	if prefetchEnabled {
+		next := nextPrefetchTime(now)
+		prefetch.NextRunAt = &next
+	}
+	jobs := []Job{
+		prefetch,
+		{Name: "grade_watch", Enabled: gradeWatchEnabled, Schedule: "every " + gradeWatchInterval.String()},
+		{Name: "consent_sweep", Enabled: true, Schedule: "every " + time.Minute.String()},
+		{Name: "subscription_digest", Enabled: true, Schedule: "every " + digestTick.String()},
+		{Name: "watch_expiry", Enabled: true, Schedule: "every " + expiryTick.String()},
+		{Name: "mqtt_publish", Enabled: mqttBroker != "" && len(mqttStudents) > 0, Schedule: "every " + mqttInterval.String()},
+	}
+	warming := warmCookies != "" && warmStudentID != ""
+	jobs = append(jobs, s.warmer.jobViews(warming, now)...)
+	return append(jobs, s.crawler.jobView(warming))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Most recent scrape of a schedule that passed the anomaly check.
+type Snapshot struct {
+	SchemaVersion int            `json:"schema_version"` // see classSchemaVersion
+	Key           string         `json:"key"`
+	StudentID     string         `json:"student_id"`
+	Semester      string         `json:"semester"`
+	Classes       []CourseClass  `json:"classes"`
+	Removed       []RemovedClass `json:"removed,omitempty"` // tombstones, see carryTombstones
+	FetchedAt     time.Time      `json:"fetched_at"`
+}
+
+// Returns the tombstones for the snapshot that replaces prev with classes:
+// prev's tombstones for classes still missing, plus a new one for every class
+// prev listed that classes does not. The new ones are also returned alone.
+func carryTombstones(prev Snapshot, classes []CourseClass, fetchedAt time.Time) (all, fresh []RemovedClass) {
+	key := classKey
+	listed := make(map[string]bool, len(classes))
+	for _, c := range classes {
+		listed[key(c)] = true
+	}
+	for _, t := range prev.Removed {
+		if !listed[key(t.CourseClass)] {
+			all = append(all, t)
+		}
+	}
+	var gone []CourseClass
+	for _, c := range prev.Classes {
+		if !listed[key(c)] {
+			gone = append(gone, c)
+		}
+	}
+	if len(gone) > 0 {
+		fresh = tombstones(gone, prev.FetchedAt, fetchedAt)
+		all = append(all, fresh...)
+	}
+	return all, fresh
+}
+
+// Last good snapshots, held in memory and, when dir is set, written under it
+// so they survive restarts.
+type snapshotStore struct {
+	mu        sync.RWMutex
+	dir       string
+	snapshots map[string]Snapshot
+}
+
+func newSnapshotStore(dir string) *snapshotStore {
+	return &snapshotStore{dir: dir, snapshots: make(map[string]Snapshot)}
+}
+
+func (st *snapshotStore) set(s Snapshot) {
+	st.mu.Lock()
+	st.snapshots[s.Key] = s
+	st.mu.Unlock()
+	if st.dir != "" {
+		if err := saveSnapshot(st.dir, s); err != nil {
+			log.Printf("last-good: save failed key=%s err=%v", s.Key, err)
+		}
+	}
+}
+
+// Returns the last good snapshot for key, loading it from disk if it is not
+// in memory.
+func (st *snapshotStore) get(key string) (Snapshot, bool) {
+	st.mu.RLock()
+	s, ok := st.snapshots[key]
+	st.mu.RUnlock()
+	if ok || st.dir == "" {
+		return s, ok
+	}
+	s, err := loadSnapshot(st.dir, key)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("last-good: load failed key=%s err=%v", key, err)
+		}
+		return Snapshot{}, false
+	}
+	st.mu.Lock()
+	st.snapshots[key] = s
+	st.mu.Unlock()
+	return s, true
+}
+
+func snapshotPath(dir, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, "last-good", hex.EncodeToString(sum[:])+".json")
+}
+
+// Writes s to a temporary file and renames it into place, so readers never
+// see a partial snapshot.
+func saveSnapshot(dir string, s Snapshot) error {
+	s.SchemaVersion = classSchemaVersion
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(snapshotPath(dir, s.Key), data)
+}
+
+// Writes data to a temporary file next to path and renames it into place,
+// creating the directory if needed.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func loadSnapshot(dir, key string) (Snapshot, error) {
+	var s Snapshot
+	data, err := os.ReadFile(snapshotPath(dir, key))
+	if err != nil {
+		return s, err
+	}
+	err = unmarshalVersioned(data, &s, func(blob map[string]any) []any {
+		return []any{blob["classes"], blob["removed"]}
+	})
+	if err != nil {
+		return s, err
+	}
+	if s.Key != key {
+		return Snapshot{}, fs.ErrNotExist
+	}
+	return s, nil
+}
+
+// Serves the most recent snapshot that passed the anomaly check, however
+// old. It never contacts SIX, so it keeps working during outages.
+func (s *Server) lastGoodHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	p, err := newPipeline(query)
+	if err != nil {
+		writeError(w, r, codeInvalidRequest, err.Error())
+		return
+	}
+	studentID := query.Get("student_id")
+	semester, relative := s.semesters.resolve(studentID, query.Get("semester"), time.Now())
+	snap, ok := s.lastGood.get(schedulePath(studentID, semester, query))
+	if !ok {
+		writeError(w, r, codeSnapshotNotFound)
+		return
+	}
+	meta := &Meta{FetchedAt: snap.FetchedAt, Cached: true}
+	if relative {
+		meta.Semester = semester
+	}
+	writeSuccessWithMeta(w, p.view(p.apply(snap.Classes)), meta)
+}
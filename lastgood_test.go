@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getLastGoodResponse(srv *Server, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/schedule/last-good?"+query, nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestSnapshot_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	want := Snapshot{Key: "k", StudentID: "123", Semester: "1945-1", Classes: []CourseClass{{Code: "IF2211"}}, FetchedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := saveSnapshot(dir, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadSnapshot(dir, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != want.Key || !got.FetchedAt.Equal(want.FetchedAt) || len(got.Classes) != 1 {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, err := loadSnapshot(dir, "other"); err == nil {
+		t.Error("expected an error for a missing snapshot")
+	}
+}
+
+func TestLastGoodHandler(t *testing.T) {
+	dir := t.TempDir()
+	srv := NewServer(Config{DataDir: dir})
+
+	if w := getLastGoodResponse(srv, "student_id=123"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing semester: got status %d, want 422", w.Code)
+	}
+	if w := getLastGoodResponse(srv, "student_id=123&semester=1945-1"); w.Code != http.StatusNotFound {
+		t.Errorf("no snapshot: got status %d, want 404", w.Code)
+	}
+
+	key := schedulePath("123", "1945-1", nil)
+	fetchedAt := time.Now().Add(-48 * time.Hour)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "IF2211"}}, fetchedAt, nil)
+	srv.updateSchedule(key, "123", "1945-1", nil, time.Now(), &Anomaly{Score: 1})
+
+	// A fresh server has nothing in memory; the snapshot must come back from disk.
+	srv = NewServer(Config{DataDir: dir})
+	w := getLastGoodResponse(srv, "student_id=123&semester=1945-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	classes := decodeData[[]CourseClass](t, w)
+	if len(classes) != 1 || classes[0].Code != "IF2211" {
+		t.Errorf("classes = %+v", classes)
+	}
+}
+
+func TestUpdateSchedule_Tombstones(t *testing.T) {
+	dir := t.TempDir()
+	srv := NewServer(Config{DataDir: dir})
+	key := schedulePath("123", "1945-1", nil)
+	a := CourseClass{Code: "IF2211", ClassNo: "01"}
+	b := CourseClass{Code: "IF2230", ClassNo: "02", Name: "Sistem Operasi"}
+	t1 := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	t2, t3 := t1.Add(time.Hour), t1.Add(2*time.Hour)
+
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{a, b}, t1, nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{a}, t2, nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{a}, t3, nil)
+
+	srv = NewServer(Config{DataDir: dir})
+	snap, ok := srv.lastGood.get(key)
+	if !ok || len(snap.Removed) != 1 {
+		t.Fatalf("snapshot = %+v, want one tombstone", snap)
+	}
+	got := snap.Removed[0]
+	if got.Code != "IF2230" || got.Name != "Sistem Operasi" || got.Status != "removed" || !got.LastSeenAt.Equal(t1) || !got.RemovedAt.Equal(t2) {
+		t.Errorf("tombstone = %+v", got)
+	}
+
+	// A class that comes back loses its tombstone.
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{a, b}, t3.Add(time.Hour), nil)
+	if snap, _ := srv.lastGood.get(key); len(snap.Removed) != 0 {
+		t.Errorf("tombstones = %+v, want none", snap.Removed)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func layoutFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "layouts", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseClasses_MergedCourseRows(t *testing.T) {
+	classes := parseClasses(docFromHTML(layoutFixture(t, "merged-course-rows.html")))
+	if len(classes) != 4 {
+		t.Fatalf("got %d classes, want 4: %+v", len(classes), classes)
+	}
+	for _, c := range classes[:3] {
+		if c.Code != "MA1101" || c.Name != "Matematika IA" || c.SKS != 4 {
+			t.Errorf("class %s carries %q %q %d", c.ClassNo, c.Code, c.Name, c.SKS)
+		}
+	}
+	if c := classes[1]; c.ClassNo != "02" || c.Notes != "Kelas internasional" || len(c.Schedules) != 1 || c.Schedules[0].Day != "Selasa" {
+		t.Errorf("second class = %+v", c)
+	}
+	if c := classes[2]; c.Quota != 80 || c.Enrolled == nil || *c.Enrolled != 75 {
+		t.Errorf("third class quota = %d, enrolled %v", c.Quota, c.Enrolled)
+	}
+	if c := classes[3]; c.Code != "FI1101" || c.Notes != "Jadwal menyusul" || len(c.Schedules) != 0 {
+		t.Errorf("last class = %+v", c)
+	}
+
+	sample := sampleScrape(docFromHTML(layoutFixture(t, "merged-course-rows.html")), classes, 0)
+	if sample.Rows != 4 || sample.Columns != 10 {
+		t.Errorf("sample = %+v", sample)
+	}
+}
+
+func TestParseTranscript_MergedSemester(t *testing.T) {
+	courses := parseTranscript(docFromHTML(layoutFixture(t, "merged-transcript-semester.html")))
+	if len(courses) != 3 {
+		t.Fatalf("got %d courses: %+v", len(courses), courses)
+	}
+	want := []struct{ code, semester, grade string }{{"MA1101", "2023-1", "A"}, {"FI1101", "2023-1", "AB"}, {"MA1201", "2023-2", "B"}}
+	for i, w := range want {
+		if c := courses[i]; c.Code != w.code || c.Semester != w.semester || c.Grade != w.grade {
+			t.Errorf("course %d = %+v, want %+v", i, c, w)
+		}
+	}
+}
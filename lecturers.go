@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"six-scraper-go/textnorm"
+)
+
+// GET /api/analytics/lecturer-load totals each lecturer's teaching in a
+// faculty's cached catalog pages, like the room report. A team-taught class
+// counts in full for every lecturer on it; shared_classes says how many of
+// a lecturer's classes are team-taught.
+
+type LecturerLoadReport struct {
+	Semester  string         `json:"semester"`
+	Fakultas  string         `json:"fakultas"`
+	Pages     int            `json:"pages"`
+	FetchedAt time.Time      `json:"fetched_at"`
+	Lecturers []LecturerLoad `json:"lecturers"`
+}
+
+type LecturerLoad struct {
+	Name          string   `json:"name"`
+	Classes       int      `json:"classes"`
+	SharedClasses int      `json:"shared_classes"`
+	SKS           int      `json:"sks"`
+	ContactHours  float64  `json:"contact_hours"` // scheduled meeting hours per week
+	Courses       []string `json:"courses"`       // distinct course codes
+}
+
+// Builds the load of every lecturer in classes, heaviest by SKS first.
+// Lecturer names are matched ignoring case and accents.
+func lecturerLoads(classes []CourseClass) []LecturerLoad {
+	type classKey struct{ code, classNo string }
+	seen := make(map[classKey]bool)
+	loads := make(map[string]*LecturerLoad)
+	minutes := make(map[string]int)
+	for _, c := range classes {
+		k := classKey{c.Code, c.ClassNo}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		contact := 0
+		for _, e := range c.Schedules {
+			startText, endText, _ := strings.Cut(e.Time, "-")
+			start, ok1 := clockMinutes(startText)
+			end, ok2 := clockMinutes(endText)
+			if ok1 && ok2 && end > start {
+				contact += end - start
+			}
+		}
+		var names []string
+		for _, name := range c.Lecturers {
+			if key := textnorm.Key(name); key != "" && !slices.Contains(names, key) {
+				names = append(names, key)
+				l, ok := loads[key]
+				if !ok {
+					l = &LecturerLoad{Name: strings.TrimSpace(name), Courses: []string{}}
+					loads[key] = l
+				}
+				l.Classes++
+				l.SKS += c.SKS
+				minutes[key] += contact
+				if !slices.Contains(l.Courses, c.Code) {
+					l.Courses = append(l.Courses, c.Code)
+				}
+			}
+		}
+		if len(names) > 1 {
+			for _, key := range names {
+				loads[key].SharedClasses++
+			}
+		}
+	}
+
+	out := make([]LecturerLoad, 0, len(loads))
+	for key, l := range loads {
+		l.ContactHours = math.Round(float64(minutes[key])/6) / 10
+		slices.Sort(l.Courses)
+		out = append(out, *l)
+	}
+	slices.SortFunc(out, func(a, b LecturerLoad) int {
+		return cmp.Or(cmp.Compare(b.SKS, a.SKS), cmp.Compare(b.ContactHours, a.ContactHours), cmp.Compare(a.Name, b.Name))
+	})
+	return out
+}
+
+// GET /api/analytics/lecturer-load
+func (s *Server) lecturerLoadHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	semester, _ := s.semesters.resolve("", query.Get("semester"), time.Now())
+	fakultas := query.Get("fakultas")
+	classes, pages, fetchedAt := s.facultyCatalog(semester, fakultas)
+	if pages == 0 {
+		writeError(w, r, codeCatalogNotCached)
+		return
+	}
+	report := LecturerLoadReport{Semester: semester, Fakultas: fakultas, Pages: pages, FetchedAt: fetchedAt, Lecturers: lecturerLoads(classes)}
+
+	if query.Get("format") != "csv" {
+		writeSuccess(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="lecturer-load-`+semester+`-`+fakultas+`.csv"`)
+	if err := writeLecturerCSV(csv.NewWriter(w), report); err != nil {
+		log.Printf("csv encode error: %v", err)
+	}
+}
+
+// Writes one row per lecturer, with the courses separated by spaces.
+func writeLecturerCSV(cw *csv.Writer, report LecturerLoadReport) error {
+	cw.Write([]string{"lecturer", "classes", "shared_classes", "sks", "contact_hours", "courses"})
+	for _, l := range report.Lecturers {
+		cw.Write([]string{
+			l.Name, strconv.Itoa(l.Classes), strconv.Itoa(l.SharedClasses), strconv.Itoa(l.SKS),
+			strconv.FormatFloat(l.ContactHours, 'f', 1, 64), strings.Join(l.Courses, " "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
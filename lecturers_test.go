@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLecturerLoads(t *testing.T) {
+	classes := []CourseClass{
+		{Code: "IF2211", ClassNo: "01", SKS: 3, Lecturers: []string{"Dr. Rinaldi", "Dr. Nur Ulfa"}, Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00"}, {Day: "Rabu", Time: "09:00-10:00"},
+		}},
+		{Code: "IF2211", ClassNo: "02", SKS: 3, Lecturers: []string{"DR. RINALDI"}, Schedules: []ScheduleEntry{
+			{Day: "Selasa", Time: "07:00-10:00"},
+		}},
+		{Code: "IF3230", ClassNo: "01", SKS: 2, Lecturers: []string{"Dr. Nur Ulfa"}, Schedules: []ScheduleEntry{
+			{Day: "Kamis", Time: "13:00-14:30"},
+		}},
+		// Listed again on another prodi's page.
+		{Code: "IF3230", ClassNo: "01", SKS: 2, Lecturers: []string{"Dr. Nur Ulfa"}},
+	}
+	loads := lecturerLoads(classes)
+	if len(loads) != 2 {
+		t.Fatalf("loads = %+v", loads)
+	}
+	rinaldi, ulfa := loads[0], loads[1]
+	if rinaldi.Name != "Dr. Rinaldi" || rinaldi.Classes != 2 || rinaldi.SharedClasses != 1 || rinaldi.SKS != 6 || rinaldi.ContactHours != 6 || !slices.Equal(rinaldi.Courses, []string{"IF2211"}) {
+		t.Errorf("Rinaldi = %+v", rinaldi)
+	}
+	if ulfa.Classes != 2 || ulfa.SharedClasses != 1 || ulfa.SKS != 5 || ulfa.ContactHours != 4.5 || !slices.Equal(ulfa.Courses, []string{"IF2211", "IF3230"}) {
+		t.Errorf("Nur Ulfa = %+v", ulfa)
+	}
+}
+
+func TestLecturerLoadHandler(t *testing.T) {
+	srv := newTestServer("")
+	srv.catalog.set("2025-2?fakultas=FMIPA&prodi=102", parseClasses(docFromHTML(testScheduleHTML)), time.Now())
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/analytics/lecturer-load?semester=2025-2&fakultas=STEI", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("other faculty: status %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/analytics/lecturer-load?semester=2025-2&fakultas=FMIPA&format=csv", nil))
+	want := "lecturer,classes,shared_classes,sks,contact_hours,courses\n" +
+		"Dosen A,1,1,3,4.0,FI1210\n" +
+		"Dosen B,1,1,3,4.0,FI1210\n" +
+		"Dosen C,1,0,3,2.0,FI1220\n"
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("status %d, csv:\n%s\nwant\n%s", w.Code, w.Body, want)
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "lecturer-load-2025-2-FMIPA.csv") {
+		t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// The LINE Messaging API bot is enabled by SIX_LINE_CHANNEL_SECRET and
+// SIX_LINE_ACCESS_TOKEN, and its webhook URL is /api/chat/webhook/line.
+var (
+	lineChannelSecret = envString("SIX_LINE_CHANNEL_SECRET", "")
+	lineAccessToken   = envString("SIX_LINE_ACCESS_TOKEN", "")
+	lineAPIURL        = envString("SIX_LINE_API_URL", "https://api.line.me")
+)
+
+type lineAdapter struct {
+	secret string
+	token  string
+	apiURL string
+}
+
+// Returns nil unless the LINE bot is configured.
+func newLINEAdapter() *lineAdapter {
+	if lineChannelSecret == "" || lineAccessToken == "" {
+		return nil
+	}
+	return &lineAdapter{secret: lineChannelSecret, token: lineAccessToken, apiURL: strings.TrimSuffix(lineAPIURL, "/")}
+}
+
+func (a *lineAdapter) Name() string { return "line" }
+
+// LINE signs the body with the channel secret, base64 encoded in
+// X-Line-Signature.
+func (a *lineAdapter) Verify(r *http.Request, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(a.secret))
+	mac.Write(body)
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(r.Header.Get("X-Line-Signature")))
+}
+
+type lineWebhook struct {
+	Events []struct {
+		Type       string `json:"type"`
+		ReplyToken string `json:"replyToken"`
+		Source     struct {
+			UserID string `json:"userId"`
+		} `json:"source"`
+		Message struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"message"`
+	} `json:"events"`
+}
+
+func (a *lineAdapter) Messages(body []byte) ([]ChatMessage, error) {
+	var hook lineWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return nil, err
+	}
+	var messages []ChatMessage
+	for _, e := range hook.Events {
+		if e.Type == "message" && e.Message.Type == "text" && e.Source.UserID != "" {
+			messages = append(messages, ChatMessage{UserID: e.Source.UserID, Text: e.Message.Text, ReplyToken: e.ReplyToken})
+		}
+	}
+	return messages, nil
+}
+
+func (a *lineAdapter) Reply(ctx context.Context, msg ChatMessage, text string) error {
+	type lineMessage struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	return postChatAPI(ctx, a.apiURL+"/v2/bot/message/reply", a.token, struct {
+		ReplyToken string        `json:"replyToken"`
+		Messages   []lineMessage `json:"messages"`
+	}{msg.ReplyToken, []lineMessage{{"text", text}}})
+}
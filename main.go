@@ -1,46 +1,41 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-)
 
-const sixBaseURL = "https://six.itb.ac.id"
+	"six-scraper-go/scraper"
+)
 
 var (
-	studentIDRe  = regexp.MustCompile(`mahasiswa:(\d+)`)
-	semesterRe   = regexp.MustCompile(`\+(\d{4}-\d)`)
-	whitespaceRe = regexp.MustCompile(`\s+`)
+	studentIDRe      = regexp.MustCompile(`mahasiswa:(\d+)`)
+	semesterRe       = regexp.MustCompile(`\+(\d{4}-\d)`)
+	studentIDParamRe = regexp.MustCompile(`^\d{1,20}$`)
+	semesterParamRe  = regexp.MustCompile(`^\d{4}-\d$`)
 )
 
-type ScheduleEntry struct {
-	Day      string `json:"day"`
-	Time     string `json:"time"`
-	Room     string `json:"room"`
-	Activity string `json:"activity"`
-	Method   string `json:"method"`
-}
-
-type CourseClass struct {
-	Code      string          `json:"code"`
-	Name      string          `json:"name"`
-	SKS       int             `json:"sks"`
-	ClassNo   string          `json:"class_no"`
-	Quota     int             `json:"quota"`
-	Lecturers []string        `json:"lecturers"`
-	Notes     string          `json:"notes"`
-	Schedules []ScheduleEntry `json:"schedules"`
-}
+// The class types live in the scraper package so that class hooks
+// registered by embedders can use them.
+type (
+	ScheduleEntry = scraper.ScheduleEntry
+	CourseClass   = scraper.CourseClass
+)
 
 type UserResponse struct {
 	StudentID string `json:"student_id"`
@@ -48,78 +43,118 @@ type UserResponse struct {
 }
 
 type APIResponse struct {
-	Success bool   `json:"success"`
-	Data    any    `json:"data,omitempty"`
-	Meta    *Meta  `json:"meta,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success   bool              `json:"success"`
+	Data      any               `json:"data,omitempty"`
+	Meta      *Meta             `json:"meta,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Code      errorCode         `json:"code,omitempty"`
+	Details   []ValidationError `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
 }
 
 type Meta struct {
 	FetchedAt time.Time `json:"fetched_at"`
 	Cached    bool      `json:"cached"`
+	Anomaly   *Anomaly  `json:"anomaly,omitempty"`
+	// Semester is the concrete semester a relative one (e.g. current)
+	// resolved to.
+	Semester string `json:"semester,omitempty"`
+	// Source says where fresh data came from: the official API ("api") or
+	// the scraped SIX pages ("scrape"). For a cached catalog page it is the
+	// shared catalog cache ("catalog") or a peer instance ("peer").
+	Source string `json:"source,omitempty"`
+	// Stale is set when an expired cache entry was served while it is
+	// refreshed in the background (SIX_STALE_WHILE_REVALIDATE).
+	Stale bool `json:"stale,omitempty"`
+	// PagesFetched is how many SIX pages a fresh scrape took when the
+	// listing spanned more than one. PagesTruncated is set when
+	// SIX_MAX_PAGES was reached before the last one.
+	PagesFetched   int  `json:"pages_fetched,omitempty"`
+	PagesTruncated bool `json:"pages_truncated,omitempty"`
 }
 
-var requiredCookies = []string{"nissin", "khongguan"}
-
-const cacheTTL = 5 * time.Minute
-
-type cacheEntry struct {
-	data      []CourseClass
-	fetchedAt time.Time
-	expiresAt time.Time
-}
-
-var (
-	scheduleCache = make(map[string]cacheEntry)
-	cacheMu       sync.RWMutex
-)
-
 func main() {
-	http.Handle("/api/user", logRequest(http.HandlerFunc(userHandler)))
-	http.Handle("/api/schedule", logRequest(http.HandlerFunc(scheduleHandler)))
-
-	fmt.Println("Server starting on :8080...")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		os.Exit(runBackfillCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelfTestCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplayCommand(os.Args[2:]))
+	}
 
-// Wraps a handler and logs method, path, status, and total duration.
-func logRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(sw, r)
-		log.Printf("%s %s status=%d duration=%s", r.Method, r.URL.String(), sw.status, time.Since(start))
-	})
-}
+	cfg := configFromEnv()
+	transport, err := upstreamTransport()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	cfg.Transport = transport
+	if recordDir != "" {
+		cfg.Transport = &recordingTransport{next: cfg.Transport}
+		log.Printf("recording cassettes to %s", recordDir)
+	}
+	srv := NewServer(cfg)
+	srv.loadCacheSpill()
+	log.Printf("politeness profile %s: concurrency=%d batch_delay=%s cache_ttl=%s catalog_ttl=%s retries=%d",
+		politeness.Name, politeness.Concurrency, politeness.BatchDelay, cfg.CacheTTL, cfg.CatalogTTL, politeness.Retries)
+	if prefetchEnabled {
+		go srv.runPrefetcher(context.Background())
+	}
+	if gradeWatchEnabled {
+		go srv.runGradeWatcher(context.Background())
+	}
+	go srv.runConsentSweeper(context.Background())
+	go srv.runDigestScheduler(context.Background())
+	go srv.runExpirySweeper(context.Background())
+	if len(warmSchedules) > 0 {
+		go srv.runCatalogWarmer(context.Background())
+	}
+	srv.resumeCatalogCrawl(context.Background())
+	if mqttBroker != "" && len(mqttStudents) > 0 {
+		go srv.runMQTTPublisher(context.Background())
+	}
 
-type statusWriter struct {
-	http.ResponseWriter
-	status      int
-	wroteHeader bool
-}
+	httpServer := &http.Server{Addr: ":8080", Handler: srv}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		log.Printf("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
 
-func (sw *statusWriter) WriteHeader(code int) {
-	if !sw.wroteHeader {
-		sw.status = code
-		sw.wroteHeader = true
+	fmt.Println("Server starting on :8080...")
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
 	}
-	sw.ResponseWriter.WriteHeader(code)
+	// ListenAndServe returns as soon as Shutdown starts; once Shutdown
+	// returns, in-flight requests have finished and the cache holds
+	// everything they fetched.
+	<-shutdown
+	if err := srv.spillCache(); err != nil {
+		log.Printf("cache spill failed: %v", err)
+	}
+	srv.audit.Close()
 }
 
 // Creates an outbound request to SIX
 func newSIXRequest(targetURL string, r *http.Request) (*http.Request, error) {
-	req, err := http.NewRequest("GET", targetURL, nil)
+	req, err := http.NewRequestWithContext(r.Context(), "GET", targetURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	v := r.Header.Get("X-Six-Khongguan")
-	if v == "" {
-		return nil, fmt.Errorf("missing required khongguan token")
+	if err := forwardCookies(req, r); err != nil {
+		return nil, err
 	}
-
-	req.AddCookie(&http.Cookie{Name: "khongguan", Value: v})
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	forwardHeaders(req, r)
 	return req, nil
 }
 
@@ -131,7 +166,7 @@ func fetchDoc(client *http.Client, targetURL string, r *http.Request) (*goquery.
 	}
 
 	fetchStart := time.Now()
-	resp, err := client.Do(req)
+	resp, err := doUpstream(client, req)
 	fetchDuration := time.Since(fetchStart)
 	if err != nil {
 		log.Printf("fetch error url=%s duration=%s err=%v", targetURL, fetchDuration, err)
@@ -139,240 +174,320 @@ func fetchDoc(client *http.Client, targetURL string, r *http.Request) (*goquery.
 	}
 
 	log.Printf("fetch url=%s status=%d duration=%s", targetURL, resp.StatusCode, fetchDuration)
+	detectSessionCookies(resp)
 
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		resp.Body.Close()
+		noteUpstreamMaintenance()
+		return nil, resp, errUpstreamMaintenance
+	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
 		return nil, resp, fmt.Errorf("upstream returned %s", resp.Status)
 	}
 
 	parseStart := time.Now()
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body := &countingReader{r: resp.Body}
+	doc, err := goquery.NewDocumentFromReader(body)
 	resp.Body.Close()
 	if err != nil {
 		return nil, resp, err
 	}
+	// Report the bytes actually read; SIX does not always send Content-Length.
+	resp.ContentLength = body.n
 	log.Printf("parse url=%s duration=%s", targetURL, time.Since(parseStart))
+
+	if isMaintenancePage(doc) {
+		noteUpstreamMaintenance()
+		return nil, resp, errUpstreamMaintenance
+	}
+	noteUpstreamHealthy()
 	return doc, resp, nil
 }
 
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func writeSuccess(w http.ResponseWriter, data any) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(APIResponse{Success: true, Data: data}); err != nil {
+	resp := APIResponse{Success: true, Data: data}
+	noteEncoded(w, resp)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		log.Printf("json encode error: %v", err)
 	}
 }
 
 func writeSuccessWithMeta(w http.ResponseWriter, data any, meta *Meta) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(APIResponse{Success: true, Data: data, Meta: meta}); err != nil {
+	resp := APIResponse{Success: true, Data: data, Meta: meta}
+	noteEncoded(w, resp)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		log.Printf("json encode error: %v", err)
 	}
 }
 
-func writeError(w http.ResponseWriter, status int, msg string) {
+func writeCreated(w http.ResponseWriter, data any) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(APIResponse{Success: false, Error: msg}); err != nil {
+	w.WriteHeader(http.StatusCreated)
+	resp := APIResponse{Success: true, Data: data}
+	noteEncoded(w, resp)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		log.Printf("json encode error: %v", err)
 	}
 }
 
-func newHTTPClient() *http.Client {
-	return &http.Client{}
-}
-
-func userHandler(w http.ResponseWriter, r *http.Request) {
-	client := newHTTPClient()
+func (s *Server) userHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
 
-	// Get Student ID from /home
-	doc, _, err := fetchDoc(client, sixBaseURL+"/home", r)
-	if err != nil {
-		writeError(w, http.StatusBadGateway, err.Error())
+	home, err := s.provider.FetchHomePage(r)
+	switch {
+	case errors.Is(err, errStudentIDNotFound):
+		writeError(w, r, codeStudentIDNotFound)
+		return
+	case errors.Is(err, errSemesterNotFound):
+		writeError(w, r, codeSemesterNotFound)
+		return
+	case err != nil:
+		writeUpstreamError(w, r, err)
 		return
 	}
 
-	var studentID string
-	doc.Find("a[href*='mahasiswa:']").EachWithBreak(func(_ int, s *goquery.Selection) bool {
-		href, _ := s.Attr("href")
-		if m := studentIDRe.FindStringSubmatch(href); len(m) > 1 {
-			studentID = m[1]
-			return false
-		}
-		return true
-	})
+	s.semesters.learn(home.StudentID, home.Semester, time.Now())
+	writeSuccessWithMeta(w, UserResponse{StudentID: home.StudentID, Semester: home.Semester}, &Meta{FetchedAt: time.Now(), Source: home.Source})
+}
 
-	if studentID == "" {
-		writeError(w, http.StatusNotFound, "Could not find student ID on /home")
+func (s *Server) scheduleHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "geojson" && s.buildings == nil {
+		writeError(w, r, codeNoBuildings)
 		return
 	}
-
-	// Get Semester from redirect URL
-	redirectURL := fmt.Sprintf("%s/app/mahasiswa:%s/kelas", sixBaseURL, studentID)
-	req, err := newSIXRequest(redirectURL, r)
+	if format == "template" && s.templates[r.URL.Query().Get("name")] == nil {
+		writeError(w, r, codeTemplateNotFound)
+		return
+	}
+	p, err := newPipeline(r.URL.Query())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, codeInvalidRequest, err.Error())
+		return
+	}
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
 		return
 	}
+	e, onlyEligible, ok := s.requestedEligibility(w, r, r.URL.Query().Get("student_id"))
+	if !ok {
+		return
+	}
+	if e != nil {
+		classes = e.annotate(classes, onlyEligible)
+	}
+	classes = p.apply(classes)
+	switch format {
+	case "geojson":
+		writeGeoJSON(w, scheduleGeoJSON(classes, s.buildings))
+	case "grid":
+		writeSuccessWithMeta(w, scheduleGrid(classes, s.buildings), meta)
+	case "template":
+		s.writeTemplate(w, r, classes, meta)
+	default:
+		writeSuccessWithMeta(w, p.view(classes), meta)
+	}
+}
 
-	resp, err := client.Do(req)
+// Returns one class of the schedule selected by the query, e.g.
+// /api/classes/IF2211/01?student_id=...&semester=....
+func (s *Server) classHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := newPipeline(r.URL.Query())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, codeInvalidRequest, err.Error())
 		return
 	}
-	resp.Body.Close()
-
-	finalURL := resp.Request.URL.String()
-	m := semesterRe.FindStringSubmatch(finalURL)
-	if len(m) < 2 {
-		writeError(w, http.StatusNotFound, "Could not infer semester from redirect URL: "+finalURL)
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
 		return
 	}
-
-	writeSuccess(w, UserResponse{StudentID: studentID, Semester: m[1]})
+	code, classNo := r.PathValue("code"), r.PathValue("class_no")
+	for _, c := range classes {
+		if strings.EqualFold(c.Code, code) && c.ClassNo == classNo {
+			// A filter such as day can leave nothing of the class.
+			if kept := p.apply([]CourseClass{c}); len(kept) == 1 {
+				writeSuccessWithMeta(w, p.viewOne(kept[0]), meta)
+				return
+			}
+			break
+		}
+	}
+	writeError(w, r, codeClassNotFound)
 }
 
-func scheduleHandler(w http.ResponseWriter, r *http.Request) {
+// Returns the schedule selected by r's query, from cache unless refresh=true,
+// otherwise from SIX. The query has already been validated against
+// scheduleParams. On failure it writes the error response and returns false.
+func (s *Server) loadSchedule(w http.ResponseWriter, r *http.Request) ([]CourseClass, *Meta, bool) {
 	query := r.URL.Query()
-	studentID := query.Get("student_id")
-	semester := query.Get("semester")
-
-	if studentID == "" || semester == "" {
-		writeError(w, http.StatusBadRequest, "Missing student_id or semester query parameters")
-		return
-	}
+	return s.schedule(w, r, query.Get("student_id"), query.Get("semester"), query)
+}
 
-	targetURL := buildScheduleURL(studentID, semester, query)
+// Like loadSchedule, for a student and semester that did not come from the
+// query. query supplies the filters and refresh.
+func (s *Server) schedule(w http.ResponseWriter, r *http.Request, studentID, semester string, query url.Values) ([]CourseClass, *Meta, bool) {
+	semester, relative := s.semesters.resolve(studentID, semester, time.Now())
+	key := schedulePath(studentID, semester, query)
 	refresh := query.Get("refresh") == "true"
+	recordPrefetchCandidate(r, key, studentID, semester)
 
 	if !refresh {
-		if entry, ok := getCached(targetURL); ok {
+		if entry, ok := s.cache.get(key); ok {
 			log.Printf("cache hit student_id=%s semester=%s", studentID, semester)
-			writeSuccessWithMeta(w, entry.data, &Meta{FetchedAt: entry.fetchedAt, Cached: true})
-			return
+			meta := &Meta{FetchedAt: entry.fetchedAt, Cached: true, Anomaly: entry.anomaly}
+			if relative {
+				meta.Semester = semester
+			}
+			return entry.data, meta, true
 		}
 	}
 	log.Printf("cache miss student_id=%s semester=%s refresh=%v", studentID, semester, refresh)
 
-	client := newHTTPClient()
-	doc, _, err := fetchDoc(client, targetURL, r)
-	if err != nil {
-		writeError(w, http.StatusBadGateway, err.Error())
-		return
+	if !refresh {
+		if entry, source, ok := s.sharedCatalog(r.Context(), semester, query); ok {
+			s.cache.put(key, entry)
+			meta := &Meta{FetchedAt: entry.fetchedAt, Cached: true, Source: source}
+			if relative {
+				meta.Semester = semester
+			}
+			return entry.data, meta, true
+		}
+		if entry, ok := s.cache.peek(key); ok && entry.anomaly == nil && staleWhileRevalidate > 0 && time.Since(entry.expiresAt) <= staleWhileRevalidate {
+			log.Printf("serving stale student_id=%s semester=%s", studentID, semester)
+			s.revalidate(r, key, studentID, semester, query)
+			meta := &Meta{FetchedAt: entry.fetchedAt, Cached: true, Stale: true}
+			if relative {
+				meta.Semester = semester
+			}
+			return entry.data, meta, true
+		}
 	}
 
-	now := time.Now()
-	classes := parseClasses(doc)
-	log.Printf("parsed classes=%d student_id=%s semester=%s", len(classes), studentID, semester)
-	setCache(targetURL, classes, now)
-	writeSuccessWithMeta(w, classes, &Meta{FetchedAt: now, Cached: false})
-}
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	defer release()
 
-func getCached(key string) (cacheEntry, bool) {
-	cacheMu.RLock()
-	defer cacheMu.RUnlock()
-	entry, ok := scheduleCache[key]
-	if !ok || time.Now().After(entry.expiresAt) {
-		return cacheEntry{}, false
+	classes, meta, err := s.scrapeSchedule(r, studentID, semester, query)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return nil, nil, false
+	}
+	log.Printf("parsed classes=%d student_id=%s semester=%s source=%s", len(classes), studentID, semester, meta.Source)
+	if ckey, ok := catalogKey(semester, query); ok && meta.Anomaly == nil {
+		s.catalog.set(ckey, classes, meta.FetchedAt)
 	}
-	return entry, true
+	if relative {
+		meta.Semester = semester
+	}
+	return classes, meta, true
 }
 
-func setCache(key string, data []CourseClass, fetchedAt time.Time) {
-	cacheMu.Lock()
-	defer cacheMu.Unlock()
-	scheduleCache[key] = cacheEntry{data: data, fetchedAt: fetchedAt, expiresAt: time.Now().Add(cacheTTL)}
-}
+// Query parameters forwarded to the SIX schedule page.
+var scheduleFilterKeys = []string{"fakultas", "prodi", "pekan", "kegiatan"}
 
-func buildScheduleURL(studentID, semester string, query url.Values) string {
-	u := fmt.Sprintf("%s/app/mahasiswa:%s+%s/kelas/jadwal/kuliah", sixBaseURL, studentID, semester)
+// Returns the path and query of the SIX schedule page for a student and
+// semester. The path doubles as the cache key, so filters are normalized:
+// unknown keys and blank values are dropped, values are trimmed, and
+// parameters are sorted by Encode.
+func schedulePath(studentID, semester string, query url.Values) string {
+	u := fmt.Sprintf("/app/mahasiswa:%s+%s/kelas/jadwal/kuliah", studentID, semester)
+	if encoded := scheduleFilters(query).Encode(); encoded != "" {
+		u += "?" + encoded
+	}
+	return u
+}
 
+func scheduleFilters(query url.Values) url.Values {
 	q := url.Values{}
-	for _, key := range []string{"fakultas", "prodi", "pekan", "kegiatan"} {
-		if v := query.Get(key); v != "" {
+	for _, key := range scheduleFilterKeys {
+		if v := strings.TrimSpace(query.Get(key)); v != "" {
 			q.Set(key, v)
 		}
 	}
-	if encoded := q.Encode(); encoded != "" {
-		u += "?" + encoded
-	}
-	return u
+	return q
 }
 
-func parseClasses(doc *goquery.Document) []CourseClass {
-	var classes []CourseClass
+// Reports whether studentID and semester are safe to splice into a SIX path.
+func validScheduleParams(studentID, semester string) bool {
+	return studentIDParamRe.MatchString(studentID) && semesterParamRe.MatchString(semester)
+}
 
-	doc.Find("table.table tbody tr").Each(func(_ int, s *goquery.Selection) {
-		cells := s.Find("td, th")
-		if cells.Length() < 10 {
-			return
-		}
+// Bump parserVersion whenever scraper.ParseClassesIter changes what it
+// extracts, so stored and exported data can say which parser produced it.
+const parserVersion = 5
 
-		sks, _ := strconv.Atoi(strings.TrimSpace(cells.Eq(4).Text()))
-		quota, _ := strconv.Atoi(strings.TrimSpace(cells.Eq(6).Text()))
-
-		class := CourseClass{
-			Code:      strings.TrimSpace(cells.Eq(2).Text()),
-			Name:      strings.TrimSpace(cells.Eq(3).Text()),
-			SKS:       sks,
-			ClassNo:   strings.TrimSpace(cells.Eq(5).Text()),
-			Quota:     quota,
-			Lecturers: parseLecturers(cells.Eq(7)),
-			Notes:     collapseWhitespace(cells.Eq(8).Text()),
-			Schedules: parseSchedules(cells.Eq(9)),
-		}
+func parseClasses(doc *goquery.Document) []CourseClass {
+	return enrichClasses(scraper.ParseClasses(doc))
+}
 
-		if class.Code != "" {
-			classes = append(classes, class)
+// Fills in what can be derived from a fetched class, then runs the
+// registered class hooks. A failed hook is logged and does not fail the
+// scrape.
+func enrichClasses(classes []CourseClass) []CourseClass {
+	for i := range classes {
+		if parts, ok := scraper.ParseCourseCode(classes[i].Code); ok {
+			classes[i].CodeParts = &parts
 		}
-	})
-
+	}
+	classes, err := scraper.ApplyClassHooks(classes)
+	if err != nil {
+		log.Printf("class hooks: %v", err)
+	}
 	return classes
 }
 
-func parseLecturers(cell *goquery.Selection) []string {
-	var lecturers []string
-	cell.Find("ul li").Each(func(_ int, li *goquery.Selection) {
-		if name := strings.TrimSpace(li.Text()); name != "" {
-			lecturers = append(lecturers, name)
-		}
-	})
-	return lecturers
+// Day names in week order, used to sort schedule entries. The English names
+// are for schedules translated with lang=en.
+var dayOrder = map[string]int{
+	"Senin": 0, "Selasa": 1, "Rabu": 2, "Kamis": 3, "Jumat": 4, "Sabtu": 5, "Minggu": 6,
+	"Monday": 0, "Tuesday": 1, "Wednesday": 2, "Thursday": 3, "Friday": 4, "Saturday": 5, "Sunday": 6,
 }
 
-func parseSchedules(cell *goquery.Selection) []ScheduleEntry {
-	var schedules []ScheduleEntry
-	seen := make(map[string]bool)
-
-	cell.Find("li").Each(func(_ int, li *goquery.Selection) {
-		text := collapseWhitespace(li.Text())
-		if text == "" || strings.Contains(text, "Tampilkan semua") {
-			return
-		}
-
-		parts := strings.Split(text, "/")
-		if len(parts) < 6 {
-			return
-		}
-
-		entry := ScheduleEntry{
-			Day:      strings.TrimSpace(parts[0]),
-			Time:     strings.TrimSpace(parts[2]),
-			Room:     strings.TrimSpace(parts[3]),
-			Activity: strings.TrimSpace(parts[4]),
-			Method:   strings.TrimSpace(parts[5]),
-		}
-
-		key := entry.Day + "|" + entry.Time + "|" + entry.Room + "|" + entry.Activity + "|" + entry.Method
-		if !seen[key] {
-			schedules = append(schedules, entry)
-			seen[key] = true
-		}
+// Sorts classes by code then class number, and each class's schedules by day
+// then time, so responses do not depend on upstream row order.
+func sortClasses(classes []CourseClass) {
+	for i := range classes {
+		slices.SortStableFunc(classes[i].Schedules, compareSchedules)
+	}
+	slices.SortStableFunc(classes, func(a, b CourseClass) int {
+		return cmp.Or(cmp.Compare(a.Code, b.Code), cmp.Compare(a.ClassNo, b.ClassNo))
 	})
+}
 
-	return schedules
+// Orders by day of week (unknown days last), then time, room, and activity.
+func compareSchedules(a, b ScheduleEntry) int {
+	return cmp.Or(
+		cmp.Compare(dayRank(a.Day), dayRank(b.Day)),
+		cmp.Compare(a.Day, b.Day),
+		cmp.Compare(a.Time, b.Time),
+		cmp.Compare(a.Room, b.Room),
+		cmp.Compare(a.Activity, b.Activity),
+	)
 }
 
-// Trims and collapses all runs of whitespace into a single space.
-func collapseWhitespace(s string) string {
-	return strings.TrimSpace(whitespaceRe.ReplaceAllString(s, " "))
+func dayRank(day string) int {
+	if rank, ok := dayOrder[day]; ok {
+		return rank
+	}
+	return len(dayOrder)
 }
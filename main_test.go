@@ -13,32 +13,13 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
-func TestCollapseWhitespace(t *testing.T) {
-	tests := []struct {
-		input, want string
-	}{
-		{"hello world", "hello world"},
-		{"  hello   world  ", "hello world"},
-		{"line1\nline2\n\nline3", "line1 line2 line3"},
-		{"\t  tabs\tand  spaces  \n", "tabs and spaces"},
-		{"", ""},
-		{"   ", ""},
-		{"single", "single"},
-	}
-	for _, tt := range tests {
-		if got := collapseWhitespace(tt.input); got != tt.want {
-			t.Errorf("collapseWhitespace(%q) = %q, want %q", tt.input, got, tt.want)
-		}
-	}
-}
-
-func TestBuildScheduleURL(t *testing.T) {
+func TestSchedulePath(t *testing.T) {
 	t.Run("base only", func(t *testing.T) {
 		q := url.Values{}
 		q.Set("student_id", "10245001")
 		q.Set("semester", "1945-1")
-		got := buildScheduleURL("10245001", "1945-1", q)
-		want := sixBaseURL + "/app/mahasiswa:10245001+1945-1/kelas/jadwal/kuliah"
+		got := schedulePath("10245001", "1945-1", q)
+		want := "/app/mahasiswa:10245001+1945-1/kelas/jadwal/kuliah"
 		if got != want {
 			t.Errorf("got %q, want %q", got, want)
 		}
@@ -48,7 +29,7 @@ func TestBuildScheduleURL(t *testing.T) {
 		q := url.Values{}
 		q.Set("fakultas", "FMIPA")
 		q.Set("prodi", "102")
-		got := buildScheduleURL("10245001", "1945-1", q)
+		got := schedulePath("10245001", "1945-1", q)
 		if !strings.Contains(got, "fakultas=FMIPA") {
 			t.Errorf("expected fakultas param in %q", got)
 		}
@@ -60,7 +41,7 @@ func TestBuildScheduleURL(t *testing.T) {
 	t.Run("ignores unknown params", func(t *testing.T) {
 		q := url.Values{}
 		q.Set("unknown", "value")
-		got := buildScheduleURL("10245001", "1945-1", q)
+		got := schedulePath("10245001", "1945-1", q)
 		if strings.Contains(got, "unknown") {
 			t.Errorf("unexpected param in %q", got)
 		}
@@ -257,66 +238,18 @@ func TestParseClasses_SkipsEmptyCode(t *testing.T) {
 	}
 }
 
-func TestParseSchedules_Deduplication(t *testing.T) {
-	html := `<ul>
-		<li>Senin / 1945-01-06 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
-		<li>Senin / 1945-01-13 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
-	</ul>`
-	doc := docFromHTML(html)
-	sel := doc.Find("ul")
-	schedules := parseSchedules(sel)
-	if len(schedules) != 1 {
-		t.Errorf("expected 1 deduplicated schedule, got %d", len(schedules))
-	}
-}
-
-func TestParseSchedules_SkipsTampilkanSemua(t *testing.T) {
-	html := `<ul>
-		<li>Senin / 1945-01-06 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
-		<li>Tampilkan semua jadwal</li>
-	</ul>`
-	doc := docFromHTML(html)
-	schedules := parseSchedules(doc.Find("ul"))
-	if len(schedules) != 1 {
-		t.Errorf("expected 1 schedule (Tampilkan semua skipped), got %d", len(schedules))
-	}
-}
-
-func TestParseSchedules_SkipsInvalidFormat(t *testing.T) {
-	html := `<ul>
-		<li>invalid text without slashes</li>
-		<li>only/three/parts</li>
-	</ul>`
-	doc := docFromHTML(html)
-	schedules := parseSchedules(doc.Find("ul"))
-	if len(schedules) != 0 {
-		t.Errorf("expected 0 schedules for invalid format, got %d", len(schedules))
-	}
-}
-
-func TestParseLecturers_Empty(t *testing.T) {
-	html := `<div><ul></ul></div>`
-	doc := docFromHTML(html)
-	lecturers := parseLecturers(doc.Find("div"))
-	if len(lecturers) != 0 {
-		t.Errorf("expected 0 lecturers, got %d", len(lecturers))
-	}
-}
-
-func clearCache() {
-	cacheMu.Lock()
-	defer cacheMu.Unlock()
-	scheduleCache = make(map[string]cacheEntry)
+func newTestServer(base string) *Server {
+	return NewServer(Config{BaseURL: base})
 }
 
 func TestCache_SetAndGet(t *testing.T) {
-	clearCache()
+	srv := newTestServer("")
 	data := []CourseClass{{Code: "FI1210", Name: "Test"}}
 	now := time.Now()
 
-	setCache("key1", data, now)
+	srv.cache.set("key1", data, now)
 
-	entry, ok := getCached("key1")
+	entry, ok := srv.cache.get("key1")
 	if !ok {
 		t.Fatal("expected cache hit")
 	}
@@ -329,25 +262,49 @@ func TestCache_SetAndGet(t *testing.T) {
 }
 
 func TestCache_Miss(t *testing.T) {
-	clearCache()
-	_, ok := getCached("nonexistent")
+	srv := newTestServer("")
+	_, ok := srv.cache.get("nonexistent")
 	if ok {
 		t.Error("expected cache miss")
 	}
 }
 
+func TestJitterTTL(t *testing.T) {
+	ttl := 10 * time.Minute
+	for r, want := range map[float64]time.Duration{0: 8 * time.Minute, 0.5: ttl, 1: 12 * time.Minute} {
+		if got := jitterTTL(ttl, 0.2, r); got != want {
+			t.Errorf("jitterTTL(r=%v) = %v, want %v", r, got, want)
+		}
+	}
+	if got := jitterTTL(ttl, 0, 0.9); got != ttl {
+		t.Errorf("no jitter: got %v", got)
+	}
+}
+
+func TestCache_SpreadsExpiry(t *testing.T) {
+	defer func(j float64) { cacheJitter = j }(cacheJitter)
+	cacheJitter = 0.2
+	c := newScheduleCache(100 * time.Minute)
+	for i := range 200 {
+		c.set(fmt.Sprint(i), nil, time.Now())
+	}
+	// 200 entries over a 40 minute spread average 5 per minute; without
+	// jitter all 200 would expire in the same minute.
+	if peak := c.expiryPeak(time.Now()); peak > 30 {
+		t.Errorf("expiry peak = %d per minute, want the entries spread out", peak)
+	}
+}
+
 func TestCache_Expiry(t *testing.T) {
-	clearCache()
+	srv := newTestServer("")
 
 	// Manually insert an expired entry
-	cacheMu.Lock()
-	scheduleCache["expired"] = cacheEntry{
+	srv.cache.entries["expired"] = cacheEntry{
 		data:      []CourseClass{{Code: "OLD"}},
 		expiresAt: time.Now().Add(-1 * time.Second),
 	}
-	cacheMu.Unlock()
 
-	_, ok := getCached("expired")
+	_, ok := srv.cache.get("expired")
 	if ok {
 		t.Error("expected cache miss for expired entry")
 	}
@@ -389,14 +346,15 @@ func TestScheduleHandler_MissingParams(t *testing.T) {
 		{"missing semester", "?student_id=123"},
 		{"missing student_id", "?semester=1945-1"},
 	}
+	srv := newTestServer("")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest("GET", "/api/schedule"+tt.query, nil)
 			addAuthCookies(req)
 			w := httptest.NewRecorder()
-			scheduleHandler(w, req)
-			if w.Code != http.StatusBadRequest {
-				t.Errorf("got status %d, want %d", w.Code, http.StatusBadRequest)
+			srv.ServeHTTP(w, req)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d, want %d", w.Code, http.StatusUnprocessableEntity)
 			}
 			var resp APIResponse
 			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
@@ -413,10 +371,10 @@ func TestScheduleHandler_MissingParams(t *testing.T) {
 }
 
 func TestScheduleHandler_MissingCookies(t *testing.T) {
-	clearCache()
+	srv := newTestServer("")
 	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1", nil)
 	w := httptest.NewRecorder()
-	scheduleHandler(w, req)
+	srv.scheduleHandler(w, req)
 	if w.Code != http.StatusBadGateway {
 		t.Errorf("got status %d, want %d", w.Code, http.StatusBadGateway)
 	}
@@ -433,16 +391,16 @@ func TestScheduleHandler_MissingCookies(t *testing.T) {
 }
 
 func TestScheduleHandler_CacheHit(t *testing.T) {
-	clearCache()
+	srv := newTestServer("")
 
 	cached := []CourseClass{{Code: "CACHED01", Name: "From Cache"}}
-	key := buildScheduleURL("123", "1945-1", url.Values{})
-	setCache(key, cached, time.Now())
+	key := schedulePath("123", "1945-1", url.Values{})
+	srv.cache.set(key, cached, time.Now())
 
 	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1", nil)
 	addAuthCookies(req)
 	w := httptest.NewRecorder()
-	scheduleHandler(w, req)
+	srv.scheduleHandler(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Fatalf("got status %d, want 200", w.Code)
@@ -477,11 +435,11 @@ func TestScheduleHandler_CacheHit(t *testing.T) {
 }
 
 func TestScheduleHandler_RefreshBypassesCache(t *testing.T) {
-	clearCache()
+	srv := newTestServer("")
 
 	cached := []CourseClass{{Code: "STALE", Name: "Stale Data"}}
-	key := buildScheduleURL("123", "1945-1", url.Values{})
-	setCache(key, cached, time.Now())
+	key := schedulePath("123", "1945-1", url.Values{})
+	srv.cache.set(key, cached, time.Now())
 
 	// With refresh=true, the handler should not return the cached data.
 	// It will try to fetch from upstream (which won't work without a real server),
@@ -489,7 +447,7 @@ func TestScheduleHandler_RefreshBypassesCache(t *testing.T) {
 	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&refresh=true", nil)
 	addAuthCookies(req)
 	w := httptest.NewRecorder()
-	scheduleHandler(w, req)
+	srv.scheduleHandler(w, req)
 
 	// Should not have returned 200 with stale data
 	if w.Code == http.StatusOK {
@@ -504,10 +462,38 @@ func TestScheduleHandler_RefreshBypassesCache(t *testing.T) {
 	}
 }
 
+func TestClassHandler(t *testing.T) {
+	srv := newTestServer("")
+	srv.cache.set(schedulePath("123", "1945-1", nil), []CourseClass{
+		{Code: "IF2211", ClassNo: "01"},
+		{Code: "IF2211", ClassNo: "02", Name: "Strategi Algoritma"},
+	}, time.Now())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path+"?student_id=123&semester=1945-1", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/classes/if2211/02")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	if c := decodeData[CourseClass](t, w); c.ClassNo != "02" || c.Name != "Strategi Algoritma" {
+		t.Errorf("class = %+v", c)
+	}
+	if w := get("/api/classes/IF2211/03"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown class: got status %d, want 404", w.Code)
+	}
+}
+
 func TestUserHandler_MissingCookies(t *testing.T) {
+	srv := newTestServer("")
 	req := httptest.NewRequest("GET", "/api/user", nil)
 	w := httptest.NewRecorder()
-	userHandler(w, req)
+	srv.userHandler(w, req)
 	if w.Code != http.StatusBadGateway {
 		t.Errorf("got status %d, want %d", w.Code, http.StatusBadGateway)
 	}
@@ -522,3 +508,70 @@ func TestUserHandler_MissingCookies(t *testing.T) {
 		t.Error("expected non-empty error message")
 	}
 }
+
+func TestSortClasses(t *testing.T) {
+	classes := []CourseClass{
+		{Code: "KU1102", ClassNo: "02"},
+		{Code: "FI1210", ClassNo: "02"},
+		{Code: "KU1102", ClassNo: "01"},
+		{Code: "FI1210", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Rabu", Time: "13:00-15:00"},
+			{Day: "Hari Lain", Time: "07:00-09:00"},
+			{Day: "Senin", Time: "09:00-11:00"},
+			{Day: "Senin", Time: "07:00-09:00"},
+		}},
+	}
+	sortClasses(classes)
+
+	var got []string
+	for _, c := range classes {
+		got = append(got, c.Code+"/"+c.ClassNo)
+	}
+	want := []string{"FI1210/01", "FI1210/02", "KU1102/01", "KU1102/02"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("class order = %v, want %v", got, want)
+	}
+
+	var days []string
+	for _, s := range classes[0].Schedules {
+		days = append(days, s.Day+" "+s.Time)
+	}
+	wantDays := []string{"Senin 07:00-09:00", "Senin 09:00-11:00", "Rabu 13:00-15:00", "Hari Lain 07:00-09:00"}
+	if strings.Join(days, ",") != strings.Join(wantDays, ",") {
+		t.Errorf("schedule order = %v, want %v", days, wantDays)
+	}
+}
+
+func TestParseClasses_MultipleTables(t *testing.T) {
+	row := func(code, classNo string) string {
+		return `<tr><td>1</td><td></td><td>` + code + `</td><td>Nama</td><td>3</td><td>` + classNo + `</td><td>40</td><td></td><td></td><td></td></tr>`
+	}
+	html := `<html><body>
+<div class="card"><div class="card-header"><h5>Jadwal Kuliah</h5></div>
+<div class="card-body"><table class="table"><thead><tr><th>No</th><th></th><th>Kode</th></tr></thead><tbody>` + row("IF2211", "01") + `</tbody></table></div></div>
+<div class="card"><div class="card-header"><h5>Jadwal Praktikum</h5></div>
+<div class="card-body"><table class="table"><thead><tr><th>No</th><th></th><th>Kode</th></tr></thead><tbody>` + row("IF2211", "01") + row("IF2230", "02") + `</tbody></table></div></div>
+<table class="table"><thead><tr><th>Tanggal</th><th>Pengumuman</th></tr></thead><tbody>` + row("RIWAYAT", "99") + `</tbody></table>
+</body></html>`
+
+	classes := parseClasses(docFromHTML(html))
+	if len(classes) != 3 {
+		t.Fatalf("got %d classes, want 3: %+v", len(classes), classes)
+	}
+	want := []string{"IF2211/01", "IF2211/01/praktikum", "IF2230/02/praktikum"}
+	for i, c := range classes {
+		if got := classKey(c); got != want[i] {
+			t.Errorf("class %d key = %q, want %q", i, got, want[i])
+		}
+	}
+	if classes[0].Category != "kuliah" {
+		t.Errorf("first table category = %q", classes[0].Category)
+	}
+
+	// A single table without a heading parses as before.
+	for _, c := range parseClasses(docFromHTML(testScheduleHTML)) {
+		if c.Category != "" {
+			t.Errorf("category = %q, want none", c.Category)
+		}
+	}
+}
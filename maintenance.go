@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/textnorm"
+)
+
+const defaultMaintenanceMessage = "SIX is under maintenance; only cached data is available"
+
+// While maintenance mode is on, background pollers stop and requests that
+// would reach SIX get a 503. Cached data is still served. Operators switch it
+// on during maintenance windows announced by ITB.
+type MaintenanceState struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+}
+
+var (
+	maintenanceMu sync.RWMutex
+	maintenance   = initialMaintenance()
+)
+
+func initialMaintenance() MaintenanceState {
+	if !envBool("SIX_MAINTENANCE", false) {
+		return MaintenanceState{}
+	}
+	return MaintenanceState{
+		Enabled: true,
+		Message: envString("SIX_MAINTENANCE_MESSAGE", defaultMaintenanceMessage),
+		Since:   time.Now(),
+	}
+}
+
+func currentMaintenance() MaintenanceState {
+	maintenanceMu.RLock()
+	defer maintenanceMu.RUnlock()
+	return maintenance
+}
+
+func setMaintenance(enabled bool, message string) MaintenanceState {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	if !enabled {
+		maintenance = MaintenanceState{}
+		return maintenance
+	}
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	since := maintenance.Since
+	if !maintenance.Enabled {
+		since = time.Now()
+	}
+	maintenance = MaintenanceState{Enabled: true, Message: message, Since: since}
+	return maintenance
+}
+
+// Reports whether requests may go to SIX, writing a 503 if maintenance mode is
+// on or SIX was recently seen in maintenance.
+func outsideMaintenance(w http.ResponseWriter, r *http.Request) bool {
+	if m := currentMaintenance(); m.Enabled {
+		w.Header().Set("Retry-After", strconv.Itoa(int(shedRetryAfter.Seconds())))
+		if m.Message == defaultMaintenanceMessage {
+			writeError(w, r, codeMaintenance)
+		} else {
+			// Operators write their own message; it is passed through as is.
+			writeErrorResponse(w, r, http.StatusServiceUnavailable, APIResponse{Code: codeMaintenance, Error: m.Message})
+		}
+		return false
+	}
+	if d := currentDetectedMaintenance(); d.Active && time.Now().Before(d.RetryAt) {
+		writeMaintenanceDetected(w, r, d)
+		return false
+	}
+	return true
+}
+
+func writeMaintenanceDetected(w http.ResponseWriter, r *http.Request, d DetectedMaintenance) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(time.Until(d.RetryAt).Seconds()), 1)))
+	writeError(w, r, codeUpstreamMaintenance, d.RetryAt.Format(time.RFC3339))
+}
+
+// Writes the response for a failed upstream fetch: 503 when SIX is in
+// maintenance, 502 otherwise. The raw error is logged, not sent, since it can
+// carry upstream hostnames and response text.
+func writeUpstreamError(w http.ResponseWriter, r *http.Request, err error) {
+	var missing *missingCookieError
+	switch {
+	case errors.Is(err, errUpstreamMaintenance):
+		writeMaintenanceDetected(w, r, currentDetectedMaintenance())
+	case errors.As(err, &missing):
+		writeError(w, r, codeMissingCookie, missing.name)
+	case isUpstreamTLSError(err):
+		log.Printf("upstream tls error request_id=%s err=%v (check SIX_CA_BUNDLE and SIX_TLS_PINS)", requestIDFrom(r.Context()), err)
+		writeError(w, r, codeUpstreamTLS)
+	default:
+		log.Printf("upstream error request_id=%s err=%v", requestIDFrom(r.Context()), err)
+		writeError(w, r, codeUpstream)
+	}
+}
+
+type maintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+func getMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	writeSuccess(w, currentMaintenance())
+}
+
+func putMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var body maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	state := setMaintenance(body.Enabled, body.Message)
+	log.Printf("maintenance mode enabled=%v", state.Enabled)
+	writeSuccess(w, state)
+}
+
+// Besides the manual switch, maintenance is detected automatically: when SIX
+// serves its maintenance page, requests that need SIX and background jobs
+// back off for a cool-down that doubles with every consecutive detection.
+var (
+	maintenanceMarkers     = envList("SIX_MAINTENANCE_MARKERS", []string{"maintenance", "pemeliharaan", "perbaikan sistem"})
+	maintenanceCooldown    = envDuration("SIX_MAINTENANCE_COOLDOWN", time.Minute)
+	maintenanceMaxCooldown = envDuration("SIX_MAINTENANCE_MAX_COOLDOWN", 30*time.Minute)
+)
+
+var errUpstreamMaintenance = errors.New("SIX is under maintenance")
+
+type DetectedMaintenance struct {
+	Active     bool      `json:"active"`
+	DetectedAt time.Time `json:"detected_at,omitzero"`
+	RetryAt    time.Time `json:"retry_at,omitzero"`
+	Detections int       `json:"detections,omitempty"` // consecutive, drives the cool-down
+}
+
+var detectedMaintenance DetectedMaintenance // guarded by maintenanceMu
+
+// Reports whether doc is the SIX maintenance page. Only the title and
+// headings are checked, since course names in table cells may legitimately
+// contain words like "pemeliharaan".
+func isMaintenancePage(doc *goquery.Document) bool {
+	text := doc.Find("title, h1, h2, h3").Text()
+	for _, marker := range maintenanceMarkers {
+		if textnorm.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records that SIX served its maintenance page and extends the cool-down.
+func noteUpstreamMaintenance() DetectedMaintenance {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	now := time.Now()
+	d := &detectedMaintenance
+	if !d.Active {
+		d.DetectedAt = now
+	}
+	d.Active = true
+	d.Detections++
+	cooldown := maintenanceCooldown
+	for i := 1; i < d.Detections && cooldown < maintenanceMaxCooldown; i++ {
+		cooldown *= 2
+	}
+	d.RetryAt = now.Add(min(cooldown, maintenanceMaxCooldown))
+	log.Printf("SIX maintenance detected detections=%d retry_at=%s", d.Detections, d.RetryAt.Format(time.RFC3339))
+	return *d
+}
+
+// Clears detected maintenance after SIX served a normal page.
+func noteUpstreamHealthy() {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	if detectedMaintenance.Active {
+		log.Printf("SIX maintenance over after %s", time.Since(detectedMaintenance.DetectedAt).Round(time.Second))
+	}
+	detectedMaintenance = DetectedMaintenance{}
+}
+
+func currentDetectedMaintenance() DetectedMaintenance {
+	maintenanceMu.RLock()
+	defer maintenanceMu.RUnlock()
+	return detectedMaintenance
+}
+
+// Reports whether background jobs should leave SIX alone right now, either
+// because of the manual switch or a detected maintenance cool-down.
+func scrapingPaused() bool {
+	d := currentDetectedMaintenance()
+	return currentMaintenance().Enabled || (d.Active && time.Now().Before(d.RetryAt))
+}
+
+type Status struct {
+	Maintenance         MaintenanceState    `json:"maintenance"`
+	DetectedMaintenance DetectedMaintenance `json:"detected_maintenance"`
+	ScrapingPaused      bool                `json:"scraping_paused"`
+}
+
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, Status{
+		Maintenance:         currentMaintenance(),
+		DetectedMaintenance: currentDetectedMaintenance(),
+		ScrapingPaused:      scrapingPaused(),
+	})
+}
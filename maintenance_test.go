@@ -0,0 +1,209 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withMaintenance(t *testing.T, message string) {
+	t.Helper()
+	setMaintenance(true, message)
+	t.Cleanup(func() { setMaintenance(false, "") })
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	old := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() {
+		adminToken = old
+		setMaintenance(false, "")
+	})
+
+	put := func(body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		putMaintenanceHandler(w, req)
+		return w
+	}
+
+	if w := put(`{"enabled":true}`, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: got status %d, want 401", w.Code)
+	}
+	w := put(`{"enabled":true,"message":"SIX down until 12:00"}`, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("enable: got status %d", w.Code)
+	}
+	state := decodeData[MaintenanceState](t, w)
+	if !state.Enabled || state.Message != "SIX down until 12:00" || state.Since.IsZero() {
+		t.Errorf("state = %+v", state)
+	}
+	if !currentMaintenance().Enabled {
+		t.Error("expected maintenance mode to be on")
+	}
+
+	if state := decodeData[MaintenanceState](t, put(`{"enabled":false}`, "secret")); state.Enabled {
+		t.Error("expected maintenance mode to be off")
+	}
+}
+
+func TestSetMaintenance_KeepsSince(t *testing.T) {
+	t.Cleanup(func() { setMaintenance(false, "") })
+	first := setMaintenance(true, "")
+	if first.Message != defaultMaintenanceMessage {
+		t.Errorf("message = %q, want default", first.Message)
+	}
+	time.Sleep(time.Millisecond)
+	if second := setMaintenance(true, "new message"); !second.Since.Equal(first.Since) {
+		t.Error("updating the message should not reset since")
+	}
+}
+
+func TestScheduleHandler_Maintenance(t *testing.T) {
+	srv := newTestServer("")
+	withMaintenance(t, "Maintenance until 12:00")
+	srv.cache.set(schedulePath("123", "1945-1", nil), []CourseClass{{Code: "FI1210"}}, time.Now())
+
+	get := func(semester string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester="+semester, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.scheduleHandler(w, req)
+		return w
+	}
+
+	if w := get("1945-1"); w.Code != http.StatusOK {
+		t.Errorf("cache hit: got status %d, want 200", w.Code)
+	}
+	w := get("1945-2")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("cache miss: got status %d, want 503", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Maintenance until 12:00") {
+		t.Errorf("expected maintenance message in %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/user", nil)
+	addAuthCookies(req)
+	srv.userHandler(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("user: got status %d, want 503", w.Code)
+	}
+}
+
+func TestPrefetch_StopsInMaintenance(t *testing.T) {
+	setupPrefetch(t)
+	srv := newTestServer("")
+	withMaintenance(t, "")
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&pekan=3", nil)
+	addAuthCookies(req)
+	recordPrefetchCandidate(req, schedulePath("123", "1945-1", req.URL.Query()), "123", "1945-1")
+	srv.prefetchNextPekan(t.Context())
+
+	if _, ok := srv.cache.peek(schedulePath("123", "1945-1", map[string][]string{"pekan": {"4"}})); ok {
+		t.Error("prefetch should not fetch during maintenance")
+	}
+}
+
+func resetDetectedMaintenance(t *testing.T) {
+	t.Helper()
+	noteUpstreamHealthy()
+	t.Cleanup(noteUpstreamHealthy)
+}
+
+func TestIsMaintenancePage(t *testing.T) {
+	tests := []struct {
+		html string
+		want bool
+	}{
+		{`<html><head><title>SIX - Sedang Dalam Pemeliharaan</title></head></html>`, true},
+		{`<html><body><h1>Scheduled Maintenance</h1></body></html>`, true},
+		{testScheduleHTML, false},
+		// Course names in table cells must not trigger detection.
+		{`<table class="table"><tr><td>IF4050</td><td>Pemeliharaan Perangkat Lunak</td></tr></table>`, false},
+	}
+	for _, tt := range tests {
+		if got := isMaintenancePage(docFromHTML(tt.html)); got != tt.want {
+			t.Errorf("isMaintenancePage(%.50q) = %v, want %v", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestNoteUpstreamMaintenance_CooldownDoubles(t *testing.T) {
+	resetDetectedMaintenance(t)
+	oldBase, oldMax := maintenanceCooldown, maintenanceMaxCooldown
+	maintenanceCooldown, maintenanceMaxCooldown = time.Minute, 3*time.Minute
+	t.Cleanup(func() { maintenanceCooldown, maintenanceMaxCooldown = oldBase, oldMax })
+
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		d := noteUpstreamMaintenance()
+		if got := time.Until(d.RetryAt).Round(time.Minute); got != want {
+			t.Errorf("detection %d: cool-down %s, want %s", i+1, got, want)
+		}
+	}
+	if !scrapingPaused() {
+		t.Error("expected scraping to be paused during the cool-down")
+	}
+
+	noteUpstreamHealthy()
+	if d := currentDetectedMaintenance(); d.Active || d.Detections != 0 {
+		t.Errorf("expected detection to reset, got %+v", d)
+	}
+}
+
+func TestScheduleHandler_DetectsMaintenance(t *testing.T) {
+	resetDetectedMaintenance(t)
+
+	hits := 0
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte(`<html><head><title>Maintenance</title></head><body>SIX sedang dalam pemeliharaan</body></html>`))
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.scheduleHandler(w, req)
+		return w
+	}
+
+	w := get()
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("got status %d (Retry-After %q), want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := get(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("during cool-down: got status %d, want 503", w.Code)
+	}
+	if hits != 1 {
+		t.Errorf("SIX was hit %d times, want 1 (no requests during cool-down)", hits)
+	}
+
+	status := httptest.NewRecorder()
+	statusHandler(status, httptest.NewRequest("GET", "/api/status", nil))
+	if s := decodeData[Status](t, status); !s.DetectedMaintenance.Active || !s.ScrapingPaused {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestFetchDoc_Upstream503IsMaintenance(t *testing.T) {
+	resetDetectedMaintenance(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	addAuthCookies(req)
+	_, _, err := fetchDoc(newSIXProvider("", nil).client(), srv.URL, req)
+	if err != errUpstreamMaintenance {
+		t.Errorf("err = %v, want errUpstreamMaintenance", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Middleware applied to every request, including unmatched paths, in order
+// from outermost to innermost.
+func (s *Server) middleware() []middleware {
+	return []middleware{withRequestID, logRequest, s.metrics.record, s.audit.record, withResponseStyle, recoverPanics}
+}
+
+const requestIDHeader = "X-Request-ID"
+
+// Inbound request IDs are kept only if they look like IDs, so arbitrary
+// header content never reaches the logs.
+var requestIDRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDCtxKey struct{}
+
+// Tags the request with the caller's X-Request-ID, or a new one, and echoes it
+// in the response.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDRe.MatchString(id) {
+			id = randomHex(8)
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id)))
+	})
+}
+
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// Wraps a handler and logs method, path, status, and total duration.
+func logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		log.Printf("%s %s status=%d duration=%s request_id=%s", r.Method, r.URL.String(), sw.status, time.Since(start), requestIDFrom(r.Context()))
+	})
+}
+
+// Turns a panic in a handler into a logged stack trace and a 500 JSON
+// response carrying the request ID. If the handler already started the
+// response, the status can no longer change and the response is cut short.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			id := requestIDFrom(r.Context())
+			log.Printf("panic %s %s request_id=%s: %v\n%s", r.Method, r.URL.Path, id, rec, debug.Stack())
+			if sw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			writeInternalError(sw, r)
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wroteHeader {
+		sw.status = code
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter { return sw.ResponseWriter }
+
+// Request counts, statuses, and latencies per route pattern.
+type requestMetrics struct {
+	mu     sync.Mutex
+	routes map[string]*RouteStats
+}
+
+type RouteStats struct {
+	Requests int64            `json:"requests"`
+	Statuses map[string]int64 `json:"statuses"`
+	MeanMS   float64          `json:"mean_ms"`
+	MaxMS    float64          `json:"max_ms"`
+	totalMS  float64
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{routes: make(map[string]*RouteStats)}
+}
+
+func (m *requestMetrics) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		// The mux sets r.Pattern on the request it was handed, which is r.
+		m.observe(r.Pattern, sw.status, time.Since(start))
+	})
+}
+
+func (m *requestMetrics) observe(pattern string, status int, d time.Duration) {
+	if pattern == "" {
+		pattern = "unmatched"
+	}
+	ms := float64(d.Microseconds()) / 1000
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.routes[pattern]
+	if !ok {
+		st = &RouteStats{Statuses: make(map[string]int64)}
+		m.routes[pattern] = st
+	}
+	st.Requests++
+	st.Statuses[strconv.Itoa(status)]++
+	st.totalMS += ms
+	st.MeanMS = st.totalMS / float64(st.Requests)
+	st.MaxMS = max(st.MaxMS, ms)
+}
+
+// Returns a copy of the stats keyed by route pattern.
+func (m *requestMetrics) snapshot() map[string]RouteStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]RouteStats, len(m.routes))
+	for pattern, st := range m.routes {
+		c := *st
+		c.Statuses = make(map[string]int64, len(st.Statuses))
+		for k, v := range st.Statuses {
+			c.Statuses[k] = v
+		}
+		out[pattern] = c
+	}
+	return out
+}
+
+// Returns per-route request metrics. Requires the admin token.
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	writeSuccess(w, s.metrics.snapshot())
+}
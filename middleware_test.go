@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	srv := newTestServer("")
+	srv.mux.HandleFunc("GET /boom", func(http.ResponseWriter, *http.Request) { panic("boom") })
+
+	req := httptest.NewRequest("GET", "/boom", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500", w.Code)
+	}
+	var resp APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if resp.Success || resp.RequestID != "abc-123" {
+		t.Errorf("response = %+v", resp)
+	}
+	if got := w.Header().Get(requestIDHeader); got != "abc-123" {
+		t.Errorf("%s = %q, want abc-123", requestIDHeader, got)
+	}
+
+	stats := srv.metrics.snapshot()["GET /boom"]
+	if stats.Requests != 1 || stats.Statuses["500"] != 1 {
+		t.Errorf("metrics = %+v", stats)
+	}
+}
+
+func TestWithRequestID_ReplacesInvalid(t *testing.T) {
+	srv := newTestServer("")
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	req.Header.Set(requestIDHeader, "bad id\nwith newline")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if got := w.Header().Get(requestIDHeader); !requestIDRe.MatchString(got) || got == req.Header.Get(requestIDHeader) {
+		t.Errorf("%s = %q, want a generated ID", requestIDHeader, got)
+	}
+}
+
+func TestMetricsHandler_RequiresAdmin(t *testing.T) {
+	old := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = old })
+	srv := newTestServer("")
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: got status %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/admin/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if stats := decodeData[map[string]RouteStats](t, w); stats["GET /readyz"].Requests != 1 {
+		t.Errorf("metrics = %+v", stats)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// For load testing, an admin can mirror a sampled fraction of upstream
+// fetches to a staging server that mocks SIX. After the real fetch, the same
+// path and query are requested from the mock without credentials, and the
+// page it returns goes through the same parser, so the staging pipeline sees
+// traffic shaped like production. Mirrored requests never go to SIX: targets
+// on an upstream host are refused, and redirects are not followed.
+var (
+	mirrorConcurrency = envInt("SIX_MIRROR_CONCURRENCY", 8)
+	mirrorTimeout     = envDuration("SIX_MIRROR_TIMEOUT", 30*time.Second)
+)
+
+const maxMirrorResponse = 16 << 20
+
+type MirrorStatus struct {
+	Target   string  `json:"target,omitempty"`
+	Fraction float64 `json:"fraction"`
+	Mirrored int64   `json:"mirrored"` // requests sent to the target
+	Failed   int64   `json:"failed"`   // requests that errored or got a non-2xx status
+	Dropped  int64   `json:"dropped"`  // sampled but skipped because SIX_MIRROR_CONCURRENCY were in flight
+	Parsed   int64   `json:"parsed"`   // mirrored pages run through a parser
+	MeanMS   float64 `json:"mean_ms"`  // mean time of a mirrored request
+}
+
+type upstreamMirror struct {
+	mu       sync.Mutex
+	target   *url.URL // nil when mirroring is off
+	fraction float64
+	inFlight int
+	limit    int
+	st       MirrorStatus
+	totalMS  float64
+	client   *http.Client
+	wg       sync.WaitGroup // mirrored requests in flight, for tests
+}
+
+func newUpstreamMirror(limit int) *upstreamMirror {
+	return &upstreamMirror{
+		limit: max(limit, 1),
+		client: &http.Client{
+			Timeout: mirrorTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				// A mock that redirects to SIX must not make us fetch SIX.
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Turns mirroring to target on for fraction of fetches, or off if target is
+// nil or fraction is 0. Counters restart.
+func (m *upstreamMirror) set(target *url.URL, fraction float64) MirrorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if target == nil || fraction <= 0 {
+		target, fraction = nil, 0
+	}
+	m.target, m.fraction = target, fraction
+	m.st, m.totalMS = MirrorStatus{Fraction: fraction}, 0
+	if target != nil {
+		m.st.Target = target.String()
+	}
+	return m.st
+}
+
+func (m *upstreamMirror) status() MirrorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.st
+}
+
+// Mirrors req with probability fraction, in the background.
+func (m *upstreamMirror) maybeMirror(req *http.Request) {
+	m.mu.Lock()
+	if m.target == nil || rand.Float64() >= m.fraction {
+		m.mu.Unlock()
+		return
+	}
+	if m.inFlight >= m.limit {
+		m.st.Dropped++
+		m.mu.Unlock()
+		return
+	}
+	m.inFlight++
+	u := *m.target
+	u.Path = strings.TrimSuffix(u.Path, "/") + req.URL.Path
+	u.RawQuery = req.URL.RawQuery
+	m.mu.Unlock()
+
+	header := sanitizeHeaders(req.Header)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		start := time.Now()
+		parsed, err := m.send(u.String(), header)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inFlight--
+		if m.target == nil || m.target.Host != u.Host {
+			return // mirroring was switched since
+		}
+		m.st.Mirrored++
+		m.totalMS += float64(time.Since(start)) / float64(time.Millisecond)
+		m.st.MeanMS = m.totalMS / float64(m.st.Mirrored)
+		if err != nil {
+			m.st.Failed++
+		}
+		if parsed {
+			m.st.Parsed++
+		}
+	}()
+}
+
+// Fetches target and parses the page like the SIX page at the same path.
+// Reports whether a parser ran.
+func (m *upstreamMirror) send(target string, header http.Header) (bool, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return false, err
+	}
+	if header != nil {
+		req.Header = header
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		log.Printf("mirror failed url=%s err=%v", target, err)
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, &mirrorStatusError{resp.StatusCode}
+	}
+	parse := mirrorParser(req.URL.Path)
+	if parse == nil {
+		_, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxMirrorResponse))
+		return false, err
+	}
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxMirrorResponse))
+	if err != nil {
+		return false, err
+	}
+	parse(doc)
+	return true, nil
+}
+
+type mirrorStatusError struct{ status int }
+
+func (e *mirrorStatusError) Error() string {
+	return "mirror target returned " + http.StatusText(e.status)
+}
+
+// Returns the parser for the SIX page at path, or nil if it has none.
+func mirrorParser(path string) func(*goquery.Document) {
+	switch {
+	case strings.HasSuffix(path, "/kelas/jadwal/kuliah"):
+		return func(doc *goquery.Document) { parseClasses(doc) }
+	case strings.HasSuffix(path, "/akademik/transkrip"):
+		return func(doc *goquery.Document) { parseTranscript(doc) }
+	case strings.HasSuffix(path, "/akademik/kurikulum"):
+		return func(doc *goquery.Document) { parseCurriculum(doc) }
+	case strings.HasSuffix(path, "/akademik/nilai"):
+		return func(doc *goquery.Document) { parseGrades(doc) }
+	case strings.HasSuffix(path, "/kelas/jadwal/ujian"):
+		return func(doc *goquery.Document) { parseExams(doc) }
+	case strings.HasSuffix(path, "/registrasi/frs"):
+		return func(doc *goquery.Document) { parseFRS(doc) }
+	case strings.HasSuffix(path, "/profil"):
+		return func(doc *goquery.Document) { parseProfile(doc) }
+	case strings.HasSuffix(path, calendarPath):
+		return func(doc *goquery.Document) { parseCalendar(doc) }
+	case strings.HasSuffix(path, "/keuangan/pembayaran"):
+		return func(doc *goquery.Document) { parsePayments(doc) }
+	}
+	return nil
+}
+
+// Sends each request on to next, then offers it to mirror.
+type mirrorTransport struct {
+	next   http.RoundTripper
+	mirror *upstreamMirror
+}
+
+func (t *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	t.mirror.maybeMirror(req)
+	return resp, err
+}
+
+type mirrorRequest struct {
+	Target   string  `json:"target"`
+	Fraction float64 `json:"fraction"`
+}
+
+// GET /api/admin/mirror
+func (s *Server) getMirrorHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	writeSuccess(w, s.mirror.status())
+}
+
+// PUT /api/admin/mirror
+//
+// Starts mirroring to target, or stops it with an empty target or a fraction
+// of 0.
+func (s *Server) putMirrorHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var body mirrorRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if body.Fraction < 0 || body.Fraction > 1 {
+		writeError(w, r, codeInvalidRequest, "fraction must be between 0 and 1")
+		return
+	}
+	var target *url.URL
+	if body.Target != "" {
+		u, err := url.Parse(body.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || s.isUpstreamHost(u.Host) {
+			writeError(w, r, codeInvalidMirrorTarget)
+			return
+		}
+		target = u
+	}
+	st := s.mirror.set(target, body.Fraction)
+	log.Printf("upstream mirroring target=%q fraction=%g", st.Target, st.Fraction)
+	writeSuccess(w, st)
+}
+
+// Reports whether host is one the server fetches student data from.
+func (s *Server) isUpstreamHost(host string) bool {
+	for _, origin := range []string{s.cfg.BaseURL, s.cfg.APIURL} {
+		if u, err := url.Parse(origin); err == nil && u.Host != "" && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
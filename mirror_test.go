@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMirror_CopiesFetchesToTarget(t *testing.T) {
+	old := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = old })
+
+	var sixHits, mockHits atomic.Int32
+	six := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sixHits.Add(1)
+		w.Write([]byte(testScheduleHTML))
+	}))
+	defer six.Close()
+	var mockPath, mockCookie atomic.Value
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mockHits.Add(1)
+		mockPath.Store(r.URL.RequestURI())
+		mockCookie.Store(r.Header.Get("Cookie"))
+		w.Write([]byte(testScheduleHTML))
+	}))
+	defer mock.Close()
+
+	srv := newTestServer(six.URL)
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/admin/mirror", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	if w := put(`{"target":"` + six.URL + `","fraction":1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("mirroring to SIX: status %d, want 400", w.Code)
+	}
+	if w := put(`{"target":"` + mock.URL + `","fraction":1.5}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("fraction 1.5: status %d, want 422", w.Code)
+	}
+	if w := put(`{"target":"` + mock.URL + `","fraction":1}`); w.Code != http.StatusOK {
+		t.Fatalf("enable: status %d: %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2024-2&prodi=135", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("schedule: status %d", w.Code)
+	}
+	srv.mirror.wg.Wait()
+
+	if sixHits.Load() != 1 || mockHits.Load() != 1 {
+		t.Errorf("six hits = %d, mock hits = %d, want 1 and 1", sixHits.Load(), mockHits.Load())
+	}
+	if got := mockPath.Load(); got != schedulePath("13520001", "2024-2", map[string][]string{"prodi": {"135"}}) {
+		t.Errorf("mirrored path = %v", got)
+	}
+	if got := mockCookie.Load(); got != "" {
+		t.Errorf("mirrored request carried cookies %q", got)
+	}
+	if st := srv.mirror.status(); st.Mirrored != 1 || st.Parsed != 1 || st.Failed != 0 {
+		t.Errorf("status = %+v", st)
+	}
+
+	put(`{"fraction":0}`)
+	req = httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2024-2&refresh=true", nil)
+	addAuthCookies(req)
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	srv.mirror.wg.Wait()
+	if mockHits.Load() != 1 {
+		t.Errorf("mirrored after turning mirroring off")
+	}
+}
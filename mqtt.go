@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The MQTT publisher keeps retained next-class and today's-schedule topics
+// up to date for the students in SIX_MQTT_STUDENTS, for Home Assistant and
+// similar automations. It publishes from the last good snapshot of each
+// student's current semester, so it never fetches from SIX itself; the
+// snapshot is refreshed whenever the schedule is fetched through the API.
+var (
+	mqttBroker      = envString("SIX_MQTT_BROKER", "")
+	mqttUsername    = envString("SIX_MQTT_USERNAME", "")
+	mqttPassword    = envString("SIX_MQTT_PASSWORD", "")
+	mqttTopicPrefix = envString("SIX_MQTT_TOPIC_PREFIX", "six")
+	mqttStudents    = envList("SIX_MQTT_STUDENTS", nil)
+	mqttInterval    = envDuration("SIX_MQTT_INTERVAL", time.Minute)
+	mqttDiscovery   = envBool("SIX_MQTT_DISCOVERY", true)
+)
+
+// Prefix under which Home Assistant looks for MQTT discovery messages.
+const mqttDiscoveryPrefix = "homeassistant"
+
+// Payload of {prefix}/{student_id}/next_class. Class is nil when there is
+// no class in the coming week.
+type MQTTNextClass struct {
+	Class     *UpcomingSession `json:"class"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// Payload of {prefix}/{student_id}/today.
+type MQTTToday struct {
+	Date      string            `json:"date"`
+	Text      string            `json:"text"`
+	Count     int               `json:"count"`
+	Sessions  []UpcomingSession `json:"sessions"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Remembers what was last published to each topic, so that unchanged
+// payloads are not sent again.
+type mqttPublisher struct {
+	mu   sync.Mutex
+	last map[string]string
+}
+
+func newMQTTPublisher() *mqttPublisher {
+	return &mqttPublisher{last: make(map[string]string)}
+}
+
+// Runs publishMQTT every mqttInterval until ctx is done.
+func (s *Server) runMQTTPublisher(ctx context.Context) {
+	ticker := time.NewTicker(mqttInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.publishMQTT(ctx, time.Now()); err != nil {
+			log.Printf("mqtt publish failed broker=%s err=%v", mqttBroker, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publishes the topics whose payload changed since the last successful
+// publish. It connects to the broker only when there is something to send.
+func (s *Server) publishMQTT(ctx context.Context, now time.Time) error {
+	messages := s.mqttMessages(now)
+	s.mqtt.mu.Lock()
+	var changed []mqttMessage
+	for _, m := range messages {
+		if s.mqtt.last[m.topic] != string(m.payload) {
+			changed = append(changed, m)
+		}
+	}
+	s.mqtt.mu.Unlock()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	conn, err := dialMQTT(ctx, mqttBroker, "six-scraper-go-"+randomHex(4), mqttUsername, mqttPassword)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for _, m := range changed {
+		if err := conn.publish(m.topic, m.payload, true); err != nil {
+			return err
+		}
+		s.mqtt.mu.Lock()
+		s.mqtt.last[m.topic] = string(m.payload)
+		s.mqtt.mu.Unlock()
+	}
+	return nil
+}
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// Returns the retained messages for every configured student with a
+// snapshot of their current semester, preceded by Home Assistant discovery
+// messages if enabled.
+func (s *Server) mqttMessages(now time.Time) []mqttMessage {
+	var messages []mqttMessage
+	add := func(topic string, v any) {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			log.Printf("mqtt encode failed topic=%s err=%v", topic, err)
+			return
+		}
+		messages = append(messages, mqttMessage{topic, payload})
+	}
+
+	for _, studentID := range mqttStudents {
+		semester, _ := s.semesters.resolve(studentID, "current", now)
+		snap, ok := s.lastGood.get(schedulePath(studentID, semester, nil))
+		if !ok {
+			continue
+		}
+		base := mqttTopicPrefix + "/" + studentID
+		if mqttDiscovery {
+			for _, sensor := range []struct{ key, name, value string }{
+				{"next_class", "Next class", "{{ value_json.class.code if value_json.class else 'none' }}"},
+				{"today", "Classes today", "{{ value_json.count }}"},
+			} {
+				add(fmt.Sprintf("%s/sensor/six_%s_%s/config", mqttDiscoveryPrefix, studentID, sensor.key), map[string]any{
+					"name":                  sensor.name,
+					"unique_id":             "six_" + studentID + "_" + sensor.key,
+					"state_topic":           base + "/" + sensor.key,
+					"value_template":        sensor.value,
+					"json_attributes_topic": base + "/" + sensor.key,
+					"device":                map[string]any{"identifiers": []string{"six_" + studentID}, "name": "SIX " + studentID},
+				})
+			}
+		}
+
+		grid := scheduleGrid(snap.Classes, s.buildings)
+		next := MQTTNextClass{UpdatedAt: snap.FetchedAt}
+		if upcoming := upcomingSessions(grid, now, 1); len(upcoming) > 0 {
+			next.Class = &upcoming[0]
+		}
+		add(base+"/next_class", next)
+
+		today := wibDay(now)
+		summary := scheduleText(snap.Classes, "id", "today", today, today)
+		sessions := sessionsOn(grid, today)
+		add(base+"/today", MQTTToday{Date: summary.Date, Text: summary.Text, Count: len(sessions), Sessions: sessions, UpdatedAt: snap.FetchedAt})
+	}
+	return messages
+}
+
+// A publish-only MQTT 3.1.1 connection with QoS 0.
+type mqttConn struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// Connects to broker, given as host:port with an optional tcp:// or mqtt://
+// scheme, and waits for the broker to accept the connection.
+func dialMQTT(ctx context.Context, broker, clientID, username, password string) (*mqttConn, error) {
+	addr := strings.TrimPrefix(strings.TrimPrefix(broker, "tcp://"), "mqtt://")
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	flags := byte(0x02) // clean session
+	var payload []byte
+	payload = appendMQTTString(payload, clientID)
+	if username != "" {
+		flags |= 0x80
+		payload = appendMQTTString(payload, username)
+		if password != "" {
+			flags |= 0x40
+			payload = appendMQTTString(payload, password)
+		}
+	}
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags, 0, 60) // protocol level 4, 60 s keepalive
+	body = append(body, payload...)
+
+	c := &mqttConn{conn: conn, w: bufio.NewWriter(conn)}
+	if err := c.send(0x10, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading CONNACK: %w", err)
+	}
+	if ack[0] != 0x20 || ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("broker refused the connection with code %d", ack[3])
+	}
+	return c, nil
+}
+
+func (c *mqttConn) publish(topic string, payload []byte, retain bool) error {
+	header := byte(0x30)
+	if retain {
+		header |= 0x01
+	}
+	return c.send(header, append(appendMQTTString(nil, topic), payload...))
+}
+
+// Sends DISCONNECT and closes the connection.
+func (c *mqttConn) Close() error {
+	err := c.send(0xe0, nil)
+	return errors.Join(err, c.conn.Close())
+}
+
+func (c *mqttConn) send(header byte, body []byte) error {
+	c.w.WriteByte(header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		c.w.WriteByte(b)
+		if n == 0 {
+			break
+		}
+	}
+	c.w.Write(body)
+	return c.w.Flush()
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	return append(append(b, byte(len(s)>>8), byte(len(s))), s...)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mqttPublish struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// Accepts MQTT connections, acknowledges CONNECT, and reports each
+// connection's client ID and publishes.
+func fakeMQTTBroker(t *testing.T) (addr string, conns chan string, published chan mqttPublish) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	conns, published = make(chan string, 10), make(chan mqttPublish, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					header, err := r.ReadByte()
+					if err != nil {
+						return
+					}
+					n, mult := 0, 1
+					for {
+						b, _ := r.ReadByte()
+						n += int(b&0x7f) * mult
+						mult *= 128
+						if b&0x80 == 0 {
+							break
+						}
+					}
+					body := make([]byte, n)
+					io.ReadFull(r, body)
+					str := func(b []byte) (string, []byte) {
+						l := int(b[0])<<8 | int(b[1])
+						return string(b[2 : 2+l]), b[2+l:]
+					}
+					switch header & 0xf0 {
+					case 0x10:
+						clientID, _ := str(body[10:])
+						conns <- clientID
+						conn.Write([]byte{0x20, 2, 0, 0})
+					case 0x30:
+						topic, rest := str(body)
+						published <- mqttPublish{topic, string(rest), header&0x01 != 0}
+					case 0xe0:
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), conns, published
+}
+
+func setupMQTT(t *testing.T, broker string, students ...string) {
+	t.Helper()
+	oldBroker, oldStudents, oldDiscovery := mqttBroker, mqttStudents, mqttDiscovery
+	mqttBroker, mqttStudents, mqttDiscovery = "tcp://"+broker, students, true
+	t.Cleanup(func() { mqttBroker, mqttStudents, mqttDiscovery = oldBroker, oldStudents, oldDiscovery })
+}
+
+func TestPublishMQTT(t *testing.T) {
+	addr, conns, published := fakeMQTTBroker(t)
+	setupMQTT(t, addr, "13520001", "13520002")
+	srv := newTestServer("")
+	now := time.Date(2026, 10, 12, 8, 0, 0, 0, wib) // a Monday
+	semester, _ := srv.semesters.resolve("13520001", "current", now)
+	srv.lastGood.set(Snapshot{
+		Key:       schedulePath("13520001", semester, nil),
+		StudentID: "13520001",
+		Semester:  semester,
+		Classes:   parseClasses(docFromHTML(testScheduleHTML)),
+		FetchedAt: now.Add(-time.Hour),
+	})
+
+	if err := srv.publishMQTT(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-conns; !strings.HasPrefix(id, "six-scraper-go-") {
+		t.Errorf("client ID = %q", id)
+	}
+	got := make(map[string]mqttPublish)
+	for range 4 {
+		select {
+		case p := <-published:
+			got[p.topic] = p
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only got %d publishes", len(got))
+		}
+	}
+
+	// The student without a snapshot is skipped.
+	for _, topic := range []string{"homeassistant/sensor/six_13520001_next_class/config", "homeassistant/sensor/six_13520001_today/config", "six/13520001/next_class", "six/13520001/today"} {
+		if p, ok := got[topic]; !ok || !p.retain {
+			t.Errorf("%s: %+v, want a retained publish", topic, p)
+		}
+	}
+	var next MQTTNextClass
+	if err := json.Unmarshal([]byte(got["six/13520001/next_class"].payload), &next); err != nil {
+		t.Fatal(err)
+	}
+	if next.Class == nil || next.Class.Code != "FI1210" || next.Class.Start != "07:00" || !next.Class.StartsAt.Equal(time.Date(2026, 10, 12, 7, 0, 0, 0, wib)) {
+		t.Errorf("next class = %+v", next.Class)
+	}
+	var today MQTTToday
+	if err := json.Unmarshal([]byte(got["six/13520001/today"].payload), &today); err != nil {
+		t.Fatal(err)
+	}
+	if today.Date != "2026-10-12" || today.Count != 1 || len(today.Sessions) != 1 || today.Text == "" {
+		t.Errorf("today = %+v", today)
+	}
+
+	// Nothing changed, so the broker is not contacted again.
+	if err := srv.publishMQTT(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-conns:
+		t.Errorf("reconnected as %s with nothing to publish", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPublishMQTT_BrokerRefuses(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.ReadFull(conn, make([]byte, 2))
+		conn.Write([]byte{0x20, 2, 0, 5}) // not authorized
+	}()
+	_, err = dialMQTT(context.Background(), ln.Addr().String(), "test", "user", "wrong")
+	if err == nil || !strings.Contains(err.Error(), "code 5") {
+		t.Errorf("err = %v, want a refusal with code 5", err)
+	}
+}
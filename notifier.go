@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// A Notification tells a student that something happened, independent of
+// how it reaches them. Features that alert students build one and hand it
+// to a Notifier.
+type Notification struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	StudentID  string    `json:"student_id"`
+	Title      string    `json:"title"`
+	Data       any       `json:"data,omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Builds a Notification with a fresh ID.
+func newNotification(typ, studentID, title string, data any) Notification {
+	return Notification{ID: randomHex(16), Type: typ, OccurredAt: time.Now(), StudentID: studentID, Title: title, Data: data}
+}
+
+// Delivers notifications as signed webhooks, with the same headers and
+// retries as schedule subscriptions. The sequence number counts
+// notifications sent through this notifier.
+type webhookNotifier struct {
+	url      string
+	secret   string
+	sequence atomic.Int64
+}
+
+func newWebhookNotifier(url, secret string) *webhookNotifier {
+	return &webhookNotifier{url: url, secret: secret}
+}
+
+func (wn *webhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	sequence := wn.sequence.Add(1)
+	client := &http.Client{Timeout: webhookTimeout}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = postWebhook(ctx, client, wn.url, wn.secret, n.ID, sequence, "application/json", body)
+		if err == nil || attempt >= webhookMaxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
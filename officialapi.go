@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ITB has no public JSON API for SIX yet. If it grows one, even a partial
+// one, setting SIX_API_URL makes the server prefer it per data type and fall
+// back to scraping for whatever it does not offer. The endpoint paths and
+// response shapes below are a best guess and will need adjusting once real
+// endpoints are published.
+var (
+	// How long a data type the API was found not to support keeps going
+	// straight to scraping before the API is tried again.
+	apiRecheck = envDuration("SIX_API_RECHECK", 6*time.Hour)
+	// Largest JSON response read from the API.
+	maxAPIResponse int64 = 16 << 20
+)
+
+// Values of Meta.Source for a fresh fetch.
+const (
+	sourceAPI    = "api"
+	sourceScrape = "scrape"
+)
+
+// Data types the official API may or may not offer.
+const (
+	capHome       = "home"
+	capSchedule   = "schedule"
+	capTranscript = "transcript"
+	capHistory    = "grade_history"
+	capCurriculum = "curriculum"
+	capGrades     = "grades"
+	capExams      = "exams"
+	capFRS        = "frs"
+	capProfile    = "profile"
+	capCalendar   = "calendar"
+	capPayments   = "payments"
+)
+
+// errAPIUnsupported means the API does not offer an endpoint: it answered
+// 404, 405, or 501, or answered with something other than JSON.
+var errAPIUnsupported = errors.New("not offered by the official API")
+
+// A Provider for the official JSON API. It authenticates with the same SIX
+// cookies as the web pages.
+type officialAPI struct {
+	baseURL   string
+	transport http.RoundTripper
+}
+
+func newOfficialAPI(baseURL string, transport http.RoundTripper) *officialAPI {
+	return &officialAPI{baseURL: baseURL, transport: transport}
+}
+
+func (a *officialAPI) Name() string { return "api" }
+
+// Fetches path and decodes its JSON response into v.
+func (a *officialAPI) get(r *http.Request, path string, v any) error {
+	req, err := newSIXRequest(a.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := doUpstream(&http.Client{Transport: a.transport}, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return errAPIUnsupported
+	default:
+		return fmt.Errorf("official API returned %s", resp.Status)
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "application/json" {
+		return errAPIUnsupported
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxAPIResponse)).Decode(v)
+}
+
+func (a *officialAPI) FetchHomePage(r *http.Request) (Home, error) {
+	var me struct {
+		StudentID string `json:"student_id"`
+		Semester  string `json:"semester"`
+	}
+	if err := a.get(r, "/api/v1/me", &me); err != nil {
+		return Home{}, err
+	}
+	switch {
+	case !studentIDParamRe.MatchString(me.StudentID):
+		return Home{}, errStudentIDNotFound
+	case !semesterParamRe.MatchString(me.Semester):
+		return Home{}, errSemesterNotFound
+	}
+	return Home{StudentID: me.StudentID, Semester: me.Semester, Source: sourceAPI}, nil
+}
+
+func (a *officialAPI) FetchSchedulePage(r *http.Request, studentID, semester string, filters url.Values) (SchedulePage, error) {
+	q := scheduleFilters(filters)
+	q.Set("semester", semester)
+	var classes []CourseClass
+	if err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/jadwal?%s", studentID, q.Encode()), &classes); err != nil {
+		return SchedulePage{}, err
+	}
+	return SchedulePage{Classes: enrichClasses(classes), Source: sourceAPI}, nil
+}
+
+func (a *officialAPI) FetchTranscript(r *http.Request, studentID string) ([]TranscriptCourse, error) {
+	var courses []TranscriptCourse
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/transkrip", studentID), &courses)
+	return courses, err
+}
+
+func (a *officialAPI) FetchGradeHistory(r *http.Request, studentID string) (GradeHistory, error) {
+	var history GradeHistory
+	courses, err := a.FetchTranscript(r, studentID)
+	if err != nil {
+		return history, err
+	}
+	history.Courses = courses
+	err = a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/ip", studentID), &history.Reported)
+	return history, err
+}
+
+func (a *officialAPI) FetchCurriculum(r *http.Request, studentID string) ([]CurriculumCourse, error) {
+	var courses []CurriculumCourse
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/kurikulum", studentID), &courses)
+	return courses, err
+}
+
+func (a *officialAPI) FetchGrades(r *http.Request, studentID, semester string) ([]GradeEntry, error) {
+	var grades []GradeEntry
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/nilai?semester=%s", studentID, url.QueryEscape(semester)), &grades)
+	return grades, err
+}
+
+func (a *officialAPI) FetchExams(r *http.Request, studentID, semester string) ([]ExamEntry, error) {
+	var exams []ExamEntry
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/ujian?semester=%s", studentID, url.QueryEscape(semester)), &exams)
+	return exams, err
+}
+
+func (a *officialAPI) FetchFRS(r *http.Request, studentID, semester string) (FRS, error) {
+	var frs FRS
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/frs?semester=%s", studentID, url.QueryEscape(semester)), &frs)
+	return frs, err
+}
+
+func (a *officialAPI) FetchProfile(r *http.Request, studentID string) (Profile, error) {
+	var profile Profile
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/profil", studentID), &profile)
+	return profile, err
+}
+
+func (a *officialAPI) FetchCalendar(r *http.Request) ([]CalendarEntry, error) {
+	var entries []CalendarEntry
+	err := a.get(r, "/api/v1/kalender-akademik", &entries)
+	return entries, err
+}
+
+func (a *officialAPI) FetchPayments(r *http.Request, studentID string) ([]Payment, error) {
+	var payments []Payment
+	err := a.get(r, fmt.Sprintf("/api/v1/mahasiswa/%s/pembayaran", studentID), &payments)
+	return payments, err
+}
+
+// A Provider that tries api first and falls back to scrape. When api turns
+// out not to offer a data type, that type goes straight to scrape for
+// apiRecheck. Other API failures fall back for that one fetch only.
+type fallbackProvider struct {
+	api    Provider
+	scrape Provider
+
+	mu          sync.Mutex
+	unsupported map[string]time.Time // data type to when it was last found unsupported
+}
+
+func newFallbackProvider(api, scrape Provider) *fallbackProvider {
+	return &fallbackProvider{api: api, scrape: scrape, unsupported: make(map[string]time.Time)}
+}
+
+func (p *fallbackProvider) Name() string { return p.api.Name() + "+" + p.scrape.Name() }
+
+// Reports whether the API should be tried for a data type.
+func (p *fallbackProvider) prefersAPI(capability string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	since, ok := p.unsupported[capability]
+	return !ok || time.Since(since) >= apiRecheck
+}
+
+// Records the outcome of an API fetch of a data type and reports whether
+// to fall back to scraping. Errors about the caller's own request, such as
+// missing cookies, are returned as they are.
+func (p *fallbackProvider) fallBack(capability string, err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var missing *missingCookieError
+	if err == nil {
+		delete(p.unsupported, capability)
+		return false
+	}
+	if errors.As(err, &missing) {
+		return false
+	}
+	if errors.Is(err, errAPIUnsupported) {
+		if _, known := p.unsupported[capability]; !known {
+			log.Printf("official API does not offer %s, scraping instead", capability)
+		}
+		p.unsupported[capability] = time.Now()
+	} else {
+		log.Printf("official API failed for %s, scraping instead: %v", capability, err)
+	}
+	return true
+}
+
+func (p *fallbackProvider) FetchHomePage(r *http.Request) (Home, error) {
+	if p.prefersAPI(capHome) {
+		home, err := p.api.FetchHomePage(r)
+		if !p.fallBack(capHome, err) {
+			return home, err
+		}
+	}
+	return p.scrape.FetchHomePage(r)
+}
+
+func (p *fallbackProvider) FetchSchedulePage(r *http.Request, studentID, semester string, filters url.Values) (SchedulePage, error) {
+	if p.prefersAPI(capSchedule) {
+		page, err := p.api.FetchSchedulePage(r, studentID, semester, filters)
+		if !p.fallBack(capSchedule, err) {
+			return page, err
+		}
+	}
+	return p.scrape.FetchSchedulePage(r, studentID, semester, filters)
+}
+
+func (p *fallbackProvider) FetchTranscript(r *http.Request, studentID string) ([]TranscriptCourse, error) {
+	if p.prefersAPI(capTranscript) {
+		courses, err := p.api.FetchTranscript(r, studentID)
+		if !p.fallBack(capTranscript, err) {
+			return courses, err
+		}
+	}
+	return p.scrape.FetchTranscript(r, studentID)
+}
+
+func (p *fallbackProvider) FetchGradeHistory(r *http.Request, studentID string) (GradeHistory, error) {
+	if p.prefersAPI(capHistory) {
+		history, err := p.api.FetchGradeHistory(r, studentID)
+		if !p.fallBack(capHistory, err) {
+			return history, err
+		}
+	}
+	return p.scrape.FetchGradeHistory(r, studentID)
+}
+
+func (p *fallbackProvider) FetchCurriculum(r *http.Request, studentID string) ([]CurriculumCourse, error) {
+	if p.prefersAPI(capCurriculum) {
+		courses, err := p.api.FetchCurriculum(r, studentID)
+		if !p.fallBack(capCurriculum, err) {
+			return courses, err
+		}
+	}
+	return p.scrape.FetchCurriculum(r, studentID)
+}
+
+func (p *fallbackProvider) FetchGrades(r *http.Request, studentID, semester string) ([]GradeEntry, error) {
+	if p.prefersAPI(capGrades) {
+		grades, err := p.api.FetchGrades(r, studentID, semester)
+		if !p.fallBack(capGrades, err) {
+			return grades, err
+		}
+	}
+	return p.scrape.FetchGrades(r, studentID, semester)
+}
+
+func (p *fallbackProvider) FetchExams(r *http.Request, studentID, semester string) ([]ExamEntry, error) {
+	if p.prefersAPI(capExams) {
+		exams, err := p.api.FetchExams(r, studentID, semester)
+		if !p.fallBack(capExams, err) {
+			return exams, err
+		}
+	}
+	return p.scrape.FetchExams(r, studentID, semester)
+}
+
+func (p *fallbackProvider) FetchFRS(r *http.Request, studentID, semester string) (FRS, error) {
+	if p.prefersAPI(capFRS) {
+		frs, err := p.api.FetchFRS(r, studentID, semester)
+		if !p.fallBack(capFRS, err) {
+			return frs, err
+		}
+	}
+	return p.scrape.FetchFRS(r, studentID, semester)
+}
+
+func (p *fallbackProvider) FetchProfile(r *http.Request, studentID string) (Profile, error) {
+	if p.prefersAPI(capProfile) {
+		profile, err := p.api.FetchProfile(r, studentID)
+		if !p.fallBack(capProfile, err) {
+			return profile, err
+		}
+	}
+	return p.scrape.FetchProfile(r, studentID)
+}
+
+func (p *fallbackProvider) FetchCalendar(r *http.Request) ([]CalendarEntry, error) {
+	if p.prefersAPI(capCalendar) {
+		entries, err := p.api.FetchCalendar(r)
+		if !p.fallBack(capCalendar, err) {
+			return entries, err
+		}
+	}
+	return p.scrape.FetchCalendar(r)
+}
+
+func (p *fallbackProvider) FetchPayments(r *http.Request, studentID string) ([]Payment, error) {
+	if p.prefersAPI(capPayments) {
+		payments, err := p.api.FetchPayments(r, studentID)
+		if !p.fallBack(capPayments, err) {
+			return payments, err
+		}
+	}
+	return p.scrape.FetchPayments(r, studentID)
+}
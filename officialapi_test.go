@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Serves the official API's schedule endpoint and 404 for everything else,
+// counting the requests that reach it.
+func mockOfficialAPI(hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !strings.HasSuffix(r.URL.Path, "/jadwal") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"code":"IF2211","name":"Strategi Algoritma","sks":3,"class_no":"01"}]`)
+	}))
+}
+
+func TestOfficialAPI_PreferredForSchedule(t *testing.T) {
+	var hits atomic.Int32
+	api := mockOfficialAPI(&hits)
+	defer api.Close()
+	six := mockSIX("13520001", "2025-2")
+	defer six.Close()
+	srv := NewServer(Config{BaseURL: six.URL, APIURL: api.URL})
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2025-2", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if meta := decodeMeta(t, w); meta.Source != sourceAPI {
+		t.Errorf("source = %q, want %q", meta.Source, sourceAPI)
+	}
+}
+
+func TestOfficialAPI_FallsBackAndRemembers(t *testing.T) {
+	var hits atomic.Int32
+	api := mockOfficialAPI(&hits)
+	defer api.Close()
+	six := mockSIX("13520001", "2025-2")
+	defer six.Close()
+	srv := NewServer(Config{BaseURL: six.URL, APIURL: api.URL})
+
+	for i := range 2 {
+		req := httptest.NewRequest("GET", "/api/user", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d: %s", i, w.Code, w.Body)
+		}
+		if meta := decodeMeta(t, w); meta.Source != sourceScrape {
+			t.Errorf("request %d: source = %q, want %q", i, meta.Source, sourceScrape)
+		}
+	}
+	// The 404 on the first request marks home as unsupported.
+	if n := hits.Load(); n != 1 {
+		t.Errorf("API requests = %d, want 1", n)
+	}
+}
+
+func TestOfficialAPI_MissingCookiesDoNotFallBack(t *testing.T) {
+	var hits atomic.Int32
+	api := mockOfficialAPI(&hits)
+	defer api.Close()
+	p := newFallbackProvider(newOfficialAPI(api.URL, nil), newSIXProvider("http://six.invalid", nil))
+
+	_, err := p.FetchHomePage(httptest.NewRequest("GET", "/api/user", nil))
+	if _, ok := err.(*missingCookieError); !ok {
+		t.Errorf("err = %v, want a missingCookieError", err)
+	}
+	if !p.prefersAPI(capHome) {
+		t.Error("missing cookies marked home as unsupported")
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The OpenAPI description of the API is built from the Operation each route
+// is registered with. The same description drives request validation, so
+// the served spec and the checks cannot drift apart.
+
+type OpenAPI struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    OpenAPIInfo                      `json:"info"`
+	Paths   map[string]map[string]*Operation `json:"paths"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Operation struct {
+	Summary     string              `json:"summary"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "query" or "path"
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string `json:"description"`
+}
+
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// One problem found while validating a request.
+type ValidationError struct {
+	In      string `json:"in"` // "query", "path", or "body"
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+func newOpenAPI() *OpenAPI {
+	return &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "six-scraper-go", Version: "1"},
+		Paths:   make(map[string]map[string]*Operation),
+	}
+}
+
+func (spec *OpenAPI) add(method, path string, op *Operation) {
+	if spec.Paths[path] == nil {
+		spec.Paths[path] = make(map[string]*Operation)
+	}
+	if op.Responses == nil {
+		op.Responses = defaultResponses
+	}
+	spec.Paths[path][strings.ToLower(method)] = op
+}
+
+var defaultResponses = map[string]Response{
+	"200":     {Description: "Success envelope"},
+	"default": {Description: "Error envelope"},
+}
+
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	noteEncoded(w, s.spec)
+	enc.Encode(s.spec)
+}
+
+// Shared parameter and body schemas.
+var (
+	studentIDParam = Parameter{Name: "student_id", In: "query", Required: true, Description: "NIM", Schema: &Schema{Type: "string", Pattern: studentIDParamRe.String()}}
+	semesterParam  = Parameter{Name: "semester", In: "query", Required: true, Description: "Semester, e.g. 2025-2", Schema: &Schema{Type: "string", Pattern: semesterParamRe.String()}}
+
+	// Like semesterParam, but also accepts current, previous, and next.
+	relativeSemesterParam = Parameter{Name: "semester", In: "query", Required: true, Description: "Semester, e.g. 2025-2, or current, previous, or next", Schema: &Schema{Type: "string", Pattern: `^(\d{4}-\d|current|previous|next)$`}}
+	// Like relativeSemesterParam, for endpoints that cover every semester
+	// without it.
+	semesterFilterParam = Parameter{Name: "semester", In: "query", Description: "Only this semester, e.g. 2025-2, or current, previous, or next", Schema: relativeSemesterParam.Schema}
+
+	// student_id, semester, and the SIX filters accepted by schedule endpoints.
+	scheduleParams = []Parameter{
+		studentIDParam,
+		relativeSemesterParam,
+		{Name: "fakultas", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "prodi", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "pekan", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "kegiatan", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "refresh", In: "query", Description: "Bypass the cache", Schema: &Schema{Type: "boolean"}},
+	}
+
+	webhookURLSchema = &Schema{Type: "string", Pattern: `^https?://[^/?#\s]+`}
+	filtersSchema    = &Schema{Type: "object", AdditionalProperties: &Schema{Type: "array", Items: &Schema{Type: "string"}}}
+	quietHoursSchema = &Schema{Type: "string", Pattern: `^(\d{2}:\d{2}-\d{2}:\d{2})?$`}
+	channelsSchema   = &Schema{Type: "array", Items: &Schema{
+		Type:     "object",
+		Required: []string{"type", "target"},
+		Properties: map[string]*Schema{
+			"type":     {Type: "string", Enum: []string{"webhook", "telegram", "email"}},
+			"target":   {Type: "string", Pattern: ".+"},
+			"template": {Type: "string"},
+		},
+	}}
+)
+
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+}
+
+// Largest request body the validator reads.
+const maxRequestBody = 1 << 20
+
+// Rejects requests that do not match op: 400 for a body that is not JSON,
+// 422 with a list of problems for anything else.
+func validateRequest(op *Operation) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var problems []ValidationError
+			query := r.URL.Query()
+			for _, p := range op.Parameters {
+				var value string
+				var present bool
+				switch p.In {
+				case "query":
+					present = query.Has(p.Name) && query.Get(p.Name) != ""
+					value = query.Get(p.Name)
+				case "path":
+					value = r.PathValue(p.Name)
+					present = value != ""
+				}
+				if !present {
+					if p.Required {
+						problems = append(problems, ValidationError{In: p.In, Name: p.Name, Message: "is required"})
+					}
+					continue
+				}
+				if msg := p.Schema.checkString(value); msg != "" {
+					problems = append(problems, ValidationError{In: p.In, Name: p.Name, Message: msg})
+				}
+			}
+
+			if op.RequestBody != nil {
+				data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+				if err != nil {
+					writeError(w, r, codeUnreadableBody)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(data))
+				var body any
+				if err := json.Unmarshal(data, &body); err != nil {
+					writeError(w, r, codeInvalidJSON)
+					return
+				}
+				schema := op.RequestBody.Content["application/json"].Schema
+				problems = append(problems, schema.check("", body)...)
+			}
+
+			if len(problems) > 0 {
+				writeValidationErrors(w, r, problems)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, problems []ValidationError) {
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.Name + " " + p.Message
+	}
+	writeErrorResponse(w, r, http.StatusUnprocessableEntity, APIResponse{
+		Code:    codeInvalidRequest,
+		Error:   errorText(r, codeInvalidRequest, strings.Join(msgs, "; ")),
+		Details: problems,
+	})
+}
+
+var (
+	patternMu    sync.Mutex
+	patternCache = make(map[string]*regexp.Regexp)
+)
+
+func compiledPattern(p string) *regexp.Regexp {
+	patternMu.Lock()
+	defer patternMu.Unlock()
+	re, ok := patternCache[p]
+	if !ok {
+		re = regexp.MustCompile(p)
+		patternCache[p] = re
+	}
+	return re
+}
+
+// Checks a query or path value, which always arrives as a string. Returns
+// a message describing the problem, or "".
+func (sc *Schema) checkString(v string) string {
+	switch sc.Type {
+	case "integer":
+		if _, err := strconv.Atoi(v); err != nil {
+			return "must be an integer"
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(v); err != nil {
+			return "must be true or false"
+		}
+	}
+	if sc.Pattern != "" && !compiledPattern(sc.Pattern).MatchString(v) {
+		return "must match " + sc.Pattern
+	}
+	if len(sc.Enum) > 0 && !slices.Contains(sc.Enum, v) {
+		return "must be one of " + strings.Join(sc.Enum, ", ")
+	}
+	return ""
+}
+
+// Checks a decoded JSON value against the schema. name is the dotted path of
+// v within the body, "" for the body itself.
+func (sc *Schema) check(name string, v any) []ValidationError {
+	problem := func(msg string) []ValidationError {
+		field := name
+		if field == "" {
+			field = "body"
+		}
+		return []ValidationError{{In: "body", Name: field, Message: msg}}
+	}
+	if v == nil {
+		return problem("must not be null")
+	}
+
+	switch sc.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return problem("must be an object")
+		}
+		var problems []ValidationError
+		for _, req := range sc.Required {
+			if _, ok := obj[req]; !ok {
+				problems = append(problems, ValidationError{In: "body", Name: joinField(name, req), Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if prop, ok := sc.Properties[k]; ok {
+				problems = append(problems, prop.check(joinField(name, k), obj[k])...)
+			} else if sc.AdditionalProperties != nil {
+				problems = append(problems, sc.AdditionalProperties.check(joinField(name, k), obj[k])...)
+			}
+		}
+		return problems
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return problem("must be an array")
+		}
+		var problems []ValidationError
+		for i, item := range arr {
+			if sc.Items != nil {
+				problems = append(problems, sc.Items.check(fmt.Sprintf("%s[%d]", name, i), item)...)
+			}
+		}
+		return problems
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return problem("must be a string")
+		}
+		if msg := sc.checkString(s); msg != "" {
+			return problem(msg)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return problem("must be true or false")
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return problem("must be a number")
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != float64(int64(f)) {
+			return problem("must be an integer")
+		}
+	}
+	return nil
+}
+
+func joinField(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSchemaCheck(t *testing.T) {
+	schema := &Schema{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]*Schema{
+			"name":  {Type: "string", Pattern: `^[a-z]+$`},
+			"count": {Type: "integer"},
+			"tags":  {Type: "array", Items: &Schema{Type: "string"}},
+		},
+	}
+	var body any
+	json.Unmarshal([]byte(`{"count":1.5,"tags":["a",2]}`), &body)
+
+	got := map[string]string{}
+	for _, p := range schema.check("", body) {
+		got[p.Name] = p.Message
+	}
+	want := map[string]string{"name": "is required", "count": "must be an integer", "tags[1]": "must be a string"}
+	for name, msg := range want {
+		if got[name] != msg {
+			t.Errorf("%s: got %q, want %q", name, got[name], msg)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("problems = %v", got)
+	}
+}
+
+func TestValidateRequest_Details(t *testing.T) {
+	srv := newTestServer("")
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=abc&refresh=maybe", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want 422", w.Code)
+	}
+	var resp APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, d := range resp.Details {
+		got[d.In+":"+d.Name] = true
+	}
+	for _, name := range []string{"query:student_id", "query:semester", "query:refresh"} {
+		if !got[name] {
+			t.Errorf("missing detail for %s in %+v", name, resp.Details)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	srv := newTestServer("")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	var spec OpenAPI
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	op := spec.Paths["/api/schedule"]["get"]
+	if op == nil || len(op.Parameters) == 0 || op.Parameters[0].Schema.Pattern == "" {
+		t.Errorf("schedule operation = %+v", op)
+	}
+	if spec.Paths["/api/subscriptions"]["post"].RequestBody == nil {
+		t.Error("expected a request body for POST /api/subscriptions")
+	}
+}
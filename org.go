@@ -0,0 +1,490 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Organizations let a dorm or student organization see when its members are
+// busy. An API key creates an organization and shares its join code. Each
+// member joins with their own SIX session and explicit consent, and can
+// leave at any time with the member token they got when joining. Only the
+// creating API key sees the aggregated schedule, and it never sees course
+// names, only who is busy when and which rooms are in use. Organizations
+// keep members' SIX cookies in memory, so they are opt-in and need API keys
+// to tell owners apart.
+var (
+	orgsEnabled   = envBool("SIX_ORGS", false)
+	orgMaxMembers = envInt("SIX_ORG_MAX_MEMBERS", 200)
+	orgMax        = envInt("SIX_ORG_MAX", 100)
+)
+
+// Free slots are looked for between these times, in minutes since midnight.
+const (
+	orgDayStart = 7 * 60
+	orgDayEnd   = 18 * 60
+)
+
+// Days the free slots cover.
+var orgDays = []string{"Senin", "Selasa", "Rabu", "Kamis", "Jumat"}
+
+type Org struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	JoinCode  string      `json:"join_code"`
+	CreatedAt time.Time   `json:"created_at"`
+	Members   []OrgMember `json:"members"`
+
+	owner string
+}
+
+type OrgMember struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	StudentID   string    `json:"student_id"`
+	JoinedAt    time.Time `json:"joined_at"`
+	ConsentedAt time.Time `json:"consented_at"`
+	Token       string    `json:"token,omitempty"` // only returned on joining
+	ConsentID   string    `json:"consent_id"`
+
+	auth http.Header // Cookie and X-Six-* headers of the joining request
+}
+
+// The aggregated schedule of an organization for one semester.
+type OrgSchedule struct {
+	Semester string         `json:"semester"`
+	Busy     []OrgBusySlot  `json:"busy"`
+	Free     []OrgFreeSlot  `json:"free"`
+	Rooms    []OrgRoomUsage `json:"rooms"`
+	// Members whose schedule could not be fetched.
+	Errors []OrgMemberError `json:"errors"`
+}
+
+type OrgBusySlot struct {
+	Day     string   `json:"day"`
+	Start   string   `json:"start"`
+	End     string   `json:"end"`
+	Members []string `json:"members"` // member IDs
+}
+
+type OrgFreeSlot struct {
+	Day     string `json:"day"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Minutes int    `json:"minutes"`
+}
+
+type OrgRoomUsage struct {
+	Room     string `json:"room"`
+	Building string `json:"building,omitempty"`
+	Meetings int    `json:"meetings"` // distinct day and time slots
+	Members  int    `json:"members"`
+}
+
+type OrgMemberError struct {
+	Member string `json:"member"`
+	Error  string `json:"error"`
+}
+
+type orgRegistry struct {
+	mu   sync.Mutex
+	orgs map[string]*Org
+}
+
+func newOrgRegistry() *orgRegistry {
+	return &orgRegistry{orgs: make(map[string]*Org)}
+}
+
+// Returns a copy of org without member tokens.
+func (o *Org) view() Org {
+	v := *o
+	v.Members = make([]OrgMember, len(o.Members))
+	for i, m := range o.Members {
+		m.Token = ""
+		v.Members[i] = m
+	}
+	return v
+}
+
+func orgsAvailable(w http.ResponseWriter, r *http.Request) bool {
+	if !orgsEnabled || len(apiKeys) == 0 {
+		writeError(w, r, codeOrgsDisabled)
+		return false
+	}
+	return true
+}
+
+// Removes the members of org id matching match and returns them.
+func (o *orgRegistry) removeMembers(id string, match func(OrgMember) bool) []OrgMember {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	org, ok := o.orgs[id]
+	if !ok {
+		return nil
+	}
+	var removed []OrgMember
+	org.Members = slices.DeleteFunc(org.Members, func(m OrgMember) bool {
+		if match(m) {
+			removed = append(removed, m)
+			return true
+		}
+		return false
+	})
+	return removed
+}
+
+// Returns the org with id if the caller created it. Callers must hold o.mu.
+func (o *orgRegistry) ownedLocked(r *http.Request, id string) (*Org, bool) {
+	org, ok := o.orgs[id]
+	if !ok || org.owner != subscriptionOwner(r) {
+		return nil, false
+	}
+	return org, true
+}
+
+type createOrgRequest struct {
+	Name string `json:"name"`
+}
+
+// POST /api/orgs
+func (s *Server) createOrg(w http.ResponseWriter, r *http.Request) {
+	if !orgsAvailable(w, r) {
+		return
+	}
+	var body createOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	org := &Org{
+		ID:        randomHex(8),
+		Name:      body.Name,
+		JoinCode:  randomHex(6),
+		CreatedAt: time.Now(),
+		Members:   []OrgMember{},
+		owner:     subscriptionOwner(r),
+	}
+
+	s.orgs.mu.Lock()
+	full := len(s.orgs.orgs) >= orgMax
+	if !full {
+		s.orgs.orgs[org.ID] = org
+	}
+	s.orgs.mu.Unlock()
+	if full {
+		writeError(w, r, codeServerBusy)
+		return
+	}
+	log.Printf("org created id=%s", org.ID)
+	writeCreated(w, org.view())
+}
+
+// GET /api/orgs/{id}
+func (s *Server) getOrg(w http.ResponseWriter, r *http.Request) {
+	if !orgsAvailable(w, r) {
+		return
+	}
+	s.orgs.mu.Lock()
+	org, ok := s.orgs.ownedLocked(r, r.PathValue("id"))
+	var v Org
+	if ok {
+		v = org.view()
+	}
+	s.orgs.mu.Unlock()
+	if !ok {
+		writeError(w, r, codeOrgNotFound)
+		return
+	}
+	writeSuccess(w, v)
+}
+
+// DELETE /api/orgs/{id}
+func (s *Server) deleteOrg(w http.ResponseWriter, r *http.Request) {
+	if !orgsAvailable(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	s.orgs.mu.Lock()
+	org, ok := s.orgs.ownedLocked(r, id)
+	if ok {
+		delete(s.orgs.orgs, id)
+	}
+	s.orgs.mu.Unlock()
+	if !ok {
+		writeError(w, r, codeOrgNotFound)
+		return
+	}
+	for _, m := range org.Members {
+		s.consents.end(m.ConsentID, revokedDelete)
+	}
+	log.Printf("org deleted id=%s", id)
+	writeSuccess(w, map[string]string{"id": id})
+}
+
+type joinOrgRequest struct {
+	JoinCode string `json:"join_code"`
+	Name     string `json:"name"`
+	Consent  bool   `json:"consent"`
+}
+
+// POST /api/orgs/{id}/members
+//
+// Joins an organization with the caller's own SIX session. The student ID is
+// taken from the session, so nobody can enroll someone else.
+func (s *Server) joinOrg(w http.ResponseWriter, r *http.Request) {
+	if !orgsAvailable(w, r) {
+		return
+	}
+	var body joinOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if !body.Consent {
+		writeError(w, r, codeInvalidRequest, "consent must be true to share your schedule with the organization")
+		return
+	}
+
+	id := r.PathValue("id")
+	s.orgs.mu.Lock()
+	org, ok := s.orgs.orgs[id]
+	validCode := ok && subtle.ConstantTimeCompare([]byte(body.JoinCode), []byte(org.JoinCode)) == 1
+	s.orgs.mu.Unlock()
+	if !validCode {
+		writeError(w, r, codeOrgNotFound)
+		return
+	}
+
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	home, err := s.provider.FetchHomePage(r)
+	release()
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	s.semesters.learn(home.StudentID, home.Semester, time.Now())
+
+	now := time.Now()
+	m := OrgMember{
+		ID:          randomHex(8),
+		Name:        body.Name,
+		StudentID:   home.StudentID,
+		JoinedAt:    now,
+		ConsentedAt: now,
+		Token:       randomHex(32),
+		auth:        sixAuthHeaders(r),
+	}
+
+	// Joining again replaces the earlier membership and its cookies.
+	for _, old := range s.orgs.removeMembers(id, func(o OrgMember) bool { return o.StudentID == m.StudentID }) {
+		s.consents.end(old.ConsentID, revokedDelete)
+	}
+	s.orgs.mu.Lock()
+	org, ok = s.orgs.orgs[id]
+	full := ok && len(org.Members) >= orgMaxMembers
+	if ok && !full {
+		org.Members = append(org.Members, m)
+	}
+	s.orgs.mu.Unlock()
+	switch {
+	case !ok:
+		writeError(w, r, codeOrgNotFound)
+		return
+	case full:
+		writeError(w, r, codeServerBusy)
+		return
+	}
+
+	consent := s.consents.grant(r, scopeOrgMembership, m.StudentID, id, func() {
+		s.orgs.removeMembers(id, func(o OrgMember) bool { return o.ID == m.ID })
+	})
+	m.ConsentID = consent.ID
+	s.orgs.mu.Lock()
+	if org, ok := s.orgs.orgs[id]; ok {
+		for i := range org.Members {
+			if org.Members[i].ID == m.ID {
+				org.Members[i].ConsentID = consent.ID
+			}
+		}
+	}
+	s.orgs.mu.Unlock()
+	log.Printf("org member joined org=%s member=%s", id, m.ID)
+	writeCreated(w, m)
+}
+
+// DELETE /api/orgs/{id}/members/{member_id}
+//
+// Removes a member. Allowed for the organization's owner and for the member
+// themselves, with the token from X-Org-Member-Token.
+func (s *Server) leaveOrg(w http.ResponseWriter, r *http.Request) {
+	if !orgsAvailable(w, r) {
+		return
+	}
+	id, memberID := r.PathValue("id"), r.PathValue("member_id")
+	token := r.Header.Get("X-Org-Member-Token")
+
+	s.orgs.mu.Lock()
+	org, ok := s.orgs.orgs[id]
+	isOwner := ok && org.owner == subscriptionOwner(r)
+	s.orgs.mu.Unlock()
+	removed := s.orgs.removeMembers(id, func(m OrgMember) bool {
+		return m.ID == memberID && (isOwner || subtle.ConstantTimeCompare([]byte(token), []byte(m.Token)) == 1)
+	})
+	if len(removed) == 0 {
+		writeError(w, r, codeOrgNotFound)
+		return
+	}
+	for _, m := range removed {
+		s.consents.end(m.ConsentID, revokedDelete)
+	}
+	log.Printf("org member left org=%s member=%s", id, memberID)
+	writeSuccess(w, map[string]string{"id": memberID})
+}
+
+// GET /api/orgs/{id}/schedule?semester=...
+func (s *Server) orgScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if !orgsAvailable(w, r) {
+		return
+	}
+	s.orgs.mu.Lock()
+	org, ok := s.orgs.ownedLocked(r, r.PathValue("id"))
+	var members []OrgMember
+	if ok {
+		members = slices.Clone(org.Members)
+	}
+	s.orgs.mu.Unlock()
+	if !ok {
+		writeError(w, r, codeOrgNotFound)
+		return
+	}
+
+	semester := r.URL.Query().Get("semester")
+	schedules := make(map[string][]CourseClass, len(members))
+	result := OrgSchedule{Errors: []OrgMemberError{}}
+	for _, m := range members {
+		resolved, _ := s.semesters.resolve(m.StudentID, semester, time.Now())
+		result.Semester = resolved
+		classes, err := s.memberSchedule(r.Context(), m, resolved)
+		if err != nil {
+			log.Printf("org schedule failed org=%s member=%s err=%v", org.ID, m.ID, err)
+			result.Errors = append(result.Errors, OrgMemberError{Member: m.ID, Error: err.Error()})
+			continue
+		}
+		schedules[m.ID] = classes
+	}
+	if result.Semester == "" {
+		result.Semester, _ = s.semesters.resolve("", semester, time.Now())
+	}
+	result.Busy, result.Free, result.Rooms = aggregateOrgSchedules(schedules, s.buildings)
+	writeSuccess(w, result)
+}
+
+// Returns a member's schedule from the cache, or fetches it with their
+// stored credentials.
+func (s *Server) memberSchedule(ctx context.Context, m OrgMember, semester string) ([]CourseClass, error) {
+	if entry, ok := s.cache.get(schedulePath(m.StudentID, semester, nil)); ok {
+		return entry.data, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "/api/orgs", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = m.auth.Clone()
+	classes, _, err := s.scrapeSchedule(req, m.StudentID, semester, nil)
+	return classes, err
+}
+
+func clockString(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Combines members' schedules, keyed by member ID, into busy slots, the
+// weekday slots between orgDayStart and orgDayEnd when nobody has class,
+// and room usage.
+func aggregateOrgSchedules(schedules map[string][]CourseClass, buildings []Building) ([]OrgBusySlot, []OrgFreeSlot, []OrgRoomUsage) {
+	type slotKey struct{ day, start, end string }
+	busy := make(map[slotKey][]string)
+	type interval struct{ start, end int }
+	byDay := make(map[string][]interval)
+	type roomStats struct {
+		slots   map[slotKey]bool
+		members map[string]bool
+	}
+	rooms := make(map[string]*roomStats)
+
+	for memberID, classes := range schedules {
+		for _, c := range classes {
+			for _, e := range c.Schedules {
+				startText, endText, _ := strings.Cut(e.Time, "-")
+				start, ok1 := clockMinutes(startText)
+				end, ok2 := clockMinutes(endText)
+				if !ok1 || !ok2 || end <= start {
+					continue
+				}
+				k := slotKey{e.Day, clockString(start), clockString(end)}
+				if !slices.Contains(busy[k], memberID) {
+					busy[k] = append(busy[k], memberID)
+				}
+				byDay[e.Day] = append(byDay[e.Day], interval{start, end})
+
+				if e.Room == "" || strings.EqualFold(e.Method, "online") {
+					continue
+				}
+				rs := rooms[e.Room]
+				if rs == nil {
+					rs = &roomStats{slots: make(map[slotKey]bool), members: make(map[string]bool)}
+					rooms[e.Room] = rs
+				}
+				rs.slots[k] = true
+				rs.members[memberID] = true
+			}
+		}
+	}
+
+	busySlots := make([]OrgBusySlot, 0, len(busy))
+	for k, members := range busy {
+		slices.Sort(members)
+		busySlots = append(busySlots, OrgBusySlot{Day: k.day, Start: k.start, End: k.end, Members: members})
+	}
+	slices.SortFunc(busySlots, func(a, b OrgBusySlot) int {
+		return cmp.Or(cmp.Compare(dayRank(a.Day), dayRank(b.Day)), cmp.Compare(a.Day, b.Day), cmp.Compare(a.Start, b.Start), cmp.Compare(a.End, b.End))
+	})
+
+	free := []OrgFreeSlot{}
+	for _, day := range orgDays {
+		intervals := byDay[day]
+		slices.SortFunc(intervals, func(a, b interval) int { return cmp.Compare(a.start, b.start) })
+		cursor := orgDayStart
+		for _, iv := range append(intervals, interval{orgDayEnd, orgDayEnd}) {
+			if start := min(iv.start, orgDayEnd); start > cursor {
+				free = append(free, OrgFreeSlot{Day: day, Start: clockString(cursor), End: clockString(start), Minutes: start - cursor})
+			}
+			cursor = max(cursor, iv.end)
+		}
+	}
+
+	usage := make([]OrgRoomUsage, 0, len(rooms))
+	for room, rs := range rooms {
+		u := OrgRoomUsage{Room: room, Meetings: len(rs.slots), Members: len(rs.members)}
+		if b, ok := locateRoom(buildings, room); ok {
+			u.Building = b.Name
+		}
+		usage = append(usage, u)
+	}
+	slices.SortFunc(usage, func(a, b OrgRoomUsage) int {
+		return cmp.Or(cmp.Compare(b.Members, a.Members), cmp.Compare(a.Room, b.Room))
+	})
+	return busySlots, free, usage
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupOrgs(t *testing.T, six string) *Server {
+	t.Helper()
+	setAPIKeys(t, "owner=0:admin,other=0:admin,member")
+	old := orgsEnabled
+	orgsEnabled = true
+	t.Cleanup(func() { orgsEnabled = old })
+	return newTestServer(six)
+}
+
+func orgRequest(srv *Server, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-API-Key", key)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestOrgs_Disabled(t *testing.T) {
+	w := httptest.NewRecorder()
+	newTestServer("").ServeHTTP(w, httptest.NewRequest("POST", "/api/orgs", strings.NewReader(`{"name":"x"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", w.Code)
+	}
+}
+
+func TestOrgs_JoinAndAccessControl(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	srv := setupOrgs(t, mock.URL)
+
+	w := orgRequest(srv, "POST", "/api/orgs", "owner", `{"name":"Asrama Kidang"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", w.Code, w.Body)
+	}
+	org := decodeData[Org](t, w)
+	base := "/api/orgs/" + org.ID
+
+	if w := orgRequest(srv, "POST", base+"/members", "member", `{"join_code":"wrong","name":"Budi","consent":true}`); w.Code != http.StatusNotFound {
+		t.Errorf("wrong join code: status %d, want 404", w.Code)
+	}
+	if w := orgRequest(srv, "POST", base+"/members", "member", `{"join_code":"`+org.JoinCode+`","name":"Budi","consent":false}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("no consent: status %d, want 422", w.Code)
+	}
+	w = orgRequest(srv, "POST", base+"/members", "member", `{"join_code":"`+org.JoinCode+`","name":"Budi","consent":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("join: status %d: %s", w.Code, w.Body)
+	}
+	member := decodeData[OrgMember](t, w)
+	if member.StudentID != "13520001" || member.Token == "" {
+		t.Errorf("member = %+v, want the student ID from the session and a token", member)
+	}
+
+	// Only the owner sees the organization and its schedule. Members lack
+	// the role, and other admins do not own it.
+	for _, path := range []string{base, base + "/schedule?semester=2025-2"} {
+		if w := orgRequest(srv, "GET", path, "member", ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s as member: status %d, want 403", path, w.Code)
+		}
+		if w := orgRequest(srv, "GET", path, "other", ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s as another admin: status %d, want 404", path, w.Code)
+		}
+	}
+	w = orgRequest(srv, "GET", base, "owner", "")
+	if got := decodeData[Org](t, w); len(got.Members) != 1 || got.Members[0].Token != "" {
+		t.Errorf("owner view = %+v, want one member without token", got)
+	}
+
+	w = orgRequest(srv, "GET", base+"/schedule?semester=2025-2", "owner", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("schedule: status %d: %s", w.Code, w.Body)
+	}
+	sched := decodeData[OrgSchedule](t, w)
+	if sched.Semester != "2025-2" || len(sched.Busy) == 0 || len(sched.Errors) != 0 {
+		t.Errorf("schedule = %+v", sched)
+	}
+	if strings.Contains(w.Body.String(), "Fisika") {
+		t.Error("org schedule leaks course names")
+	}
+
+	// A member leaves with their token; someone else's token does not work.
+	leave := func(token string) int {
+		req := httptest.NewRequest("DELETE", base+"/members/"+member.ID, nil)
+		req.Header.Set("X-API-Key", "member")
+		req.Header.Set("X-Org-Member-Token", token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := leave("bogus"); code != http.StatusNotFound {
+		t.Errorf("leave with a bad token: status %d, want 404", code)
+	}
+	if code := leave(member.Token); code != http.StatusOK {
+		t.Errorf("leave: status %d, want 200", code)
+	}
+	w = orgRequest(srv, "GET", base, "owner", "")
+	if got := decodeData[Org](t, w); len(got.Members) != 0 {
+		t.Errorf("members after leaving = %+v", got.Members)
+	}
+}
+
+func TestAggregateOrgSchedules(t *testing.T) {
+	schedules := map[string][]CourseClass{
+		"a": {{Code: "IF2211", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+			{Day: "Selasa", Time: "13:00-15:00", Room: "7603", Method: "Online"},
+		}}},
+		"b": {{Code: "FI1210", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+			{Day: "Senin", Time: "08:00-10:00", Room: "9009", Method: "Offline"},
+		}}},
+	}
+	busy, free, rooms := aggregateOrgSchedules(schedules, testBuildings)
+
+	if len(busy) != 3 || busy[0].Start != "07:00" || len(busy[0].Members) != 2 {
+		t.Errorf("busy = %+v", busy)
+	}
+	var senin, selasa []OrgFreeSlot
+	for _, f := range free {
+		switch f.Day {
+		case "Senin":
+			senin = append(senin, f)
+		case "Selasa":
+			selasa = append(selasa, f)
+		}
+	}
+	if len(senin) != 1 || senin[0].Start != "10:00" || senin[0].End != "18:00" || senin[0].Minutes != 480 {
+		t.Errorf("Senin free = %+v", senin)
+	}
+	if len(selasa) != 2 || selasa[0].End != "13:00" || selasa[1].Start != "15:00" {
+		t.Errorf("Selasa free = %+v", selasa)
+	}
+	if len(rooms) != 2 || rooms[0].Room != "7602" || rooms[0].Members != 2 || rooms[0].Meetings != 1 || rooms[0].Building != "Labtek V Lantai 6" {
+		t.Errorf("rooms = %+v", rooms)
+	}
+}
+
+func TestOrgSchedule_MemberErrors(t *testing.T) {
+	srv := setupOrgs(t, "http://six.invalid")
+	srv.orgs.orgs["o"] = &Org{ID: "o", owner: "owner", Members: []OrgMember{
+		{ID: "ok", StudentID: "111"},
+		{ID: "broken", StudentID: "222", auth: http.Header{}},
+	}}
+	srv.cache.set(schedulePath("111", "2025-2", nil), []CourseClass{{Code: "IF2211", Schedules: []ScheduleEntry{{Day: "Senin", Time: "07:00-09:00"}}}}, time.Now())
+
+	w := orgRequest(srv, "GET", "/api/orgs/o/schedule?semester=2025-2", "owner", "")
+	sched := decodeData[OrgSchedule](t, w)
+	if len(sched.Busy) != 1 || len(sched.Errors) != 1 || sched.Errors[0].Member != "broken" {
+		t.Errorf("schedule = %+v", sched)
+	}
+}
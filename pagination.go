@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SIX may split a long listing over several pages, linked from a Bootstrap
+// ".pagination" list. The pages are fetched one after another, following the
+// links each page shows, so controls that only list nearby pages are still
+// walked to the end. Only links to the same path on the same host are
+// followed, and at most SIX_MAX_PAGES pages are fetched per listing.
+var maxUpstreamPages = envInt("SIX_MAX_PAGES", 10)
+
+// The pages of one listing, in the order they were fetched.
+type pagedDocs struct {
+	docs      []*goquery.Document
+	bytes     int64
+	truncated bool // pages were left unfetched at maxUpstreamPages
+}
+
+// Fetches the listing at firstURL and every page its pagination links to. A
+// failed page fails the whole listing, since serving part of it as the whole
+// would look like classes were dropped.
+func fetchPages(client *http.Client, firstURL string, r *http.Request) (pagedDocs, error) {
+	doc, resp, err := fetchDoc(client, firstURL, r)
+	if err != nil {
+		return pagedDocs{}, err
+	}
+	pages := pagedDocs{docs: []*goquery.Document{doc}, bytes: resp.ContentLength}
+	base := resp.Request.URL
+	seen := map[string]bool{base.RequestURI(): true}
+	if first, err := url.Parse(firstURL); err == nil {
+		seen[first.RequestURI()] = true
+	}
+	queue := paginationLinks(doc, base, seen)
+	for len(queue) > 0 {
+		if len(pages.docs) >= maxUpstreamPages {
+			log.Printf("pagination: stopped at %d pages url=%s", len(pages.docs), firstURL)
+			pages.truncated = true
+			break
+		}
+		next := queue[0]
+		queue = queue[1:]
+		doc, resp, err := fetchDoc(client, next.String(), r)
+		if err != nil {
+			return pagedDocs{}, err
+		}
+		pages.docs = append(pages.docs, doc)
+		pages.bytes += resp.ContentLength
+		queue = append(queue, paginationLinks(doc, base, seen)...)
+	}
+	return pages, nil
+}
+
+// Returns the links in doc's pagination controls to pages of the listing at
+// base that are not in seen yet, in the order they appear, and adds them to
+// seen. The link of the active page is doc's own address, such as "?page=1"
+// for a first page fetched without it, so it is only marked as seen.
+func paginationLinks(doc *goquery.Document, base *url.URL, seen map[string]bool) []*url.URL {
+	var links []*url.URL
+	doc.Find(".pagination a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		u, err := base.Parse(href)
+		if err != nil || u.Host != base.Host || u.Path != base.Path {
+			return
+		}
+		u.Fragment = ""
+		if seen[u.RequestURI()] {
+			return
+		}
+		seen[u.RequestURI()] = true
+		if a.ParentsFiltered(".active, .disabled").Length() == 0 {
+			links = append(links, u)
+		}
+	})
+	return links
+}
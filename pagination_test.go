@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Serves a schedule split over pages, each with one class and a pagination
+// control listing only the previous and next page, plus a link elsewhere
+// that must not be followed.
+func mockPagedSIX(t *testing.T, pages int) (*httptest.Server, *int) {
+	t.Helper()
+	hits := 0
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		n = max(n, 1)
+		fmt.Fprintf(w, `<html><body><table class="table"><tbody>
+<tr><td>1</td><td></td><td>IF22%02d</td><td>Kelas %d</td><td>3</td><td>01</td><td>40</td><td></td><td></td><td></td></tr>
+</tbody></table><ul class="pagination">`, n, n)
+		fmt.Fprintf(w, `<li class="page-item active"><a href="?page=%d">%d</a></li>`, n, n)
+		if n > 1 {
+			fmt.Fprintf(w, `<li><a href="?page=%d">&laquo;</a></li>`, n-1)
+		}
+		if n < pages {
+			fmt.Fprintf(w, `<li><a href="?page=%d">%d</a></li><li><a href="?page=%d">&raquo;</a></li>`, n+1, n+1, n+1)
+		}
+		fmt.Fprint(w, `</ul><a class="pagination" href="/logout">Keluar</a></body></html>`)
+	}))
+	t.Cleanup(mock.Close)
+	return mock, &hits
+}
+
+func TestSchedule_FetchesAllPages(t *testing.T) {
+	mock, hits := mockPagedSIX(t, 4)
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2024-2", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if classes := decodeData[[]CourseClass](t, w); len(classes) != 4 {
+		t.Errorf("got %d classes, want one per page", len(classes))
+	}
+	if meta := decodeMeta(t, w); meta.PagesFetched != 4 || meta.PagesTruncated {
+		t.Errorf("meta = %+v", meta)
+	}
+	if *hits != 4 {
+		t.Errorf("upstream hits = %d, want 4", *hits)
+	}
+}
+
+func TestSchedule_StopsAtMaxPages(t *testing.T) {
+	old := maxUpstreamPages
+	maxUpstreamPages = 2
+	t.Cleanup(func() { maxUpstreamPages = old })
+	mock, hits := mockPagedSIX(t, 5)
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2024-2", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if meta := decodeMeta(t, w); meta.PagesFetched != 2 || !meta.PagesTruncated {
+		t.Errorf("meta = %+v", meta)
+	}
+	if *hits != 2 {
+		t.Errorf("upstream hits = %d, want 2", *hits)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+	"six-scraper-go/textnorm"
+)
+
+// Bump these whenever the parser changes what it extracts, like
+// parserVersion for parseClasses.
+const (
+	homeParserVersion       = 1
+	transcriptParserVersion = 2
+	curriculumParserVersion = 2
+	gradesParserVersion     = 1
+	examsParserVersion      = 1
+	frsParserVersion        = 1
+	profileParserVersion    = 1
+	calendarParserVersion   = 1
+	paymentsParserVersion   = 1
+)
+
+// Registered parsers.
+const (
+	parserHome       = "home"
+	parserSchedule   = "schedule"
+	parserTranscript = "transcript"
+	parserCurriculum = "curriculum"
+	parserGrades     = "grades"
+	parserExams      = "exams"
+	parserFRS        = "frs"
+	parserProfile    = "profile"
+	parserCalendar   = "calendar"
+	parserPayments   = "payments"
+)
+
+// Something on a page that a parser needs in order to work.
+type layoutMarker struct {
+	name  string
+	match func(doc *goquery.Document) bool
+}
+
+func selectorMarker(name, selector string) layoutMarker {
+	return layoutMarker{name, func(doc *goquery.Document) bool { return doc.Find(selector).Length() > 0 }}
+}
+
+// Matches pages with a table whose header has every one of columns, as
+// headerColumns reads it. Each column lists the names it may go by.
+func headerMarker(name string, columns ...[]string) layoutMarker {
+	return layoutMarker{name, func(doc *goquery.Document) bool {
+		found := false
+		doc.Find("table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+			cols := scraper.HeaderColumns(table)
+			for _, alternatives := range columns {
+				has := false
+				for _, c := range alternatives {
+					_, ok := cols[c]
+					has = has || ok
+				}
+				if !has {
+					return true
+				}
+			}
+			found = true
+			return false
+		})
+		return found
+	}}
+}
+
+var parserMarkers = map[string][]layoutMarker{
+	parserHome: {selectorMarker("student_link", "a[href*='mahasiswa:']")},
+	parserSchedule: {
+		selectorMarker("class_table", "table.table tbody tr"),
+		{"ten_columns", func(doc *goquery.Document) bool {
+			found := false
+			doc.Find("table.table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+				for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td, th") {
+					found = found || len(cells) >= 10
+				}
+				return !found
+			})
+			return found
+		}},
+		selectorMarker("meeting_list", "table.table tbody tr "+scraper.LeafItem),
+	},
+	parserTranscript: {
+		headerMarker("kode_sks_nilai_header", []string{"kode"}, []string{"sks"}, []string{"nilai"}),
+		headerMarker("nama_header", []string{"nama"}),
+		headerMarker("semester_header", []string{"semester"}),
+	},
+	parserCurriculum: {
+		headerMarker("kode_sks_sifat_header", []string{"kode"}, []string{"sks"}, []string{"sifat", "jenis"}),
+		headerMarker("prasyarat_header", []string{"prasyarat"}),
+	},
+	parserGrades: {
+		headerMarker("kode_indeks_header", []string{"kode"}, []string{"indeks", "nilai"}),
+		headerMarker("kelas_header", []string{"kelas"}),
+		headerMarker("status_header", []string{"status"}),
+	},
+	parserExams: {
+		{"kode_tanggal_header", func(doc *goquery.Document) bool {
+			found := false
+			doc.Find("table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+				cols := examColumns(table)
+				_, hasCode := cols["kode"]
+				_, hasDate := cols["tanggal"]
+				found = hasCode && hasDate
+				return !found
+			})
+			return found
+		}},
+		headerMarker("ruang_header", []string{"ruang", "ruangan"}),
+	},
+	parserFRS: {
+		headerMarker("kode_sks_header", []string{"kode"}, []string{"sks"}),
+		{"dosen_wali_label", func(doc *goquery.Document) bool { return labeledField(doc, frsAdvisorLabels) != "" }},
+		{"status_label", func(doc *goquery.Document) bool { return labeledField(doc, frsStatusLabels) != "" }},
+	},
+	parserProfile: {
+		{"nim_label", func(doc *goquery.Document) bool { return labeledField(doc, profileNIMLabels) != "" }},
+		{"nama_label", func(doc *goquery.Document) bool { return labeledField(doc, profileNameLabels) != "" }},
+		{"prodi_label", func(doc *goquery.Document) bool { return labeledField(doc, profileProgramLabels) != "" }},
+	},
+	parserCalendar: {
+		{"kegiatan_tanggal_header", func(doc *goquery.Document) bool {
+			found := false
+			doc.Find("table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+				cols := wordColumns(table, calendarHeaderWords)
+				_, hasTitle := cols["kegiatan"]
+				_, hasDate := cols["tanggal"]
+				found = hasTitle && hasDate
+				return !found
+			})
+			return found
+		}},
+		{"semester_heading", func(doc *goquery.Document) bool {
+			found := false
+			doc.Find("table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+				found = headingSemester(scraper.TableHeading(table)) != ""
+				return !found
+			})
+			return found
+		}},
+	},
+	parserPayments: {
+		{"semester_jumlah_header", func(doc *goquery.Document) bool {
+			found := false
+			doc.Find("table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+				cols := wordColumns(table, paymentHeaderWords)
+				_, hasSemester := cols["semester"]
+				_, hasAmount := cols["jumlah"]
+				found = hasSemester && hasAmount
+				return !found
+			})
+			return found
+		}},
+		headerMarker("status_header", []string{"status"}),
+	},
+}
+
+type ParserStatus struct {
+	Name          string         `json:"name"`
+	Version       int            `json:"version"`
+	Uses          int64          `json:"uses"`
+	Failures      int64          `json:"failures"`
+	LastUsedAt    *time.Time     `json:"last_used_at"`
+	LastFailureAt *time.Time     `json:"last_failure_at"`
+	Markers       []MarkerStatus `json:"markers"`
+	// Shadow is set for the schedule parser while a candidate version runs
+	// in shadow mode.
+	Shadow *ShadowStatus `json:"shadow,omitempty"`
+}
+
+type MarkerStatus struct {
+	Name          string     `json:"name"`
+	MatchedLast   bool       `json:"matched_last"` // on the last page parsed
+	Pages         int64      `json:"pages"`        // pages parsed that had it
+	LastMatchedAt *time.Time `json:"last_matched_at"`
+}
+
+type parserRegistry struct {
+	mu      sync.Mutex
+	parsers []*ParserStatus // in registration order
+	markers map[string][]layoutMarker
+}
+
+func newParserRegistry(markers map[string][]layoutMarker, versions map[string]int, order ...string) *parserRegistry {
+	pr := &parserRegistry{markers: markers}
+	for _, name := range order {
+		st := &ParserStatus{Name: name, Version: versions[name], Markers: []MarkerStatus{}}
+		for _, m := range markers[name] {
+			st.Markers = append(st.Markers, MarkerStatus{Name: m.name})
+		}
+		pr.parsers = append(pr.parsers, st)
+	}
+	return pr
+}
+
+// Every page SIX parser is registered here with the layout markers it relies
+// on. Each parse records which markers the page had and whether the parse
+// failed, so when SIX rolls out a new template to some pages but not others,
+// GET /api/admin/parsers shows which parser broke and what the pages are
+// missing.
+var parsers = newParserRegistry(parserMarkers,
+	map[string]int{
+		parserHome:       homeParserVersion,
+		parserSchedule:   parserVersion,
+		parserTranscript: transcriptParserVersion,
+		parserCurriculum: curriculumParserVersion,
+		parserGrades:     gradesParserVersion,
+		parserExams:      examsParserVersion,
+		parserFRS:        frsParserVersion,
+		parserProfile:    profileParserVersion,
+		parserCalendar:   calendarParserVersion,
+		parserPayments:   paymentsParserVersion,
+	},
+	parserHome, parserSchedule, parserTranscript, parserCurriculum, parserGrades, parserExams, parserFRS, parserProfile, parserCalendar, parserPayments)
+
+// Records that parser name parsed doc, and whether it failed.
+func (pr *parserRegistry) observe(name string, doc *goquery.Document, failed bool, now time.Time) {
+	markers := pr.markers[name]
+	matched := make([]bool, len(markers))
+	for i, m := range markers {
+		matched[i] = m.match(doc)
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	for _, st := range pr.parsers {
+		if st.Name != name {
+			continue
+		}
+		st.Uses++
+		st.LastUsedAt = &now
+		if failed {
+			st.Failures++
+			st.LastFailureAt = &now
+		}
+		for i := range st.Markers {
+			st.Markers[i].MatchedLast = matched[i]
+			if matched[i] {
+				st.Markers[i].Pages++
+				st.Markers[i].LastMatchedAt = &now
+			}
+		}
+	}
+}
+
+func (pr *parserRegistry) status() []ParserStatus {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	out := make([]ParserStatus, len(pr.parsers))
+	for i, st := range pr.parsers {
+		out[i] = *st
+		out[i].Markers = append([]MarkerStatus{}, st.Markers...)
+	}
+	return out
+}
+
+// Whether doc has table rows, which a parser that returned nothing should
+// have turned into records.
+func hasTableRows(doc *goquery.Document) bool {
+	return doc.Find("table tbody tr").Length() > 0
+}
+
+// GET /api/admin/parsers
+func parsersHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	status := parsers.status()
+	for i := range status {
+		if status[i].Name == parserSchedule {
+			status[i].Version = scheduleShadow.servedVersion()
+			status[i].Shadow = scheduleShadow.status()
+		}
+	}
+	writeSuccess(w, status)
+}
+
+// Returns the value of the first of labels, in textnorm.Key form, found on
+// the page, or "". A label is a cell or term whose text, less a trailing
+// colon, is the label, with the value in the next cell or definition; or
+// the start of a line of text such as "Dosen Wali: Dr. Budi".
+func labeledField(doc *goquery.Document, labels []string) string {
+	for _, label := range labels {
+		value, found := "", false
+		doc.Find("th, td, dt, label, strong, b").EachWithBreak(func(_ int, el *goquery.Selection) bool {
+			key := strings.TrimSpace(strings.TrimSuffix(textnorm.Key(el.Text()), ":"))
+			if key == label {
+				if next := el.Next(); next.Length() > 0 {
+					value, found = labelValue(next.Text()), true
+				} else if _, after, ok := strings.Cut(el.Parent().Text(), el.Text()); ok {
+					value, found = labelValue(after), true
+				}
+				return !found
+			}
+			if rest, ok := strings.CutPrefix(key, label); ok && strings.HasPrefix(strings.TrimSpace(rest), ":") {
+				_, after, _ := strings.Cut(el.Text(), ":")
+				value, found = labelValue(after), true
+			}
+			return !found
+		})
+		if found {
+			return value
+		}
+	}
+	return ""
+}
+
+// Trims a value and the colon that may come before it.
+func labelValue(text string) string {
+	text = strings.TrimPrefix(strings.TrimSpace(text), ":")
+	return strings.Join(strings.Fields(text), " ")
+}
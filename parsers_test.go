@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParserRegistry_Observe(t *testing.T) {
+	pr := newParserRegistry(parserMarkers, map[string]int{parserSchedule: parserVersion}, parserSchedule, parserTranscript)
+	now := time.Date(2025, 2, 3, 8, 0, 0, 0, time.UTC)
+	good := docFromHTML(testScheduleHTML)
+	pr.observe(parserSchedule, good, false, now)
+	// A partial rollout: the table is there, but with fewer columns.
+	short := docFromHTML(`<table class="table"><tbody><tr><td>IF2211</td><td>Stima</td></tr></tbody></table>`)
+	pr.observe(parserSchedule, short, true, now.Add(time.Minute))
+
+	st := pr.status()
+	if len(st) != 2 || st[0].Name != parserSchedule || st[0].Version != parserVersion {
+		t.Fatalf("status = %+v", st)
+	}
+	sched := st[0]
+	if sched.Uses != 2 || sched.Failures != 1 || !sched.LastFailureAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("schedule = %+v", sched)
+	}
+	markers := map[string]MarkerStatus{}
+	for _, m := range sched.Markers {
+		markers[m.Name] = m
+	}
+	if m := markers["class_table"]; m.Pages != 2 || !m.MatchedLast {
+		t.Errorf("class_table = %+v", m)
+	}
+	if m := markers["ten_columns"]; m.Pages != 1 || m.MatchedLast || !m.LastMatchedAt.Equal(now) {
+		t.Errorf("ten_columns = %+v", m)
+	}
+
+	if tr := st[1]; tr.Uses != 0 || tr.LastUsedAt != nil || len(tr.Markers) != 3 {
+		t.Errorf("unused transcript parser = %+v", tr)
+	}
+}
+
+func TestHeaderMarker(t *testing.T) {
+	m := headerMarker("kode_sks_sifat_header", []string{"kode"}, []string{"sks"}, []string{"sifat", "jenis"})
+	if !m.match(docFromHTML(testCurriculumHTML)) {
+		t.Error("curriculum fixture does not match its header marker")
+	}
+	if m.match(docFromHTML(testTranscriptHTML)) {
+		t.Error("transcript matches the curriculum header marker")
+	}
+}
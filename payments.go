@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+	"six-scraper-go/textnorm"
+)
+
+// SIX page with a student's tuition (UKT, uang kuliah tunggal) and other
+// payments, one row per bill.
+func paymentsPath(studentID string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s/keuangan/pembayaran", studentID)
+}
+
+// One bill and whether it was paid.
+type Payment struct {
+	// Semester is YYYY-N when SIX writes it as, e.g., "2025/2026 Ganjil",
+	// and as shown otherwise.
+	Semester    string `json:"semester"`
+	Description string `json:"description"` // e.g. "UKT", empty if SIX shows none
+	Amount      int64  `json:"amount"`      // in rupiah
+	// DueDate and PaidAt are YYYY-MM-DD when SIX shows a date, and as shown
+	// otherwise. PaidAt is empty while the bill is unpaid.
+	DueDate string `json:"due_date"`
+	PaidAt  string `json:"paid_at"`
+	// Status is passed through as SIX shows it, e.g. "Lunas".
+	Status string `json:"status"`
+	Paid   bool   `json:"paid"`
+}
+
+// Header words of each payment column, as for examHeaderWords. The due date
+// comes before the payment date, since "Tanggal Jatuh Tempo" is a due date,
+// and the amount before it, since "Jumlah Pembayaran" is an amount.
+var paymentHeaderWords = headerWords{
+	{"semester", []string{"semester", "periode", "tahun"}},
+	{"jatuh_tempo", []string{"tempo", "batas", "tenggat"}},
+	{"status", []string{"status"}},
+	{"jenis", []string{"jenis", "keterangan", "uraian", "komponen"}},
+	{"jumlah", []string{"jumlah", "nominal", "tagihan", "besar", "biaya", "total"}},
+	{"dibayar", []string{"bayar", "dibayar", "pembayaran", "lunas"}},
+}
+
+// Parses the payments page: tables with a semester and an amount column,
+// found by their header words. A row whose semester has no year, such as a
+// "Total" footer, is skipped.
+func parsePayments(doc *goquery.Document) []Payment {
+	var payments []Payment
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := wordColumns(table, paymentHeaderWords)
+		semester, hasSemester := cols["semester"]
+		amount, hasAmount := cols["jumlah"]
+		if !hasSemester || !hasAmount {
+			return
+		}
+		cell := func(cells []*goquery.Selection, col string) string {
+			if i, ok := cols[col]; ok {
+				return scraper.CellText(cells, i)
+			}
+			return ""
+		}
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			text := scraper.CellText(cells, semester)
+			if !strings.ContainsAny(text, "0123456789") {
+				continue
+			}
+			p := Payment{
+				Semester:    paymentSemester(text),
+				Description: cell(cells, "jenis"),
+				Status:      cell(cells, "status"),
+			}
+			p.Amount, _ = parseRupiah(scraper.CellText(cells, amount))
+			if due := cell(cells, "jatuh_tempo"); due != "" {
+				p.DueDate = examDate(due)
+			}
+			if paidAt := cell(cells, "dibayar"); paidAt != "" && paidAt != "-" {
+				p.PaidAt = examDate(paidAt)
+			}
+			p.Paid = paymentPaid(p.Status, p.PaidAt)
+			if !p.Paid {
+				p.PaidAt = ""
+			}
+			payments = append(payments, p)
+		}
+	})
+	return payments
+}
+
+// Returns a semester SIX writes as "2025-1", "2025/2026 Ganjil", or
+// "Semester I 2025/2026" as YYYY-N, or text as it is otherwise.
+func paymentSemester(text string) string {
+	if semesterParamRe.MatchString(text) {
+		return text
+	}
+	if semester := headingSemester(text); semester != "" {
+		return semester
+	}
+	return text
+}
+
+// Reports whether a bill is paid: its status says so, as "Lunas" or "Sudah
+// dibayar" do but "Belum lunas" does not, or, without a status, it has a
+// payment date.
+func paymentPaid(status, paidAt string) bool {
+	key := textnorm.Key(status)
+	if key == "" {
+		_, err := time.Parse(time.DateOnly, paidAt)
+		return err == nil
+	}
+	if strings.Contains(key, "belum") || strings.Contains(key, "tidak") || strings.Contains(key, "gagal") || strings.Contains(key, "unpaid") {
+		return false
+	}
+	return strings.Contains(key, "lunas") || strings.Contains(key, "dibayar") || strings.Contains(key, "terbayar") || strings.Contains(key, "paid")
+}
+
+// Cents at the end of an amount, as in "12.500.000,00" or "12,500,000.00".
+var rupiahCentsRe = regexp.MustCompile(`[.,]\d{2}$`)
+
+// Returns the whole rupiah in an amount SIX shows as "Rp 12.500.000",
+// "Rp12.500.000,00", or "12500000".
+func parseRupiah(text string) (int64, bool) {
+	s := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r == '.' || r == ',' {
+			return r
+		}
+		return -1
+	}, text)
+	// Thousands are grouped in threes, so two trailing digits are cents.
+	s = rupiahCentsRe.ReplaceAllString(strings.Trim(s, ".,"), "")
+	n, err := strconv.ParseInt(strings.NewReplacer(".", "", ",", "").Replace(s), 10, 64)
+	return n, err == nil
+}
+
+// GET /api/payments?student_id=...
+func (s *Server) paymentsHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	studentID := r.URL.Query().Get("student_id")
+	payments, err := s.provider.FetchPayments(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	if payments == nil {
+		payments = []Payment{}
+	}
+	unpaid := 0
+	for _, p := range payments {
+		if !p.Paid {
+			unpaid++
+		}
+	}
+	log.Printf("parsed payments records=%d unpaid=%d student_id=%s", len(payments), unpaid, studentID)
+	writeSuccessWithMeta(w, payments, &Meta{FetchedAt: time.Now()})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testPaymentsHTML = `<html><body>
+<h3>Riwayat Pembayaran</h3>
+<table class="table">
+  <thead><tr><th>No</th><th>Tahun Akademik</th><th>Jenis Tagihan</th><th>Jumlah Tagihan</th><th>Tanggal Jatuh Tempo</th><th>Tanggal Bayar</th><th>Status Pembayaran</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>2025/2026 Genap</td><td>UKT</td><td>Rp 12.500.000,00</td><td>16 Januari 2026</td><td>-</td><td>Belum Lunas</td></tr>
+    <tr><td>2</td><td>2025/2026 Ganjil</td><td>UKT</td><td>Rp12.500.000</td><td>15/08/2025</td><td>Senin, 4 Agustus 2025</td><td>Lunas</td></tr>
+    <tr><td>3</td><td>2024-3</td><td>Semester Pendek</td><td>1.500.000</td><td>2025-06-20</td><td></td><td>Menunggu pembayaran</td></tr>
+    <tr><td colspan="3">Total</td><td>Rp 26.500.000</td><td colspan="3"></td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+func TestParsePayments(t *testing.T) {
+	got := parsePayments(docFromHTML(testPaymentsHTML))
+	want := []Payment{
+		{Semester: "2025-2", Description: "UKT", Amount: 12500000, DueDate: "2026-01-16", Status: "Belum Lunas"},
+		{Semester: "2025-1", Description: "UKT", Amount: 12500000, DueDate: "2025-08-15", PaidAt: "2025-08-04", Status: "Lunas", Paid: true},
+		{Semester: "2024-3", Description: "Semester Pendek", Amount: 1500000, DueDate: "2025-06-20", Status: "Menunggu pembayaran"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	// Without a status column, a payment date means paid.
+	doc := docFromHTML(`<table><thead><tr><th>Semester</th><th>Nominal</th><th>Tanggal Pembayaran</th></tr></thead><tbody>
+<tr><td>Semester I 2025/2026</td><td>12500000</td><td>2025-08-04</td></tr>
+<tr><td>Semester II 2025/2026</td><td>12500000</td><td></td></tr>
+</tbody></table>`)
+	if got := parsePayments(doc); len(got) != 2 || !got[0].Paid || got[1].Paid || got[1].Semester != "2025-2" {
+		t.Errorf("without status: got %+v", got)
+	}
+}
+
+func TestParseRupiah(t *testing.T) {
+	for text, want := range map[string]int64{
+		"Rp 12.500.000":     12500000,
+		"Rp12.500.000,00":   12500000,
+		"IDR 12,500,000.00": 12500000,
+		"2.400.000,-":       2400000,
+		"750000":            750000,
+	} {
+		if got, ok := parseRupiah(text); !ok || got != want {
+			t.Errorf("%q: got %d, %t, want %d", text, got, ok, want)
+		}
+	}
+	if _, ok := parseRupiah("Gratis"); ok {
+		t.Error(`"Gratis" parsed as an amount`)
+	}
+}
+
+func TestPaymentPaid(t *testing.T) {
+	for _, tc := range []struct {
+		status, paidAt string
+		want           bool
+	}{
+		{"Lunas", "", true},
+		{"Sudah dibayar", "", true},
+		{"Belum lunas", "", false},
+		{"Belum dibayar", "2025-08-04", false},
+		{"Menunggu pembayaran", "", false},
+		{"", "2025-08-04", true},
+		{"", "-", false},
+	} {
+		if got := paymentPaid(tc.status, tc.paidAt); got != tc.want {
+			t.Errorf("%q, %q: got %t", tc.status, tc.paidAt, got)
+		}
+	}
+}
+
+func TestPaymentsHandler(t *testing.T) {
+	var cookie string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != paymentsPath("123") {
+			http.NotFound(w, r)
+			return
+		}
+		cookie = r.Header.Get("Cookie")
+		fmt.Fprint(w, testPaymentsHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/payments?student_id=123", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if payments := decodeData[[]Payment](t, w); len(payments) != 3 || payments[0].Amount != 12500000 || payments[0].Paid {
+		t.Errorf("payments = %+v", payments)
+	}
+	if cookie == "" {
+		t.Error("SIX cookies were not forwarded")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A politeness profile bundles the settings that decide how hard the server
+// leans on SIX. SIX_POLITENESS picks one, and the individual variables
+// (SIX_UPSTREAM_CONCURRENCY, SIX_CACHE_TTL, ...) still override single
+// values of it.
+type politenessProfile struct {
+	Name string
+	// Minimum time between the starts of two batch fetches. Interactive
+	// fetches, which a user is waiting on, are never delayed.
+	BatchDelay  time.Duration
+	Concurrency int
+	BatchWeight int
+	CacheTTL    time.Duration
+	CatalogTTL  time.Duration
+	// Retries of a fetch that failed with a network error, 502, or 504.
+	// 503 is SIX maintenance, which has its own back-off.
+	Retries    int
+	RetryDelay time.Duration // doubled after each retry
+}
+
+const defaultPoliteness = "gentle"
+
+var politenessProfiles = map[string]politenessProfile{
+	"gentle": {
+		Name:        "gentle",
+		BatchDelay:  time.Second,
+		Concurrency: 4,
+		BatchWeight: 4,
+		CacheTTL:    10 * time.Minute,
+		CatalogTTL:  6 * time.Hour,
+		Retries:     1,
+		RetryDelay:  5 * time.Second,
+	},
+	"normal": {
+		Name:        "normal",
+		Concurrency: 8,
+		BatchWeight: 4,
+		CacheTTL:    5 * time.Minute,
+		CatalogTTL:  time.Hour,
+		Retries:     1,
+		RetryDelay:  time.Second,
+	},
+	"aggressive": {
+		Name:        "aggressive",
+		Concurrency: 16,
+		BatchWeight: 8,
+		CacheTTL:    2 * time.Minute,
+		CatalogTTL:  30 * time.Minute,
+		Retries:     2,
+		RetryDelay:  500 * time.Millisecond,
+	},
+}
+
+// Returns the profile named name, falling back to the default profile for
+// an unknown name.
+func lookupPoliteness(name string) politenessProfile {
+	if p, ok := politenessProfiles[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return p
+	}
+	log.Printf("config: unknown SIX_POLITENESS=%q, using %s", name, defaultPoliteness)
+	return politenessProfiles[defaultPoliteness]
+}
+
+// The active profile with the individual overrides applied.
+var politeness = func() politenessProfile {
+	p := lookupPoliteness(envString("SIX_POLITENESS", defaultPoliteness))
+	p.BatchDelay = envDuration("SIX_BATCH_DELAY", p.BatchDelay)
+	p.Concurrency = envInt("SIX_UPSTREAM_CONCURRENCY", p.Concurrency)
+	p.BatchWeight = envInt("SIX_UPSTREAM_BATCH_WEIGHT", p.BatchWeight)
+	p.CacheTTL = envDuration("SIX_CACHE_TTL", p.CacheTTL)
+	p.CatalogTTL = envDuration("SIX_CATALOG_TTL", p.CatalogTTL)
+	p.Retries = envInt("SIX_UPSTREAM_RETRIES", p.Retries)
+	p.RetryDelay = envDuration("SIX_UPSTREAM_RETRY_DELAY", p.RetryDelay)
+	return p
+}()
+
+// Spaces out the starts of batch fetches by politeness.BatchDelay.
+type batchPacer struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+var pacer batchPacer
+
+// Blocks until the next batch fetch may start, or ctx is done.
+func (p *batchPacer) wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	now := time.Now()
+	start := now
+	if p.next.After(now) {
+		start = p.next
+	}
+	p.next = start.Add(delay)
+	p.mu.Unlock()
+
+	if start.Equal(now) {
+		return nil
+	}
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reports whether a fetch that ended with resp and err is worth retrying.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !isUpstreamTLSError(err)
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The suite runs with the normal profile and no retries, so tests neither
+// wait on the gentle pacing nor see extra upstream hits.
+func TestMain(m *testing.M) {
+	politeness = politenessProfiles["normal"]
+	politeness.Retries = 0
+	upstream = newUpstreamQueue(politeness.Concurrency, politeness.BatchWeight)
+	os.Exit(m.Run())
+}
+
+func setPoliteness(t *testing.T, p politenessProfile) {
+	t.Helper()
+	old := politeness
+	politeness = p
+	t.Cleanup(func() { politeness = old })
+}
+
+func TestLookupPoliteness(t *testing.T) {
+	if got := lookupPoliteness(" Aggressive "); got.Name != "aggressive" {
+		t.Errorf("lookupPoliteness(Aggressive) = %s", got.Name)
+	}
+	if got := lookupPoliteness("reckless"); got.Name != defaultPoliteness {
+		t.Errorf("unknown profile = %s, want %s", got.Name, defaultPoliteness)
+	}
+	// The default must be the most conservative profile.
+	gentle := politenessProfiles[defaultPoliteness]
+	for name, p := range politenessProfiles {
+		if p.Concurrency < gentle.Concurrency || p.BatchDelay > gentle.BatchDelay || p.CacheTTL > gentle.CacheTTL {
+			t.Errorf("%s is gentler than %s", name, defaultPoliteness)
+		}
+	}
+}
+
+func TestBatchPacer(t *testing.T) {
+	var p batchPacer
+	start := time.Now()
+	for range 3 {
+		if err := p.wait(t.Context(), 20*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("three paced starts took %s, want at least 40ms", elapsed)
+	}
+}
+
+func TestDoUpstream_Retries(t *testing.T) {
+	p := politenessProfiles["normal"]
+	p.Retries, p.RetryDelay = 2, time.Millisecond
+	setPoliteness(t, p)
+
+	var hits, status atomic.Int32
+	status.Store(http.StatusBadGateway)
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(int(status.Load()))
+		}
+	}))
+	defer mock.Close()
+
+	req, _ := http.NewRequestWithContext(t.Context(), "GET", mock.URL, nil)
+	resp, err := doUpstream(mock.Client(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 3 {
+		t.Errorf("got status %d after %d hits, want 200 after 3", resp.StatusCode, hits.Load())
+	}
+
+	// 503 is SIX maintenance and is left to the maintenance back-off.
+	hits.Store(0)
+	status.Store(http.StatusServiceUnavailable)
+	resp, err = doUpstream(mock.Client(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || hits.Load() != 1 {
+		t.Errorf("got status %d after %d hits, want 503 after 1", resp.StatusCode, hits.Load())
+	}
+}
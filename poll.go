@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// Watchers poll adaptively. A watch whose values stay the same is polled
+// less and less often, up to SIX_POLL_MAX_BACKOFF times its interval, and is
+// back to its interval as soon as something changes. Catalog pages are also
+// polled frsDeadlineSpeedup times as often in the last SIX_FRS_DEADLINE_WINDOW
+// of an FRS period, when seats change hands fastest.
+var (
+	pollMaxBackoff    = envInt("SIX_POLL_MAX_BACKOFF", 4)
+	frsDeadlineWindow = envDuration("SIX_FRS_DEADLINE_WINDOW", 48*time.Hour)
+)
+
+const frsDeadlineSpeedup = 4
+
+// Back-off state of one watch.
+type pollBackoff struct {
+	stable int // polls in a row that saw no change
+}
+
+// Records whether a poll saw a change.
+func (b *pollBackoff) observe(changed bool) {
+	if changed {
+		b.stable = 0
+	} else {
+		b.stable++
+	}
+}
+
+// Returns interval doubled for each stable poll, up to pollMaxBackoff times.
+func (b pollBackoff) stretch(interval time.Duration) time.Duration {
+	factor := 1
+	for i := 0; i < b.stable && factor*2 <= pollMaxBackoff; i++ {
+		factor *= 2
+	}
+	return interval * time.Duration(factor)
+}
+
+// Reports whether now falls in the last frsDeadlineWindow of an FRS period.
+func nearFRSDeadline(periods []dateRange, now time.Time) bool {
+	for _, p := range periods {
+		if p.contains(now) && p.end.Sub(now) <= frsDeadlineWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// Sums up the quota and enrolled count of every class, so a poll can tell
+// whether any of them changed.
+func seatsFingerprint(classes []CourseClass) [sha256.Size]byte {
+	h := sha256.New()
+	for _, c := range classes {
+		enrolled := -1
+		if c.Enrolled != nil {
+			enrolled = *c.Enrolled
+		}
+		fmt.Fprintf(h, "%s/%s %d/%d\n", c.Code, c.ClassNo, enrolled, c.Quota)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
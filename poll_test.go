@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollBackoff_Stretch(t *testing.T) {
+	var b pollBackoff
+	want := []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour, 4 * time.Hour}
+	for i, w := range want {
+		if got := b.stretch(time.Hour); got != w {
+			t.Errorf("after %d stable polls: got %s, want %s", i, got, w)
+		}
+		b.observe(false)
+	}
+	b.observe(true)
+	if got := b.stretch(time.Hour); got != time.Hour {
+		t.Errorf("after a change: got %s, want 1h", got)
+	}
+}
+
+func TestNearFRSDeadline(t *testing.T) {
+	periods := parseFRSPeriods("2026-01-05..2026-01-16")
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 1, 10, 12, 0, 0, 0, wib), false},
+		{time.Date(2026, 1, 15, 0, 0, 0, 0, wib), true}, // 48 hours before the period ends
+		{time.Date(2026, 1, 16, 23, 0, 0, 0, wib), true},
+		{time.Date(2026, 1, 17, 0, 0, 0, 0, wib), false},
+	}
+	for _, tt := range tests {
+		if got := nearFRSDeadline(periods, tt.at); got != tt.want {
+			t.Errorf("at %s: got %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestWarmDue_AdaptiveInterval(t *testing.T) {
+	hits, enrolled := 0, 10
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, strings.Replace(testScheduleHTML, "<td>45</td>", fmt.Sprintf("<td>%d/45</td>", enrolled), 1))
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	srv.warmer = newCatalogWarmer(parseWarmSchedules("FTMD=4h"), parseFRSPeriods("2026-01-05..2026-01-16"), nil)
+	auth := http.Header{"Cookie": {"nissin=a; khongguan=b"}}
+
+	// The seats stay the same, so the interval doubles after each run.
+	start := time.Date(2026, 1, 2, 8, 0, 0, 0, wib)
+	for _, h := range []int{0, 4, 8, 12, 16, 20, 24} {
+		srv.warmDue(t.Context(), auth, "123", start.Add(time.Duration(h)*time.Hour))
+	}
+	if hits != 3 { // at 0, 4, and 12 hours
+		t.Fatalf("stable page: %d hits, want 3", hits)
+	}
+
+	// A change brings the interval back to the cadence.
+	enrolled = 11
+	srv.warmDue(t.Context(), auth, "123", start.Add(28*time.Hour)) // 16 hours after the last run
+	srv.warmDue(t.Context(), auth, "123", start.Add(32*time.Hour))
+	if hits != 5 {
+		t.Fatalf("after a change: %d hits, want 5", hits)
+	}
+
+	// Near the end of FRS the cadence is sped up regardless of the back-off.
+	end := time.Date(2026, 1, 16, 8, 0, 0, 0, wib)
+	srv.warmDue(t.Context(), auth, "123", end)
+	srv.warmDue(t.Context(), auth, "123", end.Add(time.Hour))
+	if hits != 7 {
+		t.Errorf("near the FRS deadline: %d hits, want 7", hits)
+	}
+	if v := srv.warmer.jobViews(true, end.Add(time.Hour))[0]; v.Interval != "1h0m0s" {
+		t.Errorf("interval = %s, want 1h0m0s", v.Interval)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The pekan prefetcher remembers schedule requests that used a numeric pekan
+// filter during the week and, on Sunday night, fetches the same queries with
+// pekan advanced by one so Monday morning requests hit a warm cache. It keeps
+// the requester's SIX cookies in memory until the run, so it is opt-in.
+var (
+	prefetchEnabled = envBool("SIX_PREFETCH_PEKAN", false)
+	prefetchMax     = envInt("SIX_PREFETCH_MAX", 200)
+)
+
+type prefetchCandidate struct {
+	studentID string
+	semester  string
+	query     url.Values
+	auth      http.Header // Cookie and X-Six-* headers of the original request
+}
+
+var (
+	prefetchMu         sync.Mutex
+	prefetchCandidates = make(map[string]prefetchCandidate)
+)
+
+// Remembers a schedule request for the next prefetch run if it used a numeric pekan.
+// key is the schedulePath of the request.
+func recordPrefetchCandidate(r *http.Request, key, studentID, semester string) {
+	if !prefetchEnabled {
+		return
+	}
+	query := r.URL.Query()
+	if _, err := strconv.Atoi(query.Get("pekan")); err != nil {
+		return
+	}
+
+	auth := sixAuthHeaders(r)
+	prefetchMu.Lock()
+	defer prefetchMu.Unlock()
+	if _, ok := prefetchCandidates[key]; !ok && len(prefetchCandidates) >= prefetchMax {
+		return
+	}
+	prefetchCandidates[key] = prefetchCandidate{studentID: studentID, semester: semester, query: query, auth: auth}
+}
+
+// Returns the next Sunday 22:00 WIB strictly after now.
+func nextPrefetchTime(now time.Time) time.Time {
+	local := now.In(wib)
+	y, m, d := local.Date()
+	days := (int(time.Sunday) - int(local.Weekday()) + 7) % 7
+	next := time.Date(y, m, d+days, 22, 0, 0, 0, wib)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// Prefetched pages are kept until Monday morning's rush is over, rather than
+// for the usual cache TTL, which would have them expire long before the
+// first Monday request. Their expiries are spread over prefetchHoldSpread
+// before that, so they are not all fetched again at once.
+const (
+	prefetchHoldHour   = 9 // WIB
+	prefetchHoldSpread = 30 * time.Minute
+)
+
+// Returns the next Monday prefetchHoldHour:00 WIB strictly after now.
+func prefetchHoldUntil(now time.Time) time.Time {
+	local := now.In(wib)
+	y, m, d := local.Date()
+	days := (int(time.Monday) - int(local.Weekday()) + 7) % 7
+	until := time.Date(y, m, d+days, prefetchHoldHour, 0, 0, 0, wib)
+	if !until.After(now) {
+		until = until.AddDate(0, 0, 7)
+	}
+	return until
+}
+
+// Runs prefetches every Sunday night until ctx is done.
+func (s *Server) runPrefetcher(ctx context.Context) {
+	for {
+		timer := time.NewTimer(time.Until(nextPrefetchTime(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if scrapingPaused() {
+				log.Printf("prefetch skipped: SIX maintenance")
+				continue
+			}
+			s.prefetchNextPekan(ctx)
+		}
+	}
+}
+
+// Fetches every candidate with pekan advanced by one and caches the result
+// until prefetchHoldUntil. Candidates are consumed by the run.
+func (s *Server) prefetchNextPekan(ctx context.Context) {
+	prefetchMu.Lock()
+	candidates := prefetchCandidates
+	prefetchCandidates = make(map[string]prefetchCandidate)
+	prefetchMu.Unlock()
+
+	ctx = withPriority(ctx, priorityBatch)
+	holdUntil := prefetchHoldUntil(time.Now())
+	fetched := 0
+	for _, c := range candidates {
+		if ctx.Err() != nil || scrapingPaused() {
+			return
+		}
+		pekan, _ := strconv.Atoi(c.query.Get("pekan"))
+		query := url.Values{}
+		for k, v := range c.query {
+			query[k] = v
+		}
+		query.Set("pekan", strconv.Itoa(pekan+1))
+
+		key := schedulePath(c.studentID, c.semester, query)
+		if _, ok := s.cache.get(key); ok {
+			continue
+		}
+
+		s.refresher.run(ctx, refreshPrefetch, s.upstreamHost(), key, func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, "GET", "/api/schedule", nil)
+			if err != nil {
+				return err
+			}
+			req.Header = c.auth.Clone()
+			if _, _, err := s.scrapeSchedule(req, c.studentID, c.semester, query); err != nil {
+				log.Printf("prefetch failed student_id=%s semester=%s pekan=%d err=%v", c.studentID, c.semester, pekan+1, err)
+				return err
+			}
+			if entry, ok := s.cache.peek(key); ok && entry.anomaly == nil {
+				spread := time.Duration(rand.Float64() * float64(prefetchHoldSpread))
+				s.cache.putUntil(key, entry, holdUntil.Add(-spread))
+			}
+			fetched++
+			return nil
+		})
+	}
+	log.Printf("prefetch done candidates=%d fetched=%d", len(candidates), fetched)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNextPrefetchTime(t *testing.T) {
+	tests := []struct {
+		now, want string
+	}{
+		{"2025-02-05T10:00:00+07:00", "2025-02-09T22:00:00+07:00"}, // Wednesday
+		{"2025-02-09T21:59:00+07:00", "2025-02-09T22:00:00+07:00"}, // Sunday, before the run
+		{"2025-02-09T22:00:00+07:00", "2025-02-16T22:00:00+07:00"}, // Sunday, at the run
+		{"2025-02-09T16:00:00Z", "2025-02-16T22:00:00+07:00"},      // Sunday 23:00 WIB
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		want, _ := time.Parse(time.RFC3339, tt.want)
+		if got := nextPrefetchTime(now); !got.Equal(want) {
+			t.Errorf("nextPrefetchTime(%s) = %s, want %s", tt.now, got, want)
+		}
+	}
+}
+
+func TestPrefetchHoldUntil(t *testing.T) {
+	for now, want := range map[string]string{
+		"2025-02-09T22:00:00+07:00": "2025-02-10T09:00:00+07:00", // the Sunday run
+		"2025-02-10T08:59:00+07:00": "2025-02-10T09:00:00+07:00",
+		"2025-02-10T09:00:00+07:00": "2025-02-17T09:00:00+07:00",
+		"2025-02-10T03:00:00Z":      "2025-02-17T09:00:00+07:00", // Monday 10:00 WIB
+	} {
+		n, _ := time.Parse(time.RFC3339, now)
+		w, _ := time.Parse(time.RFC3339, want)
+		if got := prefetchHoldUntil(n); !got.Equal(w) {
+			t.Errorf("prefetchHoldUntil(%s) = %s, want %s", now, got, w)
+		}
+	}
+}
+
+func setupPrefetch(t *testing.T) {
+	t.Helper()
+	oldEnabled := prefetchEnabled
+	prefetchEnabled = true
+	prefetchCandidates = make(map[string]prefetchCandidate)
+	t.Cleanup(func() {
+		prefetchEnabled = oldEnabled
+		prefetchCandidates = make(map[string]prefetchCandidate)
+	})
+}
+
+func TestRecordPrefetchCandidate(t *testing.T) {
+	setupPrefetch(t)
+
+	for _, q := range []string{"", "pekan=abc", "pekan=3"} {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&"+q, nil)
+		addAuthCookies(req)
+		recordPrefetchCandidate(req, schedulePath("123", "1945-1", req.URL.Query()), "123", "1945-1")
+	}
+	if len(prefetchCandidates) != 1 {
+		t.Fatalf("got %d candidates, want 1 (only numeric pekan)", len(prefetchCandidates))
+	}
+	for _, c := range prefetchCandidates {
+		if c.auth.Get("Cookie") == "" {
+			t.Error("expected candidate to keep the request cookies")
+		}
+	}
+}
+
+func TestPrefetchNextPekan(t *testing.T) {
+	setupPrefetch(t)
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&pekan=3&prodi=102", nil)
+	addAuthCookies(req)
+	recordPrefetchCandidate(req, schedulePath("123", "1945-1", req.URL.Query()), "123", "1945-1")
+
+	srv.prefetchNextPekan(context.Background())
+
+	next := url.Values{"pekan": {"4"}, "prodi": {"102"}}
+	entry, ok := srv.cache.get(schedulePath("123", "1945-1", next))
+	if !ok {
+		t.Fatal("expected pekan=4 to be cached")
+	}
+	if min := prefetchHoldUntil(time.Now()).Add(-prefetchHoldSpread); entry.expiresAt.Before(min) {
+		t.Errorf("prefetched page expires at %s, want it kept until %s", entry.expiresAt, min)
+	}
+	if len(entry.data) != 2 {
+		t.Errorf("got %d classes, want 2", len(entry.data))
+	}
+	if len(prefetchCandidates) != 0 {
+		t.Error("candidates should be consumed by the run")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Which classes a student may take, judged by the prerequisites in their
+// curriculum against their transcript. A prerequisite is met once it has
+// been passed; taking it this semester is not enough.
+type eligibility struct {
+	passed  map[string]bool
+	prereqs map[string][]string // course code to its prerequisites
+}
+
+func newEligibility(transcript []TranscriptCourse, curriculum []CurriculumCourse) *eligibility {
+	e := &eligibility{passed: make(map[string]bool), prereqs: make(map[string][]string)}
+	for _, c := range transcript {
+		if c.passed() {
+			e.passed[strings.ToUpper(c.Code)] = true
+		}
+	}
+	for _, c := range curriculum {
+		if len(c.Prerequisites) > 0 {
+			e.prereqs[strings.ToUpper(c.Code)] = c.Prerequisites
+		}
+	}
+	return e
+}
+
+// Returns the prerequisites of code not passed yet. Courses outside the
+// curriculum have no known prerequisites.
+func (e *eligibility) missing(code string) []string {
+	var missing []string
+	for _, p := range e.prereqs[strings.ToUpper(code)] {
+		if !e.passed[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// Returns copies of classes with Eligible and MissingPrerequisites set, the
+// ineligible ones dropped if onlyEligible is set.
+func (e *eligibility) annotate(classes []CourseClass, onlyEligible bool) []CourseClass {
+	out := make([]CourseClass, 0, len(classes))
+	for _, c := range classes {
+		c.MissingPrerequisites = e.missing(c.Code)
+		eligible := len(c.MissingPrerequisites) == 0
+		c.Eligible = &eligible
+		if eligible || !onlyEligible {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// The query parameters that ask for eligibility, shared by the catalog and
+// search endpoints.
+var eligibilityParams = []Parameter{
+	{Name: "eligibility", In: "query", Description: "Mark each class eligible or not for the student, from their transcript and curriculum prerequisites", Schema: &Schema{Type: "boolean"}},
+	{Name: "only_eligible", In: "query", Description: "Only classes the student may take; implies eligibility", Schema: &Schema{Type: "boolean"}},
+}
+
+// Reports whether query asks for eligibility, and whether only eligible
+// classes should be kept.
+func wantsEligibility(query url.Values) (annotate, onlyEligible bool) {
+	onlyEligible = query.Get("only_eligible") == "true"
+	return onlyEligible || query.Get("eligibility") == "true", onlyEligible
+}
+
+// Fetches the transcript and curriculum of studentID with r's SIX
+// credentials. On failure it writes the error response and returns false.
+func (s *Server) studentEligibility(w http.ResponseWriter, r *http.Request, studentID string) (*eligibility, bool) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return nil, false
+	}
+	defer release()
+
+	transcript, err := s.provider.FetchTranscript(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return nil, false
+	}
+	curriculum, err := s.provider.FetchCurriculum(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return nil, false
+	}
+	return newEligibility(transcript, curriculum), true
+}
+
+// Like studentEligibility, but returns nil without fetching anything when
+// query does not ask for eligibility.
+func (s *Server) requestedEligibility(w http.ResponseWriter, r *http.Request, studentID string) (e *eligibility, onlyEligible, ok bool) {
+	annotate, onlyEligible := wantsEligibility(r.URL.Query())
+	if !annotate {
+		return nil, false, true
+	}
+	if studentID == "" {
+		writeError(w, r, codeInvalidRequest, "student_id is required for eligibility")
+		return nil, false, false
+	}
+	e, ok = s.studentEligibility(w, r, studentID)
+	return e, onlyEligible, ok
+}
+
+// Keeps the search hits the student may take, annotated, up to limit.
+func (e *eligibility) annotateHits(hits []SearchHit, onlyEligible bool, limit int) []SearchHit {
+	out := make([]SearchHit, 0, min(len(hits), limit))
+	for _, h := range hits {
+		if len(out) == limit {
+			break
+		}
+		annotated := e.annotate([]CourseClass{h.CourseClass}, onlyEligible)
+		if len(annotated) == 0 {
+			continue
+		}
+		h.CourseClass = annotated[0]
+		out = append(out, h)
+	}
+	return slices.Clip(out)
+}
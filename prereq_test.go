@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEligibility(t *testing.T) {
+	// testTranscriptHTML: MA1101 passed, FI1101 failed, IF2211 in progress.
+	transcript := parseTranscript(docFromHTML(testTranscriptHTML))
+	e := newEligibility(transcript, parseCurriculum(docFromHTML(testCurriculumHTML)))
+
+	classes := []CourseClass{{Code: "IF2211"}, {Code: "IF4050"}, {Code: "KU1001"}}
+	got := e.annotate(classes, false)
+	if len(got) != 3 || !*got[0].Eligible || *got[1].Eligible || !*got[2].Eligible {
+		t.Fatalf("annotated = %+v", got)
+	}
+	// In progress is not passed yet.
+	if fmt.Sprint(got[1].MissingPrerequisites) != "[IF2211 FI1101]" {
+		t.Errorf("IF4050 missing = %v", got[1].MissingPrerequisites)
+	}
+	if classes[1].Eligible != nil {
+		t.Error("input classes were modified")
+	}
+
+	if got := e.annotate(classes, true); len(got) != 2 || got[1].Code != "KU1001" {
+		t.Errorf("only eligible = %+v", got)
+	}
+}
+
+func mockSIXAcademic(t *testing.T) *httptest.Server {
+	t.Helper()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/akademik/transkrip"):
+			fmt.Fprint(w, testTranscriptHTML)
+		case strings.HasSuffix(r.URL.Path, "/akademik/kurikulum"):
+			fmt.Fprint(w, strings.Replace(testCurriculumHTML, "<td>IF4050</td>", "<td>FI1220</td>", 1))
+		default:
+			fmt.Fprint(w, testScheduleHTML)
+		}
+	}))
+	t.Cleanup(mock.Close)
+	return mock
+}
+
+func TestScheduleHandler_OnlyEligible(t *testing.T) {
+	srv := newTestServer(mockSIXAcademic(t).URL)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/schedule?student_id=123&semester=2025-2&prodi=102&eligibility=true")
+	classes := decodeData[[]CourseClass](t, w)
+	if len(classes) != 2 || !*classes[0].Eligible || *classes[1].Eligible {
+		t.Fatalf("annotated = %+v", classes)
+	}
+
+	w = get("/api/schedule?student_id=123&semester=2025-2&prodi=102&only_eligible=true")
+	if classes := decodeData[[]CourseClass](t, w); len(classes) != 1 || classes[0].Code != "FI1210" {
+		t.Errorf("only eligible = %+v", classes)
+	}
+	// The cached catalog page itself is not annotated.
+	if entry, _ := srv.catalog.get("2025-2?prodi=102"); entry.data[0].Eligible != nil {
+		t.Error("cached classes were annotated")
+	}
+}
+
+func TestSearchHandler_OnlyEligible(t *testing.T) {
+	srv := newTestServer(mockSIXAcademic(t).URL)
+	srv.catalog.set("2025-2?prodi=102", parseClasses(docFromHTML(testScheduleHTML)), time.Now())
+
+	req := httptest.NewRequest("GET", "/api/search?q=fisika&student_id=123&only_eligible=true", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	hits := decodeData[[]SearchHit](t, w)
+	if len(hits) != 1 || hits[0].Code != "FI1210" || hits[0].Eligible == nil || !*hits[0].Eligible {
+		t.Errorf("hits = %+v", hits)
+	}
+
+	req = httptest.NewRequest("GET", "/api/search?q=fisika&eligibility=true", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("without student_id: status %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SIX page with a student's personal and academic details.
+func profilePath(studentID string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s/profil", studentID)
+}
+
+// Who a student is, as their SIX profile page says.
+type Profile struct {
+	// StudentID is SIX's internal ID, as /api/user returns it, and NIM the
+	// student number ITB issues, e.g. "13520001".
+	StudentID string `json:"student_id"`
+	NIM       string `json:"nim"`
+	Name      string `json:"name"`
+	Program   string `json:"program"` // program studi
+	Faculty   string `json:"faculty"` // fakultas or sekolah
+	// EnrollmentYear is the angkatan, or 0 if neither the page nor the NIM
+	// tells.
+	EnrollmentYear int `json:"enrollment_year"`
+}
+
+// Labels of the profile page's fields, in textnorm.Key form.
+var (
+	profileNIMLabels     = []string{"nim", "nomor induk mahasiswa"}
+	profileNameLabels    = []string{"nama", "nama lengkap", "nama mahasiswa"}
+	profileProgramLabels = []string{"program studi", "prodi", "jurusan"}
+	profileFacultyLabels = []string{"fakultas/sekolah", "fakultas", "sekolah"}
+	profileYearLabels    = []string{"angkatan", "tahun masuk"}
+)
+
+// Parses the profile page, whose fields are labeled (see labeledField).
+// Without an enrollment year on the page, it is read from the NIM.
+func parseProfile(doc *goquery.Document) Profile {
+	p := Profile{
+		NIM:     labeledField(doc, profileNIMLabels),
+		Name:    labeledField(doc, profileNameLabels),
+		Program: labeledField(doc, profileProgramLabels),
+		Faculty: labeledField(doc, profileFacultyLabels),
+	}
+	if year, err := strconv.Atoi(labeledField(doc, profileYearLabels)); err == nil {
+		p.EnrollmentYear = year
+	} else {
+		p.EnrollmentYear = nimEnrollmentYear(p.NIM)
+	}
+	return p
+}
+
+// Returns the enrollment year in an ITB NIM, whose fourth and fifth digits
+// are the year, as 20 in 13520001. Returns 0 if nim is not such a number.
+func nimEnrollmentYear(nim string) int {
+	if len(nim) != 8 || strings.Trim(nim, "0123456789") != "" {
+		return 0
+	}
+	year, _ := strconv.Atoi(nim[3:5])
+	return 2000 + year
+}
+
+// errProfileNotFound means the profile page has none of the fields.
+var errProfileNotFound = errors.New("no profile on the SIX profile page")
+
+// GET /api/profile?student_id=...
+func (s *Server) profileHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	studentID := r.URL.Query().Get("student_id")
+	profile, err := s.provider.FetchProfile(r, studentID)
+	switch {
+	case errors.Is(err, errProfileNotFound):
+		writeError(w, r, codeProfileNotFound)
+		return
+	case err != nil:
+		writeUpstreamError(w, r, err)
+		return
+	}
+	profile.StudentID = studentID
+	log.Printf("parsed profile student_id=%s", studentID)
+	writeSuccessWithMeta(w, profile, &Meta{FetchedAt: time.Now()})
+}
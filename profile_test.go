@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testProfileHTML = `<html><body>
+<h3>Profil Mahasiswa</h3>
+<table class="table">
+  <tr><th>NIM</th><td>13520001</td></tr>
+  <tr><th>Nama</th><td>Budi  Santoso</td></tr>
+  <tr><th>Program Studi</th><td>Teknik Informatika</td></tr>
+  <tr><th>Fakultas/Sekolah</th><td>Sekolah Teknik Elektro dan Informatika</td></tr>
+</table>
+</body></html>`
+
+func TestParseProfile(t *testing.T) {
+	got := parseProfile(docFromHTML(testProfileHTML))
+	want := Profile{NIM: "13520001", Name: "Budi Santoso", Program: "Teknik Informatika", Faculty: "Sekolah Teknik Elektro dan Informatika", EnrollmentYear: 2020}
+	if got != want {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	doc := docFromHTML(`<html><body><dl>
+<dt>Nama Lengkap</dt><dd>Ani</dd><dt>NIM</dt><dd>18221042</dd><dt>Angkatan</dt><dd>2021</dd>
+</dl></body></html>`)
+	if got := parseProfile(doc); got.Name != "Ani" || got.EnrollmentYear != 2021 {
+		t.Errorf("dl layout: got %+v", got)
+	}
+}
+
+func TestNIMEnrollmentYear(t *testing.T) {
+	for nim, want := range map[string]int{"13520001": 2020, "16525123": 2025, "1352000": 0, "1352OOO1": 0, "": 0} {
+		if got := nimEnrollmentYear(nim); got != want {
+			t.Errorf("%q: got %d, want %d", nim, got, want)
+		}
+	}
+}
+
+func TestProfileHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/mahasiswa:123/profil") {
+			fmt.Fprint(w, `<html><body><p>Halaman tidak tersedia</p></body></html>`)
+			return
+		}
+		fmt.Fprint(w, testProfileHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	w := get("/api/profile?student_id=123")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if p := decodeData[Profile](t, w); p.StudentID != "123" || p.NIM != "13520001" || p.Program != "Teknik Informatika" {
+		t.Errorf("profile = %+v", p)
+	}
+	if w := get("/api/profile?student_id=456"); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), string(codeProfileNotFound)) {
+		t.Errorf("no profile: got status %d: %s", w.Code, w.Body)
+	}
+}
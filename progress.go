@@ -0,0 +1,94 @@
+package main
+
+import "net/http"
+
+// SKS needed to graduate from an ITB undergraduate program.
+var graduationSKS = envInt("SIX_GRADUATION_SKS", 144)
+
+// Degree audit of a student: how far the transcript is through the
+// curriculum.
+type Progress struct {
+	StudentID         string             `json:"student_id"`
+	SKSCompleted      int                `json:"sks_completed"`
+	SKSInProgress     int                `json:"sks_in_progress"`
+	SKSRequired       int                `json:"sks_required"`
+	SKSRemaining      int                `json:"sks_remaining"`
+	RequiredCompleted int                `json:"required_completed"`
+	RequiredRemaining []CurriculumCourse `json:"required_remaining"`
+	InProgress        []TranscriptCourse `json:"in_progress"`
+	// EligibleElectives are curriculum electives the student has neither
+	// passed nor is taking.
+	EligibleElectives []CurriculumCourse `json:"eligible_electives"`
+}
+
+// Compares a transcript against a curriculum. A course that was retaken
+// counts once: passed if any attempt passed, in progress if an attempt has no
+// grade yet and none passed. Courses are matched by code.
+func computeProgress(transcript []TranscriptCourse, curriculum []CurriculumCourse, sksRequired int) Progress {
+	passed := make(map[string]TranscriptCourse)
+	inProgress := make(map[string]TranscriptCourse)
+	for _, c := range transcript {
+		switch {
+		case c.passed():
+			passed[c.Code] = c
+		case c.Grade == "":
+			inProgress[c.Code] = c
+		}
+	}
+
+	p := Progress{
+		SKSRequired:       sksRequired,
+		RequiredRemaining: []CurriculumCourse{},
+		InProgress:        []TranscriptCourse{},
+		EligibleElectives: []CurriculumCourse{},
+	}
+	for _, c := range passed {
+		p.SKSCompleted += c.SKS
+	}
+	for _, c := range transcript {
+		if _, ok := passed[c.Code]; !ok && c.Grade == "" {
+			p.SKSInProgress += c.SKS
+			p.InProgress = append(p.InProgress, c)
+		}
+	}
+	p.SKSRemaining = max(0, sksRequired-p.SKSCompleted)
+
+	for _, c := range curriculum {
+		_, done := passed[c.Code]
+		_, taking := inProgress[c.Code]
+		switch {
+		case c.Required && done:
+			p.RequiredCompleted++
+		case c.Required:
+			p.RequiredRemaining = append(p.RequiredRemaining, c)
+		case !done && !taking:
+			p.EligibleElectives = append(p.EligibleElectives, c)
+		}
+	}
+	return p
+}
+
+// GET /api/progress?student_id=...
+func (s *Server) progressHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	studentID := r.URL.Query().Get("student_id")
+	transcript, err := s.provider.FetchTranscript(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	curriculum, err := s.provider.FetchCurriculum(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+
+	p := computeProgress(transcript, curriculum, graduationSKS)
+	p.StudentID = studentID
+	writeSuccess(w, p)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComputeProgress(t *testing.T) {
+	transcript := []TranscriptCourse{
+		{Code: "MA1101", SKS: 4, Grade: "A"},
+		{Code: "FI1101", SKS: 4, Grade: "E"},
+		{Code: "FI1101", SKS: 4, Grade: "BC"}, // retaken and passed
+		{Code: "IF2211", SKS: 3},              // in progress
+		{Code: "IF4050", SKS: 3},              // elective in progress
+		{Code: "KU1001", SKS: 2, Grade: "B"},  // outside the curriculum
+	}
+	curriculum := parseCurriculum(docFromHTML(testCurriculumHTML))
+
+	p := computeProgress(transcript, curriculum, 144)
+	if p.SKSCompleted != 10 || p.SKSInProgress != 6 || p.SKSRemaining != 134 {
+		t.Errorf("sks completed=%d in_progress=%d remaining=%d, want 10, 6, 134", p.SKSCompleted, p.SKSInProgress, p.SKSRemaining)
+	}
+	if p.RequiredCompleted != 2 || len(p.RequiredRemaining) != 1 || p.RequiredRemaining[0].Code != "IF2211" {
+		t.Errorf("required completed=%d remaining=%+v", p.RequiredCompleted, p.RequiredRemaining)
+	}
+	if len(p.EligibleElectives) != 1 || p.EligibleElectives[0].Code != "IF4070" {
+		t.Errorf("eligible electives = %+v, want IF4070", p.EligibleElectives)
+	}
+}
+
+func TestProgressHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/akademik/transkrip"):
+			fmt.Fprint(w, testTranscriptHTML)
+		case strings.HasSuffix(r.URL.Path, "/akademik/kurikulum"):
+			fmt.Fprint(w, testCurriculumHTML)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/progress?student_id=123", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	p := decodeData[Progress](t, w)
+	if p.StudentID != "123" || p.SKSCompleted != 4 || p.SKSRequired != graduationSKS {
+		t.Errorf("progress = %+v", p)
+	}
+
+	req = httptest.NewRequest("GET", "/api/progress", nil)
+	addAuthCookies(req)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing student_id: got status %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// A randomly generated, validated schedule query.
+type scheduleInput struct {
+	StudentID string
+	Semester  string
+	Query     url.Values
+}
+
+// Characters that are meaningful in URLs, mixed with ordinary ones.
+const trickyChars = "ab Z09/?#&=+%:;.-_~é\t"
+
+func randomString(r *rand.Rand, maxLen int) string {
+	runes := []rune(trickyChars)
+	var b strings.Builder
+	for range r.Intn(maxLen + 1) {
+		b.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return b.String()
+}
+
+func (scheduleInput) Generate(r *rand.Rand, _ int) reflect.Value {
+	in := scheduleInput{
+		StudentID: fmt.Sprint(r.Int63n(1e10)),
+		Semester:  fmt.Sprintf("%04d-%d", r.Intn(10000), r.Intn(10)),
+		Query:     url.Values{},
+	}
+	keys := append([]string{"refresh", "student_id", "other"}, scheduleFilterKeys...)
+	for _, k := range keys {
+		if r.Intn(2) == 0 {
+			in.Query.Set(k, randomString(r, 8))
+		}
+	}
+	return reflect.ValueOf(in)
+}
+
+func TestSchedulePath_StaysWithinSIX(t *testing.T) {
+	base, _ := url.Parse(defaultBaseURL)
+	property := func(in scheduleInput) bool {
+		if !validScheduleParams(in.StudentID, in.Semester) {
+			t.Logf("generator produced invalid input %+v", in)
+			return false
+		}
+		u, err := url.Parse(defaultBaseURL + schedulePath(in.StudentID, in.Semester, in.Query))
+		if err != nil {
+			return false
+		}
+		wantPath := fmt.Sprintf("/app/mahasiswa:%s+%s/kelas/jadwal/kuliah", in.StudentID, in.Semester)
+		if u.Scheme != base.Scheme || u.Host != base.Host || u.Path != wantPath || u.Fragment != "" {
+			return false
+		}
+		for k := range u.Query() {
+			if !slices.Contains(scheduleFilterKeys, k) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchedulePath_KeyIsIdempotent(t *testing.T) {
+	property := func(in scheduleInput) bool {
+		key := schedulePath(in.StudentID, in.Semester, in.Query)
+		u, err := url.Parse(key)
+		if err != nil {
+			return false
+		}
+		return schedulePath(in.StudentID, in.Semester, u.Query()) == key
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// Two inputs share a cache key exactly when they ask SIX for the same thing.
+func TestSchedulePath_KeyCollisionFree(t *testing.T) {
+	property := func(a, b scheduleInput) bool {
+		// Make collisions likely enough to exercise both directions.
+		if len(a.StudentID)%2 == 0 {
+			b.StudentID, b.Semester = a.StudentID, a.Semester
+		}
+		sameKey := schedulePath(a.StudentID, a.Semester, a.Query) == schedulePath(b.StudentID, b.Semester, b.Query)
+		sameSemantics := a.StudentID == b.StudentID && a.Semester == b.Semester &&
+			reflect.DeepEqual(scheduleFilters(a.Query), scheduleFilters(b.Query))
+		return sameKey == sameSemantics
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+
+	// Hand-picked cases where the raw query differs but the semantics do not.
+	same := []url.Values{
+		{"prodi": {"102"}},
+		{"prodi": {" 102 "}, "refresh": {"true"}},
+		{"prodi": {"102"}, "fakultas": {""}, "unknown": {"x"}},
+	}
+	want := schedulePath("1", "1945-1", same[0])
+	for _, q := range same[1:] {
+		if got := schedulePath("1", "1945-1", q); got != want {
+			t.Errorf("key for %v = %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestValidScheduleParams(t *testing.T) {
+	tests := []struct {
+		studentID, semester string
+		want                bool
+	}{
+		{"10245001", "1945-1", true},
+		{"123", "2025-2", true},
+		{"123/../x", "1945-1", false},
+		{"123", "1945-1/kelas", false},
+		{"abc", "1945-1", false},
+		{"123", "45-1", false},
+		{"", "1945-1", false},
+	}
+	for _, tt := range tests {
+		if got := validScheduleParams(tt.studentID, tt.semester); got != tt.want {
+			t.Errorf("validScheduleParams(%q, %q) = %v, want %v", tt.studentID, tt.semester, got, tt.want)
+		}
+	}
+}
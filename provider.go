@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Provider is an upstream source of student data. Handlers and background
+// jobs only go through the Server's Provider, so another source, such as an
+// official ITB API or another university's portal, can be added without
+// rewriting them. SIX is the default (see sixProvider).
+//
+// Every method takes the inbound request r, which carries the student's
+// credentials and the context that bounds the fetch.
+type Provider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	// FetchHomePage returns the logged-in student and their current
+	// semester. It returns errStudentIDNotFound or errSemesterNotFound if
+	// the provider cannot tell.
+	FetchHomePage(r *http.Request) (Home, error)
+	FetchSchedulePage(r *http.Request, studentID, semester string, filters url.Values) (SchedulePage, error)
+	FetchTranscript(r *http.Request, studentID string) ([]TranscriptCourse, error)
+	// FetchGradeHistory returns the transcript together with the averages
+	// the provider reports for each semester.
+	FetchGradeHistory(r *http.Request, studentID string) (GradeHistory, error)
+	FetchCurriculum(r *http.Request, studentID string) ([]CurriculumCourse, error)
+	FetchGrades(r *http.Request, studentID, semester string) ([]GradeEntry, error)
+	FetchExams(r *http.Request, studentID, semester string) ([]ExamEntry, error)
+	FetchFRS(r *http.Request, studentID, semester string) (FRS, error)
+	// FetchProfile returns errProfileNotFound if the provider has no
+	// details of the student.
+	FetchProfile(r *http.Request, studentID string) (Profile, error)
+	FetchCalendar(r *http.Request) ([]CalendarEntry, error)
+	FetchPayments(r *http.Request, studentID string) ([]Payment, error)
+}
+
+// The logged-in student.
+type Home struct {
+	StudentID string
+	Semester  string
+	Source    string // how it was fetched, e.g. sourceScrape
+}
+
+type SchedulePage struct {
+	Classes []CourseClass
+	// Sample describes the page for anomaly detection. Providers that do
+	// not scrape HTML leave it zero, which skips the check.
+	Sample scrapeSample
+	Source string // how it was fetched, e.g. sourceScrape
+	// Pages is how many upstream pages the listing took, and Truncated is
+	// set if some were left unfetched. Providers that do not paginate
+	// leave both zero.
+	Pages     int
+	Truncated bool
+	// FilterOptions holds the values the page offers for each of
+	// scheduleFilterKeys, such as the faculties under "fakultas". The
+	// catalog crawler walks them. Providers without filters leave it nil.
+	FilterOptions url.Values
+}
+
+type GradeHistory struct {
+	Courses []TranscriptCourse
+	// Reported holds the IP and IPK of each semester as the provider shows
+	// them, which may be none.
+	Reported []ReportedGPA
+}
+
+var (
+	errStudentIDNotFound = errors.New("student ID not found on the home page")
+	errSemesterNotFound  = errors.New("current semester not found")
+)
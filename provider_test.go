@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// A Provider that serves fixed data, standing in for a non-SIX upstream.
+type stubProvider struct {
+	home    Home
+	homeErr error
+	classes []CourseClass
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+func (p *stubProvider) FetchHomePage(r *http.Request) (Home, error) { return p.home, p.homeErr }
+
+func (p *stubProvider) FetchSchedulePage(r *http.Request, studentID, semester string, filters url.Values) (SchedulePage, error) {
+	return SchedulePage{Classes: p.classes}, nil
+}
+
+func (p *stubProvider) FetchTranscript(r *http.Request, studentID string) ([]TranscriptCourse, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchCurriculum(r *http.Request, studentID string) ([]CurriculumCourse, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchGradeHistory(r *http.Request, studentID string) (GradeHistory, error) {
+	return GradeHistory{}, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchExams(r *http.Request, studentID, semester string) ([]ExamEntry, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchFRS(r *http.Request, studentID, semester string) (FRS, error) {
+	return FRS{}, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchProfile(r *http.Request, studentID string) (Profile, error) {
+	return Profile{}, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchCalendar(r *http.Request) ([]CalendarEntry, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchPayments(r *http.Request, studentID string) ([]Payment, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *stubProvider) FetchGrades(r *http.Request, studentID, semester string) ([]GradeEntry, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestProvider_ServesHandlers(t *testing.T) {
+	p := &stubProvider{
+		home:    Home{StudentID: "13520001", Semester: "2025-2"},
+		classes: []CourseClass{{Code: "IF2211", ClassNo: "01", Name: "Strategi Algoritma"}},
+	}
+	srv := NewServer(Config{Provider: p})
+
+	req := httptest.NewRequest("GET", "/api/user", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("user: status %d: %s", w.Code, w.Body)
+	}
+	var user struct {
+		Data UserResponse `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&user)
+	if user.Data != (UserResponse{StudentID: "13520001", Semester: "2025-2"}) {
+		t.Errorf("user = %+v", user.Data)
+	}
+
+	req = httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=current", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("schedule: status %d: %s", w.Code, w.Body)
+	}
+	var schedule struct {
+		Data []CourseClass `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&schedule)
+	if len(schedule.Data) != 1 || schedule.Data[0].Code != "IF2211" {
+		t.Errorf("schedule = %+v", schedule.Data)
+	}
+}
+
+func TestProvider_HomeErrors(t *testing.T) {
+	for err, want := range map[error]errorCode{
+		errStudentIDNotFound: codeStudentIDNotFound,
+		errSemesterNotFound:  codeSemesterNotFound,
+	} {
+		srv := NewServer(Config{Provider: &stubProvider{homeErr: err}})
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/user", nil))
+		var resp APIResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp.Code != want {
+			t.Errorf("%v: code = %s, want %s", err, resp.Code, want)
+		}
+	}
+}
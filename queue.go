@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type priority int
+
+const (
+	priorityInteractive priority = iota // per-student requests a user is waiting on
+	priorityBatch                       // catalog crawls, exports, prefetches
+)
+
+type priorityKey struct{}
+
+// Returns a context whose upstream fetches are queued at priority p.
+func withPriority(ctx context.Context, p priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// Returns the upstream priority of ctx, defaulting to interactive.
+func priorityFrom(ctx context.Context) priority {
+	if p, ok := ctx.Value(priorityKey{}).(priority); ok {
+		return p
+	}
+	return priorityInteractive
+}
+
+// Limits concurrent upstream fetches. Waiting interactive fetches are served
+// first, but every batchWeight-th free slot goes to a waiting batch fetch so
+// batch work is delayed rather than starved.
+type upstreamQueue struct {
+	mu          sync.Mutex
+	slots       int
+	active      int
+	batchWeight int
+	sinceBatch  int
+	waiting     [2][]chan struct{}
+}
+
+func newUpstreamQueue(slots, batchWeight int) *upstreamQueue {
+	return &upstreamQueue{slots: max(slots, 1), batchWeight: max(batchWeight, 1)}
+}
+
+var upstream = newUpstreamQueue(politeness.Concurrency, politeness.BatchWeight)
+
+// Blocks until a slot is granted or ctx is done.
+func (q *upstreamQueue) acquire(ctx context.Context, p priority) error {
+	q.mu.Lock()
+	if q.active < q.slots && len(q.waiting[priorityInteractive])+len(q.waiting[priorityBatch]) == 0 {
+		q.active++
+		q.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	q.waiting[p] = append(q.waiting[p], ch)
+	q.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, w := range q.waiting[p] {
+			if w == ch {
+				q.waiting[p] = append(q.waiting[p][:i], q.waiting[p][i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was granted while we were giving up; pass it on.
+		q.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+// Frees a slot, handing it to the next waiter if there is one.
+func (q *upstreamQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *upstreamQueue) releaseLocked() {
+	next := priorityInteractive
+	switch {
+	case len(q.waiting[priorityInteractive]) == 0 && len(q.waiting[priorityBatch]) == 0:
+		q.active--
+		return
+	case len(q.waiting[priorityInteractive]) == 0:
+		next = priorityBatch
+	case len(q.waiting[priorityBatch]) > 0 && q.sinceBatch >= q.batchWeight:
+		next = priorityBatch
+	}
+
+	if next == priorityBatch {
+		q.sinceBatch = 0
+	} else {
+		q.sinceBatch++
+	}
+	ch := q.waiting[next][0]
+	q.waiting[next] = q.waiting[next][1:]
+	close(ch)
+}
+
+// Sends req through the upstream queue at the priority carried by its context.
+// The slot is held until the response body is closed. Batch fetches are
+// paced, and bodiless requests are retried as the politeness profile says.
+func doUpstream(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	p := priorityFrom(ctx)
+	delay := politeness.RetryDelay
+	for attempt := 0; ; attempt++ {
+		if p == priorityBatch {
+			if err := pacer.wait(ctx, politeness.BatchDelay); err != nil {
+				return nil, err
+			}
+		}
+		if err := upstream.acquire(ctx, p); err != nil {
+			return nil, err
+		}
+		if attempt == 0 {
+			chargeUpstream(ctx)
+		}
+		upstreamRate.record(time.Now())
+		resp, err := client.Do(req)
+		if attempt < politeness.Retries && req.Body == nil && retryable(resp, err) {
+			if err == nil {
+				resp.Body.Close()
+			}
+			upstream.release()
+			log.Printf("retrying upstream fetch url=%s attempt=%d in %s", req.URL, attempt+1, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			delay *= 2
+			continue
+		}
+		if err != nil {
+			upstream.release()
+			return nil, err
+		}
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: upstream.release}
+		return resp, nil
+	}
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// Counts upstream fetches per minute over the last hour, to show how bursty
+// the load on SIX is.
+type fetchRate struct {
+	mu      sync.Mutex
+	start   time.Time
+	counts  [60]int
+	minutes [60]int64 // Unix minute each count belongs to
+}
+
+var upstreamRate = newFetchRate(time.Now())
+
+func newFetchRate(now time.Time) *fetchRate {
+	return &fetchRate{start: now}
+}
+
+func (fr *fetchRate) record(now time.Time) {
+	m := now.Unix() / 60
+	i := m % int64(len(fr.counts))
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.minutes[i] != m {
+		fr.minutes[i], fr.counts[i] = m, 0
+	}
+	fr.counts[i]++
+}
+
+type UpstreamStats struct {
+	WindowMinutes int     `json:"window_minutes"`
+	Fetches       int     `json:"fetches"`
+	MeanPerMinute float64 `json:"mean_per_minute"`
+	PeakPerMinute int     `json:"peak_per_minute"`
+	// Burstiness is the peak over the mean: 1 for perfectly even load,
+	// higher the more fetches bunch up.
+	Burstiness  float64 `json:"burstiness"`
+	CacheJitter float64 `json:"cache_jitter"`
+	// Most cached schedules due to expire in any one coming minute.
+	ExpiringPeakPerMinute int `json:"expiring_peak_per_minute"`
+}
+
+// Returns the fetch counts of the last hour, or of the time since start if
+// that is shorter.
+func (fr *fetchRate) stats(now time.Time) UpstreamStats {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	window := min(int(now.Sub(fr.start)/time.Minute)+1, len(fr.counts))
+	st := UpstreamStats{WindowMinutes: window}
+	current := now.Unix() / 60
+	for i, m := range fr.minutes {
+		if m > current-int64(window) && m <= current {
+			st.Fetches += fr.counts[i]
+			st.PeakPerMinute = max(st.PeakPerMinute, fr.counts[i])
+		}
+	}
+	st.MeanPerMinute = float64(st.Fetches) / float64(window)
+	if st.Fetches > 0 {
+		st.Burstiness = float64(st.PeakPerMinute) / st.MeanPerMinute
+	}
+	return st
+}
+
+// GET /api/admin/upstream
+func (s *Server) upstreamStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	now := time.Now()
+	st := upstreamRate.stats(now)
+	st.CacheJitter = cacheJitter
+	st.ExpiringPeakPerMinute = s.cache.expiryPeak(now)
+	writeSuccess(w, st)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPriorityFrom(t *testing.T) {
+	if p := priorityFrom(context.Background()); p != priorityInteractive {
+		t.Errorf("default priority = %d, want interactive", p)
+	}
+	if p := priorityFrom(withPriority(context.Background(), priorityBatch)); p != priorityBatch {
+		t.Errorf("priority = %d, want batch", p)
+	}
+}
+
+// Queues waiters one at a time so their arrival order is deterministic.
+func enqueue(t *testing.T, q *upstreamQueue, p priority, name string, order chan<- string) {
+	t.Helper()
+	q.mu.Lock()
+	before := len(q.waiting[p])
+	q.mu.Unlock()
+
+	go func() {
+		if err := q.acquire(context.Background(), p); err != nil {
+			t.Error(err)
+			return
+		}
+		order <- name
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		q.mu.Lock()
+		n := len(q.waiting[p])
+		q.mu.Unlock()
+		if n > before {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never queued", name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUpstreamQueue_WeightedOrder(t *testing.T) {
+	q := newUpstreamQueue(1, 2)
+	if err := q.acquire(context.Background(), priorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 5)
+	enqueue(t, q, priorityBatch, "b1", order)
+	enqueue(t, q, priorityBatch, "b2", order)
+	enqueue(t, q, priorityInteractive, "i1", order)
+	enqueue(t, q, priorityInteractive, "i2", order)
+	enqueue(t, q, priorityInteractive, "i3", order)
+
+	// Interactive waiters go first, but after two of them a batch waiter
+	// gets the next slot.
+	want := []string{"i1", "i2", "b1", "i3", "b2"}
+	for _, w := range want {
+		q.release()
+		select {
+		case got := <-order:
+			if got != w {
+				t.Fatalf("got %s, want %s", got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", w)
+		}
+	}
+	q.release()
+	if q.active != 0 {
+		t.Errorf("active = %d, want 0", q.active)
+	}
+}
+
+func TestUpstreamQueue_CancelledWaiter(t *testing.T) {
+	q := newUpstreamQueue(1, 1)
+	if err := q.acquire(context.Background(), priorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.acquire(ctx, priorityBatch); err == nil {
+		t.Fatal("expected acquire to fail once the context is done")
+	}
+	if len(q.waiting[priorityBatch]) != 0 {
+		t.Error("cancelled waiter should be removed from the queue")
+	}
+
+	q.release()
+	if q.active != 0 {
+		t.Errorf("active = %d, want 0", q.active)
+	}
+}
+
+func TestFetchRate(t *testing.T) {
+	start := time.Date(2025, 2, 3, 8, 0, 0, 0, time.UTC)
+	fr := newFetchRate(start)
+	for range 6 {
+		fr.record(start)
+	}
+	fr.record(start.Add(2 * time.Minute))
+	fr.record(start.Add(90 * time.Minute))
+
+	st := fr.stats(start.Add(3 * time.Minute))
+	if st.WindowMinutes != 4 || st.Fetches != 7 || st.PeakPerMinute != 6 || st.Burstiness != 6/1.75 {
+		t.Errorf("stats = %+v", st)
+	}
+	st = fr.stats(start.Add(90 * time.Minute))
+	if st.WindowMinutes != 60 || st.Fetches != 1 || st.PeakPerMinute != 1 {
+		t.Errorf("an hour later: stats = %+v", st)
+	}
+}
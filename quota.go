@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ITB's time zone. Daily budgets reset at midnight WIB.
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// Upstream-fetch budget for one API key.
+type apiKey struct {
+	key    string
+	budget int // fetches per day; 0 means unlimited
+	role   role
+	// profile masks what responses show (see redaction.go); nil shows
+	// everything.
+	profile *redactionProfile
+
+	mu   sync.Mutex
+	day  string
+	used int
+}
+
+type Usage struct {
+	Used      int       `json:"used"`
+	Budget    int       `json:"budget"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// API keys from SIX_API_KEYS, formatted as "key=budget:role:profile,key=budget".
+// The role (see rbac.go) and redaction profile (see redaction.go) are
+// optional. When no keys are configured, API key checks, budgets, and roles
+// are disabled.
+var apiKeys = parseAPIKeys(envString("SIX_API_KEYS", ""))
+
+func parseAPIKeys(spec string) []*apiKey {
+	var keys []*apiKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, rest, _ := strings.Cut(part, "=")
+		budget, rest, _ := strings.Cut(rest, ":")
+		roleName, profileName, _ := strings.Cut(rest, ":")
+		n, err := strconv.Atoi(budget)
+		if err != nil || n < 0 {
+			log.Printf("config: invalid budget for API key %q..., treating as unlimited", key[:min(len(key), 4)])
+			n = 0
+		}
+		r, ok := parseRole(roleName)
+		if !ok {
+			log.Printf("config: invalid role %q for API key %q..., treating as viewer", roleName, key[:min(len(key), 4)])
+		}
+		p, ok := lookupRedactionProfile(profileName)
+		if !ok {
+			log.Printf("config: unknown redaction profile %q for API key %q..., masking everything", profileName, key[:min(len(key), 4)])
+		}
+		keys = append(keys, &apiKey{key: key, budget: n, role: r, profile: p})
+	}
+	return keys
+}
+
+func lookupAPIKey(key string) *apiKey {
+	for _, k := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k.key), []byte(key)) == 1 {
+			return k
+		}
+	}
+	return nil
+}
+
+// Rolls the counter over at midnight WIB. Callers must hold k.mu.
+func (k *apiKey) resetIfNewDayLocked(now time.Time) {
+	if day := now.In(wib).Format(time.DateOnly); day != k.day {
+		k.day, k.used = day, 0
+	}
+}
+
+func (k *apiKey) exhausted() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.resetIfNewDayLocked(time.Now())
+	return k.budget > 0 && k.used >= k.budget
+}
+
+func (k *apiKey) charge() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.resetIfNewDayLocked(time.Now())
+	k.used++
+}
+
+func (k *apiKey) usage() Usage {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := time.Now()
+	k.resetIfNewDayLocked(now)
+	y, m, d := now.In(wib).Date()
+	u := Usage{Used: k.used, Budget: k.budget, ResetsAt: time.Date(y, m, d+1, 0, 0, 0, 0, wib)}
+	if k.budget > 0 {
+		u.Remaining = max(k.budget-k.used, 0)
+	}
+	return u
+}
+
+type apiKeyCtxKey struct{}
+
+// Rejects requests without a valid X-API-Key when API keys are configured,
+// and attaches the key to the request context for budget accounting.
+func requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(apiKeys) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		k := lookupAPIKey(r.Header.Get("X-API-Key"))
+		if k == nil {
+			writeError(w, r, codeAPIKeyInvalid)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, k)))
+	})
+}
+
+func apiKeyFrom(ctx context.Context) *apiKey {
+	k, _ := ctx.Value(apiKeyCtxKey{}).(*apiKey)
+	return k
+}
+
+// Counts one upstream fetch against the budget of the key in ctx, if any.
+func chargeUpstream(ctx context.Context) {
+	if k := apiKeyFrom(ctx); k != nil {
+		k.charge()
+	}
+}
+
+// Reports whether the caller may still fetch from upstream today, writing a
+// 429 if its budget is spent. Cached data stays available either way.
+func withinBudget(w http.ResponseWriter, r *http.Request) bool {
+	k := apiKeyFrom(r.Context())
+	if k == nil || !k.exhausted() {
+		return true
+	}
+	u := k.usage()
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(u.ResetsAt).Seconds())))
+	writeError(w, r, codeBudgetExhausted, u.ResetsAt.Format(time.RFC3339))
+	return false
+}
+
+func usageHandler(w http.ResponseWriter, r *http.Request) {
+	k := apiKeyFrom(r.Context())
+	if k == nil {
+		writeError(w, r, codeAPIKeysDisabled)
+		return
+	}
+	writeSuccess(w, k.usage())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func setAPIKeys(t *testing.T, spec string) {
+	t.Helper()
+	old := apiKeys
+	apiKeys = parseAPIKeys(spec)
+	t.Cleanup(func() { apiKeys = old })
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys := parseAPIKeys("alpha=10, beta=0,gamma=bogus,,")
+	if len(keys) != 3 {
+		t.Fatalf("got %d keys, want 3", len(keys))
+	}
+	if keys[0].key != "alpha" || keys[0].budget != 10 {
+		t.Errorf("keys[0] = %q/%d", keys[0].key, keys[0].budget)
+	}
+	if keys[1].budget != 0 || keys[2].budget != 0 {
+		t.Error("zero and invalid budgets should mean unlimited")
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	setAPIKeys(t, "alpha=10")
+	h := requireAPIKey(http.HandlerFunc(usageHandler))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api/me/usage", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("no key: got status %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/me/usage", nil)
+	req.Header.Set("X-API-Key", "alpha")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("valid key: got status %d, want 200", w.Code)
+	}
+	var resp struct {
+		Data Usage `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Budget != 10 || resp.Data.Remaining != 10 || !resp.Data.ResetsAt.After(time.Now()) {
+		t.Errorf("unexpected usage %+v", resp.Data)
+	}
+}
+
+func TestAPIKey_ResetsDaily(t *testing.T) {
+	k := &apiKey{key: "alpha", budget: 1, day: "1945-08-17", used: 1}
+	if k.exhausted() {
+		t.Error("usage from a previous day should not count")
+	}
+}
+
+func TestScheduleHandler_BudgetExhausted(t *testing.T) {
+	setAPIKeys(t, "alpha=1")
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	h := requireAPIKey(http.HandlerFunc(srv.scheduleHandler))
+	get := func(query string) int {
+		req := httptest.NewRequest("GET", "/api/schedule?"+query, nil)
+		req.Header.Set("X-API-Key", "alpha")
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("student_id=123&semester=1945-1"); code != http.StatusOK {
+		t.Fatalf("first fetch: got status %d, want 200", code)
+	}
+	if u := apiKeys[0].usage(); u.Used != 1 || u.Remaining != 0 {
+		t.Errorf("usage after one fetch = %+v", u)
+	}
+	if code := get("student_id=123&semester=1945-1"); code != http.StatusOK {
+		t.Errorf("cache hit after budget spent: got status %d, want 200", code)
+	}
+	if code := get("student_id=123&semester=1945-2"); code != http.StatusTooManyRequests {
+		t.Errorf("cache miss after budget spent: got status %d, want 429", code)
+	}
+	srv.cache.set(schedulePath("123", "1945-2", url.Values{}), nil, time.Now())
+	if code := get("student_id=123&semester=1945-2"); code != http.StatusOK {
+		t.Errorf("cached semester: got status %d, want 200", code)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Each API key has a role, set in SIX_API_KEYS as "key=budget:role". The
+// policy below decides what each role may do.
+type role string
+
+const (
+	roleAdmin  role = "admin"  // everything, including /api/admin/ endpoints
+	roleMember role = "member" // reads and writes on their own resources
+	roleViewer role = "viewer" // reads only
+)
+
+// Role of keys configured without one, so existing deployments keep working.
+const defaultRole = roleMember
+
+type permission string
+
+const (
+	permRead  permission = "read"  // GET endpoints that are not listed in routePermissions
+	permWrite permission = "write" // other methods that are not listed in routePermissions
+	permOrgs  permission = "orgs"  // owning organizations and their aggregated schedules
+	permAdmin permission = "admin" // /api/admin/ endpoints
+)
+
+var rolePermissions = map[role][]permission{
+	roleAdmin:  {permRead, permWrite, permOrgs, permAdmin},
+	roleMember: {permRead, permWrite},
+	roleViewer: {permRead},
+}
+
+// Routes, as "METHOD path" patterns, whose permission is not the default
+// for their method.
+var routePermissions = map[string]permission{
+	"POST /api/orgs":                 permOrgs,
+	"GET /api/orgs/{id}":             permOrgs,
+	"DELETE /api/orgs/{id}":          permOrgs,
+	"GET /api/orgs/{id}/schedule":    permOrgs,
+	"GET /api/admin/metrics":         permAdmin,
+	"GET /api/admin/jobs":            permAdmin,
+	"GET /api/admin/diagnostics":     permAdmin,
+	"GET /api/admin/parsers":         permAdmin,
+	"GET /api/admin/mirror":          permAdmin,
+	"PUT /api/admin/mirror":          permAdmin,
+	"GET /api/admin/refresher":       permAdmin,
+	"GET /api/admin/upstream":        permAdmin,
+	"GET /api/admin/maintenance":     permAdmin,
+	"PUT /api/admin/maintenance":     permAdmin,
+	"POST /api/admin/backfill":       permAdmin,
+	"GET /api/admin/backfill/{id}":   permAdmin,
+	"POST /api/admin/crawl":          permAdmin,
+	"GET /api/admin/crawl":           permAdmin,
+	"GET /api/admin/catalog/export":  permAdmin,
+	"POST /api/admin/catalog/import": permAdmin,
+	"POST /api/admin/dataset/export": permAdmin,
+
+	"GET /api/admin/dead-letters":                 permAdmin,
+	"POST /api/admin/redeliveries":                permAdmin,
+	"POST /api/admin/dead-letters/{id}/redeliver": permAdmin,
+	"DELETE /api/admin/dead-letters/{id}":         permAdmin,
+	// Withdrawing consent must work even after a key is downgraded.
+	"DELETE /api/me/consent/{id}": permRead,
+	// Sync only reads; it is a POST because the held hashes can be many.
+	"POST /api/sync": permRead,
+}
+
+func parseRole(s string) (role, bool) {
+	switch r := role(strings.ToLower(strings.TrimSpace(s))); r {
+	case roleAdmin, roleMember, roleViewer:
+		return r, true
+	case "":
+		return defaultRole, true
+	}
+	// Fail closed: a typo must not grant more than reading.
+	return roleViewer, false
+}
+
+// Returns the permission a route pattern such as "GET /api/orgs/{id}" needs.
+func routePermission(pattern string) permission {
+	if p, ok := routePermissions[pattern]; ok {
+		return p
+	}
+	if method, _, _ := strings.Cut(pattern, " "); method == "GET" || method == "HEAD" {
+		return permRead
+	}
+	return permWrite
+}
+
+func (r role) can(p permission) bool {
+	for _, have := range rolePermissions[r] {
+		if have == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Rejects requests whose API key's role lacks the route's permission. It
+// runs after requireAPIKey; without API keys every request is allowed.
+func authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := apiKeyFrom(r.Context())
+		// r.Pattern has no method for the 405 fallback, which is harmless.
+		if k == nil || !strings.Contains(r.Pattern, " ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if p := routePermission(r.Pattern); !k.role.can(p) {
+			log.Printf("forbidden pattern=%q role=%s", r.Pattern, k.role)
+			writeError(w, r, codeForbidden, k.role)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
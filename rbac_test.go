@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys_Roles(t *testing.T) {
+	keys := parseAPIKeys("a=10:admin,b=0:Viewer,c=5,d=0:root")
+	want := []role{roleAdmin, roleViewer, defaultRole, roleViewer}
+	for i, k := range keys {
+		if k.role != want[i] {
+			t.Errorf("keys[%d].role = %q, want %q", i, k.role, want[i])
+		}
+	}
+	if keys[0].budget != 10 || keys[2].budget != 5 {
+		t.Errorf("budgets = %d, %d", keys[0].budget, keys[2].budget)
+	}
+}
+
+// Every registered route, for every role.
+func TestRoutePolicy_AllRoutes(t *testing.T) {
+	srv := newTestServer("")
+	for path, ops := range srv.spec.Paths {
+		for method := range ops {
+			pattern := strings.ToUpper(method) + " " + path
+			p := routePermission(pattern)
+			admin := strings.HasPrefix(path, "/api/admin/")
+			if admin != (p == permAdmin) {
+				t.Errorf("%s needs %s", pattern, p)
+			}
+			for _, r := range []role{roleAdmin, roleMember, roleViewer} {
+				var want bool
+				switch r {
+				case roleAdmin:
+					want = true
+				case roleMember:
+					want = p == permRead || p == permWrite
+				case roleViewer:
+					want = p == permRead
+				}
+				if got := r.can(p); got != want {
+					t.Errorf("%s as %s: allowed = %v, want %v", pattern, r, got, want)
+				}
+			}
+		}
+	}
+	for pattern := range routePermissions {
+		method, path, _ := strings.Cut(pattern, " ")
+		if srv.spec.Paths[path][strings.ToLower(method)] == nil {
+			t.Errorf("policy lists unregistered route %s", pattern)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	setAPIKeys(t, "adm=0:admin,mem=0:member,view=0:viewer")
+	old := orgsEnabled
+	orgsEnabled = true
+	t.Cleanup(func() { orgsEnabled = old })
+	srv := newTestServer("")
+
+	tests := []struct {
+		method, path, body string
+		want               map[string]int // API key to status
+	}{
+		{"GET", "/api/search?q=x", "", map[string]int{"adm": 200, "mem": 200, "view": 200}},
+		{"GET", "/api/subscriptions", "", map[string]int{"adm": 200, "mem": 200, "view": 200}},
+		{"DELETE", "/api/subscriptions/nope", "", map[string]int{"adm": 404, "mem": 404, "view": 403}},
+		{"POST", "/api/orgs", `{"name":"x"}`, map[string]int{"adm": 201, "mem": 403, "view": 403}},
+		{"GET", "/api/orgs/nope/schedule?semester=2025-2", "", map[string]int{"adm": 404, "mem": 403, "view": 403}},
+		{"DELETE", "/api/orgs/nope/members/m", "", map[string]int{"adm": 404, "mem": 404, "view": 403}},
+		{"GET", "/api/admin/metrics", "", map[string]int{"adm": 200, "mem": 403, "view": 403}}, // SIX_ADMIN_TOKEN is unset
+	}
+	for _, tt := range tests {
+		for key, want := range tt.want {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-API-Key", key)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s %s as %s: status %d, want %d", tt.method, tt.path, key, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestAuthorize_NoAPIKeys(t *testing.T) {
+	setAPIKeys(t, "")
+	w := httptest.NewRecorder()
+	newTestServer("").ServeHTTP(w, httptest.NewRequest("DELETE", "/api/subscriptions/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404 without API keys", w.Code)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// When set, every /api/ exchange is written to this directory as a cassette:
+// the inbound request, the response, and the upstream pages it fetched.
+// Cassettes can be replayed offline for contract tests (see recorder_test.go).
+var recordDir = envString("SIX_RECORD_DIR", "")
+
+// Headers never written to cassettes because they carry credentials.
+var sensitiveHeaders = []string{"Cookie", "Set-Cookie", "Authorization", "X-Api-Key"}
+
+type Cassette struct {
+	Request  CassetteRequest    `json:"request"`
+	Response CassetteResponse   `json:"response"`
+	Upstream []CassetteUpstream `json:"upstream"`
+}
+
+type CassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"` // path and query
+	Headers http.Header `json:"headers,omitempty"`
+}
+
+// The response body is kept in Body when it is JSON, so cassettes stay
+// readable, in Text when it is other UTF-8 text such as iCal, CSV, or
+// markdown, and in Base64 otherwise, as for PNG or tar.gz.
+type CassetteResponse struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+	Base64      []byte          `json:"base64,omitempty"`
+}
+
+func newCassetteResponse(status int, contentType string, body []byte) CassetteResponse {
+	r := CassetteResponse{Status: status, ContentType: contentType}
+	switch trimmed := bytes.TrimSpace(body); {
+	case len(trimmed) == 0:
+	case json.Valid(trimmed):
+		r.Body = json.RawMessage(trimmed)
+	case utf8.Valid(body):
+		r.Text = string(body)
+	default:
+		r.Base64 = body
+	}
+	return r
+}
+
+// Returns the recorded response body, whichever field it was kept in.
+func (r CassetteResponse) bytes() []byte {
+	switch {
+	case r.Body != nil:
+		return r.Body
+	case r.Text != "":
+		return []byte(r.Text)
+	}
+	return r.Base64
+}
+
+type CassetteUpstream struct {
+	URL      string `json:"url"` // path and query relative to the SIX origin
+	Status   int    `json:"status"`
+	Location string `json:"location,omitempty"` // redirect target, relative to the SIX origin
+	Body     string `json:"body"`
+}
+
+type recording struct {
+	mu       sync.Mutex
+	upstream []CassetteUpstream
+}
+
+type recordingKey struct{}
+
+// Returns a copy of h without credential-carrying headers.
+func sanitizeHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range sensitiveHeaders {
+		out.Del(name)
+	}
+	for name := range out {
+		if strings.HasPrefix(name, "X-Six-") {
+			out.Del(name)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// Records the wrapped handler's traffic to recordDir. A no-op when recording is off.
+func recordTraffic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if recordDir == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recording{}
+		bw := &bodyWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(bw, r.WithContext(context.WithValue(r.Context(), recordingKey{}, rec)))
+
+		rec.mu.Lock()
+		c := Cassette{
+			Request:  CassetteRequest{Method: r.Method, URL: r.URL.RequestURI(), Headers: sanitizeHeaders(r.Header)},
+			Response: newCassetteResponse(bw.status, bw.Header().Get("Content-Type"), bw.body.Bytes()),
+			Upstream: rec.upstream,
+		}
+		rec.mu.Unlock()
+		if err := writeCassette(recordDir, c); err != nil {
+			log.Printf("cassette write error: %v", err)
+		}
+	})
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Writes c to a new file in dir. The file only appears once it is complete.
+func writeCassette(dir string, c Cassette) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	path, _, _ := strings.Cut(c.Request.URL, "?")
+	name := fmt.Sprintf("%s-%s-%s.json", time.Now().Format("20060102T150405.000000000"), c.Request.Method, unsafeFileChars.ReplaceAllString(strings.Trim(path, "/"), "_"))
+	return writeFileAtomic(filepath.Join(dir, name), buf.Bytes())
+}
+
+type bodyWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (bw *bodyWriter) WriteHeader(code int) {
+	if !bw.wroteHeader {
+		bw.status = code
+		bw.wroteHeader = true
+	}
+	bw.ResponseWriter.WriteHeader(code)
+}
+
+func (bw *bodyWriter) Write(p []byte) (int, error) {
+	bw.body.Write(p)
+	return bw.ResponseWriter.Write(p)
+}
+
+func (bw *bodyWriter) Unwrap() http.ResponseWriter { return bw.ResponseWriter }
+
+// Captures upstream responses into the recording carried by the request context.
+type recordingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	rec, _ := req.Context().Value(recordingKey{}).(*recording)
+	if err != nil || rec == nil {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec.mu.Lock()
+	rec.upstream = append(rec.upstream, CassetteUpstream{
+		URL:      req.URL.RequestURI(),
+		Status:   resp.StatusCode,
+		Location: strings.TrimPrefix(resp.Header.Get("Location"), req.URL.Scheme+"://"+req.URL.Host),
+		Body:     string(body),
+	})
+	rec.mu.Unlock()
+	return resp, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Student IDs, names, and SIX cookie values never reach the logs or the audit
+// log in plaintext. Everything logged goes through redactText, which
+// replaces a student ID with a pseudonym, a keyed hash under SIX_LOG_SALT, so
+// the lines of one student can still be followed without saying who they
+// are. Without a salt, a random one is used and pseudonyms change on every
+// restart. Request metrics are keyed by route pattern, never by path, so
+// they hold no IDs to begin with.
+var logSalt = envString("SIX_LOG_SALT", "")
+
+// Prefix of pseudonyms, so they are not mistaken for IDs.
+const pseudonymPrefix = "anon_"
+
+func init() {
+	if logSalt == "" {
+		logSalt = randomHex(16)
+	}
+	log.SetOutput(&redactingWriter{out: os.Stderr})
+}
+
+// Returns the pseudonym of a student ID or name, or "" for "".
+func pseudonym(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(logSalt))
+	mac.Write([]byte(value))
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+var (
+	// A field or query parameter carrying a student ID or name, as in
+	// "student_id=13520001" or "?student_id=13520001&semester=2025-1".
+	personalFieldRe = regexp.MustCompile(`(^|[\s?&;,("])(student_id|nim|name|nama)=([^\s&;,)"]+)`)
+	// The student ID in a SIX page path, as in "/app/mahasiswa:13520001+2025-1/...".
+	sixStudentPathRe = regexp.MustCompile(`(mahasiswa(?::|%3[Aa]))([^\s+/?&"]+)`)
+)
+
+var (
+	// A Cookie or Set-Cookie header with its value, to the end of the line.
+	cookieHeaderRe = regexp.MustCompile(`(?i)(\bset-cookie|\bcookie)(: ?)([^\r\n"]*)`)
+	// A name=value pair, or an X-Six-<Name> header with its value.
+	cookiePairRe = regexp.MustCompile(`(?i)(x-six-([A-Za-z0-9_.-]+): ?|([A-Za-z0-9_.!#$%'*+^|~-]+)=)([^\s;&,"]+)`)
+)
+
+// Reports whether cookies named name are forwarded to SIX: the required
+// ones, those matching SIX_COOKIE_PASSTHROUGH, and detected session cookies.
+// Checked when each line is logged, so cookies detected later are covered.
+func isForwardedCookie(name string) bool {
+	return slices.ContainsFunc(requiredCookies, func(c string) bool { return strings.EqualFold(c, name) }) ||
+		cookiePassthrough != nil && cookiePassthrough.MatchString(name) || isDetectedCookie(name)
+}
+
+// Returns s with every student ID and name replaced by its pseudonym and
+// the value of every cookie forwarded to SIX, and of every pair in a Cookie
+// or Set-Cookie header, by "[redacted]".
+func redactText(s string) string {
+	s = personalFieldRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := personalFieldRe.FindStringSubmatch(m)
+		value, err := url.QueryUnescape(sub[3])
+		if err != nil {
+			value = sub[3]
+		}
+		return sub[1] + sub[2] + "=" + pseudonym(value)
+	})
+	s = sixStudentPathRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := sixStudentPathRe.FindStringSubmatch(m)
+		return sub[1] + pseudonym(sub[2])
+	})
+	s = cookieHeaderRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := cookieHeaderRe.FindStringSubmatch(m)
+		return sub[1] + sub[2] + cookieValuesRe.ReplaceAllString(sub[3], "${1}[redacted]")
+	})
+	return cookiePairRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := cookiePairRe.FindStringSubmatch(m)
+		if !isForwardedCookie(sub[2] + sub[3]) {
+			return m
+		}
+		return sub[1] + "[redacted]"
+	})
+}
+
+// The value of each pair in a cookie header.
+var cookieValuesRe = regexp.MustCompile(`([^\s;=]+=)([^;]*)`)
+
+// Passes log lines on to out through redactText. The log package writes
+// each line with a single call.
+type redactingWriter struct {
+	out io.Writer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, redactText(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRedactText(t *testing.T) {
+	id := pseudonym("13520001")
+	if !strings.HasPrefix(id, pseudonymPrefix) || id != pseudonym("13520001") || id == pseudonym("13520002") {
+		t.Fatalf("pseudonym = %q", id)
+	}
+	for in, want := range map[string]string{
+		"cache hit student_id=13520001 semester=2025-1":                 "cache hit student_id=" + id + " semester=2025-1",
+		"GET /api/schedule?student_id=13520001&semester=current":        "GET /api/schedule?student_id=" + id + "&semester=current",
+		"fetch url=https://six.itb.ac.id/app/mahasiswa:13520001+2025-1": "fetch url=https://six.itb.ac.id/app/mahasiswa:" + id + "+2025-1",
+		"key=/app/mahasiswa%3A13520001/akademik/transkrip":              "key=/app/mahasiswa%3A" + id + "/akademik/transkrip",
+		"member joined name=Budi":                                       "member joined name=" + pseudonym("Budi"),
+		"Cookie: nissin=abc123; khongguan=def456":                       "Cookie: nissin=[redacted]; khongguan=[redacted]",
+		"X-Six-Nissin: abc123":                                          "X-Six-Nissin: [redacted]",
+		"missing required nissin cookie":                                "missing required nissin cookie",
+		"parsed classes=12 semester=2025-1":                             "parsed classes=12 semester=2025-1",
+	} {
+		if got := redactText(in); got != want {
+			t.Errorf("redactText(%q)\n got %q\nwant %q", in, got, want)
+		}
+	}
+}
+
+// A log destination that is safe to read while handlers still log.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Sends the log through the redacting writer, as in production, into a
+// buffer for the test to read.
+func captureLog(t *testing.T) *logBuffer {
+	t.Helper()
+	b := &logBuffer{}
+	log.SetOutput(&redactingWriter{out: b})
+	t.Cleanup(func() { log.SetOutput(&redactingWriter{out: os.Stderr}) })
+	return b
+}
+
+func TestLogs_NoPlaintextStudentData(t *testing.T) {
+	logs := captureLog(t)
+	setupWebhooks(t)
+	mock := mockSIX("13520001", "1945-1")
+	defer mock.Close()
+	srv := newWebhookTestServer(t, mock.URL)
+
+	for _, target := range []string{
+		"/api/schedule?student_id=13520001&semester=1945-1",
+		"/api/user",
+		"/api/transcript?student_id=13520001",
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.AddCookie(&http.Cookie{Name: "nissin", Value: "cookie-one"})
+		req.Header.Set("X-Six-khongguan", "cookie-two")
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	postSubscription(t, strings.ReplaceAll(testSubscriptionBody, `"123"`, `"13520001"`))
+
+	out := logs.String()
+	for _, secret := range []string{"13520001", "cookie-one", "cookie-two"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log leaks %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "student_id="+pseudonym("13520001")) {
+		t.Errorf("log has no pseudonymized student ID:\n%s", out)
+	}
+
+	metrics, _ := json.Marshal(srv.metrics.snapshot())
+	if bytes.Contains(metrics, []byte("13520001")) {
+		t.Errorf("metrics leak the student ID: %s", metrics)
+	}
+}
+
+func TestLogs_NoPlaintextForwardedCookies(t *testing.T) {
+	logs := captureLog(t)
+	setCookieConfig(t, []string{"nissin"}, "^XSRF")
+	detectSessionCookies(&http.Response{Header: http.Header{"Set-Cookie": {"sixsession=from-six; HttpOnly"}}})
+
+	in := httptest.NewRequest("GET", "/api/user", nil)
+	for name, value := range map[string]string{"nissin": "cookie-one", "XSRF-TOKEN": "cookie-two", "sixsession": "cookie-three"} {
+		in.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	req := httptest.NewRequest("GET", "https://six.test/home", nil)
+	if err := forwardCookies(req, in); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("upstream request Cookie: %s", req.Header.Get("Cookie"))
+	for _, c := range req.Cookies() {
+		log.Printf("forwarded %s=%s records=3", c.Name, c.Value)
+	}
+	log.Printf("SIX answered Set-Cookie: sixsession=from-six; HttpOnly")
+
+	out := logs.String()
+	for _, secret := range []string{"cookie-one", "cookie-two", "cookie-three", "from-six"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log leaks %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "forwarded XSRF-TOKEN=[redacted] records=3") {
+		t.Errorf("log does not redact only the passthrough cookie:\n%s", out)
+	}
+}
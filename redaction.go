@@ -0,0 +1,87 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// A shared or demo deployment can hand out API keys whose responses hide who
+// teaches and what the class notes say, while keeping everything else:
+// codes, names of courses, SKS, rooms, and times. A key picks a redaction
+// profile after its role in SIX_API_KEYS, as in "demo=100:viewer:demo".
+// SIX_REDACTION_PROFILES defines profiles as the fields they mask, e.g.
+// "demo=lecturers+notes,quiet=notes". withResponseStyle masks them in JSON
+// responses on the way out, so handlers need not know about profiles.
+var redactionProfiles = parseRedactionProfiles(envString("SIX_REDACTION_PROFILES", "demo=lecturers+notes"))
+
+// What masked values are replaced with.
+const (
+	maskedLecturer = "Dosen ****"
+	maskedText     = "****"
+)
+
+type redactionProfile struct {
+	name      string
+	lecturers bool // lecturer names, in "lecturers" lists and their "name"s, and "advisor"
+	notes     bool // "notes" and "notes_html"
+}
+
+// The profile of keys naming one that is not defined: everything masked.
+var strictestProfile = &redactionProfile{name: "strict", lecturers: true, notes: true}
+
+// Parses "name=field+field,...". Unknown fields are logged and skipped.
+func parseRedactionProfiles(spec string) map[string]*redactionProfile {
+	profiles := make(map[string]*redactionProfile)
+	for _, entry := range splitList(spec) {
+		name, fields, _ := strings.Cut(entry, "=")
+		p := &redactionProfile{name: strings.TrimSpace(name)}
+		for field := range strings.SplitSeq(fields, "+") {
+			switch strings.TrimSpace(field) {
+			case "lecturers":
+				p.lecturers = true
+			case "notes":
+				p.notes = true
+			case "":
+			default:
+				log.Printf("config: unknown field %q in SIX_REDACTION_PROFILES profile %s, skipping", field, p.name)
+			}
+		}
+		if p.name != "" {
+			profiles[p.name] = p
+		}
+	}
+	return profiles
+}
+
+// Returns the profile called name, or nil for "". A name that is not
+// defined fails closed to strictestProfile.
+func lookupRedactionProfile(name string) (*redactionProfile, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, true
+	}
+	if p, ok := redactionProfiles[name]; ok {
+		return p, true
+	}
+	return strictestProfile, false
+}
+
+// Reports whether the value of key holds lecturer names under p: the
+// "lecturers" of a class, or of a lecturer report.
+func (p *redactionProfile) masksLecturers(key string) bool {
+	return p != nil && p.lecturers && key == "lecturers"
+}
+
+// Returns the masked form of the string value of key. inLecturers is set
+// inside a value that masksLecturers. An FRS "advisor" is a lecturer too.
+func (p *redactionProfile) mask(key string, inLecturers bool, value string) string {
+	switch {
+	case p == nil || value == "":
+		return value
+	case inLecturers && (key == "" || key == "name"), p.lecturers && key == "advisor":
+		return maskedLecturer
+	case p.notes && (key == "notes" || key == "notes_html"):
+		return maskedText
+	}
+	return value
+}
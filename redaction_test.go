@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys_RedactionProfiles(t *testing.T) {
+	keys := parseAPIKeys("a=0:viewer:demo,b=0::demo,c=0,d=0:member:nope")
+	if keys[0].profile != redactionProfiles["demo"] || keys[0].role != roleViewer || keys[1].profile != redactionProfiles["demo"] {
+		t.Errorf("profiles = %v, %v", keys[0].profile, keys[1].profile)
+	}
+	if keys[2].profile != nil {
+		t.Errorf("key without a profile: %v", keys[2].profile)
+	}
+	if keys[3].profile != strictestProfile {
+		t.Errorf("unknown profile = %v, want the strictest", keys[3].profile)
+	}
+
+	profiles := parseRedactionProfiles("quiet=notes, all=lecturers+notes+bogus")
+	if p := profiles["quiet"]; p == nil || p.lecturers || !p.notes {
+		t.Errorf("quiet = %+v", p)
+	}
+	if p := profiles["all"]; p == nil || !p.lecturers || !p.notes {
+		t.Errorf("all = %+v", p)
+	}
+}
+
+func TestRestyleJSON_Redaction(t *testing.T) {
+	in := `{"data":[{"code":"IF2211","name":"Strategi Algoritma","lecturers":["Dr. Budi","Dr. Ani"],"notes":"Kelas gabungan","notes_html":"<b>Kelas</b>","schedules":[{"room":"7602"}]}],` +
+		`"lecturers":[{"name":"Dr. Budi","courses":["IF2211"],"classes":2}],"stats":{"lecturers":3}}` + "\n"
+	want := `{"data":[{"code":"IF2211","name":"Strategi Algoritma","lecturers":["Dosen ****","Dosen ****"],"notes":"****","notes_html":"****","schedules":[{"room":"7602"}]}],` +
+		`"lecturers":[{"name":"Dosen ****","courses":["IF2211"],"classes":2}],"stats":{"lecturers":3}}` + "\n"
+	got, err := restyleJSON([]byte(in), responseStyle{redact: redactionProfiles["demo"]}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	quiet := &redactionProfile{name: "quiet", notes: true}
+	got, _ = restyleJSON([]byte(`{"lecturers":["Dr. Budi"],"notes":""}`), responseStyle{camel: true, redact: quiet}, nil)
+	if string(got) != `{"lecturers":["Dr. Budi"],"notes":""}`+"\n" {
+		t.Errorf("notes only: got %s", got)
+	}
+}
+
+func TestRedactionProfile_PerAPIKey(t *testing.T) {
+	setAPIKeys(t, "full=0,demo=0:viewer:demo")
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func(key, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("X-API-Key", key)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	target := "/api/schedule?student_id=123&semester=1945-1"
+	if w := get("full", target); !strings.Contains(w.Body.String(), "Dosen A") {
+		t.Errorf("key without a profile: %s", w.Body)
+	}
+	w := get("demo", target)
+	classes := decodeData[[]CourseClass](t, w)
+	if len(classes) != 2 || classes[0].Code != "FI1210" || len(classes[0].Lecturers) != 2 || classes[0].Lecturers[0] != maskedLecturer || classes[0].Notes != maskedText {
+		t.Errorf("demo key got %+v", classes)
+	}
+	if body := w.Body.String(); strings.Contains(body, "Dosen A") || strings.Contains(body, "Catatan") {
+		t.Errorf("demo key sees names or notes: %s", body)
+	}
+	if !slices.Contains(w.Header().Values("Vary"), "X-API-Key") {
+		t.Errorf("Vary = %q", w.Header().Values("Vary"))
+	}
+
+	// The FRS names the student's advisor, a lecturer.
+	mockFRS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testFRSHTML)
+	}))
+	defer mockFRS.Close()
+	frsSrv := newTestServer(mockFRS.URL)
+	req := httptest.NewRequest("GET", "/api/frs?student_id=123&semester=2025-1", nil)
+	req.Header.Set("X-API-Key", "demo")
+	addAuthCookies(req)
+	rec := httptest.NewRecorder()
+	frsSrv.ServeHTTP(rec, req)
+	if frs := decodeData[FRS](t, rec); frs.Advisor != maskedLecturer || len(frs.Classes) == 0 || strings.Contains(rec.Body.String(), "Budi") {
+		t.Errorf("demo key got FRS %s", rec.Body)
+	}
+
+	// The calendar cannot be masked field by field, so it is refused.
+	w = get("demo", "/api/schedule/ical?student_id=123&semester=1945-1")
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), string(codeRedactedFormat)) || strings.Contains(w.Body.String(), "Dosen A") {
+		t.Errorf("ical: got status %d: %s", w.Code, w.Body)
+	}
+}
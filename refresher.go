@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Background refreshes, whether stale-while-revalidate, grade watches, pekan
+// prefetches, catalog warming, or catalog crawls, share one bounded queue. At most
+// SIX_REFRESH_PER_HOST of them run against an upstream host at a time and at
+// most SIX_REFRESH_QUEUE wait; further work is dropped and counted rather
+// than piling up. Together with the batch priority of their fetches, this
+// keeps background work from taking upstream capacity that interactive
+// requests need, however many watchers are enabled.
+var (
+	refreshPerHost = envInt("SIX_REFRESH_PER_HOST", 2)
+	refreshQueue   = envInt("SIX_REFRESH_QUEUE", 100)
+	// How long past its expiry a cached schedule may still be served while
+	// it is refreshed in the background. 0 turns stale-while-revalidate off.
+	staleWhileRevalidate = envDuration("SIX_STALE_WHILE_REVALIDATE", 0)
+)
+
+// Kinds of background refresh.
+const (
+	refreshRevalidate   = "revalidate"
+	refreshGradeWatch   = "grade_watch"
+	refreshPrefetch     = "prefetch_pekan"
+	refreshCatalogWarm  = "catalog_warm"
+	refreshCatalogCrawl = "catalog_crawl"
+)
+
+type RefreshStats struct {
+	Queued    int   `json:"queued"` // waiting for a slot now
+	Running   int   `json:"running"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	Dropped   int64 `json:"dropped"`   // turned away because the queue was full
+	Coalesced int64 `json:"coalesced"` // skipped because the same refresh was already queued or running
+}
+
+type RefresherStatus struct {
+	PerHost   int                     `json:"per_host"`
+	QueueSize int                     `json:"queue_size"`
+	Queued    int                     `json:"queued"`
+	Kinds     map[string]RefreshStats `json:"kinds"`
+}
+
+type refreshWaiter struct {
+	kind string
+	ch   chan struct{}
+}
+
+type refresher struct {
+	mu       sync.Mutex
+	perHost  int
+	queueMax int
+	running  map[string]int             // by host
+	waiting  map[string][]refreshWaiter // by host, oldest first
+	queued   int
+	keys     map[string]bool // refreshes queued or running
+	stats    map[string]*RefreshStats
+}
+
+func newRefresher(perHost, queueSize int) *refresher {
+	return &refresher{
+		perHost:  max(perHost, 1),
+		queueMax: max(queueSize, 0),
+		running:  make(map[string]int),
+		waiting:  make(map[string][]refreshWaiter),
+		keys:     make(map[string]bool),
+		stats:    make(map[string]*RefreshStats),
+	}
+}
+
+func (rf *refresher) statsLocked(kind string) *RefreshStats {
+	st, ok := rf.stats[kind]
+	if !ok {
+		st = &RefreshStats{}
+		rf.stats[kind] = st
+	}
+	return st
+}
+
+// Runs fn once a slot for host is free, in the calling goroutine. kind and
+// key identify the refresh: if the same one is already queued or running, fn
+// is not run. Nor is it if the queue is full or ctx is done first. Reports
+// whether fn ran.
+func (rf *refresher) run(ctx context.Context, kind, host, key string, fn func(context.Context) error) bool {
+	key = kind + " " + key
+	rf.mu.Lock()
+	st := rf.statsLocked(kind)
+	if rf.keys[key] {
+		st.Coalesced++
+		rf.mu.Unlock()
+		return false
+	}
+	if rf.running[host] < rf.perHost {
+		rf.running[host]++
+		st.Running++
+		rf.keys[key] = true
+		rf.mu.Unlock()
+	} else {
+		if rf.queued >= rf.queueMax {
+			st.Dropped++
+			rf.mu.Unlock()
+			log.Printf("refresh dropped kind=%s key=%s: queue full", kind, key)
+			return false
+		}
+		w := refreshWaiter{kind: kind, ch: make(chan struct{})}
+		rf.waiting[host] = append(rf.waiting[host], w)
+		rf.queued++
+		st.Queued++
+		rf.keys[key] = true
+		rf.mu.Unlock()
+
+		select {
+		case <-w.ch:
+		case <-ctx.Done():
+			rf.mu.Lock()
+			defer rf.mu.Unlock()
+			delete(rf.keys, key)
+			if i := slices.IndexFunc(rf.waiting[host], func(o refreshWaiter) bool { return o.ch == w.ch }); i >= 0 {
+				rf.waiting[host] = slices.Delete(rf.waiting[host], i, i+1)
+				rf.queued--
+				st.Queued--
+				return false
+			}
+			// The slot was granted while we were giving up; pass it on.
+			st.Running--
+			rf.releaseLocked(host)
+			return false
+		}
+	}
+
+	err := fn(ctx)
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	delete(rf.keys, key)
+	st.Running--
+	if err != nil {
+		st.Failed++
+	} else {
+		st.Completed++
+	}
+	rf.releaseLocked(host)
+	return true
+}
+
+// Frees a slot of host, handing it to the oldest waiter if there is one.
+func (rf *refresher) releaseLocked(host string) {
+	if len(rf.waiting[host]) == 0 {
+		rf.running[host]--
+		return
+	}
+	w := rf.waiting[host][0]
+	rf.waiting[host] = rf.waiting[host][1:]
+	rf.queued--
+	st := rf.statsLocked(w.kind)
+	st.Queued--
+	st.Running++
+	close(w.ch)
+}
+
+func (rf *refresher) status() RefresherStatus {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	out := RefresherStatus{PerHost: rf.perHost, QueueSize: rf.queueMax, Queued: rf.queued, Kinds: make(map[string]RefreshStats, len(rf.stats))}
+	for kind, st := range rf.stats {
+		out.Kinds[kind] = *st
+	}
+	return out
+}
+
+// The host upstream fetches go to, for the per-host limit.
+func (s *Server) upstreamHost() string {
+	u, err := url.Parse(s.cfg.BaseURL)
+	if err != nil {
+		return s.cfg.BaseURL
+	}
+	return u.Host
+}
+
+// Refreshes a cached schedule in the background with the credentials of r,
+// which was just served the stale copy.
+func (s *Server) revalidate(r *http.Request, key, studentID, semester string, query url.Values) {
+	auth := sixAuthHeaders(r)
+	go s.refresher.run(context.Background(), refreshRevalidate, s.upstreamHost(), key, func(ctx context.Context) error {
+		if scrapingPaused() {
+			return errUpstreamMaintenance
+		}
+		ctx, cancel := context.WithTimeout(withPriority(ctx, priorityBatch), time.Minute)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, "GET", "/api/schedule", nil)
+		if err != nil {
+			return err
+		}
+		req.Header = auth
+		classes, meta, err := s.scrapeSchedule(req, studentID, semester, query)
+		if err != nil {
+			log.Printf("revalidate failed student_id=%s semester=%s err=%v", studentID, semester, err)
+			return err
+		}
+		if ckey, ok := catalogKey(semester, query); ok && meta.Anomaly == nil {
+			s.catalog.set(ckey, classes, meta.FetchedAt)
+		}
+		return nil
+	})
+}
+
+// GET /api/admin/refresher
+func (s *Server) refresherHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	writeSuccess(w, s.refresher.status())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresher_BoundsQueueAndCountsDroppedWork(t *testing.T) {
+	rf := newRefresher(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		done <- rf.run(t.Context(), "a", "six", "1", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	if rf.run(t.Context(), "a", "six", "1", nil) {
+		t.Error("a refresh already running should be coalesced")
+	}
+	ranB := make(chan bool)
+	go func() {
+		ranB <- rf.run(t.Context(), "b", "six", "2", func(context.Context) error { return errors.New("boom") })
+	}()
+	waitFor(t, func() bool { return rf.status().Queued == 1 })
+	if rf.run(t.Context(), "b", "six", "3", nil) {
+		t.Error("work past the queue limit should be dropped")
+	}
+	if !rf.run(t.Context(), "b", "other", "4", func(context.Context) error { return nil }) {
+		t.Error("another host should have its own slots")
+	}
+
+	close(release)
+	if !<-done || !<-ranB {
+		t.Fatal("queued refresh did not run")
+	}
+	st := rf.status()
+	if a := st.Kinds["a"]; a.Completed != 1 || a.Coalesced != 1 || a.Running != 0 {
+		t.Errorf("a = %+v", a)
+	}
+	if b := st.Kinds["b"]; b.Completed != 1 || b.Failed != 1 || b.Dropped != 1 || b.Queued != 0 {
+		t.Errorf("b = %+v", b)
+	}
+}
+
+func TestRefresher_CancelWhileQueued(t *testing.T) {
+	rf := newRefresher(1, 5)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go rf.run(t.Context(), "a", "six", "1", func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(t.Context())
+	ran := make(chan bool)
+	go func() { ran <- rf.run(ctx, "a", "six", "2", func(context.Context) error { return nil }) }()
+	waitFor(t, func() bool { return rf.status().Queued == 1 })
+	cancel()
+	if <-ran {
+		t.Error("cancelled refresh ran")
+	}
+	close(release)
+	waitFor(t, func() bool { return rf.status().Kinds["a"].Running == 0 })
+	if !rf.run(t.Context(), "a", "six", "2", func(context.Context) error { return nil }) {
+		t.Error("slot was not freed")
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	defer func(d time.Duration) { staleWhileRevalidate = d }(staleWhileRevalidate)
+	staleWhileRevalidate = time.Hour
+
+	var fetches atomic.Int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer mock.Close()
+	srv := NewServer(Config{BaseURL: mock.URL, CacheTTL: time.Millisecond})
+	get := func() Meta {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1", nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		var resp struct{ Meta Meta }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		return resp.Meta
+	}
+
+	if meta := get(); meta.Stale {
+		t.Error("first fetch marked stale")
+	}
+	time.Sleep(5 * time.Millisecond)
+	n := fetches.Load()
+	if meta := get(); !meta.Stale || !meta.Cached {
+		t.Errorf("expired entry: meta = %+v, want stale", meta)
+	}
+	waitFor(t, func() bool { return fetches.Load() > n })
+	waitFor(t, func() bool { return srv.refresher.status().Kinds[refreshRevalidate].Completed == 1 })
+}
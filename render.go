@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GET /api/schedule/render?format=epaper draws the week as a 1-bit PNG for
+// e-ink dashboards. The layout uses only black and white, thick lines, and
+// no current-time marker, so the image changes only when the schedule does.
+// The ETag is a hash of the image, so a display can poll with
+// If-None-Match and refresh only on a 200.
+
+// Limits of the width and height parameters, in pixels.
+const (
+	renderMinSize = 200
+	renderMaxSize = 2000
+)
+
+// 800x480 is the resolution of common 7.5" panels.
+const (
+	renderDefaultWidth  = 800
+	renderDefaultHeight = 480
+)
+
+const (
+	inkWhite uint8 = iota
+	inkBlack
+)
+
+var epaperPalette = color.Palette{color.White, color.Black}
+
+// The hours always shown, so that the grid does not shift between weeks.
+const (
+	renderDayStart = 7 * 60
+	renderDayEnd   = 18 * 60
+)
+
+// Draws the weekly grid at width x height. The header shows the student ID,
+// semester, and when the schedule was fetched.
+func renderEpaper(grid ScheduleGrid, studentID, semester string, fetchedAt time.Time, width, height int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, width, height), epaperPalette)
+	scale := max(1, min(width/400, height/240))
+	cellW, cellH := fontCellW*scale, fontCellH*scale
+	line := max(1, scale)
+	pad := 2 * scale
+
+	// Header, inverted.
+	headerH := cellH + 2*pad
+	fillRect(img, image.Rect(0, 0, width, headerH), inkBlack)
+	drawText(img, pad, pad, scale, width, studentID+" "+semester, inkWhite)
+	updated := fetchedAt.In(wib).Format("02/01 15:04")
+	drawText(img, width-pad-len(updated)*cellW, pad, scale, width, updated, inkWhite)
+
+	// Weekdays, plus the weekend only if it has classes.
+	days := slices.Clone(indonesianDays[:5])
+	start, end := renderDayStart, renderDayEnd
+	for _, d := range grid.Days {
+		if (d.Day == "Sabtu" || d.Day == "Minggu") && len(d.Sessions) > 0 && !slices.Contains(days, d.Day) {
+			days = append(days, d.Day)
+		}
+		for _, s := range d.Sessions {
+			if m, ok := clockMinutes(s.Start); ok {
+				start = min(start, m/60*60)
+			}
+			if m, ok := clockMinutes(s.End); ok {
+				end = max(end, (m+59)/60*60)
+			}
+		}
+	}
+	slices.SortFunc(days, func(a, b string) int { return cmp.Compare(dayRank(a), dayRank(b)) })
+
+	axisW := 5*cellW + 2*pad
+	dayHeaderH := cellH + 2*pad
+	top := headerH + dayHeaderH
+	colW := (width - axisW) / len(days)
+	minuteH := float64(height-top-pad) / float64(end-start)
+	yOf := func(minutes int) int { return top + int(float64(minutes-start)*minuteH) }
+
+	// Hour lines, dotted so that they stay lighter than session borders.
+	for h := start; h <= end; h += 60 {
+		y := yOf(h)
+		for x := axisW; x < width; x += 4 {
+			img.SetColorIndex(x, y, inkBlack)
+		}
+		if h < end {
+			drawText(img, pad, y+pad, scale, axisW, clockString(h), inkBlack)
+		}
+	}
+
+	for i, day := range days {
+		x0 := axisW + i*colW
+		fillRect(img, image.Rect(x0, headerH, x0+line, height), inkBlack)
+		drawText(img, x0+pad, headerH+pad, scale, x0+colW, day, inkBlack)
+		fillRect(img, image.Rect(x0, top-line, x0+colW, top), inkBlack)
+
+		var sessions []GridSession
+		for _, d := range grid.Days {
+			if d.Day == day {
+				sessions = d.Sessions
+			}
+		}
+		lanes, laneCount := sessionLanes(sessions)
+		laneW := colW / max(laneCount, 1)
+		for j, s := range sessions {
+			from, ok1 := clockMinutes(s.Start)
+			to, ok2 := clockMinutes(s.End)
+			if !ok1 || !ok2 || to <= from {
+				continue
+			}
+			box := image.Rect(x0+lanes[j]*laneW+pad, yOf(from)+line, x0+(lanes[j]+1)*laneW-pad, yOf(to)-line)
+			drawSession(img, box, s, scale, line)
+		}
+	}
+	return img
+}
+
+// Assigns each of a day's sessions, sorted by start, to the first lane free
+// at its start. Returns the lanes and how many there are.
+func sessionLanes(sessions []GridSession) ([]int, int) {
+	lanes := make([]int, len(sessions))
+	var laneEnds []int
+	for i, s := range sessions {
+		from, _ := clockMinutes(s.Start)
+		to, _ := clockMinutes(s.End)
+		lane := slices.IndexFunc(laneEnds, func(end int) bool { return end <= from })
+		if lane < 0 {
+			lane = len(laneEnds)
+			laneEnds = append(laneEnds, 0)
+		}
+		laneEnds[lane] = to
+		lanes[i] = lane
+	}
+	return lanes, len(laneEnds)
+}
+
+// Draws a session as a box with its code, room, and times, as many lines as
+// fit. Online sessions get a dashed border.
+func drawSession(img *image.Paletted, box image.Rectangle, s GridSession, scale, line int) {
+	border := 2 * line
+	online := strings.EqualFold(s.Method, "online")
+	if online {
+		for x := box.Min.X; x < box.Max.X; x += 6 * line {
+			fillRect(img, image.Rect(x, box.Min.Y, x+3*line, box.Min.Y+border), inkBlack)
+			fillRect(img, image.Rect(x, box.Max.Y-border, x+3*line, box.Max.Y), inkBlack)
+		}
+		for y := box.Min.Y; y < box.Max.Y; y += 6 * line {
+			fillRect(img, image.Rect(box.Min.X, y, box.Min.X+border, y+3*line), inkBlack)
+			fillRect(img, image.Rect(box.Max.X-border, y, box.Max.X, y+3*line), inkBlack)
+		}
+	} else {
+		fillRect(img, image.Rect(box.Min.X, box.Min.Y, box.Max.X, box.Min.Y+border), inkBlack)
+		fillRect(img, image.Rect(box.Min.X, box.Max.Y-border, box.Max.X, box.Max.Y), inkBlack)
+		fillRect(img, image.Rect(box.Min.X, box.Min.Y, box.Min.X+border, box.Max.Y), inkBlack)
+		fillRect(img, image.Rect(box.Max.X-border, box.Min.Y, box.Max.X, box.Max.Y), inkBlack)
+	}
+
+	room := s.Room
+	if online {
+		room = "Online"
+	}
+	x, y := box.Min.X+border+scale, box.Min.Y+border+scale
+	times := s.Start + "-" + s.End
+	if x+len(times)*fontCellW*scale > box.Max.X-border {
+		times = s.Start
+	}
+	for _, text := range []string{s.Code, room, times} {
+		if text == "" {
+			continue
+		}
+		if y+7*scale > box.Max.Y-border {
+			return
+		}
+		drawText(img, x, y, scale, box.Max.X-border, text, inkBlack)
+		y += fontCellH * scale
+	}
+}
+
+// Reads an optional integer size parameter.
+func renderSize(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < renderMinSize || n > renderMaxSize {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, renderMinSize, renderMaxSize)
+	}
+	return n, nil
+}
+
+// GET /api/schedule/render
+func (s *Server) renderHandler(w http.ResponseWriter, r *http.Request) {
+	width, err := renderSize(r, "width", renderDefaultWidth)
+	if err != nil {
+		writeError(w, r, codeInvalidRequest, err.Error())
+		return
+	}
+	height, err := renderSize(r, "height", renderDefaultHeight)
+	if err != nil {
+		writeError(w, r, codeInvalidRequest, err.Error())
+		return
+	}
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	semester := cmp.Or(meta.Semester, query.Get("semester"))
+	img := renderEpaper(scheduleGrid(classes, nil), query.Get("student_id"), semester, meta.FetchedAt, width, height)
+
+	var buf bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img); err != nil {
+		writeError(w, r, codeInternal)
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRenderEpaper(t *testing.T) {
+	grid := scheduleGrid(parseClasses(docFromHTML(testScheduleHTML)), nil)
+	fetched := time.Date(2026, 10, 11, 20, 0, 0, 0, wib)
+	img := renderEpaper(grid, "13520001", "2025-2", fetched, 800, 480)
+	if img.Bounds() != image.Rect(0, 0, 800, 480) {
+		t.Errorf("bounds = %v", img.Bounds())
+	}
+	// The same schedule renders the same image.
+	again := renderEpaper(grid, "13520001", "2025-2", fetched, 800, 480)
+	if !bytes.Equal(img.Pix, again.Pix) {
+		t.Error("rendering is not deterministic")
+	}
+
+	black := 0
+	for _, p := range img.Pix {
+		if p == inkBlack {
+			black++
+		}
+	}
+	if black == 0 || black > len(img.Pix)/2 {
+		t.Errorf("%d of %d pixels are black", black, len(img.Pix))
+	}
+}
+
+func TestSessionLanes(t *testing.T) {
+	sessions := []GridSession{
+		{Start: "07:00", End: "09:00"},
+		{Start: "08:00", End: "10:00"},
+		{Start: "09:00", End: "11:00"},
+	}
+	lanes, n := sessionLanes(sessions)
+	if n != 2 || lanes[0] != 0 || lanes[1] != 1 || lanes[2] != 0 {
+		t.Errorf("lanes = %v, %d; want [0 1 0], 2", lanes, n)
+	}
+}
+
+func TestRenderHandler(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	get := func(query, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule/render?student_id=13520001&semester=2025-2"+query, nil)
+		addAuthCookies(req)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("&format=epaper&width=400&height=300", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("status %d, type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	body := w.Body.Bytes()
+	// IHDR: width, height, then bit depth 1 and color type 3 (palette).
+	if len(body) < 26 || body[24] != 1 || body[25] != 3 {
+		t.Errorf("PNG header = % x, want bit depth 1 with a palette", body[16:26])
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 400 || img.Bounds().Dy() != 300 {
+		t.Errorf("size = %v", img.Bounds())
+	}
+
+	etag := w.Header().Get("ETag")
+	if w := get("&format=epaper&width=400&height=300", etag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", w.Code)
+	}
+	if w := get("&width=100", ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("width=100: status %d, want 422", w.Code)
+	}
+	if w := get("&format=svg", ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("format=svg: status %d, want 422", w.Code)
+	}
+}
+
+func TestFontText(t *testing.T) {
+	if got := fontText("Kuliah Daring (Café) #1"); got != "KULIAH DARING (CAFE) ?1" {
+		t.Errorf("fontText = %q", got)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// "six-scraper-go replay" plays the GET requests of an audit log back against
+// an in-process server whose SIX is a set of recorded cassettes, then reports
+// per-route latency and every request whose status differs from the logged
+// one. Use it to reproduce a production parse error from its request ID, or
+// to profile the server (-cpuprofile) under a realistic mix of routes.
+
+// Upstream fixtures are served with these placeholder cookies, since audit
+// logs hold no credentials.
+const replayCookies = "nissin=replay; khongguan=replay"
+
+// At most this much of a mismatched response is kept in the report.
+const replayBodyExcerpt = 200
+
+// Serves upstream pages from cassettes instead of the network. With byKind
+// set, a URL that has no page of its own gets the first page of the same
+// kind (see sixPageKind), so a log covering many students can be replayed
+// against a few recorded ones.
+type replayTransport struct {
+	upstream []CassetteUpstream
+	byKind   bool
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.RequestURI()
+	i := slices.IndexFunc(t.upstream, func(u CassetteUpstream) bool { return u.URL == url })
+	if i < 0 && t.byKind {
+		if kind := sixPageKind(req.URL.Path); kind != "" {
+			i = slices.IndexFunc(t.upstream, func(u CassetteUpstream) bool {
+				path, _, _ := strings.Cut(u.URL, "?")
+				return sixPageKind(path) == kind
+			})
+		}
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("no cassette entry for %s", url)
+	}
+	u := t.upstream[i]
+	header := http.Header{}
+	if u.Location != "" {
+		header.Set("Location", u.Location)
+	}
+	return &http.Response{
+		StatusCode: u.Status,
+		Status:     fmt.Sprintf("%d %s", u.Status, http.StatusText(u.Status)),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(u.Body)),
+		Request:    req,
+	}, nil
+}
+
+// Returns which SIX page path is: home, kelas (which redirects to the
+// current semester), schedule, transcript, or curriculum. Returns "" for
+// anything else.
+func sixPageKind(path string) string {
+	switch {
+	case path == "/home":
+		return "home"
+	case strings.HasSuffix(path, "/kelas"):
+		return "kelas"
+	case strings.HasSuffix(path, "/kelas/jadwal/kuliah"):
+		return "schedule"
+	case strings.HasSuffix(path, "/akademik/transkrip"):
+		return "transcript"
+	case strings.HasSuffix(path, "/akademik/kurikulum"):
+		return "curriculum"
+	}
+	return ""
+}
+
+// Reads the upstream pages of every cassette in dir.
+func loadCassetteUpstream(dir string) ([]CassetteUpstream, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var upstream []CassetteUpstream
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var c Cassette
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(f), err)
+		}
+		upstream = append(upstream, c.Upstream...)
+	}
+	return upstream, nil
+}
+
+type ReplayReport struct {
+	Requests   int
+	Skipped    int // entries that are not GET requests
+	Elapsed    time.Duration
+	Routes     map[string]RouteStats
+	Mismatches []ReplayMismatch
+}
+
+// A replayed request whose status differs from the logged one.
+type ReplayMismatch struct {
+	RequestID string
+	URL       string
+	Logged    int
+	Replayed  int
+	Body      string // start of the replayed response
+}
+
+// Replays the GET requests of entries against srv. With speed 0 they are sent
+// one after another as fast as possible; otherwise they keep their logged
+// spacing, divided by speed, and may overlap.
+func replayAudit(srv *Server, entries []AuditEntry, speed float64) ReplayReport {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		report ReplayReport
+	)
+	replay := func(e AuditEntry) {
+		req := httptest.NewRequest("GET", e.URL, nil)
+		req.Header.Set("Cookie", replayCookies)
+		if requestIDRe.MatchString(e.RequestID) {
+			req.Header.Set(requestIDHeader, e.RequestID)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code == e.Status {
+			return
+		}
+		body := w.Body.String()
+		if len(body) > replayBodyExcerpt {
+			body = body[:replayBodyExcerpt]
+		}
+		mu.Lock()
+		report.Mismatches = append(report.Mismatches, ReplayMismatch{RequestID: e.RequestID, URL: e.URL, Logged: e.Status, Replayed: w.Code, Body: body})
+		mu.Unlock()
+	}
+
+	start := time.Now()
+	var first time.Time
+	for _, e := range entries {
+		if e.Method != "GET" {
+			report.Skipped++
+			continue
+		}
+		report.Requests++
+		if speed <= 0 {
+			replay(e)
+			continue
+		}
+		if first.IsZero() {
+			first = e.Time
+		}
+		time.Sleep(time.Until(start.Add(time.Duration(float64(e.Time.Sub(first)) / speed))))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replay(e)
+		}()
+	}
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	report.Routes = srv.metrics.snapshot()
+	slices.SortFunc(report.Mismatches, func(a, b ReplayMismatch) int { return cmp.Compare(a.RequestID, b.RequestID) })
+	return report
+}
+
+func printReplayReport(w io.Writer, report ReplayReport) {
+	fmt.Fprintf(w, "replayed %d requests in %s (skipped %d that were not GET)\n", report.Requests, report.Elapsed.Round(time.Millisecond), report.Skipped)
+	routes := make([]string, 0, len(report.Routes))
+	for route := range report.Routes {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+	for _, route := range routes {
+		st := report.Routes[route]
+		statuses := make([]string, 0, len(st.Statuses))
+		for status, n := range st.Statuses {
+			statuses = append(statuses, fmt.Sprintf("%s=%d", status, n))
+		}
+		slices.Sort(statuses)
+		fmt.Fprintf(w, "%s\t%d requests\tmean %.1fms\tmax %.1fms\t%s\n", route, st.Requests, st.MeanMS, st.MaxMS, strings.Join(statuses, " "))
+	}
+	if len(report.Mismatches) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d requests got a different status:\n", len(report.Mismatches))
+	for _, m := range report.Mismatches {
+		fmt.Fprintf(w, "%s\t%s\tlogged %d, replayed %d\t%s\n", m.RequestID, m.URL, m.Logged, m.Replayed, m.Body)
+	}
+}
+
+// Runs "six-scraper-go replay" and returns the exit code: 0 if every status
+// matched the log, 1 if any differed.
+func runReplayCommand(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	logPath := fs.String("log", os.Getenv("SIX_AUDIT_LOG"), "audit log to replay (default $SIX_AUDIT_LOG)")
+	fixtures := fs.String("fixtures", filepath.Join("testdata", "cassettes"), "directory of cassettes that stand in for SIX")
+	requestID := fs.String("request-id", "", "replay only this request")
+	speed := fs.Float64("speed", 0, "replay at this multiple of the logged pace; 0 sends requests back to back")
+	cpuProfile := fs.String("cpuprofile", "", "write a CPU profile of the replay to this file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *logPath == "" {
+		fmt.Fprintln(os.Stderr, "replay: -log is required")
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(*logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 2
+	}
+	entries, err := readAuditLog(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 2
+	}
+	if *requestID != "" {
+		entries = slices.DeleteFunc(entries, func(e AuditEntry) bool { return e.RequestID != *requestID })
+	}
+	upstream, err := loadCassetteUpstream(*fixtures)
+	if err != nil || len(upstream) == 0 {
+		fmt.Fprintf(os.Stderr, "replay: no upstream pages in %s: %v\n", *fixtures, err)
+		return 2
+	}
+
+	// Never touch the real SIX, data dir, or audit log.
+	cfg := configFromEnv()
+	cfg.BaseURL = "http://six.replay"
+	cfg.APIURL, cfg.PeerURL, cfg.DataDir, cfg.AuditLog = "", "", "", ""
+	cfg.Transport = &replayTransport{upstream: upstream, byKind: true}
+	srv := NewServer(cfg)
+
+	if *cpuProfile != "" {
+		out, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			return 2
+		}
+		defer out.Close()
+		if err := pprof.StartCPUProfile(out); err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			return 2
+		}
+		defer pprof.StopCPUProfile()
+	}
+	report := replayAudit(srv, entries, *speed)
+	printReplayReport(os.Stdout, report)
+	if len(report.Mismatches) > 0 {
+		return 1
+	}
+	return 0
+}
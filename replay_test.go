@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_RecordsAPIRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := NewServer(Config{BaseURL: mock.URL, AuditLog: path})
+
+	for _, target := range []string{"/api/schedule?student_id=123&semester=1945-1", "/healthz", "/api/nope"} {
+		req := httptest.NewRequest("GET", target, nil)
+		addAuthCookies(req)
+		req.Header.Set(requestIDHeader, "req-1")
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	srv.audit.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("nissin")) {
+		t.Error("audit log leaks cookies")
+	}
+	entries, err := readAuditLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want the two /api/ requests", entries)
+	}
+	e := entries[0]
+	if e.RequestID != "req-1" || e.Route != "GET /api/schedule" || e.Status != http.StatusOK || e.URL != "/api/schedule?student_id="+pseudonym("123")+"&semester=1945-1" {
+		t.Errorf("entry = %+v", e)
+	}
+	if entries[1].Status != http.StatusNotFound {
+		t.Errorf("unmatched entry = %+v", entries[1])
+	}
+}
+
+func TestReplayAudit(t *testing.T) {
+	upstream, err := loadCassetteUpstream(filepath.Join("testdata", "cassettes"))
+	if err != nil || len(upstream) == 0 {
+		t.Fatalf("fixtures: %d pages, %v", len(upstream), err)
+	}
+	srv := NewServer(Config{BaseURL: "http://six.replay", Transport: &replayTransport{upstream: upstream, byKind: true}})
+
+	t0 := time.Now()
+	entries := []AuditEntry{
+		{Time: t0, RequestID: "a", Method: "GET", URL: "/api/user", Status: 200},
+		// Another student: served the recorded schedule page by kind.
+		{Time: t0, RequestID: "b", Method: "GET", URL: "/api/schedule?student_id=13520001&semester=2024-2", Status: 200},
+		{Time: t0, RequestID: "c", Method: "POST", URL: "/api/sync", Status: 200},
+		// Logged as an upstream failure in production; succeeds on the fixtures.
+		{Time: t0, RequestID: "d", Method: "GET", URL: "/api/schedule?student_id=10245001&semester=1945-1", Status: 502},
+	}
+	report := replayAudit(srv, entries, 0)
+	if report.Requests != 3 || report.Skipped != 1 {
+		t.Errorf("requests = %d, skipped = %d", report.Requests, report.Skipped)
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].RequestID != "d" || report.Mismatches[0].Replayed != 200 {
+		t.Errorf("mismatches = %+v", report.Mismatches)
+	}
+	if st := report.Routes["GET /api/schedule"]; st.Requests != 2 {
+		t.Errorf("schedule route = %+v", st)
+	}
+
+	var out bytes.Buffer
+	printReplayReport(&out, report)
+	if !strings.Contains(out.String(), "logged 502, replayed 200") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestSIXPageKind(t *testing.T) {
+	tests := map[string]string{
+		"/home":                          "home",
+		"/app/mahasiswa:1/kelas":         "kelas",
+		schedulePath("1", "2024-2", nil): "schedule",
+		transcriptPath("1"):              "transcript",
+		curriculumPath("1"):              "curriculum",
+		"/app/other":                     "",
+	}
+	for path, want := range tests {
+		if got := sixPageKind(path); got != want {
+			t.Errorf("sixPageKind(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GET /api/analytics/rooms reports how much of the teaching week each room
+// is booked, for facilities staff. It combines every cached catalog page of
+// a faculty in a semester, so it never contacts SIX; the catalog warmer or
+// ordinary traffic has to have fetched the pages. Classes listed on several
+// pages, such as one shared by two prodi, are counted once.
+
+// The teaching week utilization is measured against, in minutes since
+// midnight and as day names.
+const (
+	roomDayStart = 7 * 60
+	roomDayEnd   = 18 * 60
+)
+
+var roomDays = []string{"Senin", "Selasa", "Rabu", "Kamis", "Jumat"}
+
+type RoomReport struct {
+	Semester  string            `json:"semester"`
+	Fakultas  string            `json:"fakultas"`
+	Pages     int               `json:"pages"`      // catalog pages combined
+	FetchedAt time.Time         `json:"fetched_at"` // when the oldest of them was fetched
+	Days      []string          `json:"days"`
+	Hours     []string          `json:"hours"` // start of each hour column, e.g. "07:00"
+	Rooms     []RoomUtilization `json:"rooms"`
+}
+
+type RoomUtilization struct {
+	Room     string `json:"room"`
+	Building string `json:"building,omitempty"`
+	// Percentage of the teaching week the room is booked, 0 to 100.
+	Utilization   float64 `json:"utilization"`
+	BookedMinutes int     `json:"booked_minutes"`
+	// ByDay[d][h] is the percentage of hour Hours[h] on Days[d] the room
+	// is booked.
+	ByDay [][]float64 `json:"by_day"`
+}
+
+// Builds the utilization of every room in classes. Online meetings, which
+// have no room, and meetings outside the teaching week are left out.
+func roomReport(classes []CourseClass, buildings []Building) RoomReport {
+	type meetingKey struct{ code, classNo, day, time, room string }
+	seen := make(map[meetingKey]bool)
+	// Booked minutes of each room, day, and minute of the day.
+	booked := make(map[string][][]bool)
+	for _, c := range classes {
+		for _, e := range c.Schedules {
+			k := meetingKey{c.Code, c.ClassNo, e.Day, e.Time, e.Room}
+			day := slices.Index(roomDays, e.Day)
+			if e.Room == "" || strings.EqualFold(e.Method, "online") || day < 0 || seen[k] {
+				continue
+			}
+			seen[k] = true
+			startText, endText, _ := strings.Cut(e.Time, "-")
+			start, ok1 := clockMinutes(startText)
+			end, ok2 := clockMinutes(endText)
+			if !ok1 || !ok2 {
+				continue
+			}
+			if booked[e.Room] == nil {
+				booked[e.Room] = make([][]bool, len(roomDays))
+				for d := range roomDays {
+					booked[e.Room][d] = make([]bool, roomDayEnd-roomDayStart)
+				}
+			}
+			// Overlapping classes in one room count once.
+			for m := max(start, roomDayStart); m < min(end, roomDayEnd); m++ {
+				booked[e.Room][day][m-roomDayStart] = true
+			}
+		}
+	}
+
+	report := RoomReport{Days: roomDays, Rooms: []RoomUtilization{}}
+	for h := roomDayStart; h < roomDayEnd; h += 60 {
+		report.Hours = append(report.Hours, clockString(h))
+	}
+	weekMinutes := len(roomDays) * (roomDayEnd - roomDayStart)
+	for room, days := range booked {
+		u := RoomUtilization{Room: room, ByDay: make([][]float64, len(roomDays))}
+		if b, ok := locateRoom(buildings, room); ok {
+			u.Building = b.Name
+		}
+		for d, minutes := range days {
+			u.ByDay[d] = make([]float64, len(report.Hours))
+			for h := range report.Hours {
+				n := 0
+				for _, b := range minutes[h*60 : min((h+1)*60, len(minutes))] {
+					if b {
+						n++
+					}
+				}
+				u.ByDay[d][h] = percent(n, 60)
+				u.BookedMinutes += n
+			}
+		}
+		u.Utilization = percent(u.BookedMinutes, weekMinutes)
+		report.Rooms = append(report.Rooms, u)
+	}
+	slices.SortFunc(report.Rooms, func(a, b RoomUtilization) int {
+		return cmp.Or(cmp.Compare(b.BookedMinutes, a.BookedMinutes), cmp.Compare(a.Room, b.Room))
+	})
+	return report
+}
+
+// Returns n/of as a percentage rounded to one decimal.
+func percent(n, of int) float64 {
+	return math.Round(float64(n)*1000/float64(of)) / 10
+}
+
+// Returns the classes of every unexpired catalog page of fakultas in
+// semester, how many pages there were, and when the oldest was fetched.
+func (s *Server) facultyCatalog(semester, fakultas string) ([]CourseClass, int, time.Time) {
+	var classes []CourseClass
+	var pages int
+	var oldest time.Time
+	for _, e := range s.catalog.archiveEntries() {
+		pageSemester, rawQuery, _ := strings.Cut(e.Key, "?")
+		filters, err := url.ParseQuery(rawQuery)
+		if err != nil || pageSemester != semester || !strings.EqualFold(filters.Get("fakultas"), fakultas) {
+			continue
+		}
+		classes = append(classes, e.Classes...)
+		pages++
+		if oldest.IsZero() || e.FetchedAt.Before(oldest) {
+			oldest = e.FetchedAt
+		}
+	}
+	return classes, pages, oldest
+}
+
+// GET /api/analytics/rooms
+func (s *Server) roomsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	semester, _ := s.semesters.resolve("", query.Get("semester"), time.Now())
+	fakultas := query.Get("fakultas")
+	classes, pages, fetchedAt := s.facultyCatalog(semester, fakultas)
+	if pages == 0 {
+		writeError(w, r, codeCatalogNotCached)
+		return
+	}
+	report := roomReport(classes, s.buildings)
+	report.Semester, report.Fakultas, report.Pages, report.FetchedAt = semester, fakultas, pages, fetchedAt
+
+	if query.Get("format") != "csv" {
+		writeSuccess(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="rooms-`+semester+`-`+fakultas+`.csv"`)
+	if err := writeRoomCSV(csv.NewWriter(w), report); err != nil {
+		log.Printf("csv encode error: %v", err)
+	}
+}
+
+// Writes one row per room, day, and hour.
+func writeRoomCSV(cw *csv.Writer, report RoomReport) error {
+	cw.Write([]string{"room", "building", "day", "hour", "utilization"})
+	for _, u := range report.Rooms {
+		for d, day := range report.Days {
+			for h, hour := range report.Hours {
+				cw.Write([]string{u.Room, u.Building, day, hour, strconv.FormatFloat(u.ByDay[d][h], 'f', 1, 64)})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
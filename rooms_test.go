@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func roomTestClasses() []CourseClass {
+	return []CourseClass{
+		{Code: "IF2211", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+			{Day: "Rabu", Time: "13:00-15:00", Room: "7602", Method: "Online"},
+			{Day: "Sabtu", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+		}},
+		{Code: "IF2230", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "08:00-10:30", Room: "7602", Method: "Offline"},
+			{Day: "Selasa", Time: "17:00-19:00", Room: "9009", Method: "Offline"},
+		}},
+		// The same class again, as listed on another prodi's page.
+		{Code: "IF2211", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+		}},
+	}
+}
+
+func TestRoomReport(t *testing.T) {
+	r := roomReport(roomTestClasses(), nil)
+	if len(r.Hours) != 11 || r.Hours[0] != "07:00" || len(r.Days) != 5 {
+		t.Fatalf("hours = %v, days = %v", r.Hours, r.Days)
+	}
+	if len(r.Rooms) != 2 {
+		t.Fatalf("rooms = %+v", r.Rooms)
+	}
+	room := r.Rooms[0]
+	// Senin 07:00-10:30 once, despite the overlap and the duplicate.
+	if room.Room != "7602" || room.BookedMinutes != 210 || room.Utilization != 6.4 {
+		t.Errorf("7602 = %+v", room)
+	}
+	if got := room.ByDay[0][:4]; got[0] != 100 || got[1] != 100 || got[2] != 100 || got[3] != 50 {
+		t.Errorf("7602 on Senin = %v", got)
+	}
+	// Only 17:00-18:00 is within the teaching week.
+	if room := r.Rooms[1]; room.Room != "9009" || room.BookedMinutes != 60 || room.ByDay[1][10] != 100 {
+		t.Errorf("9009 = %+v", room)
+	}
+}
+
+func TestRoomsHandler(t *testing.T) {
+	srv := newTestServer("")
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/analytics/rooms?"+query, nil))
+		return w
+	}
+	if w := get("semester=2025-2&fakultas=STEI"); w.Code != http.StatusNotFound {
+		t.Errorf("nothing cached: status %d, want 404", w.Code)
+	}
+
+	srv.catalog.set("2025-2?fakultas=STEI&prodi=135", roomTestClasses()[:2], time.Now())
+	srv.catalog.set("2025-2?fakultas=STEI&prodi=182", roomTestClasses()[2:], time.Now())
+	srv.catalog.set("2025-2?fakultas=FMIPA&prodi=101", []CourseClass{{Code: "MA1101", Schedules: []ScheduleEntry{{Day: "Senin", Time: "07:00-09:00", Room: "9999"}}}}, time.Now())
+
+	w := get("semester=2025-2&fakultas=STEI")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	r := decodeData[RoomReport](t, w)
+	if r.Pages != 2 || len(r.Rooms) != 2 || r.Rooms[0].BookedMinutes != 210 {
+		t.Errorf("report = %+v", r)
+	}
+
+	w = get("semester=2025-2&fakultas=STEI&format=csv")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if w.Header().Get("Content-Type") != "text/csv; charset=utf-8" || len(lines) != 1+2*5*11 {
+		t.Fatalf("csv: type %q, %d lines", w.Header().Get("Content-Type"), len(lines))
+	}
+	if lines[0] != "room,building,day,hour,utilization" || lines[1] != "7602,,Senin,07:00,100.0" {
+		t.Errorf("csv starts %q", lines[:2])
+	}
+}
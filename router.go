@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type middleware func(http.Handler) http.Handler
+
+// Wraps h in mws so that the first middleware is the outermost.
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Registers method-specific routes on a ServeMux with a shared middleware
+// stack, and documents each in an OpenAPI spec. Requests are validated
+// against their operation before reaching the handler. Requests to a known
+// path with an unregistered method get a JSON 405 with an Allow header
+// instead of the mux's plain-text one.
+type router struct {
+	mux     *http.ServeMux
+	spec    *OpenAPI
+	mws     []middleware
+	methods map[string]*[]string // registered methods per path, shared with derived routers
+}
+
+func newRouter(mux *http.ServeMux, spec *OpenAPI) *router {
+	return &router{mux: mux, spec: spec, methods: make(map[string]*[]string)}
+}
+
+// Returns a router that registers on the same mux with mws appended to the stack.
+func (rt *router) with(mws ...middleware) *router {
+	return &router{mux: rt.mux, spec: rt.spec, mws: append(append([]middleware(nil), rt.mws...), mws...), methods: rt.methods}
+}
+
+func (rt *router) handle(method, path string, op *Operation, h http.HandlerFunc) {
+	rt.spec.add(method, path, op)
+	rt.mux.Handle(method+" "+path, chain(h, append(slices.Clone(rt.mws), validateRequest(op))...))
+
+	allowed, ok := rt.methods[path]
+	if !ok {
+		allowed = &[]string{}
+		rt.methods[path] = allowed
+		rt.mux.Handle(path, chain(methodNotAllowed(allowed), rt.mws...))
+	}
+	*allowed = append(*allowed, method)
+}
+
+func methodNotAllowed(allowed *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(*allowed, ", "))
+		writeError(w, r, codeMethodNotAllowed)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain_Order(t *testing.T) {
+	var calls []string
+	mw := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { calls = append(calls, "handler") }), mw("a"), mw("b"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := strings.Join(calls, ","); got != "a,b,handler" {
+		t.Errorf("calls = %s, want a,b,handler", got)
+	}
+}
+
+func TestRouter_MethodsAndPathValues(t *testing.T) {
+	rt := newRouter(http.NewServeMux(), newOpenAPI())
+	var got string
+	rt.handle("GET", "/items/{id}", &Operation{}, func(w http.ResponseWriter, r *http.Request) { got = "get " + r.PathValue("id") })
+	rt.handle("DELETE", "/items/{id}", &Operation{}, func(w http.ResponseWriter, r *http.Request) { got = "delete " + r.PathValue("id") })
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rt.mux.ServeHTTP(w, httptest.NewRequest(method, "/items/42", nil))
+		return w
+	}
+	if serve("DELETE"); got != "delete 42" {
+		t.Errorf("got %q, want %q", got, "delete 42")
+	}
+	w := serve("POST")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, DELETE" {
+		t.Errorf("POST: got status %d (Allow %q), want 405 with Allow: GET, DELETE", w.Code, w.Header().Get("Allow"))
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("405 Content-Type = %q, want application/json", ct)
+	}
+}
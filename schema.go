@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+
+	"six-scraper-go/scraper"
+)
+
+// Stored blobs that hold classes (last-good snapshots, history contents, the
+// cache spill, and catalog archives) record the version of CourseClass they
+// were written with in schema_version. Reading an older blob runs the
+// migrations from its version up to classSchemaVersion on the raw JSON, so
+// fields added since are filled in instead of silently missing. Blobs written
+// before versioning have no schema_version and are version 1. A blob from a
+// newer server, as after a rollback, is refused rather than half read.
+//
+// Bump classSchemaVersion whenever CourseClass gains or changes a field older
+// blobs can be upgraded to, and add the step to classMigrations.
+const classSchemaVersion = 2
+
+// classMigrations[v] upgrades one class, as decoded JSON, from version v to
+// v+1. Working on the JSON lets a step move renamed or retyped fields too.
+var classMigrations = map[int]func(class map[string]any){
+	// 2 added code_parts.
+	1: func(class map[string]any) {
+		if _, ok := class["code_parts"]; ok {
+			return
+		}
+		code, _ := class["code"].(string)
+		if parts, ok := scraper.ParseCourseCode(code); ok {
+			class["code_parts"] = parts
+		}
+	},
+}
+
+// Unmarshals the stored blob data into v, first upgrading it if it was
+// written with an older schema_version. classLists returns the lists of
+// classes in the decoded blob; each element is migrated as a class, so lists
+// of types embedding CourseClass, like RemovedClass, work too.
+func unmarshalVersioned(data []byte, v any, classLists func(blob map[string]any) []any) error {
+	var header struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+	version := cmp.Or(header.SchemaVersion, 1)
+	if version > classSchemaVersion {
+		return fmt.Errorf("schema version %d is newer than this server's %d", version, classSchemaVersion)
+	}
+	if version < classSchemaVersion {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var blob map[string]any
+		if err := dec.Decode(&blob); err != nil {
+			return err
+		}
+		for _, list := range classLists(blob) {
+			classes, _ := list.([]any)
+			for _, c := range classes {
+				class, ok := c.(map[string]any)
+				if !ok {
+					continue
+				}
+				for from := version; from < classSchemaVersion; from++ {
+					classMigrations[from](class)
+				}
+			}
+		}
+		blob["schema_version"] = classSchemaVersion
+		var err error
+		if data, err = json.Marshal(blob); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Returns the value of key in each object of the list blob[listKey].
+func nestedLists(blob map[string]any, listKey, key string) []any {
+	items, _ := blob[listKey].([]any)
+	lists := make([]any, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(map[string]any); ok {
+			lists = append(lists, obj[key])
+		}
+	}
+	return lists
+}
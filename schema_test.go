@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A snapshot as written before schema_version: no code_parts on its classes.
+const legacySnapshot = `{"key":"k","student_id":"1","semester":"2024-2","fetched_at":"2024-09-01T00:00:00Z",
+"classes":[{"code":"IF2211","name":"Strategi Algoritma","sks":3,"class_no":"01","quota":60,"lecturers":[],"notes":"","schedules":[]}],
+"removed":[{"code":"KU1101","class_no":"02","status":"removed","last_seen_at":"2024-08-01T00:00:00Z","removed_at":"2024-09-01T00:00:00Z"}]}`
+
+func TestLoadSnapshot_MigratesLegacyClasses(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFileAtomic(snapshotPath(dir, "k"), []byte(legacySnapshot)); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadSnapshot(dir, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SchemaVersion != classSchemaVersion {
+		t.Errorf("schema version = %d", s.SchemaVersion)
+	}
+	if len(s.Classes) != 1 || s.Classes[0].CodeParts == nil || s.Classes[0].CodeParts.Prefix != "IF" || s.Classes[0].Quota != 60 {
+		t.Errorf("classes = %+v", s.Classes)
+	}
+	if len(s.Removed) != 1 || s.Removed[0].CodeParts == nil || s.Removed[0].CodeParts.Level != 1 || s.Removed[0].Status != "removed" {
+		t.Errorf("removed = %+v", s.Removed)
+	}
+
+	// Saving writes the current version, and it loads unchanged.
+	if err := saveSnapshot(dir, s); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(snapshotPath(dir, "k"))
+	if !strings.Contains(string(data), `"schema_version":2`) {
+		t.Errorf("saved snapshot = %s", data)
+	}
+}
+
+func TestUnmarshalVersioned_RefusesNewerSchema(t *testing.T) {
+	var s Snapshot
+	err := unmarshalVersioned([]byte(`{"schema_version":99,"classes":[]}`), &s, func(map[string]any) []any { return nil })
+	if err == nil {
+		t.Error("expected an error for a blob from a newer server")
+	}
+}
+
+func TestVersionHistory_ReadsLegacyContent(t *testing.T) {
+	dir := t.TempDir()
+	h := newVersionHistory(dir)
+	path := h.contentPath("legacy")
+	if err := writeFileAtomic(path, []byte(`[{"code":"IF2211","class_no":"01","sks":3}]`)); err != nil {
+		t.Fatal(err)
+	}
+	classes, ok := h.content("legacy")
+	if !ok || len(classes) != 1 || classes[0].CodeParts == nil || classes[0].SKS != 3 {
+		t.Fatalf("classes = %+v, %v", classes, ok)
+	}
+
+	h.record("k", classes, time.Unix(0, 0))
+	versions := h.versions("k")
+	got, ok := h.content(versions[0].Hash)
+	if !ok || len(got) != 1 || got[0].Code != "IF2211" {
+		t.Errorf("content = %+v, %v", got, ok)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "history", "contents", versions[0].Hash+".json")); !strings.HasPrefix(string(data), `{"schema_version":2,`) {
+		t.Errorf("stored content = %s", data)
+	}
+}
@@ -0,0 +1,42 @@
+package scraper
+
+// ScheduleEntry is one meeting of a class as SIX lists it.
+type ScheduleEntry struct {
+	Day      string `json:"day"`
+	Time     string `json:"time"`
+	Room     string `json:"room"`
+	Activity string `json:"activity"`
+	Method   string `json:"method"`
+}
+
+// CourseClass is one class of a course, parsed from a row of a SIX schedule
+// or catalog page.
+type CourseClass struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	SKS     int    `json:"sks"`
+	ClassNo string `json:"class_no"`
+	Quota   int    `json:"quota"`
+	// Enrolled is how many students have taken a seat, when SIX shows it.
+	Enrolled  *int     `json:"enrolled,omitempty"`
+	Lecturers []string `json:"lecturers"`
+	Notes     string   `json:"notes"`
+	// NotesHTML is Notes as sanitized HTML, keeping links and bold text,
+	// when the notes have any. The server only returns it on request.
+	NotesHTML string          `json:"notes_html,omitempty"`
+	Schedules []ScheduleEntry `json:"schedules"`
+	// Category is which table of the page the class was listed in, from
+	// the table's heading: "kuliah", "praktikum", "tutorial", or another
+	// heading in lower case. Empty if the table has no heading.
+	Category string `json:"category,omitempty"`
+	// CodeParts is Code split into its parts, or nil if Code does not
+	// follow ITB's conventions.
+	CodeParts *CodeParts `json:"code_parts,omitempty"`
+	// Eligible and MissingPrerequisites say whether a particular student
+	// may take the class. They are set per request by callers that know
+	// the student's transcript; the parser leaves them empty.
+	Eligible             *bool    `json:"eligible,omitempty"`
+	MissingPrerequisites []string `json:"missing_prerequisites,omitempty"`
+	// Extra holds whatever class hooks attach. SIX itself never sets it.
+	Extra map[string]string `json:"extra,omitempty"`
+}
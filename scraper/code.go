@@ -0,0 +1,30 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CodeParts is a course code split along ITB's conventions: "IF2211" is
+// course 211 of the IF program at level 2. Levels 1 to 4 are the years of
+// an undergraduate program; higher levels are graduate courses.
+type CodeParts struct {
+	Prefix string `json:"prefix"`
+	Level  int    `json:"level"`
+	Serial string `json:"serial"`
+}
+
+var courseCodeRe = regexp.MustCompile(`^([A-Z]{2,3})(\d)(\d{3})$`)
+
+// ParseCourseCode splits a course code into its parts. It reports false for
+// codes that do not follow the convention, such as those of special or
+// cross-university courses.
+func ParseCourseCode(code string) (CodeParts, bool) {
+	m := courseCodeRe.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(code)))
+	if m == nil {
+		return CodeParts{}, false
+	}
+	level, _ := strconv.Atoi(m[2])
+	return CodeParts{Prefix: m[1], Level: level, Serial: m[3]}, true
+}
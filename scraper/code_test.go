@@ -0,0 +1,25 @@
+package scraper
+
+import "testing"
+
+func TestParseCourseCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want CodeParts
+		ok   bool
+	}{
+		{"IF2211", CodeParts{Prefix: "IF", Level: 2, Serial: "211"}, true},
+		{" ku1101 ", CodeParts{Prefix: "KU", Level: 1, Serial: "101"}, true},
+		{"SBM5012", CodeParts{Prefix: "SBM", Level: 5, Serial: "012"}, true},
+		{"IF221", CodeParts{}, false},
+		{"IF22110", CodeParts{}, false},
+		{"2211IF", CodeParts{}, false},
+		{"", CodeParts{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseCourseCode(tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCourseCode(%q) = %+v, %v; want %+v, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
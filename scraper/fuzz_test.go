@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func FuzzParseSchedules(f *testing.F) {
+	f.Add(`<ul><li>Senin / 1945-01-06 / 07:00-09:00 / 7602 / Kuliah / Offline</li></ul>`)
+	f.Add(`<ul><li>Senin / x / 07:00 / <ul><li>Rabu / x / 09:00 / 7602 / Kuliah / Online</li></ul> / Kuliah / Offline</li></ul>`)
+	f.Add(`<ul><li>Tampilkan semua</li><li>//////</li></ul>`)
+
+	f.Fuzz(func(t *testing.T, html string) {
+		doc := docFromHTML(html)
+		seen := make(map[ScheduleEntry]bool)
+		for _, s := range parseSchedules(doc.Find("ul").First()) {
+			if seen[s] {
+				t.Errorf("duplicate schedule %+v", s)
+			}
+			seen[s] = true
+		}
+	})
+}
+
+func FuzzCollapseWhitespace(f *testing.F) {
+	for _, s := range []string{"", "  a  b ", "a\n\tb", " x　y"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		got := collapseWhitespace(s)
+		if got != strings.TrimSpace(got) {
+			t.Errorf("result %q has surrounding whitespace", got)
+		}
+		prevSpace := false
+		for _, r := range got {
+			space := unicode.IsSpace(r) || unicode.Is(unicode.Zs, r)
+			if space && prevSpace {
+				t.Errorf("result %q has adjacent spaces", got)
+				break
+			}
+			prevSpace = space
+		}
+		if utf8.ValidString(s) && !utf8.ValidString(got) {
+			t.Errorf("result %q is not valid UTF-8", got)
+		}
+		if collapseWhitespace(got) != got {
+			t.Errorf("collapseWhitespace is not idempotent on %q", s)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// A ClassHook enriches or rewrites a class after it has been parsed, for
+// example to attach internal department metadata through CourseClass.Extra.
+//
+// Hooks run in the order they were registered, each seeing the changes made
+// by the ones before it. A hook that returns ErrDropClass removes the class,
+// and no later hooks see it. Any other error discards that hook's changes to
+// the class, keeps the class, and lets the remaining hooks run; the error is
+// reported by ApplyClassHooks.
+type ClassHook func(*CourseClass) error
+
+// ErrDropClass is returned by a ClassHook to remove a class from the result.
+var ErrDropClass = errors.New("drop class")
+
+type namedHook struct {
+	name string
+	fn   ClassHook
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []namedHook
+)
+
+// RegisterClassHook adds a hook that runs on every parsed class. It is meant
+// to be called from an init function. It panics if hook is nil or if name is
+// already registered.
+func RegisterClassHook(name string, hook ClassHook) {
+	if hook == nil {
+		panic("scraper: RegisterClassHook hook is nil")
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	for _, h := range hooks {
+		if h.name == name {
+			panic("scraper: RegisterClassHook called twice for " + name)
+		}
+	}
+	hooks = append(hooks, namedHook{name: name, fn: hook})
+}
+
+// ClassHooks returns the names of the registered hooks in the order they run.
+func ClassHooks() []string {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	names := make([]string, len(hooks))
+	for i, h := range hooks {
+		names[i] = h.name
+	}
+	return names
+}
+
+// HookError reports a hook that failed on a class.
+type HookError struct {
+	Hook    string
+	Code    string
+	ClassNo string
+	Err     error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("class hook %s failed on %s class %s: %v", e.Hook, e.Code, e.ClassNo, e.Err)
+}
+
+func (e *HookError) Unwrap() error { return e.Err }
+
+// ApplyClassHooks runs the registered hooks on every class, modifying classes
+// in place, and returns the classes that were not dropped. The error joins a
+// *HookError for every failed hook; the classes are usable either way.
+func ApplyClassHooks(classes []CourseClass) ([]CourseClass, error) {
+	hooksMu.RLock()
+	registered := slices.Clone(hooks)
+	hooksMu.RUnlock()
+	if len(registered) == 0 {
+		return classes, nil
+	}
+
+	var errs []error
+	kept := classes[:0]
+	for _, class := range classes {
+		dropped := false
+		for _, h := range registered {
+			// Run the hook on a copy so a failed hook leaves no half-made
+			// changes behind.
+			trial := cloneClass(class)
+			err := h.fn(&trial)
+			if errors.Is(err, ErrDropClass) {
+				dropped = true
+				break
+			}
+			if err != nil {
+				errs = append(errs, &HookError{Hook: h.name, Code: class.Code, ClassNo: class.ClassNo, Err: err})
+				continue
+			}
+			class = trial
+		}
+		if !dropped {
+			kept = append(kept, class)
+		}
+	}
+	return kept, errors.Join(errs...)
+}
+
+func cloneClass(c CourseClass) CourseClass {
+	c.Lecturers = slices.Clone(c.Lecturers)
+	c.Schedules = slices.Clone(c.Schedules)
+	c.Extra = maps.Clone(c.Extra)
+	return c
+}
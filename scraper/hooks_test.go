@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// Replaces the registered hooks for the duration of a test.
+func withHooks(t *testing.T) {
+	t.Helper()
+	hooksMu.Lock()
+	old := hooks
+	hooks = nil
+	hooksMu.Unlock()
+	t.Cleanup(func() {
+		hooksMu.Lock()
+		hooks = old
+		hooksMu.Unlock()
+	})
+}
+
+func TestApplyClassHooks(t *testing.T) {
+	withHooks(t)
+	errUnknown := errors.New("unknown prefix")
+	RegisterClassHook("department", func(c *CourseClass) error {
+		if c.Extra == nil {
+			c.Extra = map[string]string{}
+		}
+		switch c.Code[:2] {
+		case "IF":
+			c.Extra["department"] = "Informatika"
+		case "FI":
+			c.Extra["department"] = "Fisika"
+		default:
+			c.Extra["department"] = "half-written"
+			return errUnknown
+		}
+		return nil
+	})
+	RegisterClassHook("drop-thesis", func(c *CourseClass) error {
+		if c.Code == "IF4091" {
+			return ErrDropClass
+		}
+		return nil
+	})
+	RegisterClassHook("label", func(c *CourseClass) error {
+		// Sees the department set by the hook before it.
+		c.Extra["label"] = c.Code + " (" + c.Extra["department"] + ")"
+		return nil
+	})
+	if got := ClassHooks(); !slices.Equal(got, []string{"department", "drop-thesis", "label"}) {
+		t.Errorf("ClassHooks() = %v", got)
+	}
+
+	classes, err := ApplyClassHooks([]CourseClass{
+		{Code: "IF2211", ClassNo: "01"},
+		{Code: "IF4091", ClassNo: "01"},
+		{Code: "FI1210", ClassNo: "02"},
+		{Code: "XX1000", ClassNo: "01", Extra: map[string]string{}},
+	})
+	if len(classes) != 3 {
+		t.Fatalf("classes = %+v", classes)
+	}
+	if got := classes[0].Extra["label"]; got != "IF2211 (Informatika)" {
+		t.Errorf("IF2211 label = %q", got)
+	}
+	if got := classes[1].Extra["label"]; got != "FI1210 (Fisika)" {
+		t.Errorf("FI1210 label = %q", got)
+	}
+	// The failed hook's write is discarded, and later hooks still run.
+	if got := classes[2].Extra; got["department"] != "" || got["label"] != "XX1000 ()" {
+		t.Errorf("XX1000 extra = %v", got)
+	}
+
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || hookErr.Hook != "department" || hookErr.Code != "XX1000" || !errors.Is(err, errUnknown) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRegisterClassHook_Duplicate(t *testing.T) {
+	withHooks(t)
+	RegisterClassHook("a", func(*CourseClass) error { return nil })
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	RegisterClassHook("a", func(*CourseClass) error { return nil })
+}
@@ -0,0 +1,217 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ParseClassesIter parses the classes of a SIX schedule or catalog page and
+// passes them to yield one at a time, in page order, stopping early if yield
+// returns false. Unlike ParseClasses it holds one class, and one row of the
+// table, at a time, so embedders can stream huge catalog pages into their
+// own storage. The parsed page itself is already in memory.
+//
+// Classes are yielded as parsed: CodeParts is left nil and class hooks are
+// not run. Use ParseCourseCode and ApplyClassHooks for those.
+func ParseClassesIter(doc *goquery.Document, yield func(CourseClass) bool) {
+	ClassTables(doc).EachWithBreak(func(_ int, table *goquery.Selection) bool {
+		category := tableCategory(TableHeading(table))
+		more := true
+		EachGridRow(table.ChildrenFiltered("tbody").ChildrenFiltered("tr"), "td, th", func(cells []*goquery.Selection) bool {
+			if class, ok := parseClassRow(cells); ok {
+				class.Category = category
+				more = yield(class)
+			}
+			return more
+		})
+		return more
+	})
+}
+
+// ParseClasses returns every class ParseClassesIter yields for doc.
+func ParseClasses(doc *goquery.Document) []CourseClass {
+	var classes []CourseClass
+	ParseClassesIter(doc, func(c CourseClass) bool {
+		classes = append(classes, c)
+		return true
+	})
+	return classes
+}
+
+// ClassTables returns the class tables of doc. Some pages have several, such
+// as one for lectures and one for practicums. When there is more than one, a
+// table whose header has no "kode" column lists something else and is left
+// out.
+func ClassTables(doc *goquery.Document) *goquery.Selection {
+	tables := doc.Find("table.table")
+	if tables.Length() < 2 {
+		return tables
+	}
+	return tables.FilterFunction(func(_ int, table *goquery.Selection) bool {
+		if table.Find("thead").Length() == 0 {
+			return true
+		}
+		_, ok := HeaderColumns(table)["kode"]
+		return ok
+	})
+}
+
+const headingSelector = "h1, h2, h3, h4, h5, h6"
+
+// TableHeading returns the text of table's caption or of the nearest heading
+// before it, looking through the siblings of table and then of its
+// ancestors, as in a card whose header holds the heading. Stops at another
+// table, so a table without a heading does not take the previous table's.
+// Returns "" if there is none.
+func TableHeading(table *goquery.Selection) string {
+	if caption := collapseWhitespace(table.ChildrenFiltered("caption").Text()); caption != "" {
+		return caption
+	}
+	for s := table; s.Length() > 0 && !s.Is("body"); s = s.Parent() {
+		for prev := s.Prev(); prev.Length() > 0; prev = prev.Prev() {
+			heading := prev.Find(headingSelector).Last()
+			if prev.Is(headingSelector) {
+				heading = prev
+			}
+			if heading.Length() > 0 {
+				return collapseWhitespace(heading.Text())
+			}
+			if prev.Is("table") || prev.Find("table").Length() > 0 {
+				return ""
+			}
+		}
+	}
+	return ""
+}
+
+// Returns the category of a class table from its heading: "kuliah",
+// "praktikum", or "tutorial" (which includes responsi), or for any other
+// heading the heading itself in lower case.
+func tableCategory(heading string) string {
+	h := strings.ToLower(heading)
+	switch {
+	case strings.Contains(h, "praktikum"):
+		return "praktikum"
+	case strings.Contains(h, "tutorial"), strings.Contains(h, "responsi"):
+		return "tutorial"
+	case strings.Contains(h, "kuliah"):
+		return "kuliah"
+	}
+	return truncateText(h)
+}
+
+// Parses one row of a class table, as laid out by GridRows. Returns false
+// for rows that are not classes.
+func parseClassRow(cells []*goquery.Selection) (CourseClass, bool) {
+	if len(cells) < 10 {
+		return CourseClass{}, false
+	}
+
+	sks, _ := strconv.Atoi(strings.TrimSpace(cells[4].Text()))
+	quota, enrolled := parseQuota(cells[6].Text())
+
+	class := CourseClass{
+		Code:      truncateText(strings.TrimSpace(cells[2].Text())),
+		Name:      truncateText(strings.TrimSpace(cells[3].Text())),
+		SKS:       sks,
+		ClassNo:   truncateText(strings.TrimSpace(cells[5].Text())),
+		Quota:     quota,
+		Enrolled:  enrolled,
+		Lecturers: parseLecturers(cells[7]),
+		Notes:     truncateText(collapseWhitespace(cells[8].Text())),
+		NotesHTML: notesHTML(cells[8]),
+		Schedules: parseSchedules(cells[9]),
+	}
+	return class, class.Code != ""
+}
+
+// Parses a quota cell. During FRS SIX may show it as "enrolled/quota", e.g.
+// "38/45"; otherwise it is just the quota and enrolled is nil.
+func parseQuota(text string) (quota int, enrolled *int) {
+	before, after, found := strings.Cut(collapseWhitespace(text), "/")
+	if !found {
+		quota, _ = strconv.Atoi(before)
+		return quota, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(before))
+	quota, _ = strconv.Atoi(strings.TrimSpace(after))
+	if err != nil {
+		return quota, nil
+	}
+	return quota, &n
+}
+
+func parseLecturers(cell *goquery.Selection) []string {
+	var lecturers []string
+	cell.Find("ul " + LeafItem).Each(func(_ int, li *goquery.Selection) {
+		if name := truncateText(collapseWhitespace(li.Text())); name != "" {
+			lecturers = append(lecturers, name)
+		}
+	})
+	return lecturers
+}
+
+func parseSchedules(cell *goquery.Selection) []ScheduleEntry {
+	var schedules []ScheduleEntry
+	seen := make(map[string]bool)
+
+	cell.Find(LeafItem).Each(func(_ int, li *goquery.Selection) {
+		text := truncateText(collapseWhitespace(li.Text()))
+		if text == "" || strings.Contains(text, "Tampilkan semua") {
+			return
+		}
+
+		parts := strings.Split(text, "/")
+		if len(parts) < 6 {
+			return
+		}
+
+		entry := ScheduleEntry{
+			Day:      strings.TrimSpace(parts[0]),
+			Time:     strings.TrimSpace(parts[2]),
+			Room:     strings.TrimSpace(parts[3]),
+			Activity: strings.TrimSpace(parts[4]),
+			Method:   strings.TrimSpace(parts[5]),
+		}
+
+		key := entry.Day + "|" + entry.Time + "|" + entry.Room + "|" + entry.Activity + "|" + entry.Method
+		if !seen[key] {
+			schedules = append(schedules, entry)
+			seen[key] = true
+		}
+	})
+
+	return schedules
+}
+
+// LeafItem matches list items without nested lists. A parent item's text
+// would include its children's, so only leaves are parsed when SIX nests
+// lists.
+const LeafItem = "li:not(:has(li))"
+
+// MaxCellText is the upper bound on the length of any parsed text field, so
+// a malformed page cannot blow up response and cache sizes.
+const MaxCellText = 4 << 10
+
+var whitespaceRe = regexp.MustCompile(`[\s\v\x{85}\p{Z}]+`) // \s alone misses &nbsp; and other Unicode spaces
+
+// Cuts s to at most MaxCellText bytes without splitting a UTF-8 sequence.
+func truncateText(s string) string {
+	if len(s) <= MaxCellText {
+		return s
+	}
+	cut := MaxCellText
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// Trims and collapses all runs of whitespace into a single space.
+func collapseWhitespace(s string) string {
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,164 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func docFromHTML(html string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"hello world", "hello world"},
+		{"  hello   world  ", "hello world"},
+		{"line1\nline2\n\nline3", "line1 line2 line3"},
+		{"\t  tabs\tand  spaces  \n", "tabs and spaces"},
+		{"", ""},
+		{"   ", ""},
+		{"single", "single"},
+	}
+	for _, tt := range tests {
+		if got := collapseWhitespace(tt.input); got != tt.want {
+			t.Errorf("collapseWhitespace(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseSchedules_Deduplication(t *testing.T) {
+	html := `<ul>
+		<li>Senin / 1945-01-06 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
+		<li>Senin / 1945-01-13 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
+	</ul>`
+	doc := docFromHTML(html)
+	sel := doc.Find("ul")
+	schedules := parseSchedules(sel)
+	if len(schedules) != 1 {
+		t.Errorf("expected 1 deduplicated schedule, got %d", len(schedules))
+	}
+}
+
+func TestParseSchedules_SkipsTampilkanSemua(t *testing.T) {
+	html := `<ul>
+		<li>Senin / 1945-01-06 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
+		<li>Tampilkan semua jadwal</li>
+	</ul>`
+	doc := docFromHTML(html)
+	schedules := parseSchedules(doc.Find("ul"))
+	if len(schedules) != 1 {
+		t.Errorf("expected 1 schedule (Tampilkan semua skipped), got %d", len(schedules))
+	}
+}
+
+func TestParseSchedules_SkipsInvalidFormat(t *testing.T) {
+	html := `<ul>
+		<li>invalid text without slashes</li>
+		<li>only/three/parts</li>
+	</ul>`
+	doc := docFromHTML(html)
+	schedules := parseSchedules(doc.Find("ul"))
+	if len(schedules) != 0 {
+		t.Errorf("expected 0 schedules for invalid format, got %d", len(schedules))
+	}
+}
+
+func TestParseLecturers_Empty(t *testing.T) {
+	html := `<div><ul></ul></div>`
+	doc := docFromHTML(html)
+	lecturers := parseLecturers(doc.Find("div"))
+	if len(lecturers) != 0 {
+		t.Errorf("expected 0 lecturers, got %d", len(lecturers))
+	}
+}
+
+func TestCollapseWhitespace_UnicodeSpaces(t *testing.T) {
+	if got := collapseWhitespace("Dosen  A　B"); got != "Dosen A B" {
+		t.Errorf("got %q, want %q", got, "Dosen A B")
+	}
+}
+
+func TestParseSchedules_NestedLists(t *testing.T) {
+	html := `<ul><li>Jadwal
+		<ul>
+			<li>Senin / 1945-01-06 / 07:00-09:00 / 7602 / Kuliah / Offline</li>
+			<li>Rabu / 1945-01-08 / 13:00-15:00 / 7603 / Kuliah / Online</li>
+		</ul>
+	</li></ul>`
+	schedules := parseSchedules(docFromHTML(html).Find("ul").First())
+	if len(schedules) != 2 {
+		t.Fatalf("expected 2 schedules from nested list, got %d: %+v", len(schedules), schedules)
+	}
+	if schedules[0].Room != "7602" || schedules[1].Room != "7603" {
+		t.Errorf("unexpected schedules %+v", schedules)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	long := strings.Repeat("é", MaxCellText) // 2 bytes per rune
+	got := truncateText(long)
+	if len(got) > MaxCellText {
+		t.Errorf("len = %d, want <= %d", len(got), MaxCellText)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("truncation split a UTF-8 sequence")
+	}
+	if truncateText("short") != "short" {
+		t.Error("short strings should be unchanged")
+	}
+}
+
+func TestTableHeading_StopsAtPreviousTable(t *testing.T) {
+	doc := docFromHTML(`<h3>Kuliah</h3><table class="table" id="a"></table><table class="table" id="b"></table>`)
+	if got := TableHeading(doc.Find("#a")); got != "Kuliah" {
+		t.Errorf("first table heading = %q", got)
+	}
+	if got := TableHeading(doc.Find("#b")); got != "" {
+		t.Errorf("second table heading = %q, want none", got)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	for text, want := range map[string]string{"45": "45 <nil>", " 38 / 45 ": "45 38", "x/40": "40 <nil>", "": "0 <nil>"} {
+		quota, enrolled := parseQuota(text)
+		got := fmt.Sprint(quota, " ", enrolled)
+		if enrolled != nil {
+			got = fmt.Sprint(quota, " ", *enrolled)
+		}
+		if got != want {
+			t.Errorf("parseQuota(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestParseClassesIter(t *testing.T) {
+	row := func(code string) string {
+		return `<tr><td>1</td><td></td><td>` + code + `</td><td>Nama</td><td>3</td><td>01</td><td>40</td><td></td><td></td><td></td></tr>`
+	}
+	doc := docFromHTML(`<h3>Jadwal Praktikum</h3><table class="table"><tbody>` + row("IF2211") + row("") + row("IF2230") + row("IF2240") + `</tbody></table>`)
+
+	var codes []string
+	ParseClassesIter(doc, func(c CourseClass) bool {
+		if c.Category != "praktikum" || c.CodeParts != nil {
+			t.Errorf("class %s = %+v", c.Code, c)
+		}
+		codes = append(codes, c.Code)
+		return len(codes) < 2
+	})
+	if strings.Join(codes, ",") != "IF2211,IF2230" {
+		t.Errorf("yielded %v, want the first two classes", codes)
+	}
+	if got := ParseClasses(doc); len(got) != 3 {
+		t.Errorf("ParseClasses returned %d classes, want 3", len(got))
+	}
+}
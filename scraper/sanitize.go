@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SIX notes sometimes hold links and bold text, which Notes flattens. The
+// parser also keeps them as NotesHTML, sanitized to an allowlist: a (with an
+// http, https, or mailto href, and nothing else), b (which strong becomes),
+// and br. Other elements are replaced by their text, and scripts, styles,
+// and comments are dropped.
+
+// Elements whose content is never text a reader sees.
+var droppedElements = map[string]bool{"script": true, "style": true, "template": true, "iframe": true, "object": true, "noscript": true}
+
+// Elements that end a line when flattened.
+var blockElements = map[string]bool{"p": true, "div": true, "li": true, "tr": true}
+
+// Returns the sanitized HTML of a notes cell, or "" if it has no markup worth
+// keeping or would be too long to keep whole.
+func notesHTML(cell *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(s *goquery.Selection)
+	walk = func(s *goquery.Selection) {
+		s.Contents().Each(func(_ int, n *goquery.Selection) {
+			name := goquery.NodeName(n)
+			switch {
+			case name == "#text":
+				b.WriteString(html.EscapeString(whitespaceRe.ReplaceAllString(n.Text(), " ")))
+			case strings.HasPrefix(name, "#"), droppedElements[name]:
+			case name == "br":
+				b.WriteString("<br>")
+			case name == "b", name == "strong":
+				b.WriteString("<b>")
+				walk(n)
+				b.WriteString("</b>")
+			case name == "a":
+				href, ok := safeLink(n.AttrOr("href", ""))
+				if !ok {
+					walk(n)
+					return
+				}
+				b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">`)
+				walk(n)
+				b.WriteString("</a>")
+			case blockElements[name]:
+				walk(n)
+				b.WriteString("<br>")
+			default:
+				walk(n)
+			}
+		})
+	}
+	walk(cell)
+
+	out := strings.TrimSpace(b.String())
+	for {
+		trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(out, "<br>"), "<br>"))
+		if trimmed == out {
+			break
+		}
+		out = trimmed
+	}
+	if !strings.Contains(out, "<") || len(out) > MaxCellText {
+		return ""
+	}
+	return out
+}
+
+// Returns href if it is an absolute http, https, or mailto link.
+func safeLink(href string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
@@ -0,0 +1,24 @@
+package scraper
+
+import "testing"
+
+func TestNotesHTML(t *testing.T) {
+	tests := []struct{ cell, want string }{
+		{`Kelas gabungan`, ""},
+		{`Lihat <a href="https://edunex.itb.ac.id/c/1" target="_blank" onclick="x()">Edunex</a>`,
+			`Lihat <a href="https://edunex.itb.ac.id/c/1" rel="nofollow noopener noreferrer">Edunex</a>`},
+		{`<strong>Wajib</strong> hadir<br/>pekan 1`, `<b>Wajib</b> hadir<br>pekan 1`},
+		{`<p>Baris 1</p><p>Baris 2</p>`, `Baris 1<br>Baris 2`},
+		{`<a href="javascript:alert(1)">klik</a> <b>ok</b>`, `klik <b>ok</b>`},
+		{`<a href="/app/lain">relatif</a> <b onmouseover="x()">a &lt; b</b>`, `relatif <b>a &lt; b</b>`},
+		{`<script>alert(1)</script><!-- c --><b>x</b><img src=x onerror=alert(1)>`, `<b>x</b>`},
+		{`<a href="mailto:dosen@itb.ac.id">Email</a>`, `<a href="mailto:dosen@itb.ac.id" rel="nofollow noopener noreferrer">Email</a>`},
+		{`<a href="https://x.test/?a=1&b=&quot;2">q</a>`, `<a href="https://x.test/?a=1&amp;b=&#34;2" rel="nofollow noopener noreferrer">q</a>`},
+	}
+	for _, tt := range tests {
+		doc := docFromHTML(`<table><tr><td id="c">` + tt.cell + `</td></tr></table>`)
+		if got := notesHTML(doc.Find("#c")); got != tt.want {
+			t.Errorf("notesHTML(%s)\n got %s\nwant %s", tt.cell, got, tt.want)
+		}
+	}
+}
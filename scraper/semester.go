@@ -0,0 +1,86 @@
+// Package scraper holds the parts of six-scraper-go that are useful without
+// the HTTP server.
+package scraper
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Semester is an ITB academic semester as SIX writes it, e.g. "2025-1".
+// Year is the year the academic year starts. Term is 1 (odd, from August),
+// 2 (even, from January), or 3 (the short semester in the summer break).
+type Semester struct {
+	Year int
+	Term int
+}
+
+var semesterRe = regexp.MustCompile(`^(\d{4})-([123])$`)
+
+// ParseSemester parses a semester in SIX's "YYYY-T" form.
+func ParseSemester(s string) (Semester, error) {
+	m := semesterRe.FindStringSubmatch(s)
+	if m == nil {
+		return Semester{}, fmt.Errorf("invalid semester %q, want YYYY-1, YYYY-2, or YYYY-3", s)
+	}
+	year, _ := strconv.Atoi(m[1])
+	term, _ := strconv.Atoi(m[2])
+	return Semester{Year: year, Term: term}, nil
+}
+
+func (s Semester) String() string {
+	return fmt.Sprintf("%04d-%d", s.Year, s.Term)
+}
+
+// Next returns the following regular semester. Short semesters are skipped:
+// the semester after both 2025-2 and 2025-3 is 2026-1.
+func (s Semester) Next() Semester {
+	if s.Term == 1 {
+		return Semester{Year: s.Year, Term: 2}
+	}
+	return Semester{Year: s.Year + 1, Term: 1}
+}
+
+// Prev returns the preceding regular semester. The semester before 2025-3 is
+// 2025-2.
+func (s Semester) Prev() Semester {
+	if s.Term == 1 {
+		return Semester{Year: s.Year - 1, Term: 2}
+	}
+	return Semester{Year: s.Year, Term: s.Term - 1}
+}
+
+// Add moves n regular semesters forward, or backward if n is negative.
+func (s Semester) Add(n int) Semester {
+	for ; n > 0; n-- {
+		s = s.Next()
+	}
+	for ; n < 0; n++ {
+		s = s.Prev()
+	}
+	return s
+}
+
+// Compare returns -1, 0, or +1 as s is before, equal to, or after t.
+func (s Semester) Compare(t Semester) int {
+	return cmp.Or(cmp.Compare(s.Year, t.Year), cmp.Compare(s.Term, t.Term))
+}
+
+// CalendarSemester returns the regular semester in session at t according to
+// ITB's usual academic calendar: the odd semester runs from August through
+// January, the even semester from February through July. Short semesters
+// are never returned.
+func CalendarSemester(t time.Time) Semester {
+	year, month := t.Year(), t.Month()
+	switch {
+	case month >= time.August:
+		return Semester{Year: year, Term: 1}
+	case month == time.January:
+		return Semester{Year: year - 1, Term: 1}
+	default:
+		return Semester{Year: year - 1, Term: 2}
+	}
+}
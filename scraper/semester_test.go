@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSemester(t *testing.T) {
+	s, err := ParseSemester("2025-2")
+	if err != nil || s != (Semester{Year: 2025, Term: 2}) {
+		t.Errorf("got %+v, %v", s, err)
+	}
+	if s.String() != "2025-2" {
+		t.Errorf("String() = %q", s.String())
+	}
+	for _, bad := range []string{"", "2025", "2025-4", "25-1", "2025-1x"} {
+		if _, err := ParseSemester(bad); err == nil {
+			t.Errorf("ParseSemester(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestSemesterArithmetic(t *testing.T) {
+	tests := []struct {
+		in         string
+		next, prev string
+	}{
+		{"2025-1", "2025-2", "2024-2"},
+		{"2025-2", "2026-1", "2025-1"},
+		{"2025-3", "2026-1", "2025-2"},
+	}
+	for _, tt := range tests {
+		s, _ := ParseSemester(tt.in)
+		if got := s.Next().String(); got != tt.next {
+			t.Errorf("%s.Next() = %s, want %s", tt.in, got, tt.next)
+		}
+		if got := s.Prev().String(); got != tt.prev {
+			t.Errorf("%s.Prev() = %s, want %s", tt.in, got, tt.prev)
+		}
+	}
+
+	s := Semester{Year: 2025, Term: 1}
+	if got := s.Add(3).String(); got != "2026-2" {
+		t.Errorf("Add(3) = %s, want 2026-2", got)
+	}
+	if got := s.Add(-3).String(); got != "2023-2" {
+		t.Errorf("Add(-3) = %s, want 2023-2", got)
+	}
+}
+
+func TestSemesterCompare(t *testing.T) {
+	a, b, c := Semester{2024, 2}, Semester{2024, 3}, Semester{2025, 1}
+	if a.Compare(b) != -1 || b.Compare(c) != -1 || c.Compare(a) != 1 || a.Compare(a) != 0 {
+		t.Error("unexpected ordering")
+	}
+}
+
+func TestCalendarSemester(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2025-08-18", "2025-1"},
+		{"2025-12-20", "2025-1"},
+		{"2026-01-10", "2025-1"},
+		{"2026-02-02", "2025-2"},
+		{"2026-07-31", "2025-2"},
+	}
+	for _, tt := range tests {
+		d, _ := time.Parse(time.DateOnly, tt.date)
+		if got := CalendarSemester(d).String(); got != tt.want {
+			t.Errorf("CalendarSemester(%s) = %s, want %s", tt.date, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/textnorm"
+)
+
+// Some SIX pages merge cells: a course's code, name, and SKS span all of its
+// class rows with rowspan, and some headers group columns with colspan.
+// Parsers read tables through GridRows, which lays cells out the way a
+// browser does, so column i is the same column in every row.
+
+// Largest span honored, so a malformed page cannot blow up the grid.
+const maxCellSpan = 1000
+
+// GridRows returns the cells of each of rows, selected from each row's
+// children with cellSelector, as laid out on screen. A cell with rowspan also
+// fills its columns in the rows below, and one with colspan fills the columns
+// to its right, so values carry forward from the spanning cell. A column no
+// cell covers holds an empty selection.
+func GridRows(rows *goquery.Selection, cellSelector string) [][]*goquery.Selection {
+	var grid [][]*goquery.Selection
+	EachGridRow(rows, cellSelector, func(cells []*goquery.Selection) bool {
+		grid = append(grid, cells)
+		return true
+	})
+	return grid
+}
+
+// EachGridRow passes the cells of each of rows, as GridRows lays them out, to
+// yield one row at a time, stopping early if yield returns false.
+func EachGridRow(rows *goquery.Selection, cellSelector string, yield func(cells []*goquery.Selection) bool) {
+	type pending struct {
+		cell *goquery.Selection
+		rows int // rows below that the cell still covers
+	}
+	var spans []pending // by column
+	rows.EachWithBreak(func(_ int, row *goquery.Selection) bool {
+		empty := row.Slice(0, 0)
+		var cells []*goquery.Selection
+		carry := func(col int) {
+			if col < len(spans) && spans[col].rows > 0 {
+				cells = append(cells, spans[col].cell)
+				spans[col].rows--
+			} else {
+				cells = append(cells, empty)
+			}
+		}
+		row.ChildrenFiltered(cellSelector).Each(func(_ int, cell *goquery.Selection) {
+			for len(cells) < len(spans) && spans[len(cells)].rows > 0 {
+				carry(len(cells))
+			}
+			rowspan := cellSpan(cell, "rowspan", rows.Length())
+			for range cellSpan(cell, "colspan", maxCellSpan) {
+				if len(cells) == len(spans) {
+					spans = append(spans, pending{})
+				}
+				spans[len(cells)] = pending{cell, rowspan - 1}
+				cells = append(cells, cell)
+			}
+		})
+		last := len(spans) - 1
+		for last >= len(cells) && spans[last].rows == 0 {
+			last--
+		}
+		for len(cells) <= last {
+			carry(len(cells))
+		}
+		return yield(cells)
+	})
+}
+
+// Returns the rowspan or colspan of cell, at least 1 and at most limit. A
+// rowspan of 0 spans to the end, as in HTML.
+func cellSpan(cell *goquery.Selection, attr string, limit int) int {
+	v, ok := cell.Attr(attr)
+	if !ok {
+		return 1
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	switch {
+	case err != nil || n < 0:
+		return 1
+	case n == 0 && attr == "rowspan":
+		return max(limit, 1)
+	}
+	return min(max(n, 1), max(limit, 1))
+}
+
+// CellText returns the cleaned-up text of cell i, or "" if the row is too
+// short.
+func CellText(cells []*goquery.Selection, i int) string {
+	if i >= len(cells) {
+		return ""
+	}
+	return truncateText(collapseWhitespace(cells[i].Text()))
+}
+
+// HeaderColumns maps the normalized (see textnorm.Key) first word of each
+// header cell of table to its column index, e.g. "Nama Mata Kuliah" becomes
+// "nama". A cell spanning several columns maps to the first.
+func HeaderColumns(table *goquery.Selection) map[string]int {
+	cols := make(map[string]int)
+	for _, cells := range GridRows(table.Find("thead tr").First(), "th, td") {
+		for i, th := range cells {
+			if fields := strings.Fields(textnorm.Key(th.Text())); len(fields) > 0 {
+				if _, ok := cols[fields[0]]; !ok {
+					cols[fields[0]] = i
+				}
+			}
+		}
+	}
+	return cols
+}
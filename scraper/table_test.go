@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestGridRows(t *testing.T) {
+	doc := docFromHTML(`<table><tbody>
+<tr><td rowspan="2">a</td><td colspan="2">b</td><td rowspan="3">c</td></tr>
+<tr><td>d</td><td>e</td></tr>
+<tr><td colspan="3">f</td></tr>
+<tr><td>g</td></tr>
+</tbody></table>`)
+	want := [][]string{
+		{"a", "b", "b", "c"},
+		{"a", "d", "e", "c"},
+		{"f", "f", "f", "c"},
+		{"g"},
+	}
+	grid := GridRows(doc.Find("tbody tr"), "td")
+	if len(grid) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(grid), len(want))
+	}
+	for i, cells := range grid {
+		if len(cells) != len(want[i]) {
+			t.Errorf("row %d has %d cells, want %d", i, len(cells), len(want[i]))
+			continue
+		}
+		for j := range cells {
+			if got := CellText(cells, j); got != want[i][j] {
+				t.Errorf("cell %d,%d = %q, want %q", i, j, got, want[i][j])
+			}
+		}
+	}
+}
+
+func TestGridRows_CarriesPastShortRows(t *testing.T) {
+	// The spanning cell is in the last column, so the second row ends
+	// before it; an uncovered column in between stays empty.
+	doc := docFromHTML(`<table><tbody>
+<tr><td>a</td><td>b</td><td rowspan="2">c</td></tr>
+<tr><td>d</td></tr>
+</tbody></table>`)
+	grid := GridRows(doc.Find("tbody tr"), "td")
+	if len(grid[1]) != 3 || CellText(grid[1], 1) != "" || CellText(grid[1], 2) != "c" {
+		t.Errorf("second row = %d cells, %q %q", len(grid[1]), CellText(grid[1], 1), CellText(grid[1], 2))
+	}
+}
+
+func TestEachGridRow_StopsEarly(t *testing.T) {
+	doc := docFromHTML(`<table><tbody>
+<tr><td rowspan="3">a</td><td>b</td></tr>
+<tr><td>c</td></tr>
+<tr><td>d</td></tr>
+</tbody></table>`)
+	var rows []string
+	EachGridRow(doc.Find("tbody tr"), "td", func(cells []*goquery.Selection) bool {
+		rows = append(rows, CellText(cells, 0)+CellText(cells, 1))
+		return len(rows) < 2
+	})
+	if strings.Join(rows, ",") != "ab,ac" {
+		t.Errorf("yielded %v, want the first two rows", rows)
+	}
+}
+
+func TestCellSpan_Limits(t *testing.T) {
+	doc := docFromHTML(`<table><tr><td id="big" colspan="100000" rowspan="0"></td><td id="bad" colspan="x"></td></tr></table>`)
+	if got := cellSpan(doc.Find("#big"), "colspan", maxCellSpan); got != maxCellSpan {
+		t.Errorf("colspan = %d", got)
+	}
+	if got := cellSpan(doc.Find("#big"), "rowspan", 7); got != 7 {
+		t.Errorf("rowspan 0 = %d, want the rows left", got)
+	}
+	if got := cellSpan(doc.Find("#bad"), "colspan", maxCellSpan); got != 1 {
+		t.Errorf("bad colspan = %d", got)
+	}
+}
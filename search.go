@@ -0,0 +1,313 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"six-scraper-go/textnorm"
+)
+
+// The search index is an inverted index over the classes in the catalog
+// cache. It is updated whenever a catalog page is stored, so /api/search
+// never walks the cached pages. Matching is typo tolerant: a query term also
+// matches index terms it prefixes or is a small edit distance from.
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Relevance weight of a match in each field of a class.
+var searchFieldWeights = map[string]float64{
+	"code":     4,
+	"name":     3,
+	"lecturer": 2,
+	"notes":    1,
+}
+
+type SearchHit struct {
+	Semester string  `json:"semester"`
+	Fakultas string  `json:"fakultas,omitempty"`
+	Prodi    string  `json:"prodi,omitempty"`
+	Score    float64 `json:"score"`
+	CourseClass
+}
+
+// A class in the index, identified by its position in searchIndex.docs.
+type searchDoc struct {
+	key      string // catalog key of the page it is on
+	semester string
+	filters  url.Values
+	class    CourseClass
+}
+
+type searchPosting struct {
+	doc    int
+	weight float64 // field weight of the best field the term occurs in
+}
+
+type searchIndex struct {
+	mu      sync.RWMutex
+	docs    []*searchDoc               // nil where a page was reindexed
+	pages   map[string][]int           // catalog key to its docs
+	expires map[string]time.Time       // catalog key to when its page expires
+	terms   map[string][]searchPosting // term to the docs containing it
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		pages:   make(map[string][]int),
+		expires: make(map[string]time.Time),
+		terms:   make(map[string][]searchPosting),
+	}
+}
+
+// Splits s into letter and digit runs, normalized with textnorm.Key.
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(textnorm.Key(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Replaces the indexed classes of the catalog page at key. It is the
+// catalog cache's onStore hook.
+func (ix *searchIndex) index(key string, entry cacheEntry) {
+	semester, rawFilters, _ := strings.Cut(key, "?")
+	filters, _ := url.ParseQuery(rawFilters)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.removeLocked(key)
+	ids := make([]int, 0, len(entry.data))
+	for _, c := range entry.data {
+		id := len(ix.docs)
+		ix.docs = append(ix.docs, &searchDoc{key: key, semester: semester, filters: filters, class: c})
+		ids = append(ids, id)
+
+		weights := make(map[string]float64)
+		add := func(field, text string) {
+			for _, t := range searchTerms(text) {
+				weights[t] = max(weights[t], searchFieldWeights[field])
+			}
+		}
+		add("code", c.Code)
+		add("name", c.Name)
+		for _, l := range c.Lecturers {
+			add("lecturer", l)
+		}
+		add("notes", c.Notes)
+		for t, w := range weights {
+			ix.terms[t] = append(ix.terms[t], searchPosting{doc: id, weight: w})
+		}
+	}
+	ix.pages[key] = ids
+	ix.expires[key] = entry.expiresAt
+	ix.compactLocked()
+}
+
+// Drops the docs of the page at key. Their postings are left in place and
+// skipped until the next compaction.
+func (ix *searchIndex) removeLocked(key string) {
+	for _, id := range ix.pages[key] {
+		ix.docs[id] = nil
+	}
+	delete(ix.pages, key)
+	delete(ix.expires, key)
+}
+
+// Rebuilds the postings once more than half of the docs are dead.
+func (ix *searchIndex) compactLocked() {
+	live := 0
+	for _, ids := range ix.pages {
+		live += len(ids)
+	}
+	if len(ix.docs) < 64 || live*2 > len(ix.docs) {
+		return
+	}
+	renumber := make(map[int]int, live)
+	docs := make([]*searchDoc, 0, live)
+	for key, ids := range ix.pages {
+		for i, id := range ids {
+			renumber[id] = len(docs)
+			docs = append(docs, ix.docs[id])
+			ids[i] = renumber[id]
+		}
+		ix.pages[key] = ids
+	}
+	for t, postings := range ix.terms {
+		kept := postings[:0]
+		for _, p := range postings {
+			if id, ok := renumber[p.doc]; ok {
+				kept = append(kept, searchPosting{doc: id, weight: p.weight})
+			}
+		}
+		if len(kept) == 0 {
+			delete(ix.terms, t)
+		} else {
+			ix.terms[t] = kept
+		}
+	}
+	ix.docs = docs
+}
+
+// How well an index term matches a query term: 1 for an exact match, less
+// for a prefix or a typo, 0 for no match.
+func termMatch(query, term string) float64 {
+	switch {
+	case query == term:
+		return 1
+	case len(query) >= 2 && strings.HasPrefix(term, query):
+		return 0.75
+	}
+	allowed := 0
+	switch n := len([]rune(query)); {
+	case n >= 8:
+		allowed = 2
+	case n >= 4:
+		allowed = 1
+	}
+	if allowed > 0 && editDistance(query, term, allowed) <= allowed {
+		return 0.5
+	}
+	return 0
+}
+
+// Returns the Levenshtein distance between a and b, or max+1 once it is
+// known to exceed max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		best := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			best = min(best, cur[j])
+		}
+		if best > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Returns the classes matching every term of q, best first. An empty
+// semester searches all semesters. A class listed on several catalog pages
+// is returned once.
+func (ix *searchIndex) search(q, semester string, limit int, now time.Time) []SearchHit {
+	queryTerms := searchTerms(q)
+	if len(queryTerms) == 0 {
+		return []SearchHit{}
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var scores map[int]float64
+	for _, qt := range queryTerms {
+		termScores := make(map[int]float64)
+		for t, postings := range ix.terms {
+			m := termMatch(qt, t)
+			if m == 0 {
+				continue
+			}
+			for _, p := range postings {
+				doc := ix.docs[p.doc]
+				if doc == nil || (semester != "" && doc.semester != semester) || !now.Before(ix.expires[doc.key]) {
+					continue
+				}
+				termScores[p.doc] = max(termScores[p.doc], m*p.weight)
+			}
+		}
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := termScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	best := make(map[string]SearchHit)
+	for id, score := range scores {
+		doc := ix.docs[id]
+		k := doc.semester + " " + doc.class.Code + " " + doc.class.ClassNo
+		if hit, ok := best[k]; ok && hit.Score >= score {
+			continue
+		}
+		best[k] = SearchHit{
+			Semester:    doc.semester,
+			Fakultas:    doc.filters.Get("fakultas"),
+			Prodi:       doc.filters.Get("prodi"),
+			Score:       math.Round(score*100) / 100,
+			CourseClass: doc.class,
+		}
+	}
+	hits := make([]SearchHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, hit)
+	}
+	slices.SortFunc(hits, func(a, b SearchHit) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(b.Semester, a.Semester),
+			cmp.Compare(a.Code, b.Code),
+			cmp.Compare(a.ClassNo, b.ClassNo),
+		)
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// GET /api/search?q=...
+//
+// Searches the classes in the catalog cache. Only catalog pages some student
+// has already fetched (or that were imported) are searchable. With
+// eligibility or only_eligible, student_id and SIX cookies are needed too.
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultSearchLimit
+	if v := query.Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+	if limit < 1 || limit > maxSearchLimit {
+		writeError(w, r, codeInvalidRequest, "limit must be between 1 and "+strconv.Itoa(maxSearchLimit))
+		return
+	}
+	e, onlyEligible, ok := s.requestedEligibility(w, r, query.Get("student_id"))
+	if !ok {
+		return
+	}
+	if e == nil {
+		writeSuccess(w, s.search.search(query.Get("q"), query.Get("semester"), limit, time.Now()))
+		return
+	}
+	// Search deeper than limit, since ineligible hits may be dropped.
+	hits := s.search.search(query.Get("q"), query.Get("semester"), maxSearchLimit, time.Now())
+	writeSuccess(w, e.annotateHits(hits, onlyEligible, limit))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func searchFixture() *Server {
+	srv := newTestServer("")
+	now := time.Now()
+	srv.catalog.set("2025-2?fakultas=STEI", []CourseClass{
+		{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", Lecturers: []string{"Rinaldi Munir"}},
+		{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "02", Lecturers: []string{"Nur Ulfa Maulidevi"}},
+		{Code: "IF2120", Name: "Matematika Diskrit", ClassNo: "01", Lecturers: []string{"Rinaldi Munir"}},
+	}, now)
+	srv.catalog.set("2025-2?prodi=135", []CourseClass{
+		{Code: "IF2211", Name: "Strategi Algoritma", ClassNo: "01", Lecturers: []string{"Rinaldi Munir"}},
+	}, now)
+	srv.catalog.set("2025-1?fakultas=FMIPA", []CourseClass{
+		{Code: "FI1210", Name: "Fisika Dasar", ClassNo: "01", Notes: "Praktikum algoritma numerik"},
+	}, now)
+	return srv
+}
+
+func TestSearchIndex(t *testing.T) {
+	srv := searchFixture()
+	tests := []struct {
+		name, q, semester string
+		want              []string // code/class_no, best first
+	}{
+		{"code", "if2211", "", []string{"IF2211/01", "IF2211/02"}},
+		{"prefix", "strat", "", []string{"IF2211/01", "IF2211/02"}},
+		{"typo", "algoritme", "2025-2", []string{"IF2211/01", "IF2211/02"}},
+		{"name beats notes", "algoritma", "", []string{"IF2211/01", "IF2211/02", "FI1210/01"}},
+		{"all terms", "munir diskrit", "", []string{"IF2120/01"}},
+		{"semester", "algoritma", "2025-1", []string{"FI1210/01"}},
+		{"no match", "kalkulus", "", nil},
+		{"empty", "  ", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := srv.search.search(tt.q, tt.semester, 20, time.Now())
+			var got []string
+			for _, h := range hits {
+				got = append(got, h.Code+"/"+h.ClassNo)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchIndex_Refresh(t *testing.T) {
+	srv := searchFixture()
+	srv.catalog.set("2025-2?fakultas=STEI", []CourseClass{
+		{Code: "IF2230", Name: "Sistem Operasi", ClassNo: "01"},
+	}, time.Now())
+
+	if hits := srv.search.search("diskrit", "", 20, time.Now()); len(hits) != 0 {
+		t.Errorf("replaced page still searchable: %+v", hits)
+	}
+	if hits := srv.search.search("operasi", "", 20, time.Now()); len(hits) != 1 {
+		t.Errorf("new page not searchable: %+v", hits)
+	}
+	// Only the prodi page still lists IF2211 01.
+	if hits := srv.search.search("if2211", "", 20, time.Now()); len(hits) != 1 || hits[0].Prodi != "135" {
+		t.Errorf("hits = %+v", hits)
+	}
+	if hits := srv.search.search("operasi", "", 20, time.Now().Add(2*politeness.CatalogTTL)); len(hits) != 0 {
+		t.Errorf("expired page still searchable: %+v", hits)
+	}
+}
+
+func TestSearchIndex_Compacts(t *testing.T) {
+	ix := newSearchIndex()
+	entry := cacheEntry{data: make([]CourseClass, 50), expiresAt: time.Now().Add(time.Hour)}
+	for i := range entry.data {
+		entry.data[i] = CourseClass{Code: "IF2211", Name: "Strategi Algoritma"}
+	}
+	for range 10 {
+		ix.index("2025-2?fakultas=STEI", entry)
+	}
+	if len(ix.docs) > 2*len(entry.data) {
+		t.Errorf("docs = %d after reindexing the same page", len(ix.docs))
+	}
+	if hits := ix.search("strategi", "", 100, time.Now()); len(hits) != 1 {
+		t.Errorf("hits = %d, want 1 after deduplication", len(hits))
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"algoritma", "algoritme", 2, 1},
+		{"diskrit", "diskret", 1, 1},
+		{"fisika", "kimia", 1, 2},
+		{"abc", "abcdef", 2, 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	srv := searchFixture()
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/search?"+query, nil))
+		return w
+	}
+
+	w := get("q=rinaldi&limit=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data []SearchHit `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Data) != 1 || resp.Data[0].Semester != "2025-2" {
+		t.Errorf("data = %+v", resp.Data)
+	}
+
+	for _, q := range []string{"", "q=x&limit=0", "q=x&semester=genap"} {
+		if w := get(q); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%q: status %d, want 422", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// "six-scraper-go selftest" fetches every page the server scrapes with one
+// student's cookies, straight from SIX and bypassing every cache, and checks
+// that what was parsed makes sense. Run it after SIX changes to see which
+// parsers still work. Fetches are batch priority, so they are spaced out by
+// SIX_BATCH_DELAY like other background work.
+
+// At most this many problems are listed per endpoint.
+const selfTestMaxProblems = 10
+
+type SelfTestResult struct {
+	Endpoint string
+	Items    int // records parsed
+	Elapsed  time.Duration
+	Problems []string // broken parse invariants
+	Err      error    // the fetch failed
+	Skipped  string   // why the endpoint was not tried
+	Detail   string   // what was checked, printed instead of the count parsed
+}
+
+func (r SelfTestResult) ok() bool {
+	return r.Err == nil && r.Skipped == "" && len(r.Problems) == 0
+}
+
+// Checks what the home page yielded.
+func checkHome(home Home) []string {
+	var problems []string
+	if !studentIDParamRe.MatchString(home.StudentID) {
+		problems = append(problems, fmt.Sprintf("student ID %q is not a NIM", home.StudentID))
+	}
+	if !semesterParamRe.MatchString(home.Semester) {
+		problems = append(problems, fmt.Sprintf("semester %q is not YYYY-N", home.Semester))
+	}
+	return problems
+}
+
+// Checks the classes of a schedule or catalog page. sample is the page's
+// anomaly sample, which says how many table rows there were.
+func checkClasses(classes []CourseClass, sample scrapeSample) []string {
+	var problems []string
+	if sample.Rows > 0 && len(classes) == 0 {
+		problems = append(problems, fmt.Sprintf("table has %d rows but no class was parsed", sample.Rows))
+	}
+	seen := make(map[string]bool, len(classes))
+	for i, c := range classes {
+		id := fmt.Sprintf("class %d (%s)", i+1, classKey(c))
+		if c.Code == "" || c.Name == "" || c.ClassNo == "" {
+			problems = append(problems, id+": code, name, or class number is empty")
+		}
+		if c.SKS < 0 || c.SKS > 24 {
+			problems = append(problems, fmt.Sprintf("%s: %d SKS", id, c.SKS))
+		}
+		if c.Quota < 0 || (c.Enrolled != nil && *c.Enrolled < 0) {
+			problems = append(problems, id+": negative quota or enrollment")
+		}
+		if seen[classKey(c)] {
+			problems = append(problems, id+": listed twice")
+		}
+		seen[classKey(c)] = true
+		for _, m := range c.Schedules {
+			if m.Time == "" {
+				continue
+			}
+			if m.Day == "" || len(classSlots(CourseClass{Schedules: []ScheduleEntry{m}})) == 0 {
+				problems = append(problems, fmt.Sprintf("%s: meeting %q %q has no valid day and time", id, m.Day, m.Time))
+			}
+		}
+	}
+	return problems
+}
+
+func checkTranscript(courses []TranscriptCourse) []string {
+	var problems []string
+	for i, c := range courses {
+		id := fmt.Sprintf("course %d (%s)", i+1, c.Code)
+		if c.Code == "" || c.Name == "" {
+			problems = append(problems, id+": code or name is empty")
+		}
+		if c.SKS <= 0 || c.SKS > 24 {
+			problems = append(problems, fmt.Sprintf("%s: %d SKS", id, c.SKS))
+		}
+		if c.Grade != "" && !slices.Contains(letterGrades, c.Grade) {
+			problems = append(problems, fmt.Sprintf("%s: unknown grade %q", id, c.Grade))
+		}
+	}
+	return problems
+}
+
+func checkCurriculum(courses []CurriculumCourse) []string {
+	var problems []string
+	for i, c := range courses {
+		id := fmt.Sprintf("course %d (%s)", i+1, c.Code)
+		if c.Code == "" || c.Name == "" {
+			problems = append(problems, id+": code or name is empty")
+		}
+		if c.SKS <= 0 || c.SKS > 24 {
+			problems = append(problems, fmt.Sprintf("%s: %d SKS", id, c.SKS))
+		}
+		if c.Semester < 0 || c.Semester > 8 {
+			problems = append(problems, fmt.Sprintf("%s: recommended semester %d", id, c.Semester))
+		}
+		if slices.Contains(c.Prerequisites, c.Code) {
+			problems = append(problems, id+": is its own prerequisite")
+		}
+	}
+	return problems
+}
+
+// Runs the self-test with the SIX credentials in auth, calling report as each
+// endpoint finishes. With prodi set, that catalog page of the current
+// semester is tested too. Returns whether every endpoint passed.
+func (s *Server) selfTest(ctx context.Context, auth http.Header, prodi string, report func(SelfTestResult)) bool {
+	ctx = withPriority(ctx, priorityBatch)
+	newRequest := func() *http.Request {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "/selftest", nil)
+		req.Header = auth.Clone()
+		return req
+	}
+	passed := true
+	run := func(endpoint string, fn func(r *http.Request) (int, []string, error)) {
+		start := time.Now()
+		items, problems, err := fn(newRequest())
+		res := SelfTestResult{Endpoint: endpoint, Items: items, Elapsed: time.Since(start), Problems: problems, Err: err}
+		passed = passed && res.ok()
+		report(res)
+	}
+	skip := func(endpoint, why string) {
+		passed = false
+		report(SelfTestResult{Endpoint: endpoint, Skipped: why})
+	}
+
+	var home Home
+	run("home", func(r *http.Request) (int, []string, error) {
+		var err error
+		home, err = s.provider.FetchHomePage(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		return 1, checkHome(home), nil
+	})
+	if home.StudentID == "" || home.Semester == "" {
+		for _, endpoint := range []string{"schedule", "catalog", "transcript", "curriculum"} {
+			if endpoint != "catalog" || prodi != "" {
+				skip(endpoint, "home page did not yield a student and semester")
+			}
+		}
+		return passed
+	}
+
+	run("schedule", func(r *http.Request) (int, []string, error) {
+		page, err := s.provider.FetchSchedulePage(r, home.StudentID, home.Semester, nil)
+		return len(page.Classes), checkClasses(page.Classes, page.Sample), err
+	})
+	if prodi != "" {
+		run("catalog", func(r *http.Request) (int, []string, error) {
+			page, err := s.provider.FetchSchedulePage(r, home.StudentID, home.Semester, url.Values{"prodi": {prodi}})
+			return len(page.Classes), checkClasses(page.Classes, page.Sample), err
+		})
+	}
+	run("transcript", func(r *http.Request) (int, []string, error) {
+		courses, err := s.provider.FetchTranscript(r, home.StudentID)
+		return len(courses), checkTranscript(courses), err
+	})
+	run("curriculum", func(r *http.Request) (int, []string, error) {
+		courses, err := s.provider.FetchCurriculum(r, home.StudentID)
+		return len(courses), checkCurriculum(courses), err
+	})
+	return passed
+}
+
+// Checks that each upstream host in baseURLs can be reached, before any
+// page is fetched, so that a DNS or routing problem is not mistaken for
+// broken parsers. Returns whether every host passed.
+func selfTestConnectivity(ctx context.Context, opts upstreamOptions, baseURLs []string, report func(SelfTestResult)) bool {
+	passed := true
+	for _, baseURL := range baseURLs {
+		start := time.Now()
+		reachable, detail, problems, err := checkConnectivity(ctx, baseURL, opts)
+		res := SelfTestResult{Endpoint: "connect " + baseURL, Items: reachable, Elapsed: time.Since(start), Problems: problems, Err: err, Detail: detail}
+		if err != nil && detail != "" {
+			res.Err = fmt.Errorf("%s: %w", detail, err)
+		}
+		passed = passed && res.ok()
+		report(res)
+	}
+	return passed
+}
+
+// Writes one line for res, followed by its problems indented.
+func printSelfTestResult(w io.Writer, res SelfTestResult) {
+	switch {
+	case res.Skipped != "":
+		fmt.Fprintf(w, "%s\tSKIP\t%s\n", res.Endpoint, res.Skipped)
+		return
+	case res.Err != nil:
+		fmt.Fprintf(w, "%s\tFAIL\t%s\terror: %v\n", res.Endpoint, res.Elapsed.Round(time.Millisecond), res.Err)
+		return
+	case len(res.Problems) > 0:
+		fmt.Fprintf(w, "%s\tFAIL\t%s\t%d parsed, %d problems\n", res.Endpoint, res.Elapsed.Round(time.Millisecond), res.Items, len(res.Problems))
+	case res.Detail != "":
+		fmt.Fprintf(w, "%s\tPASS\t%s\t%s\n", res.Endpoint, res.Elapsed.Round(time.Millisecond), res.Detail)
+	default:
+		fmt.Fprintf(w, "%s\tPASS\t%s\t%d parsed\n", res.Endpoint, res.Elapsed.Round(time.Millisecond), res.Items)
+	}
+	for i, p := range res.Problems {
+		if i == selfTestMaxProblems {
+			fmt.Fprintf(w, "\t... and %d more\n", len(res.Problems)-i)
+			break
+		}
+		fmt.Fprintf(w, "\t%s\n", p)
+	}
+}
+
+// Runs "six-scraper-go selftest" and returns the exit code: 0 if every
+// endpoint passed, 1 if any failed.
+func runSelfTestCommand(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	cookies := fs.String("cookies", os.Getenv("SIX_COOKIES"), `SIX Cookie header, e.g. "nissin=...; khongguan=..." (default $SIX_COOKIES)`)
+	prodi := fs.String("prodi", "", "also test this prodi's catalog page, e.g. 135")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*cookies) == "" {
+		fmt.Fprintln(os.Stderr, "selftest: -cookies is required")
+		fs.Usage()
+		return 2
+	}
+
+	cfg := configFromEnv()
+	transport, err := upstreamTransport()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "selftest: %v\n", err)
+		return 2
+	}
+	cfg.Transport = transport
+	srv := NewServer(cfg)
+	fmt.Printf("testing %s against %s\n", srv.provider.Name(), cfg.BaseURL)
+	opts, _ := upstreamOptionsFromEnv() // already checked by upstreamTransport
+	report := func(res SelfTestResult) { printSelfTestResult(os.Stdout, res) }
+	if !selfTestConnectivity(context.Background(), opts, slices.DeleteFunc([]string{cfg.BaseURL, cfg.APIURL}, func(u string) bool { return u == "" }), report) {
+		return 1
+	}
+	auth := http.Header{"Cookie": {*cookies}}
+	if !srv.selfTest(context.Background(), auth, *prodi, report) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckClasses(t *testing.T) {
+	good := CourseClass{Code: "IF2211", Name: "Strategi Algoritma", SKS: 3, ClassNo: "01", Quota: 40,
+		Schedules: []ScheduleEntry{{Day: "Senin", Time: "07:00-09:00"}, {}}}
+	if problems := checkClasses([]CourseClass{good}, scrapeSample{Rows: 1}); len(problems) != 0 {
+		t.Errorf("problems for a good class: %v", problems)
+	}
+
+	badTime := good
+	badTime.ClassNo = "02"
+	badTime.Schedules = []ScheduleEntry{{Day: "Senin", Time: "9-11"}}
+	noName := good
+	noName.ClassNo, noName.Name = "03", ""
+	problems := checkClasses([]CourseClass{good, good, badTime, noName}, scrapeSample{Rows: 4})
+	if len(problems) != 3 {
+		t.Errorf("problems = %q, want duplicate, bad time, and empty name", problems)
+	}
+
+	if problems := checkClasses(nil, scrapeSample{Rows: 12}); len(problems) != 1 {
+		t.Errorf("rows without classes: problems = %q", problems)
+	}
+	if problems := checkClasses(nil, scrapeSample{}); len(problems) != 0 {
+		t.Errorf("empty page: problems = %q", problems)
+	}
+}
+
+func TestCheckTranscriptAndCurriculum(t *testing.T) {
+	transcript := []TranscriptCourse{{Code: "IF2211", Name: "Stima", SKS: 3, Grade: "A"}, {Code: "IF2230", Name: "OS", SKS: 3}, {Code: "IF2240", Name: "Basdat", SKS: 3, Grade: "F"}}
+	if problems := checkTranscript(transcript); len(problems) != 1 || !strings.Contains(problems[0], `"F"`) {
+		t.Errorf("transcript problems = %q", problems)
+	}
+	curriculum := []CurriculumCourse{{Code: "IF2211", Name: "Stima", SKS: 3, Semester: 4}, {Code: "IF3130", Name: "Jarkom", SKS: 0, Semester: 9, Prerequisites: []string{"IF3130"}}}
+	if problems := checkCurriculum(curriculum); len(problems) != 3 {
+		t.Errorf("curriculum problems = %q", problems)
+	}
+}
+
+func TestSelfTest_Report(t *testing.T) {
+	p := &stubProvider{
+		home:    Home{StudentID: "13520001", Semester: "2025-2"},
+		classes: []CourseClass{{Code: "IF2211", Name: "Strategi Algoritma", SKS: 3, ClassNo: "01"}},
+	}
+	srv := NewServer(Config{Provider: p})
+	var results []SelfTestResult
+	passed := srv.selfTest(context.Background(), http.Header{}, "135", func(res SelfTestResult) { results = append(results, res) })
+
+	// The stub provider has no transcript or curriculum.
+	if passed {
+		t.Error("self-test passed with failing endpoints")
+	}
+	var out bytes.Buffer
+	for _, res := range results {
+		printSelfTestResult(&out, res)
+	}
+	for _, want := range []string{"home\tPASS", "schedule\tPASS", "catalog\tPASS", "transcript\tFAIL", "curriculum\tFAIL"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	p.home = Home{}
+	results = nil
+	srv.selfTest(context.Background(), http.Header{}, "", func(res SelfTestResult) { results = append(results, res) })
+	if len(results) != 4 || results[1].Skipped == "" {
+		t.Errorf("without a home page: %+v", results)
+	}
+}
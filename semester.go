@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+// How long a semester learned from a SIX redirect is trusted before falling
+// back to the academic calendar.
+const learnedSemesterTTL = 24 * time.Hour
+
+// Relative values accepted wherever a semester is expected, as an offset
+// from the current semester.
+var relativeSemesters = map[string]int{"previous": -1, "current": 0, "next": 1}
+
+type learnedSemester struct {
+	semester  scraper.Semester
+	learnedAt time.Time
+}
+
+// Remembers the semester SIX redirected each student to, which is more
+// accurate than the calendar around semester boundaries.
+type semesterTracker struct {
+	mu       sync.Mutex
+	students map[string]learnedSemester
+}
+
+func newSemesterTracker() *semesterTracker {
+	return &semesterTracker{students: make(map[string]learnedSemester)}
+}
+
+func (t *semesterTracker) learn(studentID, semester string, now time.Time) {
+	sem, err := scraper.ParseSemester(semester)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.students[studentID] = learnedSemester{semester: sem, learnedAt: now}
+}
+
+// Returns studentID's current semester: the one SIX last redirected them to
+// if that is recent, otherwise the one the academic calendar says is in
+// session.
+func (t *semesterTracker) current(studentID string, now time.Time) scraper.Semester {
+	t.mu.Lock()
+	l, ok := t.students[studentID]
+	t.mu.Unlock()
+	if ok && now.Sub(l.learnedAt) < learnedSemesterTTL {
+		return l.semester
+	}
+	return scraper.CalendarSemester(now.In(wib))
+}
+
+// Resolves semester=current, previous, or next to a concrete semester for
+// studentID. Other values are returned unchanged, with relative false.
+func (t *semesterTracker) resolve(studentID, semester string, now time.Time) (resolved string, relative bool) {
+	offset, ok := relativeSemesters[semester]
+	if !ok {
+		return semester, false
+	}
+	return t.current(studentID, now).Add(offset).String(), true
+}
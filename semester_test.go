@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSemesterTracker_Resolve(t *testing.T) {
+	tr := newSemesterTracker()
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, wib)
+
+	tests := []struct{ in, want string }{
+		{"current", "2025-2"},
+		{"previous", "2025-1"},
+		{"next", "2026-1"},
+		{"2024-1", "2024-1"},
+	}
+	for _, tt := range tests {
+		got, relative := tr.resolve("123", tt.in, now)
+		if got != tt.want || relative != (tt.in != tt.want) {
+			t.Errorf("resolve(%q) = %q, %v; want %q", tt.in, got, relative, tt.want)
+		}
+	}
+
+	// A semester learned from SIX wins over the calendar until it goes stale.
+	tr.learn("123", "2026-1", now)
+	if got, _ := tr.resolve("123", "current", now); got != "2026-1" {
+		t.Errorf("learned current = %q, want 2026-1", got)
+	}
+	if got, _ := tr.resolve("456", "current", now); got != "2025-2" {
+		t.Errorf("other student current = %q, want 2025-2", got)
+	}
+	if got, _ := tr.resolve("123", "current", now.Add(learnedSemesterTTL)); got != "2025-2" {
+		t.Errorf("stale current = %q, want 2025-2", got)
+	}
+}
+
+func TestScheduleHandler_RelativeSemester(t *testing.T) {
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	// /api/user teaches the server the student's current semester.
+	req := httptest.NewRequest("GET", "/api/user", nil)
+	addAuthCookies(req)
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=current", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if meta := decodeMeta(t, w); meta.Semester != "1945-1" {
+		t.Errorf("meta = %+v, want semester 1945-1", meta)
+	}
+	if _, ok := srv.cache.peek(schedulePath("123", "1945-1", nil)); !ok {
+		t.Error("expected the schedule to be cached under the resolved semester")
+	}
+
+	req = httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=last", nil)
+	addAuthCookies(req)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown keyword: got status %d, want 422", w.Code)
+	}
+}
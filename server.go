@@ -0,0 +1,588 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const defaultBaseURL = "https://six.itb.ac.id"
+
+// Config holds what a Server is built from. Zero values fall back to
+// defaultBaseURL and the TTLs of the politeness profile.
+type Config struct {
+	BaseURL   string            // origin of SIX; tests point it at a mock server
+	CacheTTL  time.Duration     // how long schedule responses are served from cache
+	DataDir   string            // where last known good snapshots are persisted; empty keeps them in memory only
+	Transport http.RoundTripper // transport for all upstream requests
+	Provider  Provider          // upstream data source; nil scrapes SIX at BaseURL through Transport
+	APIURL    string            // origin of an official SIX JSON API preferred over scraping; empty disables it
+
+	CatalogTTL time.Duration // how long catalog pages are shared across students and peers
+	PeerURL    string        // origin of a peer instance asked for catalog pages before SIX; empty disables federation
+	PeerAPIKey string        // X-API-Key sent to the peer
+
+	AuditLog string // file every /api/ request is appended to; empty disables the audit log
+}
+
+// Reads the server configuration from SIX_* environment variables.
+func configFromEnv() Config {
+	return Config{
+		BaseURL:  envString("SIX_BASE_URL", defaultBaseURL),
+		CacheTTL: politeness.CacheTTL,
+		DataDir:  envString("SIX_DATA_DIR", ""),
+		APIURL:   envString("SIX_API_URL", ""),
+
+		CatalogTTL: politeness.CatalogTTL,
+		PeerURL:    envString("SIX_PEER_URL", ""),
+		PeerAPIKey: envString("SIX_PEER_API_KEY", ""),
+
+		AuditLog: envString("SIX_AUDIT_LOG", ""),
+	}
+}
+
+// Server owns the per-instance state behind the API: the schedule cache, the
+// anomaly history, the last known good snapshots, and the upstream transport.
+// Independent servers can run side by side, e.g. one per test.
+type Server struct {
+	cfg          Config
+	mux          *http.ServeMux
+	spec         *OpenAPI
+	handler      http.Handler // mux wrapped in the middleware every request passes through
+	metrics      *requestMetrics
+	cache        *scheduleCache
+	catalog      *scheduleCache // catalog pages keyed by catalogKey
+	search       *searchIndex   // classes in catalog
+	buildings    []Building     // nil unless SIX_BUILDINGS_FILE is set
+	peer         *peerClient    // nil unless federation is configured
+	provider     Provider
+	anomalies    *anomalyDetector
+	lastGood     *snapshotStore
+	history      *versionHistory
+	bundles      *bundleStore
+	refresher    *refresher
+	mirror       *upstreamMirror
+	audit        *auditLog // nil when Config.AuditLog is empty
+	semesters    *semesterTracker
+	backfills    *backfillJobs
+	gradeWatches *gradeWatcher
+	swaps        *swapBoard
+	orgs         *orgRegistry
+	consents     *consentLedger
+	academic     *academicCalendar // semester ends and FRS periods from SIX's calendar
+	warmer       *catalogWarmer
+	templates    map[string]*template.Template // nil unless SIX_TEMPLATE_DIR is set
+	chat         map[string]ChatAdapter        // configured chat providers by name
+	chatLinks    *chatLinks
+	mqtt         *mqttPublisher
+	fill         *fillHistory
+	grades       *ttlCache[[]GradeEntry] // keyed by gradesPath and sessionKey
+	crawler      *catalogCrawler
+	fullCatalogs *ttlCache[FullCatalog] // complete catalogs by semester
+	assembling   *catalogBuilds
+	pageOptions  *ttlCache[url.Values] // by catalogPageKey, for pages served from catalog
+	deliveries   sync.WaitGroup        // webhook and channel deliveries in flight
+	deadLetters  *deadLetterQueue
+}
+
+func NewServer(cfg Config) *Server {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = politeness.CacheTTL
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.CatalogTTL <= 0 {
+		cfg.CatalogTTL = politeness.CatalogTTL
+	}
+	academic := newAcademicCalendar()
+	s := &Server{
+		cfg:          cfg,
+		mux:          http.NewServeMux(),
+		spec:         newOpenAPI(),
+		metrics:      newRequestMetrics(),
+		cache:        newScheduleCache(cfg.CacheTTL),
+		catalog:      newScheduleCache(cfg.CatalogTTL),
+		anomalies:    newAnomalyDetector(),
+		lastGood:     newSnapshotStore(cfg.DataDir),
+		history:      newVersionHistory(cfg.DataDir),
+		bundles:      newBundleStore(bundleKeep),
+		refresher:    newRefresher(refreshPerHost, refreshQueue),
+		mirror:       newUpstreamMirror(mirrorConcurrency),
+		semesters:    newSemesterTracker(),
+		backfills:    newBackfillJobs(),
+		gradeWatches: newGradeWatcher(),
+		swaps:        newSwapBoard(),
+		orgs:         newOrgRegistry(),
+		consents:     newConsentLedger(),
+		academic:     academic,
+		warmer:       newCatalogWarmer(warmSchedules, frsPeriods, academic),
+		search:       newSearchIndex(),
+		chat:         newChatAdapters(),
+		chatLinks:    newChatLinks(),
+		mqtt:         newMQTTPublisher(),
+		fill:         newFillHistory(cfg.DataDir),
+		grades:       newTTLCache[[]GradeEntry](cfg.CacheTTL),
+		crawler:      newCatalogCrawler(cfg.DataDir),
+		fullCatalogs: newTTLCache[FullCatalog](cfg.CatalogTTL),
+		assembling:   newCatalogBuilds(),
+		pageOptions:  newTTLCache[url.Values](cfg.CatalogTTL),
+		deadLetters:  &deadLetterQueue{},
+	}
+	s.catalog.onStore = func(key string, entry cacheEntry) {
+		s.search.index(key, entry)
+		s.fill.record(key, entry)
+	}
+	if audit, err := openAuditLog(cfg.AuditLog); err != nil {
+		log.Printf("audit log not opened: %v", err)
+	} else {
+		s.audit = audit
+	}
+	if buildingsFile != "" {
+		buildings, err := loadBuildings(buildingsFile)
+		if err != nil {
+			log.Printf("building coordinates not loaded: %v", err)
+		} else {
+			s.buildings = buildings
+		}
+	}
+	if templateDir != "" {
+		templates, err := loadTemplates(templateDir)
+		if err != nil {
+			log.Printf("templates not loaded: %v", err)
+		} else {
+			s.templates = templates
+			log.Printf("loaded %d output templates from %s", len(templates), templateDir)
+		}
+	}
+	s.provider = cfg.Provider
+	if s.provider == nil {
+		s.provider = newSIXProvider(cfg.BaseURL, &mirrorTransport{next: cfg.Transport, mirror: s.mirror})
+		if cfg.APIURL != "" {
+			api := newOfficialAPI(strings.TrimSuffix(cfg.APIURL, "/"), cfg.Transport)
+			s.provider = newFallbackProvider(api, s.provider)
+		}
+	}
+	if cfg.PeerURL != "" {
+		s.peer = newPeerClient(strings.TrimSuffix(cfg.PeerURL, "/"), cfg.PeerAPIKey)
+	}
+	s.routes()
+	s.handler = chain(s.mux, s.middleware()...)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() {
+	public := newRouter(s.mux, s.spec)
+	api := public.with(recordTraffic, requireAPIKey, authorize)
+
+	idParam := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+
+	api.handle("GET", "/api/user", &Operation{Summary: "Current student ID and semester"}, s.userHandler)
+	api.handle("GET", "/api/profile", &Operation{Summary: "A student's name, NIM, program, faculty, and enrollment year", Parameters: []Parameter{studentIDParam}}, s.profileHandler)
+	api.handle("GET", "/api/schedule", &Operation{
+		Summary: "Class schedule",
+		Parameters: append(slices.Concat(scheduleParams, pipelineParams(), eligibilityParams), Parameter{
+			Name: "format", In: "query", Description: "json (default); grid, the week by day with the transitions between classes; geojson, the in-person meetings as map points; or template, text rendered with the operator's template given by name. Transform parameters apply to every format, fields only to json",
+			Schema: &Schema{Type: "string", Enum: []string{"json", "grid", "geojson", "template"}},
+		}, Parameter{
+			Name: "name", In: "query", Description: "Template for format=template",
+			Schema: &Schema{Type: "string", Pattern: templateNameRe.String()},
+		}),
+	}, s.scheduleHandler)
+	api.handle("GET", "/api/schedule/text", &Operation{
+		Summary: "A spoken-style summary of a day or week of classes, for voice assistants and chatbots",
+		Parameters: append(slices.Clone(scheduleParams),
+			Parameter{Name: "period", In: "query", Description: "today (default), tomorrow, or week", Schema: &Schema{Type: "string", Enum: []string{"today", "tomorrow", "week"}}},
+			Parameter{Name: "date", In: "query", Description: "Day to use as today, YYYY-MM-DD in WIB", Schema: &Schema{Type: "string", Pattern: `^\d{4}-\d{2}-\d{2}$`}},
+			Parameter{Name: "lang", In: "query", Description: "id (default) or en", Schema: &Schema{Type: "string", Enum: []string{"id", "en"}}},
+		),
+	}, s.scheduleTextHandler)
+	api.handle("GET", "/api/schedule/render", &Operation{
+		Summary: "The week as an image, for e-ink dashboards",
+		Parameters: append(slices.Clone(scheduleParams),
+			Parameter{Name: "format", In: "query", Description: "epaper (default), a 1-bit PNG", Schema: &Schema{Type: "string", Enum: []string{"epaper"}}},
+			Parameter{Name: "width", In: "query", Description: "Width in pixels, 200 to 2000 (default 800)", Schema: &Schema{Type: "integer"}},
+			Parameter{Name: "height", In: "query", Description: "Height in pixels, 200 to 2000 (default 480)", Schema: &Schema{Type: "integer"}},
+		),
+	}, s.renderHandler)
+	api.handle("GET", "/api/schedule/ical", &Operation{
+		Summary: "The schedule as an iCalendar file of weekly events, for calendar apps",
+		Parameters: append(slices.Clone(scheduleParams),
+			Parameter{Name: "start", In: "query", Description: "First day of lectures, YYYY-MM-DD in WIB (default from ITB's usual calendar)", Schema: &Schema{Type: "string", Pattern: `^\d{4}-\d{2}-\d{2}$`}},
+			Parameter{Name: "weeks", In: "query", Description: "Weeks each meeting repeats, 1 to 30 (default 16)", Schema: &Schema{Type: "integer"}},
+		),
+	}, s.icalHandler)
+	api.handle("GET", "/api/analytics/clashes", &Operation{
+		Summary: "Which courses of a catalog page, such as a prodi's, meet at the same time",
+		Parameters: append(slices.Clone(scheduleParams),
+			Parameter{Name: "top", In: "query", Description: "Course pairs in the top list (default 20)", Schema: &Schema{Type: "integer"}},
+		),
+	}, s.clashHandler)
+	api.handle("GET", "/api/analytics/rooms", &Operation{
+		Summary: "How much of the teaching week each room of a faculty is booked, from the cached catalog",
+		Parameters: []Parameter{
+			relativeSemesterParam,
+			{Name: "fakultas", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "format", In: "query", Description: "json (default) or csv, one row per room, day, and hour", Schema: &Schema{Type: "string", Enum: []string{"json", "csv"}}},
+		},
+	}, s.roomsHandler)
+	api.handle("GET", "/api/analytics/lecturer-load", &Operation{
+		Summary: "Classes, SKS, contact hours, and courses per lecturer of a faculty, from the cached catalog",
+		Parameters: []Parameter{
+			relativeSemesterParam,
+			{Name: "fakultas", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "format", In: "query", Description: "json (default) or csv, one row per lecturer", Schema: &Schema{Type: "string", Enum: []string{"json", "csv"}}},
+		},
+	}, s.lecturerLoadHandler)
+	api.handle("GET", "/api/widget", &Operation{
+		Summary:    "The next three sessions, a small stable payload for home screen widgets",
+		Parameters: []Parameter{studentIDParam, relativeSemesterParam, {Name: "refresh", In: "query", Description: "Bypass the cache", Schema: &Schema{Type: "boolean"}}},
+	}, s.widgetHandler)
+	api.handle("GET", "/api/schedule/diff", &Operation{
+		Summary: "What changed in a schedule since its last good snapshot, fetched fresh",
+		Parameters: append(slices.Clone(scheduleParams), Parameter{
+			Name: "format", In: "query", Description: "json (default), or markdown, a changelog to paste into chats",
+			Schema: &Schema{Type: "string", Enum: []string{"json", "markdown"}},
+		}),
+	}, s.diffHandler)
+	api.handle("GET", "/api/schedule/last-good", &Operation{
+		Summary:    "Last schedule snapshot that passed the anomaly check",
+		Parameters: slices.Concat(scheduleParams, pipelineParams()),
+	}, s.lastGoodHandler)
+	api.handle("GET", "/api/bundle", &Operation{
+		Summary:    "A .tar.gz of profile, schedule, calendar, and rooms for offline use",
+		Parameters: scheduleParams,
+	}, s.bundleHandler)
+	api.handle("GET", "/api/bundle/{hash}", &Operation{
+		Summary: "A recently built bundle by its hash",
+		Parameters: []Parameter{
+			{Name: "hash", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: "^[0-9a-f]{32}$"}},
+			studentIDParam,
+		},
+	}, s.storedBundleHandler)
+	api.handle("POST", "/api/sync", &Operation{
+		Summary:    "Classes of a schedule that differ from the hashes a client holds, plus deletions",
+		Parameters: scheduleParams,
+		RequestBody: jsonBody(&Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"have": {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			},
+		}),
+	}, s.syncHandler)
+	api.handle("GET", "/api/schedule/history", &Operation{
+		Summary: "Distinct versions of a schedule over time, or the classes of one version",
+		Parameters: append(slices.Clone(scheduleParams), Parameter{
+			Name: "version", In: "query", Description: "Hash of a version to return the classes of",
+			Schema: &Schema{Type: "string", Pattern: "^[0-9a-f]{64}$"},
+		}),
+	}, s.historyHandler)
+	api.handle("GET", "/api/classes/{code}/{class_no}", &Operation{
+		Summary: "One class of a schedule",
+		Parameters: append([]Parameter{
+			{Name: "code", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "class_no", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		}, slices.Concat(scheduleParams, pipelineParams())...),
+	}, s.classHandler)
+	api.handle("GET", "/api/transcript", &Operation{Summary: "Courses taken and their grades", Parameters: []Parameter{studentIDParam}}, s.transcriptHandler)
+	api.handle("GET", "/api/grades", &Operation{
+		Summary:    "A student's grades for one semester",
+		Parameters: []Parameter{studentIDParam, relativeSemesterParam, {Name: "refresh", In: "query", Description: "Bypass the cache", Schema: &Schema{Type: "boolean"}}},
+	}, s.gradesHandler)
+	api.handle("GET", "/api/catalog", &Operation{
+		Summary:    "Every class offered in a semester, merged from the catalog page of every faculty and program",
+		Parameters: []Parameter{studentIDParam, relativeSemesterParam, {Name: "refresh", In: "query", Description: "Bypass the cache", Schema: &Schema{Type: "boolean"}}},
+	}, s.fullCatalogHandler)
+	api.handle("GET", "/api/calendar", &Operation{Summary: "ITB's academic calendar: semester dates, exam weeks, and holidays", Parameters: []Parameter{semesterFilterParam}}, s.calendarHandler)
+	api.handle("GET", "/api/frs", &Operation{Summary: "A student's study plan (FRS) for one semester and its approval by their advisor", Parameters: []Parameter{studentIDParam, relativeSemesterParam}}, s.frsHandler)
+	api.handle("GET", "/api/payments", &Operation{Summary: "A student's tuition (UKT) and other bills, with their due dates and whether they were paid", Parameters: []Parameter{studentIDParam}}, s.paymentsHandler)
+	api.handle("GET", "/api/exams", &Operation{Summary: "A student's UTS and UAS exam schedule for one semester", Parameters: []Parameter{studentIDParam, relativeSemesterParam}}, s.examsHandler)
+	api.handle("GET", "/api/progress", &Operation{Summary: "Graduation progress against the curriculum", Parameters: []Parameter{studentIDParam}}, s.progressHandler)
+	api.handle("GET", "/api/gpa", &Operation{Summary: "IP of every semester and cumulative IPK, computed and as SIX reports them", Parameters: []Parameter{studentIDParam}}, s.gpaHandler)
+	api.handle("POST", "/api/gpa/what-if", &Operation{
+		Summary: "Projected IP and IPK for hypothetical grades of in-progress courses",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"student_id"},
+			Properties: map[string]*Schema{
+				"student_id": studentIDParam.Schema,
+				"grades":     {Type: "object", AdditionalProperties: &Schema{Type: "string", Enum: letterGrades}},
+			},
+		}),
+	}, s.gpaWhatIfHandler)
+	api.handle("POST", "/api/sks/check", &Operation{
+		Summary: "Check a planned class selection against the student's SKS limit",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"student_id", "classes"},
+			Properties: map[string]*Schema{
+				"student_id": studentIDParam.Schema,
+				"classes": {Type: "array", Items: &Schema{
+					Type:     "object",
+					Required: []string{"code", "sks"},
+					Properties: map[string]*Schema{
+						"code":     {Type: "string"},
+						"class_no": {Type: "string"},
+						"sks":      {Type: "integer"},
+					},
+				}},
+			},
+		}),
+	}, s.sksCheckHandler)
+	api.handle("GET", "/api/grades/watches", &Operation{Summary: "List grade release watches"}, s.listGradeWatches)
+	api.handle("POST", "/api/grades/watches", &Operation{
+		Summary: "Watch a student's transcript for newly released grades",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"student_id", "url"},
+			Properties: map[string]*Schema{
+				"student_id": studentIDParam.Schema,
+				"url":        webhookURLSchema,
+				"secret":     {Type: "string"},
+			},
+		}),
+	}, s.createGradeWatch)
+	api.handle("GET", "/api/grades/watches/{id}", &Operation{Summary: "Get a grade release watch", Parameters: []Parameter{idParam}}, s.getGradeWatch)
+	api.handle("DELETE", "/api/grades/watches/{id}", &Operation{Summary: "Stop a grade release watch", Parameters: []Parameter{idParam}}, s.deleteGradeWatch)
+	api.handle("GET", "/api/swaps", &Operation{Summary: "List class swap requests"}, s.listSwaps)
+	api.handle("POST", "/api/swaps", &Operation{
+		Summary: "Offer a class in exchange for another class of the same course",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"student_id", "semester", "course", "have", "want"},
+			Properties: map[string]*Schema{
+				"student_id": studentIDParam.Schema,
+				"semester":   relativeSemesterParam.Schema,
+				"course":     {Type: "string", Pattern: `^[A-Za-z0-9]{2,10}$`},
+				"have":       {Type: "string", Pattern: `^[A-Za-z0-9]{1,8}$`},
+				"want":       {Type: "string", Pattern: `^[A-Za-z0-9]{1,8}$`},
+				"url":        webhookURLSchema,
+				"secret":     {Type: "string"},
+			},
+		}),
+	}, s.createSwap)
+	api.handle("GET", "/api/swaps/{id}", &Operation{Summary: "Get a class swap request", Parameters: []Parameter{idParam}}, s.getSwap)
+	api.handle("DELETE", "/api/swaps/{id}", &Operation{Summary: "Withdraw a class swap request", Parameters: []Parameter{idParam}}, s.deleteSwap)
+	api.handle("POST", "/api/orgs", &Operation{
+		Summary:     "Create an organization that members can share their schedules with",
+		RequestBody: jsonBody(&Schema{Type: "object", Required: []string{"name"}, Properties: map[string]*Schema{"name": {Type: "string"}}}),
+	}, s.createOrg)
+	api.handle("GET", "/api/orgs/{id}", &Operation{Summary: "Get an organization and its members (owner)", Parameters: []Parameter{idParam}}, s.getOrg)
+	api.handle("DELETE", "/api/orgs/{id}", &Operation{Summary: "Delete an organization (owner)", Parameters: []Parameter{idParam}}, s.deleteOrg)
+	api.handle("POST", "/api/orgs/{id}/members", &Operation{
+		Summary:    "Join an organization with your own SIX session",
+		Parameters: []Parameter{idParam},
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"join_code", "name", "consent"},
+			Properties: map[string]*Schema{
+				"join_code": {Type: "string"},
+				"name":      {Type: "string"},
+				"consent":   {Type: "boolean"},
+			},
+		}),
+	}, s.joinOrg)
+	api.handle("DELETE", "/api/orgs/{id}/members/{member_id}", &Operation{
+		Summary:    "Leave an organization, or remove a member (owner)",
+		Parameters: []Parameter{idParam, {Name: "member_id", In: "path", Required: true, Schema: &Schema{Type: "string"}}},
+	}, s.leaveOrg)
+	api.handle("GET", "/api/orgs/{id}/schedule", &Operation{
+		Summary:    "Who is busy when, shared free slots, and room usage across members (owner)",
+		Parameters: []Parameter{idParam, relativeSemesterParam},
+	}, s.orgScheduleHandler)
+	api.handle("GET", "/api/me/consent", &Operation{Summary: "Consents for stored SIX cookies, active and recently ended"}, s.listConsents)
+	api.handle("DELETE", "/api/me/consent/{id}", &Operation{
+		Summary:    "Revoke a consent and drop the cookies stored under it",
+		Parameters: []Parameter{idParam},
+	}, s.revokeConsent)
+	api.handle("POST", "/api/chat/links", &Operation{Summary: "A code that links a LINE or WhatsApp account to the caller's SIX session"}, s.createChatLink)
+	api.handle("GET", "/api/peer/catalog", &Operation{
+		Summary: "A cached catalog page, for peer instances",
+		Parameters: []Parameter{
+			semesterParam,
+			{Name: "fakultas", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "prodi", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "pekan", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "kegiatan", In: "query", Schema: &Schema{Type: "string"}},
+		},
+	}, s.peerCatalogHandler)
+	api.handle("GET", "/api/search", &Operation{
+		Summary: "Typo-tolerant search over cached catalog classes",
+		Parameters: append([]Parameter{
+			{Name: "q", In: "query", Required: true, Description: "Course code, name, lecturer, or notes", Schema: &Schema{Type: "string"}},
+			{Name: "semester", In: "query", Description: "Semester, e.g. 2025-2; all cached semesters if omitted", Schema: &Schema{Type: "string", Pattern: semesterParamRe.String()}},
+			{Name: "limit", In: "query", Description: "Maximum number of results, 1 to 100 (default 20)", Schema: &Schema{Type: "integer"}},
+			{Name: "student_id", In: "query", Description: "NIM, needed with eligibility or only_eligible", Schema: studentIDParam.Schema},
+		}, eligibilityParams...),
+	}, s.searchHandler)
+	api.handle("GET", "/api/waitlist", &Operation{
+		Summary: "Heuristic chance that a full class reopens seats, from past fill rates",
+		Parameters: []Parameter{
+			relativeSemesterParam,
+			{Name: "code", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "class_no", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+		},
+	}, s.waitlistHandler)
+	api.handle("GET", "/api/me/usage", &Operation{Summary: "API key usage today"}, usageHandler)
+	api.handle("GET", "/api/subscriptions", &Operation{Summary: "List webhook subscriptions"}, listSubscriptions)
+	api.handle("POST", "/api/subscriptions", &Operation{
+		Summary: "Create a webhook subscription",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"student_id", "semester"},
+			Properties: map[string]*Schema{
+				"url":         webhookURLSchema,
+				"channels":    channelsSchema,
+				"secret":      {Type: "string"},
+				"student_id":  studentIDParam.Schema,
+				"semester":    semesterParam.Schema,
+				"filters":     filtersSchema,
+				"quiet_hours": quietHoursSchema,
+				"deadline":    {Type: "string"},
+			},
+		}),
+	}, s.createSubscription)
+	api.handle("GET", "/api/subscriptions/{id}", &Operation{Summary: "Get a webhook subscription", Parameters: []Parameter{idParam}}, getSubscription)
+	api.handle("PATCH", "/api/subscriptions/{id}", &Operation{
+		Summary:    "Update a webhook subscription",
+		Parameters: []Parameter{idParam},
+		RequestBody: jsonBody(&Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"url":         {Type: "string", Pattern: `^$|^https?://[^/?#\s]+`},
+				"channels":    channelsSchema,
+				"secret":      {Type: "string", Pattern: ".+"},
+				"paused":      {Type: "boolean"},
+				"quiet_hours": quietHoursSchema,
+				"deadline":    {Type: "string"},
+				"expires_at":  {Type: "string"},
+			},
+		}),
+	}, updateSubscription)
+	api.handle("DELETE", "/api/subscriptions/{id}", &Operation{Summary: "Delete a webhook subscription", Parameters: []Parameter{idParam}}, deleteSubscription)
+
+	chatProviderParam := Parameter{Name: "provider", In: "path", Required: true, Schema: &Schema{Type: "string", Enum: []string{"line", "whatsapp"}}}
+	public.handle("POST", "/api/chat/webhook/{provider}", &Operation{
+		Summary:    "Webhook receiver for a chat provider",
+		Parameters: []Parameter{chatProviderParam},
+	}, s.chatWebhook)
+	public.handle("GET", "/api/chat/webhook/{provider}", &Operation{
+		Summary:    "Webhook verification, for providers that use it (WhatsApp)",
+		Parameters: []Parameter{chatProviderParam},
+	}, s.chatVerifyHandler)
+	public.handle("GET", "/api/status", &Operation{Summary: "Maintenance and scraping status"}, statusHandler)
+	public.handle("GET", "/api/admin/metrics", &Operation{Summary: "Per-route request metrics (admin)"}, s.metricsHandler)
+	public.handle("GET", "/api/admin/jobs", &Operation{Summary: "Background jobs and their schedules (admin)"}, s.jobsHandler)
+	public.handle("GET", "/api/admin/upstream", &Operation{Summary: "Upstream fetch rate and burstiness over the last hour (admin)"}, s.upstreamStatsHandler)
+	public.handle("GET", "/api/admin/diagnostics", &Operation{Summary: "Goroutine stacks, caches, breakers, queues, and watchers in one document (admin)"}, s.diagnosticsHandler)
+	public.handle("GET", "/api/admin/parsers", &Operation{Summary: "Parser versions, the layout markers pages matched, and failures (admin)"}, parsersHandler)
+	public.handle("GET", "/api/admin/refresher", &Operation{Summary: "Background refresh queue and dropped work (admin)"}, s.refresherHandler)
+	public.handle("GET", "/api/admin/mirror", &Operation{Summary: "Upstream mirroring to a staging server (admin)"}, s.getMirrorHandler)
+	public.handle("PUT", "/api/admin/mirror", &Operation{
+		Summary: "Mirror a fraction of upstream fetches to a staging server, or stop (admin)",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"fraction"},
+			Properties: map[string]*Schema{
+				"target":   {Type: "string"},
+				"fraction": {Type: "number"},
+			},
+		}),
+	}, s.putMirrorHandler)
+	public.handle("GET", "/api/admin/maintenance", &Operation{Summary: "Maintenance mode (admin)"}, getMaintenanceHandler)
+	public.handle("PUT", "/api/admin/maintenance", &Operation{
+		Summary: "Turn maintenance mode on or off (admin)",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"enabled"},
+			Properties: map[string]*Schema{
+				"enabled": {Type: "boolean"},
+				"message": {Type: "string"},
+			},
+		}),
+	}, putMaintenanceHandler)
+	public.handle("POST", "/api/admin/backfill", &Operation{
+		Summary: "Start a historical semester backfill with the request's SIX credentials (admin)",
+		RequestBody: jsonBody(&Schema{
+			Type:     "object",
+			Required: []string{"student_id"},
+			Properties: map[string]*Schema{
+				"student_id": studentIDParam.Schema,
+				"from":       relativeSemesterParam.Schema,
+				"semesters":  {Type: "integer"},
+				"force":      {Type: "boolean"},
+			},
+		}),
+	}, s.startBackfillHandler)
+	public.handle("GET", "/api/admin/backfill/{id}", &Operation{
+		Summary:    "Backfill job progress (admin)",
+		Parameters: []Parameter{idParam},
+	}, s.getBackfillHandler)
+	public.handle("POST", "/api/admin/crawl", &Operation{
+		Summary: "Crawl the catalog page of every faculty and program with the service account, or resume an unfinished crawl (admin)",
+		RequestBody: jsonBody(&Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"semester": relativeSemesterParam.Schema},
+		}),
+	}, s.startCrawlHandler)
+	public.handle("GET", "/api/admin/crawl", &Operation{Summary: "Progress of the latest catalog crawl (admin)"}, s.getCrawlHandler)
+	public.handle("GET", "/api/admin/catalog/export", &Operation{Summary: "Download the catalog cache as a signed archive (admin)"}, s.exportCatalogHandler)
+	public.handle("POST", "/api/admin/catalog/import", &Operation{Summary: "Load a signed catalog archive into the catalog cache (admin)"}, s.importCatalogHandler)
+	public.handle("POST", "/api/admin/dataset/export", &Operation{Summary: "Publish the cached catalog of a semester as a versioned dataset (admin)"}, s.exportDatasetHandler)
+	public.handle("GET", "/api/admin/dead-letters", &Operation{
+		Summary:    "Subscription deliveries that failed after every retry (admin)",
+		Parameters: []Parameter{{Name: "subscription_id", In: "query", Schema: &Schema{Type: "string"}}},
+	}, s.listDeadLettersHandler)
+	public.handle("POST", "/api/admin/redeliveries", &Operation{
+		Summary: "Redeliver every dead letter, or those of one subscription (admin)",
+		RequestBody: jsonBody(&Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"subscription_id": {Type: "string"}},
+		}),
+	}, s.redeliverAllHandler)
+	public.handle("POST", "/api/admin/dead-letters/{id}/redeliver", &Operation{Summary: "Redeliver one dead letter (admin)", Parameters: []Parameter{idParam}}, s.redeliverDeadLetterHandler)
+	public.handle("DELETE", "/api/admin/dead-letters/{id}", &Operation{Summary: "Discard a dead letter (admin)", Parameters: []Parameter{idParam}}, s.deleteDeadLetterHandler)
+	public.handle("GET", "/readyz", &Operation{
+		Summary:    "Readiness probe",
+		Parameters: []Parameter{{Name: "deep", In: "query", Description: "Scrape a real page (admin)", Schema: &Schema{Type: "boolean"}}},
+	}, s.readyzHandler)
+	public.handle("GET", "/openapi.json", &Operation{Summary: "This OpenAPI description"}, s.openAPIHandler)
+}
+
+// Fetches a schedule from the provider, scores it for anomalies, and stores
+// the result under its schedulePath. r supplies the credentials.
+func (s *Server) scrapeSchedule(r *http.Request, studentID, semester string, filters url.Values) ([]CourseClass, *Meta, error) {
+	page, meta, err := s.scrapeSchedulePage(r, studentID, semester, filters)
+	return page.Classes, meta, err
+}
+
+// Like scrapeSchedule, returning the whole page.
+func (s *Server) scrapeSchedulePage(r *http.Request, studentID, semester string, filters url.Values) (SchedulePage, *Meta, error) {
+	key := schedulePath(studentID, semester, filters)
+	page, err := s.provider.FetchSchedulePage(r, studentID, semester, filters)
+	if err != nil {
+		return SchedulePage{}, nil, err
+	}
+	meta := &Meta{FetchedAt: time.Now(), Source: page.Source, PagesTruncated: page.Truncated}
+	if page.Pages > 1 {
+		meta.PagesFetched = page.Pages
+	}
+	sortClasses(page.Classes)
+	if page.Sample != (scrapeSample{}) {
+		meta.Anomaly = s.anomalies.score(key, page.Sample)
+	}
+	s.updateSchedule(key, studentID, semester, page.Classes, meta.FetchedAt, meta.Anomaly)
+	return page, meta, nil
+}
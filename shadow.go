@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A new version of the schedule parser can be tried in shadow mode before it
+// is trusted. With SIX_SHADOW_PARSER set to its version, every schedule page
+// is parsed by both the current and the candidate parser. The classes are
+// compared and disagreements are logged and counted, but the current
+// parser's classes are served until the candidate has seen
+// SIX_SHADOW_MIN_PAGES pages and agreed on at least SIX_SHADOW_AGREEMENT of
+// them. From then on the candidate's classes are served, and the old parser
+// keeps running in its shadow.
+var (
+	shadowParserVersion = envInt("SIX_SHADOW_PARSER", 0)
+	shadowAgreement     = envFloat("SIX_SHADOW_AGREEMENT", 0.99)
+	shadowMinPages      = envInt("SIX_SHADOW_MIN_PAGES", 200)
+)
+
+type scheduleParser func(doc *goquery.Document) []CourseClass
+
+// Schedule parsers by version. A new version is added here next to the old
+// one, tried with SIX_SHADOW_PARSER, and replaces parseClasses once it has
+// been promoted.
+var scheduleParsers = map[int]scheduleParser{
+	parserVersion: parseClasses,
+}
+
+type ShadowStatus struct {
+	Current    int        `json:"current"`
+	Candidate  int        `json:"candidate"`
+	Promoted   bool       `json:"promoted"` // the candidate's classes are served
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+	Threshold  float64    `json:"threshold"`
+	MinPages   int        `json:"min_pages"`
+	Pages      int64      `json:"pages"`
+	Agreed     int64      `json:"agreed"`
+	Agreement  float64    `json:"agreement"` // agreed / pages
+	LastDiff   string     `json:"last_diff,omitempty"`
+	LastDiffAt *time.Time `json:"last_diff_at,omitempty"`
+}
+
+// Parses schedule pages with the current parser and, if a candidate is set,
+// with the candidate too.
+type shadowParser struct {
+	mu        sync.Mutex
+	current   scheduleParser
+	candidate scheduleParser // nil when shadow mode is off
+	st        ShadowStatus
+}
+
+// Returns a shadowParser trying candidate against current. A candidate of 0,
+// the current version, or one without a registered parser turns shadow mode
+// off.
+func newShadowParser(parsers map[int]scheduleParser, current, candidate int, threshold float64, minPages int) *shadowParser {
+	sp := &shadowParser{current: parsers[current], st: ShadowStatus{Current: current, Threshold: threshold, MinPages: max(minPages, 1)}}
+	if candidate == 0 || candidate == current {
+		return sp
+	}
+	if parsers[candidate] == nil {
+		log.Printf("shadow parser %d is not registered; shadow mode off", candidate)
+		return sp
+	}
+	sp.candidate = parsers[candidate]
+	sp.st.Candidate = candidate
+	return sp
+}
+
+var scheduleShadow = newShadowParser(scheduleParsers, parserVersion, shadowParserVersion, shadowAgreement, shadowMinPages)
+
+// Parses doc and returns the classes to serve.
+func (sp *shadowParser) parse(doc *goquery.Document, now time.Time) []CourseClass {
+	current := sp.current(doc)
+	if sp.candidate == nil {
+		return current
+	}
+	candidate := sp.candidate(doc)
+	diff := describeParseDiff(current, candidate)
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	st := &sp.st
+	st.Pages++
+	if diff == "" {
+		st.Agreed++
+	} else {
+		st.LastDiff, st.LastDiffAt = diff, &now
+		log.Printf("shadow parser %d disagrees with %d: %s", st.Candidate, st.Current, diff)
+	}
+	st.Agreement = float64(st.Agreed) / float64(st.Pages)
+	if !st.Promoted && st.Pages >= int64(st.MinPages) && st.Agreement >= st.Threshold {
+		st.Promoted, st.PromotedAt = true, &now
+		log.Printf("shadow parser %d promoted after %d pages, agreement %.4f", st.Candidate, st.Pages, st.Agreement)
+	}
+	if st.Promoted {
+		return candidate
+	}
+	return current
+}
+
+// The version of the parser whose classes are served.
+func (sp *shadowParser) servedVersion() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.st.Promoted {
+		return sp.st.Candidate
+	}
+	return sp.st.Current
+}
+
+// Returns the shadow status, or nil when shadow mode is off.
+func (sp *shadowParser) status() *ShadowStatus {
+	if sp.candidate == nil {
+		return nil
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	st := sp.st
+	return &st
+}
+
+// Summarizes how two parses of the same page differ, or returns "" if they
+// agree.
+func describeParseDiff(current, candidate []CourseClass) string {
+	a, _ := classesHash(current)
+	b, _ := classesHash(candidate)
+	if a == b {
+		return ""
+	}
+	added, removed, changed := diffSchedules(current, candidate)
+	parts := []string{fmt.Sprintf("classes %d vs %d", len(current), len(candidate))}
+	if len(added) > 0 {
+		parts = append(parts, "only candidate has "+classKey(added[0]))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "only current has "+classKey(removed[0]))
+	}
+	if len(changed) > 0 {
+		c := changed[0]
+		parts = append(parts, fmt.Sprintf("%s/%s differs in %s", c.Code, c.ClassNo, c.Changes[0].Field))
+	}
+	if len(parts) == 1 {
+		// Same classes and fields as far as diffSchedules looks, e.g. a
+		// different order of duplicates or a field it does not compare.
+		parts = append(parts, "other fields differ")
+	}
+	return strings.Join(parts, ", ")
+}
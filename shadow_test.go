@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestShadowParser_PromotesAfterAgreement(t *testing.T) {
+	renamed := false
+	candidate := func(doc *goquery.Document) []CourseClass {
+		classes := parseClasses(doc)
+		if renamed && len(classes) > 0 {
+			classes[0].Name = "Renamed"
+		}
+		return classes
+	}
+	parsers := map[int]scheduleParser{2: parseClasses, 3: candidate}
+	sp := newShadowParser(parsers, 2, 3, 0.75, 4)
+	doc := docFromHTML(testScheduleHTML)
+	now := time.Now()
+
+	renamed = true
+	if got := sp.parse(doc, now); got[0].Name == "Renamed" {
+		t.Fatal("candidate served before promotion")
+	}
+	st := sp.status()
+	if st.Pages != 1 || st.Agreed != 0 || !strings.Contains(st.LastDiff, "differs in name") {
+		t.Errorf("after a disagreement: %+v", st)
+	}
+
+	renamed = false
+	for range 2 {
+		sp.parse(doc, now)
+	}
+	if sp.status().Promoted || sp.servedVersion() != 2 {
+		t.Error("promoted before min pages")
+	}
+	// 3 of 4 agree, which meets the threshold.
+	sp.parse(doc, now)
+	if st := sp.status(); !st.Promoted || st.Agreement != 0.75 || sp.servedVersion() != 3 {
+		t.Errorf("not promoted: %+v", st)
+	}
+	renamed = true
+	if got := sp.parse(doc, now); got[0].Name != "Renamed" {
+		t.Error("current parser served after promotion")
+	}
+}
+
+func TestShadowParser_Off(t *testing.T) {
+	parsers := map[int]scheduleParser{2: parseClasses}
+	for _, candidate := range []int{0, 2, 9} {
+		sp := newShadowParser(parsers, 2, candidate, 0.99, 1)
+		if sp.status() != nil || len(sp.parse(docFromHTML(testScheduleHTML), time.Now())) == 0 || sp.servedVersion() != 2 {
+			t.Errorf("candidate %d: shadow mode not off", candidate)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/metrics"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Limits for admitting expensive requests, i.e. anything that has to go
+// upstream. Cache hits are never shed.
+var (
+	shedMaxInflight = envInt("SIX_SHED_MAX_INFLIGHT", 32)
+	shedMaxHeapMB   = envInt("SIX_SHED_MAX_HEAP_MB", 0) // 0 disables the memory check
+	shedRetryAfter  = envDuration("SIX_SHED_RETRY_AFTER", 30*time.Second)
+)
+
+var expensiveInflight atomic.Int64
+
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// Gates a request that needs SIX: it must be outside maintenance mode, within
+// the caller's budget, and admitted by the load shedder. On success the caller
+// must call release when done; otherwise an error response has been written.
+func admitUpstream(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	if !outsideMaintenance(w, r) || !withinBudget(w, r) {
+		return nil, false
+	}
+	return admitExpensive(w, r)
+}
+
+// Reserves a slot for an expensive request. When the server is under pressure
+// it writes a 503 with Retry-After and returns ok=false; otherwise the caller
+// must call release once the request is done.
+func admitExpensive(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	n := expensiveInflight.Add(1)
+	release = func() { expensiveInflight.Add(-1) }
+
+	reason := ""
+	if shedMaxInflight > 0 && n > int64(shedMaxInflight) {
+		reason = fmt.Sprintf("inflight=%d", n-1)
+	} else if heap := heapBytes(); shedMaxHeapMB > 0 && heap > uint64(shedMaxHeapMB)<<20 {
+		reason = fmt.Sprintf("heap=%dMB", heap>>20)
+	}
+	if reason == "" {
+		return release, true
+	}
+
+	release()
+	log.Printf("shedding request %s", reason)
+	w.Header().Set("Retry-After", strconv.Itoa(int(shedRetryAfter.Seconds())))
+	writeError(w, r, codeServerBusy)
+	return nil, false
+}
+
+// Returns the bytes currently occupied by live and unswept heap objects.
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAdmitExpensive_ShedsOverInflightLimit(t *testing.T) {
+	old := shedMaxInflight
+	shedMaxInflight = 1
+	t.Cleanup(func() { shedMaxInflight = old })
+
+	release, ok := admitExpensive(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !ok {
+		t.Fatal("expected first request to be admitted")
+	}
+
+	w := httptest.NewRecorder()
+	if _, ok := admitExpensive(w, httptest.NewRequest("GET", "/", nil)); ok {
+		t.Fatal("expected second request to be shed")
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want 503", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	release()
+	release2, ok := admitExpensive(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !ok {
+		t.Fatal("expected request to be admitted after release")
+	}
+	release2()
+}
+
+func TestAdmitExpensive_ShedsOverHeapLimit(t *testing.T) {
+	old := shedMaxHeapMB
+	shedMaxHeapMB = 1
+	t.Cleanup(func() { shedMaxHeapMB = old })
+
+	keep := make([]byte, 4<<20)
+	w := httptest.NewRecorder()
+	if _, ok := admitExpensive(w, httptest.NewRequest("GET", "/", nil)); ok {
+		t.Error("expected request to be shed over the heap limit")
+	}
+	_ = keep[len(keep)-1]
+}
+
+func TestScheduleHandler_ServesCacheHitWhileShedding(t *testing.T) {
+	srv := newTestServer("")
+	old := shedMaxInflight
+	shedMaxInflight = 1
+	expensiveInflight.Add(1)
+	t.Cleanup(func() {
+		shedMaxInflight = old
+		expensiveInflight.Add(-1)
+	})
+
+	srv.cache.set(schedulePath("123", "1945-1", url.Values{}), []CourseClass{{Code: "FI1210"}}, time.Now())
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.scheduleHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("cache hit: got status %d, want 200", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-2", nil)
+	addAuthCookies(req)
+	w = httptest.NewRecorder()
+	srv.scheduleHandler(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("cache miss: got status %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The default Provider, which scrapes the SIX web pages.
+type sixProvider struct {
+	baseURL   string
+	transport http.RoundTripper
+}
+
+func newSIXProvider(baseURL string, transport http.RoundTripper) *sixProvider {
+	return &sixProvider{baseURL: baseURL, transport: transport}
+}
+
+func (p *sixProvider) Name() string { return "six" }
+
+// Returns the absolute SIX URL for a path such as a schedule cache key.
+func (p *sixProvider) url(path string) string {
+	return p.baseURL + path
+}
+
+func (p *sixProvider) client() *http.Client {
+	return &http.Client{
+		Transport: p.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			detectSessionCookies(req.Response)
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// Reads the student ID from the links on /home, then the current semester
+// from where SIX redirects /app/mahasiswa:<id>/kelas to.
+func (p *sixProvider) FetchHomePage(r *http.Request) (Home, error) {
+	client := p.client()
+	doc, _, err := fetchDoc(client, p.url("/home"), r)
+	if err != nil {
+		return Home{}, err
+	}
+
+	var studentID string
+	doc.Find("a[href*='mahasiswa:']").EachWithBreak(func(_ int, a *goquery.Selection) bool {
+		href, _ := a.Attr("href")
+		if m := studentIDRe.FindStringSubmatch(href); len(m) > 1 {
+			studentID = m[1]
+			return false
+		}
+		return true
+	})
+	parsers.observe(parserHome, doc, studentID == "", time.Now())
+	if studentID == "" {
+		return Home{}, errStudentIDNotFound
+	}
+
+	req, err := newSIXRequest(p.url(fmt.Sprintf("/app/mahasiswa:%s/kelas", studentID)), r)
+	if err != nil {
+		return Home{}, err
+	}
+	resp, err := doUpstream(client, req)
+	if err != nil {
+		return Home{}, err
+	}
+	resp.Body.Close()
+
+	finalURL := resp.Request.URL.String()
+	m := semesterRe.FindStringSubmatch(finalURL)
+	if len(m) < 2 {
+		log.Printf("no semester in redirect url=%s", finalURL)
+		return Home{}, errSemesterNotFound
+	}
+	return Home{StudentID: studentID, Semester: m[1], Source: sourceScrape}, nil
+}
+
+func (p *sixProvider) FetchSchedulePage(r *http.Request, studentID, semester string, filters url.Values) (SchedulePage, error) {
+	pages, err := fetchPages(p.client(), p.url(schedulePath(studentID, semester, filters)), r)
+	if err != nil {
+		return SchedulePage{}, err
+	}
+	// Pages are merged by classKey, in case a class moved across a page
+	// boundary between fetches. The sample covers all pages, with the
+	// columns of the first.
+	var classes []CourseClass
+	var sample scrapeSample
+	seen := make(map[string]bool)
+	now := time.Now()
+	for i, doc := range pages.docs {
+		pageClasses := scheduleShadow.parse(doc, now)
+		pageSample := sampleScrape(doc, pageClasses, 0)
+		parsers.observe(parserSchedule, doc, pageSample.Rows > 0 && len(pageClasses) == 0, now)
+		if i == 0 {
+			sample.Columns = pageSample.Columns
+		}
+		sample.Rows += pageSample.Rows
+		for _, c := range pageClasses {
+			if !seen[classKey(c)] {
+				seen[classKey(c)] = true
+				classes = append(classes, c)
+			}
+		}
+	}
+	sample.Classes, sample.Bytes = len(classes), pages.bytes
+	return SchedulePage{
+		Classes:       classes,
+		Sample:        sample,
+		Source:        sourceScrape,
+		Pages:         len(pages.docs),
+		Truncated:     pages.truncated,
+		FilterOptions: catalogFilterOptions(pages.docs[0]),
+	}, nil
+}
+
+func (p *sixProvider) FetchTranscript(r *http.Request, studentID string) ([]TranscriptCourse, error) {
+	history, err := p.FetchGradeHistory(r, studentID)
+	return history.Courses, err
+}
+
+// The transcript page also has a table of the IP and IPK of each semester.
+func (p *sixProvider) FetchGradeHistory(r *http.Request, studentID string) (GradeHistory, error) {
+	pages, err := fetchPages(p.client(), p.url(transcriptPath(studentID)), r)
+	if err != nil {
+		return GradeHistory{}, err
+	}
+	var history GradeHistory
+	for _, doc := range pages.docs {
+		pageCourses := parseTranscript(doc)
+		parsers.observe(parserTranscript, doc, hasTableRows(doc) && len(pageCourses) == 0, time.Now())
+		history.Courses = append(history.Courses, pageCourses...)
+		history.Reported = append(history.Reported, parseReportedGPA(doc)...)
+	}
+	return history, nil
+}
+
+func (p *sixProvider) FetchCurriculum(r *http.Request, studentID string) ([]CurriculumCourse, error) {
+	pages, err := fetchPages(p.client(), p.url(curriculumPath(studentID)), r)
+	if err != nil {
+		return nil, err
+	}
+	var courses []CurriculumCourse
+	for _, doc := range pages.docs {
+		pageCourses := parseCurriculum(doc)
+		parsers.observe(parserCurriculum, doc, hasTableRows(doc) && len(pageCourses) == 0, time.Now())
+		courses = append(courses, pageCourses...)
+	}
+	return courses, nil
+}
+
+func (p *sixProvider) FetchExams(r *http.Request, studentID, semester string) ([]ExamEntry, error) {
+	pages, err := fetchPages(p.client(), p.url(examsPath(studentID, semester)), r)
+	if err != nil {
+		return nil, err
+	}
+	var exams []ExamEntry
+	for _, doc := range pages.docs {
+		pageExams := parseExams(doc)
+		parsers.observe(parserExams, doc, hasTableRows(doc) && len(pageExams) == 0, time.Now())
+		exams = append(exams, pageExams...)
+	}
+	return exams, nil
+}
+
+// The study plan may take several pages; the status and advisor come from
+// the first that shows them.
+func (p *sixProvider) FetchFRS(r *http.Request, studentID, semester string) (FRS, error) {
+	pages, err := fetchPages(p.client(), p.url(frsPath(studentID, semester)), r)
+	if err != nil {
+		return FRS{}, err
+	}
+	frs := FRS{Classes: []FRSClass{}}
+	for _, doc := range pages.docs {
+		page := parseFRS(doc)
+		parsers.observe(parserFRS, doc, hasTableRows(doc) && len(page.Classes) == 0 && page.Status == "", time.Now())
+		if frs.Status == "" {
+			frs.Status, frs.Approved = page.Status, page.Approved
+		}
+		if frs.Advisor == "" {
+			frs.Advisor = page.Advisor
+		}
+		frs.Classes = append(frs.Classes, page.Classes...)
+		frs.TotalSKS += page.TotalSKS
+	}
+	return frs, nil
+}
+
+func (p *sixProvider) FetchProfile(r *http.Request, studentID string) (Profile, error) {
+	doc, _, err := fetchDoc(p.client(), p.url(profilePath(studentID)), r)
+	if err != nil {
+		return Profile{}, err
+	}
+	profile := parseProfile(doc)
+	found := profile.NIM != "" || profile.Name != ""
+	parsers.observe(parserProfile, doc, !found, time.Now())
+	if !found {
+		return Profile{}, errProfileNotFound
+	}
+	return profile, nil
+}
+
+func (p *sixProvider) FetchCalendar(r *http.Request) ([]CalendarEntry, error) {
+	pages, err := fetchPages(p.client(), p.url(calendarPath), r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []CalendarEntry
+	for _, doc := range pages.docs {
+		pageEntries := parseCalendar(doc)
+		parsers.observe(parserCalendar, doc, hasTableRows(doc) && len(pageEntries) == 0, time.Now())
+		entries = append(entries, pageEntries...)
+	}
+	return entries, nil
+}
+
+func (p *sixProvider) FetchPayments(r *http.Request, studentID string) ([]Payment, error) {
+	pages, err := fetchPages(p.client(), p.url(paymentsPath(studentID)), r)
+	if err != nil {
+		return nil, err
+	}
+	var payments []Payment
+	for _, doc := range pages.docs {
+		pagePayments := parsePayments(doc)
+		parsers.observe(parserPayments, doc, hasTableRows(doc) && len(pagePayments) == 0, time.Now())
+		payments = append(payments, pagePayments...)
+	}
+	return payments, nil
+}
+
+func (p *sixProvider) FetchGrades(r *http.Request, studentID, semester string) ([]GradeEntry, error) {
+	pages, err := fetchPages(p.client(), p.url(gradesPath(studentID, semester)), r)
+	if err != nil {
+		return nil, err
+	}
+	var grades []GradeEntry
+	for _, doc := range pages.docs {
+		pageGrades := parseGrades(doc)
+		parsers.observe(parserGrades, doc, hasTableRows(doc) && len(pageGrades) == 0, time.Now())
+		grades = append(grades, pageGrades...)
+	}
+	return grades, nil
+}
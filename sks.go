@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The SKS a student may take in a semester depends on their IP in the last
+// regular semester. Rules are "minimum IP=maximum SKS" pairs; the first rule,
+// from the highest IP down, that the student's IP reaches applies. Students
+// with no graded semester yet get sksDefaultLimit.
+var (
+	sksRules        = parseSKSRules(envString("SIX_SKS_RULES", "3.00=24,2.50=22,2.00=20,0=18"))
+	sksDefaultLimit = envInt("SIX_SKS_DEFAULT_LIMIT", 20)
+)
+
+type sksRule struct {
+	minIP  float64
+	maxSKS int
+}
+
+// Parses "3.00=24,2.50=22,0=18" and sorts it from the highest IP down.
+// Invalid entries are logged and skipped.
+func parseSKSRules(spec string) []sksRule {
+	var rules []sksRule
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		ip, sks, _ := strings.Cut(entry, "=")
+		minIP, err1 := strconv.ParseFloat(strings.TrimSpace(ip), 64)
+		maxSKS, err2 := strconv.Atoi(strings.TrimSpace(sks))
+		if err1 != nil || err2 != nil || minIP < 0 || minIP > 4 || maxSKS <= 0 {
+			log.Printf("config: invalid SIX_SKS_RULES entry %q, skipping", entry)
+			continue
+		}
+		rules = append(rules, sksRule{minIP: minIP, maxSKS: maxSKS})
+	}
+	slices.SortFunc(rules, func(a, b sksRule) int { return cmp.Compare(b.minIP, a.minIP) })
+	return rules
+}
+
+// Returns the SKS limit for ip and the rule that set it. An IP below every
+// rule gets the lowest rule's limit.
+func sksLimit(rules []sksRule, ip float64) (int, string) {
+	for _, r := range rules {
+		if ip >= r.minIP {
+			return r.maxSKS, fmt.Sprintf("ip >= %.2f", r.minIP)
+		}
+	}
+	if len(rules) == 0 {
+		return sksDefaultLimit, "default"
+	}
+	last := rules[len(rules)-1]
+	return last.maxSKS, fmt.Sprintf("ip < %.2f", last.minIP)
+}
+
+// Returns the latest regular semester with graded courses and its IP. Short
+// semesters (YYYY-3) do not count. ok is false if nothing is graded yet.
+func lastSemesterIP(transcript []TranscriptCourse) (semester string, ip float64, ok bool) {
+	for _, c := range transcript {
+		if _, graded := gradePoints[c.Grade]; graded && !strings.HasSuffix(c.Semester, "-3") {
+			semester = max(semester, c.Semester)
+		}
+	}
+	if semester == "" {
+		return "", 0, false
+	}
+	var points float64
+	sks := 0
+	for _, c := range transcript {
+		if gp, graded := gradePoints[c.Grade]; graded && c.Semester == semester {
+			points += gp * float64(c.SKS)
+			sks += c.SKS
+		}
+	}
+	return semester, roundGPA(points, sks), true
+}
+
+type SKSCheck struct {
+	StudentID  string `json:"student_id"`
+	IPSemester string `json:"ip_semester,omitempty"`
+	// IP is nil when the student has no graded semester yet.
+	IP         *float64 `json:"ip"`
+	Limit      int      `json:"limit"`
+	Rule       string   `json:"rule"`
+	PlannedSKS int      `json:"planned_sks"`
+	Remaining  int      `json:"remaining"` // negative when over the limit
+	OverLimit  bool     `json:"over_limit"`
+	// Duplicates lists courses picked more than once. Each counts once.
+	Duplicates []string `json:"duplicates"`
+}
+
+type sksPlannedClass struct {
+	Code    string `json:"code"`
+	ClassNo string `json:"class_no"`
+	SKS     int    `json:"sks"`
+}
+
+type sksCheckRequest struct {
+	StudentID string            `json:"student_id"`
+	Classes   []sksPlannedClass `json:"classes"`
+}
+
+// Checks a planned selection against the limit set by transcript.
+func checkSKS(transcript []TranscriptCourse, classes []sksPlannedClass) SKSCheck {
+	check := SKSCheck{Duplicates: []string{}}
+	if semester, ip, ok := lastSemesterIP(transcript); ok {
+		check.IPSemester, check.IP = semester, &ip
+		check.Limit, check.Rule = sksLimit(sksRules, ip)
+	} else {
+		check.Limit, check.Rule = sksDefaultLimit, "no graded semester"
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range classes {
+		code := strings.ToUpper(strings.TrimSpace(c.Code))
+		if seen[code] {
+			if !slices.Contains(check.Duplicates, code) {
+				check.Duplicates = append(check.Duplicates, code)
+			}
+			continue
+		}
+		seen[code] = true
+		check.PlannedSKS += c.SKS
+	}
+	check.Remaining = check.Limit - check.PlannedSKS
+	check.OverLimit = check.Remaining < 0
+	return check
+}
+
+// POST /api/sks/check
+func (s *Server) sksCheckHandler(w http.ResponseWriter, r *http.Request) {
+	var body sksCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	for i, c := range body.Classes {
+		if c.SKS <= 0 {
+			writeError(w, r, codeInvalidRequest, fmt.Sprintf("classes[%d].sks must be positive", i))
+			return
+		}
+	}
+
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	transcript, err := s.provider.FetchTranscript(r, body.StudentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	check := checkSKS(transcript, body.Classes)
+	check.StudentID = body.StudentID
+	writeSuccess(w, check)
+}
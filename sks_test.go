@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSKSRules(t *testing.T) {
+	rules := parseSKSRules("2.50=22, 0=18,bad,3.00=24,5=30,2.00=0")
+	if fmt.Sprint(rules) != "[{3 24} {2.5 22} {0 18}]" {
+		t.Fatalf("rules = %v", rules)
+	}
+	for ip, want := range map[float64]string{3.2: "24 ip >= 3.00", 3: "24 ip >= 3.00", 2.99: "22 ip >= 2.50", 1.1: "18 ip >= 0.00"} {
+		if limit, rule := sksLimit(rules, ip); fmt.Sprint(limit, " ", rule) != want {
+			t.Errorf("sksLimit(%v) = %d %q, want %s", ip, limit, rule, want)
+		}
+	}
+	if limit, rule := sksLimit(parseSKSRules("2.00=20"), 1.5); limit != 20 || rule != "ip < 2.00" {
+		t.Errorf("below every rule: %d %q", limit, rule)
+	}
+}
+
+func TestCheckSKS(t *testing.T) {
+	transcript := []TranscriptCourse{
+		{Code: "MA1101", SKS: 4, Semester: "2024-2", Grade: "C"},
+		{Code: "IF2110", SKS: 4, Semester: "2025-1", Grade: "A"},
+		{Code: "IF2120", SKS: 3, Semester: "2025-1", Grade: "B"},
+		{Code: "KU1011", SKS: 2, Semester: "2025-3", Grade: "E"}, // short semester
+		{Code: "IF2211", SKS: 3, Semester: "2025-2"},             // in progress
+	}
+	check := checkSKS(transcript, []sksPlannedClass{
+		{Code: "IF2211", SKS: 3}, {Code: "if2211", ClassNo: "02", SKS: 3},
+		{Code: "IF2230", SKS: 3}, {Code: "IF2240", SKS: 12}, {Code: "IF2250", SKS: 8},
+	})
+	// IP of 2025-1: A (16) + B (9) over 7 SKS = 3.57.
+	if check.IPSemester != "2025-1" || check.IP == nil || *check.IP != 3.57 || check.Limit != 24 {
+		t.Errorf("check = %+v", check)
+	}
+	if check.PlannedSKS != 26 || check.Remaining != -2 || !check.OverLimit || fmt.Sprint(check.Duplicates) != "[IF2211]" {
+		t.Errorf("check = %+v, want 26 SKS planned, 2 over, IF2211 duplicated", check)
+	}
+
+	fresh := checkSKS(nil, []sksPlannedClass{{Code: "MA1101", SKS: 4}})
+	if fresh.IP != nil || fresh.Limit != sksDefaultLimit || fresh.OverLimit {
+		t.Errorf("new student = %+v", fresh)
+	}
+}
+
+func TestSKSCheckHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testTranscriptHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/sks/check", strings.NewReader(body))
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"student_id":"123","classes":[{"code":"IF2211","class_no":"01","sks":3},{"code":"IF2230","sks":18}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	// 2023-1: A (16) + E (0) over 8 SKS = 2.00, which allows 20 SKS.
+	if c := decodeData[SKSCheck](t, w); c.Limit != 20 || c.PlannedSKS != 21 || !c.OverLimit {
+		t.Errorf("check = %+v", c)
+	}
+
+	if w := post(`{"student_id":"123","classes":[{"code":"IF2211","sks":0}]}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("zero sks: got status %d, want 422", w.Code)
+	}
+	if w := post(`{"student_id":"123"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing classes: got status %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// On graceful shutdown the schedule and catalog caches are spilled to
+// {SIX_DATA_DIR}/cache-spill.json, and the next start loads whatever has not
+// expired by then. A planned restart during a busy period then picks up where
+// the old process left off instead of re-scraping every schedule at once.
+const (
+	spillFile    = "cache-spill.json"
+	spillVersion = 1
+)
+
+type cacheSpill struct {
+	Version       int            `json:"version"`
+	SchemaVersion int            `json:"schema_version"` // see classSchemaVersion
+	SavedAt       time.Time      `json:"saved_at"`
+	Schedules     []spilledEntry `json:"schedules"`
+	Catalog       []spilledEntry `json:"catalog"`
+}
+
+type spilledEntry struct {
+	Key       string        `json:"key"`
+	FetchedAt time.Time     `json:"fetched_at"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Anomaly   *Anomaly      `json:"anomaly,omitempty"`
+	Classes   []CourseClass `json:"classes"`
+}
+
+// Returns the entries that have not expired at now.
+func (c *scheduleCache) spillEntries(now time.Time) []spilledEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries := []spilledEntry{}
+	for key, e := range c.entries {
+		if now.Before(e.expiresAt) {
+			entries = append(entries, spilledEntry{Key: key, FetchedAt: e.fetchedAt, ExpiresAt: e.expiresAt, Anomaly: e.anomaly, Classes: e.data})
+		}
+	}
+	return entries
+}
+
+// Stores a spilled entry, keeping its expiry but never past one TTL (plus
+// jitter) after it was fetched, in case the TTL was shortened across the
+// restart. Returns false if it has already expired.
+func (c *scheduleCache) reload(e spilledEntry, now time.Time) bool {
+	expiresAt := e.ExpiresAt
+	if limit := e.FetchedAt.Add(time.Duration(float64(c.ttl) * (1 + cacheJitter))); limit.Before(expiresAt) {
+		expiresAt = limit
+	}
+	if !now.Before(expiresAt) {
+		return false
+	}
+	entry := cacheEntry{data: e.Classes, fetchedAt: e.FetchedAt, expiresAt: expiresAt, anomaly: e.Anomaly}
+	c.mu.Lock()
+	c.entries[e.Key] = entry
+	c.mu.Unlock()
+	if c.onStore != nil {
+		c.onStore(e.Key, entry)
+	}
+	return true
+}
+
+// Writes the unexpired cache entries to the data dir. Without one there is
+// nowhere to spill to and nothing is written.
+func (s *Server) spillCache() error {
+	if s.cfg.DataDir == "" {
+		return nil
+	}
+	now := time.Now()
+	spill := cacheSpill{Version: spillVersion, SchemaVersion: classSchemaVersion, SavedAt: now, Schedules: s.cache.spillEntries(now), Catalog: s.catalog.spillEntries(now)}
+	data, err := json.Marshal(spill)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.cfg.DataDir, spillFile), data); err != nil {
+		return err
+	}
+	log.Printf("cache spilled schedules=%d catalog=%d", len(spill.Schedules), len(spill.Catalog))
+	return nil
+}
+
+// Loads the cache spilled by the previous process, skipping entries that have
+// expired since, and removes the spill so it is only ever loaded once.
+func (s *Server) loadCacheSpill() {
+	if s.cfg.DataDir == "" {
+		return
+	}
+	path := filepath.Join(s.cfg.DataDir, spillFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Printf("cache spill not loaded: %v", err)
+		return
+	}
+	defer os.Remove(path)
+	var spill cacheSpill
+	err = unmarshalVersioned(data, &spill, func(blob map[string]any) []any {
+		return append(nestedLists(blob, "schedules", "classes"), nestedLists(blob, "catalog", "classes")...)
+	})
+	if err != nil || spill.Version != spillVersion {
+		log.Printf("cache spill not loaded: unreadable or version mismatch: %v", err)
+		return
+	}
+	now := time.Now()
+	schedules, catalog := 0, 0
+	for _, e := range spill.Schedules {
+		if s.cache.reload(e, now) {
+			schedules++
+		}
+	}
+	for _, e := range spill.Catalog {
+		if s.catalog.reload(e, now) {
+			catalog++
+		}
+	}
+	log.Printf("cache spill loaded schedules=%d/%d catalog=%d/%d saved_at=%s",
+		schedules, len(spill.Schedules), catalog, len(spill.Catalog), spill.SavedAt.Format(time.RFC3339))
+}
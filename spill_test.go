@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheSpill_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{BaseURL: "http://six.invalid", CacheTTL: time.Hour, CatalogTTL: time.Hour, DataDir: dir}
+	old := NewServer(cfg)
+	now := time.Now()
+	old.cache.set("fresh", []CourseClass{{Code: "IF2211", ClassNo: "01"}}, now)
+	old.cache.entries["expired"] = cacheEntry{data: []CourseClass{{Code: "IF2230"}}, fetchedAt: now.Add(-2 * time.Hour), expiresAt: now.Add(-time.Hour)}
+	old.cache.entries["soon"] = cacheEntry{fetchedAt: now, expiresAt: now.Add(50 * time.Millisecond), anomaly: &Anomaly{Score: 1, Reasons: []string{"shrunk"}}}
+	old.catalog.set("2024-2?prodi=135", []CourseClass{{Code: "IF2211", ClassNo: "01", Lecturers: []string{"Dr. A"}}}, now)
+	if err := old.spillCache(); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := old.cache.get("fresh")
+	time.Sleep(100 * time.Millisecond) // "soon" expires while the server is down
+
+	srv := NewServer(cfg)
+	srv.loadCacheSpill()
+	got, ok := srv.cache.get("fresh")
+	if !ok || len(got.data) != 1 || !got.expiresAt.Equal(want.expiresAt) || !got.fetchedAt.Equal(want.fetchedAt) {
+		t.Errorf("fresh = %+v, %v; want expiry %s kept", got, ok, want.expiresAt)
+	}
+	for _, key := range []string{"expired", "soon"} {
+		if _, ok := srv.cache.peek(key); ok {
+			t.Errorf("%s was reloaded", key)
+		}
+	}
+	if _, ok := srv.catalog.get("2024-2?prodi=135"); !ok {
+		t.Error("catalog entry not reloaded")
+	}
+	if hits := srv.search.search("Dr. A", "2024-2", 10, now); len(hits) == 0 {
+		t.Error("reloaded catalog entry not indexed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, spillFile)); !os.IsNotExist(err) {
+		t.Errorf("spill file left behind: %v", err)
+	}
+}
+
+func TestCacheReload_CapsExpiryAtTTL(t *testing.T) {
+	c := newScheduleCache(time.Minute)
+	now := time.Now()
+	e := spilledEntry{Key: "k", FetchedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if !c.reload(e, now) {
+		t.Fatal("entry not reloaded")
+	}
+	got, _ := c.peek("k")
+	if limit := now.Add(time.Duration(float64(time.Minute) * (1 + cacheJitter))); got.expiresAt.After(limit) {
+		t.Errorf("expiresAt = %s, want at most %s", got.expiresAt, limit)
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Response fields are snake_case throughout. Clients that prefer camelCase
+// ask with naming=camel or the X-Field-Naming header. Clients that want a
+// stricter shape ask with compat=strict: null and empty string values are
+// omitted, except that array fields are always present, as [] if empty.
+// withResponseStyle applies both to JSON responses on the way out, so every
+// response type keeps a single set of struct tags. It also applies the
+// redaction profile of the request's API key (see redaction.go).
+const fieldNamingHeader = "X-Field-Naming"
+
+type responseStyle struct {
+	camel  bool
+	strict bool
+	redact *redactionProfile
+}
+
+func withResponseStyle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", fieldNamingHeader)
+		var style responseStyle
+		switch cmp.Or(r.URL.Query().Get("naming"), r.Header.Get(fieldNamingHeader)) {
+		case "", "snake":
+		case "camel":
+			style.camel = true
+		default:
+			writeError(w, r, codeInvalidRequest, "naming must be snake or camel")
+			return
+		}
+		switch r.URL.Query().Get("compat") {
+		case "", "default":
+		case "strict":
+			style.strict = true
+		default:
+			writeError(w, r, codeInvalidRequest, "compat must be default or strict")
+			return
+		}
+		// An invalid key gets no profile here; requireAPIKey rejects it.
+		if k := lookupAPIKey(r.Header.Get("X-API-Key")); k != nil && k.profile != nil {
+			w.Header().Add("Vary", "X-API-Key")
+			style.redact = k.profile
+		}
+		if style == (responseStyle{}) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &styleWriter{ResponseWriter: w, r: r, style: style, status: http.StatusOK, arrays: make(map[string]bool)}
+		next.ServeHTTP(sw, r)
+		sw.flush()
+	})
+}
+
+// Tells the styleWriter under w, if any, that v is about to be encoded as
+// the response body. compat=strict takes its array fields from v's type.
+// Every JSON writer calls it before encoding.
+func noteEncoded(w http.ResponseWriter, v any) {
+	for {
+		if sw, ok := w.(*styleWriter); ok {
+			if sw.style.strict {
+				maps.Copy(sw.arrays, jsonArrayFields(v))
+			}
+			return
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = u.Unwrap()
+	}
+}
+
+// Array fields by type, for types without interface fields, whose array
+// fields do not depend on the value.
+var typeArrayFields sync.Map // reflect.Type -> map[string]bool
+
+// Returns the JSON names of the slice fields of v and of the structs in it.
+// Interface fields, such as APIResponse.Data, are followed to the values
+// they hold, and count as slice fields when they hold one.
+func jsonArrayFields(v any) map[string]bool {
+	names := make(map[string]bool)
+	addJSONArrayFields(names, reflect.ValueOf(v))
+	return names
+}
+
+func addJSONArrayFields(names map[string]bool, v reflect.Value) {
+	if !v.IsValid() {
+		return
+	}
+	if fields, static := staticArrayFields(v.Type()); static {
+		maps.Copy(names, fields)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		addJSONArrayFields(names, v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			addJSONArrayFields(names, v.Index(i))
+		}
+	case reflect.Map:
+		for it := v.MapRange(); it.Next(); {
+			addJSONArrayFields(names, it.Value())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, ok := jsonFieldName(f)
+			if !ok {
+				continue
+			}
+			fv := v.Field(i)
+			if name != "" && (isJSONArray(f.Type) || f.Type.Kind() == reflect.Interface && !fv.IsNil() && isJSONArray(fv.Elem().Type())) {
+				names[name] = true
+			}
+			addJSONArrayFields(names, fv)
+		}
+	}
+}
+
+// Returns the array fields of t, and whether they are the same for every
+// value of t.
+func staticArrayFields(t reflect.Type) (map[string]bool, bool) {
+	if cached, ok := typeArrayFields.Load(t); ok {
+		fields, _ := cached.(map[string]bool)
+		return fields, fields != nil
+	}
+	names := make(map[string]bool)
+	static := true
+	seen := make(map[reflect.Type]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Interface {
+			static = false
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return
+		}
+		seen[t] = true
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, ok := jsonFieldName(f)
+			if !ok {
+				continue
+			}
+			if name != "" && isJSONArray(f.Type) {
+				names[name] = true
+			}
+			walk(f.Type)
+		}
+	}
+	walk(t)
+	if !static {
+		// Stored as a nil map, so the next lookup skips the walk.
+		typeArrayFields.Store(t, map[string]bool(nil))
+		return nil, false
+	}
+	typeArrayFields.Store(t, names)
+	return names, true
+}
+
+// Returns the JSON name of f, "" for an embedded struct whose fields are
+// promoted, and false for a field encoding/json skips.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch {
+	case name == "-":
+		return "", false
+	case f.Anonymous && name == "":
+		return "", true
+	case !f.IsExported():
+		return "", false
+	}
+	return cmp.Or(name, f.Name), true
+}
+
+// Whether t encodes as a JSON array, or as null when nil.
+func isJSONArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// Buffers a JSON response to restyle it in flush. Other responses
+// pass through untouched, except that a redaction profile refuses them,
+// since only JSON can be masked field by field.
+type styleWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	style   responseStyle
+	status  int
+	decided bool // whether the response is known to be JSON or not
+	json    bool
+	refused bool // a non-JSON response under a redaction profile
+	buf     bytes.Buffer
+	arrays  map[string]bool // array fields of the values noted by noteEncoded
+}
+
+func (cw *styleWriter) decide() {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+	mediaType, _, _ := mime.ParseMediaType(cw.Header().Get("Content-Type"))
+	cw.json = mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	cw.refused = !cw.json && cw.style.redact != nil && cw.status >= 200 && cw.status < 300
+	if !cw.json && !cw.refused {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+func (cw *styleWriter) WriteHeader(code int) {
+	if cw.decided {
+		return
+	}
+	cw.status = code
+	if code == http.StatusNotModified || code < 200 {
+		cw.decide()
+	}
+}
+
+func (cw *styleWriter) Write(b []byte) (int, error) {
+	cw.decide()
+	if cw.json {
+		return cw.buf.Write(b)
+	}
+	if cw.refused {
+		return len(b), nil
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *styleWriter) flush() {
+	cw.decide()
+	if cw.refused {
+		for _, h := range []string{"Content-Disposition", "Content-Length", "ETag", "Last-Modified"} {
+			cw.Header().Del(h)
+		}
+		writeError(cw.ResponseWriter, cw.r, codeRedactedFormat, cw.style.redact.name)
+		return
+	}
+	if !cw.json {
+		return
+	}
+	body := cw.buf.Bytes()
+	if len(body) == 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+		return
+	}
+	converted, err := restyleJSON(body, cw.style, cw.arrays)
+	switch {
+	case err == nil:
+		body = converted
+	case cw.style.redact != nil:
+		// Unmasked data must not go out as is.
+		cw.Header().Del("Content-Length")
+		writeInternalError(cw.ResponseWriter, cw.r)
+		return
+	}
+	cw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	cw.ResponseWriter.WriteHeader(cw.status)
+	cw.ResponseWriter.Write(body)
+}
+
+// Returns data in style. Values that are kept, including numbers, are
+// written as they are. Under compat=strict, null values of the fields named
+// in arrays become [].
+func restyleJSON(data []byte, style responseStyle, arrays map[string]bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	type container struct {
+		object  bool
+		members int
+		// lecturers is set for a list of lecturers being masked and the
+		// objects directly in it.
+		lecturers bool
+	}
+	var stack []container
+	var out bytes.Buffer
+	// Writes tok, the value of key ("" in an array), masking it if the
+	// profile says so.
+	write := func(key string, tok json.Token) {
+		inLecturers := len(stack) > 0 && stack[len(stack)-1].lecturers
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			stack = append(stack, container{
+				object:    v == '{',
+				lecturers: style.redact.masksLecturers(key) || (inLecturers && key == "" && v == '{'),
+			})
+		case string:
+			b, _ := json.Marshal(style.redact.mask(key, inLecturers, v))
+			out.Write(b)
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(byte(d))
+			continue
+		}
+		if len(stack) == 0 {
+			if out.Len() > 0 {
+				out.WriteByte('\n')
+			}
+			write("", tok)
+			continue
+		}
+
+		c := &stack[len(stack)-1]
+		if !c.object {
+			if c.members > 0 {
+				out.WriteByte(',')
+			}
+			c.members++
+			write("", tok)
+			continue
+		}
+		key, _ := tok.(string)
+		value, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		emptyArray := false
+		if style.strict {
+			switch v := value.(type) {
+			case nil:
+				if !arrays[key] {
+					continue
+				}
+				emptyArray = true
+			case string:
+				if v == "" {
+					continue
+				}
+			}
+		}
+		if c.members > 0 {
+			out.WriteByte(',')
+		}
+		c.members++
+		name := key
+		if style.camel {
+			name = camelCase(key)
+		}
+		b, _ := json.Marshal(name)
+		out.Write(b)
+		out.WriteByte(':')
+		if emptyArray {
+			out.WriteString("[]")
+		} else {
+			write(key, value)
+		}
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// Converts a snake_case name to camelCase, e.g. class_no to classNo.
+func camelCase(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	parts := strings.Split(s, "_")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, p := range parts[1:] {
+		if p != "" {
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRestyleJSON(t *testing.T) {
+	in := `{"student_id":"123","data":[{"class_no":"01","quota":1e2,"lecturers":["a_b"],"extra":null}],"meta":{"fetched_at":"x","cached":true},"empty_obj":{},"empty_list":[]}` + "\n"
+	want := `{"studentId":"123","data":[{"classNo":"01","quota":1e2,"lecturers":["a_b"],"extra":null}],"meta":{"fetchedAt":"x","cached":true},"emptyObj":{},"emptyList":[]}` + "\n"
+	got, err := restyleJSON([]byte(in), responseStyle{camel: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	in = `{"data":[{"code":"IF2211","notes":"","lecturers":null,"schedules":[{"day":"Senin","room":""}],"extra":null,"enrolled":0}],"meta":{"semester":""}}` + "\n"
+	want = `{"data":[{"code":"IF2211","lecturers":[],"schedules":[{"day":"Senin"}],"enrolled":0}],"meta":{}}` + "\n"
+	if got, _ := restyleJSON([]byte(in), responseStyle{strict: true}, map[string]bool{"lecturers": true, "schedules": true}); string(got) != want {
+		t.Errorf("strict:\ngot  %s\nwant %s", got, want)
+	}
+
+	for in, want := range map[string]string{"class_no": "classNo", "ip_semester": "ipSemester", "sha256": "sha256", "a__b_": "aB"} {
+		if got := camelCase(in); got != want {
+			t.Errorf("camelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResponseStyle(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	get := func(query, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1"+query, nil)
+		addAuthCookies(req)
+		if header != "" {
+			req.Header.Set(fieldNamingHeader, header)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	if body := get("", "").Body.String(); !strings.Contains(body, `"class_no"`) || !strings.Contains(body, `"fetched_at"`) {
+		t.Errorf("default naming is not snake_case: %s", body)
+	}
+	for _, w := range []*httptest.ResponseRecorder{get("&naming=camel", ""), get("", "camel")} {
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, `"classNo"`) || !strings.Contains(body, `"fetchedAt"`) || strings.Contains(body, `"class_no"`) {
+			t.Errorf("camel naming: status %d, body %s", w.Code, body)
+		}
+		if w.Header().Get("Content-Length") != fmt.Sprint(len(body)) {
+			t.Errorf("Content-Length %s, body is %d bytes", w.Header().Get("Content-Length"), len(body))
+		}
+	}
+	if body := get("&compat=strict&naming=camel", "").Body.String(); !strings.Contains(body, `"lecturers":[`) || strings.Contains(body, `null`) || strings.Contains(body, `""`) || !strings.Contains(body, `"classNo"`) {
+		t.Errorf("strict camel: %s", body)
+	}
+	if w := get("&compat=loose", ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown compat: status %d, want 422", w.Code)
+	}
+	if w := get("&naming=kebab", ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown naming: status %d, want 422", w.Code)
+	}
+	if w := get("&naming=camel&format=ics", ""); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"success":false`) {
+		t.Errorf("camel error: status %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestJSONArrayFields(t *testing.T) {
+	resp := APIResponse{Data: []CourseClass{{}}}
+	got := jsonArrayFields(resp)
+	for _, name := range []string{"data", "lecturers", "schedules", "details"} {
+		if !got[name] {
+			t.Errorf("%s is not an array field of %T", name, resp.Data)
+		}
+	}
+	for _, name := range []string{"notes", "code", "semesters"} {
+		if got[name] {
+			t.Errorf("%s should not be an array field of %T", name, resp.Data)
+		}
+	}
+	// Data is followed to the value it holds.
+	if got := jsonArrayFields(APIResponse{Data: GPAReport{}}); !got["semesters"] || got["data"] || got["lecturers"] {
+		t.Errorf("GPA report array fields = %v", got)
+	}
+}
+
+// Every GET route under compat=strict, once against SIX pages with data
+// and once against empty ones: nothing is null, and a field that holds an
+// array with data is [] where it would otherwise be null.
+func TestStrictCompat_AllRoutes(t *testing.T) {
+	full := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages := map[string]string{
+			"/kelas/jadwal/ujian":  testExamsHTML,
+			"/registrasi/frs":      testFRSHTML,
+			"/akademik/nilai":      testGradesHTML,
+			"/akademik/transkrip":  testTranscriptHTML,
+			"/akademik/kurikulum":  testCurriculumHTML,
+			"/keuangan/pembayaran": testPaymentsHTML,
+			"/profil":              testProfileHTML,
+			calendarPath:           testCalendarHTML,
+		}
+		for suffix, page := range pages {
+			if strings.HasSuffix(r.URL.Path, suffix) {
+				fmt.Fprint(w, page)
+				return
+			}
+		}
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer full.Close()
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><table></table></body></html>")
+	}))
+	defer empty.Close()
+	oldToken := adminToken
+	adminToken = "secret"
+	t.Cleanup(func() { adminToken = oldToken })
+	fullSrv, emptySrv := newTestServer(full.URL), newTestServer(empty.URL)
+
+	values := map[string]string{
+		"student_id": "123", "semester": "2025-2", "fakultas": "STEI", "q": "IF",
+		"code": "IF2211", "class_no": "01", "hash": strings.Repeat("0", 32), "provider": "line",
+	}
+	// Returns the decoded JSON response of srv to GET target, or nil for a
+	// failed or non-JSON response.
+	get := func(srv *Server, target string, query url.Values) any {
+		req := httptest.NewRequest("GET", target+"?"+query.Encode(), nil)
+		addAuthCookies(req)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), "json") {
+			return nil
+		}
+		var body any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("GET %s: %v", target, err)
+		}
+		return body
+	}
+	checked := 0
+	for path, ops := range fullSrv.spec.Paths {
+		op := ops["get"]
+		if op == nil {
+			continue
+		}
+		query := url.Values{}
+		target := path
+		for _, p := range op.Parameters {
+			v := cmp.Or(values[p.Name], "x")
+			switch {
+			case p.In == "path":
+				target = strings.ReplaceAll(target, "{"+p.Name+"}", v)
+			case p.Required:
+				query.Set(p.Name, v)
+			}
+		}
+		arrays := make(map[string]bool)
+		jsonArrayPaths(get(fullSrv, target, query), "", arrays)
+		for _, srv := range []*Server{fullSrv, emptySrv} {
+			query.Del("compat")
+			plain := get(srv, target, query)
+			query.Set("compat", "strict")
+			strict := get(srv, target, query)
+			if plain == nil || strict == nil {
+				continue
+			}
+			checked++
+			if where := findNull(strict, ""); where != "" {
+				t.Errorf("GET %s: null at %s", target, where)
+			}
+			for _, p := range missingArrays(plain, strict, "", arrays) {
+				t.Errorf("GET %s: %s is missing, want []", target, p)
+			}
+		}
+	}
+	if checked < 40 {
+		t.Errorf("only %d responses checked", checked)
+	}
+}
+
+// Returns the path of the first null in v, or "".
+func findNull(v any, path string) string {
+	switch v := v.(type) {
+	case nil:
+		return cmp.Or(path, ".")
+	case map[string]any:
+		for k, e := range v {
+			if where := findNull(e, path+"."+k); where != "" {
+				return where
+			}
+		}
+	case []any:
+		for i, e := range v {
+			if where := findNull(e, fmt.Sprintf("%s[%d]", path, i)); where != "" {
+				return where
+			}
+		}
+	}
+	return ""
+}
+
+// Adds the paths of the arrays in v to paths, with "[]" for any index, as
+// in ".data[].lecturers".
+func jsonArrayPaths(v any, path string, paths map[string]bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if _, ok := e.([]any); ok {
+				paths[path+"."+k] = true
+			}
+			jsonArrayPaths(e, path+"."+k, paths)
+		}
+	case []any:
+		for _, e := range v {
+			jsonArrayPaths(e, path+"[]", paths)
+		}
+	}
+}
+
+// Returns the paths in arrays that are null in plain and not [] in strict,
+// the same response under compat=strict.
+func missingArrays(plain, strict any, path string, arrays map[string]bool) []string {
+	var missing []string
+	switch p := plain.(type) {
+	case map[string]any:
+		s, _ := strict.(map[string]any)
+		for k, e := range p {
+			if e == nil && arrays[path+"."+k] {
+				if a, ok := s[k].([]any); !ok || len(a) > 0 {
+					missing = append(missing, path+"."+k)
+				}
+				continue
+			}
+			missing = append(missing, missingArrays(e, s[k], path+"."+k, arrays)...)
+		}
+	case []any:
+		s, _ := strict.([]any)
+		for i, e := range p {
+			if i < len(s) {
+				missing = append(missing, missingArrays(e, s[i], path+"[]", arrays)...)
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+type createSubscriptionRequest struct {
+	URL        string           `json:"url"`
+	Secret     string           `json:"secret"`
+	StudentID  string           `json:"student_id"`
+	Semester   string           `json:"semester"`
+	Filters    url.Values       `json:"filters"`
+	QuietHours string           `json:"quiet_hours"`
+	Deadline   *time.Time       `json:"deadline"`
+	Channels   []channelRequest `json:"channels"`
+}
+
+// Fields a PATCH may change. Nil fields are left alone.
+type updateSubscriptionRequest struct {
+	URL    *string `json:"url"`
+	Secret *string `json:"secret"`
+	Paused *bool   `json:"paused"`
+	// An empty QuietHours turns quiet hours off. A new Deadline gets its
+	// own summary.
+	QuietHours *string    `json:"quiet_hours"`
+	Deadline   *time.Time `json:"deadline"`
+	// Channels replaces every channel, and their delivery status. An empty
+	// URL removes the url, if channels remain.
+	Channels *[]channelRequest `json:"channels"`
+	// ExpiresAt moves the expiry, and the notice is sent again before it.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Parses the quiet hours and checks the deadline of a request, writing an
+// error and returning false if either is invalid.
+func parseDeliveryWindow(w http.ResponseWriter, r *http.Request, quietSpec string, deadline *time.Time) (*quietHours, bool) {
+	var quiet *quietHours
+	if quietSpec != "" {
+		var err error
+		if quiet, err = parseQuietHours(quietSpec); err != nil {
+			writeError(w, r, codeInvalidRequest, err.Error())
+			return nil, false
+		}
+	}
+	if deadline != nil && !deadline.After(time.Now()) {
+		writeError(w, r, codeInvalidRequest, "deadline must be in the future")
+		return nil, false
+	}
+	return quiet, true
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Returns the caller's API key, or "" when keys are disabled.
+func subscriptionOwner(r *http.Request) string {
+	if k := apiKeyFrom(r.Context()); k != nil {
+		return k.key
+	}
+	return ""
+}
+
+// Returns a copy of sub that is safe to serialize: no secret, and error
+// history not shared with the live subscription.
+func (sub *Subscription) view() Subscription {
+	v := *sub
+	v.Secret = ""
+	v.Errors = slices.Clone(sub.Errors)
+	if sub.LastDelivery != nil {
+		last := *sub.LastDelivery
+		v.LastDelivery = &last
+	}
+	v.Channels = nil
+	for _, ch := range sub.Channels {
+		v.Channels = append(v.Channels, ch.view())
+	}
+	return v
+}
+
+// POST /api/subscriptions
+func (s *Server) createSubscription(w http.ResponseWriter, r *http.Request) {
+	var body createSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	// The url may be left out when there are channels.
+	if (body.URL != "" || len(body.Channels) == 0) && !validWebhookURL(body.URL) {
+		writeError(w, r, codeInvalidWebhookURL)
+		return
+	}
+	quiet, ok := parseDeliveryWindow(w, r, body.QuietHours, body.Deadline)
+	if !ok {
+		return
+	}
+	channels, err := parseChannels(body.Channels)
+	if err != nil {
+		writeError(w, r, codeInvalidRequest, err.Error())
+		return
+	}
+	if body.Secret == "" {
+		body.Secret = randomHex(32)
+	}
+
+	sub := &Subscription{
+		ID:         randomHex(8),
+		URL:        body.URL,
+		Secret:     body.Secret,
+		StudentID:  body.StudentID,
+		Semester:   body.Semester,
+		Filters:    scheduleFilters(body.Filters),
+		QuietHours: body.QuietHours,
+		Deadline:   body.Deadline,
+		Channels:   channels,
+		ExpiresAt:  s.semesterExpiry(body.Semester),
+		CreatedAt:  time.Now(),
+		owner:      subscriptionOwner(r),
+		key:        schedulePath(body.StudentID, body.Semester, body.Filters),
+		quiet:      quiet,
+	}
+
+	subscriptionsMu.Lock()
+	subscriptions[sub.ID] = sub
+	subscriptionsMu.Unlock()
+
+	log.Printf("subscription created id=%s student_id=%s semester=%s", sub.ID, sub.StudentID, sub.Semester)
+	writeCreated(w, sub)
+}
+
+// GET /api/subscriptions
+func listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	owner := subscriptionOwner(r)
+	subscriptionsMu.Lock()
+	list := []Subscription{}
+	for _, sub := range subscriptions {
+		if sub.owner == owner {
+			list = append(list, sub.view())
+		}
+	}
+	subscriptionsMu.Unlock()
+
+	slices.SortFunc(list, func(a, b Subscription) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	writeSuccess(w, list)
+}
+
+// Returns the subscription with id if the caller owns it. Callers must hold subscriptionsMu.
+func ownedSubscriptionLocked(r *http.Request, id string) (*Subscription, bool) {
+	sub, ok := subscriptions[id]
+	if !ok || sub.owner != subscriptionOwner(r) {
+		return nil, false
+	}
+	return sub, true
+}
+
+// GET /api/subscriptions/{id}
+func getSubscription(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	subscriptionsMu.Lock()
+	sub, ok := ownedSubscriptionLocked(r, id)
+	var v Subscription
+	if ok {
+		v = sub.view()
+	}
+	subscriptionsMu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeSubscriptionNotFound)
+		return
+	}
+	writeSuccess(w, v)
+}
+
+// PATCH /api/subscriptions/{id}
+func updateSubscription(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var body updateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if body.URL != nil && *body.URL != "" && !validWebhookURL(*body.URL) {
+		writeError(w, r, codeInvalidWebhookURL)
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		writeError(w, r, codeInvalidRequest, "expires_at must be in the future")
+		return
+	}
+	var channels []*Channel
+	if body.Channels != nil {
+		var err error
+		if channels, err = parseChannels(*body.Channels); err != nil {
+			writeError(w, r, codeInvalidRequest, err.Error())
+			return
+		}
+	}
+	var quietSpec string
+	if body.QuietHours != nil {
+		quietSpec = *body.QuietHours
+	}
+	quiet, valid := parseDeliveryWindow(w, r, quietSpec, body.Deadline)
+	if !valid {
+		return
+	}
+
+	subscriptionsMu.Lock()
+	sub, ok := ownedSubscriptionLocked(r, id)
+	var v Subscription
+	// The subscription must keep a url or a channel to deliver to.
+	hasTarget := true
+	if ok {
+		target, channelCount := sub.URL, len(sub.Channels)
+		if body.URL != nil {
+			target = *body.URL
+		}
+		if body.Channels != nil {
+			channelCount = len(channels)
+		}
+		hasTarget = target != "" || channelCount > 0
+	}
+	if ok && hasTarget {
+		if body.URL != nil {
+			sub.URL = *body.URL
+		}
+		if body.Secret != nil {
+			sub.Secret = *body.Secret
+		}
+		if body.Paused != nil {
+			sub.Paused = *body.Paused
+		}
+		if body.QuietHours != nil {
+			sub.QuietHours, sub.quiet = *body.QuietHours, quiet
+		}
+		if body.Deadline != nil {
+			sub.Deadline, sub.SummarySentAt = body.Deadline, nil
+		}
+		if body.Channels != nil {
+			sub.Channels = channels
+		}
+		if body.ExpiresAt != nil {
+			sub.ExpiresAt, sub.ExpiryNoticeSentAt = body.ExpiresAt, nil
+		}
+		v = sub.view()
+	}
+	subscriptionsMu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeSubscriptionNotFound)
+		return
+	}
+	if !hasTarget {
+		writeError(w, r, codeInvalidWebhookURL)
+		return
+	}
+	log.Printf("subscription updated id=%s paused=%v", id, v.Paused)
+	writeSuccess(w, v)
+}
+
+// DELETE /api/subscriptions/{id}
+func deleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	subscriptionsMu.Lock()
+	_, ok := ownedSubscriptionLocked(r, id)
+	if ok {
+		delete(subscriptions, id)
+	}
+	subscriptionsMu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeSubscriptionNotFound)
+		return
+	}
+	log.Printf("subscription deleted id=%s", id)
+	writeSuccess(w, map[string]string{"id": id})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Sends a request through the subscription routes as the given API key.
+func subscriptionRequest(t *testing.T, method, path, body, apiKey string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	newTestServer("").ServeHTTP(w, req)
+	return w
+}
+
+func decodeData[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var resp struct {
+		Data T `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+	return resp.Data
+}
+
+const testSubscriptionBody = `{"url":"https://example.com/hook","student_id":"123","semester":"1945-1"}`
+
+func TestSubscriptions_Lifecycle(t *testing.T) {
+	setupWebhooks(t)
+
+	w := subscriptionRequest(t, "POST", "/api/subscriptions", testSubscriptionBody, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got status %d", w.Code)
+	}
+	id := decodeData[Subscription](t, w).ID
+
+	list := decodeData[[]Subscription](t, subscriptionRequest(t, "GET", "/api/subscriptions", "", ""))
+	if len(list) != 1 || list[0].ID != id {
+		t.Fatalf("list = %+v", list)
+	}
+	if list[0].Secret != "" {
+		t.Error("list must not expose the secret")
+	}
+
+	w = subscriptionRequest(t, "PATCH", "/api/subscriptions/"+id, `{"paused":true,"url":"https://example.com/new"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("patch: got status %d: %s", w.Code, w.Body)
+	}
+	if sub := decodeData[Subscription](t, w); !sub.Paused || sub.URL != "https://example.com/new" || sub.Secret != "" {
+		t.Errorf("patched subscription = %+v", sub)
+	}
+
+	if w := subscriptionRequest(t, "PATCH", "/api/subscriptions/"+id, `{"url":"nope"}`, ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid patch: got status %d, want 422", w.Code)
+	}
+
+	if w := subscriptionRequest(t, "DELETE", "/api/subscriptions/"+id, "", ""); w.Code != http.StatusOK {
+		t.Errorf("delete: got status %d", w.Code)
+	}
+	if w := subscriptionRequest(t, "GET", "/api/subscriptions/"+id, "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get after delete: got status %d, want 404", w.Code)
+	}
+}
+
+func TestSubscriptions_ScopedToAPIKey(t *testing.T) {
+	setupWebhooks(t)
+	setAPIKeys(t, "alpha=0,beta=0")
+
+	id := decodeData[Subscription](t, subscriptionRequest(t, "POST", "/api/subscriptions", testSubscriptionBody, "alpha")).ID
+
+	if list := decodeData[[]Subscription](t, subscriptionRequest(t, "GET", "/api/subscriptions", "", "beta")); len(list) != 0 {
+		t.Errorf("beta sees alpha's subscriptions: %+v", list)
+	}
+	for _, method := range []string{"GET", "PATCH", "DELETE"} {
+		if w := subscriptionRequest(t, method, "/api/subscriptions/"+id, `{}`, "beta"); w.Code != http.StatusNotFound {
+			t.Errorf("%s by another key: got status %d, want 404", method, w.Code)
+		}
+	}
+	if w := subscriptionRequest(t, "GET", "/api/subscriptions/"+id, "", "alpha"); w.Code != http.StatusOK {
+		t.Errorf("GET by owner: got status %d", w.Code)
+	}
+}
+
+func TestSubscriptions_PausedSkipsDelivery(t *testing.T) {
+	setupWebhooks(t)
+	srv := newWebhookTestServer(t, "")
+	receiver, received := webhookReceiver(t, 0)
+
+	body := strings.Replace(testSubscriptionBody, "https://example.com/hook", receiver.URL, 1)
+	id := decodeData[Subscription](t, subscriptionRequest(t, "POST", "/api/subscriptions", body, "")).ID
+	subscriptionRequest(t, "PATCH", "/api/subscriptions/"+id, `{"paused":true}`, "")
+
+	key := schedulePath("123", "1945-1", nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "A"}}, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", []CourseClass{{Code: "B"}}, time.Now(), nil)
+	time.Sleep(50 * time.Millisecond)
+	if n := len(received()); n != 0 {
+		t.Errorf("paused subscription received %d deliveries", n)
+	}
+}
+
+func TestSubscriptions_DeliveryStatusAndErrors(t *testing.T) {
+	setupWebhooks(t)
+	srv, _ := webhookReceiver(t, 1)
+
+	body := strings.Replace(testSubscriptionBody, "https://example.com/hook", srv.URL, 1)
+	id := decodeData[Subscription](t, subscriptionRequest(t, "POST", "/api/subscriptions", body, "")).ID
+
+	subscriptionsMu.Lock()
+	sub := *subscriptions[id]
+	subscriptionsMu.Unlock()
+	if err := newTestServer("").deliverWebhook(context.Background(), sub, WebhookEvent{ID: "evt1", Sequence: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := decodeData[Subscription](t, subscriptionRequest(t, "GET", "/api/subscriptions/"+id, "", ""))
+	if got.LastDelivery == nil || !got.LastDelivery.Delivered || got.LastDelivery.Attempts != 2 || got.LastDelivery.EventID != "evt1" {
+		t.Errorf("last_delivery = %+v", got.LastDelivery)
+	}
+	if len(got.Errors) != 1 || got.Errors[0].Attempt != 1 || !strings.Contains(got.Errors[0].Error, "500") {
+		t.Errorf("errors = %+v", got.Errors)
+	}
+}
+
+func TestRecordDelivery_BoundsErrorHistory(t *testing.T) {
+	setupWebhooks(t)
+	subscriptions["s1"] = &Subscription{ID: "s1"}
+	for i := range maxDeliveryErrors + 5 {
+		recordDelivery("s1", WebhookEvent{ID: "e", Sequence: int64(i)}, 1, true, context.DeadlineExceeded)
+	}
+	if n := len(subscriptions["s1"].Errors); n != maxDeliveryErrors {
+		t.Errorf("kept %d errors, want %d", n, maxDeliveryErrors)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// /api/schedule/text turns a schedule into a sentence or two for voice
+// assistants and chatbots, e.g. "Besok kamu ada 3 kelas, mulai 07.00 di
+// 7602: ...". Days come from the weekly pattern, so a date maps to its
+// weekday's meetings.
+
+type ScheduleText struct {
+	Text    string `json:"text"`
+	Lang    string `json:"lang"`
+	Period  string `json:"period"` // today, tomorrow, or week
+	Date    string `json:"date"`   // the day described, or the Monday of the week
+	Classes int    `json:"classes"`
+}
+
+// Words that differ between the languages of a summary.
+type summaryWords struct {
+	today, tomorrow, thisWeek string
+	on                        func(day string) string
+	have                      func(when string, n int) string
+	none                      func(when string) string
+	weekHave                  func(n, days int) string
+	startsAt                  string // "mulai 07.00"
+	in                        string
+	online                    string
+	from                      string
+	clock                     func(hhmm string) string
+	dayName                   func(day string) string
+}
+
+var summaryLangs = map[string]summaryWords{
+	"id": {
+		today: "Hari ini", tomorrow: "Besok", thisWeek: "Minggu ini",
+		on:   func(day string) string { return "Pada hari " + day },
+		have: func(when string, n int) string { return fmt.Sprintf("%s kamu ada %d kelas", when, n) },
+		none: func(when string) string { return when + " kamu tidak ada kelas." },
+		weekHave: func(n, days int) string {
+			return fmt.Sprintf("Minggu ini kamu ada %d pertemuan dalam %d hari.", n, days)
+		},
+		startsAt: "mulai", in: "di", online: "daring", from: "pukul",
+		clock:   func(hhmm string) string { return strings.Replace(hhmm, ":", ".", 1) },
+		dayName: func(day string) string { return day },
+	},
+	"en": {
+		today: "Today", tomorrow: "Tomorrow", thisWeek: "This week",
+		on: func(day string) string { return "On " + day },
+		have: func(when string, n int) string {
+			return fmt.Sprintf("%s you have %d %s", when, n, plural(n, "class", "classes"))
+		},
+		none: func(when string) string { return when + " you have no classes." },
+		weekHave: func(n, days int) string {
+			return fmt.Sprintf("This week you have %d %s over %d %s.", n, plural(n, "meeting", "meetings"), days, plural(days, "day", "days"))
+		},
+		startsAt: "starting at", in: "in", online: "online", from: "at",
+		clock: func(hhmm string) string { return hhmm },
+		dayName: func(day string) string {
+			if rank, ok := dayOrder[day]; ok {
+				return englishDays[rank]
+			}
+			return day
+		},
+	},
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// Where a session is: its room, or online.
+func (w summaryWords) place(s GridSession) string {
+	if strings.EqualFold(s.Method, "online") || s.Room == "" {
+		return w.online
+	}
+	return w.in + " " + s.Room
+}
+
+// Describes the sessions of one day. when is e.g. "Besok".
+func (w summaryWords) day(when string, sessions []GridSession) string {
+	if len(sessions) == 0 {
+		return w.none(when)
+	}
+	first := sessions[0]
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, %s %s %s", w.have(when, len(sessions)), w.startsAt, w.clock(first.Start), w.place(first))
+	parts := make([]string, len(sessions))
+	for i, s := range sessions {
+		parts[i] = fmt.Sprintf("%s %s %s %s–%s %s", s.Code, s.Name, w.from, w.clock(s.Start), w.clock(s.End), w.place(s))
+	}
+	fmt.Fprintf(&b, ": %s.", strings.Join(parts, "; "))
+	return b.String()
+}
+
+// Describes the week: a count, then one short clause per day with classes.
+func (w summaryWords) week(grid ScheduleGrid) string {
+	n := 0
+	for _, d := range grid.Days {
+		n += len(d.Sessions)
+	}
+	if n == 0 {
+		return w.none(w.thisWeek)
+	}
+	parts := []string{w.weekHave(n, len(grid.Days))}
+	for _, d := range grid.Days {
+		first := d.Sessions[0]
+		parts = append(parts, fmt.Sprintf("%s: %d, %s %s %s.", w.dayName(d.Day), len(d.Sessions), w.startsAt, w.clock(first.Start), w.place(first)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Summarizes classes for the period around date, which is a day in WIB.
+// today is the current day, to say "today" and "tomorrow" only when true.
+func scheduleText(classes []CourseClass, lang, period string, date, today time.Time) ScheduleText {
+	w, ok := summaryLangs[lang]
+	if !ok {
+		lang, w = "id", summaryLangs["id"]
+	}
+	grid := scheduleGrid(classes, nil)
+	out := ScheduleText{Lang: lang, Period: period}
+
+	if period == "week" {
+		monday := date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+		out.Date = monday.Format(time.DateOnly)
+		out.Text = w.week(grid)
+		for _, d := range grid.Days {
+			out.Classes += len(d.Sessions)
+		}
+		return out
+	}
+
+	if period == "tomorrow" {
+		date = date.AddDate(0, 0, 1)
+	}
+	out.Date = date.Format(time.DateOnly)
+	day := indonesianDays[(int(date.Weekday())+6)%7]
+	var sessions []GridSession
+	for _, d := range grid.Days {
+		if d.Day == day {
+			sessions = d.Sessions
+		}
+	}
+	var when string
+	switch {
+	case date.Equal(today):
+		when = w.today
+	case date.Equal(today.AddDate(0, 0, 1)):
+		when = w.tomorrow
+	default:
+		when = w.on(w.dayName(day))
+	}
+	out.Text = w.day(when, sessions)
+	out.Classes = len(sessions)
+	return out
+}
+
+// Returns midnight WIB of the day t falls on.
+func wibDay(t time.Time) time.Time {
+	y, m, d := t.In(wib).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, wib)
+}
+
+// GET /api/schedule/text
+func (s *Server) scheduleTextHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	today := wibDay(time.Now())
+	date := today
+	if v := query.Get("date"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, wib)
+		if err != nil {
+			writeError(w, r, codeInvalidRequest, "date must have the form YYYY-MM-DD")
+			return
+		}
+		date = d
+	}
+	period := query.Get("period")
+	if period == "" {
+		period = "today"
+	}
+	lang := query.Get("lang")
+	if lang == "" {
+		lang = "id"
+	}
+
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	writeSuccessWithMeta(w, scheduleText(classes, lang, period, date, today), meta)
+}
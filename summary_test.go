@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScheduleText(t *testing.T) {
+	classes := []CourseClass{
+		{Code: "FI1210", Name: "Fisika Dasar", Schedules: []ScheduleEntry{
+			{Day: "Senin", Time: "07:00-09:00", Room: "7602", Method: "Offline"},
+			{Day: "Rabu", Time: "13:00-15:00", Room: "7603", Method: "Online"},
+		}},
+		{Code: "MA1101", Name: "Matematika IA", Schedules: []ScheduleEntry{{Day: "Senin", Time: "10:00-12:00", Room: "9009"}}},
+	}
+	sunday := time.Date(2025, 2, 9, 0, 0, 0, 0, wib)
+	monday := sunday.AddDate(0, 0, 1)
+	tests := []struct {
+		lang, period string
+		date         time.Time
+		want         string
+	}{
+		{"id", "tomorrow", sunday, "Besok kamu ada 2 kelas, mulai 07.00 di 7602: FI1210 Fisika Dasar pukul 07.00–09.00 di 7602; MA1101 Matematika IA pukul 10.00–12.00 di 9009."},
+		{"id", "today", sunday, "Hari ini kamu tidak ada kelas."},
+		{"en", "today", monday.AddDate(0, 0, 2), "On Wednesday you have 1 class, starting at 13:00 online: FI1210 Fisika Dasar at 13:00–15:00 online."},
+		{"en", "week", sunday, "This week you have 3 meetings over 2 days. Monday: 2, starting at 07:00 in 7602. Wednesday: 1, starting at 13:00 online."},
+	}
+	for _, tt := range tests {
+		got := scheduleText(classes, tt.lang, tt.period, tt.date, sunday)
+		if got.Text != tt.want {
+			t.Errorf("%s %s %s:\n got %q\nwant %q", tt.lang, tt.period, tt.date.Format(time.DateOnly), got.Text, tt.want)
+		}
+	}
+	if got := scheduleText(classes, "en", "week", monday.AddDate(0, 0, 3), sunday); got.Date != "2025-02-10" || got.Classes != 3 {
+		t.Errorf("week: date %s, classes %d", got.Date, got.Classes)
+	}
+}
+
+func TestScheduleTextHandler(t *testing.T) {
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/schedule/text?student_id=123&semester=1945-1&date=2025-02-11", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	got := decodeData[ScheduleText](t, w)
+	want := "Pada hari Selasa kamu ada 1 kelas, mulai 09.00 di 7604: FI1220 Fisika Lanjut pukul 09.00–11.00 di 7604."
+	if got.Text != want || got.Date != "2025-02-11" || got.Period != "today" {
+		t.Errorf("got %+v, want text %q", got, want)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The swap board matches students who want to trade classes of the same
+// course: "I have class 01 of IF2211 and want 02" matches "I have 02 and want
+// 01". It only does matchmaking; nothing is written to SIX. Matched students
+// see each other's student ID, so the board is opt-in for operators too.
+var (
+	swapBoardEnabled = envBool("SIX_SWAP_BOARD", false)
+	swapMax          = envInt("SIX_SWAP_MAX", 2000)
+)
+
+type Swap struct {
+	ID        string     `json:"id"`
+	StudentID string     `json:"student_id"`
+	Semester  string     `json:"semester"`
+	Course    string     `json:"course"`
+	Have      string     `json:"have"`
+	Want      string     `json:"want"`
+	URL       string     `json:"url,omitempty"`
+	Secret    string     `json:"secret,omitempty"` // only returned on creation
+	CreatedAt time.Time  `json:"created_at"`
+	Match     *SwapMatch `json:"match,omitempty"`
+
+	owner    string
+	notifier Notifier // nil without a URL
+}
+
+// The other side of a matched swap.
+type SwapMatch struct {
+	SwapID    string    `json:"swap_id"`
+	StudentID string    `json:"student_id"`
+	MatchedAt time.Time `json:"matched_at"`
+}
+
+type swapBoard struct {
+	mu    sync.Mutex
+	swaps map[string]*Swap
+}
+
+func newSwapBoard() *swapBoard {
+	return &swapBoard{swaps: make(map[string]*Swap)}
+}
+
+func (sw *Swap) view() Swap {
+	v := *sw
+	v.Secret = ""
+	if sw.Match != nil {
+		m := *sw.Match
+		v.Match = &m
+	}
+	return v
+}
+
+// Reports whether a and b are open requests that satisfy each other.
+func complementary(a, b *Swap) bool {
+	return a.Match == nil && b.Match == nil &&
+		a.StudentID != b.StudentID &&
+		a.Semester == b.Semester &&
+		strings.EqualFold(a.Course, b.Course) &&
+		a.Have == b.Want && a.Want == b.Have
+}
+
+// Adds sw to the board and matches it with the oldest complementary open
+// request, if any. Returns the counterpart, or nil. Returns ok=false if the
+// board is full.
+func (b *swapBoard) add(sw *Swap) (counterpart *Swap, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.swaps) >= swapMax {
+		return nil, false
+	}
+	for _, other := range b.swaps {
+		if complementary(sw, other) && (counterpart == nil || other.CreatedAt.Before(counterpart.CreatedAt)) {
+			counterpart = other
+		}
+	}
+	b.swaps[sw.ID] = sw
+	if counterpart != nil {
+		now := time.Now()
+		sw.Match = &SwapMatch{SwapID: counterpart.ID, StudentID: counterpart.StudentID, MatchedAt: now}
+		counterpart.Match = &SwapMatch{SwapID: sw.ID, StudentID: sw.StudentID, MatchedAt: now}
+	}
+	return counterpart, true
+}
+
+// Returns the swap with id if the caller owns it. Callers must hold b.mu.
+func (b *swapBoard) ownedLocked(r *http.Request, id string) (*Swap, bool) {
+	sw, ok := b.swaps[id]
+	if !ok || sw.owner != subscriptionOwner(r) {
+		return nil, false
+	}
+	return sw, true
+}
+
+// Sends a swap.matched notification to the student of sw, if it has a URL.
+func notifySwapMatched(sw Swap) {
+	if sw.notifier == nil {
+		return
+	}
+	title := fmt.Sprintf("Swap found for %s: your class %s for class %s", sw.Course, sw.Have, sw.Want)
+	n := newNotification("swap.matched", sw.StudentID, title, sw.view())
+	go func() {
+		if err := sw.notifier.Notify(context.Background(), n); err != nil {
+			log.Printf("swap notification failed id=%s err=%v", sw.ID, err)
+		}
+	}()
+}
+
+type createSwapRequest struct {
+	StudentID string `json:"student_id"`
+	Semester  string `json:"semester"`
+	Course    string `json:"course"`
+	Have      string `json:"have"`
+	Want      string `json:"want"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret"`
+}
+
+// POST /api/swaps
+func (s *Server) createSwap(w http.ResponseWriter, r *http.Request) {
+	if !swapBoardEnabled {
+		writeError(w, r, codeSwapBoardDisabled)
+		return
+	}
+	var body createSwapRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	if body.Have == body.Want {
+		writeError(w, r, codeInvalidRequest, "want must differ from have")
+		return
+	}
+	if body.URL != "" && !validWebhookURL(body.URL) {
+		writeError(w, r, codeInvalidWebhookURL)
+		return
+	}
+
+	// Check against the student's own schedule that they hold the class
+	// they offer.
+	classes, meta, ok := s.schedule(w, r, body.StudentID, body.Semester, nil)
+	if !ok {
+		return
+	}
+	if meta.Semester != "" {
+		body.Semester = meta.Semester
+	}
+	enrolled := slices.ContainsFunc(classes, func(c CourseClass) bool {
+		return strings.EqualFold(c.Code, body.Course) && c.ClassNo == body.Have
+	})
+	if !enrolled {
+		writeError(w, r, codeNotEnrolled, body.Course, body.Have)
+		return
+	}
+
+	sw := &Swap{
+		ID:        randomHex(8),
+		StudentID: body.StudentID,
+		Semester:  body.Semester,
+		Course:    strings.ToUpper(body.Course),
+		Have:      body.Have,
+		Want:      body.Want,
+		URL:       body.URL,
+		Secret:    body.Secret,
+		CreatedAt: time.Now(),
+		owner:     subscriptionOwner(r),
+	}
+	if sw.URL != "" {
+		if sw.Secret == "" {
+			sw.Secret = randomHex(32)
+		}
+		sw.notifier = newWebhookNotifier(sw.URL, sw.Secret)
+	}
+
+	counterpart, ok := s.swaps.add(sw)
+	if !ok {
+		writeError(w, r, codeServerBusy)
+		return
+	}
+	log.Printf("swap created id=%s course=%s have=%s want=%s matched=%v", sw.ID, sw.Course, sw.Have, sw.Want, counterpart != nil)
+
+	s.swaps.mu.Lock()
+	created := *sw
+	if counterpart != nil {
+		notifySwapMatched(sw.view())
+		notifySwapMatched(counterpart.view())
+	}
+	s.swaps.mu.Unlock()
+	writeCreated(w, created)
+}
+
+// GET /api/swaps
+func (s *Server) listSwaps(w http.ResponseWriter, r *http.Request) {
+	owner := subscriptionOwner(r)
+	s.swaps.mu.Lock()
+	list := []Swap{}
+	for _, sw := range s.swaps.swaps {
+		if sw.owner == owner {
+			list = append(list, sw.view())
+		}
+	}
+	s.swaps.mu.Unlock()
+
+	slices.SortFunc(list, func(a, b Swap) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	writeSuccess(w, list)
+}
+
+// GET /api/swaps/{id}
+func (s *Server) getSwap(w http.ResponseWriter, r *http.Request) {
+	s.swaps.mu.Lock()
+	sw, ok := s.swaps.ownedLocked(r, r.PathValue("id"))
+	var v Swap
+	if ok {
+		v = sw.view()
+	}
+	s.swaps.mu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeSwapNotFound)
+		return
+	}
+	writeSuccess(w, v)
+}
+
+// DELETE /api/swaps/{id}. Withdrawing a matched swap does not reopen the
+// counterpart's request.
+func (s *Server) deleteSwap(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.swaps.mu.Lock()
+	_, ok := s.swaps.ownedLocked(r, id)
+	if ok {
+		delete(s.swaps.swaps, id)
+	}
+	s.swaps.mu.Unlock()
+
+	if !ok {
+		writeError(w, r, codeSwapNotFound)
+		return
+	}
+	log.Printf("swap deleted id=%s", id)
+	writeSuccess(w, map[string]string{"id": id})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupSwapBoard(t *testing.T) *Server {
+	t.Helper()
+	setupWebhooks(t)
+	old := swapBoardEnabled
+	swapBoardEnabled = true
+	t.Cleanup(func() { swapBoardEnabled = old })
+
+	srv := newTestServer("")
+	srv.cache.set(schedulePath("111", "1945-1", nil), []CourseClass{{Code: "IF2211", ClassNo: "01"}}, time.Now())
+	srv.cache.set(schedulePath("222", "1945-1", nil), []CourseClass{{Code: "IF2211", ClassNo: "02"}}, time.Now())
+	srv.cache.set(schedulePath("333", "1945-1", nil), []CourseClass{{Code: "IF2211", ClassNo: "02"}}, time.Now())
+	return srv
+}
+
+func postSwap(srv *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/swaps", strings.NewReader(body))
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestSwapBoard_MatchesComplementaryRequests(t *testing.T) {
+	srv := setupSwapBoard(t)
+	receiver, received := webhookReceiver(t, 0)
+
+	w := postSwap(srv, `{"student_id":"111","semester":"1945-1","course":"if2211","have":"01","want":"02","url":"`+receiver.URL+`"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	first := decodeData[Swap](t, w)
+	if first.Match != nil || first.Course != "IF2211" || first.Secret == "" {
+		t.Errorf("first swap = %+v", first)
+	}
+
+	second := decodeData[Swap](t, postSwap(srv, `{"student_id":"222","semester":"1945-1","course":"IF2211","have":"02","want":"01"}`))
+	if second.Match == nil || second.Match.SwapID != first.ID || second.Match.StudentID != "111" {
+		t.Errorf("second swap = %+v, want a match with the first", second)
+	}
+
+	// A third student offering the same class finds no open counterpart.
+	third := decodeData[Swap](t, postSwap(srv, `{"student_id":"333","semester":"1945-1","course":"IF2211","have":"02","want":"01"}`))
+	if third.Match != nil {
+		t.Errorf("third swap matched %+v, but the first is taken", third.Match)
+	}
+
+	req := httptest.NewRequest("GET", "/api/swaps/"+first.ID, nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if v := decodeData[Swap](t, w); v.Match == nil || v.Match.StudentID != "222" || v.Secret != "" {
+		t.Errorf("first swap after match = %+v", v)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := received(); len(got) != 1 || !strings.Contains(string(got[0].body), `"swap.matched"`) {
+		t.Errorf("expected one swap.matched notification, got %d", len(got))
+	}
+}
+
+func TestSwapBoard_Validation(t *testing.T) {
+	srv := setupSwapBoard(t)
+	if w := postSwap(srv, `{"student_id":"111","semester":"1945-1","course":"IF2211","have":"02","want":"03"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("class not held: got status %d, want 422", w.Code)
+	}
+	if w := postSwap(srv, `{"student_id":"111","semester":"1945-1","course":"IF2211","have":"01","want":"01"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same class: got status %d, want 422", w.Code)
+	}
+
+	swapBoardEnabled = false
+	if w := postSwap(srv, `{"student_id":"111","semester":"1945-1","course":"IF2211","have":"01","want":"02"}`); w.Code != http.StatusNotFound {
+		t.Errorf("disabled: got status %d, want 404", w.Code)
+	}
+}
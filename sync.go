@@ -0,0 +1,100 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Offline-first clients keep a copy of a schedule and send the hash of every
+// class they hold. Only classes that are new or whose hash differs are sent
+// back, plus a deletion notice for every held class that is gone.
+type syncRequest struct {
+	Have map[string]string `json:"have"` // classKey → class hash
+}
+
+type SyncResult struct {
+	Hash      string         `json:"hash"` // of the whole schedule, see classesHash
+	Upserts   []SyncClass    `json:"upserts"`
+	Deletions []SyncDeletion `json:"deletions"`
+	Unchanged int            `json:"unchanged"`
+}
+
+type SyncClass struct {
+	ID    string      `json:"id"`
+	Hash  string      `json:"hash"`
+	Class CourseClass `json:"class"`
+}
+
+// A class the client holds that the schedule no longer lists. LastSeenAt is
+// set when the class has a tombstone in the last good snapshot.
+type SyncDeletion struct {
+	ID         string     `json:"id"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+func classHash(c CourseClass) string {
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Compares the classes a client holds with the current ones. tombstones
+// supplies last_seen_at for deletions.
+func syncClasses(have map[string]string, classes []CourseClass, tombstones []RemovedClass) SyncResult {
+	res := SyncResult{Upserts: []SyncClass{}, Deletions: []SyncDeletion{}}
+	res.Hash, _ = classesHash(classes)
+	current := make(map[string]bool, len(classes))
+	for _, c := range classes {
+		id := classKey(c)
+		current[id] = true
+		hash := classHash(c)
+		if have[id] == hash {
+			res.Unchanged++
+			continue
+		}
+		res.Upserts = append(res.Upserts, SyncClass{ID: id, Hash: hash, Class: c})
+	}
+
+	lastSeen := make(map[string]time.Time, len(tombstones))
+	for _, t := range tombstones {
+		lastSeen[classKey(t.CourseClass)] = t.LastSeenAt
+	}
+	for id := range have {
+		if current[id] {
+			continue
+		}
+		d := SyncDeletion{ID: id}
+		if at, ok := lastSeen[id]; ok {
+			d.LastSeenAt = &at
+		}
+		res.Deletions = append(res.Deletions, d)
+	}
+	slices.SortFunc(res.Upserts, func(a, b SyncClass) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(res.Deletions, func(a, b SyncDeletion) int { return cmp.Compare(a.ID, b.ID) })
+	return res
+}
+
+// POST /api/sync
+//
+// Loads the schedule selected by the query like /api/schedule and returns
+// only what differs from the class hashes in the body.
+func (s *Server) syncHandler(w http.ResponseWriter, r *http.Request) {
+	var body syncRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, codeInvalidJSON)
+		return
+	}
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	semester := cmp.Or(meta.Semester, query.Get("semester"))
+	snap, _ := s.lastGood.get(schedulePath(query.Get("student_id"), semester, query))
+	writeSuccessWithMeta(w, syncClasses(body.Have, classes, snap.Removed), meta)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncClasses(t *testing.T) {
+	a := CourseClass{Code: "IF2211", ClassNo: "01", Quota: 40}
+	b := CourseClass{Code: "IF2230", ClassNo: "02"}
+	gone := CourseClass{Code: "FI1210", ClassNo: "01"}
+	lastSeen := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	have := map[string]string{
+		"IF2211/01": classHash(a),
+		"IF2230/02": "stale",
+		"FI1210/01": classHash(gone),
+		"MA1101/03": "unknown",
+	}
+
+	res := syncClasses(have, []CourseClass{a, b}, tombstones([]CourseClass{gone}, lastSeen, lastSeen.Add(time.Hour)))
+	if res.Unchanged != 1 || len(res.Upserts) != 1 || res.Upserts[0].ID != "IF2230/02" || res.Upserts[0].Hash != classHash(b) {
+		t.Errorf("upserts = %+v, unchanged %d", res.Upserts, res.Unchanged)
+	}
+	if len(res.Deletions) != 2 || res.Deletions[0].ID != "FI1210/01" || res.Deletions[1].ID != "MA1101/03" {
+		t.Fatalf("deletions = %+v", res.Deletions)
+	}
+	if at := res.Deletions[0].LastSeenAt; at == nil || !at.Equal(lastSeen) {
+		t.Errorf("tombstoned deletion last_seen_at = %v, want %v", at, lastSeen)
+	}
+	if res.Deletions[1].LastSeenAt != nil {
+		t.Error("deletion without a tombstone should have no last_seen_at")
+	}
+	if want, _ := classesHash([]CourseClass{b, a}); res.Hash != want {
+		t.Errorf("hash = %s, want %s", res.Hash, want)
+	}
+}
+
+func TestSyncHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/sync?student_id=123&semester=1945-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	first := decodeData[SyncResult](t, w)
+	if len(first.Upserts) == 0 || len(first.Deletions) != 0 {
+		t.Fatalf("initial sync = %+v", first)
+	}
+
+	have := []string{`"GONE/01":"x"`}
+	for _, u := range first.Upserts {
+		have = append(have, fmt.Sprintf("%q:%q", u.ID, u.Hash))
+	}
+	again := decodeData[SyncResult](t, post(`{"have":{`+strings.Join(have, ",")+`}}`))
+	if len(again.Upserts) != 0 || again.Unchanged != len(first.Upserts) || again.Hash != first.Hash {
+		t.Errorf("second sync = %+v", again)
+	}
+	if len(again.Deletions) != 1 || again.Deletions[0].ID != "GONE/01" {
+		t.Errorf("deletions = %+v", again.Deletions)
+	}
+
+	if w := post(`{"have":{"IF2211/01":1}}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("non-string hash: got status %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Operators can drop Go text templates into SIX_TEMPLATE_DIR and render
+// schedules with /api/schedule?format=template&name=whatsapp, which uses
+// whatsapp.tmpl. Templates only get the functions in templateFuncs, none of
+// which touch files, the network, or the environment, and their output is
+// capped at SIX_TEMPLATE_MAX_KB.
+var (
+	templateDir   = envString("SIX_TEMPLATE_DIR", "")
+	templateMaxKB = envInt("SIX_TEMPLATE_MAX_KB", 64)
+)
+
+// Template names: file names without .tmpl.
+var templateNameRe = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"trim":  strings.TrimSpace,
+	"add":   func(a, b int) int { return a + b },
+	// Formats a time in WIB, e.g. {{wib .FetchedAt "02/01 15:04"}}.
+	"wib": func(t time.Time, layout string) string { return t.In(wib).Format(layout) },
+}
+
+// What a template is executed with.
+type TemplateData struct {
+	StudentID string
+	Semester  string
+	FetchedAt time.Time
+	Cached    bool
+	Classes   []CourseClass
+	Grid      ScheduleGrid // the classes by day, as in format=grid
+}
+
+// Parses every *.tmpl file in dir. A template that fails to parse is logged
+// and left out.
+func loadTemplates(dir string) (map[string]*template.Template, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]*template.Template)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+		if !templateNameRe.MatchString(name) {
+			log.Printf("template %s skipped: names may only use a-z, 0-9, _ and -", path)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			log.Printf("template %s skipped: %v", path, err)
+			continue
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+var errTemplateTooLarge = errors.New("template output too large")
+
+// A writer that fails once more than n bytes are written.
+type cappedBuffer struct {
+	bytes.Buffer
+	n int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.n {
+		return 0, errTemplateTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// Renders the named template with data.
+func (s *Server) renderTemplate(name string, data TemplateData) ([]byte, error) {
+	tmpl, ok := s.templates[name]
+	if !ok {
+		return nil, errTemplateNotFound
+	}
+	buf := &cappedBuffer{n: templateMaxKB << 10}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+var errTemplateNotFound = errors.New("template not found")
+
+// Writes the schedule rendered with the template named by the name query
+// parameter.
+func (s *Server) writeTemplate(w http.ResponseWriter, r *http.Request, classes []CourseClass, meta *Meta) {
+	query := r.URL.Query()
+	semester := query.Get("semester")
+	if meta.Semester != "" {
+		semester = meta.Semester
+	}
+	out, err := s.renderTemplate(query.Get("name"), TemplateData{
+		StudentID: query.Get("student_id"),
+		Semester:  semester,
+		FetchedAt: meta.FetchedAt,
+		Cached:    meta.Cached,
+		Classes:   classes,
+		Grid:      scheduleGrid(classes, s.buildings),
+	})
+	if errors.Is(err, errTemplateNotFound) {
+		writeError(w, r, codeTemplateNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("template render failed: %v", err)
+		writeError(w, r, codeInternal)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(out)
+}
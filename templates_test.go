@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupTemplates(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDir, oldMax := templateDir, templateMaxKB
+	templateDir, templateMaxKB = dir, 1
+	t.Cleanup(func() { templateDir, templateMaxKB = oldDir, oldMax })
+}
+
+func TestScheduleTemplate(t *testing.T) {
+	setupTemplates(t, map[string]string{
+		"whatsapp.tmpl": `*Jadwal {{.Semester}}*
+{{range .Grid.Days}}{{upper .Day}}
+{{range .Sessions}}- {{.Start}} {{.Code}} ({{.Room}})
+{{end}}{{end}}`,
+		"broken.tmpl":   `{{range}}`,
+		"huge.tmpl":     `{{range .Classes}}{{range $.Classes}}{{range $.Classes}}{{printf "%0512d" 0}}{{end}}{{end}}{{end}}`,
+		"Bad Name.tmpl": `x`,
+	})
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	if len(srv.templates) != 2 {
+		t.Errorf("loaded %d templates, want whatsapp and huge", len(srv.templates))
+	}
+
+	get := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&format=template&name="+name, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	w := get("whatsapp")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("status %d, content type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	want := "*Jadwal 1945-1*\nSENIN\n- 07:00 FI1210 (7602)\nSELASA\n- 09:00 FI1220 (7604)\nRABU\n- 13:00 FI1210 (7603)\n"
+	if w.Body.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", w.Body, want)
+	}
+
+	if w := get("broken"); w.Code != http.StatusNotFound {
+		t.Errorf("unparsable template: status %d, want 404", w.Code)
+	}
+	if w := get("huge"); w.Code != http.StatusInternalServerError {
+		t.Errorf("oversized output: status %d, want 500", w.Code)
+	}
+}
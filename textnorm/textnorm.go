@@ -0,0 +1,76 @@
+// Package textnorm normalizes text scraped from SIX so that strings that a
+// person would call the same compare equal, whatever their Unicode form,
+// case, or accents.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Letters with no Unicode decomposition that StripDiacritics still maps to
+// their base letters, and apostrophe look-alikes that Key maps to "'".
+var (
+	undecomposable = map[rune]string{
+		'đ': "d", 'Đ': "D", 'ø': "o", 'Ø': "O", 'ł': "l", 'Ł': "L",
+		'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ı': "i",
+	}
+	apostrophes = strings.NewReplacer("’", "'", "‘", "'", "ʼ", "'", "`", "'", "´", "'")
+)
+
+// NFC returns s in Unicode normalization form C, so that "e" followed by a
+// combining acute accent and the single rune "é" are the same string.
+func NFC(s string) string {
+	return norm.NFC.String(s)
+}
+
+// Fold returns s with full Unicode case folding, which is lowercasing that
+// also maps e.g. "ß" to "ss".
+func Fold(s string) string {
+	return cases.Fold().String(s)
+}
+
+// StripDiacritics removes accents and other combining marks, e.g. "Hàsan"
+// becomes "Hasan". The result is in NFC.
+func StripDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		out = s
+	}
+	if strings.IndexFunc(out, func(r rune) bool { _, ok := undecomposable[r]; return ok }) < 0 {
+		return out
+	}
+	var b strings.Builder
+	for _, r := range out {
+		if base, ok := undecomposable[r]; ok {
+			b.WriteString(base)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Key returns the form of s used for comparisons: NFC, without diacritics,
+// case folded, with apostrophe variants unified and runs of whitespace
+// collapsed to one space.
+func Key(s string) string {
+	s = Fold(StripDiacritics(NFC(s)))
+	return strings.Join(strings.Fields(apostrophes.Replace(s)), " ")
+}
+
+// Equal reports whether a and b have the same Key.
+func Equal(a, b string) bool {
+	return Key(a) == Key(b)
+}
+
+// Contains reports whether the Key of s contains the Key of substr.
+func Contains(s, substr string) bool {
+	return strings.Contains(Key(s), Key(substr))
+}
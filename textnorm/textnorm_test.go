@@ -0,0 +1,100 @@
+package textnorm
+
+import "testing"
+
+func TestNFC(t *testing.T) {
+	decomposed := "Jose\u0301" // e + combining acute
+	if got := NFC(decomposed); got != "José" {
+		t.Errorf("NFC(%q) = %q, want %q", decomposed, got, "José")
+	}
+	if got := NFC("Fisika Dasar"); got != "Fisika Dasar" {
+		t.Errorf("NFC changed ASCII text: %q", got)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"STRATEGI ALGORITMA", "strategi algoritma"},
+		{"Straße", "strasse"},
+		{"IF2211", "if2211"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφοσ"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripDiacritics(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Hàsan", "Hasan"},
+		{"Désy Ratnasari", "Desy Ratnasari"},
+		{"José", "Jose"},
+		{"Ñoman", "Noman"},
+		{"Müller", "Muller"},
+		{"Søren Đặng", "Soren Dang"},
+		{"Œuvre", "OEuvre"},
+		{"Kalkulus IIA", "Kalkulus IIA"},
+	}
+	for _, tt := range tests {
+		if got := StripDiacritics(tt.in); got != tt.want {
+			t.Errorf("StripDiacritics(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"  Strategi   Algoritma ", "strategi algoritma"},
+		{"Ma’ruf Amin", "ma'ruf amin"},
+		{"Ma`ruf Amin", "ma'ruf amin"},
+		{"I Gusti Ngurah Rai", "i gusti ngurah rai"},
+		{"Dr. Ir. Dwi Hendratmo Widyantoro, M.Sc.", "dr. ir. dwi hendratmo widyantoro, m.sc."},
+		{"Pengantar\tRekayasa\nPerangkat Lunak", "pengantar rekayasa perangkat lunak"},
+		{"Andréas Müller", "andreas muller"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.in); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Rinaldi Munir", "RINALDI MUNIR", true},
+		{"Ni Made Désy", "Ni Made Desy", true},
+		{"Nur Ulfa Maulidevi", "Nur  Ulfa Maulidevi", true},
+		{"José", "JOSÉ", true},
+		{"Sistem Operasi", "Sistem Informasi", false},
+		{"Kalkulus IA", "Kalkulus IIA", false},
+		{"wajib", "WAJIB", true},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		s, substr string
+		want      bool
+	}{
+		{"Sedang dalam Pemeliharaan", "pemeliharaan", true},
+		{"Dr. Andréas Müller, S.T.", "andreas muller", true},
+		{"Matematika Diskrit", "diskret", false},
+		{"anything", "", true},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.s, tt.substr); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.s, tt.substr, got, tt.want)
+		}
+	}
+}
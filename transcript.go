@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"six-scraper-go/scraper"
+)
+
+// SIX page listing every course a student has taken and its grade.
+func transcriptPath(studentID string) string {
+	return fmt.Sprintf("/app/mahasiswa:%s/akademik/transkrip", studentID)
+}
+
+type TranscriptCourse struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	SKS      int    `json:"sks"`
+	Semester string `json:"semester,omitempty"`
+	// Grade is the letter grade, or empty while the course is in progress.
+	Grade string `json:"grade"`
+}
+
+// ITB letter grades and their grade points.
+var (
+	letterGrades = []string{"A", "AB", "B", "BC", "C", "D", "E"}
+	gradePoints  = map[string]float64{
+		"A": 4, "AB": 3.5, "B": 3, "BC": 2.5, "C": 2, "D": 1, "E": 0,
+	}
+)
+
+// Reports whether the course counts towards graduation: it has a grade of D
+// or better.
+func (c TranscriptCourse) passed() bool {
+	p, ok := gradePoints[c.Grade]
+	return ok && p >= gradePoints["D"]
+}
+
+// Parses the transcript page. Columns are found by their header text, so
+// tables that lack a code, SKS, or grade column are ignored.
+func parseTranscript(doc *goquery.Document) []TranscriptCourse {
+	var courses []TranscriptCourse
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := scraper.HeaderColumns(table)
+		code, hasCode := cols["kode"]
+		sks, hasSKS := cols["sks"]
+		grade, hasGrade := cols["nilai"]
+		if !hasCode || !hasSKS || !hasGrade {
+			return
+		}
+		name, hasName := cols["nama"]
+		semester, hasSemester := cols["semester"]
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			c := TranscriptCourse{
+				Code:  scraper.CellText(cells, code),
+				Grade: strings.ToUpper(scraper.CellText(cells, grade)),
+			}
+			c.SKS, _ = strconv.Atoi(scraper.CellText(cells, sks))
+			if hasName {
+				c.Name = scraper.CellText(cells, name)
+			}
+			if hasSemester {
+				c.Semester = scraper.CellText(cells, semester)
+			}
+			if c.Code != "" {
+				courses = append(courses, c)
+			}
+		}
+	})
+	return courses
+}
+
+// The averages of one semester as SIX shows them. Fields SIX leaves out or
+// shows blank are nil.
+type ReportedGPA struct {
+	Semester string   `json:"semester"`
+	IP       *float64 `json:"ip"`
+	IPK      *float64 `json:"ipk"`
+	SKS      *int     `json:"sks"`
+}
+
+// Parses the table of semester averages on the transcript page, found by
+// its "Semester" and "IP" columns.
+func parseReportedGPA(doc *goquery.Document) []ReportedGPA {
+	var reported []ReportedGPA
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		cols := scraper.HeaderColumns(table)
+		semester, hasSemester := cols["semester"]
+		ip, hasIP := cols["ip"]
+		if _, isCourses := cols["kode"]; !hasSemester || !hasIP || isCourses {
+			return
+		}
+		ipk, hasIPK := cols["ipk"]
+		sks, hasSKS := cols["sks"]
+
+		for _, cells := range scraper.GridRows(table.Find("tbody tr"), "td") {
+			g := ReportedGPA{
+				Semester: scraper.CellText(cells, semester),
+				IP:       parseDecimal(scraper.CellText(cells, ip)),
+			}
+			if hasIPK {
+				g.IPK = parseDecimal(scraper.CellText(cells, ipk))
+			}
+			if hasSKS {
+				if n, err := strconv.Atoi(scraper.CellText(cells, sks)); err == nil {
+					g.SKS = &n
+				}
+			}
+			// Skip footers such as "Total" that span every column.
+			if g.Semester != "" && g.Semester != scraper.CellText(cells, ip) {
+				reported = append(reported, g)
+			}
+		}
+	})
+	return reported
+}
+
+// Parses a number shown with a decimal point or, as SIX sometimes does, a
+// decimal comma. Returns nil if text is not a number.
+func parseDecimal(text string) *float64 {
+	f, err := strconv.ParseFloat(strings.Replace(text, ",", ".", 1), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+// A student's transcript as returned by /api/transcript.
+type Transcript struct {
+	StudentID string `json:"student_id"`
+	// IPK and SKSIPK are the cumulative grade point average and the SKS it
+	// covers, counting retaken courses once.
+	IPK     float64            `json:"ipk"`
+	SKSIPK  int                `json:"sks_ipk"`
+	Courses []TranscriptCourse `json:"courses"`
+}
+
+// GET /api/transcript?student_id=...
+func (s *Server) transcriptHandler(w http.ResponseWriter, r *http.Request) {
+	release, ok := admitUpstream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	studentID := r.URL.Query().Get("student_id")
+	courses, err := s.provider.FetchTranscript(r, studentID)
+	if err != nil {
+		writeUpstreamError(w, r, err)
+		return
+	}
+	t := Transcript{StudentID: studentID, Courses: courses}
+	if t.Courses == nil {
+		t.Courses = []TranscriptCourse{}
+	}
+	t.IPK, t.SKSIPK = cumulativeGPA(courses)
+	writeSuccess(w, t)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTranscriptHTML = `<html><body>
+<table class="table">
+  <thead><tr><th>No</th><th>Kode</th><th>Nama Mata Kuliah</th><th>SKS</th><th>Semester</th><th>Nilai</th></tr></thead>
+  <tbody>
+    <tr><td>1</td><td>MA1101</td><td>Matematika  IA</td><td>4</td><td>2023-1</td><td>A</td></tr>
+    <tr><td>2</td><td>FI1101</td><td>Fisika Dasar IA</td><td>4</td><td>2023-1</td><td>e</td></tr>
+    <tr><td>3</td><td>IF2211</td><td>Strategi Algoritma</td><td>3</td><td>2025-2</td><td></td></tr>
+    <tr><td>4</td><td></td><td>Baris kosong</td><td>2</td><td></td><td>B</td></tr>
+  </tbody>
+</table>
+<table class="table">
+  <thead><tr><th>Semester</th><th>IP</th></tr></thead>
+  <tbody><tr><td>2023-1</td><td>3.50</td></tr></tbody>
+</table>
+</body></html>`
+
+func TestParseTranscript(t *testing.T) {
+	got := parseTranscript(docFromHTML(testTranscriptHTML))
+	want := []TranscriptCourse{
+		{Code: "MA1101", Name: "Matematika IA", SKS: 4, Semester: "2023-1", Grade: "A"},
+		{Code: "FI1101", Name: "Fisika Dasar IA", SKS: 4, Semester: "2023-1", Grade: "E"},
+		{Code: "IF2211", Name: "Strategi Algoritma", SKS: 3, Semester: "2025-2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseReportedGPA(t *testing.T) {
+	doc := docFromHTML(`<table>
+  <thead><tr><th>Semester</th><th>SKS</th><th>IP</th><th>IPK</th></tr></thead>
+  <tbody>
+    <tr><td>2023-1</td><td>20</td><td>3,50</td><td>3,50</td></tr>
+    <tr><td>2023-2</td><td>-</td><td>NaN</td><td></td></tr>
+    <tr><td colspan="4">Total</td></tr>
+  </tbody>
+</table>`)
+	got := parseReportedGPA(doc)
+	if len(got) != 2 {
+		t.Fatalf("got %d semesters, want 2: %+v", len(got), got)
+	}
+	if g := got[0]; g.Semester != "2023-1" || g.IP == nil || *g.IP != 3.5 || g.IPK == nil || *g.IPK != 3.5 || g.SKS == nil || *g.SKS != 20 {
+		t.Errorf("2023-1 = %+v", g)
+	}
+	if g := got[1]; g.IP != nil || g.IPK != nil || g.SKS != nil {
+		t.Errorf("blank values = %+v, want nil", g)
+	}
+}
+
+func TestTranscriptCourse_Passed(t *testing.T) {
+	for grade, want := range map[string]bool{"A": true, "BC": true, "D": true, "E": false, "": false, "T": false} {
+		if got := (TranscriptCourse{Grade: grade}).passed(); got != want {
+			t.Errorf("passed(%q) = %v, want %v", grade, got, want)
+		}
+	}
+}
+
+func TestTranscriptHandler(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/mahasiswa:123/akademik/transkrip") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, testTranscriptHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/transcript?student_id=123", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	got := decodeData[Transcript](t, w)
+	if got.StudentID != "123" || len(got.Courses) != 3 || got.Courses[1].Grade != "E" {
+		t.Errorf("transcript = %+v", got)
+	}
+	if got.IPK != 2 || got.SKSIPK != 8 {
+		t.Errorf("ipk = %v over %d sks, want 2 over 8", got.IPK, got.SKSIPK)
+	}
+
+	req = httptest.NewRequest("GET", "/api/transcript?student_id=abc", nil)
+	addAuthCookies(req)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("bad student_id: got status %d, want 422", w.Code)
+	}
+}
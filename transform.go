@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"six-scraper-go/scraper"
+)
+
+// A Transformer rewrites parsed classes before they are encoded: filtering,
+// translating, or normalizing them. Transformers must not modify their
+// input, which may be shared with the cache.
+type Transformer interface {
+	Transform(classes []CourseClass) []CourseClass
+}
+
+type transformFunc func([]CourseClass) []CourseClass
+
+func (f transformFunc) Transform(classes []CourseClass) []CourseClass { return f(classes) }
+
+// A query parameter that adds a transformer to a request's pipeline. To add
+// a view, add an entry to transformParams; it is documented in the OpenAPI
+// description and validated like any other parameter.
+type transformParam struct {
+	Parameter
+	build func(value string) (Transformer, error)
+}
+
+// Transform parameters, in the order their transformers run. Filters come
+// first so translations see only the classes that are kept.
+var transformParams = []transformParam{
+	{Parameter{Name: "code", In: "query", Description: "Only courses whose code starts with one of these comma-separated prefixes, e.g. IF,MA1101", Schema: &Schema{Type: "string"}}, codeFilter},
+	{Parameter{Name: "prefix", In: "query", Description: "Only courses of these comma-separated programs, the letters of the code, e.g. IF,MA", Schema: &Schema{Type: "string"}}, prefixFilter},
+	{Parameter{Name: "level", In: "query", Description: "Only courses at these comma-separated levels, the first digit of the code, e.g. 3,4", Schema: &Schema{Type: "string"}}, levelFilter},
+	{Parameter{Name: "day", In: "query", Description: "Only meetings on these comma-separated days, in Indonesian or English", Schema: &Schema{Type: "string"}}, dayFilter},
+	{Parameter{Name: "method", In: "query", Description: "Only online or only offline meetings", Schema: &Schema{Type: "string", Enum: []string{"online", "offline"}}}, methodFilter},
+	{Parameter{Name: "lang", In: "query", Description: "Language of day and activity names: id (default) or en", Schema: &Schema{Type: "string", Enum: []string{"id", "en"}}}, translation},
+}
+
+// The fields parameter projects classes onto a subset of their fields. It
+// runs last, after every transformer, since it changes the encoded shape.
+var fieldsParam = Parameter{Name: "fields", In: "query", Description: "Comma-separated class fields to return, e.g. code,name,schedules", Schema: &Schema{Type: "string"}}
+
+// Fields fields may select: the JSON names of CourseClass.
+var classFields = []string{"code", "name", "sks", "class_no", "quota", "lecturers", "notes", "notes_html", "schedules", "code_parts", "eligible", "missing_prerequisites"}
+
+// The notes parameter picks whether classes keep notes_html. It is left out
+// unless asked for, so clients that only know notes see no change.
+var notesParam = Parameter{Name: "notes", In: "query", Description: "html to also return notes_html, the notes as sanitized HTML with links and bold text kept; text (default) returns plain notes only", Schema: &Schema{Type: "string", Enum: []string{"text", "html"}}}
+
+// The parameters that build a pipeline, for OpenAPI operations.
+func pipelineParams() []Parameter {
+	params := make([]Parameter, 0, len(transformParams)+2)
+	for _, tp := range transformParams {
+		params = append(params, tp.Parameter)
+	}
+	return append(params, notesParam, fieldsParam)
+}
+
+// The transformers and projection a request asked for.
+type pipeline struct {
+	steps     []Transformer
+	fields    []string // nil keeps every field
+	notesHTML bool
+}
+
+// Builds the pipeline from the transform parameters in query.
+func newPipeline(query url.Values) (pipeline, error) {
+	var p pipeline
+	for _, tp := range transformParams {
+		value := strings.TrimSpace(query.Get(tp.Name))
+		if value == "" {
+			continue
+		}
+		t, err := tp.build(value)
+		if err != nil {
+			return pipeline{}, fmt.Errorf("%s: %w", tp.Name, err)
+		}
+		p.steps = append(p.steps, t)
+	}
+	switch query.Get("notes") {
+	case "", "text":
+	case "html":
+		p.notesHTML = true
+	default:
+		return pipeline{}, fmt.Errorf("notes: must be text or html")
+	}
+	if value := query.Get("fields"); value != "" {
+		for _, f := range splitList(value) {
+			if !slices.Contains(classFields, f) {
+				return pipeline{}, fmt.Errorf("fields: unknown field %q", f)
+			}
+			p.fields = append(p.fields, f)
+		}
+	}
+	return p, nil
+}
+
+func (p pipeline) apply(classes []CourseClass) []CourseClass {
+	for _, t := range p.steps {
+		classes = t.Transform(classes)
+	}
+	return classes
+}
+
+// Returns classes as they should be encoded: as is, or projected onto the
+// requested fields.
+func (p pipeline) view(classes []CourseClass) any {
+	if !p.notesHTML {
+		classes = withoutNotesHTML(classes)
+	}
+	if p.fields == nil {
+		return classes
+	}
+	views := make([]map[string]any, len(classes))
+	for i, c := range classes {
+		views[i] = p.project(c)
+	}
+	return views
+}
+
+func (p pipeline) project(c CourseClass) map[string]any {
+	var all map[string]any
+	data, _ := json.Marshal(c)
+	json.Unmarshal(data, &all)
+	view := make(map[string]any, len(p.fields))
+	for _, f := range p.fields {
+		view[f] = all[f]
+	}
+	return view
+}
+
+// Like view, for a single class.
+func (p pipeline) viewOne(c CourseClass) any {
+	if !p.notesHTML {
+		c.NotesHTML = ""
+	}
+	if p.fields == nil {
+		return c
+	}
+	return p.project(c)
+}
+
+// Returns classes without NotesHTML, copying them only if any has it.
+func withoutNotesHTML(classes []CourseClass) []CourseClass {
+	if !slices.ContainsFunc(classes, func(c CourseClass) bool { return c.NotesHTML != "" }) {
+		return classes
+	}
+	out := slices.Clone(classes)
+	for i := range out {
+		out[i].NotesHTML = ""
+	}
+	return out
+}
+
+// Splits a comma-separated list, dropping blanks.
+func splitList(s string) []string {
+	var out []string
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func codeFilter(value string) (Transformer, error) {
+	prefixes := splitList(strings.ToUpper(value))
+	return transformFunc(func(classes []CourseClass) []CourseClass {
+		kept := []CourseClass{}
+		for _, c := range classes {
+			if slices.ContainsFunc(prefixes, func(p string) bool { return strings.HasPrefix(strings.ToUpper(c.Code), p) }) {
+				kept = append(kept, c)
+			}
+		}
+		return kept
+	}), nil
+}
+
+// Keeps the classes whose code parses and passes keep. The code is parsed
+// again rather than read from CodeParts, which older snapshots lack.
+func filterCodes(classes []CourseClass, keep func(scraper.CodeParts) bool) []CourseClass {
+	kept := []CourseClass{}
+	for _, c := range classes {
+		if parts, ok := scraper.ParseCourseCode(c.Code); ok && keep(parts) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func prefixFilter(value string) (Transformer, error) {
+	prefixes := splitList(strings.ToUpper(value))
+	return transformFunc(func(classes []CourseClass) []CourseClass {
+		return filterCodes(classes, func(p scraper.CodeParts) bool { return slices.Contains(prefixes, p.Prefix) })
+	}), nil
+}
+
+func levelFilter(value string) (Transformer, error) {
+	var levels []int
+	for _, l := range splitList(value) {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 || n > 9 {
+			return nil, fmt.Errorf("level %q is not a digit", l)
+		}
+		levels = append(levels, n)
+	}
+	return transformFunc(func(classes []CourseClass) []CourseClass {
+		return filterCodes(classes, func(p scraper.CodeParts) bool { return slices.Contains(levels, p.Level) })
+	}), nil
+}
+
+// Day names in week order.
+var (
+	indonesianDays = []string{"Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu", "Minggu"}
+	englishDays    = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+)
+
+// Returns the Indonesian name of a day given in either language.
+func indonesianDay(day string) (string, bool) {
+	for i, name := range indonesianDays {
+		if strings.EqualFold(day, name) || strings.EqualFold(day, englishDays[i]) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Keeps the classes that have a meeting passing keep, with only those
+// meetings.
+func filterMeetings(classes []CourseClass, keep func(ScheduleEntry) bool) []CourseClass {
+	kept := []CourseClass{}
+	for _, c := range classes {
+		meetings := []ScheduleEntry{}
+		for _, e := range c.Schedules {
+			if keep(e) {
+				meetings = append(meetings, e)
+			}
+		}
+		if len(meetings) > 0 {
+			c.Schedules = meetings
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func dayFilter(value string) (Transformer, error) {
+	var days []string
+	for _, d := range splitList(value) {
+		name, ok := indonesianDay(d)
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", d)
+		}
+		days = append(days, name)
+	}
+	return transformFunc(func(classes []CourseClass) []CourseClass {
+		return filterMeetings(classes, func(e ScheduleEntry) bool { return slices.Contains(days, e.Day) })
+	}), nil
+}
+
+func methodFilter(value string) (Transformer, error) {
+	return transformFunc(func(classes []CourseClass) []CourseClass {
+		return filterMeetings(classes, func(e ScheduleEntry) bool { return strings.EqualFold(e.Method, value) })
+	}), nil
+}
+
+// English names of the activities SIX lists.
+var englishActivities = map[string]string{
+	"Kuliah":    "Lecture",
+	"Praktikum": "Lab",
+	"Tutorial":  "Tutorial",
+	"Responsi":  "Recitation",
+	"Ujian":     "Exam",
+	"UTS":       "Midterm exam",
+	"UAS":       "Final exam",
+}
+
+func translation(value string) (Transformer, error) {
+	if value != "en" {
+		return transformFunc(func(classes []CourseClass) []CourseClass { return classes }), nil
+	}
+	return transformFunc(func(classes []CourseClass) []CourseClass {
+		out := make([]CourseClass, len(classes))
+		for i, c := range classes {
+			c.Schedules = slices.Clone(c.Schedules)
+			for j, e := range c.Schedules {
+				if d := slices.Index(indonesianDays, e.Day); d >= 0 {
+					c.Schedules[j].Day = englishDays[d]
+				}
+				if a, ok := englishActivities[e.Activity]; ok {
+					c.Schedules[j].Activity = a
+				}
+			}
+			out[i] = c
+		}
+		return out
+	}), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	classes := []CourseClass{
+		{Code: "IF2211", ClassNo: "01", Schedules: []ScheduleEntry{
+			{Day: "Senin", Activity: "Kuliah", Method: "Offline"},
+			{Day: "Rabu", Activity: "Praktikum", Method: "Online"},
+		}},
+		{Code: "MA1101", ClassNo: "01", Schedules: []ScheduleEntry{{Day: "Selasa", Method: "Offline"}}},
+	}
+	tests := []struct {
+		query string
+		want  []CourseClass
+	}{
+		{"code=if", classes[:1]},
+		{"prefix=ma,KU", classes[1:]},
+		{"level=2,3", classes[:1]},
+		{"prefix=IF&level=1", []CourseClass{}},
+		{"day=wednesday,Jumat", []CourseClass{{Code: "IF2211", ClassNo: "01", Schedules: []ScheduleEntry{{Day: "Rabu", Activity: "Praktikum", Method: "Online"}}}}},
+		{"method=offline&lang=en", []CourseClass{
+			{Code: "IF2211", ClassNo: "01", Schedules: []ScheduleEntry{{Day: "Monday", Activity: "Lecture", Method: "Offline"}}},
+			{Code: "MA1101", ClassNo: "01", Schedules: []ScheduleEntry{{Day: "Tuesday", Method: "Offline"}}},
+		}},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		p, err := newPipeline(q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := p.apply(classes); !sameClasses(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+	// The input, which may be cached, is left alone.
+	if classes[0].Schedules[0].Day != "Senin" || len(classes[0].Schedules) != 2 {
+		t.Errorf("input modified: %+v", classes[0])
+	}
+
+	for _, query := range []string{"day=someday", "fields=code,grade", "level=2x"} {
+		q, _ := url.ParseQuery(query)
+		if _, err := newPipeline(q); err == nil {
+			t.Errorf("%s: no error", query)
+		}
+	}
+}
+
+func TestScheduleHandler_Fields(t *testing.T) {
+	mock := mockSIX("123", "1945-1")
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/schedule?student_id=123&semester=1945-1&code=FI1220&fields=code,quota", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	got := decodeData[[]map[string]any](t, w)
+	if len(got) != 1 || len(got[0]) != 2 || got[0]["code"] != "FI1220" || got[0]["quota"] != float64(40) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestSchedule_NotesHTMLOnRequest(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><table class="table"><tbody>
+<tr><td>1</td><td></td><td>IF2211</td><td>Strategi Algoritma</td><td>3</td><td>01</td><td>60</td><td></td>
+<td>Materi di <a href="https://edunex.itb.ac.id">Edunex</a></td><td></td></tr>
+</tbody></table></body></html>`)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	get := func(query string) []CourseClass {
+		req := httptest.NewRequest("GET", "/api/schedule?student_id=13520001&semester=2024-2"+query, nil)
+		addAuthCookies(req)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body)
+		}
+		return decodeData[[]CourseClass](t, w)
+	}
+	if c := get(""); c[0].Notes != "Materi di Edunex" || c[0].NotesHTML != "" {
+		t.Errorf("default = %+v", c[0])
+	}
+	if c := get("&notes=html"); !strings.Contains(c[0].NotesHTML, `<a href="https://edunex.itb.ac.id"`) || c[0].Notes != "Materi di Edunex" {
+		t.Errorf("notes=html = %+v", c[0])
+	}
+}
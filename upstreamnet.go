@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Campus networks sometimes resolve six.itb.ac.id to an internal address
+// that only works from inside, or give it an IPv6 address that does not
+// route. SIX_IP_VERSION forces connections to SIX over "4" or "6".
+// SIX_RESOLVE overrides the address a host resolves to, as comma-separated
+// "host=address" pairs such as "six.itb.ac.id=10.10.1.5", for split-horizon
+// DNS. The TLS server name stays the host, so the certificate is still
+// checked against it. Both apply to the official API's host as well.
+var (
+	upstreamIPVersion = envString("SIX_IP_VERSION", "")
+	upstreamResolve   = envList("SIX_RESOLVE", nil)
+)
+
+// How connections to upstream hosts are made.
+type upstreamOptions struct {
+	caBundle  string
+	pins      []string
+	ipVersion string            // "4", "6", or "" for either
+	resolve   map[string]string // lowercased host to IP address
+}
+
+// Reads the upstream options from the environment.
+func upstreamOptionsFromEnv() (upstreamOptions, error) {
+	opts := upstreamOptions{caBundle: caBundlePath, pins: tlsPins, ipVersion: upstreamIPVersion}
+	if opts.ipVersion != "" && opts.ipVersion != "4" && opts.ipVersion != "6" {
+		return opts, fmt.Errorf("SIX_IP_VERSION: %q is not 4 or 6", opts.ipVersion)
+	}
+	resolve, err := parseResolveOverrides(upstreamResolve, opts.ipVersion)
+	if err != nil {
+		return opts, err
+	}
+	opts.resolve = resolve
+	return opts, nil
+}
+
+// Parses "host=address" pairs. Each address must be an IP of ipVersion, if
+// one is forced.
+func parseResolveOverrides(pairs []string, ipVersion string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	resolve := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		host, addr, _ := strings.Cut(pair, "=")
+		host, addr = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(addr)
+		ip := net.ParseIP(addr)
+		switch {
+		case host == "" || ip == nil:
+			return nil, fmt.Errorf("SIX_RESOLVE: %q is not host=address", pair)
+		case ipVersion == "4" && ip.To4() == nil, ipVersion == "6" && ip.To4() != nil:
+			return nil, fmt.Errorf("SIX_RESOLVE: %s is not an IPv%s address", addr, ipVersion)
+		}
+		resolve[host] = ip.String()
+	}
+	return resolve, nil
+}
+
+// Returns the transport for requests to SIX under SIX_CA_BUNDLE,
+// SIX_TLS_PINS, SIX_IP_VERSION, and SIX_RESOLVE. A setting that cannot be
+// used is an error rather than ignored, so a misconfigured pin never
+// quietly turns pinning off.
+func upstreamTransport() (http.RoundTripper, error) {
+	opts, err := upstreamOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return newUpstreamTransport(opts)
+}
+
+func newUpstreamTransport(opts upstreamOptions) (http.RoundTripper, error) {
+	tlsConfig, err := upstreamTLSConfig(opts.caBundle, opts.pins)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil && opts.ipVersion == "" && len(opts.resolve) == 0 {
+		return http.DefaultTransport, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if opts.ipVersion != "" || len(opts.resolve) > 0 {
+		transport.DialContext = opts.dialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	}
+	return transport, nil
+}
+
+// Returns a DialContext that dials with dialer over the forced IP version,
+// to the overridden address of a host that has one. Behind an HTTP proxy,
+// this dials the proxy, which resolves the upstream host itself.
+func (o upstreamOptions) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, o.network(network), o.address(addr))
+	}
+}
+
+// Returns network, such as "tcp", narrowed to the forced IP version.
+func (o upstreamOptions) network(network string) string {
+	if o.ipVersion != "" && (network == "tcp" || network == "udp") {
+		return network + o.ipVersion
+	}
+	return network
+}
+
+// Returns addr, a host and port, with the host's overridden address.
+func (o upstreamOptions) address(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip, ok := o.resolve[strings.ToLower(host)]; ok {
+		return net.JoinHostPort(ip, port)
+	}
+	return addr
+}
+
+// How long the connectivity check waits for each address.
+const connectivityTimeout = 5 * time.Second
+
+// Checks that the host of baseURL can be reached under opts: resolves it,
+// or takes its override, and opens a TCP connection to each address.
+// Returns what was tried, the addresses that could not be reached, and an
+// error if none could. It does not go through the upstream queue, as it
+// sends no request.
+func checkConnectivity(ctx context.Context, baseURL string, opts upstreamOptions) (reachable int, detail string, problems []string, err error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Hostname() == "" {
+		return 0, "", nil, fmt.Errorf("base URL %q has no host", baseURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+
+	var addrs []string
+	source := "DNS"
+	if ip, ok := opts.resolve[strings.ToLower(u.Hostname())]; ok {
+		addrs, source = []string{ip}, "SIX_RESOLVE"
+	} else if ip := net.ParseIP(u.Hostname()); ip != nil {
+		addrs, source = []string{ip.String()}, "URL"
+	} else {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip"+opts.ipVersion, u.Hostname())
+		if err != nil {
+			return 0, "", nil, fmt.Errorf("resolve %s: %w", u.Hostname(), err)
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, ip.String())
+		}
+	}
+	detail = fmt.Sprintf("%s -> %s (%s)", u.Hostname(), strings.Join(addrs, ", "), source)
+
+	dialer := &net.Dialer{Timeout: connectivityTimeout}
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, opts.network("tcp"), net.JoinHostPort(addr, port))
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		conn.Close()
+		reachable++
+	}
+	if reachable == 0 {
+		return 0, detail, problems, fmt.Errorf("none of %d addresses of %s is reachable on port %s", len(addrs), u.Hostname(), port)
+	}
+	return reachable, detail, problems, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseResolveOverrides(t *testing.T) {
+	resolve, err := parseResolveOverrides([]string{"SIX.itb.ac.id = 10.10.1.5", "api.example=2001:db8::1"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolve["six.itb.ac.id"] != "10.10.1.5" || resolve["api.example"] != "2001:db8::1" {
+		t.Errorf("got %v", resolve)
+	}
+	for _, tc := range []struct {
+		pair, version string
+	}{
+		{"six.itb.ac.id", ""},
+		{"six.itb.ac.id=internal.itb.ac.id", ""},
+		{"=10.10.1.5", ""},
+		{"six.itb.ac.id=2001:db8::1", "4"},
+		{"six.itb.ac.id=10.10.1.5", "6"},
+	} {
+		if _, err := parseResolveOverrides([]string{tc.pair}, tc.version); err == nil {
+			t.Errorf("%q with IPv%q: want an error", tc.pair, tc.version)
+		}
+	}
+}
+
+func TestUpstreamOptions_Dial(t *testing.T) {
+	opts := upstreamOptions{ipVersion: "4", resolve: map[string]string{"six.example": "127.0.0.1"}}
+	if got := opts.network("tcp"); got != "tcp4" {
+		t.Errorf("network = %q, want tcp4", got)
+	}
+	if got := opts.address("SIX.example:443"); got != "127.0.0.1:443" {
+		t.Errorf("address = %q", got)
+	}
+	if got := opts.address("other.example:443"); got != "other.example:443" {
+		t.Errorf("address of a host without override = %q", got)
+	}
+
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer mock.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(mock.URL, "http://"))
+	transport, err := newUpstreamTransport(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://six.example:" + port + "/")
+	if err != nil {
+		t.Fatalf("overridden host: %v", err)
+	}
+	defer resp.Body.Close()
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	if body.String() != "six.example:"+port {
+		t.Errorf("Host = %q, want the overridden host kept", body.String())
+	}
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	mock := httptest.NewServer(http.NotFoundHandler())
+	defer mock.Close()
+	u, _ := url.Parse(mock.URL)
+
+	reachable, detail, problems, err := checkConnectivity(context.Background(), mock.URL, upstreamOptions{})
+	if err != nil || reachable != 1 || len(problems) != 0 || !strings.Contains(detail, "(URL)") {
+		t.Errorf("mock: %d %q %v %v", reachable, detail, problems, err)
+	}
+
+	opts := upstreamOptions{resolve: map[string]string{"six.example": u.Hostname()}}
+	reachable, detail, _, err = checkConnectivity(context.Background(), "http://six.example:"+u.Port(), opts)
+	if err != nil || reachable != 1 || detail != "six.example -> 127.0.0.1 (SIX_RESOLVE)" {
+		t.Errorf("override: %d %q %v", reachable, detail, err)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	var out bytes.Buffer
+	passed := selfTestConnectivity(context.Background(), upstreamOptions{}, []string{mock.URL, closed.URL}, func(res SelfTestResult) {
+		printSelfTestResult(&out, res)
+	})
+	if passed {
+		t.Error("passed with an unreachable host")
+	}
+	for _, want := range []string{"connect " + mock.URL + "\tPASS", "connect " + closed.URL + "\tFAIL"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
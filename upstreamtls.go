@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Connections to SIX, and to the official API, are verified against the
+// system's CAs. Some campus networks intercept TLS with a proxy whose CA is
+// not among them: SIX_CA_BUNDLE names a PEM file of CAs to trust besides the
+// system's. SIX_TLS_PINS pins the certificates SIX may present, as
+// comma-separated SHA-256 hashes of a SubjectPublicKeyInfo in base64, e.g.
+// "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=". After the usual
+// verification, some certificate in the chain must match a pin, whether
+// SIX's own, an intermediate, or a proxy's CA.
+var (
+	caBundlePath = envString("SIX_CA_BUNDLE", "")
+	tlsPins      = envList("SIX_TLS_PINS", nil)
+)
+
+// Returns the TLS config for connections to SIX under caBundle and pins, or
+// nil for Go's defaults when neither is set.
+func upstreamTLSConfig(caBundle string, pins []string) (*tls.Config, error) {
+	if caBundle == "" && len(pins) == 0 {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("SIX_CA_BUNDLE: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("SIX_CA_BUNDLE: no PEM certificates in %s", caBundle)
+		}
+		cfg.RootCAs = roots
+	}
+	if len(pins) > 0 {
+		hashes := make([][]byte, len(pins))
+		for i, pin := range pins {
+			hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+			if err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("SIX_TLS_PINS: %q is not a base64 SHA-256 hash", pin)
+			}
+			hashes[i] = hash
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, hashes)
+		}
+	}
+	return cfg, nil
+}
+
+// Returns the pin of a certificate: the base64 SHA-256 hash of its public key.
+func certificatePin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// A verified chain with no pinned certificate in it.
+type pinMismatchError struct {
+	host string
+	pin  string // of the leaf, to pin it if SIX rotated its key on purpose
+}
+
+func (e *pinMismatchError) Error() string {
+	return fmt.Sprintf("tls: no certificate presented by %s matches SIX_TLS_PINS (its certificate is %s)", e.host, e.pin)
+}
+
+func verifyPins(cs tls.ConnectionState, hashes [][]byte) error {
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if slices.ContainsFunc(hashes, func(h []byte) bool { return string(h) == string(hash[:]) }) {
+				return nil
+			}
+		}
+	}
+	err := &pinMismatchError{host: cs.ServerName}
+	if len(cs.PeerCertificates) > 0 {
+		err.pin = certificatePin(cs.PeerCertificates[0])
+	}
+	return err
+}
+
+// Reports whether err is a failed TLS connection to SIX: a certificate that
+// is untrusted, expired, for another host, or not pinned, or a peer that
+// does not speak TLS. Retrying does not help with these, and they usually
+// mean a proxy in the way or a wrong SIX_CA_BUNDLE or SIX_TLS_PINS.
+func isUpstreamTLSError(err error) bool {
+	var (
+		verify   *tls.CertificateVerificationError
+		unknown  x509.UnknownAuthorityError
+		invalid  x509.CertificateInvalidError
+		hostname x509.HostnameError
+		record   tls.RecordHeaderError
+		alert    tls.AlertError
+		pin      *pinMismatchError
+	)
+	return errors.As(err, &verify) || errors.As(err, &unknown) || errors.As(err, &invalid) ||
+		errors.As(err, &hostname) || errors.As(err, &record) || errors.As(err, &alert) || errors.As(err, &pin)
+}
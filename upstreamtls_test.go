@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes the certificate of a TLS test server to a PEM file, as a campus
+// proxy's CA would be.
+func writeCABundle(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUpstreamTransport(t *testing.T) {
+	mock := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer mock.Close()
+	bundle := writeCABundle(t, mock)
+	pin := certificatePin(mock.Certificate())
+	otherPin := "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	get := func(transport http.RoundTripper) error {
+		resp, err := (&http.Client{Transport: transport}).Get(mock.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	transport, err := newUpstreamTransport(upstreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(transport); !isUpstreamTLSError(err) {
+		t.Errorf("system CAs only: got %v, want a TLS error", err)
+	}
+	for _, pins := range [][]string{nil, {pin}, {otherPin, pin}} {
+		transport, err := newUpstreamTransport(upstreamOptions{caBundle: bundle, pins: pins})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := get(transport); err != nil {
+			t.Errorf("bundle, pins %v: %v", pins, err)
+		}
+	}
+
+	transport, err = newUpstreamTransport(upstreamOptions{caBundle: bundle, pins: []string{otherPin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = get(transport)
+	var mismatch *pinMismatchError
+	if !errors.As(err, &mismatch) || !isUpstreamTLSError(err) {
+		t.Fatalf("wrong pin: got %v, want a pin mismatch", err)
+	}
+	if mismatch.pin != pin {
+		t.Errorf("mismatch names pin %s, want %s", mismatch.pin, pin)
+	}
+}
+
+func TestUpstreamTransport_BadConfig(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0o600)
+	for _, tc := range []struct {
+		bundle string
+		pins   []string
+	}{
+		{bundle: filepath.Join(t.TempDir(), "missing.pem")},
+		{bundle: empty},
+		{pins: []string{"sha256/not-base64!"}},
+		{pins: []string{"sha256/AAAA"}},
+	} {
+		if _, err := newUpstreamTransport(upstreamOptions{caBundle: tc.bundle, pins: tc.pins}); err == nil {
+			t.Errorf("bundle %q, pins %v: want an error", tc.bundle, tc.pins)
+		}
+	}
+}
+
+func TestUpstreamTLSError_Response(t *testing.T) {
+	requests := 0
+	mock := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+
+	req := httptest.NewRequest("GET", "/api/calendar", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	var resp APIResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusBadGateway || resp.Code != codeUpstreamTLS {
+		t.Errorf("got %d %q, want 502 %q", w.Code, resp.Code, codeUpstreamTLS)
+	}
+	if requests != 0 {
+		t.Errorf("mock served %d requests over an untrusted connection", requests)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"six-scraper-go/scraper"
+)
+
+// The fill history records how full each catalog class was over time, from
+// the catalog pages that show enrolled counts. The waitlist estimator uses
+// past semesters to guess how likely a full class is to reopen seats.
+var (
+	// Fewest past classes an estimate may rest on before widening from the
+	// same course to the same program, then to every course.
+	waitlistMinSamples = envInt("SIX_WAITLIST_MIN_SAMPLES", 10)
+	// How often the history is written to SIX_DATA_DIR at most.
+	fillSaveInterval = envDuration("SIX_FILL_SAVE_INTERVAL", time.Minute)
+)
+
+const fillHistoryFile = "fill-history.json"
+
+type fillObservation struct {
+	At       time.Time `json:"at"`
+	Enrolled int       `json:"enrolled"`
+	Quota    int       `json:"quota"`
+}
+
+// The fill of one class over a semester. Only changes are recorded.
+type fillRecord struct {
+	Semester     string            `json:"semester"`
+	Code         string            `json:"code"`
+	ClassNo      string            `json:"class_no"`
+	Observations []fillObservation `json:"observations"`
+}
+
+func (f *fillRecord) full(o fillObservation) bool { return o.Quota > 0 && o.Enrolled >= o.Quota }
+
+// Reports whether the class was ever full, and whether seats opened up
+// after it first was.
+func (f *fillRecord) outcome() (wasFull, reopened bool) {
+	for _, o := range f.Observations {
+		switch {
+		case f.full(o):
+			wasFull = true
+		case wasFull:
+			return true, true
+		}
+	}
+	return wasFull, false
+}
+
+type fillHistory struct {
+	mu      sync.Mutex
+	dir     string // empty keeps the history in memory only
+	records map[string]*fillRecord
+	savedAt time.Time
+}
+
+func newFillHistory(dir string) *fillHistory {
+	h := &fillHistory{dir: dir, records: make(map[string]*fillRecord)}
+	if dir == "" {
+		return h
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fillHistoryFile))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("fill history: load failed: %v", err)
+		}
+		return h
+	}
+	var records []*fillRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		log.Printf("fill history: load failed: %v", err)
+		return h
+	}
+	for _, r := range records {
+		h.records[fillKey(r.Semester, r.Code, r.ClassNo)] = r
+	}
+	return h
+}
+
+func fillKey(semester, code, classNo string) string {
+	return semester + "/" + strings.ToUpper(code) + "/" + classNo
+}
+
+// Records the enrolled counts on a stored catalog page. It is the catalog
+// cache's onStore hook, next to the search index.
+func (h *fillHistory) record(key string, entry cacheEntry) {
+	semester, _, _ := strings.Cut(key, "?")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := false
+	for _, c := range entry.data {
+		if c.Enrolled == nil {
+			continue
+		}
+		o := fillObservation{At: entry.fetchedAt, Enrolled: *c.Enrolled, Quota: c.Quota}
+		k := fillKey(semester, c.Code, c.ClassNo)
+		r, ok := h.records[k]
+		if !ok {
+			r = &fillRecord{Semester: semester, Code: strings.ToUpper(c.Code), ClassNo: c.ClassNo}
+			h.records[k] = r
+		}
+		if n := len(r.Observations); n > 0 {
+			last := r.Observations[n-1]
+			if !o.At.After(last.At) || (last.Enrolled == o.Enrolled && last.Quota == o.Quota) {
+				continue
+			}
+		}
+		r.Observations = append(r.Observations, o)
+		changed = true
+	}
+	if changed && h.dir != "" && time.Since(h.savedAt) >= fillSaveInterval {
+		if err := h.saveLocked(); err != nil {
+			log.Printf("fill history: save failed: %v", err)
+		}
+	}
+}
+
+// Writes the history to a temporary file and renames it into place.
+func (h *fillHistory) saveLocked() error {
+	records := make([]*fillRecord, 0, len(h.records))
+	for _, r := range h.records {
+		records = append(records, r)
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(h.dir, fillHistoryFile), data); err != nil {
+		return err
+	}
+	h.savedAt = time.Now()
+	return nil
+}
+
+const waitlistNote = "Heuristic: the share of similar classes in past semesters that reopened seats after filling up, as seen by this server. It is not a prediction from SIX and not a guarantee."
+
+type WaitlistEstimate struct {
+	Semester   string    `json:"semester"`
+	Code       string    `json:"code"`
+	ClassNo    string    `json:"class_no"`
+	Enrolled   int       `json:"enrolled"`
+	Quota      int       `json:"quota"`
+	Full       bool      `json:"full"`
+	ObservedAt time.Time `json:"observed_at"`
+	// Probability and its 95% band are nil when the class is not full or
+	// no past class can be compared.
+	Probability *float64 `json:"probability"`
+	Low         *float64 `json:"low"`
+	High        *float64 `json:"high"`
+	// Basis is which past classes were compared: "course" (same code),
+	// "prefix" (same program), or "all".
+	Basis      string `json:"basis,omitempty"`
+	Samples    int    `json:"samples"`
+	Reopened   int    `json:"reopened"`
+	Confidence string `json:"confidence,omitempty"` // low, medium, or high
+	Heuristic  bool   `json:"heuristic"`
+	Note       string `json:"note"`
+}
+
+// Estimates how likely a full class is to reopen seats. It compares classes
+// of earlier semesters that filled up, starting with the same course and
+// widening while there are fewer than waitlistMinSamples of them.
+func (h *fillHistory) estimate(semester, code, classNo string) (WaitlistEstimate, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	target, ok := h.records[fillKey(semester, code, classNo)]
+	if !ok || len(target.Observations) == 0 {
+		return WaitlistEstimate{}, false
+	}
+	last := target.Observations[len(target.Observations)-1]
+	est := WaitlistEstimate{
+		Semester: semester, Code: target.Code, ClassNo: classNo,
+		Enrolled: last.Enrolled, Quota: last.Quota, Full: target.full(last), ObservedAt: last.At,
+		Heuristic: true, Note: waitlistNote,
+	}
+	if !est.Full {
+		return est, true
+	}
+
+	parts, hasParts := scraper.ParseCourseCode(target.Code)
+	bases := []struct {
+		name  string
+		match func(*fillRecord) bool
+	}{
+		{"course", func(r *fillRecord) bool { return r.Code == target.Code }},
+		{"prefix", func(r *fillRecord) bool {
+			p, ok := scraper.ParseCourseCode(r.Code)
+			return hasParts && ok && p.Prefix == parts.Prefix
+		}},
+		{"all", func(*fillRecord) bool { return true }},
+	}
+	for _, b := range bases {
+		samples, reopened := 0, 0
+		for _, r := range h.records {
+			if r.Semester >= semester || !b.match(r) {
+				continue
+			}
+			if wasFull, again := r.outcome(); wasFull {
+				samples++
+				if again {
+					reopened++
+				}
+			}
+		}
+		est.Basis, est.Samples, est.Reopened = b.name, samples, reopened
+		if samples >= waitlistMinSamples {
+			break
+		}
+	}
+	if est.Samples == 0 {
+		est.Basis = ""
+		return est, true
+	}
+	p := float64(est.Reopened) / float64(est.Samples)
+	low, high := wilsonInterval(est.Reopened, est.Samples)
+	est.Probability, est.Low, est.High = roundedPtr(p), roundedPtr(low), roundedPtr(high)
+	switch {
+	case est.Samples < waitlistMinSamples:
+		est.Confidence = "low"
+	case est.Samples < 3*waitlistMinSamples:
+		est.Confidence = "medium"
+	default:
+		est.Confidence = "high"
+	}
+	return est, true
+}
+
+// The 95% Wilson score interval of k successes in n trials. Unlike the
+// normal approximation it stays within [0, 1] for small samples.
+func wilsonInterval(k, n int) (low, high float64) {
+	const z = 1.96
+	p, nf := float64(k)/float64(n), float64(n)
+	denom := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / denom
+	margin := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denom
+	return max(0, center-margin), min(1, center+margin)
+}
+
+func roundedPtr(v float64) *float64 {
+	v = math.Round(v*100) / 100
+	return &v
+}
+
+// GET /api/waitlist?semester=...&code=...&class_no=...
+func (s *Server) waitlistHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	semester, _ := s.semesters.resolve("", query.Get("semester"), time.Now())
+	est, ok := s.fill.estimate(semester, query.Get("code"), query.Get("class_no"))
+	if !ok {
+		writeError(w, r, codeClassNotFound)
+		return
+	}
+	writeSuccess(w, est)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Stores catalog pages of one class, one per fill, an hour apart.
+func recordFills(h *fillHistory, semester, code string, fills ...int) {
+	start := time.Date(2025, 1, 6, 8, 0, 0, 0, wib)
+	for i, n := range fills {
+		enrolled := n
+		h.record(semester+"?prodi=135", cacheEntry{
+			data:      []CourseClass{{Code: code, ClassNo: "01", Quota: 40, Enrolled: &enrolled}},
+			fetchedAt: start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+}
+
+func TestFillHistory_Estimate(t *testing.T) {
+	old := waitlistMinSamples
+	waitlistMinSamples = 3
+	t.Cleanup(func() { waitlistMinSamples = old })
+	h := newFillHistory("")
+
+	// Two past IF2211 classes filled up; one reopened.
+	recordFills(h, "2024-2", "IF2211", 30, 40, 39)
+	recordFills(h, "2023-2", "IF2211", 40, 40, 40)
+	// Other IF courses, and one that never filled.
+	recordFills(h, "2024-2", "IF2230", 40, 38)
+	recordFills(h, "2024-2", "IF2240", 40)
+	recordFills(h, "2024-2", "IF2250", 20, 25)
+	recordFills(h, "2024-2", "MA1101", 40, 35)
+	recordFills(h, "2025-2", "IF2211", 35, 40)
+
+	if got := len(h.records[fillKey("2023-2", "IF2211", "01")].Observations); got != 1 {
+		t.Errorf("unchanged fills were recorded: %d observations", got)
+	}
+
+	est, ok := h.estimate("2025-2", "if2211", "01")
+	if !ok || !est.Full || est.Enrolled != 40 || !est.Heuristic || est.Note == "" {
+		t.Fatalf("estimate = %+v, %v", est, ok)
+	}
+	// Only two IF2211 samples, so it widens to the IF program: four full
+	// classes, two reopened.
+	if est.Basis != "prefix" || est.Samples != 4 || est.Reopened != 2 || *est.Probability != 0.5 || est.Confidence != "medium" {
+		t.Errorf("estimate = %+v", est)
+	}
+	if !(*est.Low < 0.5 && *est.High > 0.5 && *est.Low >= 0 && *est.High <= 1) {
+		t.Errorf("band = [%v, %v]", *est.Low, *est.High)
+	}
+
+	recordFills(h, "2025-2", "IF2250", 20)
+	if est, _ := h.estimate("2025-2", "IF2250", "01"); est.Full || est.Probability != nil {
+		t.Errorf("open class = %+v", est)
+	}
+	if _, ok := h.estimate("2025-2", "IF9999", "01"); ok {
+		t.Error("estimate for an unknown class")
+	}
+}
+
+func TestWilsonInterval(t *testing.T) {
+	low, high := wilsonInterval(0, 5)
+	if low != 0 || high < 0.4 || high > 0.45 {
+		t.Errorf("0 of 5 = [%v, %v]", low, high)
+	}
+	low, high = wilsonInterval(50, 100)
+	if low < 0.40 || low > 0.41 || high < 0.59 || high > 0.60 {
+		t.Errorf("50 of 100 = [%v, %v]", low, high)
+	}
+}
+
+func TestFillHistory_Persists(t *testing.T) {
+	dir := t.TempDir()
+	h := newFillHistory(dir)
+	recordFills(h, "2024-2", "IF2211", 40, 39)
+	if got := newFillHistory(dir).records[fillKey("2024-2", "IF2211", "01")]; got == nil || len(got.Observations) == 0 {
+		t.Errorf("reloaded record = %+v", got)
+	}
+}
+
+func TestWaitlistHandler(t *testing.T) {
+	srv := newTestServer("")
+	enrolled := 45
+	srv.catalog.set("2025-2?prodi=102", []CourseClass{{Code: "FI1210", ClassNo: "01", Quota: 45, Enrolled: &enrolled}}, time.Now())
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/waitlist?semester=2025-2&code=FI1210&class_no=01", nil))
+	est := decodeData[WaitlistEstimate](t, w)
+	if !est.Full || est.Probability != nil || est.Samples != 0 {
+		t.Errorf("estimate without history = %+v", est)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/waitlist?semester=2025-2&code=FI1220&class_no=02", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown class: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The catalog warmer keeps the catalog pages of chosen faculties fresh by
+// scraping them on a schedule with a service account's cookies, so students
+// get catalog hits instead of waiting on SIX. Each faculty has its own
+// cadence and a shorter one for FRS periods, when the catalog changes by the
+// hour, e.g. SIX_WARM_FAKULTAS="FTMD=24h/1h,STEI=12h,FMIPA".
+var (
+	warmSchedules = parseWarmSchedules(envString("SIX_WARM_FAKULTAS", ""))
+	warmInterval  = envDuration("SIX_WARM_INTERVAL", 24*time.Hour)
+	warmCookies   = envString("SIX_WARM_COOKIES", "") // Cookie header value of the service account
+	warmStudentID = envString("SIX_WARM_STUDENT_ID", "")
+	// FRS (course registration) periods as inclusive WIB dates, e.g.
+	// "2026-01-05..2026-01-16,2026-07-27..2026-08-07". They override the
+	// periods of the academic calendar (see academicCalendar).
+	frsPeriods = parseFRSPeriods(envString("SIX_FRS_PERIODS", ""))
+)
+
+// How often the warmer checks for due faculties.
+const warmTick = time.Minute
+
+type warmSchedule struct {
+	fakultas    string
+	interval    time.Duration // zero uses warmInterval
+	frsInterval time.Duration // zero uses interval
+}
+
+// Parses "FTMD=24h/1h,STEI=12h,FMIPA": a faculty, then optionally its
+// interval, then optionally its interval during FRS. Invalid entries are
+// logged and skipped.
+func parseWarmSchedules(spec string) []warmSchedule {
+	var schedules []warmSchedule
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fakultas, intervals, _ := strings.Cut(entry, "=")
+		ws := warmSchedule{fakultas: strings.TrimSpace(fakultas)}
+		normal, frs, _ := strings.Cut(intervals, "/")
+		var err error
+		if normal = strings.TrimSpace(normal); normal != "" {
+			ws.interval, err = time.ParseDuration(normal)
+		}
+		if frs = strings.TrimSpace(frs); err == nil && frs != "" {
+			ws.frsInterval, err = time.ParseDuration(frs)
+		}
+		if ws.fakultas == "" || err != nil || ws.interval < 0 || ws.frsInterval < 0 {
+			log.Printf("config: invalid SIX_WARM_FAKULTAS entry %q, skipping", entry)
+			continue
+		}
+		schedules = append(schedules, ws)
+	}
+	return schedules
+}
+
+// A range of WIB days, from start up to but not including end.
+type dateRange struct {
+	start, end time.Time
+}
+
+func (d dateRange) contains(t time.Time) bool {
+	return !t.Before(d.start) && t.Before(d.end)
+}
+
+// Parses "2026-01-05..2026-01-16,...". Both dates are inclusive. Invalid
+// ranges are logged and skipped.
+func parseFRSPeriods(spec string) []dateRange {
+	var periods []dateRange
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		from, to, _ := strings.Cut(entry, "..")
+		start, err1 := time.ParseInLocation(time.DateOnly, strings.TrimSpace(from), wib)
+		end, err2 := time.ParseInLocation(time.DateOnly, strings.TrimSpace(to), wib)
+		if err1 != nil || err2 != nil || end.Before(start) {
+			log.Printf("config: invalid SIX_FRS_PERIODS entry %q, skipping", entry)
+			continue
+		}
+		periods = append(periods, dateRange{start: start, end: end.AddDate(0, 0, 1)})
+	}
+	return periods
+}
+
+// Reports whether t falls in one of the FRS periods.
+func inFRS(periods []dateRange, t time.Time) bool {
+	for _, p := range periods {
+		if p.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns how often the faculty is refreshed at now.
+func (ws warmSchedule) cadence(periods []dateRange, now time.Time) time.Duration {
+	interval := cmp.Or(ws.interval, warmInterval)
+	if inFRS(periods, now) {
+		return cmp.Or(ws.frsInterval, interval)
+	}
+	return interval
+}
+
+// The warmer's state for one faculty.
+type warmJob struct {
+	schedule  warmSchedule
+	lastRunAt time.Time
+	lastError string
+	semester  string
+	classes   int
+	seats     [sha256.Size]byte // seatsFingerprint of the last page
+	backoff   pollBackoff
+}
+
+type catalogWarmer struct {
+	mu       sync.Mutex
+	jobs     []*warmJob
+	periods  []dateRange       // SIX_FRS_PERIODS
+	calendar *academicCalendar // FRS periods unless periods are set; may be nil
+}
+
+func newCatalogWarmer(schedules []warmSchedule, periods []dateRange, calendar *academicCalendar) *catalogWarmer {
+	w := &catalogWarmer{periods: periods, calendar: calendar}
+	for _, ws := range schedules {
+		w.jobs = append(w.jobs, &warmJob{schedule: ws})
+	}
+	return w
+}
+
+// Returns the FRS periods in effect.
+func (w *catalogWarmer) frsPeriods() []dateRange {
+	if len(w.periods) > 0 || w.calendar == nil {
+		return w.periods
+	}
+	return w.calendar.frsPeriods()
+}
+
+// Returns how often the job is refreshed at now: its cadence, stretched
+// while its seats stay the same (see poll.go), or sped up near the end of an
+// FRS period.
+func (w *catalogWarmer) intervalLocked(job *warmJob, now time.Time) time.Duration {
+	periods := w.frsPeriods()
+	cadence := job.schedule.cadence(periods, now)
+	if nearFRSDeadline(periods, now) {
+		return cadence / frsDeadlineSpeedup
+	}
+	return job.backoff.stretch(cadence)
+}
+
+// Returns when the job is next due. A job that never ran is due at once.
+func (w *catalogWarmer) nextRunLocked(job *warmJob, now time.Time) time.Time {
+	if job.lastRunAt.IsZero() {
+		return now
+	}
+	return job.lastRunAt.Add(w.intervalLocked(job, now))
+}
+
+// Runs warmDue every warmTick until ctx is done.
+func (s *Server) runCatalogWarmer(ctx context.Context) {
+	if warmCookies == "" || warmStudentID == "" {
+		log.Printf("catalog warmer not started: SIX_WARM_COOKIES and SIX_WARM_STUDENT_ID are required")
+		return
+	}
+	auth := http.Header{"Cookie": {warmCookies}}
+	s.refreshAcademicCalendar(ctx, auth, time.Now())
+	s.warmDue(ctx, auth, warmStudentID, time.Now())
+	ticker := time.NewTicker(warmTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if scrapingPaused() {
+				continue
+			}
+			s.refreshAcademicCalendar(ctx, auth, now)
+			s.warmDue(ctx, auth, warmStudentID, now)
+		}
+	}
+}
+
+// Scrapes the catalog page of every due faculty for the current semester,
+// as studentID with the credentials in auth, and stores it in the catalog.
+func (s *Server) warmDue(ctx context.Context, auth http.Header, studentID string, now time.Time) {
+	s.warmer.mu.Lock()
+	var due []*warmJob
+	for _, job := range s.warmer.jobs {
+		if !now.Before(s.warmer.nextRunLocked(job, now)) {
+			due = append(due, job)
+		}
+	}
+	s.warmer.mu.Unlock()
+
+	ctx = withPriority(ctx, priorityBatch)
+	for _, job := range due {
+		if ctx.Err() != nil || scrapingPaused() {
+			return
+		}
+		semester, _ := s.semesters.resolve(studentID, "current", now)
+		query := url.Values{"fakultas": {job.schedule.fakultas}}
+		s.refresher.run(ctx, refreshCatalogWarm, s.upstreamHost(), semester+"/"+job.schedule.fakultas, func(ctx context.Context) error {
+			classes, err := s.warmCatalog(ctx, auth, studentID, semester, query)
+
+			s.warmer.mu.Lock()
+			defer s.warmer.mu.Unlock()
+			job.lastRunAt, job.lastError = now, ""
+			if err != nil {
+				job.lastError = err.Error()
+				log.Printf("catalog warm failed fakultas=%s semester=%s err=%v", job.schedule.fakultas, semester, err)
+			} else {
+				seats := seatsFingerprint(classes)
+				job.backoff.observe(job.semester != semester || seats != job.seats)
+				job.classes, job.seats, job.semester = len(classes), seats, semester
+			}
+			return err
+		})
+	}
+}
+
+// Scrapes one catalog page and stores it unless it looks anomalous. It
+// returns the classes.
+func (s *Server) warmCatalog(ctx context.Context, auth http.Header, studentID, semester string, query url.Values) ([]CourseClass, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "/api/schedule", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = auth.Clone()
+	classes, meta, err := s.scrapeSchedule(req, studentID, semester, query)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Anomaly != nil {
+		return nil, fmt.Errorf("anomalous page not stored: %s", strings.Join(meta.Anomaly.Reasons, "; "))
+	}
+	if key, ok := catalogKey(semester, query); ok {
+		s.catalog.set(key, classes, meta.FetchedAt)
+	}
+	return classes, nil
+}
+
+// Describes the warmer's jobs for the jobs admin endpoint.
+func (w *catalogWarmer) jobViews(enabled bool, now time.Time) []Job {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	frs := inFRS(w.frsPeriods(), now)
+	views := make([]Job, 0, len(w.jobs))
+	for _, job := range w.jobs {
+		ws := job.schedule
+		interval := cmp.Or(ws.interval, warmInterval)
+		schedule := "every " + interval.String()
+		if frsInterval := cmp.Or(ws.frsInterval, interval); frsInterval != interval {
+			schedule += ", every " + frsInterval.String() + " during FRS"
+		}
+		v := Job{
+			Name:      "catalog_warm:" + ws.fakultas,
+			Enabled:   enabled,
+			Schedule:  schedule,
+			Interval:  w.intervalLocked(job, now).String(),
+			FRS:       &frs,
+			LastError: job.lastError,
+		}
+		if enabled {
+			next := w.nextRunLocked(job, now)
+			v.NextRunAt = &next
+		}
+		if !job.lastRunAt.IsZero() {
+			last := job.lastRunAt
+			v.LastRunAt = &last
+			if job.lastError == "" {
+				v.Result = fmt.Sprintf("%d classes for %s", job.classes, job.semester)
+			}
+		}
+		views = append(views, v)
+	}
+	return views
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseWarmSchedules(t *testing.T) {
+	got := parseWarmSchedules("FTMD=24h/1h, STEI=12h,FMIPA,bad=soon,=1h")
+	want := []warmSchedule{{"FTMD", 24 * time.Hour, time.Hour}, {"STEI", 12 * time.Hour, 0}, {"FMIPA", 0, 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWarmSchedule_Cadence(t *testing.T) {
+	periods := parseFRSPeriods("2026-01-05..2026-01-16, 2026-02-01..2026-01-01")
+	if len(periods) != 1 {
+		t.Fatalf("got %d periods, want the reversed one skipped", len(periods))
+	}
+	ftmd := warmSchedule{fakultas: "FTMD", interval: 24 * time.Hour, frsInterval: time.Hour}
+	fmipa := warmSchedule{fakultas: "FMIPA"}
+	tests := []struct {
+		ws   warmSchedule
+		at   string
+		want time.Duration
+	}{
+		{ftmd, "2026-01-04T23:59:00+07:00", 24 * time.Hour},
+		{ftmd, "2026-01-05T00:00:00+07:00", time.Hour},
+		{ftmd, "2026-01-16T23:59:00+07:00", time.Hour}, // the end date is inclusive
+		{ftmd, "2026-01-17T00:00:00+07:00", 24 * time.Hour},
+		{fmipa, "2026-01-10T12:00:00+07:00", warmInterval},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := tt.ws.cadence(periods, at); got != tt.want {
+			t.Errorf("%s at %s: got %s, want %s", tt.ws.fakultas, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogWarmer_FRSPeriods(t *testing.T) {
+	calendar := newAcademicCalendar()
+	calendar.learn([]CalendarEntry{{Title: "Pengisian FRS", Type: "registration", Start: "2026-07-27", End: "2026-08-07", Semester: "2026-1"}})
+	at := time.Date(2026, 8, 1, 12, 0, 0, 0, wib)
+	if w := newCatalogWarmer(nil, nil, calendar); !inFRS(w.frsPeriods(), at) {
+		t.Error("the calendar's FRS period is not in effect")
+	}
+	// SIX_FRS_PERIODS overrides the calendar.
+	if w := newCatalogWarmer(nil, parseFRSPeriods("2026-01-05..2026-01-16"), calendar); inFRS(w.frsPeriods(), at) {
+		t.Error("the calendar's FRS period is in effect despite SIX_FRS_PERIODS")
+	}
+}
+
+func TestWarmDue_RefreshesOnCadence(t *testing.T) {
+	hits := 0
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Query().Get("fakultas") != "FTMD" {
+			t.Errorf("query = %s, want fakultas=FTMD", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, testScheduleHTML)
+	}))
+	defer mock.Close()
+	srv := newTestServer(mock.URL)
+	srv.warmer = newCatalogWarmer(parseWarmSchedules("FTMD=24h/1h"), parseFRSPeriods("2026-01-05..2026-01-16"), nil)
+	auth := http.Header{"Cookie": {"nissin=a; khongguan=b"}}
+
+	start := time.Date(2026, 1, 2, 8, 0, 0, 0, wib)
+	srv.warmDue(t.Context(), auth, "123", start)
+	if hits != 1 {
+		t.Fatalf("first run: %d hits, want 1", hits)
+	}
+	semester, _ := srv.semesters.resolve("123", "current", start)
+	if e, ok := srv.catalog.get(semester + "?fakultas=FTMD"); !ok || len(e.data) == 0 {
+		t.Errorf("catalog page not stored: %+v, %v", e, ok)
+	}
+
+	// Outside FRS the page is fresh for a day, during FRS for an hour.
+	srv.warmDue(t.Context(), auth, "123", start.Add(2*time.Hour))
+	if hits != 1 {
+		t.Errorf("two hours later: %d hits, want 1", hits)
+	}
+	srv.warmDue(t.Context(), auth, "123", start.Add(24*time.Hour))
+	srv.warmDue(t.Context(), auth, "123", time.Date(2026, 1, 5, 8, 0, 0, 0, wib))
+	srv.warmDue(t.Context(), auth, "123", time.Date(2026, 1, 5, 8, 30, 0, 0, wib))
+	if hits != 3 {
+		t.Errorf("%d hits, want 3", hits)
+	}
+}
+
+func TestJobsHandler(t *testing.T) {
+	setupArchives(t)
+	srv := newTestServer("")
+	srv.warmer = newCatalogWarmer(parseWarmSchedules("FTMD=24h/1h"), nil, nil)
+
+	w := adminRequest(srv, "GET", "/api/admin/jobs", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	jobs := decodeData[[]Job](t, w)
+	var warm *Job
+	for i := range jobs {
+		if jobs[i].Name == "catalog_warm:FTMD" {
+			warm = &jobs[i]
+		}
+	}
+	if warm == nil {
+		t.Fatalf("no catalog_warm:FTMD job in %+v", jobs)
+	}
+	if warm.Schedule != "every 24h0m0s, every 1h0m0s during FRS" || warm.Interval != "24h0m0s" || warm.FRS == nil || *warm.FRS {
+		t.Errorf("warm job = %+v", *warm)
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/jobs", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status %d, want 401", w.Code)
+	}
+}
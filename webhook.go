@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook subscriptions notify a receiver whenever a fresh fetch of a
+// schedule differs from the previously cached one. Every delivery is signed
+// with the subscription's secret and carries an idempotency key and a
+// per-subscription sequence number so receivers can verify and deduplicate.
+const (
+	webhookSignatureHeader   = "X-Signature-256"
+	webhookTimestampHeader   = "X-Webhook-Timestamp"
+	webhookSequenceHeader    = "X-Webhook-Sequence"
+	webhookIdempotencyHeader = "Idempotency-Key"
+)
+
+var (
+	webhookMaxAttempts = envInt("SIX_WEBHOOK_MAX_ATTEMPTS", 5)
+	webhookRetryDelay  = envDuration("SIX_WEBHOOK_RETRY_DELAY", 2*time.Second) // doubled after each failure
+	webhookTimeout     = envDuration("SIX_WEBHOOK_TIMEOUT", 10*time.Second)
+)
+
+type Subscription struct {
+	ID         string     `json:"id"`
+	URL        string     `json:"url"`
+	Secret     string     `json:"secret,omitempty"` // only returned on creation
+	StudentID  string     `json:"student_id"`
+	Semester   string     `json:"semester"`
+	Filters    url.Values `json:"filters,omitempty"`
+	Paused     bool       `json:"paused"`
+	QuietHours string     `json:"quiet_hours,omitempty"` // WIB, e.g. "22:00-07:00"; see deadline.go
+	Deadline   *time.Time `json:"deadline,omitempty"`
+	// HeldSince is when the oldest change held back by quiet hours or the
+	// deadline digest happened. SummarySentAt is set once the deadline
+	// summary has gone out.
+	HeldSince     *time.Time      `json:"held_since,omitempty"`
+	SummarySentAt *time.Time      `json:"summary_sent_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	LastDelivery  *DeliveryStatus `json:"last_delivery,omitempty"`
+	Errors        []DeliveryError `json:"errors,omitempty"`   // most recent failed attempts, oldest first
+	Channels      []*Channel      `json:"channels,omitempty"` // see channels.go
+	// ExpiresAt is when the semester ends and the subscription is removed,
+	// nil for semesters that are not YYYY-T; see expiry.go.
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	ExpiryNoticeSentAt *time.Time `json:"expiry_notice_sent_at,omitempty"`
+
+	owner    string // API key that created it, empty when keys are disabled
+	key      string // schedule cache key the subscription watches
+	sequence int64
+
+	quiet      *quietHours
+	held       *WebhookEvent // changes waiting for delivery, merged
+	lastSentAt time.Time
+}
+
+// Outcome of the latest delivery to a subscription.
+type DeliveryStatus struct {
+	EventID   string    `json:"event_id"`
+	Sequence  int64     `json:"sequence"`
+	Attempts  int       `json:"attempts"`
+	Delivered bool      `json:"delivered"`
+	At        time.Time `json:"at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type DeliveryError struct {
+	EventID string    `json:"event_id"`
+	Attempt int       `json:"attempt"`
+	At      time.Time `json:"at"`
+	Error   string    `json:"error"`
+}
+
+// Number of failed attempts kept per subscription.
+const maxDeliveryErrors = 20
+
+type WebhookEvent struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Sequence   int64          `json:"sequence"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	StudentID  string         `json:"student_id"`
+	Semester   string         `json:"semester"`
+	Classes    []CourseClass  `json:"classes"`
+	Removed    []RemovedClass `json:"removed,omitempty"`    // classes gone since the previous event
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"` // of subscription.expiring events
+}
+
+var (
+	subscriptionsMu sync.Mutex
+	subscriptions   = make(map[string]*Subscription)
+)
+
+// Returns a random hex string of n bytes.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Signs a webhook body. The signature covers the timestamp so a captured
+// delivery cannot be replayed later with a fresh timestamp.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Reports whether signature is valid for the body and timestamp. Receivers
+// written in Go can use this as a reference implementation.
+func verifyWebhook(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signWebhook(secret, timestamp, body)), []byte(signature))
+}
+
+// Caches a freshly fetched schedule. Unless the scrape was flagged as
+// anomalous, it becomes the last known good snapshot, it is recorded in the
+// version history, and, if it differs from what was cached before, the
+// subscriptions watching it are notified.
+func (s *Server) updateSchedule(key, studentID, semester string, classes []CourseClass, fetchedAt time.Time, anomaly *Anomaly) {
+	prev, hadPrev := s.cache.peek(key)
+	s.cache.put(key, cacheEntry{data: classes, fetchedAt: fetchedAt, anomaly: anomaly})
+	if anomaly != nil {
+		log.Printf("anomalous scrape key=%s score=%.2f reasons=%v", key, anomaly.Score, anomaly.Reasons)
+		return
+	}
+	snap := Snapshot{Key: key, StudentID: studentID, Semester: semester, Classes: classes, FetchedAt: fetchedAt}
+	var removed []RemovedClass
+	if before, ok := s.lastGood.get(key); ok {
+		snap.Removed, removed = carryTombstones(before, classes, fetchedAt)
+	}
+	s.lastGood.set(snap)
+	s.history.record(key, classes, fetchedAt)
+	if hadPrev && !sameClasses(prev.data, classes) {
+		s.notifyScheduleChanged(key, studentID, semester, classes, removed)
+	}
+}
+
+func sameClasses(a, b []CourseClass) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func (s *Server) notifyScheduleChanged(key, studentID, semester string, classes []CourseClass, removed []RemovedClass) {
+	now := time.Now()
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	for _, sub := range subscriptions {
+		if sub.key != key || sub.Paused {
+			continue
+		}
+		event := WebhookEvent{
+			Type:       "schedule.changed",
+			OccurredAt: now,
+			StudentID:  studentID,
+			Semester:   semester,
+			Classes:    classes,
+			Removed:    removed,
+		}
+		if sub.releaseAt(now).After(now) {
+			sub.hold(event, now)
+			continue
+		}
+		s.sendLocked(sub, event, now)
+	}
+}
+
+// Posts event to the subscription, retrying with exponential backoff. Every
+// attempt reuses the event ID as idempotency key so receivers can drop
+// duplicates. Returns the last error if all attempts failed.
+func (s *Server) deliverWebhook(ctx context.Context, sub Subscription, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	return retryDelivery(ctx, "webhook", sub.ID, event, func() error {
+		return postWebhook(ctx, client, sub.URL, sub.Secret, event.ID, event.Sequence, "application/json", body)
+	}, func(attempt int, final bool, err error) {
+		recordDelivery(sub.ID, event, attempt, final, err)
+		if final && err != nil {
+			s.deadLetters.add(sub.ID, "", event, attempt, err)
+		}
+	})
+}
+
+// Runs attempt until it succeeds or webhookMaxAttempts attempts failed,
+// doubling the delay between attempts, and passes each outcome to record.
+// what names the delivery in logs.
+func retryDelivery(ctx context.Context, what, subID string, event WebhookEvent, attempt func() error, record func(attempt int, final bool, err error)) error {
+	delay := webhookRetryDelay
+	for n := 1; ; n++ {
+		err := attempt()
+		final := err == nil || n >= webhookMaxAttempts
+		record(n, final, err)
+		if err == nil {
+			log.Printf("%s delivered subscription=%s event=%s attempt=%d", what, subID, event.ID, n)
+			return nil
+		}
+		log.Printf("%s failed subscription=%s event=%s attempt=%d err=%v", what, subID, event.ID, n, err)
+		if final {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Updates the delivery status and error history of a subscription, if it
+// still exists.
+func recordDelivery(subID string, event WebhookEvent, attempt int, final bool, err error) {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	if sub, ok := subscriptions[subID]; ok {
+		noteDelivery(&sub.LastDelivery, &sub.Errors, event, attempt, final, err)
+	}
+}
+
+// Records an attempt in a delivery status and error history. Callers must
+// hold subscriptionsMu.
+func noteDelivery(last **DeliveryStatus, errs *[]DeliveryError, event WebhookEvent, attempt int, final bool, err error) {
+	now := time.Now()
+	status := &DeliveryStatus{EventID: event.ID, Sequence: event.Sequence, Attempts: attempt, Delivered: err == nil, At: now}
+	if err != nil {
+		status.Error = err.Error()
+		*errs = append(*errs, DeliveryError{EventID: event.ID, Attempt: attempt, At: now, Error: err.Error()})
+		if len(*errs) > maxDeliveryErrors {
+			*errs = (*errs)[len(*errs)-maxDeliveryErrors:]
+		}
+	}
+	// Keep a newer event's outcome if deliveries finish out of order.
+	if final && (*last == nil || (*last).Sequence <= event.Sequence) {
+		*last = status
+	}
+}
+
+// Makes one signed delivery attempt of body to target.
+func postWebhook(ctx context.Context, client *http.Client, target, secret, eventID string, sequence int64, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "six-scraper-go-webhook")
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhookSignatureHeader, signWebhook(secret, timestamp, body))
+	req.Header.Set(webhookIdempotencyHeader, eventID)
+	req.Header.Set(webhookSequenceHeader, strconv.FormatInt(sequence, 10))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	sig := signWebhook("secret", 1700000000, body)
+	if !strings.HasPrefix(sig, "sha256=") {
+		t.Errorf("signature %q lacks scheme prefix", sig)
+	}
+	if !verifyWebhook("secret", 1700000000, body, sig) {
+		t.Error("expected signature to verify")
+	}
+	if verifyWebhook("other", 1700000000, body, sig) {
+		t.Error("signature verified with the wrong secret")
+	}
+	if verifyWebhook("secret", 1700000001, body, sig) {
+		t.Error("signature verified with a different timestamp")
+	}
+	if verifyWebhook("secret", 1700000000, []byte(`{"id":"2"}`), sig) {
+		t.Error("signature verified for a tampered body")
+	}
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// Starts a receiver that fails the first failures requests and records all of them.
+func webhookReceiver(t *testing.T, failures int) (*httptest.Server, func() []receivedWebhook) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []receivedWebhook
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, receivedWebhook{header: r.Header.Clone(), body: body})
+		n := len(got)
+		mu.Unlock()
+		if n <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []receivedWebhook {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedWebhook(nil), got...)
+	}
+}
+
+func setupWebhooks(t *testing.T) {
+	t.Helper()
+	oldDelay := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	subscriptions = make(map[string]*Subscription)
+	t.Cleanup(func() {
+		webhookRetryDelay = oldDelay
+		subscriptions = make(map[string]*Subscription)
+	})
+}
+
+// Returns a test server whose deliveries still running when the test ends
+// are waited for, since they write to subscriptions when they finish.
+// Create it after setupWebhooks, so the wait runs before its reset.
+func newWebhookTestServer(t *testing.T, base string) *Server {
+	t.Helper()
+	srv := newTestServer(base)
+	t.Cleanup(srv.deliveries.Wait)
+	return srv
+}
+
+func postSubscription(t *testing.T, body string) (*httptest.ResponseRecorder, Subscription) {
+	t.Helper()
+	w := subscriptionRequest(t, "POST", "/api/subscriptions", body, "")
+	var resp struct {
+		Data Subscription `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp.Data
+}
+
+func TestSubscriptionsHandler_Validation(t *testing.T) {
+	setupWebhooks(t)
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`not json`, http.StatusBadRequest},
+		{`{"url":"ftp://x","student_id":"1","semester":"1945-1"}`, http.StatusUnprocessableEntity},
+		{`{"url":"/relative","student_id":"1","semester":"1945-1"}`, http.StatusUnprocessableEntity},
+		{`{"url":"https://example.com","student_id":"x","semester":"1945-1"}`, http.StatusUnprocessableEntity},
+		{`{"url":"https://example.com","semester":"1945-1"}`, http.StatusUnprocessableEntity},
+	} {
+		if w, _ := postSubscription(t, tt.body); w.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.body, w.Code, tt.want)
+		}
+	}
+
+	if w := subscriptionRequest(t, "PUT", "/api/subscriptions", "", ""); w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("PUT: got status %d (Allow %q), want 405 with Allow: GET, POST", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestWebhook_DeliversSignedChanges(t *testing.T) {
+	setupWebhooks(t)
+	srv := newWebhookTestServer(t, "")
+	receiver, received := webhookReceiver(t, 0)
+
+	w, sub := postSubscription(t, `{"url":"`+receiver.URL+`","student_id":"123","semester":"1945-1","filters":{"prodi":["102"]}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want 201: %s", w.Code, w.Body)
+	}
+	if sub.ID == "" || len(sub.Secret) != 64 {
+		t.Fatalf("expected id and generated secret, got %+v", sub)
+	}
+
+	key := schedulePath("123", "1945-1", url.Values{"prodi": {"102"}})
+	v1 := []CourseClass{{Code: "FI1210", Quota: 40}}
+	v2 := []CourseClass{{Code: "FI1210", Quota: 50}}
+	v3 := []CourseClass{{Code: "FI1210", Quota: 45}}
+
+	srv.updateSchedule(key, "123", "1945-1", v1, time.Now(), nil) // first fetch is not a change
+	srv.updateSchedule(key, "123", "1945-1", v1, time.Now(), nil) // unchanged
+	srv.updateSchedule(schedulePath("123", "1945-1", nil), "123", "1945-1", v2, time.Now(), nil)
+	srv.updateSchedule(key, "123", "1945-1", v2, time.Now(), nil)
+	waitFor(t, func() bool { return len(received()) == 1 })
+	srv.updateSchedule(key, "123", "1945-1", v3, time.Now(), nil)
+	waitFor(t, func() bool { return len(received()) == 2 })
+
+	for i, got := range received() {
+		ts, _ := strconv.ParseInt(got.header.Get(webhookTimestampHeader), 10, 64)
+		if !verifyWebhook(sub.Secret, ts, got.body, got.header.Get(webhookSignatureHeader)) {
+			t.Errorf("delivery %d: signature does not verify", i)
+		}
+		var event WebhookEvent
+		if err := json.Unmarshal(got.body, &event); err != nil {
+			t.Fatal(err)
+		}
+		if got.header.Get(webhookIdempotencyHeader) != event.ID {
+			t.Errorf("delivery %d: idempotency key %q != event id %q", i, got.header.Get(webhookIdempotencyHeader), event.ID)
+		}
+		if event.Sequence != int64(i+1) || got.header.Get(webhookSequenceHeader) != strconv.Itoa(i+1) {
+			t.Errorf("delivery %d: sequence = %d, want %d", i, event.Sequence, i+1)
+		}
+	}
+}
+
+func TestDeliverWebhook_RetriesWithSameIdempotencyKey(t *testing.T) {
+	setupWebhooks(t)
+	srv, received := webhookReceiver(t, 2)
+
+	sub := Subscription{ID: "s1", URL: srv.URL, Secret: "secret"}
+	event := WebhookEvent{ID: "evt1", Sequence: 1}
+	if err := newTestServer("").deliverWebhook(context.Background(), sub, event); err != nil {
+		t.Fatalf("expected delivery to succeed on the third attempt: %v", err)
+	}
+	got := received()
+	if len(got) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(got))
+	}
+	for _, r := range got {
+		if r.header.Get(webhookIdempotencyHeader) != "evt1" {
+			t.Errorf("idempotency key = %q, want evt1", r.header.Get(webhookIdempotencyHeader))
+		}
+	}
+}
+
+func TestDeliverWebhook_GivesUp(t *testing.T) {
+	setupWebhooks(t)
+	srv, received := webhookReceiver(t, 100)
+	oldMax := webhookMaxAttempts
+	webhookMaxAttempts = 2
+	defer func() { webhookMaxAttempts = oldMax }()
+
+	err := newTestServer("").deliverWebhook(context.Background(), Subscription{URL: srv.URL, Secret: "s"}, WebhookEvent{ID: "e"})
+	if err == nil {
+		t.Fatal("expected an error after exhausting attempts")
+	}
+	if n := len(received()); n != 2 {
+		t.Errorf("got %d attempts, want 2", n)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
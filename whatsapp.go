@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// The WhatsApp Business (Cloud API) bot is enabled by SIX_WHATSAPP_APP_SECRET,
+// SIX_WHATSAPP_TOKEN, and SIX_WHATSAPP_PHONE_ID. Its webhook URL is
+// /api/chat/webhook/whatsapp, verified with SIX_WHATSAPP_VERIFY_TOKEN.
+var (
+	whatsappAppSecret   = envString("SIX_WHATSAPP_APP_SECRET", "")
+	whatsappToken       = envString("SIX_WHATSAPP_TOKEN", "")
+	whatsappPhoneID     = envString("SIX_WHATSAPP_PHONE_ID", "")
+	whatsappVerifyToken = envString("SIX_WHATSAPP_VERIFY_TOKEN", "")
+	whatsappAPIURL      = envString("SIX_WHATSAPP_API_URL", "https://graph.facebook.com/v21.0")
+)
+
+type whatsappAdapter struct {
+	secret      string
+	token       string
+	phoneID     string
+	verifyToken string
+	apiURL      string
+}
+
+// Returns nil unless the WhatsApp bot is configured.
+func newWhatsAppAdapter() *whatsappAdapter {
+	if whatsappAppSecret == "" || whatsappToken == "" || whatsappPhoneID == "" {
+		return nil
+	}
+	return &whatsappAdapter{
+		secret:      whatsappAppSecret,
+		token:       whatsappToken,
+		phoneID:     whatsappPhoneID,
+		verifyToken: whatsappVerifyToken,
+		apiURL:      strings.TrimSuffix(whatsappAPIURL, "/"),
+	}
+}
+
+func (a *whatsappAdapter) Name() string { return "whatsapp" }
+
+// Meta signs the body with the app secret, as "sha256=" and hex in
+// X-Hub-Signature-256.
+func (a *whatsappAdapter) Verify(r *http.Request, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(a.secret))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(r.Header.Get("X-Hub-Signature-256")))
+}
+
+type whatsappWebhook struct {
+	Entry []struct {
+		Changes []struct {
+			Value struct {
+				Messages []struct {
+					From string `json:"from"`
+					Type string `json:"type"`
+					Text struct {
+						Body string `json:"body"`
+					} `json:"text"`
+				} `json:"messages"`
+			} `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+func (a *whatsappAdapter) Messages(body []byte) ([]ChatMessage, error) {
+	var hook whatsappWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return nil, err
+	}
+	var messages []ChatMessage
+	for _, e := range hook.Entry {
+		for _, c := range e.Changes {
+			for _, m := range c.Value.Messages {
+				if m.Type == "text" && m.From != "" {
+					messages = append(messages, ChatMessage{UserID: m.From, Text: m.Text.Body})
+				}
+			}
+		}
+	}
+	return messages, nil
+}
+
+func (a *whatsappAdapter) Reply(ctx context.Context, msg ChatMessage, text string) error {
+	type whatsappText struct {
+		Body string `json:"body"`
+	}
+	return postChatAPI(ctx, a.apiURL+"/"+a.phoneID+"/messages", a.token, struct {
+		MessagingProduct string       `json:"messaging_product"`
+		To               string       `json:"to"`
+		Type             string       `json:"type"`
+		Text             whatsappText `json:"text"`
+	}{"whatsapp", msg.UserID, "text", whatsappText{text}})
+}
+
+// GET /api/chat/webhook/{provider}
+//
+// Answers Meta's webhook verification by echoing hub.challenge when
+// hub.verify_token matches SIX_WHATSAPP_VERIFY_TOKEN. Other providers do
+// not verify webhooks this way.
+func (s *Server) chatVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.chat[r.PathValue("provider")].(*whatsappAdapter)
+	if !ok || a.verifyToken == "" {
+		writeError(w, r, codeChatDisabled)
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("hub.mode") != "subscribe" || subtle.ConstantTimeCompare([]byte(q.Get("hub.verify_token")), []byte(a.verifyToken)) != 1 {
+		writeError(w, r, codeChatSignature)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(q.Get("hub.challenge")))
+}
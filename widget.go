@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GET /api/widget is a small payload for home screen widgets (Scriptable on
+// iOS, KWGT on Android), separate from /api/schedule so that it can stay
+// stable while the schedule API grows. The widget works out which session
+// is next from the absolute times, so the response stays valid until the
+// first listed session ends and can be cached that long.
+var widgetMaxAge = envDuration("SIX_WIDGET_MAX_AGE", 6*time.Hour)
+
+// Sessions a widget response lists.
+const widgetSessions = 3
+
+// Version of the widget payload. Fields are only ever added; anything else
+// gets a new version.
+const widgetVersion = 1
+
+type Widget struct {
+	Version   int             `json:"v"`
+	UpdatedAt time.Time       `json:"updated_at"` // when the schedule was fetched from SIX
+	Sessions  []WidgetSession `json:"sessions"`
+}
+
+type WidgetSession struct {
+	Code   string    `json:"code"`
+	Name   string    `json:"name"`
+	Room   string    `json:"room,omitempty"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Online bool      `json:"online,omitempty"`
+}
+
+func widgetPayload(classes []CourseClass, fetchedAt, now time.Time) Widget {
+	out := Widget{Version: widgetVersion, UpdatedAt: fetchedAt, Sessions: []WidgetSession{}}
+	for _, u := range upcomingSessions(scheduleGrid(classes, nil), now, widgetSessions) {
+		out.Sessions = append(out.Sessions, WidgetSession{
+			Code: u.Code, Name: u.Name, Room: u.Room,
+			Start: u.StartsAt, End: u.EndsAt,
+			Online: strings.EqualFold(u.Method, "online"),
+		})
+	}
+	return out
+}
+
+// How long a widget payload may be cached: until its first session ends,
+// at most widgetMaxAge.
+func widgetCacheAge(wg Widget, now time.Time) time.Duration {
+	age := widgetMaxAge
+	if len(wg.Sessions) > 0 {
+		age = min(age, wg.Sessions[0].End.Sub(now))
+	}
+	return max(age, 0)
+}
+
+// GET /api/widget
+func (s *Server) widgetHandler(w http.ResponseWriter, r *http.Request) {
+	classes, meta, ok := s.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	wg := widgetPayload(classes, meta.FetchedAt, now)
+	w.Header().Set("Content-Type", "application/json")
+	// private: the payload belongs to the student whose cookies fetched it.
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(widgetCacheAge(wg, now).Seconds())))
+	noteEncoded(w, wg)
+	if err := json.NewEncoder(w).Encode(wg); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
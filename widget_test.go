@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWidgetPayload(t *testing.T) {
+	classes := parseClasses(docFromHTML(testScheduleHTML))
+	fetched := time.Date(2026, 10, 11, 20, 0, 0, 0, wib)
+	now := time.Date(2026, 10, 12, 8, 0, 0, 0, wib) // a Monday, during FI1210
+
+	wg := widgetPayload(classes, fetched, now)
+	if wg.Version != 1 || !wg.UpdatedAt.Equal(fetched) || len(wg.Sessions) != 3 {
+		t.Fatalf("widget = %+v", wg)
+	}
+	first, last := wg.Sessions[0], wg.Sessions[2]
+	if first.Code != "FI1210" || first.Room != "7602" || first.Online || !first.End.Equal(time.Date(2026, 10, 12, 9, 0, 0, 0, wib)) {
+		t.Errorf("first session = %+v", first)
+	}
+	if last.Code != "FI1210" || !last.Online {
+		t.Errorf("last session = %+v, want the online Rabu meeting", last)
+	}
+	if age := widgetCacheAge(wg, now); age != time.Hour {
+		t.Errorf("cache age = %s, want 1h, until the first session ends", age)
+	}
+	if age := widgetCacheAge(widgetPayload(nil, fetched, now), now); age != widgetMaxAge {
+		t.Errorf("cache age without sessions = %s, want %s", age, widgetMaxAge)
+	}
+}
+
+func TestWidgetHandler(t *testing.T) {
+	mock := mockSIX("13520001", "2025-2")
+	defer mock.Close()
+	req := httptest.NewRequest("GET", "/api/widget?student_id=13520001&semester=2025-2", nil)
+	addAuthCookies(req)
+	w := httptest.NewRecorder()
+	newTestServer(mock.URL).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private, max-age=") {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["success"]; ok || string(raw["v"]) != "1" {
+		t.Errorf("body = %s, want the bare version 1 payload", w.Body)
+	}
+	if w.Body.Len() > 1024 {
+		t.Errorf("payload is %d bytes", w.Body.Len())
+	}
+}